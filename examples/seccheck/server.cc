@@ -125,6 +125,22 @@ std::vector<Callback> dispatchers = {
     unpackSyscall<::gvisor::syscall::InotifyRmWatch>,
     unpackSyscall<::gvisor::syscall::SocketPair>,
     unpackSyscall<::gvisor::syscall::Write>,
+    unpackSyscall<::gvisor::syscall::Unlink>,
+    unpackSyscall<::gvisor::syscall::Rename>,
+    unpackSyscall<::gvisor::syscall::Chmod>,
+    unpackSyscall<::gvisor::syscall::Chown>,
+    unpackSyscall<::gvisor::syscall::Link>,
+    unpackSyscall<::gvisor::syscall::Symlink>,
+    unpackSyscall<::gvisor::syscall::Mmap>,
+    unpackSyscall<::gvisor::syscall::Mprotect>,
+    unpackSyscall<::gvisor::syscall::Mount>,
+    unpackSyscall<::gvisor::syscall::Umount>,
+    unpackSyscall<::gvisor::syscall::Ptrace>,
+    unpackSyscall<::gvisor::syscall::Setsockopt>,
+    unpackSyscall<::gvisor::syscall::InitModule>,
+    unpackSyscall<::gvisor::syscall::DeleteModule>,
+    unpackSyscall<::gvisor::syscall::Capset>,
+    unpack<::gvisor::sentry::Denial>,
 };
 
 void unpack(absl::string_view buf) {
@@ -0,0 +1,80 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// IP tunnel attributes, from uapi/linux/if_tunnel.h.
+const (
+	IFLA_IPTUN_UNSPEC              = 0
+	IFLA_IPTUN_LINK                = 1
+	IFLA_IPTUN_LOCAL               = 2
+	IFLA_IPTUN_REMOTE              = 3
+	IFLA_IPTUN_TTL                 = 4
+	IFLA_IPTUN_TOS                 = 5
+	IFLA_IPTUN_ENCAP_LIMIT         = 6
+	IFLA_IPTUN_FLOWINFO            = 7
+	IFLA_IPTUN_FLAGS               = 8
+	IFLA_IPTUN_PROTO               = 9
+	IFLA_IPTUN_PMTUDISC            = 10
+	IFLA_IPTUN_6RD_PREFIX          = 11
+	IFLA_IPTUN_6RD_RELAY_PREFIX    = 12
+	IFLA_IPTUN_6RD_PREFIXLEN       = 13
+	IFLA_IPTUN_6RD_RELAY_PREFIXLEN = 14
+	IFLA_IPTUN_ENCAP_TYPE          = 15
+	IFLA_IPTUN_ENCAP_FLAGS         = 16
+	IFLA_IPTUN_ENCAP_SPORT         = 17
+	IFLA_IPTUN_ENCAP_DPORT         = 18
+	IFLA_IPTUN_COLLECT_METADATA    = 19
+	IFLA_IPTUN_FWMARK              = 20
+)
+
+// GRE tunnel attributes, from uapi/linux/if_tunnel.h.
+const (
+	IFLA_GRE_UNSPEC           = 0
+	IFLA_GRE_LINK             = 1
+	IFLA_GRE_IFLAGS           = 2
+	IFLA_GRE_OFLAGS           = 3
+	IFLA_GRE_IKEY             = 4
+	IFLA_GRE_OKEY             = 5
+	IFLA_GRE_LOCAL            = 6
+	IFLA_GRE_REMOTE           = 7
+	IFLA_GRE_TTL              = 8
+	IFLA_GRE_TOS              = 9
+	IFLA_GRE_PMTUDISC         = 10
+	IFLA_GRE_ENCAP_LIMIT      = 11
+	IFLA_GRE_FLOWINFO         = 12
+	IFLA_GRE_FLAGS            = 13
+	IFLA_GRE_ENCAP_TYPE       = 14
+	IFLA_GRE_ENCAP_FLAGS      = 15
+	IFLA_GRE_ENCAP_SPORT      = 16
+	IFLA_GRE_ENCAP_DPORT      = 17
+	IFLA_GRE_COLLECT_METADATA = 18
+	IFLA_GRE_IGNORE_DF        = 19
+	IFLA_GRE_FWMARK           = 20
+)
+
+// GRE flags, from uapi/linux/if_tunnel.h. The flags are carried in network
+// byte order in IFLA_GRE_IFLAGS and IFLA_GRE_OFLAGS; the values below are in
+// host byte order.
+const (
+	GRE_CSUM    = 0x8000
+	GRE_ROUTING = 0x4000
+	GRE_KEY     = 0x2000
+	GRE_SEQ     = 0x1000
+	GRE_STRICT  = 0x0800
+	GRE_REC     = 0x0700
+	GRE_ACK     = 0x0080
+	GRE_FLAGS   = 0x0078
+	GRE_VERSION = 0x0007
+)
@@ -425,6 +425,42 @@ type XTNATTargetV1 struct {
 // SizeOfXTNATTargetV1 is the size of an XTNATTargetV1.
 const SizeOfXTNATTargetV1 = SizeOfXTEntryTarget + SizeOfNFNATRange
 
+// XTTProxyTargetV0 diverts packets to a local socket when reached. It
+// corresponds to struct xt_tproxy_target_info in
+// include/uapi/linux/netfilter/xt_TPROXY.h, and is only used with IPv4.
+// LAddr and LPort are in network byte order.
+//
+// +marshal
+type XTTProxyTargetV0 struct {
+	Target    XTEntryTarget
+	MarkMask  uint32
+	MarkValue uint32
+	LAddr     InetAddr
+	LPort     uint16
+	_         [2]byte
+}
+
+// SizeOfXTTProxyTargetV0 is the size of an XTTProxyTargetV0.
+const SizeOfXTTProxyTargetV0 = 48
+
+// XTTProxyTargetV1 diverts packets to a local socket when reached. It
+// corresponds to struct xt_tproxy_target_info_v1 in
+// include/uapi/linux/netfilter/xt_TPROXY.h. LAddr and LPort are in network
+// byte order. Adding 4 bytes of padding to make the struct 8 byte aligned.
+//
+// +marshal
+type XTTProxyTargetV1 struct {
+	Target    XTEntryTarget
+	MarkMask  uint32
+	MarkValue uint32
+	LAddr     [16]byte
+	LPort     uint16
+	_         [6]byte
+}
+
+// SizeOfXTTProxyTargetV1 is the size of an XTTProxyTargetV1.
+const SizeOfXTTProxyTargetV1 = 64
+
 // XTNATTargetV2 triggers NAT when reached.
 //
 // +marshal
@@ -728,6 +764,29 @@ const (
 	XT_OWNER_SOCKET = 1 << 2
 )
 
+// XTSocketMatchInfo holds data for matching packets with the socket
+// matcher, revisions 1 to 3. It corresponds to struct xt_socket_mtinfo1 in
+// include/uapi/linux/netfilter/xt_socket.h.
+//
+// +marshal
+type XTSocketMatchInfo struct {
+	Flags uint8
+}
+
+// SizeOfXTSocketMatchInfo is the size of an XTSocketMatchInfo.
+const SizeOfXTSocketMatchInfo = 1
+
+// Flags in XTSocketMatchInfo.Flags. Corresponding constants are in
+// include/uapi/linux/netfilter/xt_socket.h.
+const (
+	// Only match sockets with IP_TRANSPARENT set.
+	XT_SOCKET_TRANSPARENT = 1 << 0
+	// Don't match listening sockets bound to a wildcard address.
+	XT_SOCKET_NOWILDCARD = 1 << 1
+	// Restore the packet mark from the socket mark.
+	XT_SOCKET_RESTORESKMARK = 1 << 2
+)
+
 // XT_MULTI_PORTS is the maximum number of ports that the
 // multiport match can handle.
 const XT_MULTI_PORTS = 15
@@ -214,7 +214,11 @@ const (
 const (
 	ARPHRD_NONE     = 65534
 	ARPHRD_ETHER    = 1
+	ARPHRD_TUNNEL   = 768
+	ARPHRD_TUNNEL6  = 769
 	ARPHRD_LOOPBACK = 772
+	ARPHRD_SIT      = 776
+	ARPHRD_IPGRE    = 778
 )
 
 // RouteMessage is struct rtmsg, from uapi/linux/rtnetlink.h.
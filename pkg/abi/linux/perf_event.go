@@ -0,0 +1,274 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// Event types for PerfEventAttr.Type. See include/uapi/linux/perf_event.h.
+const (
+	PERF_TYPE_HARDWARE   = 0
+	PERF_TYPE_SOFTWARE   = 1
+	PERF_TYPE_TRACEPOINT = 2
+	PERF_TYPE_HW_CACHE   = 3
+	PERF_TYPE_RAW        = 4
+	PERF_TYPE_BREAKPOINT = 5
+)
+
+// Software events for PerfEventAttr.Config when PerfEventAttr.Type is
+// PERF_TYPE_SOFTWARE. See include/uapi/linux/perf_event.h.
+const (
+	PERF_COUNT_SW_CPU_CLOCK        = 0
+	PERF_COUNT_SW_TASK_CLOCK       = 1
+	PERF_COUNT_SW_PAGE_FAULTS      = 2
+	PERF_COUNT_SW_CONTEXT_SWITCHES = 3
+	PERF_COUNT_SW_CPU_MIGRATIONS   = 4
+	PERF_COUNT_SW_PAGE_FAULTS_MIN  = 5
+	PERF_COUNT_SW_PAGE_FAULTS_MAJ  = 6
+	PERF_COUNT_SW_ALIGNMENT_FAULTS = 7
+	PERF_COUNT_SW_EMULATION_FAULTS = 8
+	PERF_COUNT_SW_DUMMY            = 9
+	PERF_COUNT_SW_BPF_OUTPUT       = 10
+	PERF_COUNT_SW_CGROUP_SWITCHES  = 11
+)
+
+// Bits for PerfEventAttr.SampleType. See include/uapi/linux/perf_event.h.
+const (
+	PERF_SAMPLE_IP             = 1 << 0
+	PERF_SAMPLE_TID            = 1 << 1
+	PERF_SAMPLE_TIME           = 1 << 2
+	PERF_SAMPLE_ADDR           = 1 << 3
+	PERF_SAMPLE_READ           = 1 << 4
+	PERF_SAMPLE_CALLCHAIN      = 1 << 5
+	PERF_SAMPLE_ID             = 1 << 6
+	PERF_SAMPLE_CPU            = 1 << 7
+	PERF_SAMPLE_PERIOD         = 1 << 8
+	PERF_SAMPLE_STREAM_ID      = 1 << 9
+	PERF_SAMPLE_RAW            = 1 << 10
+	PERF_SAMPLE_BRANCH_STACK   = 1 << 11
+	PERF_SAMPLE_REGS_USER      = 1 << 12
+	PERF_SAMPLE_STACK_USER     = 1 << 13
+	PERF_SAMPLE_WEIGHT         = 1 << 14
+	PERF_SAMPLE_DATA_SRC       = 1 << 15
+	PERF_SAMPLE_IDENTIFIER     = 1 << 16
+	PERF_SAMPLE_TRANSACTION    = 1 << 17
+	PERF_SAMPLE_REGS_INTR      = 1 << 18
+	PERF_SAMPLE_PHYS_ADDR      = 1 << 19
+	PERF_SAMPLE_AUX            = 1 << 20
+	PERF_SAMPLE_CGROUP         = 1 << 21
+	PERF_SAMPLE_DATA_PAGE_SIZE = 1 << 22
+	PERF_SAMPLE_CODE_PAGE_SIZE = 1 << 23
+	PERF_SAMPLE_WEIGHT_STRUCT  = 1 << 24
+)
+
+// Bits for PerfEventAttr.ReadFormat. See include/uapi/linux/perf_event.h.
+const (
+	PERF_FORMAT_TOTAL_TIME_ENABLED = 1 << 0
+	PERF_FORMAT_TOTAL_TIME_RUNNING = 1 << 1
+	PERF_FORMAT_ID                 = 1 << 2
+	PERF_FORMAT_GROUP              = 1 << 3
+	PERF_FORMAT_LOST               = 1 << 4
+)
+
+// Bits for PerfEventAttr.Flags, which holds the bitfields of struct
+// perf_event_attr. See include/uapi/linux/perf_event.h.
+const (
+	PERF_ATTR_FLAG_DISABLED                 = 1 << 0
+	PERF_ATTR_FLAG_INHERIT                  = 1 << 1
+	PERF_ATTR_FLAG_PINNED                   = 1 << 2
+	PERF_ATTR_FLAG_EXCLUSIVE                = 1 << 3
+	PERF_ATTR_FLAG_EXCLUDE_USER             = 1 << 4
+	PERF_ATTR_FLAG_EXCLUDE_KERNEL           = 1 << 5
+	PERF_ATTR_FLAG_EXCLUDE_HV               = 1 << 6
+	PERF_ATTR_FLAG_EXCLUDE_IDLE             = 1 << 7
+	PERF_ATTR_FLAG_MMAP                     = 1 << 8
+	PERF_ATTR_FLAG_COMM                     = 1 << 9
+	PERF_ATTR_FLAG_FREQ                     = 1 << 10
+	PERF_ATTR_FLAG_INHERIT_STAT             = 1 << 11
+	PERF_ATTR_FLAG_ENABLE_ON_EXEC           = 1 << 12
+	PERF_ATTR_FLAG_TASK                     = 1 << 13
+	PERF_ATTR_FLAG_WATERMARK                = 1 << 14
+	PERF_ATTR_FLAG_PRECISE_IP_MASK          = 3 << 15
+	PERF_ATTR_FLAG_MMAP_DATA                = 1 << 17
+	PERF_ATTR_FLAG_SAMPLE_ID_ALL            = 1 << 18
+	PERF_ATTR_FLAG_EXCLUDE_HOST             = 1 << 19
+	PERF_ATTR_FLAG_EXCLUDE_GUEST            = 1 << 20
+	PERF_ATTR_FLAG_EXCLUDE_CALLCHAIN_KERNEL = 1 << 21
+	PERF_ATTR_FLAG_EXCLUDE_CALLCHAIN_USER   = 1 << 22
+	PERF_ATTR_FLAG_MMAP2                    = 1 << 23
+	PERF_ATTR_FLAG_COMM_EXEC                = 1 << 24
+	PERF_ATTR_FLAG_USE_CLOCKID              = 1 << 25
+	PERF_ATTR_FLAG_CONTEXT_SWITCH           = 1 << 26
+	PERF_ATTR_FLAG_WRITE_BACKWARD           = 1 << 27
+	PERF_ATTR_FLAG_NAMESPACES               = 1 << 28
+	PERF_ATTR_FLAG_KSYMBOL                  = 1 << 29
+	PERF_ATTR_FLAG_BPF_EVENT                = 1 << 30
+	PERF_ATTR_FLAG_AUX_OUTPUT               = 1 << 31
+	PERF_ATTR_FLAG_CGROUP                   = 1 << 32
+	PERF_ATTR_FLAG_TEXT_POKE                = 1 << 33
+	PERF_ATTR_FLAG_BUILD_ID                 = 1 << 34
+	PERF_ATTR_FLAG_INHERIT_THREAD           = 1 << 35
+	PERF_ATTR_FLAG_REMOVE_ON_EXEC           = 1 << 36
+	PERF_ATTR_FLAG_SIGTRAP                  = 1 << 37
+)
+
+// Sizes of the published versions of struct perf_event_attr. See
+// include/uapi/linux/perf_event.h.
+const (
+	PERF_ATTR_SIZE_VER0 = 64
+	PERF_ATTR_SIZE_VER1 = 72
+	PERF_ATTR_SIZE_VER2 = 80
+	PERF_ATTR_SIZE_VER3 = 96
+	PERF_ATTR_SIZE_VER4 = 104
+	PERF_ATTR_SIZE_VER5 = 112
+	PERF_ATTR_SIZE_VER6 = 120
+	PERF_ATTR_SIZE_VER7 = 128
+	PERF_ATTR_SIZE_VER8 = 136
+)
+
+// PerfEventAttr is struct perf_event_attr, from
+// include/uapi/linux/perf_event.h. Unions are represented by their first
+// member.
+//
+// +marshal
+type PerfEventAttr struct {
+	Type             uint32
+	Size             uint32
+	Config           uint64
+	SamplePeriod     uint64 // Union with sample_freq.
+	SampleType       uint64
+	ReadFormat       uint64
+	Flags            uint64 // PERF_ATTR_FLAG_*.
+	WakeupEvents     uint32 // Union with wakeup_watermark.
+	BPType           uint32
+	Config1          uint64 // Union with bp_addr, kprobe_func, uprobe_path.
+	Config2          uint64 // Union with bp_len, kprobe_addr, probe_offset.
+	BranchSampleType uint64
+	SampleRegsUser   uint64
+	SampleStackUser  uint32
+	ClockID          int32
+	SampleRegsIntr   uint64
+	AuxWatermark     uint32
+	SampleMaxStack   uint16
+	_                uint16
+	AuxSampleSize    uint32
+	_                uint32
+	SigData          uint64
+	Config3          uint64
+}
+
+// Flags for perf_event_open(2). See include/uapi/linux/perf_event.h.
+const (
+	PERF_FLAG_FD_NO_GROUP = 1 << 0
+	PERF_FLAG_FD_OUTPUT   = 1 << 1
+	PERF_FLAG_PID_CGROUP  = 1 << 2
+	PERF_FLAG_FD_CLOEXEC  = 1 << 3
+)
+
+// Ioctls for perf event file descriptors. See
+// include/uapi/linux/perf_event.h.
+var (
+	PERF_EVENT_IOC_ENABLE            = IO('$', 0)
+	PERF_EVENT_IOC_DISABLE           = IO('$', 1)
+	PERF_EVENT_IOC_REFRESH           = IO('$', 2)
+	PERF_EVENT_IOC_RESET             = IO('$', 3)
+	PERF_EVENT_IOC_PERIOD            = IOW('$', 4, 8)
+	PERF_EVENT_IOC_SET_OUTPUT        = IO('$', 5)
+	PERF_EVENT_IOC_SET_FILTER        = IOW('$', 6, 8)
+	PERF_EVENT_IOC_ID                = IOR('$', 7, 8)
+	PERF_EVENT_IOC_SET_BPF           = IOW('$', 8, 4)
+	PERF_EVENT_IOC_PAUSE_OUTPUT      = IOW('$', 9, 4)
+	PERF_EVENT_IOC_QUERY_BPF         = IOWR('$', 10, 8)
+	PERF_EVENT_IOC_MODIFY_ATTRIBUTES = IOW('$', 11, 8)
+)
+
+// PERF_IOC_FLAG_GROUP applies an ioctl to all events in the group of a perf
+// event.
+const PERF_IOC_FLAG_GROUP = 1 << 0
+
+// PerfEventMmapPage is the header of struct perf_event_mmap_page, from
+// include/uapi/linux/perf_event.h, which is the first page of a perf event
+// ring buffer mapping. The reserved space between the header and DataHead is
+// not included.
+//
+// +marshal
+type PerfEventMmapPage struct {
+	Version       uint32
+	CompatVersion uint32
+	Lock          uint32
+	Index         uint32
+	Offset        int64
+	TimeEnabled   uint64
+	TimeRunning   uint64
+	Capabilities  uint64
+	PMCWidth      uint16
+	TimeShift     uint16
+	TimeMult      uint32
+	TimeOffset    uint64
+	TimeZero      uint64
+	Size          uint32
+	_             uint32
+	TimeCycles    uint64
+	TimeMask      uint64
+}
+
+// Offsets of the ring buffer control fields in struct perf_event_mmap_page.
+// See include/uapi/linux/perf_event.h.
+const (
+	PERF_MMAP_PAGE_DATA_HEAD   = 1024
+	PERF_MMAP_PAGE_DATA_TAIL   = 1032
+	PERF_MMAP_PAGE_DATA_OFFSET = 1040
+	PERF_MMAP_PAGE_DATA_SIZE   = 1048
+)
+
+// PERF_MMAP_PAGE_CAP_BIT0_IS_DEPRECATED is set in
+// PerfEventMmapPage.Capabilities to indicate that cap_usr_time and
+// cap_usr_rdpmc are valid.
+const PERF_MMAP_PAGE_CAP_BIT0_IS_DEPRECATED = 1 << 1
+
+// PerfEventHeader is struct perf_event_header, from
+// include/uapi/linux/perf_event.h.
+//
+// +marshal
+type PerfEventHeader struct {
+	Type uint32
+	Misc uint16
+	Size uint16
+}
+
+// Record types for PerfEventHeader.Type. See include/uapi/linux/perf_event.h.
+const (
+	PERF_RECORD_MMAP   = 1
+	PERF_RECORD_LOST   = 2
+	PERF_RECORD_COMM   = 3
+	PERF_RECORD_EXIT   = 4
+	PERF_RECORD_FORK   = 7
+	PERF_RECORD_SAMPLE = 9
+	PERF_RECORD_MMAP2  = 10
+)
+
+// Bits for PerfEventHeader.Misc. See include/uapi/linux/perf_event.h.
+const (
+	PERF_RECORD_MISC_KERNEL    = 1
+	PERF_RECORD_MISC_USER      = 2
+	PERF_RECORD_MISC_COMM_EXEC = 1 << 13
+)
+
+// Callchain context markers. See include/uapi/linux/perf_event.h.
+const (
+	PERF_CONTEXT_KERNEL = ^uint64(128 - 1)
+	PERF_CONTEXT_USER   = ^uint64(512 - 1)
+)
+
+// PERF_MAX_STACK_DEPTH is the default maximum number of frames in a
+// callchain, as in /proc/sys/kernel/perf_event_max_stack.
+const PERF_MAX_STACK_DEPTH = 127
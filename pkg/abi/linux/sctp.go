@@ -0,0 +1,160 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// SOL_SCTP is the socket option level for SCTP, from include/linux/socket.h.
+const SOL_SCTP = 132
+
+// Socket options from uapi/linux/sctp.h.
+const (
+	SCTP_RTOINFO            = 0
+	SCTP_ASSOCINFO          = 1
+	SCTP_INITMSG            = 2
+	SCTP_NODELAY            = 3
+	SCTP_AUTOCLOSE          = 4
+	SCTP_DEFAULT_SEND_PARAM = 10
+	SCTP_MAXSEG             = 13
+	SCTP_STATUS             = 14
+	SCTP_DEFAULT_SNDINFO    = 34
+)
+
+// Send and receive flags from uapi/linux/sctp.h.
+const (
+	SCTP_UNORDERED = 1 << 0
+)
+
+// Peer address states from uapi/linux/sctp.h.
+const (
+	SCTP_INACTIVE    = 0
+	SCTP_PF          = 1
+	SCTP_ACTIVE      = 2
+	SCTP_UNCONFIRMED = 3
+)
+
+// Association states from uapi/linux/sctp.h.
+const (
+	SCTP_EMPTY             = 0
+	SCTP_CLOSED            = 1
+	SCTP_COOKIE_WAIT       = 2
+	SCTP_COOKIE_ECHOED     = 3
+	SCTP_ESTABLISHED       = 4
+	SCTP_SHUTDOWN_PENDING  = 5
+	SCTP_SHUTDOWN_SENT     = 6
+	SCTP_SHUTDOWN_RECEIVED = 7
+	SCTP_SHUTDOWN_ACK_SENT = 8
+)
+
+// SCTPRTOInfo is struct sctp_rtoinfo, from uapi/linux/sctp.h. Times are in
+// milliseconds.
+//
+// +marshal
+type SCTPRTOInfo struct {
+	AssocID int32
+	Initial uint32
+	Max     uint32
+	Min     uint32
+}
+
+// SizeOfSCTPRTOInfo is the size of an SCTPRTOInfo struct.
+const SizeOfSCTPRTOInfo = 16
+
+// SCTPInitMsg is struct sctp_initmsg, from uapi/linux/sctp.h.
+//
+// +marshal
+type SCTPInitMsg struct {
+	NumOStreams  uint16
+	MaxInStreams uint16
+	MaxAttempts  uint16
+	MaxInitTimeo uint16
+}
+
+// SizeOfSCTPInitMsg is the size of an SCTPInitMsg struct.
+const SizeOfSCTPInitMsg = 8
+
+// SCTPSndInfo is struct sctp_sndinfo, from uapi/linux/sctp.h.
+//
+// +marshal
+type SCTPSndInfo struct {
+	SID     uint16
+	Flags   uint16
+	PPID    uint32
+	Context uint32
+	AssocID int32
+}
+
+// SizeOfSCTPSndInfo is the size of an SCTPSndInfo struct.
+const SizeOfSCTPSndInfo = 16
+
+// SCTPSndRcvInfo is struct sctp_sndrcvinfo, from uapi/linux/sctp.h.
+//
+// +marshal
+type SCTPSndRcvInfo struct {
+	Stream     uint16
+	SSN        uint16
+	Flags      uint16
+	_          uint16
+	PPID       uint32
+	Context    uint32
+	TimeToLive uint32
+	TSN        uint32
+	CumTSN     uint32
+	AssocID    int32
+}
+
+// SizeOfSCTPSndRcvInfo is the size of an SCTPSndRcvInfo struct.
+const SizeOfSCTPSndRcvInfo = 32
+
+// SCTPAssocValue is struct sctp_assoc_value, from uapi/linux/sctp.h.
+//
+// +marshal
+type SCTPAssocValue struct {
+	AssocID int32
+	Value   uint32
+}
+
+// SizeOfSCTPAssocValue is the size of an SCTPAssocValue struct.
+const SizeOfSCTPAssocValue = 8
+
+// SCTPPAddrInfo is struct sctp_paddrinfo, from uapi/linux/sctp.h. The kernel
+// declares it packed with 4-byte alignment, which matches the layout here.
+//
+// +marshal
+type SCTPPAddrInfo struct {
+	AssocID int32
+	Address [SockAddrMax]byte
+	State   int32
+	CWnd    uint32
+	SRTT    uint32
+	RTO     uint32
+	MTU     uint32
+}
+
+// SCTPStatus is struct sctp_status, from uapi/linux/sctp.h.
+//
+// +marshal
+type SCTPStatus struct {
+	AssocID            int32
+	State              int32
+	RWnd               uint32
+	UnackData          uint16
+	PendData           uint16
+	InStrms            uint16
+	OutStrms           uint16
+	FragmentationPoint uint32
+	Primary            SCTPPAddrInfo
+}
+
+// SizeOfSCTPStatus is the size of an SCTPStatus struct.
+const SizeOfSCTPStatus = 176
@@ -461,6 +461,7 @@ func (s *SockAddrInet6) implementsSockAddr()   {}
 func (s *SockAddrLink) implementsSockAddr()    {}
 func (s *SockAddrUnix) implementsSockAddr()    {}
 func (s *SockAddrNetlink) implementsSockAddr() {}
+func (s *SockAddrVM) implementsSockAddr()      {}
 
 // Linger is struct linger, from include/linux/socket.h.
 //
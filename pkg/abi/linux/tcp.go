@@ -54,6 +54,42 @@ const (
 	TCP_TX_DELAY             = 37
 )
 
+// Values for TCP_REPAIR, from uapi/linux/tcp.h.
+const (
+	TCP_REPAIR_ON        = 1
+	TCP_REPAIR_OFF       = 0
+	TCP_REPAIR_OFF_NO_WP = -1
+)
+
+// Queues selected by TCP_REPAIR_QUEUE, from uapi/linux/tcp.h.
+const (
+	TCP_NO_QUEUE   = 0
+	TCP_RECV_QUEUE = 1
+	TCP_SEND_QUEUE = 2
+)
+
+// Option codes for TCP_REPAIR_OPTIONS, from include/net/tcp.h.
+const (
+	TCPOPT_MSS       = 2
+	TCPOPT_WINDOW    = 3
+	TCPOPT_SACK_PERM = 4
+	TCPOPT_TIMESTAMP = 8
+)
+
+// Flags for tcp_info.tcpi_options, from uapi/linux/tcp.h.
+const (
+	TCPI_OPT_TIMESTAMPS = 1
+	TCPI_OPT_SACK       = 2
+	TCPI_OPT_WSCALE     = 4
+	TCPI_OPT_ECN        = 8
+)
+
+// SizeOfTCPRepairOpt is the size of struct tcp_repair_opt.
+const SizeOfTCPRepairOpt = 8
+
+// SizeOfTCPRepairWindow is the size of struct tcp_repair_window.
+const SizeOfTCPRepairWindow = 20
+
 // Socket constants from include/net/tcp.h.
 const (
 	MAX_TCP_KEEPIDLE  = 32767
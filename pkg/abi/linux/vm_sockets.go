@@ -0,0 +1,66 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// Well-known context IDs, from uapi/linux/vm_sockets.h.
+const (
+	VMADDR_CID_ANY        = 0xFFFFFFFF
+	VMADDR_CID_HYPERVISOR = 0
+	VMADDR_CID_LOCAL      = 1
+	VMADDR_CID_HOST       = 2
+)
+
+// Special ports, from uapi/linux/vm_sockets.h.
+const (
+	VMADDR_PORT_ANY = 0xFFFFFFFF
+
+	// LAST_RESERVED_PORT is the last port that requires
+	// CAP_NET_BIND_SERVICE to bind, from include/net/af_vsock.h.
+	LAST_RESERVED_PORT = 1023
+)
+
+// Socket options for level AF_VSOCK, from uapi/linux/vm_sockets.h.
+const (
+	SO_VM_SOCKETS_BUFFER_SIZE         = 0
+	SO_VM_SOCKETS_BUFFER_MIN_SIZE     = 1
+	SO_VM_SOCKETS_BUFFER_MAX_SIZE     = 2
+	SO_VM_SOCKETS_PEER_HOST_VM_ID     = 3
+	SO_VM_SOCKETS_TRUSTED             = 5
+	SO_VM_SOCKETS_CONNECT_TIMEOUT     = 6
+	SO_VM_SOCKETS_NONBLOCK_TXRX       = 7
+	SO_VM_SOCKETS_CONNECT_TIMEOUT_NEW = 8
+)
+
+// Default buffer sizes, from include/net/af_vsock.h.
+const (
+	VSOCK_DEFAULT_BUFFER_SIZE     = 1024 * 256
+	VSOCK_DEFAULT_BUFFER_MAX_SIZE = 1024 * 256
+	VSOCK_DEFAULT_BUFFER_MIN_SIZE = 128
+)
+
+// SockAddrVM is struct sockaddr_vm, from uapi/linux/vm_sockets.h.
+//
+// +marshal
+type SockAddrVM struct {
+	Family uint16
+	_      uint16
+	Port   uint32
+	CID    uint32
+	Flags  uint8
+	_      [3]uint8
+}
+
+// SockAddrVMSize is the size of SockAddrVM.
+const SockAddrVMSize = 16
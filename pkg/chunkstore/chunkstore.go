@@ -0,0 +1,414 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package chunkstore provides a content-addressed store of memory pages,
+// shared by checkpoints to store each distinct page once.
+//
+// A Store runs outside of the sandbox being checkpointed, which sends it pages
+// over a connection served by Store.Serve and gets back their locations. The
+// store hashes and deduplicates pages itself, and only ever appends to its
+// files, so a sandbox can't modify pages that other checkpoints refer to.
+// Restored sandboxes read pages from the pack file directly, verifying them
+// against the hashes recorded in their checkpoint.
+//
+// Pages are appended to a pack file, whose offsets are recorded in an index
+// file mapping hashes to offsets. The index consists of fixed-size entries,
+// each holding a hash followed by the little-endian pack file offset and
+// length of the page.
+//
+// If a Store compresses pages, each page is compressed independently, and
+// stored uncompressed if it doesn't compress, so that pages remain
+// deduplicated by their uncompressed contents. Pages are then not page-aligned
+// in the pack file. All users of a store must use equivalent codecs.
+//
+// Pages are never removed from a store, since it doesn't know which
+// checkpoints refer to them: the store grows with each distinct page saved to
+// it, and lives as long as the longest-lived checkpoint saved to it. Every
+// checkpoint saved to a store can read all pages in it, so a store should
+// only be shared by checkpoints of mutually trusting sandboxes.
+package chunkstore
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/compressio"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+)
+
+const (
+	// indexEntrySize is the size of an entry in a Store's index.
+	indexEntrySize = sha256.Size + 8 + 4
+
+	// bufSize is the size of Store.buf.
+	bufSize = 4 << 20
+)
+
+// Loc is the location of a page in a Store's pack file.
+type Loc struct {
+	// Off and Len are the offset and length of the page in the pack file.
+	Off uint64
+	Len uint32
+
+	// Sum is the SHA-256 hash of the page's uncompressed contents.
+	Sum [sha256.Size]byte
+
+	// Added is true if the page was added to the store, rather than
+	// already present in it.
+	Added bool
+}
+
+// A Store stores pages addressed by their SHA-256 hash.
+//
+// Store is not safe for concurrent use, including by multiple processes;
+// callers must serialize users of a store, e.g. by locking its index file.
+type Store struct {
+	pack  *os.File
+	index *os.File
+
+	// If codec is not nil, pages are compressed with codec. scratch holds
+	// compressed pages.
+	codec   compressio.Codec
+	scratch []byte
+
+	// packSize is the size of pack, including pages in buf. Pages in buf
+	// start at packSize - len(buf).
+	packSize uint64
+
+	// locs maps the hashes of pages in the store to their locations in
+	// pack.
+	locs map[[sha256.Size]byte]Loc
+
+	// buf holds new pages not yet written to pack.
+	buf []byte
+
+	// entries holds index entries for pages in buf and in pack that haven't
+	// been written to index.
+	entries []byte
+}
+
+// New returns a Store storing pages in pack, indexed by index, and
+// compressing them with codec if it is not nil. Empty files are an empty
+// store. Both files must be open for reading and appending (O_APPEND). The
+// returned Store borrows pack and index.
+func New(pack, index *os.File, codec compressio.Codec) (*Store, error) {
+	packInfo, err := pack.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat chunk store pack file: %w", err)
+	}
+	indexInfo, err := index.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat chunk store index: %w", err)
+	}
+	s := &Store{
+		pack:     pack,
+		packSize: uint64(packInfo.Size()),
+		index:    index,
+		codec:    codec,
+		locs:     make(map[[sha256.Size]byte]Loc),
+		buf:      make([]byte, 0, bufSize),
+	}
+	// An interrupted save may have left a partial index entry and, if pages
+	// aren't compressed, a partial page. Neither is referred to by any
+	// checkpoint, so drop them to keep entries and pages aligned.
+	indexSize := indexInfo.Size() - indexInfo.Size()%indexEntrySize
+	if indexSize != indexInfo.Size() {
+		if err := index.Truncate(indexSize); err != nil {
+			return nil, fmt.Errorf("failed to truncate partial chunk store index entry: %w", err)
+		}
+	}
+	if codec == nil && s.packSize%hostarch.PageSize != 0 {
+		s.packSize -= s.packSize % hostarch.PageSize
+		if err := pack.Truncate(int64(s.packSize)); err != nil {
+			return nil, fmt.Errorf("failed to truncate partial chunk store page: %w", err)
+		}
+	}
+	var entry [indexEntrySize]byte
+	for off := int64(0); off < indexSize; off += indexEntrySize {
+		if _, err := index.ReadAt(entry[:], off); err != nil {
+			return nil, fmt.Errorf("failed to read chunk store index: %w", err)
+		}
+		var loc Loc
+		copy(loc.Sum[:], entry[:sha256.Size])
+		loc.Off = binary.LittleEndian.Uint64(entry[sha256.Size:])
+		loc.Len = binary.LittleEndian.Uint32(entry[sha256.Size+8:])
+		if loc.Len == 0 || loc.Len > hostarch.PageSize || loc.Off > s.packSize || uint64(loc.Len) > s.packSize-loc.Off || (codec == nil && (loc.Off%hostarch.PageSize != 0 || loc.Len != hostarch.PageSize)) {
+			return nil, fmt.Errorf("chunk store index entry %d has invalid pack file location %+v", off/indexEntrySize, loc)
+		}
+		s.locs[loc.Sum] = loc
+	}
+	log.Infof("Opened chunk store with %d pages, %d bytes", len(s.locs), s.packSize)
+	return s, nil
+}
+
+// Add returns the location in the store of a page with the contents of pg,
+// adding it to the store if necessary. Added pages are only recorded in the
+// index by Commit.
+func (s *Store) Add(pg []byte) (Loc, error) {
+	if len(pg) != hostarch.PageSize {
+		return Loc{}, fmt.Errorf("invalid page length %d", len(pg))
+	}
+	sum := sha256.Sum256(pg)
+	if loc, ok := s.locs[sum]; ok {
+		loc.Added = false
+		return loc, nil
+	}
+	data := pg
+	if s.codec != nil {
+		out, err := s.codec.Compress(s.scratch[:0], pg)
+		if err != nil {
+			return Loc{}, fmt.Errorf("failed to compress page: %w", err)
+		}
+		s.scratch = out
+		if len(out) < len(pg) {
+			data = out
+		}
+	}
+	if len(s.buf)+len(data) > cap(s.buf) {
+		if err := s.flushPages(); err != nil {
+			return Loc{}, err
+		}
+	}
+	loc := Loc{
+		Off:   s.packSize,
+		Len:   uint32(len(data)),
+		Sum:   sum,
+		Added: true,
+	}
+	s.buf = append(s.buf, data...)
+	s.packSize += uint64(len(data))
+	s.locs[sum] = loc
+	s.entries = append(s.entries, sum[:]...)
+	s.entries = binary.LittleEndian.AppendUint64(s.entries, loc.Off)
+	s.entries = binary.LittleEndian.AppendUint32(s.entries, loc.Len)
+	return loc, nil
+}
+
+// flushPages appends the pages in s.buf to s.pack.
+func (s *Store) flushPages() error {
+	if _, err := s.pack.Write(s.buf); err != nil {
+		return fmt.Errorf("failed to write chunk store pack file: %w", err)
+	}
+	s.buf = s.buf[:0]
+	return nil
+}
+
+// Commit makes all added pages durable, then records them in the index.
+// Since entries are written after the pages they refer to, an interrupted
+// Commit never leaves entries referring to missing pages.
+func (s *Store) Commit() error {
+	if err := s.flushPages(); err != nil {
+		return err
+	}
+	if err := s.pack.Sync(); err != nil {
+		return fmt.Errorf("failed to sync chunk store pack file: %w", err)
+	}
+	if _, err := s.index.Write(s.entries); err != nil {
+		return fmt.Errorf("failed to write chunk store index: %w", err)
+	}
+	if err := s.index.Sync(); err != nil {
+		return fmt.Errorf("failed to sync chunk store index: %w", err)
+	}
+	s.entries = s.entries[:0]
+	return nil
+}
+
+// The protocol spoken over connections served by Serve consists of requests
+// made by a Client, each starting with a requestHeader, and replies:
+//
+//   - opAdd is followed by count pages. The reply holds the location of each
+//     page, encoded by appendLoc.
+//
+//   - opCommit has a count of 0. The reply is a single byte, sent once added
+//     pages have been committed.
+const (
+	opAdd uint32 = iota + 1
+	opCommit
+
+	// requestHeaderSize is the size of a request header, which holds the
+	// little-endian op and count.
+	requestHeaderSize = 8
+
+	// locSize is the size of an encoded Loc.
+	locSize = 8 + 4 + sha256.Size + 1
+
+	// maxBatchPages is the maximum number of pages in an opAdd request.
+	maxBatchPages = 256
+)
+
+// appendLoc appends the encoding of loc to b.
+func appendLoc(b []byte, loc Loc) []byte {
+	b = binary.LittleEndian.AppendUint64(b, loc.Off)
+	b = binary.LittleEndian.AppendUint32(b, loc.Len)
+	b = append(b, loc.Sum[:]...)
+	if loc.Added {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+// decodeLoc decodes a Loc encoded by appendLoc.
+func decodeLoc(b []byte) Loc {
+	loc := Loc{
+		Off:   binary.LittleEndian.Uint64(b),
+		Len:   binary.LittleEndian.Uint32(b[8:]),
+		Added: b[12+sha256.Size] != 0,
+	}
+	copy(loc.Sum[:], b[12:])
+	return loc
+}
+
+// Serve serves requests from a Client on conn until conn is closed. Pages
+// added after the last commit are left in the pack file, but aren't recorded
+// in the index.
+func (s *Store) Serve(conn io.ReadWriter) error {
+	var hdr [requestHeaderSize]byte
+	pages := make([]byte, maxBatchPages*hostarch.PageSize)
+	reply := make([]byte, 0, maxBatchPages*locSize)
+	for {
+		if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read chunk store request: %w", err)
+		}
+		op := binary.LittleEndian.Uint32(hdr[:])
+		count := binary.LittleEndian.Uint32(hdr[4:])
+		switch {
+		case op == opAdd && count > 0 && count <= maxBatchPages:
+			batch := pages[:count*hostarch.PageSize]
+			if _, err := io.ReadFull(conn, batch); err != nil {
+				return fmt.Errorf("failed to read chunk store pages: %w", err)
+			}
+			reply = reply[:0]
+			for off := 0; off < len(batch); off += hostarch.PageSize {
+				loc, err := s.Add(batch[off : off+hostarch.PageSize])
+				if err != nil {
+					return err
+				}
+				reply = appendLoc(reply, loc)
+			}
+			if _, err := conn.Write(reply); err != nil {
+				return fmt.Errorf("failed to write chunk store reply: %w", err)
+			}
+		case op == opCommit && count == 0:
+			if err := s.Commit(); err != nil {
+				return err
+			}
+			if _, err := conn.Write([]byte{0}); err != nil {
+				return fmt.Errorf("failed to write chunk store reply: %w", err)
+			}
+		default:
+			return fmt.Errorf("invalid chunk store request: op %d, count %d", op, count)
+		}
+	}
+}
+
+// Client adds pages to a Store through a connection served by Store.Serve.
+type Client struct {
+	conn io.ReadWriter
+
+	// batch holds the request header and pages of the next opAdd request.
+	batch []byte
+
+	// locs holds the locations of pages sent since the last commit.
+	locs []Loc
+}
+
+// NewClient returns a Client using conn.
+func NewClient(conn io.ReadWriter) *Client {
+	return &Client{
+		conn:  conn,
+		batch: make([]byte, requestHeaderSize, requestHeaderSize+maxBatchPages*hostarch.PageSize),
+	}
+}
+
+// Add queues pg to be added to the store.
+func (c *Client) Add(pg []byte) error {
+	if len(pg) != hostarch.PageSize {
+		return fmt.Errorf("invalid page length %d", len(pg))
+	}
+	c.batch = append(c.batch, pg...)
+	if len(c.batch) == cap(c.batch) {
+		return c.send()
+	}
+	return nil
+}
+
+// send sends queued pages and receives their locations.
+func (c *Client) send() error {
+	count := (len(c.batch) - requestHeaderSize) / hostarch.PageSize
+	if count == 0 {
+		return nil
+	}
+	binary.LittleEndian.PutUint32(c.batch[0:], opAdd)
+	binary.LittleEndian.PutUint32(c.batch[4:], uint32(count))
+	if _, err := c.conn.Write(c.batch); err != nil {
+		return fmt.Errorf("failed to send pages to chunk store: %w", err)
+	}
+	c.batch = c.batch[:requestHeaderSize]
+	reply := make([]byte, count*locSize)
+	if _, err := io.ReadFull(c.conn, reply); err != nil {
+		return fmt.Errorf("failed to receive page locations from chunk store: %w", err)
+	}
+	for off := 0; off < len(reply); off += locSize {
+		c.locs = append(c.locs, decodeLoc(reply[off:]))
+	}
+	return nil
+}
+
+// Commit adds all queued pages to the store and returns the locations of all
+// pages passed to Add since the last call to Commit, in order.
+func (c *Client) Commit() ([]Loc, error) {
+	if err := c.send(); err != nil {
+		return nil, err
+	}
+	var req [requestHeaderSize]byte
+	binary.LittleEndian.PutUint32(req[0:], opCommit)
+	if _, err := c.conn.Write(req[:]); err != nil {
+		return nil, fmt.Errorf("failed to commit pages to chunk store: %w", err)
+	}
+	var ack [1]byte
+	if _, err := io.ReadFull(c.conn, ack[:]); err != nil {
+		return nil, fmt.Errorf("failed to commit pages to chunk store: %w", err)
+	}
+	locs := c.locs
+	c.locs = nil
+	return locs, nil
+}
+
+// OpenFiles opens the pack and index files at the given paths for New,
+// creating them if necessary, and locks the index so that other processes
+// using OpenFiles wait until it is closed.
+func OpenFiles(packPath, indexPath string) (pack, index *os.File, err error) {
+	index, err = os.OpenFile(indexPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening chunk store index %q: %w", indexPath, err)
+	}
+	if err := unix.Flock(int(index.Fd()), unix.LOCK_EX); err != nil {
+		_ = index.Close()
+		return nil, nil, fmt.Errorf("locking chunk store index %q: %w", indexPath, err)
+	}
+	pack, err = os.OpenFile(packPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		_ = index.Close()
+		return nil, nil, fmt.Errorf("opening chunk store pack file %q: %w", packPath, err)
+	}
+	return pack, index, nil
+}
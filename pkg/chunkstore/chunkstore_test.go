@@ -0,0 +1,227 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chunkstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/compressio"
+	"gvisor.dev/gvisor/pkg/hostarch"
+)
+
+// openStore opens the store in dir, closing its files when the test ends.
+func openStore(t *testing.T, dir string, codec compressio.Codec) *Store {
+	t.Helper()
+	pack, index, err := OpenFiles(filepath.Join(dir, "pack"), filepath.Join(dir, "index"))
+	if err != nil {
+		t.Fatalf("OpenFiles failed: %v", err)
+	}
+	t.Cleanup(func() {
+		pack.Close()
+		index.Close()
+	})
+	s, err := New(pack, index, codec)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return s
+}
+
+// closeStore closes the files of s, unlocking its store so that it can be
+// reopened.
+func closeStore(s *Store) {
+	s.pack.Close()
+	s.index.Close()
+}
+
+// page returns a page filled with b.
+func page(b byte) []byte {
+	return bytes.Repeat([]byte{b}, hostarch.PageSize)
+}
+
+// fileSize returns the size of the file at path.
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	return info.Size()
+}
+
+func TestStoreDeduplicates(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, nil /* codec */)
+	first, err := s.Add(page(1))
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if !first.Added || first.Off != 0 || first.Len != hostarch.PageSize || first.Sum != sha256.Sum256(page(1)) {
+		t.Errorf("Add returned %+v, want added page at offset 0", first)
+	}
+	if loc, err := s.Add(page(1)); err != nil || loc.Added || loc.Off != first.Off {
+		t.Errorf("Add of duplicate page returned (%+v, %v), want existing page at offset %d", loc, err, first.Off)
+	}
+	if err := s.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	// Pages committed to the store are found by later users.
+	closeStore(s)
+	s = openStore(t, dir, nil /* codec */)
+	if loc, err := s.Add(page(1)); err != nil || loc.Added || loc.Off != first.Off {
+		t.Errorf("Add of committed page returned (%+v, %v), want existing page at offset %d", loc, err, first.Off)
+	}
+	if loc, err := s.Add(page(2)); err != nil || !loc.Added || loc.Off != hostarch.PageSize {
+		t.Errorf("Add of new page returned (%+v, %v), want added page at offset %d", loc, err, hostarch.PageSize)
+	}
+}
+
+func TestStoreUncommittedPages(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, nil /* codec */)
+	if _, err := s.Add(page(1)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := s.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if _, err := s.Add(page(2)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := s.flushPages(); err != nil {
+		t.Fatalf("flushPages failed: %v", err)
+	}
+	// Simulate an interrupted save that left part of a page and an index
+	// entry behind.
+	for _, name := range []string{"pack", "index"} {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		if _, err := f.Write([]byte{1, 2, 3}); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		f.Close()
+	}
+
+	closeStore(s)
+	s = openStore(t, dir, nil /* codec */)
+	if got, want := fileSize(t, filepath.Join(dir, "pack")), int64(2*hostarch.PageSize); got != want {
+		t.Errorf("pack file holds %d bytes, want %d", got, want)
+	}
+	if got, want := fileSize(t, filepath.Join(dir, "index")), int64(indexEntrySize); got != want {
+		t.Errorf("index holds %d bytes, want %d", got, want)
+	}
+	// The uncommitted page is added again, after it.
+	if loc, err := s.Add(page(2)); err != nil || !loc.Added || loc.Off != 2*hostarch.PageSize {
+		t.Errorf("Add of uncommitted page returned (%+v, %v), want added page at offset %d", loc, err, 2*hostarch.PageSize)
+	}
+}
+
+func TestStoreCompresses(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, compressio.NewLZ4Codec())
+	loc, err := s.Add(page(1))
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if loc.Len >= hostarch.PageSize {
+		t.Errorf("Add stored compressible page in %d bytes", loc.Len)
+	}
+	if err := s.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if got := fileSize(t, filepath.Join(dir, "pack")); got != int64(loc.Len) {
+		t.Errorf("pack file holds %d bytes, want %d", got, loc.Len)
+	}
+}
+
+func TestServe(t *testing.T) {
+	s := openStore(t, t.TempDir(), nil /* codec */)
+	sconn, cconn := net.Pipe()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(sconn)
+	}()
+
+	// Add more pages than fit in one request, with duplicates.
+	const numPages = maxBatchPages + 10
+	c := NewClient(cconn)
+	for i := 0; i < numPages; i++ {
+		if err := c.Add(page(byte(i % 200))); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	locs, err := c.Commit()
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if len(locs) != numPages {
+		t.Fatalf("Commit returned %d locations, want %d", len(locs), numPages)
+	}
+	for i, loc := range locs {
+		want := Loc{
+			Off:   uint64(i%200) * hostarch.PageSize,
+			Len:   hostarch.PageSize,
+			Sum:   sha256.Sum256(page(byte(i % 200))),
+			Added: i < 200,
+		}
+		if loc != want {
+			t.Errorf("page %d: got location %+v, want %+v", i, loc, want)
+		}
+	}
+	cconn.Close()
+	if err := <-serveErr; err != nil {
+		t.Errorf("Serve failed: %v", err)
+	}
+}
+
+func TestServeInvalidRequest(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		op    uint32
+		count uint32
+	}{
+		{name: "unknown_op", op: 100},
+		{name: "empty_add", op: opAdd},
+		{name: "oversized_add", op: opAdd, count: maxBatchPages + 1},
+		{name: "commit_with_pages", op: opCommit, count: 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := openStore(t, t.TempDir(), nil /* codec */)
+			sconn, cconn := net.Pipe()
+			defer cconn.Close()
+			serveErr := make(chan error, 1)
+			go func() {
+				serveErr <- s.Serve(sconn)
+			}()
+			var req [requestHeaderSize]byte
+			binary.LittleEndian.PutUint32(req[0:], test.op)
+			binary.LittleEndian.PutUint32(req[4:], test.count)
+			if _, err := cconn.Write(req[:]); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			if err := <-serveErr; err == nil {
+				t.Errorf("Serve succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compressio
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec compresses and decompresses independent blocks of data. Codecs must be
+// safe for concurrent use.
+type Codec interface {
+	// Compress appends the compressed form of src to dst and returns the
+	// updated slice.
+	Compress(dst, src []byte) ([]byte, error)
+
+	// Decompress appends the decompressed form of src to dst and returns
+	// the updated slice. It fails if src decompresses to more than maxSize
+	// bytes.
+	Decompress(dst, src []byte, maxSize int) ([]byte, error)
+}
+
+// ErrCorrupt is returned by Codec.Decompress when compressed data is invalid.
+var ErrCorrupt = errors.New("corrupt compressed data")
+
+// zstdCodec implements Codec using Zstandard frames.
+type zstdCodec struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+// NewZstdCodec returns a Codec compressing data with Zstandard at the given
+// level, as defined by the reference implementation. Level 0 selects the
+// default level.
+func NewZstdCodec(level int) (Codec, error) {
+	encLevel := zstd.SpeedDefault
+	if level != 0 {
+		encLevel = zstd.EncoderLevelFromZstd(level)
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(encLevel), zstd.WithEncoderConcurrency(runtime.GOMAXPROCS(0)))
+	if err != nil {
+		return nil, fmt.Errorf("creating zstd encoder: %w", err)
+	}
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(runtime.GOMAXPROCS(0)))
+	if err != nil {
+		return nil, fmt.Errorf("creating zstd decoder: %w", err)
+	}
+	return &zstdCodec{enc: enc, dec: dec}, nil
+}
+
+// Compress implements Codec.Compress.
+func (c *zstdCodec) Compress(dst, src []byte) ([]byte, error) {
+	return c.enc.EncodeAll(src, dst), nil
+}
+
+// Decompress implements Codec.Decompress.
+func (c *zstdCodec) Decompress(dst, src []byte, maxSize int) ([]byte, error) {
+	n := len(dst)
+	out, err := c.dec.DecodeAll(src, dst)
+	if err != nil {
+		return dst, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	if len(out)-n > maxSize {
+		return dst, ErrCorrupt
+	}
+	return out, nil
+}
+
+// lz4Codec implements Codec using LZ4 blocks.
+type lz4Codec struct{}
+
+// NewLZ4Codec returns a Codec compressing data with LZ4.
+func NewLZ4Codec() Codec {
+	return lz4Codec{}
+}
+
+// Compress implements Codec.Compress.
+func (lz4Codec) Compress(dst, src []byte) ([]byte, error) {
+	return lz4Compress(dst, src), nil
+}
+
+// Decompress implements Codec.Decompress.
+func (lz4Codec) Decompress(dst, src []byte, maxSize int) ([]byte, error) {
+	return lz4Decompress(dst, src, maxSize)
+}
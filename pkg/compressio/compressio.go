@@ -42,6 +42,10 @@
 //
 // so the stream integrity cannot be compromised by switching and mixing
 // compressed chunks.
+//
+// Chunks are compressed with flate by default, or with a Codec (see
+// NewCodecWriter). The stream doesn't record how chunks are compressed, so
+// readers must be created with the same Codec as the writer.
 package compressio
 
 import (
@@ -132,13 +136,17 @@ type worker struct {
 	input    chan *chunk
 	output   chan result
 
+	// chunkSize bounds the size of decompressed chunks.
+	chunkSize int
+
 	// scratch is a temporary buffer used for marshalling. This is declared
 	// unfront here to avoid reallocation.
 	scratch [4]byte
 }
 
-// work is the main work routine; see worker.
-func (w *worker) work(compress bool, level int) {
+// work is the main work routine; see worker. If codec is nil, chunks are
+// compressed with flate at the given level.
+func (w *worker) work(compress bool, level int, codec Codec) {
 	defer close(w.output)
 
 	var h hash.Hash
@@ -147,7 +155,23 @@ func (w *worker) work(compress bool, level int) {
 		if h == nil && w.hashPool != nil {
 			h = w.hashPool.getHash()
 		}
-		if compress {
+		if compress && codec != nil {
+			out, err := codec.Compress(c.compressed.AvailableBuffer(), c.uncompressed.Bytes())
+			if err != nil {
+				w.output <- result{c, err}
+				continue
+			}
+			c.compressed.Write(out)
+
+			// Write the hash, if enabled.
+			if h != nil {
+				h.Write(c.compressed.Bytes())
+				binary.BigEndian.PutUint32(w.scratch[:], uint32(c.compressed.Len()))
+				h.Write(w.scratch[:4])
+				c.h = h
+				h = nil
+			}
+		} else if compress {
 			mw := io.Writer(c.compressed)
 			if h != nil {
 				mw = io.MultiWriter(mw, h)
@@ -193,6 +217,20 @@ func (w *worker) work(compress bool, level int) {
 				}
 			}
 
+			if codec != nil {
+				// Decode into the spare capacity of the
+				// uncompressed buffer, which is the chunk size
+				// for inline buffers.
+				out, err := codec.Decompress(c.uncompressed.AvailableBuffer(), c.compressed.Bytes(), w.chunkSize)
+				if err != nil {
+					w.output <- result{c, err}
+					continue
+				}
+				c.uncompressed.Write(out)
+				w.output <- result{c, nil}
+				continue
+			}
+
 			// Decode this slice.
 			fr := flate.NewReader(c.compressed)
 
@@ -279,18 +317,19 @@ type pool struct {
 // init initializes the worker pool.
 //
 // This should only be called once.
-func (p *pool) init(key []byte, workers int, compress bool, level int) {
+func (p *pool) init(key []byte, workers int, compress bool, level int, codec Codec) {
 	if key != nil {
 		p.hashPool = &hashPool{key: key}
 	}
 	p.workers = make([]worker, workers)
 	for i := 0; i < len(p.workers); i++ {
 		p.workers[i] = worker{
-			hashPool: p.hashPool,
-			input:    make(chan *chunk, 1),
-			output:   make(chan result, 1),
+			hashPool:  p.hashPool,
+			input:     make(chan *chunk, 1),
+			output:    make(chan result, 1),
+			chunkSize: int(p.chunkSize),
 		}
-		go p.workers[i].work(compress, level) // S/R-SAFE: In save path only.
+		go p.workers[i].work(compress, level, codec) // S/R-SAFE: In save path only.
 	}
 	runtime.SetFinalizer(p, (*pool).stop)
 }
@@ -382,18 +421,25 @@ var _ io.Reader = (*Reader)(nil)
 // hash values computed from the compressed bytes. See package comments for
 // details.
 func NewReader(in io.ReadCloser, key []byte) (*Reader, error) {
+	return NewCodecReader(in, key, nil)
+}
+
+// NewCodecReader returns a new compressed reader for a stream written by a
+// writer returned by NewCodecWriter with the same codec. If codec is nil, it
+// is equivalent to NewReader.
+func NewCodecReader(in io.ReadCloser, key []byte, codec Codec) (*Reader, error) {
 	r := &Reader{
 		in: in,
 	}
 
-	// Use double buffering for read.
-	r.init(key, 2*runtime.GOMAXPROCS(0), false, 0)
-
 	if _, err := io.ReadFull(in, r.scratch[:4]); err != nil {
 		return nil, err
 	}
 	r.chunkSize = binary.BigEndian.Uint32(r.scratch[:4])
 
+	// Use double buffering for read.
+	r.init(key, 2*runtime.GOMAXPROCS(0), false, 0, codec)
+
 	if r.hashPool != nil {
 		h := r.hashPool.getHash()
 		binary.BigEndian.PutUint32(r.scratch[:], r.chunkSize)
@@ -605,6 +651,16 @@ var _ io.Writer = (*Writer)(nil)
 // buffered (in the form of read-ahead, or buffered writes), and is limited to
 // O(chunkSize * [1+GOMAXPROCS]).
 func NewWriter(out io.Writer, key []byte, chunkSize uint32, level int) (*Writer, error) {
+	return newWriter(out, key, chunkSize, level, nil)
+}
+
+// NewCodecWriter returns a new compressed writer that compresses chunks with
+// codec instead of flate. See NewWriter.
+func NewCodecWriter(out io.Writer, key []byte, chunkSize uint32, codec Codec) (*Writer, error) {
+	return newWriter(out, key, chunkSize, 0, codec)
+}
+
+func newWriter(out io.Writer, key []byte, chunkSize uint32, level int, codec Codec) (*Writer, error) {
 	w := &Writer{
 		pool: pool{
 			chunkSize: chunkSize,
@@ -612,7 +668,7 @@ func NewWriter(out io.Writer, key []byte, chunkSize uint32, level int) (*Writer,
 		},
 		out: out,
 	}
-	w.init(key, 1+runtime.GOMAXPROCS(0), true, level)
+	w.init(key, 1+runtime.GOMAXPROCS(0), true, level, codec)
 
 	binary.BigEndian.PutUint32(w.scratch[:], chunkSize)
 	if _, err := w.out.Write(w.scratch[:4]); err != nil {
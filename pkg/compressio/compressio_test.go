@@ -207,6 +207,45 @@ func TestCompress(t *testing.T) {
 	}
 }
 
+func TestCodecCompress(t *testing.T) {
+	zstdCodec, err := NewZstdCodec(0)
+	if err != nil {
+		t.Fatalf("NewZstdCodec failed: %v", err)
+	}
+	codecs := map[string]Codec{
+		"zstd": zstdCodec,
+		"lz4":  NewLZ4Codec(),
+	}
+	data := initTest(t, 1024*1024)
+	for name, codec := range codecs {
+		for _, data := range [][]byte{data[:0], data[:1], data[:16], data} {
+			for _, blockSize := range []uint32{4, 4 * 1024, 64 * 1024} {
+				if blockSize <= 16 && len(data) > 16 {
+					continue
+				}
+				for _, key := range [][]byte{nil, hashKey} {
+					for _, corruptData := range []bool{false, true} {
+						if key == nil && corruptData {
+							continue
+						}
+						doTest(t, testOpts{
+							Name: fmt.Sprintf("codec=%s, len(data)=%d, blockSize=%d, key=%s, corruptData=%v", name, len(data), blockSize, string(key), corruptData),
+							Data: data,
+							NewWriter: func(b *bytes.Buffer) (io.WriteCloser, error) {
+								return NewCodecWriter(b, key, blockSize, codec)
+							},
+							NewReader: func(b *bytes.Buffer) (io.Reader, error) {
+								return NewCodecReader(io.NopCloser(b), key, codec)
+							},
+							CorruptData: corruptData,
+						})
+					}
+				}
+			}
+		}
+	}
+}
+
 const (
 	benchDataSize = 600 * 1024 * 1024
 )
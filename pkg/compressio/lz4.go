@@ -0,0 +1,198 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compressio
+
+import (
+	"encoding/binary"
+
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// This file implements the LZ4 block format, as specified by
+// https://github.com/lz4/lz4/blob/dev/doc/lz4_Block_format.md.
+//
+// A block is a sequence of sequences. Each sequence consists of a token, whose
+// high and low 4 bits hold the number of literals and the match length minus
+// lz4MinMatch respectively, followed by extra literal length bytes, literals,
+// a 2-byte little-endian match offset and extra match length bytes. The last
+// sequence only holds literals.
+const (
+	lz4MinMatch = 4
+
+	// lz4MFLimit is the minimum distance between the start of the last
+	// match and the end of the block.
+	lz4MFLimit = 12
+
+	// lz4LastLiterals is the number of bytes at the end of the block that
+	// must be literals.
+	lz4LastLiterals = 5
+
+	lz4MaxOffset = 1<<16 - 1
+
+	lz4HashLog = 16
+)
+
+// lz4Table maps hashes of 4-byte sequences to one plus their last position in
+// the block being compressed.
+type lz4Table [1 << lz4HashLog]int32
+
+var lz4TablePool = sync.Pool{
+	New: func() any {
+		return new(lz4Table)
+	},
+}
+
+func lz4Hash(v uint32) uint32 {
+	return (v * 2654435761) >> (32 - lz4HashLog)
+}
+
+// lz4Compress appends src compressed as an LZ4 block to dst.
+func lz4Compress(dst, src []byte) []byte {
+	n := len(src)
+	anchor := 0
+	if n > lz4MFLimit {
+		table := lz4TablePool.Get().(*lz4Table)
+		defer lz4TablePool.Put(table)
+		clear(table[:])
+
+		limit := n - lz4MFLimit
+		matchLimit := n - lz4LastLiterals
+		for i := 0; i < limit; {
+			v := binary.LittleEndian.Uint32(src[i:])
+			h := lz4Hash(v)
+			ref := int(table[h]) - 1
+			table[h] = int32(i + 1)
+			if ref < 0 || i-ref > lz4MaxOffset || binary.LittleEndian.Uint32(src[ref:]) != v {
+				// Skip faster through data that doesn't compress.
+				i += 1 + (i-anchor)>>6
+				continue
+			}
+			// Extend the match backwards and forwards.
+			for i > anchor && ref > 0 && src[i-1] == src[ref-1] {
+				i--
+				ref--
+			}
+			end := i + lz4MinMatch
+			for end < matchLimit && src[end] == src[ref+end-i] {
+				end++
+			}
+			dst = lz4AppendSequence(dst, src[anchor:i], i-ref, end-i)
+			i = end
+			anchor = end
+		}
+	}
+	return lz4AppendSequence(dst, src[anchor:], 0, 0)
+}
+
+// lz4AppendSequence appends a sequence to dst. If matchLen is 0, the sequence
+// is the last one of the block.
+func lz4AppendSequence(dst, literals []byte, offset, matchLen int) []byte {
+	litLen := len(literals)
+	token := byte(min(litLen, 15) << 4)
+	if matchLen != 0 {
+		token |= byte(min(matchLen-lz4MinMatch, 15))
+	}
+	dst = append(dst, token)
+	if litLen >= 15 {
+		dst = lz4AppendLength(dst, litLen-15)
+	}
+	dst = append(dst, literals...)
+	if matchLen == 0 {
+		return dst
+	}
+	dst = append(dst, byte(offset), byte(offset>>8))
+	if matchLen-lz4MinMatch >= 15 {
+		dst = lz4AppendLength(dst, matchLen-lz4MinMatch-15)
+	}
+	return dst
+}
+
+func lz4AppendLength(dst []byte, l int) []byte {
+	for ; l >= 255; l -= 255 {
+		dst = append(dst, 255)
+	}
+	return append(dst, byte(l))
+}
+
+// lz4Decompress appends the LZ4 block src, decompressed, to dst. It fails if
+// the block decompresses to more than maxSize bytes.
+func lz4Decompress(dst, src []byte, maxSize int) ([]byte, error) {
+	start := len(dst)
+	i := 0
+	readLength := func(l int) (int, bool) {
+		for {
+			if i >= len(src) {
+				return 0, false
+			}
+			b := src[i]
+			i++
+			l += int(b)
+			if b != 255 {
+				return l, true
+			}
+		}
+	}
+	for {
+		if i >= len(src) {
+			return dst, ErrCorrupt
+		}
+		token := src[i]
+		i++
+		litLen := int(token >> 4)
+		if litLen == 15 {
+			var ok bool
+			if litLen, ok = readLength(litLen); !ok {
+				return dst, ErrCorrupt
+			}
+		}
+		if litLen > len(src)-i || litLen > maxSize-(len(dst)-start) {
+			return dst, ErrCorrupt
+		}
+		dst = append(dst, src[i:i+litLen]...)
+		i += litLen
+		if i == len(src) {
+			return dst, nil
+		}
+
+		if len(src)-i < 2 {
+			return dst, ErrCorrupt
+		}
+		offset := int(binary.LittleEndian.Uint16(src[i:]))
+		i += 2
+		if offset == 0 || offset > len(dst)-start {
+			return dst, ErrCorrupt
+		}
+		matchLen := int(token & 15)
+		if matchLen == 15 {
+			var ok bool
+			if matchLen, ok = readLength(matchLen); !ok {
+				return dst, ErrCorrupt
+			}
+		}
+		matchLen += lz4MinMatch
+		if matchLen > maxSize-(len(dst)-start) {
+			return dst, ErrCorrupt
+		}
+		// The match may overlap the bytes it produces, in which case it
+		// repeats the last offset bytes. Copy the repeated bytes in chunks
+		// whose length is a multiple of offset, doubling each time.
+		pos := len(dst) - offset
+		for matchLen > 0 {
+			n := min(matchLen, len(dst)-pos)
+			dst = append(dst, dst[pos:pos+n]...)
+			matchLen -= n
+		}
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compressio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestLZ4RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	random := make([]byte, 256*1024)
+	rng.Read(random)
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"short", []byte("abc")},
+		{"mflimit", []byte("aaaaaaaaaaaaa")},
+		{"zeroes", make([]byte, 1<<20)},
+		{"random", random},
+		{"repeated", bytes.Repeat([]byte("0123456789abcdef0123"), 10000)},
+		{"mixed", append(append(bytes.Repeat([]byte{'x'}, 300), random[:70000]...), random[:70000]...)},
+		{"text", initTest(t, 512*1024)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prefix := []byte("prefix")
+			compressed := lz4Compress(append([]byte(nil), prefix...), tc.data)
+			if !bytes.HasPrefix(compressed, prefix) {
+				t.Fatalf("lz4Compress overwrote dst")
+			}
+			got, err := lz4Decompress(append([]byte(nil), prefix...), compressed[len(prefix):], len(tc.data))
+			if err != nil {
+				t.Fatalf("lz4Decompress failed: %v", err)
+			}
+			if !bytes.Equal(got[len(prefix):], tc.data) || !bytes.HasPrefix(got, prefix) {
+				t.Errorf("round trip mismatch")
+			}
+			if len(tc.data) > 0 {
+				if _, err := lz4Decompress(nil, compressed[len(prefix):], len(tc.data)-1); err == nil {
+					t.Errorf("lz4Decompress with maxSize too small succeeded")
+				}
+			}
+		})
+	}
+}
+
+func TestLZ4Corrupt(t *testing.T) {
+	data := bytes.Repeat([]byte("gVisor LZ4 "), 1000)
+	compressed := lz4Compress(nil, data)
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 1000; i++ {
+		corrupt := append([]byte(nil), compressed...)
+		corrupt[rng.Intn(len(corrupt))] = byte(rng.Intn(256))
+		corrupt = corrupt[:rng.Intn(len(corrupt)+1)]
+		// Decompressing corrupt data may fail or produce garbage, but
+		// must not panic or exceed maxSize.
+		if got, err := lz4Decompress(nil, corrupt, len(data)); err == nil && len(got) > len(data) {
+			t.Fatalf("lz4Decompress produced %d bytes, more than maxSize %d", len(got), len(data))
+		}
+	}
+}
+
+// lz4ReferenceInputs returns inputs for which lz4ReferenceBlocks holds blocks
+// produced by the reference encoder.
+func lz4ReferenceInputs() map[string][]byte {
+	pattern := make([]byte, 3000)
+	for i := range pattern {
+		pattern[i] = byte((i*i + 7*i) % 251)
+	}
+	return map[string][]byte{
+		"text": bytes.Repeat([]byte("The quick brown fox jumps over the lazy dog. "), 40),
+		"runs": bytes.Join([][]byte{
+			bytes.Repeat([]byte{'a'}, 300),
+			bytes.Repeat([]byte{'b'}, 17),
+			bytes.Repeat([]byte("abc"), 80),
+			make([]byte, 100),
+			[]byte("end"),
+		}, nil),
+		"pattern": bytes.Repeat(pattern, 2),
+	}
+}
+
+// lz4ReferenceBlocks holds hex-encoded blocks compressed from
+// lz4ReferenceInputs() by the reference LZ4 implementation, lz4 v1.9.4, using
+// "lz4 -l" at the given level.
+var lz4ReferenceBlocks = []struct {
+	name  string
+	level string
+	block string
+}{
+	{
+		name:  "text",
+		level: "-1",
+		block: "ff1e54686520717569636b2062726f776e20666f78206a756d7073206f76657220746865206c617a7920646f672e202d00ffffffffffffc950646f672e20",
+	},
+	{
+		name:  "runs",
+		level: "-1",
+		block: "1f610100ff191c6201003f6162630300da1f0001004e500000656e64",
+	},
+	{
+		name:  "pattern",
+		level: "-9",
+		block: "ffec0008121e2c3c4e627890aac6e4092b4f759dc7f3265688bcf22f69a5e328" +
+			"6aaef4418bd72a7acc257bd3328eec51b31c82ea59c538a81f930e8605810484" +
+			"0b8f1aa231bd50e07710a643dd7e21c16811b76413bf7227d9924d0ac485480d" +
+			"cf986330facb9e734a23f9d6b596795e452e1906f0e1d4c9c0b9b4b1b0b1b4b9" +
+			"c0c9d4e1f006192e455e7996b5d6f9234a739ecbfa306398cf0d4885c40a4d92" +
+			"d92772bf1364b71168c1217edd43a61077e050bd31a21a8f0b84048105860e93" +
+			"1fa838c559ea821cb351ec8e32d37b25cc7a2ad78b41f4ae6a28e3a5692ff2bc" +
+			"885626f3c79d754f2b09e4c6aa9078624e3c2c1e120800f5f1efeff1f5fb00ff" +
+			"ffffffffffffffffffb40fb80bffffffffffffffffffffffab50aa9078624e",
+	},
+}
+
+// TestLZ4ReferenceBlocks checks that blocks produced by the reference encoder
+// decompress correctly.
+func TestLZ4ReferenceBlocks(t *testing.T) {
+	inputs := lz4ReferenceInputs()
+	for _, tc := range lz4ReferenceBlocks {
+		t.Run(tc.name+tc.level, func(t *testing.T) {
+			block, err := hex.DecodeString(tc.block)
+			if err != nil {
+				t.Fatalf("invalid block: %v", err)
+			}
+			want := inputs[tc.name]
+			got, err := lz4Decompress(nil, block, len(want))
+			if err != nil {
+				t.Fatalf("lz4Decompress failed: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("lz4Decompress returned %d bytes that differ from the %d byte input", len(got), len(want))
+			}
+		})
+	}
+}
+
+// lz4LegacyMagic is the magic number of the LZ4 legacy frame format, which
+// holds a sequence of independent blocks, each preceded by its little-endian
+// 32-bit length, that decompress to at most lz4LegacyBlockSize bytes each.
+const (
+	lz4LegacyMagic     = 0x184c2102
+	lz4LegacyBlockSize = 8 << 20
+)
+
+// TestLZ4ReferenceCLI checks that blocks are interchangeable with those of the
+// reference LZ4 implementation, if its command line tool is installed.
+func TestLZ4ReferenceCLI(t *testing.T) {
+	lz4, err := exec.LookPath("lz4")
+	if err != nil {
+		t.Skipf("lz4 not found: %v", err)
+	}
+	rng := rand.New(rand.NewSource(0))
+	random := make([]byte, 256*1024)
+	rng.Read(random)
+	inputs := lz4ReferenceInputs()
+	inputs["random"] = random
+	inputs["zeroes"] = make([]byte, 1<<20)
+	inputs["mixed"] = append(append(bytes.Repeat([]byte{'x'}, 300), random[:70000]...), random[:70000]...)
+	inputs["largetext"] = initTest(t, 512*1024)
+	for name, data := range inputs {
+		t.Run(name, func(t *testing.T) {
+			// Decompress our blocks with the reference implementation.
+			frame := binary.LittleEndian.AppendUint32(nil, lz4LegacyMagic)
+			block := lz4Compress(nil, data)
+			frame = binary.LittleEndian.AppendUint32(frame, uint32(len(block)))
+			frame = append(frame, block...)
+			cmd := exec.Command(lz4, "-d", "-c")
+			cmd.Stdin = bytes.NewReader(frame)
+			got, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("lz4 -d failed: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("lz4 -d returned %d bytes that differ from the %d byte input", len(got), len(data))
+			}
+
+			// Decompress blocks compressed by the reference implementation.
+			for _, level := range []string{"-1", "-9"} {
+				cmd := exec.Command(lz4, level, "-l", "-c")
+				cmd.Stdin = bytes.NewReader(data)
+				frame, err := cmd.Output()
+				if err != nil {
+					t.Fatalf("lz4 %s -l failed: %v", level, err)
+				}
+				if len(frame) < 4 || binary.LittleEndian.Uint32(frame) != lz4LegacyMagic {
+					t.Fatalf("lz4 %s -l returned an invalid frame", level)
+				}
+				frame = frame[4:]
+				var got []byte
+				for len(frame) != 0 {
+					if len(frame) < 4 {
+						t.Fatalf("lz4 %s -l returned a truncated frame", level)
+					}
+					n := binary.LittleEndian.Uint32(frame)
+					frame = frame[4:]
+					if uint64(n) > uint64(len(frame)) {
+						t.Fatalf("lz4 %s -l returned a truncated block", level)
+					}
+					if got, err = lz4Decompress(got, frame[:n], lz4LegacyBlockSize); err != nil {
+						t.Fatalf("lz4Decompress of block from lz4 %s -l failed: %v", level, err)
+					}
+					frame = frame[n:]
+				}
+				if !bytes.Equal(got, data) {
+					t.Errorf("lz4Decompress of lz4 %s -l output returned %d bytes that differ from the %d byte input", level, len(got), len(data))
+				}
+			}
+		})
+	}
+}
+
+// lz4FuzzSeeds returns seeds for the fuzz tests below.
+func lz4FuzzSeeds() [][]byte {
+	seeds := [][]byte{
+		nil,
+		{0x00},
+		{0xf0},
+		{0x1f, 'a', 0x01, 0x00},
+		{0x1f, 'a', 0x00, 0x00, 0xff},
+		{0x0f, 0x01, 0x00, 0xff, 0xff, 0xff},
+		[]byte(strings.Repeat("gVisor LZ4 ", 100)),
+	}
+	for _, tc := range lz4ReferenceBlocks {
+		block, _ := hex.DecodeString(tc.block)
+		seeds = append(seeds, block)
+	}
+	for _, data := range lz4ReferenceInputs() {
+		seeds = append(seeds, lz4Compress(nil, data))
+	}
+	return seeds
+}
+
+// FuzzLZ4Decompress checks that decompressing arbitrary, possibly malformed,
+// blocks doesn't panic or produce more than maxSize bytes, and that
+// successfully decompressed blocks are recompressed losslessly.
+func FuzzLZ4Decompress(f *testing.F) {
+	for _, seed := range lz4FuzzSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, block []byte) {
+		const maxSize = 64 * 1024
+		prefix := []byte("prefix")
+		got, err := lz4Decompress(append([]byte(nil), prefix...), block, maxSize)
+		if !bytes.HasPrefix(got, prefix) {
+			t.Fatalf("lz4Decompress overwrote dst")
+		}
+		if len(got)-len(prefix) > maxSize {
+			t.Fatalf("lz4Decompress produced %d bytes, more than maxSize %d", len(got)-len(prefix), maxSize)
+		}
+		if err != nil {
+			return
+		}
+		data := got[len(prefix):]
+		roundTrip, err := lz4Decompress(nil, lz4Compress(nil, data), len(data))
+		if err != nil {
+			t.Fatalf("lz4Decompress of recompressed block failed: %v", err)
+		}
+		if !bytes.Equal(roundTrip, data) {
+			t.Fatalf("round trip mismatch")
+		}
+	})
+}
+
+// FuzzLZ4RoundTrip checks that arbitrary data is compressed losslessly, and
+// within the worst-case size bound of the LZ4 block format.
+func FuzzLZ4RoundTrip(f *testing.F) {
+	for _, seed := range lz4FuzzSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		block := lz4Compress(nil, data)
+		if bound := len(data) + len(data)/255 + 16; len(block) > bound {
+			t.Fatalf("lz4Compress produced %d bytes from %d, more than the bound %d", len(block), len(data), bound)
+		}
+		got, err := lz4Decompress(nil, block, len(data))
+		if err != nil {
+			t.Fatalf("lz4Decompress failed: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("round trip mismatch")
+		}
+	})
+}
@@ -0,0 +1,318 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlp implements a minimal OpenTelemetry protocol (OTLP) client that
+// exports logs and metrics to a collector over OTLP/gRPC or OTLP/HTTP, using
+// the binary protobuf encoding in both cases.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	pb "gvisor.dev/gvisor/pkg/otlp/otlp_go_proto"
+)
+
+// Protocols supported by Config.Protocol.
+const (
+	// ProtocolGRPC is OTLP/gRPC, usually served on port 4317.
+	ProtocolGRPC = "grpc"
+	// ProtocolHTTP is OTLP/HTTP with protobuf payloads, usually served on port
+	// 4318.
+	ProtocolHTTP = "http"
+)
+
+// unixPrefix is the prefix of endpoints that refer to a Unix domain socket.
+const unixPrefix = "unix:"
+
+// Service names and HTTP paths from the OTLP specification.
+const (
+	logsMethod    = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+	metricsMethod = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+
+	// LogsPath is the OTLP/HTTP path to export logs.
+	LogsPath = "/v1/logs"
+	// MetricsPath is the OTLP/HTTP path to export metrics.
+	MetricsPath = "/v1/metrics"
+	// TracesPath is the OTLP/HTTP path to export traces.
+	TracesPath = "/v1/traces"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Config configures a Client.
+type Config struct {
+	// Endpoint is the address of the collector. It's either "host:port" or
+	// "unix:<path>" for a Unix domain socket.
+	Endpoint string
+
+	// Protocol is either ProtocolGRPC or ProtocolHTTP.
+	Protocol string
+
+	// Headers are added to every request, e.g. for authentication.
+	Headers map[string]string
+
+	// Timeout limits the duration of each export request. Defaults to 10s.
+	Timeout time.Duration
+
+	// TLS, if set, is used to secure connections to the collector. If its
+	// ServerName is empty, the host of Endpoint is used.
+	TLS *tls.Config
+
+	// Dial, if set, is used to connect to the collector instead of connecting
+	// to Endpoint.
+	Dial func(ctx context.Context) (net.Conn, error)
+}
+
+// Client exports telemetry to an OTLP collector.
+type Client interface {
+	// ExportLogs sends log records to the collector.
+	ExportLogs(ctx context.Context, req *pb.ExportLogsServiceRequest) error
+
+	// ExportMetrics sends metrics to the collector.
+	ExportMetrics(ctx context.Context, req *pb.ExportMetricsServiceRequest) error
+
+	// Close releases resources used by the client.
+	Close() error
+}
+
+// NewClient creates a new Client. The connection to the collector is
+// established lazily.
+func NewClient(cfg Config) (Client, error) {
+	if len(cfg.Endpoint) == 0 && cfg.Dial == nil {
+		return nil, fmt.Errorf("OTLP endpoint not set")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.Dial == nil {
+		cfg.Dial = dialer(cfg.Endpoint)
+	}
+	if cfg.TLS != nil && len(cfg.TLS.ServerName) == 0 {
+		cfg.TLS = cfg.TLS.Clone()
+		cfg.TLS.ServerName = host(cfg.Endpoint)
+		if h, _, err := net.SplitHostPort(cfg.TLS.ServerName); err == nil {
+			cfg.TLS.ServerName = h
+		}
+	}
+	switch cfg.Protocol {
+	case ProtocolGRPC:
+		return newGRPCClient(cfg)
+	case ProtocolHTTP:
+		return newHTTPClient(cfg), nil
+	default:
+		return nil, fmt.Errorf("invalid OTLP protocol %q, must be %q or %q", cfg.Protocol, ProtocolGRPC, ProtocolHTTP)
+	}
+}
+
+func dialer(endpoint string) func(ctx context.Context) (net.Conn, error) {
+	network, addr := "tcp", endpoint
+	if strings.HasPrefix(endpoint, unixPrefix) {
+		network, addr = "unix", strings.TrimPrefix(endpoint, unixPrefix)
+	}
+	return func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+// host returns the host used in requests to endpoint. Since all connections
+// go through the dialer, it only matters to the collector.
+func host(endpoint string) string {
+	if len(endpoint) == 0 || strings.HasPrefix(endpoint, unixPrefix) {
+		return "localhost"
+	}
+	return endpoint
+}
+
+// partialSuccessError returns an error if the collector rejected part of the
+// request.
+func partialSuccessError(rejected int64, msg string) error {
+	if rejected == 0 {
+		return nil
+	}
+	return fmt.Errorf("collector rejected %d items: %s", rejected, msg)
+}
+
+type grpcClient struct {
+	cfg  Config
+	conn *grpc.ClientConn
+}
+
+func newGRPCClient(cfg Config) (*grpcClient, error) {
+	creds := insecure.NewCredentials()
+	if cfg.TLS != nil {
+		creds = credentials.NewTLS(cfg.TLS)
+	}
+	// The target is only used for logging and for the :authority header, all
+	// connections go through the dialer.
+	conn, err := grpc.Dial("passthrough:///"+host(cfg.Endpoint),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return cfg.Dial(ctx)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcClient{cfg: cfg, conn: conn}, nil
+}
+
+func (c *grpcClient) invoke(ctx context.Context, method string, req, resp proto.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	for k, v := range c.cfg.Headers {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+	}
+	return c.conn.Invoke(ctx, method, req, resp)
+}
+
+// ExportLogs implements Client.
+func (c *grpcClient) ExportLogs(ctx context.Context, req *pb.ExportLogsServiceRequest) error {
+	resp := &pb.ExportLogsServiceResponse{}
+	if err := c.invoke(ctx, logsMethod, req, resp); err != nil {
+		return err
+	}
+	return partialSuccessError(resp.GetPartialSuccess().GetRejectedLogRecords(), resp.GetPartialSuccess().GetErrorMessage())
+}
+
+// ExportMetrics implements Client.
+func (c *grpcClient) ExportMetrics(ctx context.Context, req *pb.ExportMetricsServiceRequest) error {
+	resp := &pb.ExportMetricsServiceResponse{}
+	if err := c.invoke(ctx, metricsMethod, req, resp); err != nil {
+		return err
+	}
+	return partialSuccessError(resp.GetPartialSuccess().GetRejectedDataPoints(), resp.GetPartialSuccess().GetErrorMessage())
+}
+
+// Close implements Client.
+func (c *grpcClient) Close() error {
+	return c.conn.Close()
+}
+
+type httpClient struct {
+	cfg    Config
+	client *http.Client
+	// baseURL is the scheme and host used in requests. Like in gRPC, all
+	// connections go through the dialer.
+	baseURL string
+}
+
+func newHTTPClient(cfg Config) *httpClient {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return cfg.Dial(ctx)
+		},
+		MaxIdleConns:    1,
+		IdleConnTimeout: 90 * time.Second,
+	}
+	scheme := "http://"
+	if cfg.TLS != nil {
+		scheme = "https://"
+		transport.DialTLSContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			conn, err := cfg.Dial(ctx)
+			if err != nil {
+				return nil, err
+			}
+			tlsConn := tls.Client(conn, cfg.TLS)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return tlsConn, nil
+		}
+	}
+	return &httpClient{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: transport,
+		},
+		baseURL: scheme + host(cfg.Endpoint),
+	}
+}
+
+func (c *httpClient) post(ctx context.Context, path string, req, resp proto.Message) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range c.cfg.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	httpResp, err := c.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(httpResp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("collector returned %q: %s", httpResp.Status, respBody)
+	}
+	return proto.Unmarshal(respBody, resp)
+}
+
+// ExportLogs implements Client.
+func (c *httpClient) ExportLogs(ctx context.Context, req *pb.ExportLogsServiceRequest) error {
+	resp := &pb.ExportLogsServiceResponse{}
+	if err := c.post(ctx, LogsPath, req, resp); err != nil {
+		return err
+	}
+	return partialSuccessError(resp.GetPartialSuccess().GetRejectedLogRecords(), resp.GetPartialSuccess().GetErrorMessage())
+}
+
+// ExportMetrics implements Client.
+func (c *httpClient) ExportMetrics(ctx context.Context, req *pb.ExportMetricsServiceRequest) error {
+	resp := &pb.ExportMetricsServiceResponse{}
+	if err := c.post(ctx, MetricsPath, req, resp); err != nil {
+		return err
+	}
+	return partialSuccessError(resp.GetPartialSuccess().GetRejectedDataPoints(), resp.GetPartialSuccess().GetErrorMessage())
+}
+
+// Close implements Client.
+func (c *httpClient) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// StringAttr returns a string attribute.
+func StringAttr(key, val string) *pb.KeyValue {
+	return &pb.KeyValue{Key: key, Value: &pb.AnyValue{Value: &pb.AnyValue_StringValue{StringValue: val}}}
+}
+
+// IntAttr returns an integer attribute.
+func IntAttr(key string, val int64) *pb.KeyValue {
+	return &pb.KeyValue{Key: key, Value: &pb.AnyValue{Value: &pb.AnyValue_IntValue{IntValue: val}}}
+}
@@ -0,0 +1,146 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/otlp"
+	pb "gvisor.dev/gvisor/pkg/otlp/otlp_go_proto"
+	"gvisor.dev/gvisor/pkg/otlp/otlptest"
+)
+
+func TestExport(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		protocol string
+		tls      bool
+	}{
+		{name: "grpc", protocol: otlp.ProtocolGRPC},
+		{name: "http", protocol: otlp.ProtocolHTTP},
+		{name: "grpc-tls", protocol: otlp.ProtocolGRPC, tls: true},
+		{name: "http-tls", protocol: otlp.ProtocolHTTP, tls: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			start := otlptest.Start
+			if tc.tls {
+				start = otlptest.StartTLS
+			}
+			collector, err := start(tc.protocol)
+			if err != nil {
+				t.Fatalf("otlptest.Start(): %v", err)
+			}
+			defer collector.Stop()
+
+			cfg := otlp.Config{
+				Endpoint: collector.Endpoint,
+				Protocol: tc.protocol,
+				Headers:  map[string]string{"authorization": "token"},
+			}
+			if tc.tls {
+				roots := x509.NewCertPool()
+				roots.AppendCertsFromPEM(collector.CACert)
+				cfg.TLS = &tls.Config{RootCAs: roots}
+			}
+			client, err := otlp.NewClient(cfg)
+			if err != nil {
+				t.Fatalf("NewClient(): %v", err)
+			}
+			defer client.Close()
+
+			resource := &pb.Resource{Attributes: []*pb.KeyValue{otlp.StringAttr("service.name", "test")}}
+			logs := &pb.ExportLogsServiceRequest{
+				ResourceLogs: []*pb.ResourceLogs{
+					{
+						Resource: resource,
+						ScopeLogs: []*pb.ScopeLogs{
+							{
+								LogRecords: []*pb.LogRecord{
+									{TimeUnixNano: 1, EventName: "first"},
+									{TimeUnixNano: 2, EventName: "second", Attributes: []*pb.KeyValue{otlp.IntAttr("pid", 123)}},
+								},
+							},
+						},
+					},
+				},
+			}
+			if err := client.ExportLogs(context.Background(), logs); err != nil {
+				t.Fatalf("ExportLogs(): %v", err)
+			}
+			metrics := &pb.ExportMetricsServiceRequest{
+				ResourceMetrics: []*pb.ResourceMetrics{
+					{
+						Resource: resource,
+						ScopeMetrics: []*pb.ScopeMetrics{
+							{
+								Metrics: []*pb.Metric{
+									{
+										Name: "count",
+										Unit: "1",
+										Data: &pb.Metric_Sum{Sum: &pb.Sum{
+											DataPoints:             []*pb.NumberDataPoint{{Value: &pb.NumberDataPoint_AsInt{AsInt: 42}}},
+											AggregationTemporality: pb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+											IsMonotonic:            true,
+										}},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			if err := client.ExportMetrics(context.Background(), metrics); err != nil {
+				t.Fatalf("ExportMetrics(): %v", err)
+			}
+
+			records := collector.LogRecords()
+			if len(records) != 2 || records[0].GetEventName() != "first" || records[1].GetEventName() != "second" {
+				t.Errorf("wrong log records: %v", records)
+			}
+			if got := collector.Logs()[0].GetResource().GetAttributes()[0].GetValue().GetStringValue(); got != "test" {
+				t.Errorf("wrong resource, got: %q, want: %q", got, "test")
+			}
+			gotMetrics := collector.Metrics()
+			if len(gotMetrics) != 1 {
+				t.Fatalf("wrong number of metrics: %v", gotMetrics)
+			}
+			if got := gotMetrics[0].GetScopeMetrics()[0].GetMetrics()[0].GetSum().GetDataPoints()[0].GetAsInt(); got != 42 {
+				t.Errorf("wrong metric value, got: %d, want: 42", got)
+			}
+			for _, headers := range collector.Headers() {
+				found := false
+				for k, v := range headers {
+					found = found || (strings.ToLower(k) == "authorization" && len(v) == 1 && v[0] == "token")
+				}
+				if !found {
+					t.Errorf("authorization header not found: %v", headers)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	if _, err := otlp.NewClient(otlp.Config{Protocol: otlp.ProtocolGRPC}); err == nil {
+		t.Errorf("NewClient() without endpoint should fail")
+	}
+	if _, err := otlp.NewClient(otlp.Config{Endpoint: "localhost:4317", Protocol: "udp"}); err == nil {
+		t.Errorf("NewClient() with invalid protocol should fail")
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlptest provides a stand-in for an OpenTelemetry collector that
+// records everything it receives over OTLP/gRPC and OTLP/HTTP, optionally
+// over TLS.
+package otlptest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/otlp"
+	pb "gvisor.dev/gvisor/pkg/otlp/otlp_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// Collector records OTLP requests. It's safe for concurrent use.
+type Collector struct {
+	// Endpoint is the address where the collector is listening.
+	Endpoint string
+
+	// CACert is the PEM-encoded certificate that the collector's certificate
+	// is verified with when it serves TLS. The certificate is valid for
+	// 127.0.0.1 and localhost.
+	CACert []byte
+
+	listener net.Listener
+	grpcSrv  *grpc.Server
+	httpSrv  *http.Server
+
+	mu sync.Mutex
+	// +checklocks:mu
+	logs []*pb.ResourceLogs
+	// +checklocks:mu
+	metrics []*pb.ResourceMetrics
+	// +checklocks:mu
+	spans []*pb.ResourceSpans
+	// +checklocks:mu
+	headers []map[string][]string
+	// changed is closed and replaced every time a request is received.
+	//
+	// +checklocks:mu
+	changed chan struct{}
+}
+
+// Start starts a collector serving protocol, either otlp.ProtocolGRPC or
+// otlp.ProtocolHTTP, on a random localhost port.
+func Start(protocol string) (*Collector, error) {
+	return start(protocol, false /* useTLS */)
+}
+
+// StartTLS is like Start, but the collector serves TLS with a self-signed
+// certificate, available in CACert.
+func StartTLS(protocol string) (*Collector, error) {
+	return start(protocol, true /* useTLS */)
+}
+
+func start(protocol string, useTLS bool) (*Collector, error) {
+	var (
+		tlsConfig *tls.Config
+		caCert    []byte
+	)
+	if useTLS {
+		cert, err := selfSignedCert()
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		caCert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	c := &Collector{
+		Endpoint: listener.Addr().String(),
+		CACert:   caCert,
+		listener: listener,
+		changed:  make(chan struct{}),
+	}
+	switch protocol {
+	case otlp.ProtocolGRPC:
+		var opts []grpc.ServerOption
+		if tlsConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+		c.grpcSrv = grpc.NewServer(opts...)
+		c.grpcSrv.RegisterService(&logsServiceDesc, c)
+		c.grpcSrv.RegisterService(&metricsServiceDesc, c)
+		c.grpcSrv.RegisterService(&traceServiceDesc, c)
+		go c.grpcSrv.Serve(listener)
+	case otlp.ProtocolHTTP:
+		mux := http.NewServeMux()
+		mux.HandleFunc(otlp.LogsPath, c.serveLogs)
+		mux.HandleFunc(otlp.MetricsPath, c.serveMetrics)
+		mux.HandleFunc(otlp.TracesPath, c.serveTraces)
+		c.httpSrv = &http.Server{Handler: mux}
+		if tlsConfig != nil {
+			listener = tls.NewListener(listener, tlsConfig)
+		}
+		go c.httpSrv.Serve(listener)
+	default:
+		listener.Close()
+		return nil, fmt.Errorf("invalid protocol %q", protocol)
+	}
+	return c, nil
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 and localhost that is
+// its own CA.
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "otlptest"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// Stop stops the collector.
+func (c *Collector) Stop() {
+	if c.grpcSrv != nil {
+		c.grpcSrv.Stop()
+	}
+	if c.httpSrv != nil {
+		c.httpSrv.Close()
+	}
+}
+
+// Logs returns all log records received so far with their resource.
+func (c *Collector) Logs() []*pb.ResourceLogs {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*pb.ResourceLogs(nil), c.logs...)
+}
+
+// LogRecords returns all log records received so far.
+func (c *Collector) LogRecords() []*pb.LogRecord {
+	var records []*pb.LogRecord
+	for _, rl := range c.Logs() {
+		for _, sl := range rl.GetScopeLogs() {
+			records = append(records, sl.GetLogRecords()...)
+		}
+	}
+	return records
+}
+
+// Metrics returns all metrics received so far with their resource.
+func (c *Collector) Metrics() []*pb.ResourceMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*pb.ResourceMetrics(nil), c.metrics...)
+}
+
+// Traces returns all spans received so far with their resource.
+func (c *Collector) Traces() []*pb.ResourceSpans {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*pb.ResourceSpans(nil), c.spans...)
+}
+
+// Spans returns all spans received so far.
+func (c *Collector) Spans() []*pb.Span {
+	var spans []*pb.Span
+	for _, rs := range c.Traces() {
+		for _, ss := range rs.GetScopeSpans() {
+			spans = append(spans, ss.GetSpans()...)
+		}
+	}
+	return spans
+}
+
+// Headers returns the headers, or gRPC metadata, of all requests received so
+// far.
+func (c *Collector) Headers() []map[string][]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]map[string][]string(nil), c.headers...)
+}
+
+// WaitFor waits until cond returns true or the timeout expires. cond is
+// checked every time a request is received.
+func (c *Collector) WaitFor(timeout time.Duration, cond func() bool) error {
+	deadline := time.After(timeout)
+	for {
+		c.mu.Lock()
+		changed := c.changed
+		c.mu.Unlock()
+		if cond() {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return fmt.Errorf("timed out after %v", timeout)
+		}
+	}
+}
+
+func (c *Collector) record(headers map[string][]string, logs []*pb.ResourceLogs, metrics []*pb.ResourceMetrics, spans []*pb.ResourceSpans) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.headers = append(c.headers, headers)
+	c.logs = append(c.logs, logs...)
+	c.metrics = append(c.metrics, metrics...)
+	c.spans = append(c.spans, spans...)
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *Collector) serveLogs(w http.ResponseWriter, r *http.Request) {
+	req := &pb.ExportLogsServiceRequest{}
+	if !readRequest(w, r, req) {
+		return
+	}
+	c.record(r.Header, req.GetResourceLogs(), nil, nil)
+	writeResponse(w, &pb.ExportLogsServiceResponse{})
+}
+
+func (c *Collector) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	req := &pb.ExportMetricsServiceRequest{}
+	if !readRequest(w, r, req) {
+		return
+	}
+	c.record(r.Header, nil, req.GetResourceMetrics(), nil)
+	writeResponse(w, &pb.ExportMetricsServiceResponse{})
+}
+
+func (c *Collector) serveTraces(w http.ResponseWriter, r *http.Request) {
+	req := &pb.ExportTraceServiceRequest{}
+	if !readRequest(w, r, req) {
+		return
+	}
+	c.record(r.Header, nil, nil, req.GetResourceSpans())
+	writeResponse(w, &pb.ExportTraceServiceResponse{})
+}
+
+func readRequest(w http.ResponseWriter, r *http.Request, req proto.Message) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return false
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "application/x-protobuf" {
+		http.Error(w, fmt.Sprintf("unsupported content type %q", ct), http.StatusUnsupportedMediaType)
+		return false
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if err := proto.Unmarshal(body, req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeResponse(w http.ResponseWriter, resp proto.Message) {
+	out, err := proto.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(out)
+}
+
+func grpcHeaders(ctx context.Context) map[string][]string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return md
+}
+
+var logsServiceDesc = grpc.ServiceDesc{
+	ServiceName: "opentelemetry.proto.collector.logs.v1.LogsService",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Export",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := &pb.ExportLogsServiceRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				srv.(*Collector).record(grpcHeaders(ctx), req.GetResourceLogs(), nil, nil)
+				return &pb.ExportLogsServiceResponse{}, nil
+			},
+		},
+	},
+}
+
+var metricsServiceDesc = grpc.ServiceDesc{
+	ServiceName: "opentelemetry.proto.collector.metrics.v1.MetricsService",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Export",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := &pb.ExportMetricsServiceRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				srv.(*Collector).record(grpcHeaders(ctx), nil, req.GetResourceMetrics(), nil)
+				return &pb.ExportMetricsServiceResponse{}, nil
+			},
+		},
+	},
+}
+
+var traceServiceDesc = grpc.ServiceDesc{
+	ServiceName: "opentelemetry.proto.collector.trace.v1.TraceService",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Export",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := &pb.ExportTraceServiceRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				srv.(*Collector).record(grpcHeaders(ctx), nil, nil, req.GetResourceSpans())
+				return &pb.ExportTraceServiceResponse{}, nil
+			},
+		},
+	},
+}
@@ -20,6 +20,7 @@ import (
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/strace"
 	"gvisor.dev/gvisor/pkg/tcpip/link/sniffer"
+	"gvisor.dev/gvisor/pkg/urpc"
 )
 
 // LoggingArgs are the arguments to use for changing the logging
@@ -63,6 +64,29 @@ type LoggingArgs struct {
 	// StraceEventAllowlist is the allowlist of syscalls to trace
 	// to event log.
 	StraceEventAllowlist []string
+
+	// SetJSONStrace is a flag used to indicate that JSON strace related
+	// arguments were passed in.
+	SetJSONStrace bool
+
+	// EnableJSONStrace enables strace in JSON format to the file in
+	// FilePayload. If false, JSON strace is disabled.
+	EnableJSONStrace bool
+
+	// StraceJSONAllowlist is the allowlist of syscalls to trace in JSON
+	// format. If empty, all syscalls are traced.
+	StraceJSONAllowlist []string
+
+	// StraceJSONPIDs restricts JSON strace to the given processes. If empty,
+	// all processes are traced.
+	StraceJSONPIDs []int32
+
+	// StraceJSONContainerIDs restricts JSON strace to the given containers. If
+	// empty, all containers are traced.
+	StraceJSONContainerIDs []string
+
+	// FilePayload contains the file to write JSON strace to.
+	urpc.FilePayload
 }
 
 // Logging provides functions related to logging.
@@ -101,6 +125,12 @@ func (l *Logging) Change(args *LoggingArgs, code *int) error {
 		}
 	}
 
+	if args.SetJSONStrace {
+		if err := l.configureJSONStrace(args); err != nil {
+			return fmt.Errorf("error configuring JSON strace: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -133,3 +163,22 @@ func (l *Logging) configureEventStrace(args *LoggingArgs) error {
 	}
 	return nil
 }
+
+func (l *Logging) configureJSONStrace(args *LoggingArgs) error {
+	if !args.EnableJSONStrace {
+		strace.DisableJSON()
+		return nil
+	}
+	if len(args.Files) != 1 {
+		return fmt.Errorf("JSON strace requires exactly one file, got: %d", len(args.Files))
+	}
+	opts := strace.JSONOptions{
+		PIDs:         args.StraceJSONPIDs,
+		ContainerIDs: args.StraceJSONContainerIDs,
+		MaxDataSize:  strace.LogMaximumSize,
+	}
+	// Ownership of the file is transferred to strace.
+	f := args.Files[0]
+	args.Files = nil
+	return strace.EnableJSON(args.StraceJSONAllowlist, f, opts)
+}
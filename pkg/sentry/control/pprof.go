@@ -15,12 +15,14 @@
 package control
 
 import (
+	"fmt"
 	"runtime"
 	"runtime/pprof"
 	"runtime/trace"
 	"time"
 
 	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/perf"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/urpc"
@@ -98,12 +100,18 @@ func (p *Profile) CPU(o *CPUProfileOpts, _ *struct{}) error {
 	output := o.FilePayload.Files[0]
 	defer output.Close()
 
-	p.cpuMu.Lock()
+	// The Go runtime supports a single CPU profile at a time. Refuse
+	// concurrent profiles rather than waiting, since the profile being
+	// collected may be a continuous one that doesn't end.
+	if !p.cpuMu.TryLock() {
+		return fmt.Errorf("a CPU profile of the sandbox is already being collected (e.g. by the metric server's continuous profiling); retry once it is done")
+	}
 	defer p.cpuMu.Unlock()
 
-	// Returns an error if profiling is already started.
+	// Returns an error if profiling was started some other way, e.g. with
+	// --profile-cpu.
 	if err := pprof.StartCPUProfile(output); err != nil {
-		return err
+		return fmt.Errorf("starting CPU profile: %w", err)
 	}
 	defer pprof.StopCPUProfile()
 
@@ -298,3 +306,121 @@ func (p *Profile) Trace(o *TraceProfileOpts, _ *struct{}) error {
 
 	return nil
 }
+
+// DefaultGuestProfilePeriod is the default sampling period of guest profiles.
+const DefaultGuestProfilePeriod = 10 * time.Millisecond
+
+// GuestProfileOpts contains options specifically for guest profiles.
+type GuestProfileOpts struct {
+	// Duration is the duration of the profile.
+	Duration time.Duration `json:"duration"`
+
+	// Period is the CPU time between samples of a task. If zero,
+	// DefaultGuestProfilePeriod is used.
+	Period time.Duration `json:"period"`
+}
+
+// GuestMapping is an executable mapping of a profiled process.
+type GuestMapping struct {
+	Start  uint64 `json:"start"`
+	End    uint64 `json:"end"`
+	Offset uint64 `json:"offset"`
+	Path   string `json:"path"`
+}
+
+// GuestProcess is an application process sampled by a guest profile.
+type GuestProcess struct {
+	// ContainerID is the container that the process belongs to.
+	ContainerID string `json:"container_id"`
+
+	// PID is the process ID in the process's own PID namespace.
+	PID kernel.ThreadID `json:"pid"`
+
+	// Comm is the name of the process's thread group leader.
+	Comm string `json:"comm"`
+
+	// Exe is the path of the process's executable, or empty if unknown.
+	Exe string `json:"exe"`
+
+	// Mappings are the executable mappings of the process, to symbolize
+	// the addresses of its samples.
+	Mappings []GuestMapping `json:"mappings"`
+}
+
+// GuestSample is the number of times a process was sampled with a given
+// user stack.
+type GuestSample struct {
+	// Process is the index of the sampled process in GuestProfile.Processes.
+	Process int `json:"process"`
+
+	// Stack is the user callchain, starting with the instruction pointer.
+	Stack []uint64 `json:"stack"`
+
+	// Count is the number of samples. Each sample accounts for one period of
+	// CPU time.
+	Count uint64 `json:"count"`
+}
+
+// GuestProfile is the result of Profile.Guest.
+type GuestProfile struct {
+	// Start is the time at which the profile started.
+	Start time.Time `json:"start"`
+
+	// Duration is the duration of the profile.
+	Duration time.Duration `json:"duration"`
+
+	// Period is the sampling period.
+	Period time.Duration `json:"period"`
+
+	// Processes are the processes that were sampled during the profile.
+	Processes []GuestProcess `json:"processes"`
+
+	// Samples are sorted by process and descending count.
+	Samples []GuestSample `json:"samples"`
+}
+
+// Guest collects a CPU profile of the application. Unlike the other profiles,
+// it doesn't depend on the Go runtime: like perf_event_open(2) task clock
+// events sampling callchains, the user stack of every task is sampled each
+// period of CPU time it uses.
+func (p *Profile) Guest(o *GuestProfileOpts, out *GuestProfile) error {
+	period := o.Period
+	if period <= 0 {
+		period = DefaultGuestProfilePeriod
+	}
+	out.Start = time.Now()
+	out.Period = period
+
+	profiler := perf.NewProfiler(p.kernel, period)
+	select {
+	case <-time.After(o.Duration):
+	case <-p.done:
+	}
+	samples := profiler.Stop()
+	out.Duration = time.Since(out.Start)
+
+	procs := make(map[*perf.ProfileProcess]int)
+	for _, s := range samples {
+		idx, ok := procs[s.Process]
+		if !ok {
+			idx = len(out.Processes)
+			procs[s.Process] = idx
+			proc := GuestProcess{
+				ContainerID: s.Process.ContainerID,
+				PID:         s.Process.PID,
+				Comm:        s.Process.Comm,
+				Exe:         s.Process.Exe,
+			}
+			for _, m := range s.Process.Mappings {
+				proc.Mappings = append(proc.Mappings, GuestMapping(m))
+			}
+			out.Processes = append(out.Processes, proc)
+		}
+		out.Samples = append(out.Samples, GuestSample{
+			Process: idx,
+			Stack:   s.Stack,
+			Count:   s.Count,
+		})
+	}
+	return nil
+}
@@ -40,6 +40,9 @@ type SaveOpts struct {
 	// Key is used for state integrity check.
 	Key []byte `json:"key"`
 
+	// EncryptionKey, if not nil, is used to encrypt the checkpoint image.
+	EncryptionKey []byte `json:"encryption_key"`
+
 	// Metadata is the set of metadata to prepend to the state file.
 	Metadata map[string]string `json:"metadata"`
 
@@ -50,6 +53,11 @@ type SaveOpts struct {
 	// metadata file is provided.
 	HavePagesFile bool `json:"have_pages_file"`
 
+	// HaveChunkStore indicates whether pages are saved to a chunk store
+	// outside of the sandbox, in which case the pages file is a connection
+	// to the chunk store. HaveChunkStore requires HavePagesFile.
+	HaveChunkStore bool `json:"have_chunk_store"`
+
 	// FilePayload contains the following:
 	// 1. checkpoint state file.
 	// 2. optional checkpoint pages metadata file.
@@ -67,6 +75,9 @@ func (s *State) Save(o *SaveOpts, _ *struct{}) error {
 	if o.HavePagesFile {
 		wantFiles += 2
 	}
+	if o.HaveChunkStore && !o.HavePagesFile {
+		return fmt.Errorf("chunk store requires a pages file")
+	}
 	if gotFiles := len(o.FilePayload.Files); gotFiles != wantFiles {
 		return fmt.Errorf("got %d files, wanted %d", gotFiles, wantFiles)
 	}
@@ -80,6 +91,7 @@ func (s *State) Save(o *SaveOpts, _ *struct{}) error {
 	saveOpts := state.SaveOpts{
 		Destination:        stateFile,
 		Key:                o.Key,
+		EncryptionKey:      o.EncryptionKey,
 		Metadata:           o.Metadata,
 		MemoryFileSaveOpts: o.MemoryFileSaveOpts,
 		Resume:             o.Resume,
@@ -97,5 +109,8 @@ func (s *State) Save(o *SaveOpts, _ *struct{}) error {
 		}
 		defer saveOpts.PagesFile.Close()
 	}
+	if o.HaveChunkStore {
+		saveOpts.MemoryFileSaveOpts.ChunkStore = pgalloc.NewChunkStore(saveOpts.PagesFile)
+	}
 	return saveOpts.Save(s.Kernel.SupervisorContext(), s.Kernel, s.Watchdog)
 }
@@ -0,0 +1,118 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+)
+
+// SyscallStats includes syscall stats related RPC stubs.
+type SyscallStats struct {
+	Kernel *kernel.Kernel
+}
+
+// SyscallStatsArgs are the arguments to SyscallStats.Collect.
+type SyscallStatsArgs struct {
+	// ContainerID is the container to return stats for.
+	ContainerID string `json:"container_id"`
+
+	// SetEnabled indicates that syscall stats collection should be enabled or
+	// disabled according to Enabled, after the stats collected so far are
+	// returned.
+	SetEnabled bool `json:"set_enabled"`
+
+	// Enabled is the new state of syscall stats collection if SetEnabled is
+	// true. Enabling collection discards stats collected previously.
+	Enabled bool `json:"enabled"`
+}
+
+// SyscallStatsResult is the result of SyscallStats.Collect.
+type SyscallStatsResult struct {
+	// Enabled is true if syscall stats are being collected.
+	Enabled bool `json:"enabled"`
+
+	// Stats are the stats collected for the container, sorted by descending
+	// cumulative time.
+	Stats []kernel.SyscallStat `json:"stats"`
+}
+
+// Collect returns the syscall stats collected for a container, and optionally
+// enables or disables syscall stats collection for all containers.
+func (s *SyscallStats) Collect(args *SyscallStatsArgs, out *SyscallStatsResult) error {
+	out.Stats = s.Kernel.SyscallStats(args.ContainerID)
+	if args.SetEnabled {
+		if args.Enabled {
+			s.Kernel.EnableSyscallStats()
+		} else {
+			s.Kernel.DisableSyscallStats()
+		}
+	}
+	out.Enabled = s.Kernel.SyscallStatsEnabled()
+	return nil
+}
+
+// WriteSyscallStatsTable writes stats to w as a table in the format used by
+// `strace -c`. Time is wall time spent in the syscall, like `strace -c -w`.
+func WriteSyscallStatsTable(w io.Writer, stats []kernel.SyscallStat) error {
+	const (
+		header    = "%6s %11s %11s %9s %9s %s\n"
+		row       = "%6.2f %11.6f %11d %9d %9s %s\n"
+		separator = "------ ----------- ----------- --------- --------- ----------------\n"
+	)
+	var total kernel.SyscallStat
+	for _, stat := range stats {
+		total.Calls += stat.Calls
+		total.Errors += stat.Errors
+		total.TimeNs += stat.TimeNs
+	}
+	total.Name = "total"
+
+	if _, err := fmt.Fprintf(w, header, "% time", "seconds", "usecs/call", "calls", "errors", "syscall"); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, separator); err != nil {
+		return err
+	}
+	writeRow := func(stat kernel.SyscallStat) error {
+		var percent float64
+		if total.TimeNs > 0 {
+			percent = 100 * float64(stat.TimeNs) / float64(total.TimeNs)
+		}
+		var usecsPerCall int64
+		if stat.Calls > 0 {
+			usecsPerCall = stat.TimeNs / int64(stat.Calls) / int64(time.Microsecond)
+		}
+		errors := ""
+		if stat.Errors > 0 {
+			errors = fmt.Sprint(stat.Errors)
+		}
+		seconds := time.Duration(stat.TimeNs).Seconds()
+		_, err := fmt.Fprintf(w, row, percent, seconds, usecsPerCall, stat.Calls, errors, stat.Name)
+		return err
+	}
+	for _, stat := range stats {
+		if err := writeRow(stat); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, separator); err != nil {
+		return err
+	}
+	return writeRow(total)
+}
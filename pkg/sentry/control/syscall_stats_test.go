@@ -0,0 +1,44 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control
+
+import (
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+)
+
+// Tests that WriteSyscallStatsTable prints in the `strace -c` format.
+func TestSyscallStatsTable(t *testing.T) {
+	stats := []kernel.SyscallStat{
+		{Name: "read", Calls: 4, Errors: 1, TimeNs: 3000000},
+		{Name: "write", Calls: 1, TimeNs: 1000000},
+	}
+	var b strings.Builder
+	if err := WriteSyscallStatsTable(&b, stats); err != nil {
+		t.Fatalf("WriteSyscallStatsTable failed: %v", err)
+	}
+	want := `% time     seconds  usecs/call     calls    errors syscall
+------ ----------- ----------- --------- --------- ----------------
+ 75.00    0.003000         750         4         1 read
+ 25.00    0.001000        1000         1           write
+------ ----------- ----------- --------- --------- ----------------
+100.00    0.004000         800         5         1 total
+`
+	if got := b.String(); got != want {
+		t.Errorf("wrong table, got:\n%s\nwant:\n%s", got, want)
+	}
+}
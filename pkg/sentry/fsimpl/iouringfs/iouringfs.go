@@ -337,6 +337,9 @@ func (fd *FileDescription) ProcessSubmissions(t *kernel.Task, toSubmit uint32, m
 		fd.mapSharedBuffers()
 		fd.remap = false
 	}
+	// The rings buffer is written through internal mappings retained across
+	// saves.
+	fd.mf.MarkDirty(fd.rbmf.fr)
 
 	var err error
 	var sqe linux.IOUringSqe
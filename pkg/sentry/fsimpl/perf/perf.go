@@ -0,0 +1,635 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package perf implements perf_event_open(2) file descriptions.
+//
+// Only software events observing specific tasks (and optionally their
+// children) are supported. Events can be counted, and sampled into a ring
+// buffer mapped by the application, from which profilers read samples with
+// user callchains as well as the side-band records needed to symbolize them.
+//
+// Clock events are driven by the task CPU clocks, so their precision is
+// limited to linux.ClockTick.
+package perf
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	// MaxSampleRate is the maximum sampling frequency of events, as reported
+	// by /proc/sys/kernel/perf_event_max_sample_rate.
+	MaxSampleRate = 100000
+
+	// minClockPeriod is the minimum sample period of clock events in
+	// nanoseconds. See kernel/events/core.c:perf_swevent_init_hrtimer().
+	minClockPeriod = 10000
+)
+
+// lastID is the last ID assigned to an event.
+var lastID atomicbitops.Uint64
+
+// Event implements vfs.FileDescriptionImpl for perf events.
+//
+// +stateify savable
+type Event struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+
+	// queue is notified of sample overflows, of the ring buffer becoming
+	// readable, and of the target task exiting.
+	queue waiter.Queue
+
+	// The fields below are immutable.
+	k      *kernel.Kernel
+	id     uint64
+	attr   linux.PerfEventAttr
+	target *kernel.Task
+	pidns  *kernel.PIDNamespace
+	cpu    int32
+
+	// clockEvent is true for PERF_COUNT_SW_CPU_CLOCK and
+	// PERF_COUNT_SW_TASK_CLOCK events, which count nanoseconds of CPU time.
+	// Other events count occurrences reported by kernel.PerfEvent hooks.
+	clockEvent bool
+
+	// maxStack is the maximum number of addresses in sampled callchains.
+	maxStack int
+
+	// lost is the number of samples that couldn't be written to the ring
+	// buffer.
+	lost atomicbitops.Uint64
+
+	// mu protects the fields below.
+	mu sync.Mutex `state:"nosave"`
+
+	// enabled is true if the event is counting.
+	//
+	// +checklocks:mu
+	enabled bool
+
+	// enabledAt is the time at which the event was last enabled, and
+	// timeEnabled the total time it was enabled before that, in nanoseconds.
+	//
+	// +checklocks:mu
+	enabledAt int64
+	// +checklocks:mu
+	timeEnabled int64
+
+	// count is the event count. For clock events, the time counted by the
+	// tasks since they were last started is added by valueLocked.
+	//
+	// +checklocks:mu
+	count uint64
+
+	// period is the sample period, or zero if the event isn't sampled.
+	//
+	// +checklocks:mu
+	period uint64
+
+	// periodLeft is the number of events counted since the last sample
+	// period elapsed, for events other than clock events.
+	//
+	// +checklocks:mu
+	periodLeft uint64
+
+	// limit is the number of overflows after which the event is disabled, as
+	// set by PERF_EVENT_IOC_REFRESH, or zero if unlimited.
+	//
+	// +checklocks:mu
+	limit int64
+
+	// tasks are the tasks observed by the event. tasks is nil after the
+	// event is released.
+	//
+	// +checklocks:mu
+	tasks map[*kernel.Task]*taskState
+
+	// ring is the ring buffer to which records are written, which is either
+	// allocated by mmap of this event (ownsRing) or shared with another
+	// event by PERF_EVENT_IOC_SET_OUTPUT. ring holds a reference.
+	//
+	// +checklocks:mu
+	ring *ringBuffer
+	// +checklocks:mu
+	ownsRing bool
+
+	// hup is true after the target exited.
+	//
+	// +checklocks:mu
+	hup bool
+}
+
+var _ vfs.FileDescriptionImpl = (*Event)(nil)
+var _ kernel.PerfEvent = (*Event)(nil)
+
+// Options are the options of New.
+type Options struct {
+	// Target is the task observed by the event.
+	Target *kernel.Task
+
+	// PIDNamespace is the namespace of the PIDs reported in records.
+	PIDNamespace *kernel.PIDNamespace
+
+	// CPU restricts the event to tasks running on a CPU, if not -1.
+	CPU int32
+
+	// Attr is the event configuration.
+	Attr linux.PerfEventAttr
+}
+
+// New creates a new perf event fd.
+func New(ctx context.Context, vfsObj *vfs.VirtualFilesystem, opts *Options) (*vfs.FileDescription, error) {
+	attr := &opts.Attr
+	if err := validateAttr(attr); err != nil {
+		return nil, err
+	}
+
+	vd := vfsObj.NewAnonVirtualDentry("[perf_event]")
+	defer vd.DecRef(ctx)
+
+	e := &Event{
+		k:      opts.Target.Kernel(),
+		id:     lastID.Add(1),
+		attr:   *attr,
+		target: opts.Target,
+		pidns:  opts.PIDNamespace,
+		cpu:    opts.CPU,
+		tasks:  make(map[*kernel.Task]*taskState),
+	}
+	e.clockEvent = attr.Config == linux.PERF_COUNT_SW_CPU_CLOCK || attr.Config == linux.PERF_COUNT_SW_TASK_CLOCK
+	e.maxStack = int(attr.SampleMaxStack)
+	if e.maxStack == 0 {
+		e.maxStack = linux.PERF_MAX_STACK_DEPTH
+	}
+	e.period = e.samplePeriod(attr.SamplePeriod)
+
+	if err := e.vfsfd.Init(e, linux.O_RDWR, vd.Mount(), vd.Dentry(), &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+		DenyPRead:         true,
+		DenyPWrite:        true,
+		DenySpliceIn:      true,
+	}); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	if !opts.Target.AttachPerfEvent(e) {
+		e.mu.Unlock()
+		e.vfsfd.DecRef(ctx)
+		return nil, linuxerr.ESRCH
+	}
+	e.tasks[opts.Target] = e.newTaskState(opts.Target)
+	if attr.Flags&linux.PERF_ATTR_FLAG_DISABLED == 0 {
+		e.enableLocked()
+	}
+	e.mu.Unlock()
+	return &e.vfsfd, nil
+}
+
+// validateAttr returns an error if attr isn't supported.
+func validateAttr(attr *linux.PerfEventAttr) error {
+	if attr.Type != linux.PERF_TYPE_SOFTWARE {
+		return linuxerr.ENOENT
+	}
+	switch attr.Config {
+	case linux.PERF_COUNT_SW_CPU_CLOCK,
+		linux.PERF_COUNT_SW_TASK_CLOCK,
+		linux.PERF_COUNT_SW_PAGE_FAULTS,
+		linux.PERF_COUNT_SW_PAGE_FAULTS_MIN,
+		linux.PERF_COUNT_SW_CONTEXT_SWITCHES,
+		linux.PERF_COUNT_SW_CPU_MIGRATIONS,
+		linux.PERF_COUNT_SW_DUMMY:
+	default:
+		return linuxerr.ENOENT
+	}
+
+	const supportedSampleType = linux.PERF_SAMPLE_IDENTIFIER | linux.PERF_SAMPLE_IP |
+		linux.PERF_SAMPLE_TID | linux.PERF_SAMPLE_TIME | linux.PERF_SAMPLE_ADDR |
+		linux.PERF_SAMPLE_ID | linux.PERF_SAMPLE_STREAM_ID | linux.PERF_SAMPLE_CPU |
+		linux.PERF_SAMPLE_PERIOD | linux.PERF_SAMPLE_READ | linux.PERF_SAMPLE_CALLCHAIN
+	if attr.SampleType >= linux.PERF_SAMPLE_WEIGHT_STRUCT<<1 {
+		return linuxerr.EINVAL
+	}
+	if attr.SampleType&^supportedSampleType != 0 {
+		return linuxerr.EOPNOTSUPP
+	}
+
+	const allReadFormat = linux.PERF_FORMAT_TOTAL_TIME_ENABLED | linux.PERF_FORMAT_TOTAL_TIME_RUNNING |
+		linux.PERF_FORMAT_ID | linux.PERF_FORMAT_GROUP | linux.PERF_FORMAT_LOST
+	if attr.ReadFormat&^allReadFormat != 0 {
+		return linuxerr.EINVAL
+	}
+	if attr.ReadFormat&linux.PERF_FORMAT_GROUP != 0 {
+		return linuxerr.EOPNOTSUPP
+	}
+
+	if attr.Flags >= linux.PERF_ATTR_FLAG_SIGTRAP<<1 {
+		return linuxerr.EINVAL
+	}
+	const unsupportedFlags = linux.PERF_ATTR_FLAG_EXCLUDE_USER | linux.PERF_ATTR_FLAG_WRITE_BACKWARD |
+		linux.PERF_ATTR_FLAG_AUX_OUTPUT | linux.PERF_ATTR_FLAG_REMOVE_ON_EXEC | linux.PERF_ATTR_FLAG_SIGTRAP
+	if attr.Flags&unsupportedFlags != 0 {
+		return linuxerr.EOPNOTSUPP
+	}
+	if attr.Flags&linux.PERF_ATTR_FLAG_INHERIT != 0 && attr.SampleType&linux.PERF_SAMPLE_READ != 0 {
+		return linuxerr.EINVAL
+	}
+
+	if attr.Flags&linux.PERF_ATTR_FLAG_FREQ != 0 {
+		if attr.SamplePeriod > MaxSampleRate {
+			return linuxerr.EINVAL
+		}
+	} else if attr.SamplePeriod&(1<<63) != 0 {
+		return linuxerr.EINVAL
+	}
+
+	if attr.Flags&linux.PERF_ATTR_FLAG_USE_CLOCKID != 0 {
+		switch attr.ClockID {
+		case linux.CLOCK_MONOTONIC, linux.CLOCK_MONOTONIC_RAW, linux.CLOCK_BOOTTIME, linux.CLOCK_REALTIME:
+		default:
+			return linuxerr.EINVAL
+		}
+	}
+
+	if attr.SampleMaxStack > linux.PERF_MAX_STACK_DEPTH {
+		return linuxerr.EOVERFLOW
+	}
+	return nil
+}
+
+// samplePeriod returns the sample period for attr.sample_period (or
+// attr.sample_freq) v.
+func (e *Event) samplePeriod(v uint64) uint64 {
+	if v == 0 || e.attr.Config == linux.PERF_COUNT_SW_DUMMY {
+		return 0
+	}
+	if !e.clockEvent {
+		// Unlike Linux, which adjusts the period of events sampled by
+		// frequency to the observed event rate, sample each event.
+		if e.attr.Flags&linux.PERF_ATTR_FLAG_FREQ != 0 {
+			return 1
+		}
+		return v
+	}
+	if e.attr.Flags&linux.PERF_ATTR_FLAG_FREQ != 0 {
+		v = 1e9 / v
+	}
+	return max(v, minClockPeriod)
+}
+
+// now returns the time reported in records.
+func (e *Event) now() int64 {
+	if e.attr.Flags&linux.PERF_ATTR_FLAG_USE_CLOCKID != 0 && e.attr.ClockID == linux.CLOCK_REALTIME {
+		return e.k.RealtimeClock().Now().Nanoseconds()
+	}
+	return e.k.MonotonicClock().Now().Nanoseconds()
+}
+
+// enableLocked starts counting.
+//
+// +checklocks:e.mu
+func (e *Event) enableLocked() {
+	if e.enabled {
+		return
+	}
+	e.enabled = true
+	e.enabledAt = e.k.MonotonicClock().Now().Nanoseconds()
+	for _, ts := range e.tasks {
+		e.startTaskLocked(ts)
+	}
+}
+
+// disableLocked stops counting.
+//
+// +checklocks:e.mu
+func (e *Event) disableLocked() {
+	if !e.enabled {
+		return
+	}
+	for _, ts := range e.tasks {
+		e.stopTaskLocked(ts)
+	}
+	e.timeEnabled += e.k.MonotonicClock().Now().Nanoseconds() - e.enabledAt
+	e.enabled = false
+}
+
+// valueLocked returns the event count.
+//
+// +checklocks:e.mu
+func (e *Event) valueLocked() uint64 {
+	v := e.count
+	if e.enabled {
+		for _, ts := range e.tasks {
+			if ts.clock != nil {
+				v += uint64(ts.clock.Now().Nanoseconds() - ts.base)
+			}
+		}
+	}
+	return v
+}
+
+// readValuesLocked returns the values returned by read(2).
+//
+// +checklocks:e.mu
+func (e *Event) readValuesLocked() readValues {
+	timeEnabled := e.timeEnabled
+	if e.enabled {
+		timeEnabled += e.k.MonotonicClock().Now().Nanoseconds() - e.enabledAt
+	}
+	return readValues{
+		value:       e.valueLocked(),
+		timeEnabled: uint64(timeEnabled),
+		// Software events are always running while enabled.
+		timeRunning: uint64(timeEnabled),
+		lost:        e.lost.Load(),
+	}
+}
+
+// ringRef returns the ring buffer of e with an extra reference, or nil if e
+// has none.
+func (e *Event) ringRef() *ringBuffer {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.ring != nil {
+		e.ring.IncRef()
+	}
+	return e.ring
+}
+
+// output writes rec to the ring buffer of e, if any. si is used to build
+// PERF_RECORD_LOST records.
+func (e *Event) output(si sampleInfo, rec []byte) {
+	ring := e.ringRef()
+	if ring == nil {
+		return
+	}
+	defer ring.DecRef()
+	if !ring.output(rec, func(lost uint64) []byte { return e.lostRecord(si, lost) }) {
+		e.lost.Add(1)
+	}
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (e *Event) Release(ctx context.Context) {
+	e.mu.Lock()
+	for t, ts := range e.tasks {
+		t.DetachPerfEvent(e)
+		if ts.timer != nil {
+			ts.timer.Destroy()
+		}
+	}
+	e.tasks = nil
+	e.enabled = false
+	ring := e.ring
+	e.ring = nil
+	e.mu.Unlock()
+
+	if ring != nil {
+		ring.removeQueue(&e.queue)
+		ring.DecRef()
+	}
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (e *Event) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	e.mu.Lock()
+	v := e.readValuesLocked()
+	e.mu.Unlock()
+
+	var r record
+	e.appendReadValues(&r, v)
+	if dst.NumBytes() < int64(len(r.buf)) {
+		return 0, linuxerr.ENOSPC
+	}
+	n, err := dst.CopyOut(ctx, r.buf)
+	return int64(n), err
+}
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (e *Event) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		return 0, linuxerr.ENOTTY
+	}
+	switch cmd := args[1].Uint(); cmd {
+	case linux.PERF_EVENT_IOC_ENABLE:
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.enableLocked()
+		return 0, nil
+
+	case linux.PERF_EVENT_IOC_DISABLE:
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.disableLocked()
+		return 0, nil
+
+	case linux.PERF_EVENT_IOC_RESET:
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.count = 0
+		e.periodLeft = 0
+		if e.enabled {
+			for _, ts := range e.tasks {
+				if ts.clock != nil {
+					ts.base = ts.clock.Now().Nanoseconds()
+				}
+			}
+		}
+		return 0, nil
+
+	case linux.PERF_EVENT_IOC_REFRESH:
+		refresh := int64(args[2].Int())
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if e.attr.Flags&linux.PERF_ATTR_FLAG_INHERIT != 0 || e.period == 0 || refresh <= 0 {
+			return 0, linuxerr.EINVAL
+		}
+		e.limit += refresh
+		e.enableLocked()
+		return 0, nil
+
+	case linux.PERF_EVENT_IOC_PERIOD:
+		var v primitive.Uint64
+		if _, err := v.CopyIn(t, args[2].Pointer()); err != nil {
+			return 0, err
+		}
+		if e.attr.Flags&linux.PERF_ATTR_FLAG_FREQ != 0 {
+			if v > MaxSampleRate {
+				return 0, linuxerr.EINVAL
+			}
+		} else if v&(1<<63) != 0 {
+			return 0, linuxerr.EINVAL
+		}
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if e.period == 0 {
+			return 0, linuxerr.EINVAL
+		}
+		period := e.samplePeriod(uint64(v))
+		if period == 0 {
+			return 0, linuxerr.EINVAL
+		}
+		enabled := e.enabled
+		e.disableLocked()
+		e.period = period
+		e.periodLeft = 0
+		if enabled {
+			e.enableLocked()
+		}
+		return 0, nil
+
+	case linux.PERF_EVENT_IOC_ID:
+		id := primitive.Uint64(e.id)
+		_, err := id.CopyOut(t, args[2].Pointer())
+		return 0, err
+
+	case linux.PERF_EVENT_IOC_SET_OUTPUT:
+		var output *Event
+		if fd := args[2].Int(); fd != -1 {
+			file := t.GetFile(fd)
+			if file == nil {
+				return 0, linuxerr.EBADF
+			}
+			defer file.DecRef(t)
+			var ok bool
+			if output, ok = file.Impl().(*Event); !ok {
+				return 0, linuxerr.EINVAL
+			}
+		}
+		return 0, e.setOutput(output)
+
+	case linux.PERF_EVENT_IOC_SET_FILTER, linux.PERF_EVENT_IOC_SET_BPF:
+		// Only apply to tracepoint and kprobe events.
+		return 0, linuxerr.EINVAL
+
+	default:
+		return 0, linuxerr.ENOTTY
+	}
+}
+
+// setOutput redirects the records of e to the ring buffer of output, or
+// stops writing records if output is nil.
+func (e *Event) setOutput(output *Event) error {
+	if output == e {
+		return nil
+	}
+	var ring *ringBuffer
+	if output != nil {
+		if output.cpu != e.cpu || (output.cpu == -1 && output.target != e.target) {
+			return linuxerr.EINVAL
+		}
+		output.mu.Lock()
+		if output.ring != nil && output.ownsRing {
+			ring = output.ring
+			ring.IncRef()
+		}
+		output.mu.Unlock()
+		if ring == nil {
+			return linuxerr.EINVAL
+		}
+	}
+
+	e.mu.Lock()
+	old := e.ring
+	if old != nil && e.ownsRing && old.mapped() {
+		e.mu.Unlock()
+		if ring != nil {
+			ring.DecRef()
+		}
+		return linuxerr.EBUSY
+	}
+	e.ring = ring
+	e.ownsRing = false
+	e.mu.Unlock()
+
+	if old != nil {
+		old.removeQueue(&e.queue)
+		old.DecRef()
+	}
+	if ring != nil {
+		ring.addQueue(&e.queue)
+	}
+	return nil
+}
+
+// ConfigureMMap implements vfs.FileDescriptionImpl.ConfigureMMap.
+func (e *Event) ConfigureMMap(ctx context.Context, opts *memmap.MMapOpts) error {
+	if opts.Offset != 0 || opts.Private {
+		return linuxerr.EINVAL
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.ring == nil {
+		// The mapping is writable if the application consumes records by
+		// updating data_tail.
+		ring, err := newRingBuffer(ctx, opts.Length, !opts.Perms.Write, &e.attr)
+		if err != nil {
+			return err
+		}
+		e.ring = ring
+		e.ownsRing = true
+		ring.addQueue(&e.queue)
+	} else if !e.ownsRing || e.ring.fr.Length() != opts.Length {
+		return linuxerr.EINVAL
+	}
+	return vfs.GenericConfigureMMap(&e.vfsfd, e.ring, opts)
+}
+
+// Readiness implements waiter.Waitable.Readiness.
+func (e *Event) Readiness(mask waiter.EventMask) waiter.EventMask {
+	var ready waiter.EventMask
+	e.mu.Lock()
+	if e.hup {
+		ready |= waiter.EventHUp
+	}
+	e.mu.Unlock()
+	if ring := e.ringRef(); ring != nil {
+		if ring.readable() {
+			ready |= waiter.ReadableEvents
+		}
+		ring.DecRef()
+	}
+	return ready & mask
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (e *Event) EventRegister(we *waiter.Entry) error {
+	e.queue.EventRegister(we)
+	return nil
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (e *Event) EventUnregister(we *waiter.Entry) {
+	e.queue.EventUnregister(we)
+}
+
+// Epollable implements FileDescriptionImpl.Epollable.
+func (e *Event) Epollable() bool {
+	return true
+}
@@ -0,0 +1,249 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package perf
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/contexttest"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+)
+
+func TestValidateAttr(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		attr linux.PerfEventAttr
+		want error
+	}{
+		{
+			name: "cpu-clock",
+			attr: linux.PerfEventAttr{Type: linux.PERF_TYPE_SOFTWARE, Config: linux.PERF_COUNT_SW_CPU_CLOCK, SampleType: linux.PERF_SAMPLE_IP | linux.PERF_SAMPLE_CALLCHAIN},
+		},
+		{
+			name: "hardware",
+			attr: linux.PerfEventAttr{Type: linux.PERF_TYPE_HARDWARE},
+			want: linuxerr.ENOENT,
+		},
+		{
+			name: "bpf-output",
+			attr: linux.PerfEventAttr{Type: linux.PERF_TYPE_SOFTWARE, Config: linux.PERF_COUNT_SW_BPF_OUTPUT},
+			want: linuxerr.ENOENT,
+		},
+		{
+			name: "regs-user",
+			attr: linux.PerfEventAttr{Type: linux.PERF_TYPE_SOFTWARE, SampleType: linux.PERF_SAMPLE_REGS_USER},
+			want: linuxerr.EOPNOTSUPP,
+		},
+		{
+			name: "invalid-sample-type",
+			attr: linux.PerfEventAttr{Type: linux.PERF_TYPE_SOFTWARE, SampleType: 1 << 40},
+			want: linuxerr.EINVAL,
+		},
+		{
+			name: "group",
+			attr: linux.PerfEventAttr{Type: linux.PERF_TYPE_SOFTWARE, ReadFormat: linux.PERF_FORMAT_GROUP},
+			want: linuxerr.EOPNOTSUPP,
+		},
+		{
+			name: "freq-too-high",
+			attr: linux.PerfEventAttr{Type: linux.PERF_TYPE_SOFTWARE, SamplePeriod: MaxSampleRate + 1, Flags: linux.PERF_ATTR_FLAG_FREQ},
+			want: linuxerr.EINVAL,
+		},
+		{
+			name: "max-stack",
+			attr: linux.PerfEventAttr{Type: linux.PERF_TYPE_SOFTWARE, SampleMaxStack: linux.PERF_MAX_STACK_DEPTH + 1},
+			want: linuxerr.EOVERFLOW,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := validateAttr(&tc.attr); got != tc.want {
+				t.Errorf("validateAttr got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSampleRecord(t *testing.T) {
+	e := &Event{
+		id: 7,
+		attr: linux.PerfEventAttr{
+			SampleType: linux.PERF_SAMPLE_IP | linux.PERF_SAMPLE_TID | linux.PERF_SAMPLE_TIME |
+				linux.PERF_SAMPLE_PERIOD | linux.PERF_SAMPLE_CALLCHAIN | linux.PERF_SAMPLE_IDENTIFIER,
+		},
+	}
+	si := sampleInfo{pid: 1, tid: 2, time: 3, cpu: 4}
+	rec := e.sampleRecord(si, 0x1000, []uint64{linux.PERF_CONTEXT_USER, 0x1000, 0x2000}, 10, readValues{})
+
+	var want record
+	want.u32(linux.PERF_RECORD_SAMPLE)
+	want.buf = hostarch.ByteOrder.AppendUint16(want.buf, linux.PERF_RECORD_MISC_USER)
+	want.buf = hostarch.ByteOrder.AppendUint16(want.buf, 8+8+8+8+8+8+8+3*8)
+	want.u64(7) // identifier
+	want.u64(0x1000)
+	want.u32(1)
+	want.u32(2)
+	want.u64(3)
+	want.u64(10)
+	want.u64(3)
+	want.u64(linux.PERF_CONTEXT_USER)
+	want.u64(0x1000)
+	want.u64(0x2000)
+	if !bytes.Equal(rec, want.buf) {
+		t.Errorf("sampleRecord got: %x, want: %x", rec, want.buf)
+	}
+}
+
+func TestRecordString(t *testing.T) {
+	for _, s := range []string{"", "a", "1234567", "12345678"} {
+		var r record
+		r.str(s)
+		if len(r.buf)%8 != 0 || len(r.buf) <= len(s) || r.buf[len(s)] != 0 {
+			t.Errorf("str(%q) got: %q, want NUL-terminated and padded to 8 bytes", s, r.buf)
+		}
+	}
+}
+
+// readRing returns the contents of r's memory at off.
+func readRing(t *testing.T, r *ringBuffer, off, n uint64) []byte {
+	t.Helper()
+	ims, err := r.mf.MapInternal(memmap.FileRange{r.fr.Start + off, r.fr.Start + off + n}, hostarch.Read)
+	if err != nil {
+		t.Fatalf("MapInternal failed: %v", err)
+	}
+	buf := make([]byte, n)
+	if _, err := safemem.CopySeq(safemem.BlockSeqOf(safemem.BlockFromSafeSlice(buf)), ims); err != nil {
+		t.Fatalf("CopySeq failed: %v", err)
+	}
+	return buf
+}
+
+// setTail sets data_tail of r.
+func setTail(t *testing.T, r *ringBuffer, tail uint64) {
+	t.Helper()
+	b, err := r.headerField(linux.PERF_MMAP_PAGE_DATA_TAIL)
+	if err != nil {
+		t.Fatalf("headerField failed: %v", err)
+	}
+	safemem.SwapUint64(b, tail)
+}
+
+func TestRingBuffer(t *testing.T) {
+	ctx := contexttest.Context(t)
+	if _, err := newRingBuffer(ctx, 4*hostarch.PageSize, false, &linux.PerfEventAttr{}); err == nil {
+		t.Errorf("newRingBuffer with 3 data pages succeeded, want error")
+	}
+	r, err := newRingBuffer(ctx, 2*hostarch.PageSize, false, &linux.PerfEventAttr{WakeupEvents: 1})
+	if err != nil {
+		t.Fatalf("newRingBuffer failed: %v", err)
+	}
+	defer r.DecRef()
+
+	if got := hostarch.ByteOrder.Uint64(readRing(t, r, linux.PERF_MMAP_PAGE_DATA_SIZE, 8)); got != hostarch.PageSize {
+		t.Errorf("data_size got: %d, want: %d", got, hostarch.PageSize)
+	}
+
+	lostRecord := func(lost uint64) []byte {
+		rec := newRecord(linux.PERF_RECORD_LOST, 0)
+		rec.u64(0)
+		rec.u64(lost)
+		return rec.bytes()
+	}
+	rec := bytes.Repeat([]byte{1}, 1000)
+
+	// Fill the buffer, then check that the next record is dropped.
+	for i := 0; i < 4; i++ {
+		if !r.output(rec, lostRecord) {
+			t.Fatalf("output %d failed", i)
+		}
+	}
+	if r.output(rec, lostRecord) {
+		t.Fatalf("output to full buffer succeeded")
+	}
+	if got := hostarch.ByteOrder.Uint64(readRing(t, r, linux.PERF_MMAP_PAGE_DATA_HEAD, 8)); got != 4000 {
+		t.Errorf("data_head got: %d, want: 4000", got)
+	}
+
+	// Consume two records. A LOST record is written before the next record,
+	// which wraps around the end of the data area.
+	setTail(t, r, 2000)
+	if !r.output(rec, lostRecord) {
+		t.Fatalf("output after consuming records failed")
+	}
+	lost := readRing(t, r, hostarch.PageSize+4000, 24)
+	if got := hostarch.ByteOrder.Uint32(lost); got != linux.PERF_RECORD_LOST {
+		t.Errorf("record type got: %d, want: %d", got, linux.PERF_RECORD_LOST)
+	}
+	if got := hostarch.ByteOrder.Uint64(lost[16:]); got != 1 {
+		t.Errorf("lost got: %d, want: 1", got)
+	}
+	got := append(readRing(t, r, hostarch.PageSize+4024, 72), readRing(t, r, hostarch.PageSize, 928)...)
+	if !bytes.Equal(got, rec) {
+		t.Errorf("record after LOST record doesn't match")
+	}
+	if got := hostarch.ByteOrder.Uint64(readRing(t, r, linux.PERF_MMAP_PAGE_DATA_HEAD, 8)); got != 5024 {
+		t.Errorf("data_head got: %d, want: 5024", got)
+	}
+}
+
+func TestProfilerSamples(t *testing.T) {
+	p := &Profiler{
+		tasks:   make(map[*kernel.Task]*taskState),
+		samples: make(map[*ProfileProcess]map[string]*ProfileSample),
+	}
+	sh := &ProfileProcess{ContainerID: "c1", PID: 2}
+	init := &ProfileProcess{ContainerID: "c1", PID: 1}
+	other := &ProfileProcess{ContainerID: "c0", PID: 5}
+	p.mu.Lock()
+	p.recordLocked(sh, []uint64{0x1000, 0x2000}, 1)
+	p.recordLocked(sh, []uint64{0x3000}, 1)
+	p.recordLocked(init, []uint64{0x1000}, 1)
+	p.recordLocked(sh, []uint64{0x3000}, 2)
+	// Stacks are distinct even if one is a prefix of the other.
+	p.recordLocked(sh, []uint64{0x1000}, 1)
+	p.recordLocked(other, []uint64{0x1000}, 4)
+	p.mu.Unlock()
+
+	type sample struct {
+		proc  *ProfileProcess
+		stack []uint64
+		count uint64
+	}
+	want := []sample{
+		{other, []uint64{0x1000}, 4},
+		{init, []uint64{0x1000}, 1},
+		{sh, []uint64{0x3000}, 3},
+	}
+	got := p.Stop()
+	if len(got) != 5 {
+		t.Fatalf("Stop returned %d samples, want 5", len(got))
+	}
+	for i, w := range want {
+		if s := got[i]; s.Process != w.proc || !slices.Equal(s.Stack, w.stack) || s.Count != w.count {
+			t.Errorf("sample %d: got %+v, want %+v", i, *s, w)
+		}
+	}
+	for _, s := range got[3:] {
+		if s.Process != sh || s.Count != 1 {
+			t.Errorf("got sample %+v, want one sample of process 2", *s)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package perf
+
+import (
+	"encoding/binary"
+	"sort"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/ktime"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// ProfileMapping is an executable mapping of a sampled process.
+//
+// +stateify savable
+type ProfileMapping struct {
+	Start  uint64
+	End    uint64
+	Offset uint64
+	Path   string
+}
+
+// ProfileProcess is a process sampled by a Profiler. A process that calls
+// execve is reported as a new ProfileProcess.
+//
+// +stateify savable
+type ProfileProcess struct {
+	// ContainerID is the container that the process belongs to.
+	ContainerID string
+
+	// PID is the process ID in the process's own PID namespace.
+	PID kernel.ThreadID
+
+	// Comm is the name of the process's thread group leader.
+	Comm string
+
+	// Exe is the path of the process's executable, or empty if unknown.
+	Exe string
+
+	// Mappings are the executable mappings of the process when its samples
+	// were taken.
+	Mappings []ProfileMapping
+
+	// mmapGen is incremented when the process creates an executable mapping,
+	// and mappingsGen is the value of mmapGen when Mappings were last read.
+	// Both are protected by Profiler.mu.
+	mmapGen     uint64
+	mappingsGen uint64
+}
+
+// ProfileSample is the number of times a process was sampled with a given
+// user callchain.
+//
+// +stateify savable
+type ProfileSample struct {
+	Process *ProfileProcess
+
+	// Stack is the user callchain, starting with the instruction pointer.
+	Stack []uint64
+
+	// Count is the number of samples.
+	Count uint64
+}
+
+// Profiler samples the user callchains of all tasks of a kernel every period
+// of CPU time. It behaves like a PERF_COUNT_SW_TASK_CLOCK event sampling
+// PERF_SAMPLE_CALLCHAIN, inherited by children and attached to every task,
+// but aggregates samples in memory instead of writing them to a ring buffer,
+// so that the application can be profiled from outside the sandbox.
+//
+// +stateify savable
+type Profiler struct {
+	// The fields below are immutable.
+	k        *kernel.Kernel
+	period   time.Duration
+	maxStack int
+
+	mu sync.Mutex `state:"nosave"`
+
+	// stopped is true after Stop, or after the profiler is restored, since
+	// nothing will stop it then. Tasks are detached from a stopped profiler
+	// the next time they notify it.
+	//
+	// +checklocks:mu
+	stopped bool
+
+	// tasks are the tasks observed by the profiler.
+	//
+	// +checklocks:mu
+	tasks map[*kernel.Task]*taskState
+
+	// procs maps thread groups to the process that their samples are
+	// attributed to. Entries are removed on exec.
+	//
+	// +checklocks:mu
+	procs map[*kernel.ThreadGroup]*ProfileProcess
+
+	// samples are the samples taken so far, by process and encoded stack.
+	//
+	// +checklocks:mu
+	samples map[*ProfileProcess]map[string]*ProfileSample
+}
+
+var _ kernel.PerfEvent = (*Profiler)(nil)
+
+// NewProfiler starts a Profiler that samples the tasks of k every period of
+// CPU time. Like for clock events, the period is rounded up to the precision
+// of task CPU clocks.
+func NewProfiler(k *kernel.Kernel, period time.Duration) *Profiler {
+	p := &Profiler{
+		k:        k,
+		period:   max(period, time.Duration(minClockPeriod)),
+		maxStack: linux.PERF_MAX_STACK_DEPTH,
+		tasks:    make(map[*kernel.Task]*taskState),
+		procs:    make(map[*kernel.ThreadGroup]*ProfileProcess),
+		samples:  make(map[*ProfileProcess]map[string]*ProfileSample),
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Tasks cloned by a task before it's attached are missed by the
+	// snapshot, so repeat until all tasks are attached.
+	for attached := true; attached; {
+		attached = false
+		for _, t := range k.TaskSet().Root.Tasks() {
+			if _, ok := p.tasks[t]; !ok && p.attachLocked(t) {
+				attached = true
+			}
+		}
+	}
+	return p
+}
+
+// attachLocked starts sampling t. It returns false if t has exited.
+//
+// +checklocks:p.mu
+func (p *Profiler) attachLocked(t *kernel.Task) bool {
+	if !t.AttachPerfEvent(p) {
+		return false
+	}
+	ts := &taskState{
+		s:     p,
+		t:     t,
+		clock: t.CPUClock(),
+	}
+	ts.timer = ts.clock.NewTimer(ts)
+	ts.timer.Set(ktime.Setting{
+		Enabled: true,
+		Next:    ts.clock.Now().Add(p.period),
+		Period:  p.period,
+	}, nil)
+	p.tasks[t] = ts
+	return true
+}
+
+// detachLocked stops sampling t.
+//
+// +checklocks:p.mu
+func (p *Profiler) detachLocked(t *kernel.Task) {
+	if ts, ok := p.tasks[t]; ok {
+		ts.timer.Destroy()
+		delete(p.tasks, t)
+	}
+	t.DetachPerfEvent(p)
+}
+
+// Stop stops p and returns the samples it took, by process and descending
+// count.
+func (p *Profiler) Stop() []*ProfileSample {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	for t := range p.tasks {
+		p.detachLocked(t)
+	}
+	var samples []*ProfileSample
+	for _, stacks := range p.samples {
+		for _, s := range stacks {
+			samples = append(samples, s)
+		}
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		a, b := samples[i], samples[j]
+		if a.Process != b.Process {
+			if a.Process.ContainerID != b.Process.ContainerID {
+				return a.Process.ContainerID < b.Process.ContainerID
+			}
+			return a.Process.PID < b.Process.PID
+		}
+		return a.Count > b.Count
+	})
+	return samples
+}
+
+// overflow implements sampler.overflow.
+func (p *Profiler) overflow(t *kernel.Task, ts *taskState, n uint64) {
+	tg := t.ThreadGroup()
+	p.mu.Lock()
+	if p.stopped {
+		p.detachLocked(t)
+	}
+	if p.tasks[t] != ts {
+		p.mu.Unlock()
+		return
+	}
+	proc := p.procs[tg]
+	var gen uint64
+	if proc != nil {
+		gen = proc.mmapGen
+	}
+	update := proc == nil || proc.mappingsGen != gen
+	p.mu.Unlock()
+
+	// Read the process's mappings and callchain without holding p.mu, since
+	// this accesses the task's MemoryManager.
+	var (
+		newProc  *ProfileProcess
+		mappings []ProfileMapping
+	)
+	if proc == nil {
+		newProc = newProfileProcess(t)
+	}
+	if update {
+		mappings = executableMappings(t)
+	}
+	// Skip the PERF_CONTEXT_USER marker.
+	stack := userCallchain(t, p.maxStack)[1:]
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tasks[t] != ts {
+		return
+	}
+	proc = p.procs[tg]
+	if proc == nil {
+		if newProc == nil {
+			// Another thread called execve since proc was looked up.
+			return
+		}
+		proc = newProc
+		p.procs[tg] = proc
+	}
+	if update {
+		proc.addMappings(mappings, gen)
+	}
+	p.recordLocked(proc, stack, n)
+}
+
+// recordLocked records n samples of proc with the given user callchain.
+//
+// +checklocks:p.mu
+func (p *Profiler) recordLocked(proc *ProfileProcess, stack []uint64, n uint64) {
+	key := make([]byte, 8*len(stack))
+	for i, pc := range stack {
+		binary.LittleEndian.PutUint64(key[8*i:], pc)
+	}
+	stacks := p.samples[proc]
+	if stacks == nil {
+		stacks = make(map[string]*ProfileSample)
+		p.samples[proc] = stacks
+	}
+	s, ok := stacks[string(key)]
+	if !ok {
+		s = &ProfileSample{
+			Process: proc,
+			Stack:   stack,
+		}
+		stacks[string(key)] = s
+	}
+	s.Count += n
+}
+
+// newProfileProcess returns a ProfileProcess identifying the process of t.
+//
+// Preconditions: The caller must be running on the task goroutine of t.
+func newProfileProcess(t *kernel.Task) *ProfileProcess {
+	tg := t.ThreadGroup()
+	proc := &ProfileProcess{
+		ContainerID: t.ContainerID(),
+		PID:         tg.PIDNamespace().IDOfThreadGroup(tg),
+		Comm:        t.Name(),
+	}
+	if leader := tg.Leader(); leader != nil {
+		proc.Comm = leader.Name()
+	}
+	if mm := t.MemoryManager(); mm != nil {
+		if exe := mm.Executable(); exe != nil {
+			proc.Exe = exe.MappedName(t)
+			exe.DecRef(t)
+		}
+	}
+	return proc
+}
+
+// executableMappings returns the executable mappings of t.
+//
+// Preconditions: The caller must be running on the task goroutine of t.
+func executableMappings(t *kernel.Task) []ProfileMapping {
+	mm := t.MemoryManager()
+	if mm == nil {
+		return nil
+	}
+	var mappings []ProfileMapping
+	mm.ReadMapsDataInto(t, func(start, end hostarch.Addr, perms hostarch.AccessType, private string, offset uint64, devMajor, devMinor uint32, inode uint64, path string) {
+		// The vsyscall page isn't a real mapping.
+		if perms.Execute && path != "[vsyscall]" {
+			mappings = append(mappings, ProfileMapping{
+				Start:  uint64(start),
+				End:    uint64(end),
+				Offset: offset,
+				Path:   path,
+			})
+		}
+	})
+	return mappings
+}
+
+// addMappings adds the mappings that proc doesn't have yet, which were read
+// when proc.mmapGen was gen. Earlier mappings are kept, since samples may
+// refer to them.
+//
+// Preconditions: Profiler.mu must be locked.
+func (proc *ProfileProcess) addMappings(mappings []ProfileMapping, gen uint64) {
+	known := make(map[ProfileMapping]struct{}, len(proc.Mappings))
+	for _, m := range proc.Mappings {
+		known[m] = struct{}{}
+	}
+	for _, m := range mappings {
+		if _, ok := known[m]; !ok {
+			proc.Mappings = append(proc.Mappings, m)
+		}
+	}
+	proc.mappingsGen = gen
+}
+
+// PerfEventCount implements kernel.PerfEvent.PerfEventCount.
+func (p *Profiler) PerfEventCount(t *kernel.Task, config uint64, count uint64) {}
+
+// PerfEventExec implements kernel.PerfEvent.PerfEventExec.
+func (p *Profiler) PerfEventExec(t *kernel.Task) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		p.detachLocked(t)
+		return
+	}
+	// Samples after exec are attributed to a new process.
+	delete(p.procs, t.ThreadGroup())
+}
+
+// PerfEventMmap implements kernel.PerfEvent.PerfEventMmap.
+func (p *Profiler) PerfEventMmap(t *kernel.Task, ar hostarch.AddrRange) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		p.detachLocked(t)
+		return
+	}
+	if proc := p.procs[t.ThreadGroup()]; proc != nil {
+		proc.mmapGen++
+	}
+}
+
+// PerfEventClone implements kernel.PerfEvent.PerfEventClone.
+func (p *Profiler) PerfEventClone(t, child *kernel.Task, flags uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		p.detachLocked(t)
+		return
+	}
+	if _, ok := p.tasks[child]; !ok {
+		p.attachLocked(child)
+	}
+}
+
+// PerfEventExit implements kernel.PerfEvent.PerfEventExit.
+func (p *Profiler) PerfEventExit(t *kernel.Task) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ts, ok := p.tasks[t]; ok {
+		ts.timer.Destroy()
+		delete(p.tasks, t)
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package perf
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+)
+
+// record builds a perf record, which starts with a struct perf_event_header.
+type record struct {
+	buf []byte
+}
+
+func newRecord(typ uint32, misc uint16) *record {
+	r := &record{buf: make([]byte, (*linux.PerfEventHeader)(nil).SizeBytes(), 64)}
+	hostarch.ByteOrder.PutUint32(r.buf[0:], typ)
+	hostarch.ByteOrder.PutUint16(r.buf[4:], misc)
+	return r
+}
+
+func (r *record) u32(v uint32) {
+	r.buf = hostarch.ByteOrder.AppendUint32(r.buf, v)
+}
+
+func (r *record) u64(v uint64) {
+	r.buf = hostarch.ByteOrder.AppendUint64(r.buf, v)
+}
+
+// str appends s NUL-terminated and padded to a multiple of 8 bytes.
+func (r *record) str(s string) {
+	r.buf = append(r.buf, s...)
+	r.buf = append(r.buf, make([]byte, 8-len(s)%8)...)
+}
+
+// bytes sets the record size in the header and returns the record.
+func (r *record) bytes() []byte {
+	hostarch.ByteOrder.PutUint16(r.buf[6:], uint16(len(r.buf)))
+	return r.buf
+}
+
+// sampleInfo is the information about the task generating a record that may
+// be included in it.
+type sampleInfo struct {
+	pid  uint32
+	tid  uint32
+	time uint64
+	cpu  uint32
+}
+
+// sampleInfo returns the sampleInfo of records generated by t.
+func (e *Event) sampleInfo(t *kernel.Task) sampleInfo {
+	return sampleInfo{
+		pid:  uint32(e.pidns.IDOfThreadGroup(t.ThreadGroup())),
+		tid:  uint32(e.pidns.IDOfTask(t)),
+		time: uint64(e.now()),
+		cpu:  uint32(t.CPU()),
+	}
+}
+
+// sampleID appends the sample_id trailer of non-sample records if
+// attr.sample_id_all is set.
+func (e *Event) sampleID(r *record, si sampleInfo) {
+	if e.attr.Flags&linux.PERF_ATTR_FLAG_SAMPLE_ID_ALL == 0 {
+		return
+	}
+	st := e.attr.SampleType
+	if st&linux.PERF_SAMPLE_TID != 0 {
+		r.u32(si.pid)
+		r.u32(si.tid)
+	}
+	if st&linux.PERF_SAMPLE_TIME != 0 {
+		r.u64(si.time)
+	}
+	if st&linux.PERF_SAMPLE_ID != 0 {
+		r.u64(e.id)
+	}
+	if st&linux.PERF_SAMPLE_STREAM_ID != 0 {
+		r.u64(e.id)
+	}
+	if st&linux.PERF_SAMPLE_CPU != 0 {
+		r.u32(si.cpu)
+		r.u32(0)
+	}
+	if st&linux.PERF_SAMPLE_IDENTIFIER != 0 {
+		r.u64(e.id)
+	}
+}
+
+// readValues are the values returned by read(2), and included in samples
+// with PERF_SAMPLE_READ.
+type readValues struct {
+	value       uint64
+	timeEnabled uint64
+	timeRunning uint64
+	lost        uint64
+}
+
+// appendReadValues appends v in the layout selected by attr.read_format.
+func (e *Event) appendReadValues(r *record, v readValues) {
+	rf := e.attr.ReadFormat
+	r.u64(v.value)
+	if rf&linux.PERF_FORMAT_TOTAL_TIME_ENABLED != 0 {
+		r.u64(v.timeEnabled)
+	}
+	if rf&linux.PERF_FORMAT_TOTAL_TIME_RUNNING != 0 {
+		r.u64(v.timeRunning)
+	}
+	if rf&linux.PERF_FORMAT_ID != 0 {
+		r.u64(e.id)
+	}
+	if rf&linux.PERF_FORMAT_LOST != 0 {
+		r.u64(v.lost)
+	}
+}
+
+// sampleRecord returns a PERF_RECORD_SAMPLE record. ip and callchain are the
+// user instruction pointer and callchain of the sampled task.
+func (e *Event) sampleRecord(si sampleInfo, ip uint64, callchain []uint64, period uint64, v readValues) []byte {
+	r := newRecord(linux.PERF_RECORD_SAMPLE, linux.PERF_RECORD_MISC_USER)
+	st := e.attr.SampleType
+	if st&linux.PERF_SAMPLE_IDENTIFIER != 0 {
+		r.u64(e.id)
+	}
+	if st&linux.PERF_SAMPLE_IP != 0 {
+		r.u64(ip)
+	}
+	if st&linux.PERF_SAMPLE_TID != 0 {
+		r.u32(si.pid)
+		r.u32(si.tid)
+	}
+	if st&linux.PERF_SAMPLE_TIME != 0 {
+		r.u64(si.time)
+	}
+	if st&linux.PERF_SAMPLE_ADDR != 0 {
+		r.u64(0)
+	}
+	if st&linux.PERF_SAMPLE_ID != 0 {
+		r.u64(e.id)
+	}
+	if st&linux.PERF_SAMPLE_STREAM_ID != 0 {
+		r.u64(e.id)
+	}
+	if st&linux.PERF_SAMPLE_CPU != 0 {
+		r.u32(si.cpu)
+		r.u32(0)
+	}
+	if st&linux.PERF_SAMPLE_PERIOD != 0 {
+		r.u64(period)
+	}
+	if st&linux.PERF_SAMPLE_READ != 0 {
+		e.appendReadValues(r, v)
+	}
+	if st&linux.PERF_SAMPLE_CALLCHAIN != 0 {
+		r.u64(uint64(len(callchain)))
+		for _, pc := range callchain {
+			r.u64(pc)
+		}
+	}
+	return r.bytes()
+}
+
+// lostRecord returns a PERF_RECORD_LOST record.
+func (e *Event) lostRecord(si sampleInfo, lost uint64) []byte {
+	r := newRecord(linux.PERF_RECORD_LOST, 0)
+	r.u64(e.id)
+	r.u64(lost)
+	e.sampleID(r, si)
+	return r.bytes()
+}
+
+// commRecord returns a PERF_RECORD_COMM record.
+func (e *Event) commRecord(si sampleInfo, comm string, exec bool) []byte {
+	var misc uint16
+	if exec && e.attr.Flags&linux.PERF_ATTR_FLAG_COMM_EXEC != 0 {
+		misc = linux.PERF_RECORD_MISC_COMM_EXEC
+	}
+	r := newRecord(linux.PERF_RECORD_COMM, misc)
+	r.u32(si.pid)
+	r.u32(si.tid)
+	r.str(comm)
+	e.sampleID(r, si)
+	return r.bytes()
+}
+
+// taskRecord returns a PERF_RECORD_FORK or PERF_RECORD_EXIT record. si
+// describes the child or exiting task, and psi its parent.
+func (e *Event) taskRecord(typ uint32, si, psi sampleInfo) []byte {
+	r := newRecord(typ, 0)
+	r.u32(si.pid)
+	r.u32(psi.pid)
+	r.u32(si.tid)
+	r.u32(psi.tid)
+	r.u64(si.time)
+	e.sampleID(r, si)
+	return r.bytes()
+}
+
+// mapping describes a memory mapping reported by PERF_RECORD_MMAP(2).
+type mapping struct {
+	start    hostarch.Addr
+	end      hostarch.Addr
+	perms    hostarch.AccessType
+	private  bool
+	offset   uint64
+	devMajor uint32
+	devMinor uint32
+	inode    uint64
+	path     string
+}
+
+// mmapRecord returns a PERF_RECORD_MMAP2 record if attr.mmap2 is set, and a
+// PERF_RECORD_MMAP record otherwise.
+func (e *Event) mmapRecord(si sampleInfo, m *mapping) []byte {
+	typ := uint32(linux.PERF_RECORD_MMAP)
+	if e.attr.Flags&linux.PERF_ATTR_FLAG_MMAP2 != 0 {
+		typ = linux.PERF_RECORD_MMAP2
+	}
+	r := newRecord(typ, linux.PERF_RECORD_MISC_USER)
+	r.u32(si.pid)
+	r.u32(si.tid)
+	r.u64(uint64(m.start))
+	r.u64(uint64(m.end - m.start))
+	r.u64(m.offset)
+	if typ == linux.PERF_RECORD_MMAP2 {
+		r.u32(m.devMajor)
+		r.u32(m.devMinor)
+		r.u64(m.inode)
+		r.u64(0) // ino_generation
+		r.u32(uint32(m.perms.Prot()))
+		flags := uint32(linux.MAP_SHARED)
+		if m.private {
+			flags = linux.MAP_PRIVATE
+		}
+		r.u32(flags)
+	}
+	path := m.path
+	if path == "" {
+		path = "//anon"
+	}
+	r.str(path)
+	e.sampleID(r, si)
+	return r.bytes()
+}
+
+// userCallchain returns the user callchain of t, found by following the
+// chain of frame pointers from the current user registers. The callchain
+// starts with PERF_CONTEXT_USER, and holds at most maxStack addresses.
+//
+// Preconditions: The caller must be running on the task goroutine, and t's
+// AddressSpace must be active.
+func userCallchain(t *kernel.Task, maxStack int) []uint64 {
+	ac := t.Arch()
+	callchain := []uint64{linux.PERF_CONTEXT_USER, uint64(ac.IP())}
+	fp := uint64(ac.FramePointer())
+	// Each frame record holds the caller's frame pointer followed by the
+	// return address.
+	var frame [16]byte
+	for len(callchain)-1 < maxStack && fp != 0 && fp%8 == 0 {
+		if _, err := t.CopyInBytes(hostarch.Addr(fp), frame[:]); err != nil {
+			break
+		}
+		next := hostarch.ByteOrder.Uint64(frame[0:])
+		ret := hostarch.ByteOrder.Uint64(frame[8:])
+		if ret == 0 {
+			break
+		}
+		callchain = append(callchain, ret)
+		// The stack grows down, so caller frames are at higher addresses.
+		// This also guarantees termination on corrupted stacks.
+		if next <= fp {
+			break
+		}
+		fp = next
+	}
+	return callchain
+}
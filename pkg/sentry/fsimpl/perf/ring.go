@@ -0,0 +1,363 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package perf
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// ringBuffer is the buffer shared with the application through which perf
+// records are delivered. It consists of a header page (struct
+// perf_event_mmap_page) followed by a power-of-two number of data pages. See
+// kernel/events/ring_buffer.c.
+//
+// A ringBuffer is shared by all events redirected to it with
+// PERF_EVENT_IOC_SET_OUTPUT, and is released when the last reference on it
+// is dropped.
+//
+// +stateify savable
+type ringBuffer struct {
+	mf *pgalloc.MemoryFile `state:"nosave"`
+
+	// fr is the allocated range, including the header page. fr is immutable.
+	fr memmap.FileRange
+
+	// dataSize is the size of the data area in bytes. dataSize is zero or a
+	// power of two, and is immutable.
+	dataSize uint64
+
+	// overwrite is true if the buffer was mapped read-only, in which case
+	// the application doesn't consume records by updating data_tail and the
+	// oldest records are overwritten. overwrite is immutable.
+	overwrite bool
+
+	// wakeupEvents and watermark determine when the events sharing the
+	// buffer are notified of new records, see attr.wakeup_events. They are
+	// immutable.
+	wakeupEvents uint32
+	watermark    uint64
+
+	refs atomicbitops.Int64
+
+	// mu protects the fields below.
+	mu sync.Mutex `state:"nosave"`
+
+	// head is the offset at which the next record will be written. It is
+	// mirrored in data_head.
+	//
+	// +checklocks:mu
+	head uint64
+
+	// lost is the number of records dropped since the last PERF_RECORD_LOST.
+	//
+	// +checklocks:mu
+	lost uint64
+
+	// events is the number of records written since the last wakeup, and
+	// wakeupHead is head at the last wakeup.
+	//
+	// +checklocks:mu
+	events uint32
+	// +checklocks:mu
+	wakeupHead uint64
+
+	// mappings is the number of memory mappings of the buffer.
+	//
+	// +checklocks:mu
+	mappings int
+
+	// queues are the waiter queues of the events sharing the buffer, which
+	// are notified when the buffer becomes readable.
+	//
+	// +checklocks:mu
+	queues []*waiter.Queue
+}
+
+var _ memmap.Mappable = (*ringBuffer)(nil)
+
+// newRingBuffer allocates a ring buffer of the given size in bytes, which
+// includes the header page.
+func newRingBuffer(ctx context.Context, size uint64, overwrite bool, attr *linux.PerfEventAttr) (*ringBuffer, error) {
+	if size < hostarch.PageSize || size%hostarch.PageSize != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	dataSize := size - hostarch.PageSize
+	if dataSize&(dataSize-1) != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	mf := pgalloc.MemoryFileFromContext(ctx)
+	fr, err := mf.Allocate(size, pgalloc.AllocOpts{Kind: usage.Anonymous, MemCgID: pgalloc.MemoryCgroupIDFromContext(ctx)})
+	if err != nil {
+		return nil, linuxerr.ENOMEM
+	}
+	r := &ringBuffer{
+		mf:        mf,
+		fr:        fr,
+		dataSize:  dataSize,
+		overwrite: overwrite,
+	}
+	r.refs.Store(1)
+	if attr.Flags&linux.PERF_ATTR_FLAG_WATERMARK != 0 {
+		r.watermark = uint64(attr.WakeupEvents)
+	} else {
+		r.wakeupEvents = attr.WakeupEvents
+	}
+	if r.watermark == 0 || r.watermark > dataSize {
+		r.watermark = dataSize / 2
+	}
+	if err := r.initHeader(); err != nil {
+		r.DecRef()
+		return nil, err
+	}
+	return r, nil
+}
+
+// initHeader initializes the header page.
+func (r *ringBuffer) initHeader() error {
+	hdr := linux.PerfEventMmapPage{
+		Capabilities: linux.PERF_MMAP_PAGE_CAP_BIT0_IS_DEPRECATED,
+	}
+	hdr.Size = uint32(hdr.SizeBytes())
+	buf := make([]byte, linux.PERF_MMAP_PAGE_DATA_SIZE+8)
+	hdr.MarshalUnsafe(buf)
+	hostarch.ByteOrder.PutUint64(buf[linux.PERF_MMAP_PAGE_DATA_OFFSET:], hostarch.PageSize)
+	hostarch.ByteOrder.PutUint64(buf[linux.PERF_MMAP_PAGE_DATA_SIZE:], r.dataSize)
+	ims, err := r.mf.MapInternal(memmap.FileRange{r.fr.Start, r.fr.Start + uint64(len(buf))}, hostarch.Write)
+	if err != nil {
+		return err
+	}
+	_, err = safemem.CopySeq(ims, safemem.BlockSeqOf(safemem.BlockFromSafeSlice(buf)))
+	return err
+}
+
+// IncRef increments the buffer's reference count.
+func (r *ringBuffer) IncRef() {
+	r.refs.Add(1)
+}
+
+// DecRef decrements the buffer's reference count, releasing its memory when
+// it reaches zero.
+func (r *ringBuffer) DecRef() {
+	if r.refs.Add(-1) == 0 {
+		r.mf.DecRef(r.fr)
+	}
+}
+
+// headerField returns the block holding the 8-byte field of the header page
+// at offset off.
+func (r *ringBuffer) headerField(off uint64) (safemem.Block, error) {
+	ims, err := r.mf.MapInternal(memmap.FileRange{r.fr.Start + off, r.fr.Start + off + 8}, hostarch.ReadWrite)
+	if err != nil {
+		return safemem.Block{}, err
+	}
+	return ims.Head(), nil
+}
+
+// tailLocked returns the offset up to which the application has consumed
+// records, as published in data_tail.
+//
+// +checklocks:r.mu
+func (r *ringBuffer) tailLocked() (uint64, error) {
+	b, err := r.headerField(linux.PERF_MMAP_PAGE_DATA_TAIL)
+	if err != nil {
+		return 0, err
+	}
+	// Only the low 32 bits of data_tail can be loaded atomically. The
+	// application can't be more than dataSize behind head, so the upper bits
+	// are those of head.
+	tail32, err := safemem.LoadUint32(b.TakeFirst(4))
+	if err != nil {
+		return 0, err
+	}
+	tail := r.head&^uint64(0xffffffff) | uint64(tail32)
+	if tail > r.head {
+		tail -= 1 << 32
+	}
+	return tail, nil
+}
+
+// readable returns true if the buffer holds records that the application
+// hasn't consumed.
+func (r *ringBuffer) readable() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.overwrite {
+		return r.head != 0
+	}
+	tail, err := r.tailLocked()
+	return err == nil && tail != r.head
+}
+
+// output writes rec to the buffer, preceded by a PERF_RECORD_LOST record
+// built by lostRecord if records were dropped previously. It returns false if
+// rec was dropped because the buffer is full.
+func (r *ringBuffer) output(rec []byte, lostRecord func(lost uint64) []byte) bool {
+	r.mu.Lock()
+	if r.lost > 0 {
+		if r.writeLocked(lostRecord(r.lost)) {
+			r.lost = 0
+		}
+	}
+	ok := r.lost == 0 && r.writeLocked(rec)
+	if !ok {
+		r.lost++
+		r.mu.Unlock()
+		return false
+	}
+
+	wakeup := false
+	if r.wakeupEvents > 0 {
+		r.events++
+		if r.events >= r.wakeupEvents {
+			r.events = 0
+			wakeup = true
+		}
+	} else if r.head-r.wakeupHead >= r.watermark {
+		wakeup = true
+	}
+	var queues []*waiter.Queue
+	if wakeup {
+		r.wakeupHead = r.head
+		queues = append(queues, r.queues...)
+	}
+	r.mu.Unlock()
+
+	for _, q := range queues {
+		q.Notify(waiter.EventIn)
+	}
+	return true
+}
+
+// writeLocked copies rec into the data area at head and publishes it by
+// advancing data_head. It returns false if there isn't enough space for rec.
+//
+// +checklocks:r.mu
+func (r *ringBuffer) writeLocked(rec []byte) bool {
+	size := uint64(len(rec))
+	if size > r.dataSize {
+		return false
+	}
+	if !r.overwrite {
+		tail, err := r.tailLocked()
+		if err != nil || r.head-tail+size > r.dataSize {
+			return false
+		}
+	}
+
+	dataStart := r.fr.Start + hostarch.PageSize
+	off := r.head & (r.dataSize - 1)
+	src := rec
+	for len(src) > 0 {
+		n := min(uint64(len(src)), r.dataSize-off)
+		ims, err := r.mf.MapInternal(memmap.FileRange{dataStart + off, dataStart + off + n}, hostarch.Write)
+		if err != nil {
+			return false
+		}
+		if _, err := safemem.CopySeq(ims, safemem.BlockSeqOf(safemem.BlockFromSafeSlice(src[:n]))); err != nil {
+			return false
+		}
+		src = src[n:]
+		off = 0
+	}
+
+	head, err := r.headerField(linux.PERF_MMAP_PAGE_DATA_HEAD)
+	if err != nil {
+		return false
+	}
+	r.head += size
+	safemem.SwapUint64(head, r.head)
+	return true
+}
+
+// addQueue registers q to be notified when the buffer becomes readable.
+func (r *ringBuffer) addQueue(q *waiter.Queue) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queues = append(r.queues, q)
+}
+
+// removeQueue undoes addQueue.
+func (r *ringBuffer) removeQueue(q *waiter.Queue) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, rq := range r.queues {
+		if rq == q {
+			r.queues = append(r.queues[:i], r.queues[i+1:]...)
+			return
+		}
+	}
+}
+
+// mapped returns true if the buffer is mapped into an address space.
+func (r *ringBuffer) mapped() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.mappings > 0
+}
+
+// AddMapping implements memmap.Mappable.AddMapping.
+func (r *ringBuffer) AddMapping(ctx context.Context, ms memmap.MappingSpace, ar hostarch.AddrRange, offset uint64, writable bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mappings++
+	return nil
+}
+
+// RemoveMapping implements memmap.Mappable.RemoveMapping.
+func (r *ringBuffer) RemoveMapping(ctx context.Context, ms memmap.MappingSpace, ar hostarch.AddrRange, offset uint64, writable bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mappings--
+}
+
+// CopyMapping implements memmap.Mappable.CopyMapping.
+func (r *ringBuffer) CopyMapping(ctx context.Context, ms memmap.MappingSpace, srcAR, dstAR hostarch.AddrRange, offset uint64, writable bool) error {
+	return r.AddMapping(ctx, ms, dstAR, offset, writable)
+}
+
+// Translate implements memmap.Mappable.Translate.
+func (r *ringBuffer) Translate(ctx context.Context, required, optional memmap.MappableRange, at hostarch.AccessType) ([]memmap.Translation, error) {
+	if required.End > r.fr.Length() {
+		return nil, &memmap.BusError{linuxerr.EFAULT}
+	}
+
+	if source := optional.Intersect(memmap.MappableRange{0, r.fr.Length()}); source.Length() != 0 {
+		return []memmap.Translation{
+			{
+				Source: source,
+				File:   pgalloc.MemoryFileFromContext(ctx),
+				Offset: r.fr.Start + source.Start,
+				Perms:  hostarch.AnyAccess,
+			},
+		}, nil
+	}
+
+	return nil, linuxerr.EFAULT
+}
+
+// InvalidateUnsavable implements memmap.Mappable.InvalidateUnsavable.
+func (r *ringBuffer) InvalidateUnsavable(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,340 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package perf
+
+import (
+	"time"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/ktime"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// sampler takes samples of the tasks it observes. It's implemented by Event
+// and Profiler.
+type sampler interface {
+	// overflow takes a sample of t after n sample periods elapsed.
+	//
+	// Preconditions: The caller must be running on the task goroutine of t.
+	overflow(t *kernel.Task, ts *taskState, n uint64)
+}
+
+// taskState is the state of an Event or Profiler for one of the tasks it
+// observes.
+//
+// +stateify savable
+type taskState struct {
+	s sampler
+	t *kernel.Task
+
+	// clock is the CPU clock of t measured by clock events, and timer is a
+	// timer on clock that expires once per sample period. clock and timer
+	// are nil for other events. They are immutable.
+	clock ktime.Clock
+	timer ktime.Timer
+
+	// base is the value of clock when the event was last enabled, or when
+	// the count was last folded into Event.count. base is protected by
+	// Event.mu.
+	base int64
+
+	// pending is the number of sample periods that elapsed since the last
+	// sample.
+	pending atomicbitops.Uint64
+
+	// queued is 1 if ts is registered as task work of t.
+	queued atomicbitops.Uint32
+}
+
+// newTaskState returns the taskState of e for t.
+func (e *Event) newTaskState(t *kernel.Task) *taskState {
+	ts := &taskState{
+		s: e,
+		t: t,
+	}
+	if e.clockEvent {
+		if e.attr.Flags&linux.PERF_ATTR_FLAG_EXCLUDE_KERNEL != 0 {
+			ts.clock = t.UserCPUClock()
+		} else {
+			ts.clock = t.CPUClock()
+		}
+		ts.timer = ts.clock.NewTimer(ts)
+	}
+	return ts
+}
+
+// NotifyTimer implements ktime.Listener.NotifyTimer.
+func (ts *taskState) NotifyTimer(exp uint64) {
+	// The timer expires on the goroutine advancing the CPU clock of t, while
+	// samples must be taken on the task goroutine of t. Interrupt t so that
+	// the sample is taken before it returns to user space.
+	ts.queue(exp)
+	ts.t.Interrupt()
+}
+
+// queue records that n sample periods elapsed, and arranges for the sample
+// to be taken when t returns to user space.
+func (ts *taskState) queue(n uint64) {
+	ts.pending.Add(n)
+	if ts.queued.CompareAndSwap(0, 1) {
+		ts.t.RegisterWork(ts)
+	}
+}
+
+// TaskWork implements kernel.TaskWorker.TaskWork.
+func (ts *taskState) TaskWork(t *kernel.Task) {
+	ts.queued.Store(0)
+	if n := ts.pending.Swap(0); n > 0 {
+		ts.s.overflow(t, ts, n)
+	}
+}
+
+// startTaskLocked starts counting the events of ts.
+//
+// +checklocks:e.mu
+func (e *Event) startTaskLocked(ts *taskState) {
+	if ts.clock == nil {
+		return
+	}
+	now := ts.clock.Now()
+	ts.base = now.Nanoseconds()
+	if e.period != 0 {
+		period := time.Duration(e.period)
+		ts.timer.Set(ktime.Setting{
+			Enabled: true,
+			Next:    now.Add(period),
+			Period:  period,
+		}, nil)
+	}
+}
+
+// stopTaskLocked stops counting the events of ts, and folds its count into
+// e.count.
+//
+// +checklocks:e.mu
+func (e *Event) stopTaskLocked(ts *taskState) {
+	if ts.clock == nil {
+		return
+	}
+	ts.timer.Set(ktime.Setting{}, nil)
+	e.count += uint64(ts.clock.Now().Nanoseconds() - ts.base)
+}
+
+// overflow takes a sample of t after n sample periods elapsed.
+//
+// Preconditions: The caller must be running on the task goroutine of t.
+func (e *Event) overflow(t *kernel.Task, ts *taskState, n uint64) {
+	e.mu.Lock()
+	if e.tasks[t] != ts || !e.enabled || (e.cpu >= 0 && t.CPU() != e.cpu) {
+		e.mu.Unlock()
+		return
+	}
+	period := n * e.period
+	disabled := false
+	if e.limit > 0 {
+		e.limit--
+		if e.limit == 0 {
+			e.disableLocked()
+			disabled = true
+		}
+	}
+	var v readValues
+	if e.attr.SampleType&linux.PERF_SAMPLE_READ != 0 {
+		v = e.readValuesLocked()
+	}
+	e.mu.Unlock()
+
+	var callchain []uint64
+	if e.attr.SampleType&linux.PERF_SAMPLE_CALLCHAIN != 0 && e.attr.Flags&linux.PERF_ATTR_FLAG_EXCLUDE_CALLCHAIN_USER == 0 {
+		callchain = userCallchain(t, e.maxStack)
+	}
+	si := e.sampleInfo(t)
+	e.output(si, e.sampleRecord(si, uint64(t.Arch().IP()), callchain, period, v))
+
+	// Each overflow is notified, which sends a signal to the owner of the
+	// file if O_ASYNC is set, as is done by profilers to collect samples.
+	if disabled {
+		e.queue.Notify(waiter.EventHUp)
+	} else {
+		e.queue.Notify(waiter.EventIn)
+	}
+}
+
+// countsConfig returns true if software events of type config, one of
+// linux.PERF_COUNT_SW_*, are counted by e.
+func (e *Event) countsConfig(config uint64) bool {
+	switch config {
+	case linux.PERF_COUNT_SW_PAGE_FAULTS:
+		return e.attr.Config == linux.PERF_COUNT_SW_PAGE_FAULTS || e.attr.Config == linux.PERF_COUNT_SW_PAGE_FAULTS_MIN
+	case linux.PERF_COUNT_SW_CONTEXT_SWITCHES:
+		// Context switches happen in the kernel.
+		return e.attr.Config == config && e.attr.Flags&linux.PERF_ATTR_FLAG_EXCLUDE_KERNEL == 0
+	default:
+		return e.attr.Config == config
+	}
+}
+
+// PerfEventCount implements kernel.PerfEvent.PerfEventCount.
+func (e *Event) PerfEventCount(t *kernel.Task, config uint64, count uint64) {
+	if !e.countsConfig(config) {
+		return
+	}
+	e.mu.Lock()
+	ts := e.tasks[t]
+	if ts == nil || !e.enabled || (e.cpu >= 0 && t.CPU() != e.cpu) {
+		e.mu.Unlock()
+		return
+	}
+	e.count += count
+	var n uint64
+	if e.period != 0 {
+		e.periodLeft += count
+		n = e.periodLeft / e.period
+		e.periodLeft %= e.period
+	}
+	e.mu.Unlock()
+
+	// The task may be in the middle of a syscall, so defer the sample to the
+	// next return to user space like clock events.
+	if n > 0 {
+		ts.queue(n)
+	}
+}
+
+// PerfEventExec implements kernel.PerfEvent.PerfEventExec.
+func (e *Event) PerfEventExec(t *kernel.Task) {
+	e.mu.Lock()
+	if e.attr.Flags&linux.PERF_ATTR_FLAG_ENABLE_ON_EXEC != 0 {
+		e.enableLocked()
+	}
+	enabled := e.enabled
+	e.mu.Unlock()
+	if !enabled {
+		return
+	}
+
+	si := e.sampleInfo(t)
+	if e.attr.Flags&linux.PERF_ATTR_FLAG_COMM != 0 {
+		e.output(si, e.commRecord(si, t.Name(), true))
+	}
+	e.outputMmaps(t, si, hostarch.AddrRange{0, ^hostarch.Addr(0)})
+}
+
+// PerfEventMmap implements kernel.PerfEvent.PerfEventMmap.
+func (e *Event) PerfEventMmap(t *kernel.Task, ar hostarch.AddrRange) {
+	e.mu.Lock()
+	enabled := e.enabled
+	e.mu.Unlock()
+	if enabled {
+		e.outputMmaps(t, e.sampleInfo(t), ar)
+	}
+}
+
+// outputMmaps writes a mmap record for each executable mapping of t in ar,
+// if attr.mmap or attr.mmap2 is set.
+func (e *Event) outputMmaps(t *kernel.Task, si sampleInfo, ar hostarch.AddrRange) {
+	if e.attr.Flags&(linux.PERF_ATTR_FLAG_MMAP|linux.PERF_ATTR_FLAG_MMAP2) == 0 {
+		return
+	}
+	mm := t.MemoryManager()
+	if mm == nil {
+		return
+	}
+	var mappings []mapping
+	mm.ReadMapsDataInto(t, func(start, end hostarch.Addr, perms hostarch.AccessType, private string, offset uint64, devMajor, devMinor uint32, inode uint64, path string) {
+		// The vsyscall page isn't a real mapping, and isn't reported by
+		// Linux either.
+		if !perms.Execute || path == "[vsyscall]" || !ar.Overlaps(hostarch.AddrRange{start, end}) {
+			return
+		}
+		mappings = append(mappings, mapping{
+			start:    start,
+			end:      end,
+			perms:    perms,
+			private:  private == "p",
+			offset:   offset,
+			devMajor: devMajor,
+			devMinor: devMinor,
+			inode:    inode,
+			path:     path,
+		})
+	})
+	for i := range mappings {
+		e.output(si, e.mmapRecord(si, &mappings[i]))
+	}
+}
+
+// PerfEventClone implements kernel.PerfEvent.PerfEventClone.
+func (e *Event) PerfEventClone(t, child *kernel.Task, flags uint64) {
+	inherit := e.attr.Flags&linux.PERF_ATTR_FLAG_INHERIT != 0
+	if e.attr.Flags&linux.PERF_ATTR_FLAG_INHERIT_THREAD != 0 && flags&linux.CLONE_THREAD == 0 {
+		inherit = false
+	}
+	e.mu.Lock()
+	if _, ok := e.tasks[t]; ok && inherit && child.AttachPerfEvent(e) {
+		ts := e.newTaskState(child)
+		e.tasks[child] = ts
+		if e.enabled {
+			e.startTaskLocked(ts)
+		}
+	}
+	enabled := e.enabled
+	e.mu.Unlock()
+
+	if enabled && e.attr.Flags&linux.PERF_ATTR_FLAG_TASK != 0 {
+		psi := e.sampleInfo(t)
+		si := e.sampleInfo(child)
+		si.time = psi.time
+		e.output(psi, e.taskRecord(linux.PERF_RECORD_FORK, si, psi))
+	}
+}
+
+// PerfEventExit implements kernel.PerfEvent.PerfEventExit.
+func (e *Event) PerfEventExit(t *kernel.Task) {
+	e.mu.Lock()
+	ts, ok := e.tasks[t]
+	if !ok {
+		e.mu.Unlock()
+		return
+	}
+	if e.enabled {
+		e.stopTaskLocked(ts)
+	}
+	if ts.timer != nil {
+		ts.timer.Destroy()
+	}
+	delete(e.tasks, t)
+	hup := t == e.target
+	if hup {
+		e.hup = true
+	}
+	enabled := e.enabled
+	e.mu.Unlock()
+
+	if enabled && e.attr.Flags&linux.PERF_ATTR_FLAG_TASK != 0 {
+		si := e.sampleInfo(t)
+		var psi sampleInfo
+		if parent := t.Parent(); parent != nil {
+			psi = e.sampleInfo(parent)
+		}
+		e.output(si, e.taskRecord(linux.PERF_RECORD_EXIT, si, psi))
+	}
+	if hup {
+		e.queue.Notify(waiter.EventHUp)
+	}
+}
@@ -53,6 +53,11 @@ func (fs *filesystem) newSysDir(ctx context.Context, root *auth.Credentials, k *
 			"overflowgid":  fs.newInode(ctx, root, 0444, newStaticFile(fmt.Sprintf("%d\n", auth.OverflowGID))),
 			"overflowuid":  fs.newInode(ctx, root, 0444, newStaticFile(fmt.Sprintf("%d\n", auth.OverflowUID))),
 			"pid_max":      fs.newInode(ctx, root, 0644, newStaticFile(fmt.Sprintf("%d\n", kernel.TasksLimit))),
+			// Kernel profiling is not supported by perf_event_open(2), see
+			// fsimpl/perf.
+			"perf_event_paranoid":        fs.newInode(ctx, root, 0444, newStaticFile("2\n")),
+			"perf_event_max_sample_rate": fs.newInode(ctx, root, 0444, newStaticFile("100000\n")),
+			"perf_event_max_stack":       fs.newInode(ctx, root, 0444, newStaticFile(fmt.Sprintf("%d\n", linux.PERF_MAX_STACK_DEPTH))),
 			"random": fs.newStaticDir(ctx, root, map[string]kernfs.Inode{
 				"boot_id": fs.newInode(ctx, root, 0444, newStaticFile(randUUID())),
 			}),
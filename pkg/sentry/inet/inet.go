@@ -97,6 +97,10 @@ type Stack interface {
 	// Resume resumes the network stack after save.
 	Resume()
 
+	// BeforeResume is called after save if the sandbox continues running.
+	// Unlike Resume, it isn't called if the sandbox exits after save.
+	BeforeResume()
+
 	// Restore restarts the network stack after restore.
 	Restore()
 
@@ -121,6 +125,11 @@ type Stack interface {
 	// for restoring a stack after a save.
 	RestoreCleanupEndpoints([]stack.TransportEndpoint)
 
+	// RestoreResets returns the connected endpoints that were reset after
+	// restore because their connections couldn't be restored, e.g. because
+	// the stack was restored with a different network configuration.
+	RestoreResets() []stack.RestoreResetEndpoint
+
 	// SetForwarding enables or disables packet forwarding between NICs.
 	SetForwarding(protocol tcpip.NetworkProtocolNumber, enable bool) error
 
@@ -181,6 +181,9 @@ func (s *TestStack) ReplaceConfig(_ Stack) {}
 // Resume implements Stack.
 func (s *TestStack) Resume() {}
 
+// BeforeResume implements Stack.
+func (s *TestStack) BeforeResume() {}
+
 // RegisteredEndpoints implements Stack.
 func (s *TestStack) RegisteredEndpoints() []stack.TransportEndpoint {
 	return nil
@@ -194,6 +197,11 @@ func (s *TestStack) CleanupEndpoints() []stack.TransportEndpoint {
 // RestoreCleanupEndpoints implements Stack.
 func (s *TestStack) RestoreCleanupEndpoints([]stack.TransportEndpoint) {}
 
+// RestoreResets implements Stack.
+func (s *TestStack) RestoreResets() []stack.RestoreResetEndpoint {
+	return nil
+}
+
 // SetForwarding implements Stack.
 func (s *TestStack) SetForwarding(protocol tcpip.NetworkProtocolNumber, enable bool) error {
 	s.IPForwarding = enable
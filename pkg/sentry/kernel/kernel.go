@@ -370,6 +370,10 @@ type Kernel struct {
 
 	// UnixSocketOpts stores configuration options for management of unix sockets.
 	UnixSocketOpts transport.UnixSocketOpts
+
+	// syscallStats holds the syscall stats collected by
+	// EnableSyscallStats.
+	syscallStats syscallStats `state:"nosave"`
 }
 
 // InitKernelArgs holds arguments to Init.
@@ -617,6 +621,13 @@ func (k *Kernel) SaveTo(ctx context.Context, w, pagesMetadata io.Writer, pagesFi
 		mf.MarkSavable()
 	}
 
+	if mfOpts.SaveID != "" {
+		// Saved MemoryFiles track writes for later incremental saves, even if
+		// saving fails after they're saved. This is deferred before waiting
+		// for parallel MemoryFile saving below, so it runs after it.
+		defer k.writeProtectMemoryManagers()
+	}
+
 	var (
 		mfSaveWg  sync.WaitGroup
 		mfSaveErr error
@@ -673,7 +684,6 @@ func (k *Kernel) SaveTo(ctx context.Context, w, pagesMetadata io.Writer, pagesFi
 	if mfSaveErr != nil {
 		return mfSaveErr
 	}
-
 	log.Infof("Overall save took [%s].", time.Since(saveStart))
 	return nil
 }
@@ -681,6 +691,9 @@ func (k *Kernel) SaveTo(ctx context.Context, w, pagesMetadata io.Writer, pagesFi
 // BeforeResume is called before the kernel is resumed after save.
 func (k *Kernel) BeforeResume(ctx context.Context) {
 	k.vfs.BeforeResume(ctx)
+	if rootNS := k.rootNetworkNamespace; rootNS != nil && rootNS.Stack() != nil {
+		rootNS.Stack().BeforeResume()
+	}
 }
 
 func (k *Kernel) saveMemoryFiles(ctx context.Context, w, pagesMetadata io.Writer, pagesFile *fd.FD, mfsToSave map[string]*pgalloc.MemoryFile, mfOpts pgalloc.SaveOpts) error {
@@ -729,6 +742,47 @@ func (k *Kernel) invalidateUnsavableMappings(ctx context.Context) error {
 	return nil
 }
 
+// writeProtectMemoryManagers calls mm.MemoryManager.WriteProtect on all
+// MemoryManagers, so that writes to MemoryFiles that track them are reported.
+//
+// Preconditions: The kernel must be paused.
+func (k *Kernel) writeProtectMemoryManagers() {
+	protected := make(map[*mm.MemoryManager]struct{})
+	k.tasks.mu.RLock()
+	defer k.tasks.mu.RUnlock()
+	for t := range k.tasks.Root.tids {
+		// We can skip locking Task.mu here since the kernel is paused.
+		if memMgr := t.image.MemoryManager; memMgr != nil {
+			if _, ok := protected[memMgr]; !ok {
+				memMgr.WriteProtect()
+				protected[memMgr] = struct{}{}
+			}
+		}
+		if r, ok := t.runState.(*runSyscallAfterExecStop); ok {
+			r.image.MemoryManager.WriteProtect()
+		}
+	}
+}
+
+// PreCopyMemory writes the contents of k's main MemoryFile that may have been
+// written since the last call to SaveTo() or PreCopyMemory() with the same
+// saveID to w, without stopping k for longer than it takes to start tracking
+// writes. See pgalloc.MemoryFile.PreCopyTo().
+func (k *Kernel) PreCopyMemory(ctx context.Context, w io.Writer, saveID string) (uint64, error) {
+	k.Pause()
+	paused := true
+	defer func() {
+		if paused {
+			k.Unpause()
+		}
+	}()
+	return k.mf.PreCopyTo(ctx, w, saveID, func() {
+		k.writeProtectMemoryManagers()
+		k.Unpause()
+		paused = false
+	})
+}
+
 // LoadFrom returns a new Kernel loaded from args.
 func (k *Kernel) LoadFrom(ctx context.Context, r io.Reader, loadMFs bool, timeReady chan struct{}, net inet.Stack, clocks sentrytime.Clocks, vfsOpts *vfs.CompleteRestoreOptions, saveRestoreNet bool) error {
 	loadStart := time.Now()
@@ -807,6 +861,9 @@ func (k *Kernel) LoadFrom(ctx context.Context, r io.Reader, loadMFs bool, timeRe
 	}
 
 	tcpip.AsyncLoading.Wait()
+	if st := k.rootNetworkNamespace.Stack(); st != nil {
+		emitNetworkRestoreEvent(st.RestoreResets())
+	}
 
 	log.Infof("Overall load took [%s] after async work", time.Since(loadStart))
 
@@ -16,17 +16,27 @@ package kernel
 
 import (
 	"bufio"
+	"crypto/cipher"
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"strconv"
 
 	"gvisor.dev/gvisor/pkg/cleanup"
+	"gvisor.dev/gvisor/pkg/compressio"
 	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/eventchannel"
 	"gvisor.dev/gvisor/pkg/fd"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	epb "gvisor.dev/gvisor/pkg/sentry/socket/netstack/events_go_proto"
 	"gvisor.dev/gvisor/pkg/state"
+	"gvisor.dev/gvisor/pkg/state/statefile"
 	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
 	"gvisor.dev/gvisor/pkg/timing"
 )
 
@@ -230,6 +240,152 @@ func loadPrivateMemoryFiles(ctx context.Context, r io.Reader, mfmap map[string]*
 	return nil
 }
 
+// savedMemoryFiles are the MemoryFiles of a checkpoint image with a separate
+// pages file.
+type savedMemoryFiles struct {
+	main    *pgalloc.SavedMemoryFile
+	owners  []string
+	private map[string]*pgalloc.SavedMemoryFile
+}
+
+func readSavedMemoryFiles(ctx context.Context, r io.Reader, opts pgalloc.ReadSavedOpts) (*savedMemoryFiles, error) {
+	var (
+		s   savedMemoryFiles
+		off uint64
+		err error
+	)
+	if s.main, err = pgalloc.ReadSavedMemoryFile(ctx, r, &off, opts); err != nil {
+		return nil, fmt.Errorf("failed to read main MemoryFile: %w", err)
+	}
+	var meta privateMemoryFileMetadata
+	if _, err := state.Load(ctx, r, &meta); err != nil {
+		return nil, err
+	}
+	s.owners = meta.owners
+	s.private = make(map[string]*pgalloc.SavedMemoryFile)
+	for _, fsID := range meta.owners {
+		if s.private[fsID], err = pgalloc.ReadSavedMemoryFile(ctx, r, &off, opts); err != nil {
+			return nil, fmt.Errorf("failed to read MemoryFile for fsID %q: %w", fsID, err)
+		}
+	}
+	return &s, nil
+}
+
+// MergeImage is a checkpoint image merged by MergeMemoryFiles.
+type MergeImage struct {
+	// PagesMetadata and PagesFile are the image's pages metadata and pages
+	// files.
+	PagesMetadata io.Reader
+	PagesFile     io.ReaderAt
+
+	// PagesAEAD and PagesCodec are the AEAD and codec that the image's pages
+	// were saved with, if any.
+	PagesAEAD  cipher.AEAD
+	PagesCodec compressio.Codec
+}
+
+// MergeMemoryFiles writes the MemoryFiles of a chain of checkpoint images as
+// those of a single full image to pagesMetadata and pagesFile. Image i+1 must
+// be the parent of the incremental image i, and the last image must be a full
+// image. The merged pages are encrypted and compressed as specified by opts.
+func MergeMemoryFiles(ctx context.Context, images []MergeImage, pagesMetadata, pagesFile io.Writer, opts pgalloc.MergeOpts) error {
+	saved := make([]*savedMemoryFiles, len(images))
+	pages := make([]io.ReaderAt, len(images))
+	for i, image := range images {
+		var err error
+		saved[i], err = readSavedMemoryFiles(ctx, image.PagesMetadata, pgalloc.ReadSavedOpts{
+			Incremental: i != len(images)-1,
+			PagesAEAD:   image.PagesAEAD,
+			PagesCodec:  image.PagesCodec,
+		})
+		if err != nil {
+			return fmt.Errorf("image %d: %w", i, err)
+		}
+		pages[i] = image.PagesFile
+	}
+
+	chain := make([]*pgalloc.SavedMemoryFile, len(saved))
+	for i, s := range saved {
+		chain[i] = s.main
+	}
+	if err := pgalloc.MergeSavedMemoryFiles(ctx, chain, pages, pagesMetadata, pagesFile, opts); err != nil {
+		return fmt.Errorf("failed to merge main MemoryFile: %w", err)
+	}
+	return mergePrivateMemoryFiles(ctx, saved, pages, pagesMetadata, pagesFile, opts)
+}
+
+// SparsePagesFile is the pages file written by WritePreCopiedMemoryFiles.
+type SparsePagesFile interface {
+	io.WriterAt
+	Truncate(size int64) error
+}
+
+// WritePreCopiedMemoryFiles writes the MemoryFiles of an incremental image,
+// whose parent is the main MemoryFile's pages pre-copied by
+// pgalloc.MemoryFile.PreCopyTo(), as those of a full image with sparse pages;
+// see pgalloc.LoadOpts.PagesSparse. metadata and pages are the pages metadata
+// and pages files of the incremental image. pagesFile must be the file that
+// pre-copied pages were written to by pgalloc.ReadPreCopy(), and preCopied
+// the union of the ranges that it returned; only pages saved by the
+// incremental image are written to it, so that loading the result can start
+// without copying pre-copied pages. Private MemoryFiles aren't pre-copied, so
+// the image must hold them in full.
+func WritePreCopiedMemoryFiles(ctx context.Context, metadata io.Reader, pages io.ReaderAt, preCopied []memmap.FileRange, pagesMetadata io.Writer, pagesFile SparsePagesFile) error {
+	image, err := readSavedMemoryFiles(ctx, metadata, pgalloc.ReadSavedOpts{Incremental: true})
+	if err != nil {
+		return err
+	}
+	off, err := image.main.WriteSparse(ctx, pages, pagesMetadata, pagesFile, 0)
+	if err != nil {
+		return fmt.Errorf("failed to write main MemoryFile: %w", err)
+	}
+	// Pre-copied pages beyond the end of the main MemoryFile would be read as
+	// pages of private MemoryFiles.
+	for _, fr := range preCopied {
+		if fr.End > off {
+			return fmt.Errorf("pre-copied pages %v are beyond the end of the main MemoryFile (%d bytes)", fr, off)
+		}
+	}
+	meta := privateMemoryFileMetadata{owners: image.owners}
+	if _, err := state.Save(ctx, pagesMetadata, &meta); err != nil {
+		return err
+	}
+	for _, fsID := range meta.owners {
+		n, err := image.private[fsID].WriteSparse(ctx, pages, pagesMetadata, pagesFile, off)
+		if err != nil {
+			return fmt.Errorf("failed to write MemoryFile for fsID %q: %w", fsID, err)
+		}
+		off += n
+	}
+	// Pages that aren't written are read as zeroes, including at the end of
+	// the file.
+	return pagesFile.Truncate(int64(off))
+}
+
+func mergePrivateMemoryFiles(ctx context.Context, images []*savedMemoryFiles, pages []io.ReaderAt, pagesMetadata, pagesFile io.Writer, opts pgalloc.MergeOpts) error {
+	meta := privateMemoryFileMetadata{owners: images[0].owners}
+	if _, err := state.Save(ctx, pagesMetadata, &meta); err != nil {
+		return err
+	}
+	var chain []*pgalloc.SavedMemoryFile
+	for _, fsID := range meta.owners {
+		// A private MemoryFile that's missing from a parent image was created
+		// since, so it's saved in full by newer images.
+		chain = chain[:0]
+		for _, image := range images {
+			mf, ok := image.private[fsID]
+			if !ok {
+				break
+			}
+			chain = append(chain, mf)
+		}
+		if err := pgalloc.MergeSavedMemoryFiles(ctx, chain, pages[:len(chain)], pagesMetadata, pagesFile, opts); err != nil {
+			return fmt.Errorf("failed to merge MemoryFile for fsID %q: %w", fsID, err)
+		}
+	}
+	return nil
+}
+
 func (k *Kernel) loadMemoryFiles(ctx context.Context, r io.Reader) error {
 	var opts pgalloc.LoadOpts
 	if err := k.mf.LoadFrom(ctx, r, &opts); err != nil {
@@ -270,18 +426,23 @@ type AsyncMFLoader struct {
 // loading the main MemoryFile.
 // If timeline is provided, it will be used to track async page loading.
 // It takes ownership of the timeline, and will end it when done loading all
-// pages.
-func NewAsyncMFLoader(pagesMetadata, pagesFile *fd.FD, mainMF *pgalloc.MemoryFile, timeline *timing.Timeline) *AsyncMFLoader {
+// pages. If enc is not nil, pagesMetadata and pagesFile are decrypted with it;
+// saveID is the save ID that the MemoryFiles were saved with, which selects
+// the key of pagesFile. If pagesCodec is not nil, pagesFile is decompressed
+// with it. If pagesDeduplicated is true, pagesFile is the pack file of the
+// pgalloc.ChunkStore that pages were saved to. If pagesSparse is true,
+// pagesFile was written by WritePreCopiedMemoryFiles.
+func NewAsyncMFLoader(pagesMetadata, pagesFile *fd.FD, enc *statefile.Encryption, saveID string, pagesCodec compressio.Codec, pagesDeduplicated, pagesSparse bool, mainMF *pgalloc.MemoryFile, timeline *timing.Timeline) *AsyncMFLoader {
 	mfl := &AsyncMFLoader{
 		privateMFsChan: make(chan map[string]*pgalloc.MemoryFile, 1),
 	}
 	mfl.metadataWg.Add(1)
 	mfl.loadWg.Add(1)
-	go mfl.backgroundGoroutine(pagesMetadata, pagesFile, mainMF, timeline)
+	go mfl.backgroundGoroutine(pagesMetadata, pagesFile, enc, saveID, pagesCodec, pagesDeduplicated, pagesSparse, mainMF, timeline)
 	return mfl
 }
 
-func (mfl *AsyncMFLoader) backgroundGoroutine(pagesMetadataFD, pagesFileFD *fd.FD, mainMF *pgalloc.MemoryFile, timeline *timing.Timeline) {
+func (mfl *AsyncMFLoader) backgroundGoroutine(pagesMetadataFD, pagesFileFD *fd.FD, enc *statefile.Encryption, saveID string, pagesCodec compressio.Codec, pagesDeduplicated, pagesSparse bool, mainMF *pgalloc.MemoryFile, timeline *timing.Timeline) {
 	defer timeline.End()
 	defer pagesMetadataFD.Close()
 	defer pagesFileFD.Close()
@@ -295,10 +456,26 @@ func (mfl *AsyncMFLoader) backgroundGoroutine(pagesMetadataFD, pagesFileFD *fd.F
 	// avoid making one syscall per read. For the "main" state file, this
 	// buffering is handled by statefile.NewReader() => compressio.Reader
 	// or compressio.NewSimpleReader().
-	pagesMetadata := bufio.NewReader(pagesMetadataFD)
+	var pagesMetadataSrc io.Reader = pagesMetadataFD
+	var pagesAEAD cipher.AEAD
+	if enc != nil {
+		var err error
+		if pagesMetadataSrc, err = enc.NewStreamReader(pagesMetadataFD, statefile.EncryptionPurposePagesMetadata); err == nil {
+			pagesAEAD, err = enc.PagesAEAD(saveID)
+		}
+		if err != nil {
+			mfl.metadataErr = err
+			return
+		}
+	}
+	pagesMetadata := bufio.NewReader(pagesMetadataSrc)
 
 	opts := pgalloc.LoadOpts{
-		PagesFile: pagesFileFD,
+		PagesAEAD:         pagesAEAD,
+		PagesCodec:        pagesCodec,
+		PagesDeduplicated: pagesDeduplicated,
+		PagesSparse:       pagesSparse,
+		PagesFile:         pagesFileFD,
 		OnAsyncPageLoadStart: func(mf *pgalloc.MemoryFile) {
 			mfl.loadWg.Add(1)
 			log.Infof("Starting async page load for %p", mf)
@@ -369,3 +546,31 @@ func (mfl *AsyncMFLoader) Wait() error {
 	mfl.loadWg.Wait()
 	return errors.Join(mfl.loadErrs...)
 }
+
+// emitNetworkRestoreEvent logs the restored connected endpoints that were
+// reset because their connections couldn't be restored, e.g. because the
+// sandbox was restored with a different network configuration, and emits a
+// SentryNetworkRestoreEvent listing them.
+func emitNetworkRestoreEvent(resets []stack.RestoreResetEndpoint) {
+	if len(resets) == 0 {
+		return
+	}
+	ev := &epb.SentryNetworkRestoreEvent{}
+	for _, r := range resets {
+		protocol := strconv.Itoa(int(r.TransProto))
+		if r.TransProto == header.TCPProtocolNumber {
+			protocol = "tcp"
+		}
+		s := &epb.SentryNetworkRestoreEvent_ResetSocket{
+			Protocol:      protocol,
+			LocalAddress:  net.JoinHostPort(r.ID.LocalAddress.String(), strconv.Itoa(int(r.ID.LocalPort))),
+			RemoteAddress: net.JoinHostPort(r.ID.RemoteAddress.String(), strconv.Itoa(int(r.ID.RemotePort))),
+			Error:         r.Err.String(),
+		}
+		log.Warningf("Restore reset %s connection %s -> %s: %s", s.Protocol, s.LocalAddress, s.RemoteAddress, s.Error)
+		ev.ResetSockets = append(ev.ResetSockets, s)
+	}
+	if err := eventchannel.Emit(ev); err != nil {
+		log.Warningf("Failed to emit network restore event: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/eventchannel"
+	epb "gvisor.dev/gvisor/pkg/sentry/socket/netstack/events_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// recordingEmitter is an eventchannel.Emitter that records the
+// SentryNetworkRestoreEvents emitted to it.
+type recordingEmitter struct {
+	mu     sync.Mutex
+	events []*epb.SentryNetworkRestoreEvent
+}
+
+// Emit implements eventchannel.Emitter.Emit.
+func (e *recordingEmitter) Emit(msg proto.Message) (bool, error) {
+	if ev, ok := msg.(*epb.SentryNetworkRestoreEvent); ok {
+		e.mu.Lock()
+		e.events = append(e.events, ev)
+		e.mu.Unlock()
+	}
+	return false, nil
+}
+
+// Close implements eventchannel.Emitter.Close.
+func (e *recordingEmitter) Close() error {
+	return nil
+}
+
+func TestEmitNetworkRestoreEvent(t *testing.T) {
+	e := &recordingEmitter{}
+	eventchannel.AddEmitter(e)
+
+	// Nothing is emitted if no connections were reset.
+	emitNetworkRestoreEvent(nil)
+
+	emitNetworkRestoreEvent([]stack.RestoreResetEndpoint{
+		{
+			TransProto: header.TCPProtocolNumber,
+			ID: stack.TransportEndpointID{
+				LocalAddress:  tcpip.AddrFrom4([4]byte{10, 0, 0, 1}),
+				LocalPort:     1234,
+				RemoteAddress: tcpip.AddrFrom4([4]byte{10, 0, 0, 2}),
+				RemotePort:    80,
+			},
+			Err: &tcpip.ErrHostUnreachable{},
+		},
+		{
+			TransProto: header.UDPProtocolNumber,
+			ID: stack.TransportEndpointID{
+				LocalAddress:  tcpip.AddrFrom16([16]byte{15: 1}),
+				LocalPort:     53,
+				RemoteAddress: tcpip.AddrFrom16([16]byte{15: 2}),
+				RemotePort:    5353,
+			},
+			Err: &tcpip.ErrConnectionReset{},
+		},
+	})
+
+	want := &epb.SentryNetworkRestoreEvent{
+		ResetSockets: []*epb.SentryNetworkRestoreEvent_ResetSocket{
+			{
+				Protocol:      "tcp",
+				LocalAddress:  "10.0.0.1:1234",
+				RemoteAddress: "10.0.0.2:80",
+				Error:         (&tcpip.ErrHostUnreachable{}).String(),
+			},
+			{
+				Protocol:      "17",
+				LocalAddress:  "[::1]:53",
+				RemoteAddress: "[::2]:5353",
+				Error:         (&tcpip.ErrConnectionReset{}).String(),
+			},
+		},
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.events) != 1 {
+		t.Fatalf("got %d events, want 1: %v", len(e.events), e.events)
+	}
+	if !proto.Equal(e.events[0], want) {
+		t.Errorf("got event %v, want %v", e.events[0], want)
+	}
+}
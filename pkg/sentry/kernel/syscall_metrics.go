@@ -0,0 +1,230 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux/errno"
+	"gvisor.dev/gvisor/pkg/abi/sentry"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/metric"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// maxSyscallMetricContainers is the number of containers whose syscalls are
+// reported separately by syscall metrics. Syscalls of other containers share
+// otherContainerField.
+const maxSyscallMetricContainers = 8
+
+var (
+	// syscallMetricsInit ensures that syscall metrics are only registered
+	// once.
+	syscallMetricsInit sync.Once
+
+	// syscallLatency tracks the time spent executing each syscall, from the
+	// moment the sentry starts handling it until it returns, broken down by
+	// syscall name and container.
+	syscallLatency *metric.TimerMetric
+
+	// syscallErrors counts the number of times each syscall failed, broken
+	// down by syscall name and container.
+	syscallErrors *metric.Uint64Metric
+
+	// syscallErrnos counts the number of syscalls that failed, broken down by
+	// errno. It's separate from syscallErrors to bound the number of field
+	// combinations.
+	syscallErrnos *metric.Uint64Metric
+
+	// unknownSyscallField is the syscall field value used for syscall
+	// numbers that are not in the syscall table.
+	unknownSyscallField = &metric.FieldValue{"unknown"}
+
+	// errnoFields maps errno values to their field value in syscallErrnos.
+	// Errnos that are out of range map to otherErrnoField. Errnos used
+	// internally to restart syscalls, starting at ERESTARTSYS, are not
+	// reported.
+	errnoFields [errno.ERESTARTSYS]*metric.FieldValue
+
+	// otherErrnoField is the errno field value used for errnos that don't
+	// have a name.
+	otherErrnoField = &metric.FieldValue{"other"}
+
+	// containerFields are the container field values of syscall metrics, in
+	// the order they're assigned to containers.
+	containerFields = func() []*metric.FieldValue {
+		fields := make([]*metric.FieldValue, maxSyscallMetricContainers)
+		for i := range fields {
+			fields[i] = &metric.FieldValue{strconv.Itoa(i)}
+		}
+		return fields
+	}()
+
+	// otherContainerField is the container field value used once all of
+	// containerFields are assigned.
+	otherContainerField = &metric.FieldValue{"other"}
+
+	// syscallMetricContainersMu protects syscallMetricContainers.
+	syscallMetricContainersMu sync.Mutex
+
+	// syscallMetricContainers maps container IDs to their container field
+	// value.
+	syscallMetricContainers = make(map[string]*metric.FieldValue)
+)
+
+// syscallMetricFields returns the syscall field values of all syscalls in
+// tables. Syscalls with the same name share the same field value across
+// tables.
+func syscallMetricFields(tables []*SyscallTable) ([]*metric.FieldValue, map[*SyscallTable]*[sentry.MaxSyscallNum + 1]*metric.FieldValue) {
+	byName := make(map[string]*metric.FieldValue)
+	allowedValues := []*metric.FieldValue{unknownSyscallField}
+	perTable := make(map[*SyscallTable]*[sentry.MaxSyscallNum + 1]*metric.FieldValue, len(tables))
+	for _, s := range tables {
+		fields := &[sentry.MaxSyscallNum + 1]*metric.FieldValue{}
+		for i := range fields {
+			fields[i] = unknownSyscallField
+		}
+		for sysno, sc := range s.Table {
+			if sysno > sentry.MaxSyscallNum || len(sc.Name) == 0 {
+				continue
+			}
+			f, ok := byName[sc.Name]
+			if !ok {
+				f = &metric.FieldValue{sc.Name}
+				byName[sc.Name] = f
+				allowedValues = append(allowedValues, f)
+			}
+			fields[sysno] = f
+		}
+		perTable[s] = fields
+	}
+	return allowedValues, perTable
+}
+
+// errnoMetricFields initializes errnoFields and returns all errno field
+// values.
+func errnoMetricFields() []*metric.FieldValue {
+	allowedValues := []*metric.FieldValue{otherErrnoField}
+	for i := range errnoFields {
+		name := unix.ErrnoName(unix.Errno(i))
+		if len(name) == 0 {
+			errnoFields[i] = otherErrnoField
+			continue
+		}
+		f := &metric.FieldValue{name}
+		errnoFields[i] = f
+		allowedValues = append(allowedValues, f)
+	}
+	return allowedValues
+}
+
+// EnableSyscallMetrics registers the syscall latency and error metrics, and
+// enables their collection in all registered syscall tables. Metric collection
+// adds a small overhead to every syscall, so it's disabled by default.
+//
+// It must be called after all syscall tables have been registered and before
+// metric.Initialize.
+func EnableSyscallMetrics() {
+	syscallMetricsInit.Do(func() {
+		syscallValues, perTable := syscallMetricFields(allSyscallTables)
+		errnoValues := errnoMetricFields()
+		containerValues := append(containerFields[:len(containerFields):len(containerFields)], otherContainerField)
+		syscallLatency = metric.MustCreateNewTimerMetric("/syscalls/latency",
+			metric.NewDurationBucketer(20, time.Microsecond, 10*time.Second),
+			"Time spent executing syscalls, broken down by syscall name and container. It includes the time the syscall was blocked.",
+			metric.NewField("syscall", syscallValues...),
+			metric.NewField("container", containerValues...))
+		syscallErrors = metric.MustCreateNewUint64Metric("/syscalls/errors",
+			metric.Uint64Metadata{
+				Cumulative:  true,
+				Description: "Number of syscalls that failed, broken down by syscall name and container.",
+				Fields: []metric.Field{
+					metric.NewField("syscall", syscallValues...),
+					metric.NewField("container", containerValues...),
+				},
+			})
+		syscallErrnos = metric.MustCreateNewUint64Metric("/syscalls/errnos",
+			metric.Uint64Metadata{
+				Cumulative:  true,
+				Description: "Number of syscalls that failed, broken down by errno.",
+				Fields: []metric.Field{
+					metric.NewField("errno", errnoValues...),
+				},
+			})
+		for s, fields := range perTable {
+			s.metricFields = fields
+			s.FeatureEnable.EnableAll(SyscallMetricsEnable)
+		}
+	})
+}
+
+// metricField returns the syscall field value of sysno in syscall metrics.
+func (s *SyscallTable) metricField(sysno uintptr) *metric.FieldValue {
+	if s.metricFields == nil || sysno > sentry.MaxSyscallNum {
+		return unknownSyscallField
+	}
+	return s.metricFields[sysno]
+}
+
+// metricContainerField returns the container field value of t in syscall
+// metrics. Containers are assigned field values in the order they first make
+// a syscall.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) metricContainerField() *metric.FieldValue {
+	if t.metricsContainer != nil {
+		return t.metricsContainer
+	}
+	syscallMetricContainersMu.Lock()
+	defer syscallMetricContainersMu.Unlock()
+	f, ok := syscallMetricContainers[t.containerID]
+	if !ok {
+		f = otherContainerField
+		if n := len(syscallMetricContainers); n < len(containerFields) {
+			f = containerFields[n]
+		}
+		syscallMetricContainers[t.containerID] = f
+		log.Infof("Syscall metrics of container %q (%q) have container=%q", t.containerID, t.k.ContainerName(t.containerID), f.Value)
+	}
+	t.metricsContainer = f
+	return f
+}
+
+// recordSyscallMetrics records the latency and result of a syscall made by t
+// that started at startNs.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (s *SyscallTable) recordSyscallMetrics(t *Task, sysno uintptr, startNs int64, err error) {
+	syscall := s.metricField(sysno)
+	container := t.metricContainerField()
+	syscallLatency.AddSample(metric.CheapNowNano()-startNs, syscall, container)
+	if err == nil {
+		return
+	}
+	e := ExtractErrno(err, int(sysno))
+	if e >= errno.ERESTARTSYS && e <= errno.ERESTART_RESTARTBLOCK {
+		// The syscall is restarted, or fails with EINTR, once the
+		// interrupting signal is handled.
+		return
+	}
+	syscallErrors.Increment(syscall, container)
+	errnoField := otherErrnoField
+	if e > 0 && e < len(errnoFields) {
+		errnoField = errnoFields[e]
+	}
+	syscallErrnos.Increment(errnoField)
+}
@@ -0,0 +1,133 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/metric"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+)
+
+func TestSyscallMetricFields(t *testing.T) {
+	amd64 := &SyscallTable{
+		OS:   abi.Linux,
+		Arch: arch.AMD64,
+		Table: map[uintptr]Syscall{
+			0: {Name: "read"},
+			1: {Name: "write"},
+			2: {Name: "open"},
+		},
+	}
+	arm64 := &SyscallTable{
+		OS:   abi.Linux,
+		Arch: arch.ARM64,
+		Table: map[uintptr]Syscall{
+			63: {Name: "read"},
+			64: {Name: "write"},
+		},
+	}
+	allowed, perTable := syscallMetricFields([]*SyscallTable{amd64, arm64})
+
+	var names []string
+	for _, f := range allowed {
+		names = append(names, f.Value)
+	}
+	if len(names) != 4 {
+		t.Errorf("wrong allowed values, got: %v, want: [unknown read write open] in any order", names)
+	}
+
+	for _, tc := range []struct {
+		table *SyscallTable
+		sysno uintptr
+		want  string
+	}{
+		{table: amd64, sysno: 0, want: "read"},
+		{table: amd64, sysno: 2, want: "open"},
+		{table: amd64, sysno: 63, want: "unknown"},
+		{table: arm64, sysno: 63, want: "read"},
+		{table: arm64, sysno: 0, want: "unknown"},
+	} {
+		if got := perTable[tc.table][tc.sysno].Value; got != tc.want {
+			t.Errorf("%v sysno %d: got %q, want %q", tc.table.Arch, tc.sysno, got, tc.want)
+		}
+	}
+	if perTable[amd64][0] != perTable[arm64][63] {
+		t.Errorf("syscalls with the same name must share the field value")
+	}
+}
+
+func TestErrnoMetricFields(t *testing.T) {
+	errnoMetricFields()
+	for _, tc := range []struct {
+		errno int
+		want  string
+	}{
+		{errno: int(unix.EPERM), want: "EPERM"},
+		{errno: int(unix.EAGAIN), want: "EAGAIN"},
+		{errno: 200, want: "other"},
+	} {
+		if got := errnoFields[tc.errno].Value; got != tc.want {
+			t.Errorf("errno %d: got %q, want %q", tc.errno, got, tc.want)
+		}
+	}
+}
+
+func TestRecordSyscallMetrics(t *testing.T) {
+	EnableSyscallMetrics()
+	s := &SyscallTable{}
+	k := &Kernel{}
+
+	// Containers are assigned field values in the order they first make a
+	// syscall, and share a field value once they run out.
+	var tasks []*Task
+	for i := 0; i < maxSyscallMetricContainers+2; i++ {
+		tasks = append(tasks, &Task{k: k, containerID: fmt.Sprintf("test-container-%d", i)})
+	}
+	for i, task := range tasks {
+		want := otherContainerField
+		if i < maxSyscallMetricContainers {
+			want = containerFields[i]
+		}
+		if got := task.metricContainerField(); got != want {
+			t.Errorf("container %d: got field value %q, want %q", i, got.Value, want.Value)
+		}
+	}
+	sibling := &Task{k: k, containerID: tasks[1].containerID}
+	if got := sibling.metricContainerField(); got != containerFields[1] {
+		t.Errorf("task of container 1: got field value %q, want %q", got.Value, containerFields[1].Value)
+	}
+
+	task := tasks[0]
+	container := containerFields[0]
+	failures := syscallErrors.Value(unknownSyscallField, container)
+	eperms := syscallErrnos.Value(errnoFields[unix.EPERM])
+	s.recordSyscallMetrics(task, 0, metric.CheapNowNano(), nil)
+	s.recordSyscallMetrics(task, 0, metric.CheapNowNano(), linuxerr.ERESTARTSYS)
+	if got := syscallErrors.Value(unknownSyscallField, container); got != failures {
+		t.Errorf("got %d errors after a successful and a restarted syscall, want %d", got, failures)
+	}
+	s.recordSyscallMetrics(task, 0, metric.CheapNowNano(), linuxerr.EPERM)
+	if got := syscallErrors.Value(unknownSyscallField, container); got != failures+1 {
+		t.Errorf("got %d errors after a failed syscall, want %d", got, failures+1)
+	}
+	if got := syscallErrnos.Value(errnoFields[unix.EPERM]); got != eperms+1 {
+		t.Errorf("got %d EPERM errors after a failed syscall, want %d", got, eperms+1)
+	}
+}
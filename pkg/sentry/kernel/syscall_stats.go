@@ -0,0 +1,240 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"sort"
+
+	"gvisor.dev/gvisor/pkg/abi/sentry"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/metric"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// SyscallStat holds the statistics of a syscall, similar to `strace -c`.
+type SyscallStat struct {
+	// Name is the syscall name.
+	Name string `json:"name"`
+
+	// Calls is the number of times the syscall was executed.
+	Calls uint64 `json:"calls"`
+
+	// Errors is the number of times the syscall failed.
+	Errors uint64 `json:"errors"`
+
+	// TimeNs is the cumulative time spent executing the syscall, including
+	// the time it was blocked.
+	TimeNs int64 `json:"time_ns"`
+}
+
+// syscallStats collects SyscallStat per container while syscall stats are
+// enabled. See Kernel.EnableSyscallStats.
+//
+// Syscalls are counted in per-syscall atomic counters of each container, which
+// tasks cache, so that recording a syscall doesn't serialize tasks. Counters
+// are aggregated by syscall name when stats are read.
+type syscallStats struct {
+	// gen is incremented each time stats are enabled, which invalidates the
+	// counters cached by tasks.
+	gen atomicbitops.Uint64
+
+	mu sync.Mutex
+
+	// enabled is true while syscall stats are collected.
+	//
+	// +checklocks:mu
+	enabled bool
+
+	// containers holds the counters of each container and syscall table.
+	//
+	// +checklocks:mu
+	containers map[syscallStatsKey]*syscallCounters
+}
+
+// syscallStatsKey identifies the syscalls counted by a syscallCounters.
+type syscallStatsKey struct {
+	cid   string
+	table *SyscallTable
+}
+
+// syscallCounters counts the syscalls of a syscall table executed by a
+// container.
+type syscallCounters struct {
+	// gen is the value of syscallStats.gen when the counters were created.
+	gen uint64
+
+	// table is the syscall table of the counted syscalls.
+	table *SyscallTable
+
+	// sysnos is indexed by syscall number. Syscall numbers greater than
+	// sentry.MaxSyscallNum are counted in the last element.
+	sysnos [sentry.MaxSyscallNum + 2]sysnoCounters
+}
+
+// sysnoCounters counts the executions of a syscall.
+type sysnoCounters struct {
+	calls  atomicbitops.Uint64
+	errors atomicbitops.Uint64
+	timeNs atomicbitops.Int64
+}
+
+// EnableSyscallStats starts collecting syscall stats for all containers,
+// discarding stats collected previously.
+func (k *Kernel) EnableSyscallStats() {
+	k.syscallStats.mu.Lock()
+	defer k.syscallStats.mu.Unlock()
+	k.syscallStats.enabled = true
+	k.syscallStats.containers = make(map[syscallStatsKey]*syscallCounters)
+	k.syscallStats.gen.Add(1)
+	for _, s := range SyscallTables() {
+		s.FeatureEnable.EnableAll(SyscallStatsEnable)
+	}
+}
+
+// DisableSyscallStats stops collecting syscall stats. Stats collected so far
+// are kept until the next call to EnableSyscallStats.
+func (k *Kernel) DisableSyscallStats() {
+	k.syscallStats.mu.Lock()
+	defer k.syscallStats.mu.Unlock()
+	k.syscallStats.enabled = false
+	for _, s := range SyscallTables() {
+		s.FeatureEnable.Enable(SyscallStatsEnable, nil, false)
+	}
+}
+
+// SyscallStatsEnabled returns true if syscall stats are being collected.
+func (k *Kernel) SyscallStatsEnabled() bool {
+	k.syscallStats.mu.Lock()
+	defer k.syscallStats.mu.Unlock()
+	return k.syscallStats.enabled
+}
+
+// SyscallStats returns the syscall stats collected for the given container,
+// sorted by descending cumulative time.
+func (k *Kernel) SyscallStats(cid string) []SyscallStat {
+	k.syscallStats.mu.Lock()
+	defer k.syscallStats.mu.Unlock()
+	return k.syscallStats.containerStatsLocked(cid, false /* take */)
+}
+
+// TakeSyscallStats is like SyscallStats, but also discards the stats of the
+// container. It's used when the container exits.
+func (k *Kernel) TakeSyscallStats(cid string) []SyscallStat {
+	k.syscallStats.mu.Lock()
+	defer k.syscallStats.mu.Unlock()
+	return k.syscallStats.containerStatsLocked(cid, true /* take */)
+}
+
+// containerStatsLocked returns the stats of container cid, aggregated by
+// syscall name and sorted by descending cumulative time. If take is true, the
+// stats are also discarded.
+//
+// +checklocks:s.mu
+func (s *syscallStats) containerStatsLocked(cid string, take bool) []SyscallStat {
+	byName := make(map[string]*SyscallStat)
+	for key, counters := range s.containers {
+		if key.cid != cid {
+			continue
+		}
+		if take {
+			delete(s.containers, key)
+		}
+		for sysno := range counters.sysnos {
+			c := &counters.sysnos[sysno]
+			calls := c.calls.Load()
+			if calls == 0 {
+				continue
+			}
+			name := "unknown"
+			if sysno <= sentry.MaxSyscallNum {
+				name = key.table.LookupName(uintptr(sysno))
+			}
+			stat, ok := byName[name]
+			if !ok {
+				stat = &SyscallStat{Name: name}
+				byName[name] = stat
+			}
+			stat.Calls += calls
+			stat.Errors += c.errors.Load()
+			stat.TimeNs += c.timeNs.Load()
+		}
+	}
+	return sortedSyscallStats(byName)
+}
+
+func sortedSyscallStats(stats map[string]*SyscallStat) []SyscallStat {
+	if len(stats) == 0 {
+		return nil
+	}
+	sorted := make([]SyscallStat, 0, len(stats))
+	for _, stat := range stats {
+		sorted = append(sorted, *stat)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].TimeNs != sorted[j].TimeNs {
+			return sorted[i].TimeNs > sorted[j].TimeNs
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// counters returns the counters of the syscalls of table executed by container
+// cid, or nil if stats are disabled.
+func (s *syscallStats) counters(cid string, table *SyscallTable) *syscallCounters {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.enabled {
+		return nil
+	}
+	key := syscallStatsKey{cid: cid, table: table}
+	counters, ok := s.containers[key]
+	if !ok {
+		counters = &syscallCounters{gen: s.gen.Load(), table: table}
+		s.containers[key] = counters
+	}
+	return counters
+}
+
+// record adds an execution of syscall sysno to c.
+func (c *syscallCounters) record(sysno uintptr, durationNs int64, failed bool) {
+	if sysno > sentry.MaxSyscallNum {
+		sysno = sentry.MaxSyscallNum + 1
+	}
+	counters := &c.sysnos[sysno]
+	counters.calls.Add(1)
+	if failed {
+		counters.errors.Add(1)
+	}
+	counters.timeNs.Add(durationNs)
+}
+
+// recordSyscallStats records the execution of syscall sysno that started at
+// startNs in the stats of the task's container.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) recordSyscallStats(sysno uintptr, startNs int64, err error) {
+	durationNs := metric.CheapNowNano() - startNs
+	counters := t.syscallCounters
+	if counters == nil || counters.gen != t.k.syscallStats.gen.Load() || counters.table != t.SyscallTable() {
+		counters = t.k.syscallStats.counters(t.ContainerID(), t.SyscallTable())
+		if counters == nil {
+			// Raced with DisableSyscallStats.
+			return
+		}
+		t.syscallCounters = counters
+	}
+	counters.record(sysno, durationNs, err != nil)
+}
@@ -0,0 +1,111 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSyscallStats(t *testing.T) {
+	k := &Kernel{}
+	table := &SyscallTable{
+		Table: map[uintptr]Syscall{
+			0: {Name: "read"},
+			1: {Name: "write"},
+			3: {Name: "close"},
+		},
+	}
+	// Another table with the same syscall name at a different number, as
+	// for different architectures.
+	table2 := &SyscallTable{
+		Table: map[uintptr]Syscall{
+			63: {Name: "read"},
+		},
+	}
+
+	// Nothing is recorded until stats are enabled.
+	if c := k.syscallStats.counters("a", table); c != nil {
+		t.Errorf("counters returned while disabled")
+	}
+
+	k.EnableSyscallStats()
+	a := k.syscallStats.counters("a", table)
+	a.record(0, 10, false)
+	a.record(0, 15, true)
+	a.record(1, 100, false)
+	a.record(3, 25, false)
+	k.syscallStats.counters("a", table2).record(63, 5, false)
+	k.syscallStats.counters("b", table).record(0, 1, true)
+	if c := k.syscallStats.counters("a", table); c != a {
+		t.Errorf("counters of the same container and table differ")
+	}
+
+	want := []SyscallStat{
+		{Name: "write", Calls: 1, TimeNs: 100},
+		{Name: "read", Calls: 3, Errors: 1, TimeNs: 30},
+		{Name: "close", Calls: 1, TimeNs: 25},
+	}
+	if got := k.SyscallStats("a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("SyscallStats(a) got: %+v, want: %+v", got, want)
+	}
+
+	wantB := []SyscallStat{{Name: "read", Calls: 1, Errors: 1, TimeNs: 1}}
+	if got := k.TakeSyscallStats("b"); !reflect.DeepEqual(got, wantB) {
+		t.Errorf("TakeSyscallStats(b) got: %+v, want: %+v", got, wantB)
+	}
+	if got := k.SyscallStats("b"); got != nil {
+		t.Errorf("SyscallStats(b) after TakeSyscallStats got: %+v, want: nil", got)
+	}
+
+	// Enabling stats again discards them, and invalidates counters cached
+	// by tasks.
+	k.EnableSyscallStats()
+	if got := k.SyscallStats("a"); got != nil {
+		t.Errorf("SyscallStats(a) after EnableSyscallStats got: %+v, want: nil", got)
+	}
+	if c := k.syscallStats.counters("a", table); c.gen == a.gen {
+		t.Errorf("counters have the same generation after EnableSyscallStats")
+	}
+}
+
+func TestSyscallStatsConcurrent(t *testing.T) {
+	k := &Kernel{}
+	table := &SyscallTable{Table: map[uintptr]Syscall{0: {Name: "read"}}}
+	k.EnableSyscallStats()
+
+	const (
+		goroutines = 8
+		calls      = 1000
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := k.syscallStats.counters("a", table)
+			for j := 0; j < calls; j++ {
+				c.record(0, 1, j%2 == 0)
+			}
+		}()
+	}
+	wg.Wait()
+
+	want := []SyscallStat{{Name: "read", Calls: goroutines * calls, Errors: goroutines * calls / 2, TimeNs: goroutines * calls}}
+	if got := k.SyscallStats("a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("SyscallStats(a) got: %+v, want: %+v", got, want)
+	}
+}
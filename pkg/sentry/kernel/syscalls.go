@@ -104,6 +104,9 @@ const (
 	// StraceEnableEvent enables syscall event tracing.
 	StraceEnableEvent
 
+	// StraceEnableJSON enables syscall tracing in JSON format.
+	StraceEnableJSON
+
 	// ExternalBeforeEnable enables the external hook before syscall execution.
 	ExternalBeforeEnable
 
@@ -121,10 +124,18 @@ const (
 
 	// SecCheckRawExit represents raw/exit syscall seccheck event.
 	SecCheckRawExit
+
+	// SyscallMetricsEnable enables syscall latency and error metrics. See
+	// EnableSyscallMetrics.
+	SyscallMetricsEnable
+
+	// SyscallStatsEnable enables per-container syscall stats. See
+	// Kernel.EnableSyscallStats.
+	SyscallStatsEnable
 )
 
-// StraceEnableBits combines both strace log and event flags.
-const StraceEnableBits = StraceEnableLog | StraceEnableEvent
+// StraceEnableBits combines all strace flags.
+const StraceEnableBits = StraceEnableLog | StraceEnableEvent | StraceEnableJSON
 
 // SyscallFlagsTable manages a set of enable/disable bit fields on a per-syscall
 // basis.
@@ -328,6 +339,11 @@ type SyscallTable struct {
 
 	// FeatureEnable stores the strace and one-shot enable bits.
 	FeatureEnable SyscallFlagsTable
+
+	// metricFields holds the syscall field values used by syscall metrics,
+	// indexed by syscall number. It's nil until EnableSyscallMetrics is
+	// called.
+	metricFields *[sentry.MaxSyscallNum + 1]*metric.FieldValue
 }
 
 // MaxSysno returns the largest system call number.
@@ -281,6 +281,16 @@ type Task struct {
 	// NOTE: cgroups can be used to track this when implemented.
 	containerID string
 
+	// metricsContainer is the container field value of the task in syscall
+	// metrics, or nil if it hasn't been looked up. It's only accessed by the
+	// task goroutine.
+	metricsContainer *metric.FieldValue `state:"nosave"`
+
+	// syscallCounters holds the syscall stats counters of the task's
+	// container, or nil if they haven't been looked up. It's only accessed by
+	// the task goroutine.
+	syscallCounters *syscallCounters `state:"nosave"`
+
 	// mu protects some of the following fields.
 	mu taskMutex `state:"nosave"`
 
@@ -610,6 +620,25 @@ type Task struct {
 	// kcov is exclusive to the task goroutine.
 	kcov *Kcov
 
+	// perfEventsMu protects perfEvents and perfEventsExited.
+	perfEventsMu sync.Mutex `state:"nosave"`
+
+	// perfEvents are the perf events attached to the task. See
+	// AttachPerfEvent.
+	//
+	// +checklocks:perfEventsMu
+	perfEvents []PerfEvent
+
+	// perfEventsExited is set when the task exits, after which perf events
+	// can no longer be attached.
+	//
+	// +checklocks:perfEventsMu
+	perfEventsExited bool
+
+	// numPerfEvents is len(perfEvents), and is used to avoid acquiring
+	// perfEventsMu when no perf events are attached.
+	numPerfEvents atomicbitops.Int32
+
 	// cgroups is the set of cgroups this task belongs to. This may be empty if
 	// no cgroup controllers are enabled. Protected by mu.
 	//
@@ -841,6 +870,8 @@ func (t *Task) ContainerID() string {
 // is different from when it was saved.
 func (t *Task) RestoreContainerID(cid string) {
 	t.containerID = cid
+	t.metricsContainer = nil
+	t.syscallCounters = nil
 }
 
 // OOMScoreAdj gets the task's thread group's OOM score adjustment.
@@ -19,6 +19,7 @@ import (
 	"runtime/trace"
 	"time"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/ktime"
 	"gvisor.dev/gvisor/pkg/sync"
@@ -216,6 +217,7 @@ func (t *Task) block(C <-chan struct{}, timerChan <-chan struct{}) error {
 // prepareSleep prepares to sleep.
 func (t *Task) prepareSleep() {
 	t.assertTaskGoroutine()
+	t.perfEventCount(linux.PERF_COUNT_SW_CONTEXT_SWITCHES, 1)
 	t.p.PrepareSleep()
 	t.Deactivate()
 	t.accountTaskGoroutineEnter(TaskGoroutineBlockedInterruptible)
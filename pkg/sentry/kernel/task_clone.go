@@ -304,20 +304,22 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 
 	if seccheck.Global.Enabled(seccheck.PointClone) {
 		mask, info := getCloneSeccheckInfo(t, nt, args.Flags)
-		if err := seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
-			return c.Clone(t, mask, info)
-		}); err != nil {
-			// nt has been visible to the rest of the system since NewTask, so
-			// it may be blocking execve or a group stop, have been notified
-			// for group signal delivery, had children reparented to it, etc.
-			// Thus we can't just drop it on the floor. Instead, instruct the
-			// task goroutine to exit immediately, as quietly as possible.
-			nt.exitTracerNotified = true
-			nt.exitTracerAcked = true
-			nt.exitParentNotified = true
-			nt.exitParentAcked = true
-			nt.runState = (*runExitMain)(nil)
-			return 0, nil, err
+		if mask.Filter.Match(info) {
+			if err := seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
+				return c.Clone(t, mask, info)
+			}); err != nil {
+				// nt has been visible to the rest of the system since NewTask, so
+				// it may be blocking execve or a group stop, have been notified
+				// for group signal delivery, had children reparented to it, etc.
+				// Thus we can't just drop it on the floor. Instead, instruct the
+				// task goroutine to exit immediately, as quietly as possible.
+				nt.exitTracerNotified = true
+				nt.exitTracerAcked = true
+				nt.exitParentNotified = true
+				nt.exitParentAcked = true
+				nt.runState = (*runExitMain)(nil)
+				return 0, nil, err
+			}
 		}
 	}
 
@@ -348,6 +350,7 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 	}
 
 	t.traceCloneEvent(tid)
+	t.perfEventClone(nt, args.Flags)
 	kind := ptraceCloneKindClone
 	if args.Flags&linux.CLONE_VFORK != 0 {
 		kind = ptraceCloneKindVfork
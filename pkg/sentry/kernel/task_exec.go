@@ -105,10 +105,12 @@ func (t *Task) Execve(newImage *TaskImage, argv, env []string, executable *vfs.F
 	// We can't clearly hold kernel package locks while stat'ing executable.
 	if seccheck.Global.Enabled(seccheck.PointExecve) {
 		mask, info := getExecveSeccheckInfo(t, argv, env, executable, pathname)
-		if err := seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
-			return c.Execve(t, mask, info)
-		}); err != nil {
-			return nil, err
+		if mask.Filter.Match(info) {
+			if err := seccheck.Global.Enforce(t, mask, info.GetContextData(), func(c seccheck.Sink) error {
+				return c.Execve(t, mask, info)
+			}); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -263,6 +265,7 @@ func (r *runSyscallAfterExecStop) execute(t *Task) taskRunState {
 	// NOTE(b/30316266): All locks must be dropped prior to calling Activate.
 	t.MemoryManager().Activate(t)
 
+	t.perfEventExec()
 	t.ptraceExec(oldTID)
 	return (*runSyscallExit)(nil)
 }
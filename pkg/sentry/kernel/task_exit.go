@@ -241,14 +241,17 @@ func (*runExitMain) execute(t *Task) taskRunState {
 			info.ContextData = &pb.ContextData{}
 			LoadSeccheckData(t, fields.Context, info.ContextData)
 		}
-		seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
-			return c.TaskExit(t, fields, info)
-		})
+		if fields.Filter.Match(info) {
+			seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
+				return c.TaskExit(t, fields, info)
+			})
+		}
 	}
 
 	lastExiter := t.exitThreadGroup()
 
 	t.ResetKcov()
+	t.perfEventExit()
 
 	// If the task has a cleartid, and the thread group wasn't killed by a
 	// signal, handle that before releasing the MM.
@@ -724,10 +727,12 @@ func (t *Task) exitNotifyLocked(fromPtraceDetach bool) {
 			// Clone or Exec events for the initial process.
 			if t.tg != t.k.globalInit && seccheck.Global.Enabled(seccheck.PointExitNotifyParent) {
 				mask, info := getExitNotifyParentSeccheckInfo(t)
-				if err := seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
-					return c.ExitNotifyParent(t, mask, info)
-				}); err != nil {
-					log.Infof("Ignoring error from ExitNotifyParent point: %v", err)
+				if mask.Filter.Match(info) {
+					if err := seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
+						return c.ExitNotifyParent(t, mask, info)
+					}); err != nil {
+						log.Infof("Ignoring error from ExitNotifyParent point: %v", err)
+					}
 				}
 			}
 		}
@@ -0,0 +1,136 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"gvisor.dev/gvisor/pkg/hostarch"
+)
+
+// PerfEvent is a perf_event_open(2) event that observes the tasks it is
+// attached to with Task.AttachPerfEvent.
+//
+// Unless noted otherwise, methods are called on the task goroutine of the
+// task generating the event, without holding any kernel locks.
+type PerfEvent interface {
+	// PerfEventCount is called when t generates count software events of type
+	// config, one of linux.PERF_COUNT_SW_*.
+	PerfEventCount(t *Task, config uint64, count uint64)
+
+	// PerfEventExec is called after t completes an execve.
+	PerfEventExec(t *Task)
+
+	// PerfEventMmap is called after t creates an executable mapping at ar.
+	PerfEventMmap(t *Task, ar hostarch.AddrRange)
+
+	// PerfEventClone is called on the task goroutine of t after t creates
+	// child with the given clone flags, before child starts running.
+	PerfEventClone(t, child *Task, flags uint64)
+
+	// PerfEventExit is called when t exits. The event is detached from t
+	// after PerfEventExit returns.
+	PerfEventExit(t *Task)
+}
+
+// AttachPerfEvent attaches e to t, so that e observes the events generated
+// by t. It returns false if t has already exited.
+func (t *Task) AttachPerfEvent(e PerfEvent) bool {
+	t.perfEventsMu.Lock()
+	defer t.perfEventsMu.Unlock()
+	if t.perfEventsExited {
+		return false
+	}
+	t.perfEvents = append(t.perfEvents, e)
+	t.numPerfEvents.Store(int32(len(t.perfEvents)))
+	return true
+}
+
+// DetachPerfEvent detaches e from t.
+func (t *Task) DetachPerfEvent(e PerfEvent) {
+	t.perfEventsMu.Lock()
+	defer t.perfEventsMu.Unlock()
+	for i, pe := range t.perfEvents {
+		if pe == e {
+			t.perfEvents = append(t.perfEvents[:i], t.perfEvents[i+1:]...)
+			break
+		}
+	}
+	t.numPerfEvents.Store(int32(len(t.perfEvents)))
+}
+
+// perfEventsSnapshot returns the perf events attached to t.
+func (t *Task) perfEventsSnapshot() []PerfEvent {
+	if t.numPerfEvents.Load() == 0 {
+		return nil
+	}
+	t.perfEventsMu.Lock()
+	defer t.perfEventsMu.Unlock()
+	return append([]PerfEvent(nil), t.perfEvents...)
+}
+
+// perfEventCount notifies the perf events attached to t that t generated
+// count software events of type config.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) perfEventCount(config uint64, count uint64) {
+	for _, e := range t.perfEventsSnapshot() {
+		e.PerfEventCount(t, config, count)
+	}
+}
+
+// perfEventExec notifies the perf events attached to t that t completed an
+// execve.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) perfEventExec() {
+	for _, e := range t.perfEventsSnapshot() {
+		e.PerfEventExec(t)
+	}
+}
+
+// PerfEventMmap notifies the perf events attached to t that t created an
+// executable mapping at ar.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) PerfEventMmap(ar hostarch.AddrRange) {
+	for _, e := range t.perfEventsSnapshot() {
+		e.PerfEventMmap(t, ar)
+	}
+}
+
+// perfEventClone notifies the perf events attached to t that t created
+// child.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) perfEventClone(child *Task, flags uint64) {
+	for _, e := range t.perfEventsSnapshot() {
+		e.PerfEventClone(t, child, flags)
+	}
+}
+
+// perfEventExit notifies the perf events attached to t that t is exiting, and
+// detaches them from t.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) perfEventExit() {
+	t.perfEventsMu.Lock()
+	events := t.perfEvents
+	t.perfEvents = nil
+	t.perfEventsExited = true
+	t.numPerfEvents.Store(0)
+	t.perfEventsMu.Unlock()
+	for _, e := range events {
+		e.PerfEventExit(t)
+	}
+}
@@ -15,8 +15,10 @@
 package kernel
 
 import (
+	gocontext "context"
 	"fmt"
 	"runtime"
+	"runtime/pprof"
 	"runtime/trace"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
@@ -59,6 +61,12 @@ type taskRunState interface {
 func (t *Task) run(threadID uintptr) {
 	t.goid.Store(goid.Get())
 
+	// Attribute sentry CPU profile samples taken while running this task to
+	// its container.
+	if cid := t.ContainerID(); cid != "" {
+		pprof.SetGoroutineLabels(pprof.WithLabels(gocontext.Background(), pprof.Labels("container", cid)))
+	}
+
 	refs.CleanupSync.Add(1)
 	defer refs.CleanupSync.Done()
 
@@ -292,6 +300,7 @@ func (app *runApp) execute(t *Task) taskRunState {
 			if err == nil {
 				// The fault was handled appropriately.
 				// We can resume running the application.
+				t.perfEventCount(linux.PERF_COUNT_SW_PAGE_FAULTS, 1)
 				return (*runApp)(nil)
 			}
 
@@ -86,6 +86,11 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 
 	fe := s.FeatureEnable.Word(sysno)
 
+	var startNs int64
+	if bits.IsAnyOn32(fe, SyscallMetricsEnable|SyscallStatsEnable) {
+		startNs = metric.CheapNowNano()
+	}
+
 	var straceContext any
 	if bits.IsAnyOn32(fe, StraceEnableBits) {
 		straceContext = s.Stracer.SyscallEnter(t, sysno, args, fe)
@@ -106,9 +111,13 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 			info.ContextData = &pb.ContextData{}
 			LoadSeccheckData(t, fields.Context, info.ContextData)
 		}
-		seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
-			return c.RawSyscall(t, fields, &info)
-		})
+		if fields.Filter.Match(&info) {
+			if denyErr := seccheck.Global.Enforce(t, fields, info.ContextData, func(c seccheck.Sink) error {
+				return c.RawSyscall(t, fields, &info)
+			}); denyErr != nil && fields.Enforcement != nil {
+				err = denyErr
+			}
+		}
 	}
 	if bits.IsAnyOn32(fe, SecCheckEnter) {
 		fields := seccheck.Global.GetFieldSet(seccheck.GetPointForSyscall(seccheck.SyscallEnter, sysno))
@@ -123,16 +132,25 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 		}
 		cb := s.LookupSyscallToProto(sysno)
 		msg, msgType := cb(t, fields, ctxData, info)
-		seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
-			return c.Syscall(t, fields, ctxData, msgType, msg)
-		})
+		if fields.Filter.Match(msg) {
+			// Only enforcing sessions can deny syscalls, other sessions are
+			// observational and sink errors are ignored.
+			if denyErr := seccheck.Global.Enforce(t, fields, ctxData, func(c seccheck.Sink) error {
+				return c.Syscall(t, fields, ctxData, msgType, msg)
+			}); denyErr != nil && fields.Enforcement != nil && err == nil {
+				err = denyErr
+			}
+		}
 	}
 
-	if bits.IsOn32(fe, ExternalBeforeEnable) && (s.ExternalFilterBefore == nil || s.ExternalFilterBefore(t, sysno, args)) {
+	switch {
+	case err != nil:
+		// The syscall was denied by a trace session, don't execute it.
+	case bits.IsOn32(fe, ExternalBeforeEnable) && (s.ExternalFilterBefore == nil || s.ExternalFilterBefore(t, sysno, args)):
 		t.invokeExternal()
 		// Ensure we check for stops, then invoke the syscall again.
 		ctrl = ctrlStopAndReinvokeSyscall
-	} else {
+	default:
 		fn := s.Lookup(sysno)
 		var region *trace.Region // Only non-nil if tracing == true.
 		if trace.IsEnabled() {
@@ -155,6 +173,14 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 		// Don't reinvoke the unix.
 	}
 
+	if bits.IsOn32(fe, SyscallMetricsEnable) {
+		s.recordSyscallMetrics(t, sysno, startNs, err)
+	}
+
+	if bits.IsOn32(fe, SyscallStatsEnable) {
+		t.recordSyscallStats(sysno, startNs, err)
+	}
+
 	if bits.IsAnyOn32(fe, StraceEnableBits) {
 		s.Stracer.SyscallExit(straceContext, t, sysno, rval, err)
 	}
@@ -178,9 +204,11 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 			info.ContextData = &pb.ContextData{}
 			LoadSeccheckData(t, fields.Context, info.ContextData)
 		}
-		seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
-			return c.RawSyscall(t, fields, &info)
-		})
+		if fields.Filter.Match(&info) {
+			seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
+				return c.RawSyscall(t, fields, &info)
+			})
+		}
 	}
 	if bits.IsAnyOn32(fe, SecCheckExit) {
 		fields := seccheck.Global.GetFieldSet(seccheck.GetPointForSyscall(seccheck.SyscallExit, sysno))
@@ -198,9 +226,11 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 		}
 		cb := s.LookupSyscallToProto(sysno)
 		msg, msgType := cb(t, fields, ctxData, info)
-		seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
-			return c.Syscall(t, fields, ctxData, msgType, msg)
-		})
+		if fields.Filter.Match(msg) {
+			seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
+				return c.Syscall(t, fields, ctxData, msgType, msg)
+			})
+		}
 	}
 
 	return
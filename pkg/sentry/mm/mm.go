@@ -366,8 +366,8 @@ type pma struct {
 	// effectivePerms is the permissions allowed for non-ignorePermissions
 	// accesses. maxPerms is the permissions allowed for ignorePermissions
 	// accesses. These are vma.effectivePerms and vma.maxPerms respectively,
-	// masked by pma.translatePerms and with Write disallowed if pma.needCOW is
-	// true.
+	// masked by pma.translatePerms and with Write disallowed if pma.needCOW or
+	// pma.writeProtected is true.
 	//
 	// These are stored in the pma so that the IO implementation can avoid
 	// iterating mm.vmas when pmas already exist.
@@ -377,6 +377,11 @@ type pma struct {
 	// needCOW is true if writes to the mapping must be propagated to a copy.
 	needCOW bool
 
+	// writeProtected is true if writes to the mapping must be reported to
+	// file, which must be a *pgalloc.MemoryFile, before they're allowed. See
+	// MemoryManager.WriteProtect.
+	writeProtected bool
+
 	// private is true if this pma represents private memory.
 	//
 	// If private is true, file must be MemoryManager.mfp.MemoryFile(), and
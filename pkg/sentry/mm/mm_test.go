@@ -15,6 +15,7 @@
 package mm
 
 import (
+	"io"
 	"testing"
 
 	"gvisor.dev/gvisor/pkg/context"
@@ -235,6 +236,67 @@ func TestIOAfterMProtect(t *testing.T) {
 	}
 }
 
+// TestWriteProtect tests that writes to memory whose writes are tracked are
+// reported, and that reads are not.
+func TestWriteProtect(t *testing.T) {
+	ctx := contexttest.Context(t)
+	mm := testMemoryManager(ctx)
+	defer mm.DecUsers(ctx)
+
+	addr, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   hostarch.PageSize,
+		Private:  true,
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.AnyAccess,
+	})
+	if err != nil {
+		t.Fatalf("MMap got err %v want nil", err)
+	}
+	b := make([]byte, 1)
+	if _, err := mm.CopyOut(ctx, addr, b, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyOut got err %v want nil", err)
+	}
+
+	// Start tracking writes.
+	if _, err := mm.mf.PreCopyTo(ctx, io.Discard, "test", mm.WriteProtect); err != nil {
+		t.Fatalf("PreCopyTo got err %v want nil", err)
+	}
+	writeProtected := func() bool {
+		mm.activeMu.RLock()
+		defer mm.activeMu.RUnlock()
+		pma := mm.pmas.FindSegment(addr).ValuePtr()
+		return pma.writeProtected && !pma.effectivePerms.Write && !pma.maxPerms.Write
+	}
+	if !writeProtected() {
+		t.Fatalf("pma isn't write-protected after WriteProtect")
+	}
+
+	// Neither reads nor mprotect remove write protection.
+	if _, err := mm.CopyIn(ctx, addr, b, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyIn got err %v want nil", err)
+	}
+	if err := mm.MProtect(addr, hostarch.PageSize, hostarch.ReadWrite, false); err != nil {
+		t.Fatalf("MProtect got err %v want nil", err)
+	}
+	if !writeProtected() {
+		t.Errorf("pma isn't write-protected after read and mprotect")
+	}
+	if sent, err := mm.mf.PreCopyTo(ctx, io.Discard, "test", mm.WriteProtect); err != nil || sent != 0 {
+		t.Errorf("PreCopyTo after read got (%d, %v) want (0, nil)", sent, err)
+	}
+
+	// Writes are reported, and remove write protection.
+	if _, err := mm.CopyOut(ctx, addr, b, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyOut got err %v want nil", err)
+	}
+	if writeProtected() {
+		t.Errorf("pma is write-protected after write")
+	}
+	if sent, err := mm.mf.PreCopyTo(ctx, io.Discard, "test", mm.WriteProtect); err != nil || sent != hostarch.PageSize {
+		t.Errorf("PreCopyTo after write got (%d, %v) want (%d, nil)", sent, err, hostarch.PageSize)
+	}
+}
+
 // TestAIOPrepareAfterDestroy tests that AIOContext should not be able to be
 // prepared after destruction.
 func TestAIOPrepareAfterDestroy(t *testing.T) {
@@ -363,6 +363,8 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 							newpma.effectivePerms.Write = false
 							newpma.maxPerms.Write = false
 							newpma.needCOW = true
+						} else {
+							newpma.trackWrites(t.FileRange(), at)
 						}
 						mm.addRSSLocked(newpmaAR)
 						t.File.IncRef(t.FileRange(), memCgID)
@@ -385,6 +387,23 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 
 			case pseg.Ok() && pseg.Start() < vsegAR.End:
 				oldpma := pseg.ValuePtr()
+				if at.Write && oldpma.writeProtected {
+					// Report the write, limited to ar expanded to hugepage
+					// alignment, before allowing it.
+					if wpAR := pseg.Range().Intersect(hugeMaskAR).Intersect(vseg.Range()); wpAR != pseg.Range() {
+						pseg = mm.pmas.Isolate(pseg, wpAR)
+						pstart = pmaIterator{} // iterators invalidated
+						oldpma = pseg.ValuePtr()
+					}
+					oldpma.file.(*pgalloc.MemoryFile).MarkDirty(pseg.fileRange())
+					oldpma.writeProtected = false
+					oldpma.effectivePerms = vma.effectivePerms.Intersect(oldpma.translatePerms)
+					oldpma.maxPerms = vma.maxPerms.Intersect(oldpma.translatePerms)
+					if oldpma.needCOW {
+						oldpma.effectivePerms.Write = false
+						oldpma.maxPerms.Write = false
+					}
+				}
 				if at.Write && mm.isPMACopyOnWriteLocked(vseg, pseg) {
 					// Break copy-on-write by copying.
 					if checkInvariants {
@@ -469,6 +488,7 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 					oldpma.effectivePerms = vma.effectivePerms
 					oldpma.maxPerms = vma.maxPerms
 					oldpma.needCOW = false
+					oldpma.writeProtected = false
 					oldpma.private = true
 					oldpma.huge = huge
 					oldpma.internalMappings = safemem.BlockSeq{}
@@ -539,6 +559,8 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 							newpma.effectivePerms.Write = false
 							newpma.maxPerms.Write = false
 							newpma.needCOW = true
+						} else {
+							newpma.trackWrites(t.FileRange(), at)
 						}
 						t.File.IncRef(t.FileRange(), memCgID)
 						pseg = mm.pmas.Insert(pgap, newpmaAR, newpma)
@@ -946,6 +968,7 @@ func (pmaSetFunctions) Merge(ar1 hostarch.AddrRange, pma1 pma, ar2 hostarch.Addr
 		pma1.effectivePerms != pma2.effectivePerms ||
 		pma1.maxPerms != pma2.maxPerms ||
 		pma1.needCOW != pma2.needCOW ||
+		pma1.writeProtected != pma2.writeProtected ||
 		pma1.private != pma2.private ||
 		pma1.huge != pma2.huge {
 		return pma{}, false
@@ -19,6 +19,8 @@ import (
 	"fmt"
 
 	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 )
 
@@ -37,6 +39,52 @@ func (mm *MemoryManager) InvalidateUnsavable(ctx context.Context) error {
 	return nil
 }
 
+// WriteProtect removes write permission from all pmas that map
+// pgalloc.MemoryFiles that track writes, such that writes to them are
+// reported to the MemoryFile before they're allowed. It must be called
+// whenever a MemoryFile starts tracking writes; see pgalloc.SaveOpts.SaveID.
+func (mm *MemoryManager) WriteProtect() {
+	mm.activeMu.Lock()
+	defer mm.activeMu.Unlock()
+	unmap := false
+	for pseg := mm.pmas.FirstSegment(); pseg.Ok(); pseg = pseg.NextSegment() {
+		pma := pseg.ValuePtr()
+		if pma.writeProtected {
+			continue
+		}
+		if mf, ok := pma.file.(*pgalloc.MemoryFile); !ok || !mf.TrackingWrites() {
+			continue
+		}
+		unmap = unmap || pma.effectivePerms.Write
+		pma.writeProtect()
+	}
+	if unmap {
+		mm.unmapASLocked(mm.applicationAddrRange())
+	}
+}
+
+// writeProtect sets p.writeProtected and removes write permission from p.
+func (p *pma) writeProtect() {
+	p.writeProtected = true
+	p.effectivePerms.Write = false
+	p.maxPerms.Write = false
+}
+
+// trackWrites prepares p, a new pma for a translation of fr, for tracking of
+// writes to p.file. If p.file tracks writes, writes to p are reported
+// immediately if at.Write is true, and p is write-protected otherwise.
+func (p *pma) trackWrites(fr memmap.FileRange, at hostarch.AccessType) {
+	mf, ok := p.file.(*pgalloc.MemoryFile)
+	if !ok || !mf.TrackingWrites() {
+		return
+	}
+	if at.Write {
+		mf.MarkDirty(fr)
+	} else {
+		p.writeProtect()
+	}
+}
+
 // afterLoad is invoked by stateify.
 func (mm *MemoryManager) afterLoad(ctx goContext.Context) {
 	mm.mf = pgalloc.MemoryFileFromContext(ctx)
@@ -716,7 +716,7 @@ func (mm *MemoryManager) MProtect(addr hostarch.Addr, length uint64, realPerms h
 					didUnmapAS = true
 				}
 				pma.effectivePerms = effectivePerms.Intersect(pma.translatePerms)
-				if pma.needCOW {
+				if pma.needCOW || pma.writeProtected {
 					pma.effectivePerms.Write = false
 				}
 			}
@@ -0,0 +1,187 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+
+	"gvisor.dev/gvisor/pkg/chunkstore"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+)
+
+// A ChunkStore is a connection to a chunkstore.Store outside of the sandbox,
+// which stores the contents of pages saved by SaveTo(), addressed by their
+// SHA-256 hash, so that pages with identical contents are stored once across
+// all images sharing the store. The store hashes, deduplicates and compresses
+// pages itself, and images saved to it refer to pages by their offset in the
+// store's pack file and their hash. The pack file is thus the pages file of
+// all such images, which can be loaded by LoadFrom() with
+// LoadOpts.PagesDeduplicated; each page is verified against its hash when
+// loaded.
+type ChunkStore struct {
+	client *chunkstore.Client
+}
+
+// chunkZeroOff is the offset of zero pages in chunkRuns. Zero pages are never
+// sent to the store.
+const chunkZeroOff = math.MaxUint64
+
+// NewChunkStore returns a ChunkStore using conn, a connection served by
+// chunkstore.Store.Serve(). The returned ChunkStore borrows conn.
+func NewChunkStore(conn *fd.FD) *ChunkStore {
+	return &ChunkStore{
+		client: chunkstore.NewClient(conn),
+	}
+}
+
+// commit commits the pages sent to cs, and fills in the locations of the
+// non-zero pages in runs, which must be those pages in order. It returns the
+// number of bytes of pages that were added to the store.
+func (cs *ChunkStore) commit(runs []chunkRun) (uint64, error) {
+	locs, err := cs.client.Commit()
+	if err != nil {
+		return 0, err
+	}
+	addedBytes := uint64(0)
+	for i := range runs {
+		run := &runs[i]
+		if run.off == chunkZeroOff {
+			continue
+		}
+		if len(locs) == 0 {
+			return 0, fmt.Errorf("chunk store returned too few page locations")
+		}
+		loc := locs[0]
+		locs = locs[1:]
+		run.off = loc.Off
+		run.flen = loc.Len
+		run.sum = loc.Sum
+		if loc.Added {
+			addedBytes += hostarch.PageSize
+		}
+	}
+	if len(locs) != 0 {
+		return 0, fmt.Errorf("chunk store returned %d extra page locations", len(locs))
+	}
+	return addedBytes, nil
+}
+
+// chunkRun is a run of pages saved to a ChunkStore by SaveTo(): either a run
+// of zero pages, or a single non-zero page.
+//
+// +stateify savable
+type chunkRun struct {
+	// off is the offset of the page in the ChunkStore's pack file, or
+	// chunkZeroOff if the pages are zero pages.
+	off uint64
+
+	// len is the length of the run in bytes.
+	len uint64
+
+	// If the run is a non-zero page, flen is its length in the pack file,
+	// and sum is the SHA-256 hash of its uncompressed contents. If flen is
+	// less than len, the page is compressed.
+	flen uint32
+	sum  [sha256.Size]byte
+}
+
+// appendZeroChunkRun appends a zero page to runs.
+func appendZeroChunkRun(runs []chunkRun) []chunkRun {
+	if n := len(runs); n != 0 && runs[n-1].off == chunkZeroOff {
+		runs[n-1].len += hostarch.PageSize
+		return runs
+	}
+	return append(runs, chunkRun{off: chunkZeroOff, len: hostarch.PageSize})
+}
+
+// checkChunkRuns returns an error if runs, saved by a call to SaveTo() that
+// saved totalBytes of pages, are invalid. compressed is true if the
+// ChunkStore compresses pages.
+func checkChunkRuns(runs []chunkRun, totalBytes uint64, compressed bool) error {
+	runsBytes := uint64(0)
+	for _, run := range runs {
+		if run.len == 0 || run.len%hostarch.PageSize != 0 {
+			return fmt.Errorf("invalid chunk run %+v", run)
+		}
+		if run.off != chunkZeroOff {
+			if run.len != hostarch.PageSize || run.flen == 0 || run.flen > hostarch.PageSize || (!compressed && run.flen != hostarch.PageSize) {
+				return fmt.Errorf("invalid chunk run %+v", run)
+			}
+		}
+		runsBytes += run.len
+	}
+	if runsBytes != totalBytes {
+		return fmt.Errorf("mismatched chunk runs: expected %d bytes, got %d", totalBytes, runsBytes)
+	}
+	return nil
+}
+
+// newChunkFrames returns the frames holding the pages in pending, whose
+// offsets are offsets into the pages saved by the call to SaveTo() that
+// produced runs. compressed is true if the ChunkStore compresses pages. Each
+// non-zero page is a frame of its own, which is read from the pack file,
+// decompressed like the frames of compressed pages files (see compress.go),
+// and verified against its hash, since other images share the pack file.
+// Zero pages aren't held by any frame, since restored MemoryFiles are
+// initially zero-filled.
+func newChunkFrames(pending []aplPendingRange, runs []chunkRun, totalBytes uint64, compressed bool) ([]aplFrame, error) {
+	if err := checkChunkRuns(runs, totalBytes, compressed); err != nil {
+		return nil, err
+	}
+	var frames []aplFrame
+	runStart := uint64(0)
+	for _, run := range runs {
+		if run.off != chunkZeroOff {
+			frames = append(frames, aplFrame{
+				off:    runStart,
+				len:    run.len,
+				foff:   run.off,
+				flen:   run.flen,
+				sum:    run.sum,
+				hashed: true,
+			})
+		}
+		runStart += run.len
+	}
+	// Frames are in the order of runs, so the frame of the next non-zero run
+	// is frames[next].
+	runStart = 0
+	next := 0
+	for _, pr := range pending {
+		fr, off := pr.fr, pr.off
+		for fr.Length() != 0 {
+			for off >= runStart+runs[0].len {
+				if runs[0].off != chunkZeroOff {
+					next++
+				}
+				runStart += runs[0].len
+				runs = runs[1:]
+			}
+			run := runs[0]
+			n := min(fr.Length(), runStart+run.len-off)
+			if run.off != chunkZeroOff {
+				frame := &frames[next]
+				frame.frs = append(frame.frs, memmap.FileRange{fr.Start, fr.Start + n})
+			}
+			fr.Start += n
+			off += n
+		}
+	}
+	return frames, nil
+}
@@ -0,0 +1,222 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/chunkstore"
+	"gvisor.dev/gvisor/pkg/compressio"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+)
+
+// saveToChunkStore saves f to the chunk store in dir, served over a
+// connection as it is to a sandbox being checkpointed.
+func saveToChunkStore(t *testing.T, f *MemoryFile, dir string, codec compressio.Codec) *bytes.Buffer {
+	t.Helper()
+	pack, index, err := chunkstore.OpenFiles(dir+"/pack", dir+"/index")
+	if err != nil {
+		t.Fatalf("OpenFiles failed: %v", err)
+	}
+	defer pack.Close()
+	defer index.Close()
+	store, err := chunkstore.New(pack, index, codec)
+	if err != nil {
+		t.Fatalf("chunkstore.New failed: %v", err)
+	}
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("Socketpair failed: %v", err)
+	}
+	serverConn := os.NewFile(uintptr(fds[0]), "chunk store server")
+	defer serverConn.Close()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- store.Serve(serverConn)
+	}()
+	conn := fd.New(fds[1])
+	var meta bytes.Buffer
+	err = f.SaveTo(context.Background(), &meta, conn, SaveOpts{ChunkStore: NewChunkStore(conn)})
+	conn.Close()
+	if err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+	if err := <-serveErr; err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+	return &meta
+}
+
+// packSize returns the size of the pack file in dir.
+func packSize(t *testing.T, dir string) int64 {
+	t.Helper()
+	info, err := os.Stat(dir + "/pack")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	return info.Size()
+}
+
+func TestChunkStoreSaveLoad(t *testing.T) {
+	zstdCodec, err := compressio.NewZstdCodec(0)
+	if err != nil {
+		t.Fatalf("NewZstdCodec failed: %v", err)
+	}
+	for _, test := range []struct {
+		name  string
+		codec compressio.Codec
+	}{
+		{name: "none"},
+		{name: "zstd", codec: zstdCodec},
+		{name: "lz4", codec: compressio.NewLZ4Codec()},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			testChunkStoreSaveLoad(t, test.codec)
+		})
+	}
+}
+
+func testChunkStoreSaveLoad(t *testing.T, codec compressio.Codec) {
+	ctx := context.Background()
+	const numPages = 64
+	var (
+		mfs []*MemoryFile
+		frs []memmap.FileRange
+	)
+	for i := 0; i < 2; i++ {
+		f := newTestMemoryFile(t)
+		fr, err := f.Allocate(numPages*page, AllocOpts{Kind: usage.Anonymous, Mode: AllocateAndCommit})
+		if err != nil {
+			t.Fatalf("Allocate failed: %v", err)
+		}
+		// Both MemoryFiles hold the same 12 distinct pages, each repeated,
+		// and zero pages. The second also holds one incompressible page of
+		// its own.
+		for j := uint64(0); j < numPages; j++ {
+			if j%4 != 3 {
+				fillPage(f, fr.Start+j*page, byte(j%16+1))
+			}
+		}
+		if i == 1 {
+			rng := rand.New(rand.NewSource(1))
+			f.forEachMappingSlice(memmap.FileRange{fr.Start + 5*page, fr.Start + 6*page}, func(s []byte) {
+				rng.Read(s)
+			})
+		}
+		mfs = append(mfs, f)
+		frs = append(frs, fr)
+	}
+
+	dir := t.TempDir()
+	metas := []*bytes.Buffer{saveToChunkStore(t, mfs[0], dir, codec)}
+	firstSize := packSize(t, dir)
+	if codec == nil {
+		if want := int64(12 * page); firstSize != want {
+			t.Errorf("pack file holds %d bytes after first save, want %d", firstSize, want)
+		}
+	} else if firstSize == 0 || firstSize >= 12*page {
+		t.Errorf("pack file holds %d bytes after first save, want (0, %d)", firstSize, 12*page)
+	}
+	metas = append(metas, saveToChunkStore(t, mfs[1], dir, codec))
+	// Only the incompressible page is added, uncompressed.
+	if got, want := packSize(t, dir), firstSize+page; got != want {
+		t.Errorf("pack file holds %d bytes after second save, want %d", got, want)
+	}
+
+	pack, err := fd.Open(dir+"/pack", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer pack.Close()
+	for i, f := range mfs {
+		restored := newTestMemoryFile(t)
+		opts := LoadOpts{
+			PagesFile:         pack,
+			PagesCodec:        codec,
+			PagesDeduplicated: true,
+		}
+		if err := restored.LoadFrom(ctx, bytes.NewReader(metas[i].Bytes()), &opts); err != nil {
+			t.Fatalf("LoadFrom failed: %v", err)
+		}
+		if err := restored.AwaitLoadAll(); err != nil {
+			t.Fatalf("AwaitLoadAll failed: %v", err)
+		}
+		if got, want := readPages(restored, frs[i]), readPages(f, frs[i]); !bytes.Equal(got, want) {
+			t.Errorf("MemoryFile %d: restored pages differ from saved pages", i)
+		}
+	}
+}
+
+func TestChunkStoreVerifiesPages(t *testing.T) {
+	f := newTestMemoryFile(t)
+	fr, err := f.Allocate(4*page, AllocOpts{Kind: usage.Anonymous, Mode: AllocateAndCommit})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	for i := uint64(0); i < 4; i++ {
+		fillPage(f, fr.Start+i*page, byte(i+1))
+	}
+	dir := t.TempDir()
+	meta := saveToChunkStore(t, f, dir, nil /* codec */)
+
+	// Overwrite a page in the pack file, as another checkpoint might if it
+	// could write to the pack file.
+	pack, err := fd.Open(dir+"/pack", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer pack.Close()
+	if _, err := pack.WriteAt(bytes.Repeat([]byte{0xff}, page), 2*page); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	restored := newTestMemoryFile(t)
+	opts := LoadOpts{
+		PagesFile:         pack,
+		PagesDeduplicated: true,
+	}
+	if err := restored.LoadFrom(context.Background(), bytes.NewReader(meta.Bytes()), &opts); err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if err := restored.AwaitLoadAll(); err == nil {
+		t.Errorf("AwaitLoadAll succeeded, want error for page that doesn't match its hash")
+	}
+}
+
+func TestChunkStoreOptionConflicts(t *testing.T) {
+	f := newTestMemoryFile(t)
+	// The conflicts are detected before the connection is used.
+	cs := NewChunkStore(nil /* conn */)
+	for _, test := range []struct {
+		name string
+		opts SaveOpts
+	}{
+		{name: "incremental", opts: SaveOpts{ChunkStore: cs, SaveID: "a"}},
+		{name: "encrypted", opts: SaveOpts{ChunkStore: cs, PagesAEAD: newTestAEAD(t)}},
+		{name: "compressed", opts: SaveOpts{ChunkStore: cs, PagesCodec: compressio.NewLZ4Codec()}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if err := f.SaveTo(context.Background(), &bytes.Buffer{}, &bytes.Buffer{}, test.opts); err == nil {
+				t.Errorf("SaveTo succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/compressio"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+)
+
+// Pages compressed by SaveTo() are split into frames of pagesFrameSize bytes
+// (except for the last frame saved by each call to SaveTo()), which are
+// compressed independently with SaveOpts.PagesCodec, so that the async page
+// loader can load any frame without decompressing the others. Frames that
+// don't compress are stored uncompressed. The length of each frame in the
+// pages file is written to the metadata stream after all page headers; frames
+// whose length is that of their uncompressed contents are uncompressed. If
+// SaveOpts.PagesAEAD is also set, frames are encrypted after compression; see
+// encrypt.go.
+//
+// Since pagesFrameSize is aplReadMaxBytes, each frame is read by a single
+// aplOp, whose frs() can hold all pages in the frame.
+const pagesFrameSize = aplReadMaxBytes
+
+// aplFrameMaxInflight is the maximum number of concurrent reads of compressed
+// frames, which bounds the memory used by the async page loader to buffer
+// them.
+const aplFrameMaxInflight = 32
+
+// compressFrame is a frame being compressed by a pageCompressor.
+type compressFrame struct {
+	raw  []byte
+	out  []byte
+	err  error
+	done chan struct{}
+}
+
+// pageCompressor compresses pages written to a pages file by SaveTo(). Frames
+// are compressed concurrently, and written to the pages file in order.
+type pageCompressor struct {
+	codec compressio.Codec
+	pw    io.Writer
+
+	// If enc is not nil, compressed frames are encrypted with enc before
+	// they're written to pw.
+	enc *pageEncrypter
+
+	// cur is the frame being filled.
+	cur *compressFrame
+
+	// queue holds frames being compressed, in order.
+	queue []*compressFrame
+
+	// free holds frames that may be reused.
+	free []*compressFrame
+
+	// frameLens are the lengths of the frames written to pw, in order.
+	frameLens []uint32
+}
+
+func newPageCompressor(codec compressio.Codec, pw io.Writer, enc *pageEncrypter) *pageCompressor {
+	return &pageCompressor{
+		codec: codec,
+		pw:    pw,
+		enc:   enc,
+	}
+}
+
+// write compresses the pages in s to c.pw.
+func (c *pageCompressor) write(s []byte) error {
+	for len(s) > 0 {
+		if c.cur == nil {
+			if n := len(c.free); n != 0 {
+				c.cur = c.free[n-1]
+				c.free = c.free[:n-1]
+			} else {
+				c.cur = &compressFrame{raw: make([]byte, 0, pagesFrameSize)}
+			}
+		}
+		fr := c.cur
+		n := copy(fr.raw[len(fr.raw):cap(fr.raw)], s)
+		fr.raw = fr.raw[:len(fr.raw)+n]
+		s = s[n:]
+		if len(fr.raw) == cap(fr.raw) {
+			if err := c.submit(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// submit starts compressing c.cur.
+func (c *pageCompressor) submit() error {
+	fr := c.cur
+	c.cur = nil
+	fr.done = make(chan struct{})
+	c.queue = append(c.queue, fr)
+	go func() { // S/R-SAFE: In save path only.
+		defer close(fr.done)
+		fr.out, fr.err = c.codec.Compress(fr.out[:0], fr.raw)
+	}()
+	if len(c.queue) > runtime.GOMAXPROCS(0) {
+		return c.writeOldest()
+	}
+	return nil
+}
+
+// writeOldest waits for the oldest frame being compressed and writes it.
+func (c *pageCompressor) writeOldest() error {
+	fr := c.queue[0]
+	c.queue = c.queue[1:]
+	<-fr.done
+	if fr.err != nil {
+		return fmt.Errorf("failed to compress pages: %w", fr.err)
+	}
+	data := fr.out
+	if len(data) >= len(fr.raw) {
+		data = fr.raw
+	}
+	if c.enc != nil {
+		var err error
+		if data, err = c.enc.sealFrame(data, uint64(len(c.frameLens))*pagesFrameSize); err != nil {
+			return err
+		}
+	}
+	if _, err := c.pw.Write(data); err != nil {
+		return err
+	}
+	c.frameLens = append(c.frameLens, uint32(len(data)))
+	fr.raw = fr.raw[:0]
+	c.free = append(c.free, fr)
+	return nil
+}
+
+// flush compresses and writes all buffered pages.
+func (c *pageCompressor) flush() error {
+	if c.cur != nil && len(c.cur.raw) != 0 {
+		if err := c.submit(); err != nil {
+			return err
+		}
+	}
+	for len(c.queue) != 0 {
+		if err := c.writeOldest(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// release waits for frames being compressed, so that c's buffers are no
+// longer in use.
+func (c *pageCompressor) release() {
+	for _, fr := range c.queue {
+		<-fr.done
+	}
+	c.queue = nil
+}
+
+// aplFrame is a compressed frame in the pages file.
+type aplFrame struct {
+	// off is the offset of the frame in the uncompressed pages saved by
+	// the call to SaveTo() that compressed it, and len is its uncompressed
+	// length.
+	off uint64
+	len uint64
+
+	// foff is the offset of the frame in the pages file, and flen is its
+	// length there. If flen == len, the frame is stored uncompressed.
+	foff uint64
+	flen uint32
+
+	// If hashed is true, sum is the SHA-256 hash of the frame's uncompressed
+	// contents.
+	sum    [sha256.Size]byte
+	hashed bool
+
+	// frs are the MemoryFile ranges of the pages in the frame, in order.
+	frs []memmap.FileRange
+}
+
+// newAPLFrames returns the frames holding the pages in pending, whose offsets
+// are offsets into the uncompressed pages, given the length of each frame in
+// the pages file and the pages file offset of the first frame. It also returns
+// the number of bytes in the pages file holding the frames.
+func newAPLFrames(pending []aplPendingRange, frameLens []uint32, totalBytes, foff uint64) ([]aplFrame, uint64, error) {
+	if want := (totalBytes + pagesFrameSize - 1) / pagesFrameSize; uint64(len(frameLens)) != want {
+		return nil, 0, fmt.Errorf("mismatched compressed frames: expected %d, got %d", want, len(frameLens))
+	}
+	frames := make([]aplFrame, len(frameLens))
+	fileBytes := uint64(0)
+	for i, flen := range frameLens {
+		frame := &frames[i]
+		frame.off = uint64(i) * pagesFrameSize
+		frame.len = min(totalBytes-frame.off, pagesFrameSize)
+		frame.foff = foff + fileBytes
+		frame.flen = flen
+		if flen == 0 || uint64(flen) > frame.len {
+			return nil, 0, fmt.Errorf("invalid length %d of compressed frame %d", flen, i)
+		}
+		fileBytes += uint64(flen)
+	}
+	for _, pr := range pending {
+		fr, off := pr.fr, pr.off
+		for fr.Length() != 0 {
+			frame := &frames[off/pagesFrameSize]
+			n := min(fr.Length(), frame.off+frame.len-off)
+			sub := memmap.FileRange{fr.Start, fr.Start + n}
+			if k := len(frame.frs); k != 0 && frame.frs[k-1].End == sub.Start {
+				frame.frs[k-1].End = sub.End
+			} else {
+				frame.frs = append(frame.frs, sub)
+			}
+			fr.Start += n
+			off += n
+		}
+	}
+	return frames, fileBytes, nil
+}
+
+// findFrame returns the frame containing the page at the given offset into the
+// uncompressed pages.
+func (g *aplGoroutine) findFrame(off uint64) *aplFrame {
+	i := sort.Search(len(g.frames), func(i int) bool {
+		return g.frames[i].off+g.frames[i].len > off
+	})
+	return &g.frames[i]
+}
+
+func (g *aplGoroutine) canEnqueueFrame() bool {
+	return g.canEnqueue() && aplQueueCapacity-g.qavail < aplFrameMaxInflight
+}
+
+// enqueueFrames enqueues reads of frames holding unloaded pages, prioritizing
+// pages with waiters, as aplGoroutine.main() does for uncompressed pages.
+func (g *aplGoroutine) enqueueFrames(minUnstarted *uint64) {
+	apl := &g.apl
+	// Frames hold pages with and without waiters; take references on all of
+	// them while reading to prevent pages from becoming waste.
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	apl.mu.Lock()
+	defer apl.mu.Unlock()
+	for g.canEnqueueFrame() && !apl.priority.Empty() {
+		fr := apl.priority.PopFront()
+		if ulseg := g.firstUnstarted(fr); ulseg.Ok() {
+			fr.Start = ulseg.Start()
+			g.enqueueFrame(g.findFrame(ulseg.ValuePtr().off))
+			// The rest of fr may be in other frames.
+			apl.priority.PushFront(fr)
+		}
+	}
+	for g.canEnqueueFrame() {
+		ulseg := g.firstUnstarted(memmap.FileRange{*minUnstarted, math.MaxUint64})
+		if !ulseg.Ok() {
+			break
+		}
+		*minUnstarted = ulseg.Start()
+		g.enqueueFrame(g.findFrame(ulseg.ValuePtr().off))
+	}
+}
+
+// firstUnstarted returns the first unstarted segment in g.apl.unloaded that
+// intersects fr, or a terminal iterator if none exists.
+//
+// Preconditions: g.apl.mu must be locked.
+func (g *aplGoroutine) firstUnstarted(fr memmap.FileRange) aplUnloadedIterator {
+	ulseg := g.apl.unloaded.LowerBoundSegment(fr.Start)
+	for ulseg.Ok() && ulseg.Start() < fr.End {
+		if !ulseg.ValuePtr().started {
+			return ulseg
+		}
+		ulseg = ulseg.NextSegment()
+	}
+	return aplUnloadedIterator{}
+}
+
+// enqueueFrame enqueues a read of the given frame, which must hold unstarted
+// pages. Since frames are only loaded as a whole, either all or none of the
+// unloaded pages in a frame are started.
+//
+// Preconditions:
+// - g.canEnqueueFrame() == true.
+// - g.f.mu and g.apl.mu must be locked.
+func (g *aplGoroutine) enqueueFrame(frame *aplFrame) {
+	id, err := g.opsBusy.FirstZero(0)
+	if err != nil {
+		panic(fmt.Sprintf("all ops busy with qavail=%d: %v", g.qavail, err))
+	}
+	g.opsBusy.Add(id)
+	op := &g.ops[id]
+	op.total = uint64(frame.flen)
+	op.end = frame.foff + op.total
+	op.frsLen = 0
+	op.tempRef = true
+	op.frame = frame
+	for _, fr := range frame.frs {
+		g.apl.unloaded.MutateRange(fr, func(ulseg aplUnloadedIterator) bool {
+			ul := ulseg.ValuePtr()
+			if ul.started {
+				panic(fmt.Sprintf("pages %v in unstarted frame at pages file offset %d were already started", ulseg.Range(), frame.foff))
+			}
+			ul.started = true
+			ulFR := ulseg.Range()
+			g.f.incRefLocked(ulFR)
+			if op.frsLen > 0 && op.frsData[op.frsLen-1].End == ulFR.Start {
+				op.frsData[op.frsLen-1].End = ulFR.End
+			} else {
+				op.frsData[op.frsLen] = ulFR
+				op.frsLen++
+			}
+			return true
+		})
+	}
+	buf := &g.frameBufs[id]
+	if cap(*buf) < int(frame.flen) {
+		*buf = make([]byte, pagesFrameSize)
+	}
+	*buf = (*buf)[:frame.flen]
+	op.iovecsData[0] = unix.Iovec{Base: &(*buf)[0]}
+	op.iovecsData[0].SetLen(len(*buf))
+	op.iovecsLen = 1
+	g.curOp = op
+	g.curOpID = id
+	g.enqueueCurOp()
+}
+
+// decompressOp decrypts, decompresses and verifies the frame read by op, and
+// copies the pages that op loads to the MemoryFile.
+func (g *aplGoroutine) decompressOp(id uint64, op *aplOp) error {
+	frame := op.frame
+	data := g.frameBufs[id][:frame.flen]
+	if g.decrypter != nil {
+		if err := g.decrypter.decryptFrame(data, frame.off); err != nil {
+			return fmt.Errorf("frame at pages file offset %d: %w", frame.foff, err)
+		}
+	}
+	if uint64(frame.flen) != frame.len {
+		out, err := g.codec.Decompress(g.frameScratch[:0], data, int(frame.len))
+		if err != nil {
+			return fmt.Errorf("failed to decompress frame at pages file offset %d: %w", frame.foff, err)
+		}
+		if uint64(len(out)) != frame.len {
+			return fmt.Errorf("frame at pages file offset %d decompressed to %d bytes, expected %d", frame.foff, len(out), frame.len)
+		}
+		g.frameScratch = out
+		data = out
+	}
+	if frame.hashed {
+		if sum := sha256.Sum256(data); !bytes.Equal(sum[:], frame.sum[:]) {
+			return fmt.Errorf("frame at pages file offset %d doesn't match its hash", frame.foff)
+		}
+	}
+	for _, fr := range op.frs() {
+		src := data[frame.offsetOf(fr.Start):]
+		g.f.forEachMappingSlice(fr, func(s []byte) {
+			n := copy(s, src)
+			src = src[n:]
+		})
+	}
+	return nil
+}
+
+// offsetOf returns the offset into the frame's uncompressed contents of the
+// page at the given MemoryFile offset, which must be in frame.frs.
+func (frame *aplFrame) offsetOf(off uint64) uint64 {
+	pos := uint64(0)
+	for _, fr := range frame.frs {
+		if fr.Contains(off) {
+			return pos + off - fr.Start
+		}
+		pos += fr.Length()
+	}
+	panic(fmt.Sprintf("MemoryFile offset %#x isn't in frame at pages file offset %d", off, frame.foff))
+}
@@ -0,0 +1,121 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"math/rand"
+	"os"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/compressio"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+)
+
+func TestCompressedSaveLoad(t *testing.T) {
+	zstdCodec, err := compressio.NewZstdCodec(0)
+	if err != nil {
+		t.Fatalf("NewZstdCodec failed: %v", err)
+	}
+	for _, test := range []struct {
+		name    string
+		codec   compressio.Codec
+		encrypt bool
+	}{
+		{name: "zstd", codec: zstdCodec},
+		{name: "lz4", codec: compressio.NewLZ4Codec()},
+		{name: "zstd_encrypted", codec: zstdCodec, encrypt: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newTestMemoryFile(t)
+			// Span several frames, some of which don't compress.
+			const numPages = 3*pagesFrameSize/page + 5
+			fr, err := f.Allocate(numPages*page, AllocOpts{Kind: usage.Anonymous, Mode: AllocateAndCommit})
+			if err != nil {
+				t.Fatalf("Allocate failed: %v", err)
+			}
+			rng := rand.New(rand.NewSource(0))
+			for i := uint64(0); i < numPages; i++ {
+				off := fr.Start + i*page
+				if i/(pagesFrameSize/page) == 1 {
+					f.forEachMappingSlice(memmap.FileRange{off, off + page}, func(s []byte) {
+						rng.Read(s)
+					})
+				} else {
+					fillPage(f, off, byte(i+1))
+				}
+			}
+
+			var aead cipher.AEAD
+			if test.encrypt {
+				aead = newTestAEAD(t)
+			}
+			var image savedImage
+			if err := f.SaveTo(ctx, &image.meta, &image.pages, SaveOpts{PagesCodec: test.codec, PagesAEAD: aead}); err != nil {
+				t.Fatalf("SaveTo failed: %v", err)
+			}
+			if got, max := image.pages.Len(), numPages*page/2; got > max {
+				t.Errorf("image holds %d bytes of pages, want at most %d", got, max)
+			}
+
+			pagesPath := t.TempDir() + "/pages"
+			// Offset the pages in the pages file, as if other MemoryFiles'
+			// pages preceded them.
+			const pagesFileOffset = page
+			if err := os.WriteFile(pagesPath, append(make([]byte, pagesFileOffset), image.pages.Bytes()...), 0644); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			pagesFile, err := fd.Open(pagesPath, os.O_RDONLY, 0)
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer pagesFile.Close()
+			restored := newTestMemoryFile(t)
+			opts := LoadOpts{
+				PagesFile:       pagesFile,
+				PagesFileOffset: pagesFileOffset,
+				PagesCodec:      test.codec,
+				PagesAEAD:       aead,
+			}
+			if err := restored.LoadFrom(ctx, bytes.NewReader(image.meta.Bytes()), &opts); err != nil {
+				t.Fatalf("LoadFrom failed: %v", err)
+			}
+			if got, want := opts.PagesFileOffset, uint64(pagesFileOffset+image.pages.Len()); got != want {
+				t.Errorf("LoadFrom advanced PagesFileOffset to %d, want %d", got, want)
+			}
+			// Await pages in the middle of the MemoryFile first, so that
+			// their frames are loaded before the preceding ones.
+			if apl := restored.asyncPageLoad.Load(); apl != nil {
+				mid := memmap.FileRange{fr.Start + 2*pagesFrameSize - page, fr.Start + 2*pagesFrameSize + page}
+				restored.IncRef(mid, 0)
+				if err := apl.awaitLoad(restored, mid); err != nil {
+					t.Fatalf("awaitLoad failed: %v", err)
+				}
+				restored.DecRef(mid)
+			}
+			if err := restored.AwaitLoadAll(); err != nil {
+				t.Fatalf("AwaitLoadAll failed: %v", err)
+			}
+			if got, want := readPages(restored, fr), readPages(f, fr); !bytes.Equal(got, want) {
+				t.Errorf("restored pages differ from saved pages")
+			}
+		})
+	}
+}
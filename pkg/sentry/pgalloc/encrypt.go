@@ -0,0 +1,271 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+)
+
+// Pages encrypted by SaveTo() are sealed individually with
+// SaveOpts.PagesAEAD. The nonce of each page consists of a random prefix,
+// chosen by each call to SaveTo(), followed by the index of the page among
+// the pages saved by that call. Each page is authenticated along with its
+// MemoryFile offset. Authentication tags are written to the metadata stream
+// after all page headers, so that the pages file keeps the layout of
+// unencrypted images.
+//
+// If SaveOpts.PagesCodec is also set, pages are compressed first, and each
+// compressed frame (see compress.go) is sealed as a whole instead. The nonce
+// of a frame is formed from its index among the frames saved by the call to
+// SaveTo(), and each frame is authenticated along with its offset into the
+// uncompressed pages.
+const (
+	pageNoncePrefixSize = 8
+	pageNonceSize       = pageNoncePrefixSize + 4
+)
+
+// pageEncryptBufSize is the size of the buffer holding encrypted pages before
+// they're written to the pages file.
+const pageEncryptBufSize = 4 << 20
+
+// errPageDecryption is returned when a page fails authentication.
+var errPageDecryption = errors.New("failed to decrypt pages: wrong key or corrupted data")
+
+func checkPagesAEAD(aead cipher.AEAD) error {
+	if aead.NonceSize() != pageNonceSize {
+		return fmt.Errorf("pages AEAD has nonce size %d, want %d", aead.NonceSize(), pageNonceSize)
+	}
+	return nil
+}
+
+// checkPageTags returns an error if tags doesn't hold n authentication tags
+// for aead.
+func checkPageTags(aead cipher.AEAD, tags []byte, n uint64) error {
+	if want := n * uint64(aead.Overhead()); uint64(len(tags)) != want {
+		return fmt.Errorf("mismatched page authentication tags: expected %d bytes, got %d", want, len(tags))
+	}
+	return nil
+}
+
+func pageNonce(nonce []byte, prefix uint64, index uint64) {
+	binary.BigEndian.PutUint64(nonce[:pageNoncePrefixSize], prefix)
+	binary.BigEndian.PutUint32(nonce[pageNoncePrefixSize:], uint32(index))
+}
+
+func pageAAD(aad []byte, off uint64) {
+	binary.BigEndian.PutUint64(aad, off)
+}
+
+// pageEncrypter encrypts pages written to a pages file by SaveTo().
+type pageEncrypter struct {
+	aead   cipher.AEAD
+	prefix uint64
+	index  uint64
+	nonce  [pageNonceSize]byte
+	aad    [8]byte
+
+	// tags are the authentication tags of encrypted pages, in order.
+	tags []byte
+
+	// buf holds encrypted pages not yet written to the pages file. It is
+	// page-aligned, so that the pages file may be opened with O_DIRECT, and
+	// has an extra page for the tag appended by sealing its last page. n is
+	// the number of bytes of encrypted pages in buf.
+	buf []byte
+	n   int
+}
+
+func newPageEncrypter(aead cipher.AEAD) (*pageEncrypter, error) {
+	if err := checkPagesAEAD(aead); err != nil {
+		return nil, err
+	}
+	var prefix [pageNoncePrefixSize]byte
+	if _, err := rand.Read(prefix[:]); err != nil {
+		return nil, err
+	}
+	buf, err := unix.Mmap(-1, 0, pageEncryptBufSize+hostarch.PageSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate encryption buffer: %w", err)
+	}
+	return &pageEncrypter{
+		aead:   aead,
+		prefix: binary.BigEndian.Uint64(prefix[:]),
+		buf:    buf,
+	}, nil
+}
+
+// release releases resources held by e.
+func (e *pageEncrypter) release() {
+	unix.Munmap(e.buf)
+}
+
+// write encrypts the pages in s, whose first page is at MemoryFile offset
+// off, to pw.
+func (e *pageEncrypter) write(pw io.Writer, s []byte, off uint64) error {
+	for pgoff := 0; pgoff < len(s); pgoff += hostarch.PageSize {
+		if e.index > math.MaxUint32 {
+			return fmt.Errorf("too many pages to encrypt")
+		}
+		pageNonce(e.nonce[:], e.prefix, e.index)
+		pageAAD(e.aad[:], off+uint64(pgoff))
+		sealed := e.aead.Seal(e.buf[e.n:e.n], e.nonce[:], s[pgoff:pgoff+hostarch.PageSize], e.aad[:])
+		e.tags = append(e.tags, sealed[hostarch.PageSize:]...)
+		e.index++
+		e.n += hostarch.PageSize
+		if e.n == pageEncryptBufSize {
+			if err := e.flush(pw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sealFrame encrypts the given compressed frame, whose offset into the
+// uncompressed pages is off. The returned slice is valid until the next call
+// to sealFrame().
+//
+// Preconditions: sealFrame() and write() can't both be used with e.
+func (e *pageEncrypter) sealFrame(data []byte, off uint64) ([]byte, error) {
+	if e.index > math.MaxUint32 {
+		return nil, fmt.Errorf("too many frames to encrypt")
+	}
+	pageNonce(e.nonce[:], e.prefix, e.index)
+	pageAAD(e.aad[:], off)
+	sealed := e.aead.Seal(e.buf[:0], e.nonce[:], data, e.aad[:])
+	e.tags = append(e.tags, sealed[len(data):]...)
+	e.index++
+	return sealed[:len(data)], nil
+}
+
+// flush writes buffered encrypted pages to pw.
+func (e *pageEncrypter) flush(pw io.Writer) error {
+	if e.n == 0 {
+		return nil
+	}
+	_, err := pw.Write(e.buf[:e.n])
+	e.n = 0
+	return err
+}
+
+// pageDecrypter decrypts pages read from a pages file by the async page
+// loader.
+type pageDecrypter struct {
+	aead   cipher.AEAD
+	prefix uint64
+	tags   []byte
+
+	// base is the pages file offset of the first page saved by the call to
+	// SaveTo() that encrypted the pages.
+	base uint64
+
+	nonce   [pageNonceSize]byte
+	aad     [8]byte
+	scratch []byte
+}
+
+func newPageDecrypter(aead cipher.AEAD, prefix uint64, tags []byte, base uint64) *pageDecrypter {
+	return &pageDecrypter{
+		aead:    aead,
+		prefix:  prefix,
+		tags:    tags,
+		base:    base,
+		scratch: make([]byte, hostarch.PageSize+aead.Overhead()),
+	}
+}
+
+// decrypt decrypts, in place, the pages in s, whose first page is at
+// MemoryFile offset off and was read from pages file offset foff.
+func (d *pageDecrypter) decrypt(s []byte, off, foff uint64) error {
+	overhead := d.aead.Overhead()
+	for pgoff := 0; pgoff < len(s); pgoff += hostarch.PageSize {
+		index := (foff + uint64(pgoff) - d.base) / hostarch.PageSize
+		tagOff := index * uint64(overhead)
+		if tagOff+uint64(overhead) > uint64(len(d.tags)) {
+			return errPageDecryption
+		}
+		pg := s[pgoff : pgoff+hostarch.PageSize]
+		scratch := d.scratch[:hostarch.PageSize+overhead]
+		copy(scratch, pg)
+		copy(scratch[hostarch.PageSize:], d.tags[tagOff:tagOff+uint64(overhead)])
+		pageNonce(d.nonce[:], d.prefix, index)
+		pageAAD(d.aad[:], off+uint64(pgoff))
+		if _, err := d.aead.Open(pg[:0], d.nonce[:], scratch, d.aad[:]); err != nil {
+			return errPageDecryption
+		}
+	}
+	return nil
+}
+
+// decryptFrame decrypts, in place, the compressed frame in data, whose offset
+// into the uncompressed pages is off.
+func (d *pageDecrypter) decryptFrame(data []byte, off uint64) error {
+	overhead := d.aead.Overhead()
+	index := off / pagesFrameSize
+	tagOff := index * uint64(overhead)
+	if tagOff+uint64(overhead) > uint64(len(d.tags)) {
+		return errPageDecryption
+	}
+	if n := len(data) + overhead; cap(d.scratch) < n {
+		d.scratch = make([]byte, n)
+	}
+	scratch := d.scratch[:len(data)+overhead]
+	copy(scratch, data)
+	copy(scratch[len(data):], d.tags[tagOff:tagOff+uint64(overhead)])
+	pageNonce(d.nonce[:], d.prefix, index)
+	pageAAD(d.aad[:], off)
+	if _, err := d.aead.Open(data[:0], d.nonce[:], scratch, d.aad[:]); err != nil {
+		return errPageDecryption
+	}
+	return nil
+}
+
+// decryptOp decrypts the pages read by op.
+func (g *aplGoroutine) decryptOp(op *aplOp) error {
+	foff := uint64(op.off())
+	var err error
+	for _, fr := range op.frs() {
+		off := fr.Start
+		g.f.forEachMappingSlice(fr, func(s []byte) {
+			if err == nil {
+				err = g.decrypter.decrypt(s, off, foff)
+			}
+			off += uint64(len(s))
+			foff += uint64(len(s))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aplPendingRange is a range of pages whose insertion into
+// aplShared.unloaded is deferred until LoadFrom() has read the
+// authentication tags of encrypted pages.
+type aplPendingRange struct {
+	fr  memmap.FileRange
+	off uint64
+}
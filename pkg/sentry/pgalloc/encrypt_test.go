@@ -0,0 +1,101 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"os"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+)
+
+func newTestAEAD(t *testing.T) cipher.AEAD {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("rand.Read failed: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("aes.NewCipher failed: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("cipher.NewGCM failed: %v", err)
+	}
+	return aead
+}
+
+func TestEncryptedSaveLoad(t *testing.T) {
+	ctx := context.Background()
+	f := newTestMemoryFile(t)
+	fr, err := f.Allocate(4*page, AllocOpts{Kind: usage.Anonymous, Mode: AllocateAndCommit})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	for i := uint64(0); i < 4; i++ {
+		fillPage(f, fr.Start+i*page, byte(i+1))
+	}
+
+	aead := newTestAEAD(t)
+	var image savedImage
+	if err := f.SaveTo(ctx, &image.meta, &image.pages, SaveOpts{SaveID: "a", PagesAEAD: aead}); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+	if got, want := image.pages.Len(), 4*page; got != want {
+		t.Errorf("image holds %d bytes of pages, want %d", got, want)
+	}
+	if bytes.Contains(image.pages.Bytes(), readPages(f, fr)[:page]) {
+		t.Errorf("pages file contains plaintext pages")
+	}
+
+	pagesPath := t.TempDir() + "/pages"
+	if err := os.WriteFile(pagesPath, image.pages.Bytes(), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	load := func(aead cipher.AEAD) (*MemoryFile, error) {
+		pagesFile, err := fd.Open(pagesPath, os.O_RDONLY, 0)
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		defer pagesFile.Close()
+		restored := newTestMemoryFile(t)
+		opts := LoadOpts{
+			PagesFile: pagesFile,
+			PagesAEAD: aead,
+		}
+		if err := restored.LoadFrom(ctx, bytes.NewReader(image.meta.Bytes()), &opts); err != nil {
+			return nil, err
+		}
+		return restored, restored.AwaitLoadAll()
+	}
+
+	restored, err := load(aead)
+	if err != nil {
+		t.Fatalf("loading with the right key failed: %v", err)
+	}
+	if got, want := readPages(restored, fr), readPages(f, fr); !bytes.Equal(got, want) {
+		t.Errorf("restored pages differ from saved pages")
+	}
+	if _, err := load(newTestAEAD(t)); err == nil {
+		t.Errorf("loading with the wrong key succeeded, want error")
+	}
+}
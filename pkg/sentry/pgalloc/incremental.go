@@ -0,0 +1,522 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"context"
+	"crypto/cipher"
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/compressio"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/state"
+	"gvisor.dev/gvisor/pkg/state/wire"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// mergeBufSize is the size of the buffer used by MergeSavedMemoryFiles() to
+// copy pages.
+const mergeBufSize = 1 << 20
+
+// TrackingWrites returns true if f is tracking writes to its pages; see
+// SaveOpts.SaveID.
+func (f *MemoryFile) TrackingWrites() bool {
+	return f.trackingWrites.Load()
+}
+
+// MarkDirty records that pages in fr may have been written, if f is tracking
+// writes. Writes through mappings returned by MapInternal() with write access
+// are recorded when the mappings are returned; users of f that write to it in
+// other ways, such as through platform.AddressSpace mappings or internal
+// mappings retained across saves, must call MarkDirty() before each write.
+func (f *MemoryFile) MarkDirty(fr memmap.FileRange) {
+	if !f.trackingWrites.Load() {
+		return
+	}
+	f.dirty.add(fr)
+}
+
+// setSaveIDLocked sets f.saveID, and starts tracking writes to f if saveID is
+// not empty. Callers must take pages tracked under a previous save ID from
+// f.dirty before calling setSaveIDLocked with a new one. If saveID is empty,
+// setSaveIDLocked stops tracking writes and discards tracked pages.
+//
+// Preconditions: f.mu must be locked.
+func (f *MemoryFile) setSaveIDLocked(saveID string) {
+	f.saveID = saveID
+	f.trackingWrites.Store(saveID != "")
+	if saveID == "" {
+		f.dirty.take()
+	}
+}
+
+// dirtyPages is a set of pages of a MemoryFile that may have been written.
+// Pages may be added to a dirtyPages concurrently.
+type dirtyPages struct {
+	// mu protects words. Bits in words are set using atomic memory operations
+	// with mu locked for reading, so that concurrent calls to add() don't
+	// serialize; words is only replaced with mu locked for writing.
+	mu sync.RWMutex
+
+	// words is a bitmap of page indices, which are offsets into the
+	// MemoryFile divided by hostarch.PageSize: page i is in the set if bit
+	// i%64 of words[i/64] is set.
+	//
+	// +checklocks:mu
+	words []atomicbitops.Uint64
+}
+
+// add adds the pages in fr to d.
+func (d *dirtyPages) add(fr memmap.FileRange) {
+	if fr.Length() == 0 {
+		return
+	}
+	first := fr.Start / hostarch.PageSize
+	last := (fr.End - 1) / hostarch.PageSize
+	d.mu.RLock()
+	words := d.words
+	if last/64 >= uint64(len(words)) {
+		d.mu.RUnlock()
+		d.grow(last/64 + 1)
+		d.mu.RLock()
+		words = d.words
+	}
+	for i := first / 64; i <= last/64; i++ {
+		mask := ^uint64(0)
+		if i == first/64 {
+			mask &= ^uint64(0) << (first % 64)
+		}
+		if i == last/64 {
+			mask &= ^uint64(0) >> (63 - last%64)
+		}
+		// Pages are usually written repeatedly between saves, so avoid
+		// writing words whose bits are already set.
+		for {
+			old := words[i].Load()
+			if old&mask == mask || words[i].CompareAndSwap(old, old|mask) {
+				break
+			}
+		}
+	}
+	d.mu.RUnlock()
+}
+
+// grow ensures that d.words has at least n words.
+func (d *dirtyPages) grow(n uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if n <= uint64(len(d.words)) {
+		return
+	}
+	words := make([]atomicbitops.Uint64, max(n, 2*uint64(len(d.words))))
+	for i := range d.words {
+		words[i].Store(d.words[i].Load())
+	}
+	d.words = words
+}
+
+// take removes all pages from d and returns them, as a bitmap with the same
+// layout as d.words. Pages added concurrently are either returned or remain
+// in d.
+func (d *dirtyPages) take() []atomicbitops.Uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	words := d.words
+	d.words = make([]atomicbitops.Uint64, len(words))
+	return words
+}
+
+// appendFileRange appends fr to frs, merging it with the last range in frs
+// if they're adjacent.
+func appendFileRange(frs []memmap.FileRange, fr memmap.FileRange) []memmap.FileRange {
+	if n := len(frs); n != 0 && frs[n-1].End == fr.Start {
+		frs[n-1].End = fr.End
+		return frs
+	}
+	return append(frs, fr)
+}
+
+// appendDirtyFileRanges appends the ranges of pages in fr that are in dirty,
+// a bitmap returned by dirtyPages.take(), to frs.
+func appendDirtyFileRanges(frs []memmap.FileRange, dirty []atomicbitops.Uint64, fr memmap.FileRange) []memmap.FileRange {
+	end := min(fr.End/hostarch.PageSize, uint64(len(dirty))*64)
+	for i := fr.Start / hostarch.PageSize; i < end; {
+		word := dirty[i/64].RacyLoad() >> (i % 64)
+		if word == 0 {
+			i = (i/64 + 1) * 64
+			continue
+		}
+		i += uint64(bits.TrailingZeros64(word))
+		if i >= end {
+			break
+		}
+		off := i * hostarch.PageSize
+		frs = appendFileRange(frs, memmap.FileRange{off, off + hostarch.PageSize})
+		i++
+	}
+	return frs
+}
+
+// SavedMemoryFile is a MemoryFile saved by SaveTo() with a separate pages
+// file, read without being loaded. It is used to merge incremental images
+// with their parents.
+type SavedMemoryFile struct {
+	// Metadata, in the order written by SaveTo().
+	unwasteSmall        unwasteSet
+	unwasteHuge         unwasteSet
+	unfreeSmall         unfreeSet
+	unfreeHuge          unfreeSet
+	subreleased         map[uint64]uint64
+	memAcct             memAcctSet
+	knownCommittedBytes uint64
+	commitSeq           uint64
+	chunks              []chunkInfo
+
+	// pages are the ranges of the MemoryFile whose contents are saved in the
+	// image, in ascending order. pageOffs[i] is the offset of pages[i] into
+	// the pages saved by the image, before any compression. base is the
+	// offset of the image's first page in its pages file.
+	pages    []memmap.FileRange
+	pageOffs []uint64
+	base     uint64
+
+	// If decrypter is not nil, the image's pages are encrypted. If codec is
+	// not nil, they're compressed in frames.
+	decrypter *pageDecrypter
+	codec     compressio.Codec
+	frames    []aplFrame
+
+	// frame is the last frame read by readFrame(), or nil. frameData holds
+	// its uncompressed contents. frameBuf and frameScratch are buffers used
+	// to read and decompress frames.
+	frame        *aplFrame
+	frameData    []byte
+	frameBuf     []byte
+	frameScratch []byte
+}
+
+func (s *SavedMemoryFile) metadata() []any {
+	return []any{
+		&s.unwasteSmall,
+		&s.unwasteHuge,
+		&s.unfreeSmall,
+		&s.unfreeHuge,
+		&s.subreleased,
+		&s.memAcct,
+		&s.knownCommittedBytes,
+		&s.commitSeq,
+		&s.chunks,
+	}
+}
+
+// ReadSavedOpts provides options to ReadSavedMemoryFile().
+type ReadSavedOpts struct {
+	// Incremental must be true if the MemoryFile was saved with a
+	// ParentSaveID.
+	Incremental bool
+
+	// PagesAEAD and PagesCodec must be equivalent to the SaveOpts fields of
+	// the same names used to save the MemoryFile.
+	PagesAEAD  cipher.AEAD
+	PagesCodec compressio.Codec
+}
+
+// ReadSavedMemoryFile reads a MemoryFile saved by SaveTo() from r, which
+// must be the pages metadata stream passed as w to SaveTo(). pagesOffset is
+// the offset of the MemoryFile's pages in the pages file; it's incremented by
+// the number of bytes used by the MemoryFile.
+func ReadSavedMemoryFile(ctx context.Context, r io.Reader, pagesOffset *uint64, opts ReadSavedOpts) (*SavedMemoryFile, error) {
+	if opts.PagesAEAD != nil {
+		if err := checkPagesAEAD(opts.PagesAEAD); err != nil {
+			return nil, err
+		}
+	}
+	s := &SavedMemoryFile{
+		base:  *pagesOffset,
+		codec: opts.PagesCodec,
+	}
+	for _, obj := range s.metadata() {
+		if _, err := state.Load(ctx, r, obj); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Incremental {
+		if _, err := state.Load(ctx, r, &s.pages); err != nil {
+			return nil, err
+		}
+	} else {
+		for maseg := s.memAcct.FirstSegment(); maseg.Ok(); maseg = maseg.NextSegment() {
+			if maseg.ValuePtr().knownCommitted {
+				s.pages = append(s.pages, maseg.Range())
+			}
+		}
+	}
+
+	wr := wire.Reader{Reader: r}
+	s.pageOffs = make([]uint64, len(s.pages))
+	savedBytes := uint64(0)
+	for i, fr := range s.pages {
+		length, object, err := state.ReadHeader(&wr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+		if object {
+			return nil, fmt.Errorf("unexpected object")
+		}
+		if length != fr.Length() {
+			return nil, fmt.Errorf("mismatched segment: expected %d, got %d", fr.Length(), length)
+		}
+		s.pageOffs[i] = savedBytes
+		savedBytes += length
+	}
+
+	// Read the metadata written after the page headers by pagesWriter.finish().
+	var (
+		prefix uint64
+		tags   []byte
+	)
+	if opts.PagesAEAD != nil {
+		if _, err := state.Load(ctx, r, &prefix); err != nil {
+			return nil, fmt.Errorf("failed to load page nonce prefix: %w", err)
+		}
+		if _, err := state.Load(ctx, r, &tags); err != nil {
+			return nil, fmt.Errorf("failed to load page authentication tags: %w", err)
+		}
+	}
+	fileBytes := savedBytes
+	sealed := savedBytes / hostarch.PageSize
+	if opts.PagesCodec != nil {
+		var frameLens []uint32
+		if _, err := state.Load(ctx, r, &frameLens); err != nil {
+			return nil, fmt.Errorf("failed to load compressed frame lengths: %w", err)
+		}
+		var err error
+		if s.frames, fileBytes, err = newAPLFrames(nil, frameLens, savedBytes, s.base); err != nil {
+			return nil, err
+		}
+		sealed = uint64(len(s.frames))
+	}
+	if opts.PagesAEAD != nil {
+		if err := checkPageTags(opts.PagesAEAD, tags, sealed); err != nil {
+			return nil, err
+		}
+		s.decrypter = newPageDecrypter(opts.PagesAEAD, prefix, tags, s.base)
+	}
+	*pagesOffset += fileBytes
+	return s, nil
+}
+
+// readPages reads the saved contents of the pages at MemoryFile offset mfOff
+// into dst from pr, the image's pages file. off is the offset of the pages
+// into the pages saved by the image.
+func (s *SavedMemoryFile) readPages(pr io.ReaderAt, dst []byte, off, mfOff uint64) error {
+	if s.codec == nil {
+		foff := s.base + off
+		if n, err := pr.ReadAt(dst, int64(foff)); n != len(dst) {
+			return fmt.Errorf("failed to read pages: %w", err)
+		}
+		if s.decrypter != nil {
+			return s.decrypter.decrypt(dst, mfOff, foff)
+		}
+		return nil
+	}
+	for len(dst) != 0 {
+		if err := s.readFrame(pr, off); err != nil {
+			return err
+		}
+		n := copy(dst, s.frameData[off-s.frame.off:])
+		dst = dst[n:]
+		off += uint64(n)
+	}
+	return nil
+}
+
+// readFrame sets s.frame to the frame containing the page at the given offset
+// into the pages saved by the image, and s.frameData to its contents.
+func (s *SavedMemoryFile) readFrame(pr io.ReaderAt, off uint64) error {
+	if s.frame != nil && s.frame.off <= off && off < s.frame.off+s.frame.len {
+		return nil
+	}
+	i := sort.Search(len(s.frames), func(i int) bool {
+		return s.frames[i].off+s.frames[i].len > off
+	})
+	if i == len(s.frames) {
+		return fmt.Errorf("no compressed frame holds saved page at offset %d", off)
+	}
+	frame := &s.frames[i]
+	if cap(s.frameBuf) < int(frame.flen) {
+		s.frameBuf = make([]byte, pagesFrameSize)
+	}
+	data := s.frameBuf[:frame.flen]
+	s.frame = nil
+	if n, err := pr.ReadAt(data, int64(frame.foff)); n != len(data) {
+		return fmt.Errorf("failed to read frame at pages file offset %d: %w", frame.foff, err)
+	}
+	if s.decrypter != nil {
+		if err := s.decrypter.decryptFrame(data, frame.off); err != nil {
+			return fmt.Errorf("frame at pages file offset %d: %w", frame.foff, err)
+		}
+	}
+	if uint64(frame.flen) != frame.len {
+		out, err := s.codec.Decompress(s.frameScratch[:0], data, int(frame.len))
+		if err != nil {
+			return fmt.Errorf("failed to decompress frame at pages file offset %d: %w", frame.foff, err)
+		}
+		if uint64(len(out)) != frame.len {
+			return fmt.Errorf("frame at pages file offset %d decompressed to %d bytes, expected %d", frame.foff, len(out), frame.len)
+		}
+		s.frameScratch = out
+		data = out
+	}
+	s.frame = frame
+	s.frameData = data
+	return nil
+}
+
+// MergeOpts provides options to MergeSavedMemoryFiles().
+type MergeOpts struct {
+	// PagesAEAD and PagesCodec are used to encrypt and compress the merged
+	// pages, as by the SaveOpts fields of the same names.
+	PagesAEAD  cipher.AEAD
+	PagesCodec compressio.Codec
+}
+
+// MergeSavedMemoryFiles writes chain[0] to w and pw as a full image, in the
+// format written by SaveTo() without a ParentSaveID. chain[i+1] must be the
+// parent of chain[i], and pages[i] must be the pages file of chain[i]. Page
+// contents are read from the newest image that holds them.
+func MergeSavedMemoryFiles(ctx context.Context, chain []*SavedMemoryFile, pages []io.ReaderAt, w, pw io.Writer, opts MergeOpts) error {
+	if len(chain) == 0 || len(chain) != len(pages) {
+		return fmt.Errorf("invalid image chain: %d images, %d pages files", len(chain), len(pages))
+	}
+	s := chain[0]
+	for _, obj := range s.metadata() {
+		if _, err := state.Save(ctx, w, obj); err != nil {
+			return err
+		}
+	}
+
+	out, err := newPagesWriter(pw, opts.PagesAEAD, opts.PagesCodec)
+	if err != nil {
+		return err
+	}
+	defer out.release()
+	buf := make([]byte, mergeBufSize)
+	ww := wire.Writer{Writer: w}
+	for maseg := s.memAcct.FirstSegment(); maseg.Ok(); maseg = maseg.NextSegment() {
+		if !maseg.ValuePtr().knownCommitted {
+			continue
+		}
+		fr := maseg.Range()
+		if err := state.WriteHeader(&ww, fr.Length(), false); err != nil {
+			return err
+		}
+		for fr.Length() != 0 {
+			n, err := copySavedPages(chain, pages, fr, buf, out)
+			if err != nil {
+				return err
+			}
+			fr.Start += n
+		}
+	}
+	return out.finish(ctx, w)
+}
+
+// WriteSparse writes s to w and pw as a full image in the layout read by
+// LoadFrom() with LoadOpts.PagesSparse, with the page at each MemoryFile
+// offset at base plus that offset in pw; pages is the pages file of s.
+// WriteSparse only writes the pages held by s, so other known-committed pages
+// must already be in pw, or be zero: e.g. if s is the incremental image whose
+// parent was pre-copied by PreCopyTo(), pw may be the pages file passed to
+// ReadPreCopy(), with a base of 0. WriteSparse returns the size of the region
+// of pw used by s, which is the size of the MemoryFile.
+func (s *SavedMemoryFile) WriteSparse(ctx context.Context, pages io.ReaderAt, w io.Writer, pw io.WriterAt, base uint64) (uint64, error) {
+	if s.decrypter != nil || s.codec != nil {
+		return 0, fmt.Errorf("encrypted or compressed pages can't be written sparsely")
+	}
+	for _, obj := range s.metadata() {
+		if _, err := state.Save(ctx, w, obj); err != nil {
+			return 0, err
+		}
+	}
+	ww := wire.Writer{Writer: w}
+	for maseg := s.memAcct.FirstSegment(); maseg.Ok(); maseg = maseg.NextSegment() {
+		if !maseg.ValuePtr().knownCommitted {
+			continue
+		}
+		if err := state.WriteHeader(&ww, maseg.Range().Length(), false); err != nil {
+			return 0, err
+		}
+	}
+
+	size := uint64(len(s.chunks)) * chunkSize
+	buf := make([]byte, mergeBufSize)
+	for i, fr := range s.pages {
+		if fr.End > size {
+			return 0, fmt.Errorf("saved pages %v are beyond the end of the MemoryFile (%d bytes)", fr, size)
+		}
+		for done := uint64(0); done < fr.Length(); {
+			n := min(fr.Length()-done, uint64(len(buf)))
+			if err := s.readPages(pages, buf[:n], s.pageOffs[i]+done, fr.Start+done); err != nil {
+				return 0, err
+			}
+			if _, err := pw.WriteAt(buf[:n], int64(base+fr.Start+done)); err != nil {
+				return 0, fmt.Errorf("failed to write pages: %w", err)
+			}
+			done += n
+		}
+	}
+	return size, nil
+}
+
+// copySavedPages copies a non-empty prefix of fr from the newest image in
+// chain that holds it to out, using buf, and returns the prefix's length.
+func copySavedPages(chain []*SavedMemoryFile, pages []io.ReaderAt, fr memmap.FileRange, buf []byte, out *pagesWriter) (uint64, error) {
+	for i, s := range chain {
+		j := sort.Search(len(s.pages), func(j int) bool {
+			return s.pages[j].End > fr.Start
+		})
+		if j < len(s.pages) && s.pages[j].Start <= fr.Start {
+			saved := s.pages[j]
+			n := min(fr.End, saved.End, fr.Start+uint64(len(buf))) - fr.Start
+			off := s.pageOffs[j] + (fr.Start - saved.Start)
+			if err := s.readPages(pages[i], buf[:n], off, fr.Start); err != nil {
+				return 0, err
+			}
+			if err := out.write(buf[:n], fr.Start); err != nil {
+				return 0, fmt.Errorf("failed to write pages: %w", err)
+			}
+			return n, nil
+		}
+		// Pages saved by this image are newer than any parent's, so only
+		// look in parents up to the next range saved by this image.
+		if j < len(s.pages) {
+			fr.End = min(fr.End, s.pages[j].Start)
+		}
+	}
+	// Pages that aren't saved by any image weren't committed when the oldest
+	// image was saved, and haven't been written since, so they're zero.
+	n := min(fr.Length(), uint64(len(buf)))
+	clear(buf[:n])
+	if err := out.write(buf[:n], fr.Start); err != nil {
+		return 0, fmt.Errorf("failed to write zero pages: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,537 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"io"
+	"os"
+	"slices"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/compressio"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+)
+
+func newTestMemoryFile(t *testing.T) *MemoryFile {
+	t.Helper()
+	fd, err := unix.MemfdCreate("pgalloc_test", 0)
+	if err != nil {
+		t.Skipf("memfd_create failed: %v", err)
+	}
+	f, err := NewMemoryFile(os.NewFile(uintptr(fd), "pgalloc_test"), MemoryFileOpts{
+		DelayedEviction:         DelayedEvictionDisabled,
+		DisableMemoryAccounting: true,
+	})
+	if err != nil {
+		t.Fatalf("NewMemoryFile failed: %v", err)
+	}
+	t.Cleanup(f.Destroy)
+	return f
+}
+
+// fillPage fills the page at off with b, and marks it dirty as writers of f
+// must.
+func fillPage(f *MemoryFile, off uint64, b byte) {
+	fr := memmap.FileRange{off, off + page}
+	f.forEachMappingSlice(fr, func(s []byte) {
+		for i := range s {
+			s[i] = b
+		}
+	})
+	f.MarkDirty(fr)
+}
+
+// readPages returns the contents of fr.
+func readPages(f *MemoryFile, fr memmap.FileRange) []byte {
+	var b []byte
+	f.forEachMappingSlice(fr, func(s []byte) {
+		b = append(b, s...)
+	})
+	return b
+}
+
+type savedImage struct {
+	meta  bytes.Buffer
+	pages bytes.Buffer
+}
+
+func TestIncrementalSaveMerge(t *testing.T) {
+	ctx := context.Background()
+	f := newTestMemoryFile(t)
+	fr, err := f.Allocate(4*page, AllocOpts{Kind: usage.Anonymous, Mode: AllocateAndCommit})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	for i := uint64(0); i < 4; i++ {
+		fillPage(f, fr.Start+i*page, byte(i+1))
+	}
+
+	// Save a full image, then two incremental images each changing one page.
+	var images [3]savedImage
+	if err := f.SaveTo(ctx, &images[2].meta, &images[2].pages, SaveOpts{SaveID: "a"}); err != nil {
+		t.Fatalf("SaveTo(full) failed: %v", err)
+	}
+	fillPage(f, fr.Start+page, 0x11)
+	if err := f.SaveTo(ctx, &images[1].meta, &images[1].pages, SaveOpts{SaveID: "b", ParentSaveID: "a"}); err != nil {
+		t.Fatalf("SaveTo(incremental) failed: %v", err)
+	}
+	fillPage(f, fr.Start+3*page, 0x33)
+	if err := f.SaveTo(ctx, &images[0].meta, &images[0].pages, SaveOpts{SaveID: "c", ParentSaveID: "b"}); err != nil {
+		t.Fatalf("SaveTo(incremental) failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if got := images[i].pages.Len(); got != page {
+			t.Errorf("incremental image %d holds %d bytes of pages, want %d", i, got, page)
+		}
+	}
+
+	var (
+		chain []*SavedMemoryFile
+		pages []io.ReaderAt
+	)
+	for i := range images {
+		var off uint64
+		s, err := ReadSavedMemoryFile(ctx, &images[i].meta, &off, ReadSavedOpts{Incremental: i != len(images)-1})
+		if err != nil {
+			t.Fatalf("ReadSavedMemoryFile(%d) failed: %v", i, err)
+		}
+		if got, want := off, uint64(images[i].pages.Len()); got != want {
+			t.Errorf("image %d pages offset: got %d, want %d", i, got, want)
+		}
+		chain = append(chain, s)
+		pages = append(pages, bytes.NewReader(images[i].pages.Bytes()))
+	}
+	// Write a single stream, which LoadFrom reads without a pages file.
+	var merged bytes.Buffer
+	if err := MergeSavedMemoryFiles(ctx, chain, pages, &merged, &merged, MergeOpts{}); err != nil {
+		t.Fatalf("MergeSavedMemoryFiles failed: %v", err)
+	}
+
+	restored := newTestMemoryFile(t)
+	if err := restored.LoadFrom(ctx, &merged, &LoadOpts{}); err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if got, want := readPages(restored, fr), readPages(f, fr); !bytes.Equal(got, want) {
+		t.Errorf("restored pages differ from saved pages")
+	}
+}
+
+func TestIncrementalSaveMergeEncryptedCompressed(t *testing.T) {
+	ctx := context.Background()
+	f := newTestMemoryFile(t)
+	// Span several compressed frames.
+	const numPages = 2*pagesFrameSize/page + 3
+	fr, err := f.Allocate(numPages*page, AllocOpts{Kind: usage.Anonymous, Mode: AllocateAndCommit})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	for i := uint64(0); i < numPages; i++ {
+		fillPage(f, fr.Start+i*page, byte(i+1))
+	}
+
+	// Each image is encrypted with a different key, as if keyed by its save
+	// ID, and compressed.
+	codec := compressio.NewLZ4Codec()
+	aeads := []cipher.AEAD{newTestAEAD(t), newTestAEAD(t)}
+	var images [2]savedImage
+	if err := f.SaveTo(ctx, &images[1].meta, &images[1].pages, SaveOpts{SaveID: "a", PagesAEAD: aeads[1], PagesCodec: codec}); err != nil {
+		t.Fatalf("SaveTo(full) failed: %v", err)
+	}
+	fillPage(f, fr.Start+page, 0x11)
+	fillPage(f, fr.Start+(numPages-1)*page, 0x22)
+	if err := f.SaveTo(ctx, &images[0].meta, &images[0].pages, SaveOpts{SaveID: "b", ParentSaveID: "a", PagesAEAD: aeads[0], PagesCodec: codec}); err != nil {
+		t.Fatalf("SaveTo(incremental) failed: %v", err)
+	}
+
+	merge := func(aeads []cipher.AEAD, opts MergeOpts) (*savedImage, error) {
+		var (
+			chain []*SavedMemoryFile
+			pages []io.ReaderAt
+		)
+		for i := range images {
+			var off uint64
+			s, err := ReadSavedMemoryFile(ctx, bytes.NewReader(images[i].meta.Bytes()), &off, ReadSavedOpts{
+				Incremental: i != len(images)-1,
+				PagesAEAD:   aeads[i],
+				PagesCodec:  codec,
+			})
+			if err != nil {
+				t.Fatalf("ReadSavedMemoryFile(%d) failed: %v", i, err)
+			}
+			if got, want := off, uint64(images[i].pages.Len()); got != want {
+				t.Errorf("image %d pages offset: got %d, want %d", i, got, want)
+			}
+			chain = append(chain, s)
+			pages = append(pages, bytes.NewReader(images[i].pages.Bytes()))
+		}
+		var merged savedImage
+		return &merged, MergeSavedMemoryFiles(ctx, chain, pages, &merged.meta, &merged.pages, opts)
+	}
+	if _, err := merge([]cipher.AEAD{aeads[1], aeads[0]}, MergeOpts{}); err == nil {
+		t.Errorf("merging with the wrong keys succeeded, want error")
+	}
+	opts := MergeOpts{
+		PagesAEAD:  newTestAEAD(t),
+		PagesCodec: codec,
+	}
+	merged, err := merge(aeads, opts)
+	if err != nil {
+		t.Fatalf("MergeSavedMemoryFiles failed: %v", err)
+	}
+
+	pagesPath := t.TempDir() + "/pages"
+	if err := os.WriteFile(pagesPath, merged.pages.Bytes(), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	pagesFile, err := fd.Open(pagesPath, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer pagesFile.Close()
+	restored := newTestMemoryFile(t)
+	loadOpts := LoadOpts{
+		PagesFile:  pagesFile,
+		PagesAEAD:  opts.PagesAEAD,
+		PagesCodec: opts.PagesCodec,
+	}
+	if err := restored.LoadFrom(ctx, &merged.meta, &loadOpts); err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if err := restored.AwaitLoadAll(); err != nil {
+		t.Fatalf("AwaitLoadAll failed: %v", err)
+	}
+	if got, want := readPages(restored, fr), readPages(f, fr); !bytes.Equal(got, want) {
+		t.Errorf("restored pages differ from saved pages")
+	}
+}
+
+func TestPreCopyMerge(t *testing.T) {
+	ctx := context.Background()
+	f := newTestMemoryFile(t)
+	fr, err := f.Allocate(4*page, AllocOpts{Kind: usage.Anonymous, Mode: AllocateAndCommit})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	for i := uint64(0); i < 4; i++ {
+		fillPage(f, fr.Start+i*page, byte(i+1))
+	}
+
+	preCopied, err := os.CreateTemp(t.TempDir(), "precopy")
+	if err != nil {
+		t.Fatalf("CreateTemp failed: %v", err)
+	}
+	defer preCopied.Close()
+	var preCopiedFRs []memmap.FileRange
+	// The first round copies all pages, and each later round copies the page
+	// changed since the previous round.
+	for round, want := range []uint64{4 * page, page, page} {
+		if round > 0 {
+			fillPage(f, fr.Start+uint64(round)*page, byte(0x10*round))
+		}
+		var stream bytes.Buffer
+		sent, err := f.PreCopyTo(ctx, &stream, "precopy", func() {})
+		if err != nil {
+			t.Fatalf("PreCopyTo(round %d) failed: %v", round, err)
+		}
+		if sent != want {
+			t.Errorf("PreCopyTo(round %d) sent %d bytes, want %d", round, sent, want)
+		}
+		frs, err := ReadPreCopy(ctx, &stream, preCopied)
+		if err != nil {
+			t.Fatalf("ReadPreCopy(round %d) failed: %v", round, err)
+		}
+		preCopiedFRs = append(preCopiedFRs, frs...)
+	}
+
+	fillPage(f, fr.Start+3*page, 0x33)
+	var image savedImage
+	if err := f.SaveTo(ctx, &image.meta, &image.pages, SaveOpts{ParentSaveID: "precopy"}); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+	if got := image.pages.Len(); got != page {
+		t.Errorf("final image holds %d bytes of pages, want %d", got, page)
+	}
+	var off uint64
+	s, err := ReadSavedMemoryFile(ctx, &image.meta, &off, ReadSavedOpts{Incremental: true})
+	if err != nil {
+		t.Fatalf("ReadSavedMemoryFile failed: %v", err)
+	}
+	chain := []*SavedMemoryFile{s, PreCopiedMemoryFile(preCopiedFRs)}
+	pages := []io.ReaderAt{bytes.NewReader(image.pages.Bytes()), preCopied}
+	var merged bytes.Buffer
+	if err := MergeSavedMemoryFiles(ctx, chain, pages, &merged, &merged, MergeOpts{}); err != nil {
+		t.Fatalf("MergeSavedMemoryFiles failed: %v", err)
+	}
+
+	restored := newTestMemoryFile(t)
+	if err := restored.LoadFrom(ctx, &merged, &LoadOpts{}); err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if got, want := readPages(restored, fr), readPages(f, fr); !bytes.Equal(got, want) {
+		t.Errorf("restored pages differ from saved pages")
+	}
+}
+
+func TestPreCopySparse(t *testing.T) {
+	ctx := context.Background()
+	f := newTestMemoryFile(t)
+	fr, err := f.Allocate(4*page, AllocOpts{Kind: usage.Anonymous, Mode: AllocateAndCommit})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	for i := uint64(0); i < 4; i++ {
+		fillPage(f, fr.Start+i*page, byte(i+1))
+	}
+	// A second MemoryFile, saved in full, is written after the first.
+	f2 := newTestMemoryFile(t)
+	fr2, err := f2.Allocate(2*page, AllocOpts{Kind: usage.Anonymous, Mode: AllocateAndCommit})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	fillPage(f2, fr2.Start+page, 0x77)
+
+	pagesPath := t.TempDir() + "/pages"
+	preCopied, err := os.Create(pagesPath)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer preCopied.Close()
+	var stream bytes.Buffer
+	if _, err := f.PreCopyTo(ctx, &stream, "precopy", func() {}); err != nil {
+		t.Fatalf("PreCopyTo failed: %v", err)
+	}
+	if _, err := ReadPreCopy(ctx, &stream, preCopied); err != nil {
+		t.Fatalf("ReadPreCopy failed: %v", err)
+	}
+
+	// Pages written or allocated after pre-copy are saved in the final
+	// image.
+	fillPage(f, fr.Start+2*page, 0x22)
+	frNew, err := f.Allocate(page, AllocOpts{Kind: usage.Anonymous, Mode: AllocateAndCommit})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	fillPage(f, frNew.Start, 0x55)
+	var image, image2 savedImage
+	if err := f.SaveTo(ctx, &image.meta, &image.pages, SaveOpts{ParentSaveID: "precopy"}); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+	if got, want := image.pages.Len(), 2*page; got != want {
+		t.Errorf("final image holds %d bytes of pages, want %d", got, want)
+	}
+	if err := f2.SaveTo(ctx, &image2.meta, &image2.pages, SaveOpts{}); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+
+	var (
+		off  uint64
+		meta bytes.Buffer
+	)
+	s, err := ReadSavedMemoryFile(ctx, &image.meta, &off, ReadSavedOpts{Incremental: true})
+	if err != nil {
+		t.Fatalf("ReadSavedMemoryFile failed: %v", err)
+	}
+	size, err := s.WriteSparse(ctx, bytes.NewReader(image.pages.Bytes()), &meta, preCopied, 0)
+	if err != nil {
+		t.Fatalf("WriteSparse failed: %v", err)
+	}
+	off = 0
+	s2, err := ReadSavedMemoryFile(ctx, &image2.meta, &off, ReadSavedOpts{})
+	if err != nil {
+		t.Fatalf("ReadSavedMemoryFile failed: %v", err)
+	}
+	size2, err := s2.WriteSparse(ctx, bytes.NewReader(image2.pages.Bytes()), &meta, preCopied, size)
+	if err != nil {
+		t.Fatalf("WriteSparse failed: %v", err)
+	}
+	if err := preCopied.Truncate(int64(size + size2)); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+
+	pagesFile, err := fd.Open(pagesPath, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer pagesFile.Close()
+	opts := LoadOpts{
+		PagesFile:   pagesFile,
+		PagesSparse: true,
+	}
+	restored := newTestMemoryFile(t)
+	if err := restored.LoadFrom(ctx, &meta, &opts); err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if opts.PagesFileOffset != size {
+		t.Errorf("PagesFileOffset after LoadFrom: got %d, want %d", opts.PagesFileOffset, size)
+	}
+	restored2 := newTestMemoryFile(t)
+	if err := restored2.LoadFrom(ctx, &meta, &opts); err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	for _, mf := range []*MemoryFile{restored, restored2} {
+		if err := mf.AwaitLoadAll(); err != nil {
+			t.Fatalf("AwaitLoadAll failed: %v", err)
+		}
+	}
+	for _, c := range []struct {
+		saved, restored *MemoryFile
+		fr              memmap.FileRange
+	}{
+		{f, restored, memmap.FileRange{fr.Start, frNew.End}},
+		{f2, restored2, fr2},
+	} {
+		if got, want := readPages(c.restored, c.fr), readPages(c.saved, c.fr); !bytes.Equal(got, want) {
+			t.Errorf("restored pages %v differ from saved pages", c.fr)
+		}
+	}
+}
+
+func TestIncrementalSaveTracksWrites(t *testing.T) {
+	ctx := context.Background()
+	f := newTestMemoryFile(t)
+	fr, err := f.Allocate(4*page, AllocOpts{Kind: usage.Anonymous, Mode: AllocateAndCommit})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	for i := uint64(0); i < 4; i++ {
+		fillPage(f, fr.Start+i*page, byte(i+1))
+	}
+	var full savedImage
+	if err := f.SaveTo(ctx, &full.meta, &full.pages, SaveOpts{SaveID: "a"}); err != nil {
+		t.Fatalf("SaveTo(full) failed: %v", err)
+	}
+	if !f.TrackingWrites() {
+		t.Fatalf("TrackingWrites() = false after SaveTo with SaveID")
+	}
+
+	// Read-only mappings don't cause pages to be saved again; writable
+	// mappings and MarkDirty() do.
+	if _, err := f.MapInternal(fr, hostarch.Read); err != nil {
+		t.Fatalf("MapInternal(Read) failed: %v", err)
+	}
+	if _, err := f.MapInternal(memmap.FileRange{fr.Start + page, fr.Start + 2*page}, hostarch.Write); err != nil {
+		t.Fatalf("MapInternal(Write) failed: %v", err)
+	}
+	f.MarkDirty(memmap.FileRange{fr.Start + 3*page, fr.Start + 4*page})
+	var incremental savedImage
+	if err := f.SaveTo(ctx, &incremental.meta, &incremental.pages, SaveOpts{ParentSaveID: "a"}); err != nil {
+		t.Fatalf("SaveTo(incremental) failed: %v", err)
+	}
+	if got, want := incremental.pages.Len(), 2*page; got != want {
+		t.Errorf("incremental image holds %d bytes of pages, want %d", got, want)
+	}
+	if f.TrackingWrites() {
+		t.Errorf("TrackingWrites() = true after SaveTo without SaveID")
+	}
+
+	// Without tracked writes since the parent save, all pages are saved.
+	var untracked savedImage
+	if err := f.SaveTo(ctx, &untracked.meta, &untracked.pages, SaveOpts{ParentSaveID: "a"}); err != nil {
+		t.Fatalf("SaveTo(incremental) failed: %v", err)
+	}
+	if got, want := untracked.pages.Len(), full.pages.Len(); got != want {
+		t.Errorf("untracked incremental image holds %d bytes of pages, want %d", got, want)
+	}
+}
+
+func TestDirtyPages(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		add  []memmap.FileRange
+		in   memmap.FileRange
+		want []memmap.FileRange
+	}{
+		{
+			name: "empty",
+			in:   memmap.FileRange{0, 128 * page},
+		},
+		{
+			name: "merged",
+			add:  []memmap.FileRange{{page, 3 * page}, {3 * page, 4 * page}, {10 * page, 11 * page}},
+			in:   memmap.FileRange{0, 128 * page},
+			want: []memmap.FileRange{{page, 4 * page}, {10 * page, 11 * page}},
+		},
+		{
+			name: "across_words",
+			add:  []memmap.FileRange{{62 * page, 130 * page}},
+			in:   memmap.FileRange{0, 256 * page},
+			want: []memmap.FileRange{{62 * page, 130 * page}},
+		},
+		{
+			name: "clipped",
+			add:  []memmap.FileRange{{62 * page, 130 * page}},
+			in:   memmap.FileRange{64 * page, 65 * page},
+			want: []memmap.FileRange{{64 * page, 65 * page}},
+		},
+		{
+			name: "beyond_bitmap",
+			add:  []memmap.FileRange{{0, page}},
+			in:   memmap.FileRange{0, 1024 * page},
+			want: []memmap.FileRange{{0, page}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var d dirtyPages
+			for _, fr := range tc.add {
+				d.add(fr)
+			}
+			got := appendDirtyFileRanges(nil, d.take(), tc.in)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got dirty ranges %v, want %v", got, tc.want)
+			}
+			if got := appendDirtyFileRanges(nil, d.take(), tc.in); len(got) != 0 {
+				t.Errorf("got dirty ranges %v after take, want none", got)
+			}
+		})
+	}
+}
+
+func TestDirtyPagesConcurrentTake(t *testing.T) {
+	const numPages = 4096
+	var d dirtyPages
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := uint64(0); i < numPages; i++ {
+			d.add(memmap.FileRange{i * page, (i + 1) * page})
+		}
+	}()
+	// Every added page must be taken exactly once or remain in d.
+	var taken []memmap.FileRange
+	all := memmap.FileRange{0, numPages * page}
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		taken = appendDirtyFileRanges(taken, d.take(), all)
+	}
+	taken = appendDirtyFileRanges(taken, d.take(), all)
+	var got uint64
+	for _, fr := range taken {
+		got += fr.Length()
+	}
+	if got != all.Length() {
+		t.Errorf("took %d bytes of dirty pages, want %d", got, all.Length())
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"context"
+	"crypto/cipher"
+	"io"
+
+	"gvisor.dev/gvisor/pkg/compressio"
+	"gvisor.dev/gvisor/pkg/state"
+)
+
+// pagesWriter writes page contents to a pages file, compressing and then
+// encrypting them if required.
+type pagesWriter struct {
+	pw   io.Writer
+	enc  *pageEncrypter
+	comp *pageCompressor
+}
+
+func newPagesWriter(pw io.Writer, aead cipher.AEAD, codec compressio.Codec) (*pagesWriter, error) {
+	p := &pagesWriter{pw: pw}
+	if aead != nil {
+		enc, err := newPageEncrypter(aead)
+		if err != nil {
+			return nil, err
+		}
+		p.enc = enc
+	}
+	if codec != nil {
+		p.comp = newPageCompressor(codec, pw, p.enc)
+	}
+	return p, nil
+}
+
+// release releases resources held by p.
+func (p *pagesWriter) release() {
+	if p.comp != nil {
+		p.comp.release()
+	}
+	if p.enc != nil {
+		p.enc.release()
+	}
+}
+
+// write writes the pages in s, whose first page is at MemoryFile offset off.
+func (p *pagesWriter) write(s []byte, off uint64) error {
+	switch {
+	case p.comp != nil:
+		return p.comp.write(s)
+	case p.enc != nil:
+		return p.enc.write(p.pw, s, off)
+	default:
+		_, err := p.pw.Write(s)
+		return err
+	}
+}
+
+// finish writes buffered pages to the pages file, then writes the metadata
+// needed to load them to w.
+func (p *pagesWriter) finish(ctx context.Context, w io.Writer) error {
+	if p.comp != nil {
+		if err := p.comp.flush(); err != nil {
+			return err
+		}
+	}
+	if p.enc != nil {
+		if err := p.enc.flush(p.pw); err != nil {
+			return err
+		}
+		if _, err := state.Save(ctx, w, &p.enc.prefix); err != nil {
+			return err
+		}
+		if _, err := state.Save(ctx, w, &p.enc.tags); err != nil {
+			return err
+		}
+	}
+	if p.comp != nil {
+		if _, err := state.Save(ctx, w, &p.comp.frameLens); err != nil {
+			return err
+		}
+	}
+	return nil
+}
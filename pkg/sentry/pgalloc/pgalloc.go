@@ -179,6 +179,23 @@ type MemoryFile struct {
 	// the kernel's SaveTo operation. savable is protected by mu.
 	savable bool
 
+	// saveMu serializes SaveTo() and PreCopyTo(). saveMu is ordered before mu.
+	saveMu sync.Mutex
+
+	// saveID is SaveOpts.SaveID from the last call to SaveTo(), or the save ID
+	// passed to the last call to PreCopyTo(), if writes to f have been
+	// tracked since; see SaveOpts.SaveID. saveID is protected by mu.
+	saveID string
+
+	// trackingWrites is true if saveID is not empty. It is stored separately
+	// so that MarkDirty() and MapInternal() can check it without locking mu.
+	// trackingWrites is only mutated with mu locked.
+	trackingWrites atomicbitops.Bool
+
+	// If trackingWrites is true, dirty contains each page of f that may have
+	// been written since tracking started. dirty.mu is ordered after mu.
+	dirty dirtyPages
+
 	// destroyed is set by Destroy to instruct the releaser goroutine to
 	// release all MemoryFile resources and exit. destroyed is protected by mu.
 	destroyed bool
@@ -655,6 +672,9 @@ func (f *MemoryFile) Allocate(length uint64, opts AllocOpts) (memmap.FileRange,
 	if err != nil {
 		return fr, err
 	}
+	// The contents of allocated pages are unrelated to their contents at the
+	// time of any previous save.
+	f.MarkDirty(fr)
 
 	var dsts safemem.BlockSeq
 	if alloc.willCommit {
@@ -1080,6 +1100,7 @@ func (f *MemoryFile) Decommit(fr memmap.FileRange) {
 	}
 
 	f.decommitOrManuallyZero(fr)
+	f.MarkDirty(fr)
 
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -1440,6 +1461,9 @@ func (f *MemoryFile) MapInternal(fr memmap.FileRange, at hostarch.AccessType) (s
 			return safemem.BlockSeq{}, err
 		}
 	}
+	if at.Write && f.trackingWrites.Load() {
+		f.MarkDirty(fr)
+	}
 
 	chunks := ((fr.End + chunkMask) / chunkSize) - (fr.Start / chunkSize)
 	if chunks == 1 {
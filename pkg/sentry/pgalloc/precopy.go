@@ -0,0 +1,188 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/state"
+)
+
+// preCopyBatchSize is the maximum number of bytes of f read by each batch of
+// PreCopyTo().
+const preCopyBatchSize = 4 << 20
+
+// PreCopyTo writes the contents of f's known-committed pages that may have
+// been written since the last call to SaveTo() or PreCopyTo() with the same
+// saveID to w, and tracks writes to f under saveID. Unlike SaveTo(),
+// PreCopyTo() doesn't require f's users to be stopped: pages that are written
+// while or after they're copied are written again by the next call with the
+// same saveID, or by SaveTo() with SaveOpts.ParentSaveID equal to saveID. The
+// latter yields an incremental image whose parent is the union of pages read
+// from w by ReadPreCopy(); see PreCopiedMemoryFile().
+//
+// PreCopyTo calls startedTracking once it has started tracking writes, before
+// copying pages. As for SaveOpts.SaveID, writes through
+// platform.AddressSpace mappings of f must be reported by calling MarkDirty()
+// once startedTracking returns, and f's users must be stopped from before the
+// call to PreCopyTo until then.
+//
+// PreCopyTo returns the number of bytes of page contents written to w.
+func (f *MemoryFile) PreCopyTo(ctx context.Context, w io.Writer, saveID string, startedTracking func()) (sent uint64, err error) {
+	if saveID == "" {
+		return 0, fmt.Errorf("pre-copy requires a save ID")
+	}
+	if err := f.AwaitLoadAll(); err != nil {
+		return 0, fmt.Errorf("previous async page loading failed: %w", err)
+	}
+
+	f.saveMu.Lock()
+	defer f.saveMu.Unlock()
+
+	f.mu.Lock()
+	// Pages committed since the last call to UpdateUsage() aren't yet
+	// known-committed; find them so that they're pre-copied as well.
+	if err := f.updateUsageLocked(nil, false /* alsoScanCommitted */, false /* callerIsSaveTo */, mincore); err != nil {
+		f.mu.Unlock()
+		return 0, err
+	}
+	// Pages written after f.dirty is taken are copied by the next call, even
+	// if they're also copied by this one.
+	trackedWrites := f.saveID == saveID
+	dirty := f.dirty.take()
+	var frs []memmap.FileRange
+	for maseg := f.memAcct.FirstSegment(); maseg.Ok(); maseg = maseg.NextSegment() {
+		if !maseg.ValuePtr().knownCommitted {
+			continue
+		}
+		if trackedWrites {
+			frs = appendDirtyFileRanges(frs, dirty, maseg.Range())
+		} else {
+			frs = appendFileRange(frs, maseg.Range())
+		}
+	}
+	f.setSaveIDLocked(saveID)
+	f.mu.Unlock()
+	startedTracking()
+	defer func() {
+		if err != nil {
+			// Pages that weren't copied must be copied by the next call.
+			f.mu.Lock()
+			f.setSaveIDLocked("")
+			f.mu.Unlock()
+		}
+	}()
+
+	// Read pages with pread(2) rather than through f's mappings, since the
+	// latter may commit pages that were decommitted since frs was collected.
+	timeStart := time.Now()
+	buf := make([]byte, preCopyBatchSize)
+	for _, fr := range frs {
+		for off := fr.Start; off < fr.End; {
+			n := min(fr.End-off, preCopyBatchSize)
+			if _, err := f.file.ReadAt(buf[:n], int64(off)); err != nil {
+				return sent, fmt.Errorf("failed to read MemoryFile offsets %#x-%#x: %w", off, off+n, err)
+			}
+			batch := []memmap.FileRange{{off, off + n}}
+			if _, err := state.Save(ctx, w, &batch); err != nil {
+				return sent, err
+			}
+			if _, err := w.Write(buf[:n]); err != nil {
+				return sent, err
+			}
+			sent += n
+			off += n
+		}
+	}
+	// An empty batch terminates the stream.
+	var batch []memmap.FileRange
+	if _, err := state.Save(ctx, w, &batch); err != nil {
+		return sent, err
+	}
+	dur := time.Since(timeStart)
+	log.Infof("MemoryFile(%p): pre-copied %d bytes in %s (%.3f MiB/s)", f, sent, dur, float64(sent)/dur.Seconds()/(1024.0*1024.0))
+	return sent, nil
+}
+
+// ReadPreCopy reads a stream written by PreCopyTo() from r, and writes page
+// contents to pages at their offset in the MemoryFile. It returns the ranges
+// of the MemoryFile that it wrote.
+func ReadPreCopy(ctx context.Context, r io.Reader, pages io.WriterAt) ([]memmap.FileRange, error) {
+	var frs []memmap.FileRange
+	var buf []byte
+	for {
+		var batch []memmap.FileRange
+		if _, err := state.Load(ctx, r, &batch); err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			return frs, nil
+		}
+		for _, fr := range batch {
+			if fr.Start%hostarch.PageSize != 0 || fr.End%hostarch.PageSize != 0 || fr.Length() == 0 || fr.Length() > preCopyBatchSize {
+				return nil, fmt.Errorf("invalid pre-copied range %v", fr)
+			}
+			buf = slices.Grow(buf[:0], int(fr.Length()))[:fr.Length()]
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return nil, fmt.Errorf("failed to read pre-copied range %v: %w", fr, err)
+			}
+			if _, err := pages.WriteAt(buf, int64(fr.Start)); err != nil {
+				return nil, fmt.Errorf("failed to write pre-copied range %v: %w", fr, err)
+			}
+			frs = append(frs, fr)
+		}
+	}
+}
+
+// PreCopiedMemoryFile returns a SavedMemoryFile holding the pages written by
+// ReadPreCopy(), given the union of the ranges that it returned. The returned
+// SavedMemoryFile may be used as the parent of the incremental image written
+// by SaveTo() with a ParentSaveID equal to the save ID passed to PreCopyTo(),
+// with the pages file passed to ReadPreCopy(). It can't be the first image of
+// a chain passed to MergeSavedMemoryFiles(), since it holds no metadata.
+func PreCopiedMemoryFile(frs []memmap.FileRange) *SavedMemoryFile {
+	frs = slices.Clone(frs)
+	slices.SortFunc(frs, func(a, b memmap.FileRange) int {
+		switch {
+		case a.Start < b.Start:
+			return -1
+		case a.Start > b.Start:
+			return 1
+		default:
+			return 0
+		}
+	})
+	s := &SavedMemoryFile{}
+	for _, fr := range frs {
+		if n := len(s.pages); n != 0 && s.pages[n-1].End >= fr.Start {
+			s.pages[n-1].End = max(s.pages[n-1].End, fr.End)
+			continue
+		}
+		s.pages = append(s.pages, fr)
+	}
+	s.pageOffs = make([]uint64, len(s.pages))
+	for i, fr := range s.pages {
+		// ReadPreCopy() writes pages at their offset in the MemoryFile.
+		s.pageOffs[i] = fr.Start
+	}
+	return s
+}
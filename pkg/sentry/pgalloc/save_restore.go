@@ -17,6 +17,7 @@ package pgalloc
 import (
 	"bytes"
 	"context"
+	"crypto/cipher"
 	"fmt"
 	"io"
 	"math"
@@ -27,6 +28,7 @@ import (
 	"gvisor.dev/gvisor/pkg/aio"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/bitmap"
+	"gvisor.dev/gvisor/pkg/compressio"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/fd"
 	"gvisor.dev/gvisor/pkg/gohacks"
@@ -56,6 +58,44 @@ type SaveOpts struct {
 	// but may instead improve SaveTo() and LoadFrom() time, and checkpoint
 	// size, if the application has many committed zero pages.
 	ExcludeCommittedZeroPages bool
+
+	// If SaveID is not empty, f tracks writes to its pages after SaveTo()
+	// returns, so that a later incremental save with ParentSaveID equal to
+	// SaveID can omit pages that haven't been written since. Writes through
+	// platform.AddressSpace mappings of f must then be reported by calling
+	// MarkDirty(); mm.MemoryManager.WriteProtect() arranges for this. Users
+	// of f must be stopped until it has been called.
+	SaveID string
+
+	// If ParentSaveID is not empty, SaveTo() writes an incremental image
+	// holding only the pages that may have been written since the save
+	// identified by ParentSaveID. If f hasn't tracked writes since that save,
+	// e.g. because f was restored or saved with another SaveID since, all
+	// pages are written, still as an incremental image. Incremental images
+	// can't be loaded by LoadFrom(); they must be merged with their parents by
+	// MergeSavedMemoryFiles() first.
+	ParentSaveID string
+
+	// If PagesAEAD is not nil, SaveTo() encrypts the pages written to pw with
+	// PagesAEAD, and writes their authentication tags to w. PagesAEAD must use
+	// 12-byte nonces. Loading encrypted pages requires LoadOpts.PagesAEAD to
+	// be equivalent. Since nonces are partly random, callers saving many
+	// images with the same key should derive PagesAEAD from SaveID.
+	PagesAEAD cipher.AEAD `json:"-"`
+
+	// If PagesCodec is not nil, SaveTo() compresses the pages written to pw
+	// with PagesCodec, before encrypting them if PagesAEAD is also set, and
+	// writes the length of each compressed frame to w. Loading compressed
+	// pages requires LoadOpts.PagesCodec to be equivalent.
+	PagesCodec compressio.Codec `json:"-"`
+
+	// If ChunkStore is not nil, SaveTo() sends non-zero pages to ChunkStore
+	// rather than writing them to pw, and writes the location and hash of
+	// each page in ChunkStore to w. The chunk store compresses pages itself,
+	// so ChunkStore can't be used with PagesCodec, nor with PagesAEAD,
+	// SaveID or ParentSaveID. Loading pages stored in ChunkStore requires
+	// LoadOpts.PagesDeduplicated.
+	ChunkStore *ChunkStore `json:"-"`
 }
 
 // SaveTo writes f's state to the given stream.
@@ -63,6 +103,26 @@ func (f *MemoryFile) SaveTo(ctx context.Context, w io.Writer, pw io.Writer, opts
 	if err := f.AwaitLoadAll(); err != nil {
 		return fmt.Errorf("previous async page loading failed: %w", err)
 	}
+	cs := opts.ChunkStore
+	if cs != nil {
+		if opts.PagesAEAD != nil || opts.SaveID != "" || opts.ParentSaveID != "" {
+			return fmt.Errorf("pages saved to a chunk store can't be encrypted or saved incrementally")
+		}
+		if opts.PagesCodec != nil {
+			return fmt.Errorf("pages saved to a chunk store are compressed by the chunk store")
+		}
+	}
+	var pages *pagesWriter
+	if cs == nil {
+		var err error
+		if pages, err = newPagesWriter(pw, opts.PagesAEAD, opts.PagesCodec); err != nil {
+			return err
+		}
+		defer pages.release()
+	}
+
+	f.saveMu.Lock()
+	defer f.saveMu.Unlock()
 
 	// Wait for memory release.
 	f.mu.Lock()
@@ -194,34 +254,104 @@ func (f *MemoryFile) SaveTo(ctx context.Context, w io.Writer, pw io.Writer, opts
 	}
 	log.Infof("MemoryFile(%p): saved metadata in %s", f, time.Since(timeMetadataStart))
 
-	// Dump out committed pages.
-	ww := wire.Writer{Writer: w}
-	timePagesStart := time.Now()
-	savedBytes := uint64(0)
+	// Determine the pages to save. Pages that haven't been written since the
+	// parent save are omitted from incremental images.
+	incremental := opts.ParentSaveID != ""
+	trackedWrites := incremental && f.saveID == opts.ParentSaveID
+	if incremental && !trackedWrites {
+		log.Warningf("MemoryFile(%p): writes weren't tracked since save %q, saving all pages", f, opts.ParentSaveID)
+	}
+	dirty := f.dirty.take()
+	saved := false
+	defer func() {
+		if !saved {
+			// Pages taken from f.dirty must be saved by the next save.
+			f.setSaveIDLocked("")
+		}
+	}()
+	var saveFRs []memmap.FileRange
 	for maseg := f.memAcct.FirstSegment(); maseg.Ok(); maseg = maseg.NextSegment() {
 		if !maseg.ValuePtr().knownCommitted {
 			continue
 		}
+		if trackedWrites {
+			saveFRs = appendDirtyFileRanges(saveFRs, dirty, maseg.Range())
+		} else {
+			saveFRs = appendFileRange(saveFRs, maseg.Range())
+		}
+	}
+	if incremental {
+		if _, err := state.Save(ctx, w, &saveFRs); err != nil {
+			return err
+		}
+	}
+
+	// Dump out saved pages.
+	ww := wire.Writer{Writer: w}
+	timePagesStart := time.Now()
+	savedBytes := uint64(0)
+	var (
+		runs       []chunkRun
+		addedBytes uint64
+	)
+	for _, fr := range saveFRs {
 		// Write a header to distinguish from objects.
-		if err := state.WriteHeader(&ww, uint64(maseg.Range().Length()), false); err != nil {
+		if err := state.WriteHeader(&ww, fr.Length(), false); err != nil {
 			return err
 		}
 		// Write out data.
 		var ioErr error
-		f.forEachMappingSlice(maseg.Range(), func(s []byte) {
+		off := fr.Start
+		f.forEachMappingSlice(fr, func(s []byte) {
 			if ioErr != nil {
 				return
 			}
-			_, ioErr = pw.Write(s)
+			if cs != nil {
+				for pgoff := 0; pgoff < len(s); pgoff += hostarch.PageSize {
+					pg := s[pgoff : pgoff+hostarch.PageSize]
+					if bytes.Equal(pg, zeroPage) {
+						runs = appendZeroChunkRun(runs)
+						continue
+					}
+					if err := cs.client.Add(pg); err != nil {
+						ioErr = err
+						return
+					}
+					// The page's location is filled in by cs.commit().
+					runs = append(runs, chunkRun{len: hostarch.PageSize})
+				}
+			} else {
+				ioErr = pages.write(s, off)
+			}
+			off += uint64(len(s))
 		})
 		if ioErr != nil {
 			return ioErr
 		}
-		savedBytes += maseg.Range().Length()
+		savedBytes += fr.Length()
+	}
+	if pages != nil {
+		if err := pages.finish(ctx, w); err != nil {
+			return err
+		}
+	}
+	if cs != nil {
+		var err error
+		if addedBytes, err = cs.commit(runs); err != nil {
+			return err
+		}
+		if _, err := state.Save(ctx, w, &runs); err != nil {
+			return err
+		}
 	}
 	durPages := time.Since(timePagesStart)
 	log.Infof("MemoryFile(%p): saved pages in %s (%d bytes, %.3f MiB/s)", f, durPages, savedBytes, float64(savedBytes)/durPages.Seconds()/(1024.0*1024.0))
+	if cs != nil {
+		log.Infof("MemoryFile(%p): added %d of %d saved bytes to chunk store in %d runs", f, addedBytes, savedBytes, len(runs))
+	}
 
+	saved = true
+	f.setSaveIDLocked(opts.SaveID)
 	return nil
 }
 
@@ -259,6 +389,35 @@ type LoadOpts struct {
 	OnAsyncPageLoadStart func(*MemoryFile)
 	OnAsyncPageLoadDone  func(*MemoryFile, error)
 
+	// If PagesAEAD is not nil, pages read from PagesFile were encrypted by
+	// SaveTo() with SaveOpts.PagesAEAD, and are decrypted with PagesAEAD.
+	// PagesAEAD requires PagesFile.
+	PagesAEAD cipher.AEAD
+
+	// If PagesCodec is not nil, pages read from PagesFile were compressed by
+	// SaveTo() with SaveOpts.PagesCodec, and are decompressed with PagesCodec
+	// after being decrypted with PagesAEAD, if it's also set. PagesCodec
+	// requires PagesFile. When PagesCodec is not nil, the increment of
+	// PagesFileOffset is the length of the compressed pages.
+	PagesCodec compressio.Codec
+
+	// If PagesDeduplicated is true, pages were stored in a ChunkStore by
+	// SaveTo() with SaveOpts.ChunkStore, and PagesFile is the store's pack
+	// file. PagesDeduplicated requires PagesFile, and can't be used with
+	// PagesAEAD; PagesCodec must be set if the store compresses pages. Pages
+	// are read from the pack file locations recorded by SaveTo(), so
+	// PagesFileOffset is neither used nor incremented, and are verified
+	// against the hashes recorded by SaveTo().
+	PagesDeduplicated bool
+
+	// If PagesSparse is true, pages were written by
+	// SavedMemoryFile.WriteSparse(), and the page at each MemoryFile offset is
+	// read from PagesFile at PagesFileOffset plus that offset. PagesFileOffset
+	// is incremented by the size of the MemoryFile. PagesSparse requires
+	// PagesFile, and can't be used with PagesAEAD, PagesCodec or
+	// PagesDeduplicated.
+	PagesSparse bool
+
 	// Optional timeline for the restore process.
 	// If async page loading is enabled, a forked timeline will be created for
 	// that async goroutine, so ownership of this timeline remains in the hands
@@ -278,6 +437,26 @@ func (f *MemoryFile) LoadFrom(ctx context.Context, r io.Reader, opts *LoadOpts)
 	f.unfreeSmall.RemoveAll()
 	f.unfreeHuge.RemoveAll()
 	f.memAcct.RemoveAll()
+	f.mu.Lock()
+	f.setSaveIDLocked("")
+	f.mu.Unlock()
+	if opts.PagesAEAD != nil {
+		if opts.PagesFile == nil {
+			return fmt.Errorf("encrypted pages require a pages file")
+		}
+		if err := checkPagesAEAD(opts.PagesAEAD); err != nil {
+			return err
+		}
+	}
+	if opts.PagesCodec != nil && opts.PagesFile == nil {
+		return fmt.Errorf("compressed pages require a pages file")
+	}
+	if opts.PagesDeduplicated && (opts.PagesFile == nil || opts.PagesAEAD != nil) {
+		return fmt.Errorf("deduplicated pages require an unencrypted pages file")
+	}
+	if opts.PagesSparse && (opts.PagesFile == nil || opts.PagesAEAD != nil || opts.PagesCodec != nil || opts.PagesDeduplicated) {
+		return fmt.Errorf("sparse pages require an unencrypted, uncompressed pages file")
+	}
 
 	// Load metadata.
 	if _, err := state.Load(ctx, r, &f.unwasteSmall); err != nil {
@@ -384,6 +563,8 @@ func (f *MemoryFile) LoadFrom(ctx context.Context, r io.Reader, opts *LoadOpts)
 			qavail:       aplQueueCapacity,
 			fd:           int32(opts.PagesFile.FD()),
 			opsBusy:      bitmap.New(aplQueueCapacity),
+			framed:       opts.PagesCodec != nil || opts.PagesDeduplicated,
+			codec:        opts.PagesCodec,
 			timeline:     mfTimeline.Transfer(),
 		}
 		apl = &aplg.apl
@@ -404,7 +585,20 @@ func (f *MemoryFile) LoadFrom(ctx context.Context, r io.Reader, opts *LoadOpts)
 	wr := wire.Reader{Reader: r}
 	timePagesStart := time.Now()
 	loadedBytes := uint64(0)
-	defer func() { opts.PagesFileOffset += loadedBytes }()
+	framesBytes := uint64(0)
+	defer func() {
+		if opts.PagesDeduplicated {
+			return
+		}
+		if opts.PagesSparse {
+			opts.PagesFileOffset += uint64(len(chunks)) * chunkSize
+		} else if opts.PagesCodec != nil {
+			opts.PagesFileOffset += framesBytes
+		} else {
+			opts.PagesFileOffset += loadedBytes
+		}
+	}()
+	var pending []aplPendingRange
 	for maseg := f.memAcct.FirstSegment(); maseg.Ok(); maseg = maseg.NextSegment() {
 		if !maseg.ValuePtr().knownCommitted {
 			continue
@@ -428,11 +622,31 @@ func (f *MemoryFile) LoadFrom(ctx context.Context, r io.Reader, opts *LoadOpts)
 		for madviseEnd.Load() < maFR.End {
 			<-madviseChan
 		}
-		if apl != nil {
+		if opts.PagesCodec != nil || opts.PagesDeduplicated {
+			// Compressed pages can't be loaded until the lengths of
+			// compressed frames have been read below, and likewise for
+			// deduplicated pages and their locations in the pack file. These
+			// pages are tracked by their offset into the pages saved by
+			// SaveTo().
+			pending = append(pending, aplPendingRange{
+				fr:  maFR,
+				off: loadedBytes,
+			})
+		} else if opts.PagesAEAD != nil {
+			// Likewise for encrypted pages and their authentication tags.
+			pending = append(pending, aplPendingRange{
+				fr:  maFR,
+				off: opts.PagesFileOffset + loadedBytes,
+			})
+		} else if apl != nil {
 			// Record where to read data.
+			off := opts.PagesFileOffset + loadedBytes
+			if opts.PagesSparse {
+				off = opts.PagesFileOffset + maFR.Start
+			}
 			apl.mu.Lock()
 			apl.unloaded.InsertRange(maFR, aplUnloadedInfo{
-				off: opts.PagesFileOffset + loadedBytes,
+				off: off,
 			})
 			apl.mu.Unlock()
 			aplg.lfStatus.Notify(aplLFPending)
@@ -458,6 +672,75 @@ func (f *MemoryFile) LoadFrom(ctx context.Context, r io.Reader, opts *LoadOpts)
 			usage.MemoryAccounting.Inc(amount, maseg.ValuePtr().kind, maseg.ValuePtr().memCgID)
 		}
 	}
+	var (
+		prefix uint64
+		tags   []byte
+	)
+	if opts.PagesAEAD != nil {
+		if _, err := state.Load(ctx, r, &prefix); err != nil {
+			return fmt.Errorf("failed to load page nonce prefix: %w", err)
+		}
+		if _, err := state.Load(ctx, r, &tags); err != nil {
+			return fmt.Errorf("failed to load page authentication tags: %w", err)
+		}
+	}
+	var frames []aplFrame
+	if opts.PagesCodec != nil && !opts.PagesDeduplicated {
+		var frameLens []uint32
+		if _, err := state.Load(ctx, r, &frameLens); err != nil {
+			return fmt.Errorf("failed to load compressed frame lengths: %w", err)
+		}
+		var err error
+		if frames, framesBytes, err = newAPLFrames(pending, frameLens, loadedBytes, opts.PagesFileOffset); err != nil {
+			return err
+		}
+	}
+	if (opts.PagesAEAD != nil || opts.PagesCodec != nil) && !opts.PagesDeduplicated {
+		var decrypter *pageDecrypter
+		if opts.PagesAEAD != nil {
+			// Compressed pages are encrypted per frame rather than per page.
+			sealed := loadedBytes / hostarch.PageSize
+			if opts.PagesCodec != nil {
+				sealed = uint64(len(frames))
+			}
+			if err := checkPageTags(opts.PagesAEAD, tags, sealed); err != nil {
+				return err
+			}
+			decrypter = newPageDecrypter(opts.PagesAEAD, prefix, tags, opts.PagesFileOffset)
+		}
+		apl.mu.Lock()
+		aplg.decrypter = decrypter
+		aplg.frames = frames
+		for _, pr := range pending {
+			apl.unloaded.InsertRange(pr.fr, aplUnloadedInfo{
+				off: pr.off,
+			})
+		}
+		apl.mu.Unlock()
+		aplg.lfStatus.Notify(aplLFPending)
+	}
+	if opts.PagesDeduplicated {
+		var runs []chunkRun
+		if _, err := state.Load(ctx, r, &runs); err != nil {
+			return fmt.Errorf("failed to load chunk runs: %w", err)
+		}
+		var err error
+		if frames, err = newChunkFrames(pending, runs, loadedBytes, opts.PagesCodec != nil); err != nil {
+			return err
+		}
+		apl.mu.Lock()
+		aplg.frames = frames
+		for i := range frames {
+			frame := &frames[i]
+			for _, fr := range frame.frs {
+				apl.unloaded.InsertRange(fr, aplUnloadedInfo{
+					off: frame.off,
+				})
+			}
+		}
+		apl.mu.Unlock()
+		aplg.lfStatus.Notify(aplLFPending)
+	}
 	durPages := time.Since(timePagesStart)
 	if apl != nil {
 		log.Infof("MemoryFile(%p): loaded page file offsets in %s; async loading %d bytes", f, durPages, loadedBytes)
@@ -671,6 +954,23 @@ type aplGoroutine struct {
 	// fd is the host file descriptor for the pages file.
 	fd int32 // immutable
 
+	// If decrypter is not nil, pages are decrypted after they're read.
+	// decrypter is set before the first encrypted pages are inserted into
+	// apl.unloaded, and is immutable thereafter.
+	decrypter *pageDecrypter
+
+	// If framed is true, the pages file holds frames, which are read as a
+	// whole, decompressed with codec if they're compressed, and verified if
+	// they're hashed; see compress.go. frames are set before the first framed
+	// pages are inserted into apl.unloaded, and are immutable thereafter.
+	// frameBufs holds frames read by each aplOp, and frameScratch holds
+	// decompressed frames.
+	framed       bool             // immutable
+	codec        compressio.Codec // immutable
+	frames       []aplFrame
+	frameBufs    [aplFrameMaxInflight][]byte
+	frameScratch []byte
+
 	// opsBusy tracks which aplOps in ops are in use (correspond to
 	// inflight operations or curOp).
 	opsBusy bitmap.Bitmap
@@ -709,6 +1009,9 @@ type aplOp struct {
 	// should be dropped after completion.
 	tempRef bool
 
+	// If frame is not nil, the operation reads the given compressed frame.
+	frame *aplFrame
+
 	// iovecs() = iovecsData[:iovecsLen] contains mappings of frs().
 	iovecsData [aplOpMaxIovecs]unix.Iovec
 }
@@ -772,6 +1075,7 @@ func (g *aplGoroutine) enqueueRange(fr memmap.FileRange, off uint64, tempRef boo
 			op.total = 0
 			op.frsLen = 0
 			op.iovecsLen = 0
+			op.frame = nil
 			g.curOp = op
 			g.curOpID = id
 		}
@@ -964,9 +1268,12 @@ func (g *aplGoroutine) main() {
 		if !g.canEnqueue() {
 			panic("main loop invariant failed")
 		}
+		if g.framed {
+			g.enqueueFrames(&minUnstarted)
+		}
 		// Prioritize reading pages with waiters.
 		apl.mu.Lock()
-		for g.canEnqueue() && !apl.priority.Empty() {
+		for !g.framed && g.canEnqueue() && !apl.priority.Empty() {
 			fr := apl.priority.PopFront()
 			// All pages in apl.priority have non-zero waiters and were split
 			// around fr by f.awaitLoad(), and apl.unloaded never merges
@@ -1007,7 +1314,7 @@ func (g *aplGoroutine) main() {
 		}
 		apl.mu.Unlock()
 		// Fill remaining queue with reads for pages with no waiters.
-		if g.canEnqueue() {
+		if !g.framed && g.canEnqueue() {
 			f.mu.Lock()
 			apl.mu.Lock()
 			ulseg := apl.unloaded.LowerBoundSegment(minUnstarted)
@@ -1079,6 +1386,37 @@ func (g *aplGoroutine) main() {
 			return
 		}
 
+		// Decrypt or decompress pages without holding apl.mu, so that
+		// waiters aren't blocked behind decryption or decompression of
+		// unrelated pages.
+		if g.decrypter != nil || g.framed {
+			for _, c := range completions {
+				op := &g.ops[c.ID]
+				if c.Err() != nil || uint64(c.Result) != op.total {
+					// Handled below.
+					continue
+				}
+				var err error
+				if g.framed {
+					err = g.decompressOp(c.ID, op)
+				} else {
+					err = g.decryptOp(op)
+				}
+				if err != nil {
+					log.Warningf("MemoryFile(%p): async page loading: loading pages %v failed: %v", f, op.frs(), err)
+					for _, c := range completions {
+						if op := &g.ops[c.ID]; op.tempRef {
+							decRefs = append(decRefs, op.frs()...)
+						}
+					}
+					apl.mu.Lock()
+					apl.err = err
+					apl.mu.Unlock()
+					return
+				}
+			}
+		}
+
 		// Process completions.
 		apl.mu.Lock()
 		for _, c := range completions {
@@ -55,6 +55,12 @@ type SessionConfig struct {
 	IgnoreMissing bool `json:"ignore_missing,omitempty"`
 	// Sinks are the sinks that will process the points enabled above.
 	Sinks []SinkConfig `json:"sinks,omitempty"`
+	// Enforce makes the session able to deny operations. Points that are
+	// collected before their operation takes place (sentry/execve and syscall
+	// enter points) fail the operation if they are configured with the "deny"
+	// action or if a sink returns an error for them. Otherwise, sessions are
+	// only observational.
+	Enforce bool `json:"enforce,omitempty"`
 }
 
 // PointConfig describes a point to be enabled in a given session.
@@ -65,6 +71,16 @@ type PointConfig struct {
 	OptionalFields []string `json:"optional_fields,omitempty"`
 	// ContextFields is the list of context fields to collect.
 	ContextFields []string `json:"context_fields,omitempty"`
+	// Filters are predicates that the point must match to be sent to sinks.
+	// All predicates must match. Filters that reference context data require
+	// the corresponding context field to be collected.
+	Filters []FilterConfig `json:"filters,omitempty"`
+	// Action is applied to points that match Filters in an enforcing session.
+	// It's either "allow" (default) or "deny".
+	Action string `json:"action,omitempty"`
+	// Errno is the error returned by operations denied by this point, either
+	// by name (e.g. "EACCES") or number. Defaults to "EPERM".
+	Errno string `json:"errno,omitempty"`
 }
 
 // SinkConfig describes the sink that will process the points in a given
@@ -79,8 +95,8 @@ type SinkConfig struct {
 	IgnoreSetupError bool `json:"ignore_setup_error,omitempty"`
 	// Status is the runtime status for the sink.
 	Status SinkStatus `json:"status,omitempty"`
-	// FD is the endpoint returned from Setup. It may be nil.
-	FD *fd.FD `json:"-"`
+	// FDs are the endpoints returned from Setup. Entries may be nil.
+	FDs []*fd.FD `json:"-"`
 }
 
 // Create reads the session configuration and applies it to the system.
@@ -127,6 +143,18 @@ func Create(conf *SessionConfig, force bool) error {
 		}
 		req.Fields.Context = mask
 
+		filter, err := newFilter(ptConfig.Filters, ptConfig.ContextFields)
+		if err != nil {
+			return fmt.Errorf("configuring point %q: %w", ptConfig.Name, err)
+		}
+		req.Fields.Filter = filter
+
+		enforcement, err := newEnforcement(conf, &ptConfig, desc)
+		if err != nil {
+			return fmt.Errorf("configuring point %q: %w", ptConfig.Name, err)
+		}
+		req.Fields.Enforcement = enforcement
+
 		reqs = append(reqs, req)
 	}
 
@@ -135,7 +163,7 @@ func Create(conf *SessionConfig, force bool) error {
 		if err != nil {
 			return err
 		}
-		sink, err := desc.New(sinkConfig.Config, sinkConfig.FD)
+		sink, err := desc.New(sinkConfig.Config, sinkConfig.FDs)
 		if err != nil {
 			return fmt.Errorf("creating event sink: %w", err)
 		}
@@ -147,35 +175,73 @@ func Create(conf *SessionConfig, force bool) error {
 	return nil
 }
 
-// SetupSinks runs the setup step of all sinks in the configuration.
+// SetupSinks runs the setup step of all sinks in the configuration. The files
+// of all sinks are returned in a single list, in the order of the sinks. Use
+// SetSinkFDs to assign them back to the sinks inside the sandbox.
 func SetupSinks(sinks []SinkConfig) ([]*os.File, error) {
 	var files []*os.File
 	for _, sink := range sinks {
-		sinkFile, err := setupSink(sink)
+		sinkFiles, err := setupSink(sink)
 		if err != nil {
-			if !sink.IgnoreSetupError {
-				return nil, err
+			for _, f := range files {
+				_ = f.Close()
 			}
-			log.Warningf("Ignoring sink setup failure: %v", err)
-			// Set sinkFile is nil and append it to the list to ensure the file
-			// order is preserved.
-			sinkFile = nil
+			return nil, err
 		}
-		files = append(files, sinkFile)
+		files = append(files, sinkFiles...)
 	}
 	return files, nil
 }
 
-// setupSink runs the setup step for a given sink.
-func setupSink(config SinkConfig) (*os.File, error) {
+// setupSink runs the setup step for a given sink. It always returns the
+// number of files expected by the sink, using nil for files that were not
+// setup, to ensure the file order is preserved.
+func setupSink(config SinkConfig) ([]*os.File, error) {
 	sink, err := findSinkDesc(config.Name)
 	if err != nil {
-		return nil, err
+		if !config.IgnoreSetupError {
+			return nil, err
+		}
+		log.Warningf("Ignoring sink setup failure: %v", err)
+		return []*os.File{nil}, nil
 	}
+	want := sink.numFiles(config.Config)
 	if sink.Setup == nil {
-		return nil, nil
+		return make([]*os.File, want), nil
+	}
+	files, err := sink.Setup(config.Config)
+	if err == nil && len(files) != want {
+		for _, f := range files {
+			_ = f.Close()
+		}
+		err = fmt.Errorf("sink %q setup returned %d files, want %d", config.Name, len(files), want)
 	}
-	return sink.Setup(config.Config)
+	if err != nil {
+		if !config.IgnoreSetupError {
+			return nil, err
+		}
+		log.Warningf("Ignoring sink setup failure: %v", err)
+		return make([]*os.File, want), nil
+	}
+	return files, nil
+}
+
+// SetSinkFDs assigns fds, in the order returned by SetupSinks, to sinks.
+func SetSinkFDs(sinks []SinkConfig, fds []*fd.FD) error {
+	for i := range sinks {
+		n := 1
+		if sink, err := findSinkDesc(sinks[i].Name); err == nil {
+			n = sink.numFiles(sinks[i].Config)
+		}
+		if n > len(fds) {
+			return fmt.Errorf("sink %q requires %d files, only %d left", sinks[i].Name, n, len(fds))
+		}
+		sinks[i].FDs, fds = fds[:n], fds[n:]
+	}
+	if len(fds) > 0 {
+		return fmt.Errorf("%d files left after assigning files to sinks", len(fds))
+	}
+	return nil
 }
 
 // Delete deletes an existing session.
@@ -15,8 +15,11 @@
 package seccheck
 
 import (
+	"slices"
 	"strings"
 	"testing"
+
+	"gvisor.dev/gvisor/pkg/fd"
 )
 
 func TestLifecycle(t *testing.T) {
@@ -45,6 +48,14 @@ func TestLifecycle(t *testing.T) {
 						OptionalFields: []string{"fd_path"},
 						ContextFields:  []string{"time"},
 					},
+					{
+						Name:          "syscall/openat/exit",
+						ContextFields: []string{"container_id"},
+						Filters: []FilterConfig{
+							{Field: "pathname", Operator: "prefix", Values: []string{"/etc/"}},
+							{Field: "context_data.container_id", Operator: "eq", Values: []string{"abc"}},
+						},
+					},
 				},
 				Sinks: []SinkConfig{
 					{Name: "test-sink"},
@@ -154,6 +165,21 @@ func TestFailure(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "filter",
+			err:  `filter on "foobar"`,
+			conf: SessionConfig{
+				Name: "Default",
+				Points: []PointConfig{
+					{
+						Name: "syscall/openat/enter",
+						Filters: []FilterConfig{
+							{Field: "foobar", Operator: "eq", Values: []string{"abc"}},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "sink",
 			err:  `sink "foobar" not found`,
@@ -188,3 +214,35 @@ func TestFailure(t *testing.T) {
 		})
 	}
 }
+
+func TestSetSinkFDs(t *testing.T) {
+	RegisterSink(SinkDesc{
+		Name: "test-multi-file",
+		NumFiles: func(config map[string]any) int {
+			return int(config["files"].(float64))
+		},
+		New: newTestSink,
+	})
+	defer delete(Sinks, "test-multi-file")
+
+	sinks := []SinkConfig{
+		{Name: "test-sink"},
+		{Name: "test-multi-file", Config: map[string]any{"files": float64(3)}},
+		{Name: "test-sink"},
+	}
+	fds := []*fd.FD{fd.New(-1), fd.New(-2), fd.New(-3), nil, fd.New(-5)}
+	if err := SetSinkFDs(sinks, fds); err != nil {
+		t.Fatalf("SetSinkFDs(): %v", err)
+	}
+	for i, want := range [][]*fd.FD{fds[0:1], fds[1:4], fds[4:5]} {
+		if got := sinks[i].FDs; !slices.Equal(got, want) {
+			t.Errorf("sink %d, got: %v, want: %v", i, got, want)
+		}
+	}
+
+	for _, n := range []int{len(fds) - 1, len(fds) + 1} {
+		if err := SetSinkFDs(sinks, make([]*fd.FD, n)); err == nil {
+			t.Errorf("SetSinkFDs() with %d files should have failed", n)
+		}
+	}
+}
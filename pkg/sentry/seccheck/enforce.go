@@ -0,0 +1,182 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"fmt"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/metric"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// Point actions supported by PointConfig.Action.
+const (
+	// ActionAllow only reports the point to sinks. Sinks in an enforcing
+	// session can still deny the operation by returning an error.
+	ActionAllow = "allow"
+	// ActionDeny makes the operation fail for all points that match the point
+	// filters.
+	ActionDeny = "deny"
+)
+
+// defaultDenyErrno is returned by denied operations unless configured
+// otherwise.
+const defaultDenyErrno = unix.EPERM
+
+// maxErrno is the largest valid errno value.
+const maxErrno = 4095
+
+var deniedCounter = metric.MustCreateNewUint64Metric("/trace/operations_denied",
+	metric.Uint64Metadata{
+		Cumulative:  true,
+		Description: "Counts the number of operations denied by enforcing trace sessions.",
+	})
+
+// Enforcement configures how a point affects the operation it's collected
+// for. Points are collected from the raw arguments of the operation, before
+// e.g. paths are resolved, so enforcement is best-effort argument filtering
+// and not a security boundary.
+type Enforcement struct {
+	// Deny makes the operation fail for all points that match the filter.
+	// Otherwise, the operation only fails if a sink returns an error.
+	Deny bool
+
+	// Errno is the error returned by denied operations. Sinks may override it
+	// by returning a unix.Errno.
+	Errno unix.Errno
+
+	// point is the name of the point, reported in Denial messages.
+	point string
+}
+
+// enforceable returns whether the point is collected before its operation
+// takes place, and thus can be used to deny it.
+func enforceable(pt Point) bool {
+	if pt == PointExecve {
+		return true
+	}
+	if pt < pointLengthBeforeSyscalls {
+		return false
+	}
+	typ := SyscallType((pt - pointLengthBeforeSyscalls) % Point(syscallTypesCount))
+	return typ == SyscallEnter || typ == SyscallRawEnter
+}
+
+// parseErrno parses an errno given by name, e.g. "EACCES", or number.
+func parseErrno(s string) (unix.Errno, error) {
+	if len(s) == 0 {
+		return defaultDenyErrno, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n <= 0 || n > maxErrno {
+			return 0, fmt.Errorf("errno %d out of range", n)
+		}
+		return unix.Errno(n), nil
+	}
+	for errno := unix.Errno(1); errno <= maxErrno; errno++ {
+		if unix.ErrnoName(errno) == s {
+			return errno, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown errno %q", s)
+}
+
+// newEnforcement validates the enforcement configuration of a point and
+// returns its Enforcement. It returns nil if the session is not enforcing or
+// the point cannot be enforced.
+func newEnforcement(session *SessionConfig, ptConfig *PointConfig, desc PointDesc) (*Enforcement, error) {
+	switch ptConfig.Action {
+	case "", ActionAllow, ActionDeny:
+	default:
+		return nil, fmt.Errorf("invalid action %q, must be %q or %q", ptConfig.Action, ActionAllow, ActionDeny)
+	}
+	if !session.Enforce {
+		if ptConfig.Action == ActionDeny || len(ptConfig.Errno) > 0 {
+			return nil, fmt.Errorf("action and errno require an enforcing session")
+		}
+		return nil, nil
+	}
+	if !enforceable(desc.ID) {
+		if ptConfig.Action == ActionDeny || len(ptConfig.Errno) > 0 {
+			return nil, fmt.Errorf("point %q cannot deny operations, only sentry/execve and syscall enter points can", desc.Name)
+		}
+		return nil, nil
+	}
+	errno, err := parseErrno(ptConfig.Errno)
+	if err != nil {
+		return nil, err
+	}
+	return &Enforcement{
+		Deny:  ptConfig.Action == ActionDeny,
+		Errno: errno,
+		point: desc.Name,
+	}, nil
+}
+
+// Enforce sends a point that matched its filter to all sinks, like
+// SentToSinks, and returns the error that the operation must fail with, or nil
+// if it's allowed.
+//
+// If fields has no Enforcement, the error returned by the first sink that
+// fails is returned as is. Otherwise, the operation is denied if the point is
+// configured to deny it or a sink returns an error, in which case a Denial is
+// sent to all sinks and the configured errno is returned.
+func (s *State) Enforce(ctx context.Context, fields FieldSet, ctxData *pb.ContextData, fn func(c Sink) error) error {
+	var (
+		sinkErr  error
+		deniedBy string
+	)
+	sinks := s.getSinks()
+	for _, c := range sinks {
+		if err := fn(c); err != nil {
+			sinkErr = err
+			deniedBy = c.Name()
+			break
+		}
+	}
+	enf := fields.Enforcement
+	if enf == nil {
+		return sinkErr
+	}
+
+	errno := enf.Errno
+	switch {
+	case sinkErr != nil:
+		if e, ok := sinkErr.(unix.Errno); ok {
+			errno = e
+		}
+	case enf.Deny:
+		deniedBy = ""
+	default:
+		return nil
+	}
+
+	deniedCounter.Increment()
+	log.Debugf("Trace session denied %q with %v, sink: %q", enf.point, errno, deniedBy)
+	denial := &pb.Denial{
+		ContextData: ctxData,
+		Point:       enf.point,
+		Errorno:     int64(errno),
+		Sink:        deniedBy,
+	}
+	for _, c := range sinks {
+		_ = c.Denial(ctx, fields, denial)
+	}
+	return errno
+}
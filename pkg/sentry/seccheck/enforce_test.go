@@ -0,0 +1,231 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/context"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestParseErrno(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want unix.Errno
+	}{
+		{in: "", want: unix.EPERM},
+		{in: "EACCES", want: unix.EACCES},
+		{in: "ECONNREFUSED", want: unix.ECONNREFUSED},
+		{in: "13", want: unix.EACCES},
+	} {
+		got, err := parseErrno(tc.in)
+		if err != nil {
+			t.Errorf("parseErrno(%q): %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("parseErrno(%q): got %v, want %v", tc.in, got, tc.want)
+		}
+	}
+	for _, in := range []string{"EFOO", "eacces", "0", "-1", "5000"} {
+		if _, err := parseErrno(in); err == nil {
+			t.Errorf("parseErrno(%q) should have failed", in)
+		}
+	}
+}
+
+func TestNewEnforcement(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		enforce bool
+		point   string
+		action  string
+		errno   string
+		want    *Enforcement
+		wantErr bool
+	}{
+		{
+			name:  "observational",
+			point: "syscall/openat/enter",
+		},
+		{
+			name:    "deny-not-enforcing",
+			point:   "syscall/openat/enter",
+			action:  ActionDeny,
+			wantErr: true,
+		},
+		{
+			name:    "errno-not-enforcing",
+			point:   "syscall/openat/enter",
+			errno:   "EACCES",
+			wantErr: true,
+		},
+		{
+			name:    "allow",
+			enforce: true,
+			point:   "syscall/openat/enter",
+			want:    &Enforcement{Errno: unix.EPERM, point: "syscall/openat/enter"},
+		},
+		{
+			name:    "deny",
+			enforce: true,
+			point:   "syscall/connect/enter",
+			action:  ActionDeny,
+			errno:   "ECONNREFUSED",
+			want:    &Enforcement{Deny: true, Errno: unix.ECONNREFUSED, point: "syscall/connect/enter"},
+		},
+		{
+			name:    "execve",
+			enforce: true,
+			point:   "sentry/execve",
+			action:  ActionDeny,
+			want:    &Enforcement{Deny: true, Errno: unix.EPERM, point: "sentry/execve"},
+		},
+		{
+			name:    "exit-point",
+			enforce: true,
+			point:   "syscall/openat/exit",
+		},
+		{
+			name:    "deny-exit-point",
+			enforce: true,
+			point:   "syscall/openat/exit",
+			action:  ActionDeny,
+			wantErr: true,
+		},
+		{
+			name:    "deny-after-the-fact",
+			enforce: true,
+			point:   "sentry/task_exit",
+			action:  ActionDeny,
+			wantErr: true,
+		},
+		{
+			name:    "invalid-action",
+			enforce: true,
+			point:   "syscall/openat/enter",
+			action:  "block",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-errno",
+			enforce: true,
+			point:   "syscall/openat/enter",
+			errno:   "EFOO",
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			desc, err := findPointDesc(tc.point)
+			if err != nil {
+				t.Fatal(err)
+			}
+			session := &SessionConfig{Enforce: tc.enforce}
+			ptConfig := &PointConfig{Name: tc.point, Action: tc.action, Errno: tc.errno}
+			got, err := newEnforcement(session, ptConfig, desc)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("newEnforcement() should have failed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newEnforcement(): %v", err)
+			}
+			if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+				t.Errorf("newEnforcement(): got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	sinkErr := errors.New("sink error")
+	for _, tc := range []struct {
+		name        string
+		enforcement *Enforcement
+		sinkErr     error
+		want        error
+		wantSink    string
+	}{
+		{
+			name: "observational",
+		},
+		{
+			name:    "observational-sink-error",
+			sinkErr: sinkErr,
+			want:    sinkErr,
+		},
+		{
+			name:        "allow",
+			enforcement: &Enforcement{Errno: unix.EPERM, point: "sentry/clone"},
+		},
+		{
+			name:        "deny",
+			enforcement: &Enforcement{Deny: true, Errno: unix.EACCES, point: "sentry/clone"},
+			want:        unix.EACCES,
+		},
+		{
+			name:        "sink-deny",
+			enforcement: &Enforcement{Errno: unix.EPERM, point: "sentry/clone"},
+			sinkErr:     sinkErr,
+			want:        unix.EPERM,
+			wantSink:    "test-sink",
+		},
+		{
+			name:        "sink-deny-errno",
+			enforcement: &Enforcement{Errno: unix.EPERM, point: "sentry/clone"},
+			sinkErr:     unix.ENOENT,
+			want:        unix.ENOENT,
+			wantSink:    "test-sink",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var s State
+			sink := &testSink{
+				onClone: func(context.Context, FieldSet, *pb.CloneInfo) error {
+					return tc.sinkErr
+				},
+			}
+			s.AppendSink(sink, []PointReq{{Pt: PointClone}})
+			fields := FieldSet{Enforcement: tc.enforcement}
+			ctxData := &pb.ContextData{ThreadId: 1}
+			err := s.Enforce(context.Background(), fields, ctxData, func(c Sink) error {
+				return c.Clone(context.Background(), fields, &pb.CloneInfo{ContextData: ctxData})
+			})
+			if err != tc.want {
+				t.Errorf("Enforce(): got %v, want %v", err, tc.want)
+			}
+
+			denied := tc.enforcement != nil && tc.want != nil
+			if !denied {
+				if len(sink.denials) != 0 {
+					t.Errorf("unexpected denials: %v", sink.denials)
+				}
+				return
+			}
+			if len(sink.denials) != 1 {
+				t.Fatalf("wrong number of denials, got: %d, want: 1", len(sink.denials))
+			}
+			denial := sink.denials[0]
+			if denial.GetPoint() != "sentry/clone" || denial.GetErrorno() != int64(tc.want.(unix.Errno)) || denial.GetSink() != tc.wantSink || denial.GetContextData().GetThreadId() != 1 {
+				t.Errorf("wrong denial: %v", denial)
+			}
+		})
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Filter operators supported by FilterConfig.Operator.
+const (
+	// FilterOpEqual matches if the field is equal to any of the values.
+	FilterOpEqual = "eq"
+	// FilterOpNotEqual matches if the field is not equal to any of the values.
+	FilterOpNotEqual = "ne"
+	// FilterOpPrefix matches if the field starts with any of the values.
+	FilterOpPrefix = "prefix"
+	// FilterOpLess matches if the field is less than the value.
+	FilterOpLess = "lt"
+	// FilterOpGreater matches if the field is greater than the value.
+	FilterOpGreater = "gt"
+)
+
+// FilterConfig describes a predicate that a point must match in order to be
+// sent to sinks. Predicates are evaluated in the sentry after the point is
+// collected and before it's handed to sinks, so points that don't match are
+// never serialized.
+type FilterConfig struct {
+	// Field is the name of the field in the point message. Fields of nested
+	// messages are separated by ".", e.g. "context_data.container_id" or
+	// "exit.errorno". Points that don't have the field never match.
+	Field string `json:"field,omitempty"`
+	// Operator is one of "eq", "ne", "prefix", "lt" or "gt".
+	Operator string `json:"operator,omitempty"`
+	// Values are the values the field is compared to. "eq" and "prefix" match
+	// if any value matches and "ne" matches if no value matches. "lt" and "gt"
+	// take a single value.
+	Values []string `json:"values,omitempty"`
+}
+
+// ctxFieldsByProtoName maps ContextData fields to the context field that must
+// be collected for them to be set.
+var ctxFieldsByProtoName = map[protoreflect.Name]string{
+	"time_ns":                    "time",
+	"thread_id":                  "thread_id",
+	"thread_start_time_ns":       "task_start_time",
+	"thread_group_id":            "group_id",
+	"thread_group_start_time_ns": "thread_group_start_time",
+	"container_id":               "container_id",
+	"credentials":                "credentials",
+	"cwd":                        "cwd",
+	"process_name":               "process_name",
+}
+
+// Filter is a set of predicates that a point must match in order to be sent
+// to sinks. A nil Filter matches everything.
+type Filter struct {
+	preds []predicate
+}
+
+type predicate struct {
+	path   []protoreflect.Name
+	op     string
+	values []filterValue
+}
+
+// filterValue is a value from FilterConfig.Values, parsed ahead of time for
+// all kinds of fields it may be compared to.
+type filterValue struct {
+	str string
+
+	i   int64
+	iOK bool
+
+	u   uint64
+	uOK bool
+
+	b   bool
+	bOK bool
+}
+
+func newFilterValue(s string) filterValue {
+	v := filterValue{str: s}
+	var err error
+	v.i, err = strconv.ParseInt(s, 0, 64)
+	v.iOK = err == nil
+	v.u, err = strconv.ParseUint(s, 0, 64)
+	v.uOK = err == nil
+	v.b, err = strconv.ParseBool(s)
+	v.bOK = err == nil
+	return v
+}
+
+// newFilter validates configs and creates the Filter for a point that
+// collects ctxFields. It returns nil if there are no configs.
+func newFilter(configs []FilterConfig, ctxFields []string) (*Filter, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	f := &Filter{}
+	for _, cfg := range configs {
+		pred, err := newPredicate(cfg, ctxFields)
+		if err != nil {
+			return nil, fmt.Errorf("filter on %q: %w", cfg.Field, err)
+		}
+		f.preds = append(f.preds, pred)
+	}
+	return f, nil
+}
+
+func newPredicate(cfg FilterConfig, ctxFields []string) (predicate, error) {
+	pred := predicate{op: cfg.Operator}
+	for _, val := range cfg.Values {
+		pred.values = append(pred.values, newFilterValue(val))
+	}
+	for _, name := range strings.Split(cfg.Field, ".") {
+		pred.path = append(pred.path, protoreflect.Name(name))
+	}
+	switch cfg.Operator {
+	case FilterOpEqual, FilterOpNotEqual, FilterOpPrefix:
+		if len(cfg.Values) == 0 {
+			return predicate{}, fmt.Errorf("operator %q requires at least one value", cfg.Operator)
+		}
+	case FilterOpLess, FilterOpGreater:
+		if len(cfg.Values) != 1 {
+			return predicate{}, fmt.Errorf("operator %q requires a single value", cfg.Operator)
+		}
+	default:
+		return predicate{}, fmt.Errorf("unknown operator %q", cfg.Operator)
+	}
+
+	if len(pred.path) > 1 && pred.path[0] == "context_data" {
+		ctxField, ok := ctxFieldsByProtoName[pred.path[1]]
+		if !ok {
+			return predicate{}, fmt.Errorf("unknown context field %q", pred.path[1])
+		}
+		found := false
+		for _, name := range ctxFields {
+			found = found || name == ctxField
+		}
+		if !found {
+			return predicate{}, fmt.Errorf("context field %q must be collected", ctxField)
+		}
+	}
+
+	// The message type of a point is only known when the point fires, so
+	// check that at least one point message has a compatible field.
+	var kindErr error
+	found := false
+	protoregistry.GlobalTypes.RangeMessages(func(mt protoreflect.MessageType) bool {
+		desc := mt.Descriptor()
+		if !strings.HasPrefix(string(desc.FullName()), "gvisor.") {
+			return true
+		}
+		fd := findFieldDesc(desc, pred.path)
+		if fd == nil {
+			return true
+		}
+		if err := pred.validate(fd); err != nil {
+			kindErr = err
+			return true
+		}
+		found = true
+		return false
+	})
+	if !found {
+		if kindErr != nil {
+			return predicate{}, kindErr
+		}
+		return predicate{}, fmt.Errorf("field not found in any point")
+	}
+	return pred, nil
+}
+
+// findFieldDesc returns the descriptor of the field at path in desc, or nil if
+// it doesn't exist.
+func findFieldDesc(desc protoreflect.MessageDescriptor, path []protoreflect.Name) protoreflect.FieldDescriptor {
+	for i, name := range path {
+		fd := desc.Fields().ByName(name)
+		if fd == nil {
+			return nil
+		}
+		if i == len(path)-1 {
+			return fd
+		}
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			return nil
+		}
+		desc = fd.Message()
+	}
+	return nil
+}
+
+// validate checks that the predicate can be evaluated against the field fd.
+func (p *predicate) validate(fd protoreflect.FieldDescriptor) error {
+	if fd.IsMap() {
+		return fmt.Errorf("map fields are not supported")
+	}
+	switch fd.Kind() {
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		if p.op == FilterOpLess || p.op == FilterOpGreater {
+			return fmt.Errorf("operator %q is not supported for %v fields", p.op, fd.Kind())
+		}
+	case protoreflect.BoolKind:
+		if p.op != FilterOpEqual && p.op != FilterOpNotEqual {
+			return fmt.Errorf("operator %q is not supported for bool fields", p.op)
+		}
+		for _, v := range p.values {
+			if !v.bOK {
+				return fmt.Errorf("invalid bool value %q", v.str)
+			}
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return fmt.Errorf("message fields are not supported")
+	default:
+		if p.op == FilterOpPrefix {
+			return fmt.Errorf("operator %q is not supported for numeric fields", p.op)
+		}
+		for _, v := range p.values {
+			if !v.iOK && !v.uOK {
+				return fmt.Errorf("invalid numeric value %q", v.str)
+			}
+		}
+	}
+	return nil
+}
+
+// Match returns true if msg matches all predicates in the filter.
+func (f *Filter) Match(msg proto.Message) bool {
+	if f == nil {
+		return true
+	}
+	m := msg.ProtoReflect()
+	for i := range f.preds {
+		if !f.preds[i].match(m) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *predicate) match(m protoreflect.Message) bool {
+	var fd protoreflect.FieldDescriptor
+	for i, name := range p.path {
+		fd = m.Descriptor().Fields().ByName(name)
+		if fd == nil {
+			return false
+		}
+		if i == len(p.path)-1 {
+			break
+		}
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			return false
+		}
+		m = m.Get(fd).Message()
+	}
+	if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind || fd.IsMap() {
+		return false
+	}
+	v := m.Get(fd)
+	if !fd.IsList() {
+		return p.matchValue(fd, v)
+	}
+	// Repeated fields match if any element matches, except for "ne" which
+	// requires that no element is equal.
+	list := v.List()
+	for i := 0; i < list.Len(); i++ {
+		matched := p.matchValue(fd, list.Get(i))
+		if p.op == FilterOpNotEqual && !matched {
+			return false
+		}
+		if p.op != FilterOpNotEqual && matched {
+			return true
+		}
+	}
+	return p.op == FilterOpNotEqual
+}
+
+func (p *predicate) matchValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+	// cmp compares the field to val, returning false if they can't be compared.
+	var cmp func(val *filterValue) (int, bool)
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		s := v.String()
+		if p.op == FilterOpPrefix {
+			return p.any(func(val *filterValue) bool { return strings.HasPrefix(s, val.str) })
+		}
+		cmp = func(val *filterValue) (int, bool) { return strings.Compare(s, val.str), true }
+	case protoreflect.BytesKind:
+		b := v.Bytes()
+		if p.op == FilterOpPrefix {
+			return p.any(func(val *filterValue) bool { return bytes.HasPrefix(b, []byte(val.str)) })
+		}
+		cmp = func(val *filterValue) (int, bool) { return bytes.Compare(b, []byte(val.str)), true }
+	case protoreflect.BoolKind:
+		b := v.Bool()
+		cmp = func(val *filterValue) (int, bool) {
+			if b == val.b {
+				return 0, val.bOK
+			}
+			return 1, val.bOK
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n := v.Uint()
+		cmp = func(val *filterValue) (int, bool) {
+			if !val.uOK {
+				// Negative values are less than any unsigned value.
+				return 1, val.iOK
+			}
+			return compareUint(n, val.u), true
+		}
+	default:
+		var n int64
+		if fd.Kind() == protoreflect.EnumKind {
+			n = int64(v.Enum())
+		} else {
+			n = v.Int()
+		}
+		cmp = func(val *filterValue) (int, bool) {
+			if !val.iOK {
+				// Values that don't fit in int64 are greater than any signed
+				// value.
+				return -1, val.uOK
+			}
+			return compareInt(n, val.i), true
+		}
+	}
+
+	switch p.op {
+	case FilterOpEqual:
+		return p.any(func(val *filterValue) bool {
+			c, ok := cmp(val)
+			return ok && c == 0
+		})
+	case FilterOpNotEqual:
+		return !p.any(func(val *filterValue) bool {
+			c, ok := cmp(val)
+			return ok && c == 0
+		})
+	case FilterOpLess:
+		c, ok := cmp(&p.values[0])
+		return ok && c < 0
+	case FilterOpGreater:
+		c, ok := cmp(&p.values[0])
+		return ok && c > 0
+	}
+	return false
+}
+
+func (p *predicate) any(fn func(*filterValue) bool) bool {
+	for i := range p.values {
+		if fn(&p.values[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,193 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestFilterMatch(t *testing.T) {
+	open := &pb.Open{
+		ContextData: &pb.ContextData{
+			ContainerId: "container-1",
+			ProcessName: "bash",
+			Credentials: &pb.Credentials{RealUid: 1000},
+		},
+		Exit: &pb.Exit{
+			Result:  -1,
+			Errorno: 2,
+		},
+		Pathname: "/etc/passwd",
+		Flags:    2,
+	}
+	execve := &pb.Execve{
+		Pathname: "/bin/sh",
+		Argv:     []string{"sh", "-c", "true"},
+	}
+	ctxFields := []string{"container_id", "process_name", "credentials"}
+
+	for _, tc := range []struct {
+		name    string
+		filters []FilterConfig
+		msg     proto.Message
+		want    bool
+	}{
+		{
+			name: "no-filter",
+			msg:  open,
+			want: true,
+		},
+		{
+			name:    "prefix",
+			filters: []FilterConfig{{Field: "pathname", Operator: "prefix", Values: []string{"/tmp/", "/etc/"}}},
+			msg:     open,
+			want:    true,
+		},
+		{
+			name:    "prefix-no-match",
+			filters: []FilterConfig{{Field: "pathname", Operator: "prefix", Values: []string{"/tmp/"}}},
+			msg:     open,
+			want:    false,
+		},
+		{
+			name:    "container-id",
+			filters: []FilterConfig{{Field: "context_data.container_id", Operator: "eq", Values: []string{"container-1"}}},
+			msg:     open,
+			want:    true,
+		},
+		{
+			name:    "exe-name",
+			filters: []FilterConfig{{Field: "context_data.process_name", Operator: "ne", Values: []string{"bash", "sh"}}},
+			msg:     open,
+			want:    false,
+		},
+		{
+			name:    "uid",
+			filters: []FilterConfig{{Field: "context_data.credentials.real_uid", Operator: "gt", Values: []string{"0"}}},
+			msg:     open,
+			want:    true,
+		},
+		{
+			name:    "return-value",
+			filters: []FilterConfig{{Field: "exit.result", Operator: "lt", Values: []string{"0"}}},
+			msg:     open,
+			want:    true,
+		},
+		{
+			name:    "unsigned-negative",
+			filters: []FilterConfig{{Field: "flags", Operator: "gt", Values: []string{"-1"}}},
+			msg:     open,
+			want:    true,
+		},
+		{
+			name: "all-must-match",
+			filters: []FilterConfig{
+				{Field: "exit.errorno", Operator: "eq", Values: []string{"2"}},
+				{Field: "pathname", Operator: "eq", Values: []string{"/etc/shadow"}},
+			},
+			msg:  open,
+			want: false,
+		},
+		{
+			name:    "missing-field",
+			filters: []FilterConfig{{Field: "argv", Operator: "eq", Values: []string{"sh"}}},
+			msg:     open,
+			want:    false,
+		},
+		{
+			name:    "list",
+			filters: []FilterConfig{{Field: "argv", Operator: "eq", Values: []string{"-c"}}},
+			msg:     execve,
+			want:    true,
+		},
+		{
+			name:    "list-ne",
+			filters: []FilterConfig{{Field: "argv", Operator: "ne", Values: []string{"-c"}}},
+			msg:     execve,
+			want:    false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := newFilter(tc.filters, ctxFields)
+			if err != nil {
+				t.Fatalf("newFilter(): %v", err)
+			}
+			if got := f.Match(tc.msg); got != tc.want {
+				t.Errorf("Match(): got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFilterFailure(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		filter    FilterConfig
+		ctxFields []string
+		err       string
+	}{
+		{
+			name:   "unknown-operator",
+			filter: FilterConfig{Field: "pathname", Operator: "contains", Values: []string{"a"}},
+			err:    "unknown operator",
+		},
+		{
+			name:   "no-values",
+			filter: FilterConfig{Field: "pathname", Operator: "eq"},
+			err:    "at least one value",
+		},
+		{
+			name:   "too-many-values",
+			filter: FilterConfig{Field: "exit.result", Operator: "lt", Values: []string{"0", "1"}},
+			err:    "single value",
+		},
+		{
+			name:   "unknown-field",
+			filter: FilterConfig{Field: "foo", Operator: "eq", Values: []string{"a"}},
+			err:    "not found",
+		},
+		{
+			name:   "context-not-collected",
+			filter: FilterConfig{Field: "context_data.container_id", Operator: "eq", Values: []string{"a"}},
+			err:    `context field "container_id" must be collected`,
+		},
+		{
+			name:   "prefix-numeric",
+			filter: FilterConfig{Field: "exit.result", Operator: "prefix", Values: []string{"1"}},
+			err:    "not supported for numeric fields",
+		},
+		{
+			name:   "invalid-number",
+			filter: FilterConfig{Field: "exit.result", Operator: "eq", Values: []string{"abc"}},
+			err:    "invalid numeric value",
+		},
+		{
+			name:   "message",
+			filter: FilterConfig{Field: "exit", Operator: "eq", Values: []string{"a"}},
+			err:    "message fields are not supported",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newFilter([]FilterConfig{tc.filter}, tc.ctxFields)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("newFilter(): got: %v, want: %q", err, tc.err)
+			}
+		})
+	}
+}
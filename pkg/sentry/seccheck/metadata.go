@@ -117,14 +117,26 @@ type SinkDesc struct {
 	// Name is a unique identifier for the sink.
 	Name string
 	// Setup is called outside the protection of the sandbox. This is done to
-	// allow the sink to do whatever is necessary to set it up. If it returns a
-	// file, this file is donated to the sandbox and passed to the sink when New
-	// is called. config is an opaque json object passed to the sink.
-	Setup func(config map[string]any) (*os.File, error)
+	// allow the sink to do whatever is necessary to set it up. If it returns
+	// files, these files are donated to the sandbox and passed to the sink when
+	// New is called. It must return NumFiles(config) files. config is an opaque
+	// json object passed to the sink.
+	Setup func(config map[string]any) ([]*os.File, error)
+	// NumFiles returns the number of files that Setup returns for config. If
+	// it's nil, Setup returns a single file.
+	NumFiles func(config map[string]any) int
 	// New creates a new sink. config is an opaque json object passed to the sink.
-	// endpoing is a file descriptor to the file returned in Setup. It's set to -1
-	// if Setup returned nil.
-	New func(config map[string]any, endpoint *fd.FD) (Sink, error)
+	// endpoints are file descriptors to the files returned in Setup, in the same
+	// order. Entries are nil if Setup didn't run or returned nil files.
+	New func(config map[string]any, endpoints []*fd.FD) (Sink, error)
+}
+
+// numFiles returns the number of files that the sink uses for config.
+func (s *SinkDesc) numFiles(config map[string]any) int {
+	if s.NumFiles == nil {
+		return 1
+	}
+	return s.NumFiles(config)
 }
 
 // RegisterSink registers a new sink to make it discoverable.
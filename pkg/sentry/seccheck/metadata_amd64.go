@@ -39,6 +39,13 @@ func archInit() {
 			Name: "fd_path",
 		},
 	})
+	addSyscallPoint(9, "mmap", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(10, "mprotect", nil)
 	addSyscallPoint(17, "pread64", []FieldDesc{
 		{
 			ID:   FieldSyscallPath,
@@ -96,6 +103,12 @@ func archInit() {
 		},
 	})
 	addSyscallPoint(53, "socketpair", nil)
+	addSyscallPoint(54, "setsockopt", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
 	addSyscallPoint(56, "clone", nil)
 	addSyscallPoint(57, "fork", nil)
 	addSyscallPoint(58, "vfork", nil)
@@ -111,25 +124,161 @@ func archInit() {
 			Name: "fd_path",
 		},
 	})
+	addSyscallPoint(80, "chdir", nil)
+	addSyscallPoint(81, "fchdir", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(82, "rename", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(84, "rmdir", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
 	addSyscallPoint(85, "creat", []FieldDesc{
 		{
 			ID:   FieldSyscallPath,
 			Name: "fd_path",
 		},
 	})
-	addSyscallPoint(80, "chdir", nil)
-	addSyscallPoint(81, "fchdir", []FieldDesc{
+	addSyscallPoint(86, "link", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(87, "unlink", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(88, "symlink", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(90, "chmod", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+		{
+			ID:   FieldSyscallOldAttrs,
+			Name: "old_attrs",
+		},
+	})
+	addSyscallPoint(91, "fchmod", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+		{
+			ID:   FieldSyscallOldAttrs,
+			Name: "old_attrs",
+		},
+	})
+	addSyscallPoint(92, "chown", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+		{
+			ID:   FieldSyscallOldAttrs,
+			Name: "old_attrs",
+		},
+	})
+	addSyscallPoint(93, "fchown", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+		{
+			ID:   FieldSyscallOldAttrs,
+			Name: "old_attrs",
+		},
+	})
+	addSyscallPoint(94, "lchown", []FieldDesc{
 		{
 			ID:   FieldSyscallPath,
 			Name: "fd_path",
 		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+		{
+			ID:   FieldSyscallOldAttrs,
+			Name: "old_attrs",
+		},
 	})
+	addSyscallPoint(101, "ptrace", nil)
 	addSyscallPoint(105, "setuid", nil)
 	addSyscallPoint(106, "setgid", nil)
 	addSyscallPoint(112, "setsid", nil)
 	addSyscallPoint(117, "setresuid", nil)
 	addSyscallPoint(119, "setresgid", nil)
+	addSyscallPoint(126, "capset", nil)
 	addSyscallPoint(161, "chroot", nil)
+	addSyscallPoint(165, "mount", []FieldDesc{
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(166, "umount2", []FieldDesc{
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(175, "init_module", nil)
+	addSyscallPoint(176, "delete_module", nil)
 	addSyscallPoint(253, "inotify_init", nil)
 	addSyscallPoint(254, "inotify_add_watch", []FieldDesc{
 		{
@@ -149,6 +298,74 @@ func archInit() {
 			Name: "fd_path",
 		},
 	})
+	addSyscallPoint(260, "fchownat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+		{
+			ID:   FieldSyscallOldAttrs,
+			Name: "old_attrs",
+		},
+	})
+	addSyscallPoint(263, "unlinkat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(264, "renameat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(265, "linkat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(266, "symlinkat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(268, "fchmodat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+		{
+			ID:   FieldSyscallOldAttrs,
+			Name: "old_attrs",
+		},
+	})
 	addSyscallPoint(282, "signalfd", []FieldDesc{
 		{
 			ID:   FieldSyscallPath,
@@ -203,6 +420,22 @@ func archInit() {
 		},
 	})
 	addSyscallPoint(302, "prlimit64", nil)
+	addSyscallPoint(313, "finit_module", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(316, "renameat2", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
 	addSyscallPoint(322, "execveat", []FieldDesc{
 		{
 			ID:   FieldSyscallPath,
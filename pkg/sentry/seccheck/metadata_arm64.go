@@ -53,6 +53,58 @@ func archInit() {
 			Name: "fd_path",
 		},
 	})
+	addSyscallPoint(35, "unlinkat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(36, "symlinkat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(37, "linkat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(38, "renameat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(39, "umount2", []FieldDesc{
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
+	addSyscallPoint(40, "mount", []FieldDesc{
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
 	addSyscallPoint(49, "chdir", nil)
 	addSyscallPoint(50, "fchdir", []FieldDesc{
 		{
@@ -61,6 +113,62 @@ func archInit() {
 		},
 	})
 	addSyscallPoint(51, "chroot", nil)
+	addSyscallPoint(52, "fchmod", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+		{
+			ID:   FieldSyscallOldAttrs,
+			Name: "old_attrs",
+		},
+	})
+	addSyscallPoint(53, "fchmodat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+		{
+			ID:   FieldSyscallOldAttrs,
+			Name: "old_attrs",
+		},
+	})
+	addSyscallPoint(54, "fchownat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+		{
+			ID:   FieldSyscallOldAttrs,
+			Name: "old_attrs",
+		},
+	})
+	addSyscallPoint(55, "fchown", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+		{
+			ID:   FieldSyscallOldAttrs,
+			Name: "old_attrs",
+		},
+	})
 	addSyscallPoint(56, "openat", []FieldDesc{
 		{
 			ID:   FieldSyscallPath,
@@ -141,6 +249,10 @@ func archInit() {
 			Name: "fd_path",
 		},
 	})
+	addSyscallPoint(91, "capset", nil)
+	addSyscallPoint(105, "init_module", nil)
+	addSyscallPoint(106, "delete_module", nil)
+	addSyscallPoint(117, "ptrace", nil)
 	addSyscallPoint(144, "setgid", nil)
 	addSyscallPoint(146, "setuid", nil)
 	addSyscallPoint(147, "setresuid", nil)
@@ -166,6 +278,12 @@ func archInit() {
 			Name: "fd_path",
 		},
 	})
+	addSyscallPoint(208, "setsockopt", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
 	addSyscallPoint(220, "clone", nil)
 	addSyscallPoint(221, "execve", []FieldDesc{
 		{
@@ -173,6 +291,13 @@ func archInit() {
 			Name: "envv",
 		},
 	})
+	addSyscallPoint(222, "mmap", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(226, "mprotect", nil)
 	addSyscallPoint(242, "accept4", []FieldDesc{
 		{
 			ID:   FieldSyscallPath,
@@ -180,6 +305,22 @@ func archInit() {
 		},
 	})
 	addSyscallPoint(261, "prlimit64", nil)
+	addSyscallPoint(273, "finit_module", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(276, "renameat2", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallResolvedPath,
+			Name: "resolved_path",
+		},
+	})
 	addSyscallPoint(281, "execveat", []FieldDesc{
 		{
 			ID:   FieldSyscallPath,
@@ -34,7 +34,8 @@ const (
 	numPointBitmaskUint32s = (totalPoints-1)/numPointsPerUint32 + 1
 )
 
-// FieldSet contains all optional fields to be collected by a given Point.
+// FieldSet contains all optional fields to be collected by a given Point, and
+// the filter that the Point must match to be sent to sinks.
 type FieldSet struct {
 	// Local indicates which optional fields from the Point that needs to be
 	// collected, e.g. resolving path from an FD, or collecting a large field.
@@ -43,6 +44,14 @@ type FieldSet struct {
 	// Context indicates which optional fields from the Context that needs to be
 	// collected, e.g. PID, credentials, current time.
 	Context FieldMask
+
+	// Filter is checked against the Point before it's sent to sinks. It may be
+	// nil, in which case all Points are sent.
+	Filter *Filter
+
+	// Enforcement is set when the Point can deny the operation it's collected
+	// for. It's nil for Points that are only observed.
+	Enforcement *Enforcement
 }
 
 // Field represents the index of a single optional field to be collect for a
@@ -113,6 +122,10 @@ type Sink interface {
 
 	Syscall(context.Context, FieldSet, *pb.ContextData, pb.MessageType, proto.Message) error
 	RawSyscall(context.Context, FieldSet, *pb.Syscall) error
+
+	// Denial is called when an operation is denied by an enforcing session,
+	// after the Point that caused the denial. Errors are ignored.
+	Denial(context.Context, FieldSet, *pb.Denial) error
 }
 
 // SinkStatus represents stats about each Sink instance.
@@ -179,6 +192,11 @@ func (SinkDefaults) Syscall(context.Context, FieldSet, *pb.ContextData, pb.Messa
 	return nil
 }
 
+// Denial implements Sink.Denial.
+func (SinkDefaults) Denial(context.Context, FieldSet, *pb.Denial) error {
+	return nil
+}
+
 // PointReq indicates what Point a corresponding Sink runs at, and what
 // information it requires at those Points.
 type PointReq struct {
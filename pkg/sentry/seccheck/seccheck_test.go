@@ -28,11 +28,13 @@ type testSink struct {
 	SinkDefaults
 
 	onClone func(ctx context.Context, fields FieldSet, info *pb.CloneInfo) error
+
+	denials []*pb.Denial
 }
 
 var _ Sink = (*testSink)(nil)
 
-func newTestSink(_ map[string]any, _ *fd.FD) (Sink, error) {
+func newTestSink(_ map[string]any, _ []*fd.FD) (Sink, error) {
 	return &testSink{}, nil
 }
 
@@ -49,6 +51,12 @@ func (c *testSink) Clone(ctx context.Context, fields FieldSet, info *pb.CloneInf
 	return c.onClone(ctx, fields, info)
 }
 
+// Denial implements Sink.Denial.
+func (c *testSink) Denial(_ context.Context, _ FieldSet, info *pb.Denial) error {
+	c.denials = append(c.denials, info)
+	return nil
+}
+
 func TestNoSink(t *testing.T) {
 	var s State
 	if s.Enabled(PointClone) {
@@ -0,0 +1,449 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package file defines a seccheck.Sink that serializes points to a file on the
+// host. Points are serialized using the same protobuf wire format used by the
+// remote sink, prefixed by their size, so that the file can be processed
+// offline with "runsc trace read" or replayed with tools/tracereplay.
+package file
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/remote/wire"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const name = "file"
+
+const (
+	compressionNone = "none"
+	compressionGzip = "gzip"
+)
+
+const defaultMaxFiles = 5
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     name,
+		Setup:    setupSink,
+		NumFiles: numFiles,
+		New:      new,
+	})
+}
+
+// file writes points to a file in the host. If maxSize is set, files are
+// rotated when they grow larger than maxSize, among a fixed set of maxFiles+1
+// files named <name>.0 to <name>.<maxFiles>. The files are created by runsc
+// outside the sandbox, so rotation only needs to truncate the next file
+// before reusing it.
+type file struct {
+	maxSize     int64
+	compression string
+
+	droppedCount atomicbitops.Uint32
+
+	mu sync.Mutex
+
+	// files are the files that the sink writes to. They are all closed when
+	// the sink is stopped.
+	//
+	// +checklocks:mu
+	files []*fd.FD
+
+	// cur is the index of the file being written to in files.
+	//
+	// +checklocks:mu
+	cur int
+
+	// seq is the sequence number of the file being written to. It's
+	// incremented every time files are rotated.
+	//
+	// +checklocks:mu
+	seq uint64
+
+	// out is the file being written to. It's nil after the sink is stopped.
+	//
+	// +checklocks:mu
+	out *fd.FD
+
+	// gz compresses data written to out. It's nil when compression is not
+	// enabled.
+	//
+	// +checklocks:mu
+	gz *gzip.Writer
+
+	// size is the number of bytes written to out.
+	//
+	// +checklocks:mu
+	size int64
+}
+
+var _ seccheck.Sink = (*file)(nil)
+
+// numFiles returns the number of files used by the sink: maxFiles+1 files if
+// files are rotated, otherwise a single file. Invalid configurations are
+// reported by setupSink and new.
+func numFiles(config map[string]any) int {
+	maxSize, err := parseInt(config, "max_size", 0)
+	if err != nil || maxSize == 0 {
+		return 1
+	}
+	maxFiles, err := parseInt(config, "max_files", defaultMaxFiles)
+	if err != nil || maxFiles == 0 {
+		return 1
+	}
+	return int(maxFiles) + 1
+}
+
+// setupSink creates the files configured in "path" and returns them. If files
+// are rotated, all files used for rotation are created upfront, so that the
+// sandbox doesn't need access to the directory containing them. Existing files
+// are truncated. The caller is responsible to close the files.
+func setupSink(config map[string]any) ([]*os.File, error) {
+	path, err := parsePath(config)
+	if err != nil {
+		return nil, err
+	}
+	n := numFiles(config)
+	if n == 1 {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, err
+		}
+		return []*os.File{f}, nil
+	}
+
+	// Files are opened with O_APPEND, so that writes start at the beginning of
+	// the file after it's truncated when rotating.
+	files := make([]*os.File, 0, n)
+	for i := 0; i < n; i++ {
+		f, err := os.OpenFile(rotatedName(path, i), os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND|unix.O_NOFOLLOW, 0644)
+		if err != nil {
+			for _, f := range files {
+				_ = f.Close()
+			}
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// rotatedName returns the name of the i-th file used for rotation.
+func rotatedName(path string, i int) string {
+	return path + "." + strconv.Itoa(i)
+}
+
+func parsePath(config map[string]any) (string, error) {
+	opaque, ok := config["path"]
+	if !ok {
+		return "", fmt.Errorf("path not present in configuration")
+	}
+	path, ok := opaque.(string)
+	if !ok {
+		return "", fmt.Errorf("path %q is not a string", opaque)
+	}
+	if len(path) == 0 {
+		return "", fmt.Errorf("path cannot be empty")
+	}
+	return path, nil
+}
+
+func parseInt(config map[string]any, name string, def int64) (int64, error) {
+	opaque, ok := config[name]
+	if !ok {
+		return def, nil
+	}
+	val, ok := opaque.(float64)
+	if !ok || float64(int64(val)) != val {
+		return 0, fmt.Errorf("%s %v is not an int", name, opaque)
+	}
+	if val < 0 {
+		return 0, fmt.Errorf("%s %v cannot be negative", name, opaque)
+	}
+	return int64(val), nil
+}
+
+// new creates a new file sink.
+func new(config map[string]any, files []*fd.FD) (seccheck.Sink, error) {
+	if _, err := parsePath(config); err != nil {
+		return nil, err
+	}
+	f := &file{
+		compression: compressionNone,
+	}
+	var err error
+	if f.maxSize, err = parseInt(config, "max_size", 0); err != nil {
+		return nil, err
+	}
+	maxFiles, err := parseInt(config, "max_files", defaultMaxFiles)
+	if err != nil {
+		return nil, err
+	}
+	if maxFiles == 0 {
+		return nil, fmt.Errorf("max_files must be at least 1")
+	}
+	if opaque, ok := config["compression"]; ok {
+		compression, ok := opaque.(string)
+		if !ok {
+			return nil, fmt.Errorf("compression %v is not a string", opaque)
+		}
+		switch compression {
+		case compressionNone, compressionGzip:
+			f.compression = compression
+		default:
+			return nil, fmt.Errorf("invalid compression %q, must be %q or %q", compression, compressionNone, compressionGzip)
+		}
+	}
+	if want := numFiles(config); len(files) != want {
+		return nil, fmt.Errorf("file sink requires %d files, got %d", want, len(files))
+	}
+	for _, out := range files {
+		if out == nil {
+			return nil, fmt.Errorf("file sink requires a file")
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files = files
+	if err := f.startLocked(); err != nil {
+		return nil, err
+	}
+
+	log.Debugf("File sink created, FD: %d, files: %d, max size: %d, compression: %s", files[0].FD(), len(files), f.maxSize, f.compression)
+	return f, nil
+}
+
+func (*file) Name() string {
+	return name
+}
+
+func (f *file) Status() seccheck.SinkStatus {
+	return seccheck.SinkStatus{
+		DroppedCount: uint64(f.droppedCount.Load()),
+	}
+}
+
+// Stop implements seccheck.Sink.
+func (f *file) Stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.finishLocked()
+	for _, out := range f.files {
+		out.Close()
+	}
+	f.files = nil
+}
+
+// startLocked starts writing to files[cur].
+//
+// +checklocks:f.mu
+func (f *file) startLocked() error {
+	f.out = f.files[f.cur]
+	f.size = 0
+	if f.compression == compressionGzip {
+		f.gz = gzip.NewWriter(&fileWriter{f: f})
+	}
+	cfg, err := json.Marshal(Config{Version: wire.CurrentVersion, Sequence: f.seq})
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 0, len(Signature)+8+len(cfg))
+	buf = append(buf, Signature...)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(cfg)))
+	buf = append(buf, cfg...)
+	return f.writeLocked(buf)
+}
+
+// finishLocked flushes the current file and stops writing to it.
+//
+// +checklocks:f.mu
+func (f *file) finishLocked() {
+	if f.out == nil {
+		return
+	}
+	if f.gz != nil {
+		if err := f.gz.Close(); err != nil {
+			log.Warningf("Closing compressed trace file: %v", err)
+		}
+		f.gz = nil
+	}
+	f.out = nil
+}
+
+// rotateLocked finishes the current file and starts writing to the next one,
+// overwriting the oldest file.
+//
+// +checklocks:f.mu
+func (f *file) rotateLocked() error {
+	f.finishLocked()
+
+	f.cur = (f.cur + 1) % len(f.files)
+	f.seq++
+	if err := unix.Ftruncate(f.files[f.cur].FD(), 0); err != nil {
+		return fmt.Errorf("truncating file: %w", err)
+	}
+	return f.startLocked()
+}
+
+// writeLocked writes buf to the current file, compressing it if needed.
+//
+// +checklocks:f.mu
+func (f *file) writeLocked(buf []byte) error {
+	if f.gz == nil {
+		return f.writeRawLocked(buf)
+	}
+	if _, err := f.gz.Write(buf); err != nil {
+		return err
+	}
+	// Flush after every write to ensure that points are not lost if the
+	// sandbox dies, at the cost of a worse compression ratio.
+	return f.gz.Flush()
+}
+
+// +checklocks:f.mu
+func (f *file) writeRawLocked(buf []byte) error {
+	for len(buf) > 0 {
+		n, err := unix.Write(f.out.FD(), buf)
+		if err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return err
+		}
+		f.size += int64(n)
+		buf = buf[n:]
+	}
+	return nil
+}
+
+// fileWriter adapts the file to io.Writer for the compressor.
+type fileWriter struct {
+	f *file
+}
+
+// Write implements io.Writer. It's only called with f.mu held, from
+// writeLocked and closeLocked.
+//
+// +checklocksignore
+func (w *fileWriter) Write(buf []byte) (int, error) {
+	if err := w.f.writeRawLocked(buf); err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+var _ io.Writer = (*fileWriter)(nil)
+
+func (f *file) write(msg proto.Message, msgType pb.MessageType) {
+	out, err := proto.Marshal(msg)
+	if err != nil {
+		log.Debugf("Marshal(%+v): %v", msg, err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.out == nil {
+		// The sink was stopped or the last rotation failed.
+		f.droppedCount.Add(1)
+		return
+	}
+
+	hdr := wire.Header{
+		HeaderSize:   uint16(wire.HeaderStructSize),
+		DroppedCount: f.droppedCount.Load(),
+		MessageType:  uint16(msgType),
+	}
+	size := wire.HeaderStructSize + len(out)
+	buf := make([]byte, 8+size)
+	binary.LittleEndian.PutUint64(buf, uint64(size))
+	hdr.MarshalUnsafe(buf[8:])
+	copy(buf[8+wire.HeaderStructSize:], out)
+
+	if err := f.writeLocked(buf); err != nil {
+		log.Debugf("Write failed, dropping point: %v", err)
+		f.droppedCount.Add(1)
+		return
+	}
+	if f.maxSize > 0 && f.size >= f.maxSize {
+		if err := f.rotateLocked(); err != nil {
+			log.Warningf("Rotating trace file failed, points will be dropped: %v", err)
+		}
+	}
+}
+
+// Clone implements seccheck.Sink.
+func (f *file) Clone(_ context.Context, _ seccheck.FieldSet, info *pb.CloneInfo) error {
+	f.write(info, pb.MessageType_MESSAGE_SENTRY_CLONE)
+	return nil
+}
+
+// Execve implements seccheck.Sink.
+func (f *file) Execve(_ context.Context, _ seccheck.FieldSet, info *pb.ExecveInfo) error {
+	f.write(info, pb.MessageType_MESSAGE_SENTRY_EXEC)
+	return nil
+}
+
+// ExitNotifyParent implements seccheck.Sink.
+func (f *file) ExitNotifyParent(_ context.Context, _ seccheck.FieldSet, info *pb.ExitNotifyParentInfo) error {
+	f.write(info, pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT)
+	return nil
+}
+
+// TaskExit implements seccheck.Sink.
+func (f *file) TaskExit(_ context.Context, _ seccheck.FieldSet, info *pb.TaskExit) error {
+	f.write(info, pb.MessageType_MESSAGE_SENTRY_TASK_EXIT)
+	return nil
+}
+
+// ContainerStart implements seccheck.Sink.
+func (f *file) ContainerStart(_ context.Context, _ seccheck.FieldSet, info *pb.Start) error {
+	f.write(info, pb.MessageType_MESSAGE_CONTAINER_START)
+	return nil
+}
+
+// RawSyscall implements seccheck.Sink.
+func (f *file) RawSyscall(_ context.Context, _ seccheck.FieldSet, info *pb.Syscall) error {
+	f.write(info, pb.MessageType_MESSAGE_SYSCALL_RAW)
+	return nil
+}
+
+// Syscall implements seccheck.Sink.
+func (f *file) Syscall(_ context.Context, _ seccheck.FieldSet, _ *pb.ContextData, msgType pb.MessageType, msg proto.Message) error {
+	f.write(msg, msgType)
+	return nil
+}
+
+// Denial implements seccheck.Sink.
+func (f *file) Denial(_ context.Context, _ seccheck.FieldSet, info *pb.Denial) error {
+	f.write(info, pb.MessageType_MESSAGE_SENTRY_DENIAL)
+	return nil
+}
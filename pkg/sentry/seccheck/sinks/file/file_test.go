@@ -0,0 +1,224 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func newSink(t *testing.T, config map[string]any) seccheck.Sink {
+	t.Helper()
+	files, err := setupSink(config)
+	if err != nil {
+		t.Fatalf("setupSink(): %v", err)
+	}
+	if got, want := len(files), numFiles(config); got != want {
+		t.Fatalf("setupSink(): got %d files, want: %d", got, want)
+	}
+	var outs []*fd.FD
+	for _, f := range files {
+		out, err := fd.NewFromFile(f)
+		if err != nil {
+			t.Fatalf("fd.NewFromFile(): %v", err)
+		}
+		_ = f.Close()
+		outs = append(outs, out)
+	}
+	sink, err := new(config, outs)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	return sink
+}
+
+// readAll reads all points from the trace file in path.
+func readAll(t *testing.T, path string) (Config, []proto.Message) {
+	t.Helper()
+	in, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open(%q): %v", path, err)
+	}
+	defer in.Close()
+
+	rd, err := NewReader(in)
+	if err != nil {
+		t.Fatalf("NewReader(%q): %v", path, err)
+	}
+	var msgs []proto.Message
+	for {
+		rec, err := rd.Next()
+		if errors.Is(err, io.EOF) {
+			return rd.Config(), msgs
+		}
+		if err != nil {
+			t.Fatalf("Next(%q): %v", path, err)
+		}
+		_, msg, err := Decode(rec)
+		if err != nil {
+			t.Fatalf("Decode(): %v", err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestFile(t *testing.T) {
+	for _, compression := range []string{compressionNone, compressionGzip} {
+		t.Run(compression, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "trace")
+			sink := newSink(t, map[string]any{
+				"path":        path,
+				"compression": compression,
+			})
+			want := []proto.Message{
+				&pb.Open{Pathname: "/etc/passwd"},
+				&pb.ExecveInfo{BinaryPath: "/bin/true"},
+				&pb.Syscall{Sysno: 123},
+			}
+			_ = sink.Syscall(nil, seccheck.FieldSet{}, nil, pb.MessageType_MESSAGE_SYSCALL_OPEN, want[0])
+			_ = sink.Execve(nil, seccheck.FieldSet{}, want[1].(*pb.ExecveInfo))
+			_ = sink.RawSyscall(nil, seccheck.FieldSet{}, want[2].(*pb.Syscall))
+			sink.Stop()
+
+			_, got := readAll(t, path)
+			if len(got) != len(want) {
+				t.Fatalf("wrong number of points, got: %d, want: %d", len(got), len(want))
+			}
+			for i := range want {
+				if !proto.Equal(got[i], want[i]) {
+					t.Errorf("point %d, got: %v, want: %v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	for _, compression := range []string{compressionNone, compressionGzip} {
+		t.Run(compression, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "trace")
+			const maxFiles = 3
+			// Leftover from a previous session must be truncated.
+			if err := os.WriteFile(rotatedName(path, 0), []byte("old"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			sink := newSink(t, map[string]any{
+				"path":        path,
+				"max_size":    float64(256),
+				"max_files":   float64(maxFiles),
+				"compression": compression,
+			})
+			const count = 1000
+			for i := 0; i < count; i++ {
+				open := &pb.Open{Pathname: fmt.Sprintf("/file/%d", i)}
+				_ = sink.Syscall(nil, seccheck.FieldSet{}, nil, pb.MessageType_MESSAGE_SYSCALL_OPEN, open)
+			}
+			sink.Stop()
+			if dropped := sink.Status().DroppedCount; dropped != 0 {
+				t.Errorf("points dropped: %d", dropped)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != maxFiles+1 {
+				t.Errorf("wrong number of files, got: %d, want: %d", len(entries), maxFiles+1)
+			}
+			// Sort files from oldest to newest, points must be in order and
+			// end with the last point.
+			files := make(map[uint64][]proto.Message)
+			var last uint64
+			for i := 0; i <= maxFiles; i++ {
+				cfg, msgs := readAll(t, rotatedName(path, i))
+				if len(msgs) == 0 {
+					t.Fatalf("%q is empty", rotatedName(path, i))
+				}
+				files[cfg.Sequence] = msgs
+				last = max(last, cfg.Sequence)
+			}
+			next := count
+			for i := uint64(0); i <= maxFiles; i++ {
+				seq := last - i
+				msgs, ok := files[seq]
+				if !ok {
+					t.Fatalf("file with sequence %d not found", seq)
+				}
+				for j := len(msgs) - 1; j >= 0; j-- {
+					next--
+					if want, got := fmt.Sprintf("/file/%d", next), msgs[j].(*pb.Open).Pathname; got != want {
+						t.Fatalf("sequence %d: got: %q, want: %q", seq, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestConfigFailure(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config map[string]any
+		err    string
+	}{
+		{
+			name:   "no-path",
+			config: map[string]any{},
+			err:    "path not present",
+		},
+		{
+			name:   "max-size",
+			config: map[string]any{"path": "trace", "max_size": "1M"},
+			err:    "max_size",
+		},
+		{
+			name:   "max-files",
+			config: map[string]any{"path": "trace", "max_files": float64(0)},
+			err:    "max_files",
+		},
+		{
+			name:   "compression",
+			config: map[string]any{"path": "trace", "compression": "zip"},
+			err:    "invalid compression",
+		},
+		{
+			name:   "rotation-files",
+			config: map[string]any{"path": "trace", "max_size": float64(1024), "max_files": float64(3)},
+			err:    "requires 4 files",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := fd.NewFromFile(os.Stdout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			if _, err := new(tc.config, []*fd.FD{out}); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("new(): got: %v, want: %q", err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/remote/wire"
+)
+
+// Signature is written at the beginning of every trace file. The file format
+// is the same used by tools/tracereplay, so uncompressed trace files can be
+// replayed directly:
+//
+//	signature <size>config JSON [<size>header+message]*
+//
+// Sizes are little-endian uint64. Each message is preceded by a wire.Header,
+// the same way it's sent by the remote sink.
+const Signature = "tracereplay file"
+
+// maxRecordSize is the largest record accepted by Reader to avoid OOMs on
+// corrupted files.
+const maxRecordSize = 1024 * 1024
+
+// Config is stored at the beginning of the trace file and contains information
+// required to process it.
+type Config struct {
+	// Version is the wire format used in the file.
+	Version uint32 `json:"version"`
+	// Sequence orders files written by a sink that rotates files. It starts
+	// at 0 and is incremented for every new file.
+	Sequence uint64 `json:"sequence,omitempty"`
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Reader decodes messages from a trace file. Files compressed with gzip are
+// detected and decompressed automatically.
+type Reader struct {
+	in  *bufio.Reader
+	cfg Config
+}
+
+// NewReader reads the signature and configuration from r and returns a Reader
+// positioned at the first message.
+func NewReader(r io.Reader) (*Reader, error) {
+	in := bufio.NewReader(r)
+	if magic, err := in.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(in)
+		if err != nil {
+			return nil, err
+		}
+		in = bufio.NewReader(gz)
+	}
+
+	sig := make([]byte, len(Signature))
+	if _, err := io.ReadFull(in, sig); err != nil {
+		return nil, fmt.Errorf("reading signature: %w", err)
+	}
+	if string(sig) != Signature {
+		return nil, fmt.Errorf("not a trace file")
+	}
+	rd := &Reader{in: in}
+	cfg, err := rd.readRecord()
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	if err := json.Unmarshal(cfg, &rd.cfg); err != nil {
+		return nil, fmt.Errorf("decoding config: %w", err)
+	}
+	if rd.cfg.Version > wire.CurrentVersion {
+		return nil, fmt.Errorf("unsupported version %d, max supported is %d", rd.cfg.Version, wire.CurrentVersion)
+	}
+	return rd, nil
+}
+
+// Config returns the configuration stored in the trace file.
+func (r *Reader) Config() Config {
+	return r.cfg
+}
+
+// Next returns the next record in the file, which contains the wire header
+// followed by the serialized message. It returns io.EOF when there are no more
+// records.
+func (r *Reader) Next() ([]byte, error) {
+	rec, err := r.readRecord()
+	if err == io.ErrUnexpectedEOF {
+		// The sandbox may have been killed in the middle of a write, ignore
+		// the partial record.
+		return nil, io.EOF
+	}
+	return rec, err
+}
+
+func (r *Reader) readRecord() ([]byte, error) {
+	var sizeBuf [8]byte
+	if _, err := io.ReadFull(r.in, sizeBuf[:]); err != nil {
+		return nil, err
+	}
+	size := binary.LittleEndian.Uint64(sizeBuf[:])
+	if size > maxRecordSize {
+		return nil, fmt.Errorf("record is too big: %d bytes", size)
+	}
+	rec := make([]byte, size)
+	if _, err := io.ReadFull(r.in, rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Decode parses a record returned by Reader.Next into its header and message.
+func Decode(rec []byte) (wire.Header, proto.Message, error) {
+	var hdr wire.Header
+	if len(rec) < wire.HeaderStructSize {
+		return hdr, nil, fmt.Errorf("record is too small: %d bytes", len(rec))
+	}
+	hdr.UnmarshalUnsafe(rec)
+	if int(hdr.HeaderSize) < wire.HeaderStructSize || int(hdr.HeaderSize) > len(rec) {
+		return hdr, nil, fmt.Errorf("invalid header size: %d", hdr.HeaderSize)
+	}
+	msgType := pb.MessageType(hdr.MessageType)
+	newMsg, ok := messageTypes[msgType]
+	if !ok {
+		return hdr, nil, fmt.Errorf("unknown message type: %d", hdr.MessageType)
+	}
+	msg := newMsg()
+	if err := proto.Unmarshal(rec[hdr.HeaderSize:], msg); err != nil {
+		return hdr, nil, fmt.Errorf("unmarshalling %v: %w", msgType, err)
+	}
+	return hdr, msg, nil
+}
+
+// messageTypes maps message types to the proto message that they carry.
+var messageTypes = map[pb.MessageType]func() proto.Message{
+	pb.MessageType_MESSAGE_CONTAINER_START:           func() proto.Message { return &pb.Start{} },
+	pb.MessageType_MESSAGE_SENTRY_CLONE:              func() proto.Message { return &pb.CloneInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_EXEC:               func() proto.Message { return &pb.ExecveInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT: func() proto.Message { return &pb.ExitNotifyParentInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_TASK_EXIT:          func() proto.Message { return &pb.TaskExit{} },
+	pb.MessageType_MESSAGE_SYSCALL_RAW:               func() proto.Message { return &pb.Syscall{} },
+	pb.MessageType_MESSAGE_SYSCALL_OPEN:              func() proto.Message { return &pb.Open{} },
+	pb.MessageType_MESSAGE_SYSCALL_CLOSE:             func() proto.Message { return &pb.Close{} },
+	pb.MessageType_MESSAGE_SYSCALL_READ:              func() proto.Message { return &pb.Read{} },
+	pb.MessageType_MESSAGE_SYSCALL_CONNECT:           func() proto.Message { return &pb.Connect{} },
+	pb.MessageType_MESSAGE_SYSCALL_EXECVE:            func() proto.Message { return &pb.Execve{} },
+	pb.MessageType_MESSAGE_SYSCALL_SOCKET:            func() proto.Message { return &pb.Socket{} },
+	pb.MessageType_MESSAGE_SYSCALL_CHDIR:             func() proto.Message { return &pb.Chdir{} },
+	pb.MessageType_MESSAGE_SYSCALL_SETID:             func() proto.Message { return &pb.Setid{} },
+	pb.MessageType_MESSAGE_SYSCALL_SETRESID:          func() proto.Message { return &pb.Setresid{} },
+	pb.MessageType_MESSAGE_SYSCALL_PRLIMIT64:         func() proto.Message { return &pb.Prlimit{} },
+	pb.MessageType_MESSAGE_SYSCALL_PIPE:              func() proto.Message { return &pb.Pipe{} },
+	pb.MessageType_MESSAGE_SYSCALL_FCNTL:             func() proto.Message { return &pb.Fcntl{} },
+	pb.MessageType_MESSAGE_SYSCALL_DUP:               func() proto.Message { return &pb.Dup{} },
+	pb.MessageType_MESSAGE_SYSCALL_SIGNALFD:          func() proto.Message { return &pb.Signalfd{} },
+	pb.MessageType_MESSAGE_SYSCALL_CHROOT:            func() proto.Message { return &pb.Chroot{} },
+	pb.MessageType_MESSAGE_SYSCALL_EVENTFD:           func() proto.Message { return &pb.Eventfd{} },
+	pb.MessageType_MESSAGE_SYSCALL_CLONE:             func() proto.Message { return &pb.Clone{} },
+	pb.MessageType_MESSAGE_SYSCALL_BIND:              func() proto.Message { return &pb.Bind{} },
+	pb.MessageType_MESSAGE_SYSCALL_ACCEPT:            func() proto.Message { return &pb.Accept{} },
+	pb.MessageType_MESSAGE_SYSCALL_TIMERFD_CREATE:    func() proto.Message { return &pb.TimerfdCreate{} },
+	pb.MessageType_MESSAGE_SYSCALL_TIMERFD_SETTIME:   func() proto.Message { return &pb.TimerfdSetTime{} },
+	pb.MessageType_MESSAGE_SYSCALL_TIMERFD_GETTIME:   func() proto.Message { return &pb.TimerfdGetTime{} },
+	pb.MessageType_MESSAGE_SYSCALL_FORK:              func() proto.Message { return &pb.Fork{} },
+	pb.MessageType_MESSAGE_SYSCALL_INOTIFY_INIT:      func() proto.Message { return &pb.InotifyInit{} },
+	pb.MessageType_MESSAGE_SYSCALL_INOTIFY_ADD_WATCH: func() proto.Message { return &pb.InotifyAddWatch{} },
+	pb.MessageType_MESSAGE_SYSCALL_INOTIFY_RM_WATCH:  func() proto.Message { return &pb.InotifyRmWatch{} },
+	pb.MessageType_MESSAGE_SYSCALL_SOCKETPAIR:        func() proto.Message { return &pb.SocketPair{} },
+	pb.MessageType_MESSAGE_SYSCALL_WRITE:             func() proto.Message { return &pb.Write{} },
+	pb.MessageType_MESSAGE_SYSCALL_UNLINK:            func() proto.Message { return &pb.Unlink{} },
+	pb.MessageType_MESSAGE_SYSCALL_RENAME:            func() proto.Message { return &pb.Rename{} },
+	pb.MessageType_MESSAGE_SYSCALL_CHMOD:             func() proto.Message { return &pb.Chmod{} },
+	pb.MessageType_MESSAGE_SYSCALL_CHOWN:             func() proto.Message { return &pb.Chown{} },
+	pb.MessageType_MESSAGE_SYSCALL_LINK:              func() proto.Message { return &pb.Link{} },
+	pb.MessageType_MESSAGE_SYSCALL_SYMLINK:           func() proto.Message { return &pb.Symlink{} },
+	pb.MessageType_MESSAGE_SYSCALL_MMAP:              func() proto.Message { return &pb.Mmap{} },
+	pb.MessageType_MESSAGE_SYSCALL_MPROTECT:          func() proto.Message { return &pb.Mprotect{} },
+	pb.MessageType_MESSAGE_SYSCALL_MOUNT:             func() proto.Message { return &pb.Mount{} },
+	pb.MessageType_MESSAGE_SYSCALL_UMOUNT:            func() proto.Message { return &pb.Umount{} },
+	pb.MessageType_MESSAGE_SYSCALL_PTRACE:            func() proto.Message { return &pb.Ptrace{} },
+	pb.MessageType_MESSAGE_SYSCALL_SETSOCKOPT:        func() proto.Message { return &pb.Setsockopt{} },
+	pb.MessageType_MESSAGE_SYSCALL_INIT_MODULE:       func() proto.Message { return &pb.InitModule{} },
+	pb.MessageType_MESSAGE_SYSCALL_DELETE_MODULE:     func() proto.Message { return &pb.DeleteModule{} },
+	pb.MessageType_MESSAGE_SYSCALL_CAPSET:            func() proto.Message { return &pb.Capset{} },
+	pb.MessageType_MESSAGE_SENTRY_DENIAL:             func() proto.Message { return &pb.Denial{} },
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// fdConn is a net.Conn backed by a connected host socket in blocking mode.
+// Unlike net.FileConn, it only uses read(2), write(2), shutdown(2) and
+// close(2), which are allowed by the sentry seccomp filters.
+//
+// Deadlines are not supported. A blocked Read or Write is interrupted by
+// Close.
+type fdConn struct {
+	// mu is held for reading while the FD is used, and for writing to close
+	// it, so that the FD number isn't reused by another file while a Read or
+	// Write is still using it.
+	mu sync.RWMutex
+	fd *fd.FD
+	// +checklocks:mu
+	closed bool
+}
+
+var _ net.Conn = (*fdConn)(nil)
+
+func newFDConn(f *fd.FD) *fdConn {
+	return &fdConn{fd: f}
+}
+
+// Read implements net.Conn.
+func (c *fdConn) Read(buf []byte) (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	for {
+		n, err := unix.Read(c.fd.FD(), buf)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 && len(buf) > 0 {
+			return 0, io.EOF
+		}
+		return n, nil
+	}
+}
+
+// Write implements net.Conn.
+func (c *fdConn) Write(buf []byte) (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	written := 0
+	for written < len(buf) {
+		n, err := unix.Write(c.fd.FD(), buf[written:])
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+// Close implements net.Conn. It's safe to call it more than once.
+func (c *fdConn) Close() error {
+	// Wake up blocked readers and writers, so that they release mu.
+	c.mu.RLock()
+	if !c.closed {
+		_ = unix.Shutdown(c.fd.FD(), unix.SHUT_RDWR)
+	}
+	c.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.fd.Close()
+}
+
+// LocalAddr implements net.Conn.
+func (*fdConn) LocalAddr() net.Addr {
+	return fdAddr{}
+}
+
+// RemoteAddr implements net.Conn.
+func (*fdConn) RemoteAddr() net.Addr {
+	return fdAddr{}
+}
+
+// SetDeadline implements net.Conn.
+func (*fdConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+// SetReadDeadline implements net.Conn.
+func (*fdConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline implements net.Conn.
+func (*fdConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// fdAddr is the address of a fdConn. The actual address is not known inside
+// the sandbox.
+type fdAddr struct{}
+
+// Network implements net.Addr.
+func (fdAddr) Network() string {
+	return "fd"
+}
+
+// String implements net.Addr.
+func (fdAddr) String() string {
+	return "collector"
+}
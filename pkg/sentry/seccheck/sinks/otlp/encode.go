@@ -0,0 +1,311 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// This file encodes the few OTLP messages exported by the sink. Field numbers
+// are those of the upstream definitions in
+// https://github.com/open-telemetry/opentelemetry-proto.
+
+// Field numbers of opentelemetry/proto/common/v1/common.proto.
+const (
+	anyValueString = 1
+	anyValueInt    = 3
+
+	keyValueKey   = 1
+	keyValueValue = 2
+
+	instrumentationScopeName = 1
+
+	resourceAttributes = 1
+)
+
+// Field numbers of opentelemetry/proto/logs/v1/logs.proto and
+// opentelemetry/proto/collector/logs/v1/logs_service.proto.
+const (
+	exportLogsResourceLogs = 1
+
+	resourceLogsResource  = 1
+	resourceLogsScopeLogs = 2
+
+	scopeLogsScope      = 1
+	scopeLogsLogRecords = 2
+
+	logRecordTime           = 1
+	logRecordSeverityNumber = 2
+	logRecordBody           = 5
+	logRecordAttributes     = 6
+	logRecordTraceID        = 9
+	logRecordSpanID         = 10
+	logRecordObservedTime   = 11
+	logRecordEventName      = 12
+
+	severityNumberInfo = 9
+	severityNumberWarn = 13
+)
+
+// Field numbers of opentelemetry/proto/trace/v1/trace.proto and
+// opentelemetry/proto/collector/trace/v1/trace_service.proto.
+const (
+	exportTraceResourceSpans = 1
+
+	resourceSpansResource   = 1
+	resourceSpansScopeSpans = 2
+
+	scopeSpansScope = 1
+	scopeSpansSpans = 2
+
+	spanTraceID    = 1
+	spanSpanID     = 2
+	spanName       = 5
+	spanKind       = 6
+	spanStartTime  = 7
+	spanEndTime    = 8
+	spanAttributes = 9
+	spanStatus     = 15
+
+	spanKindInternal = 1
+
+	statusMessage   = 2
+	statusCode      = 3
+	statusCodeError = 2
+)
+
+// Field numbers of the export responses, which are the same in all OTLP
+// services.
+const (
+	exportPartialSuccess   = 1
+	partialSuccessRejected = 1
+	partialSuccessMessage  = 2
+)
+
+// attr is an attribute with a string or integer value.
+type attr struct {
+	key   string
+	str   string
+	num   int64
+	isNum bool
+}
+
+func stringAttr(key, val string) attr {
+	return attr{key: key, str: val}
+}
+
+func intAttr(key string, val int64) attr {
+	return attr{key: key, num: val, isNum: true}
+}
+
+// record is a point converted to the fields of a log record and a span.
+type record struct {
+	// timeNs is the time of the point, and observedNs is when it was
+	// received by the sink, in nanoseconds since the Unix epoch.
+	timeNs     uint64
+	observedNs uint64
+
+	// name is the name of the point's message type.
+	name string
+
+	// body is the point encoded as JSON.
+	body string
+
+	// denial is true for sentry denial points, which are reported with a
+	// higher severity.
+	denial bool
+
+	attrs   []attr
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendFixed64(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, v)
+}
+
+func appendStringValue(b []byte, num protowire.Number, s string) []byte {
+	return appendMessage(b, num, appendString(nil, anyValueString, s))
+}
+
+func appendAttrs(b []byte, num protowire.Number, attrs []attr) []byte {
+	for _, a := range attrs {
+		kv := appendString(nil, keyValueKey, a.key)
+		if a.isNum {
+			kv = appendMessage(kv, keyValueValue, appendVarint(nil, anyValueInt, uint64(a.num)))
+		} else {
+			kv = appendStringValue(kv, keyValueValue, a.str)
+		}
+		b = appendMessage(b, num, kv)
+	}
+	return b
+}
+
+// encodeResource returns an encoded Resource with attrs.
+func encodeResource(attrs []attr) []byte {
+	return appendAttrs(nil, resourceAttributes, attrs)
+}
+
+// encodeScope returns an encoded InstrumentationScope.
+func encodeScope(name string) []byte {
+	return appendString(nil, instrumentationScopeName, name)
+}
+
+// encodeLogs returns an encoded ExportLogsServiceRequest holding records as
+// log records. withSpans is true if records are also exported as spans, in
+// which case each log record refers to its span.
+func encodeLogs(resource, scope []byte, records []record, withSpans bool) []byte {
+	sl := appendMessage(nil, scopeLogsScope, scope)
+	for i := range records {
+		r := &records[i]
+		var lr []byte
+		if r.timeNs != 0 {
+			lr = appendFixed64(lr, logRecordTime, r.timeNs)
+		}
+		severity := uint64(severityNumberInfo)
+		if r.denial {
+			severity = severityNumberWarn
+		}
+		lr = appendVarint(lr, logRecordSeverityNumber, severity)
+		if len(r.body) > 0 {
+			lr = appendStringValue(lr, logRecordBody, r.body)
+		}
+		lr = appendAttrs(lr, logRecordAttributes, r.attrs)
+		if withSpans {
+			lr = appendMessage(lr, logRecordTraceID, r.traceID[:])
+			lr = appendMessage(lr, logRecordSpanID, r.spanID[:])
+		}
+		lr = appendFixed64(lr, logRecordObservedTime, r.observedNs)
+		lr = appendString(lr, logRecordEventName, r.name)
+		sl = appendMessage(sl, scopeLogsLogRecords, lr)
+	}
+	rl := appendMessage(nil, resourceLogsResource, resource)
+	rl = appendMessage(rl, resourceLogsScopeLogs, sl)
+	return appendMessage(nil, exportLogsResourceLogs, rl)
+}
+
+// encodeTraces returns an encoded ExportTraceServiceRequest holding records
+// as spans. Points are instantaneous, so each span starts and ends at the
+// time of its point.
+func encodeTraces(resource, scope []byte, records []record) []byte {
+	ss := appendMessage(nil, scopeSpansScope, scope)
+	for i := range records {
+		r := &records[i]
+		t := r.timeNs
+		if t == 0 {
+			t = r.observedNs
+		}
+		s := appendMessage(nil, spanTraceID, r.traceID[:])
+		s = appendMessage(s, spanSpanID, r.spanID[:])
+		s = appendString(s, spanName, r.name)
+		s = appendVarint(s, spanKind, spanKindInternal)
+		s = appendFixed64(s, spanStartTime, t)
+		s = appendFixed64(s, spanEndTime, t)
+		s = appendAttrs(s, spanAttributes, r.attrs)
+		if len(r.body) > 0 {
+			s = appendAttrs(s, spanAttributes, []attr{stringAttr("gvisor.point", r.body)})
+		}
+		if r.denial {
+			status := appendString(nil, statusMessage, "denied")
+			status = appendVarint(status, statusCode, statusCodeError)
+			s = appendMessage(s, spanStatus, status)
+		}
+		ss = appendMessage(ss, scopeSpansSpans, s)
+	}
+	rs := appendMessage(nil, resourceSpansResource, resource)
+	rs = appendMessage(rs, resourceSpansScopeSpans, ss)
+	return appendMessage(nil, exportTraceResourceSpans, rs)
+}
+
+// partialSuccessError decodes an export response. It returns an error if the
+// collector rejected part of the request.
+func partialSuccessError(resp []byte) error {
+	ps, err := findBytes(resp, exportPartialSuccess)
+	if err != nil || ps == nil {
+		return err
+	}
+	var (
+		rejected uint64
+		msg      string
+	)
+	for b := ps; len(b) > 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch {
+		case num == partialSuccessRejected && typ == protowire.VarintType:
+			rejected, n = protowire.ConsumeVarint(b)
+		case num == partialSuccessMessage && typ == protowire.BytesType:
+			var v []byte
+			v, n = protowire.ConsumeBytes(b)
+			msg = string(v)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	if int64(rejected) == 0 {
+		return nil
+	}
+	return fmt.Errorf("collector rejected %d items: %s", int64(rejected), msg)
+}
+
+// findBytes returns the value of the last length-delimited field num of the
+// message b, or nil if there is none.
+func findBytes(b []byte, num protowire.Number) ([]byte, error) {
+	var found []byte
+	for len(b) > 0 {
+		fnum, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if fnum == num && typ == protowire.BytesType {
+			var v []byte
+			v, n = protowire.ConsumeBytes(b)
+			found = v
+		} else {
+			n = protowire.ConsumeFieldValue(fnum, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return found, nil
+}
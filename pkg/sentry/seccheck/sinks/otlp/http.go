@@ -0,0 +1,248 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OTLP/HTTP paths to export each signal.
+const (
+	logsPath   = "/v1/logs"
+	tracesPath = "/v1/traces"
+)
+
+// maxResponseSize bounds the size of response bodies read from the
+// collector.
+const maxResponseSize = 1 << 20
+
+// httpClient sends OTLP/HTTP requests with protobuf payloads on a single
+// HTTP/1.1 connection. It implements only what exporting requires, so that
+// the sentry doesn't depend on net/http. It's not safe for concurrent use.
+type httpClient struct {
+	conn net.Conn
+	// closer closes the connection underlying conn, interrupting requests
+	// that time out.
+	closer  io.Closer
+	r       *bufio.Reader
+	timeout time.Duration
+
+	// header holds the header lines sent with every request.
+	header []byte
+
+	// err is set once the connection can't be used anymore.
+	err error
+}
+
+func newHTTPClient(conn net.Conn, closer io.Closer, host string, headers map[string]string, timeout time.Duration) *httpClient {
+	var header bytes.Buffer
+	fmt.Fprintf(&header, "Host: %s\r\nContent-Type: application/x-protobuf\r\n", host)
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&header, "%s: %s\r\n", k, headers[k])
+	}
+	return &httpClient{
+		conn:    conn,
+		closer:  closer,
+		r:       bufio.NewReader(conn),
+		timeout: timeout,
+		header:  header.Bytes(),
+	}
+}
+
+// validHeader returns an error if key and val can't be sent as an HTTP
+// header.
+func validHeader(key, val string) error {
+	if len(key) == 0 || strings.ContainsAny(key, ": \t\r\n") {
+		return fmt.Errorf("invalid header name %q", key)
+	}
+	if strings.ContainsAny(val, "\r\n") {
+		return fmt.Errorf("invalid value for header %q", key)
+	}
+	return nil
+}
+
+// post sends body to path and returns the body of the response. If the
+// request fails midway or the collector closes the connection, all later
+// requests fail. If the request takes longer than the client's timeout, the
+// connection is closed.
+func (c *httpClient) post(path string, body []byte) ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	timer := time.AfterFunc(c.timeout, func() { c.closer.Close() })
+	resp, err := c.roundTrip(path, body)
+	if !timer.Stop() {
+		err = fmt.Errorf("request timed out after %v", c.timeout)
+		c.err = fmt.Errorf("connection to the OTLP collector was closed after a request timed out")
+	} else if err != nil {
+		c.err = fmt.Errorf("connection to the OTLP collector was lost: %w", err)
+	}
+	return resp, err
+}
+
+func (c *httpClient) roundTrip(path string, body []byte) ([]byte, error) {
+	req := make([]byte, 0, 128+len(c.header)+len(body))
+	req = fmt.Appendf(req, "POST %s HTTP/1.1\r\n", path)
+	req = append(req, c.header...)
+	req = fmt.Appendf(req, "Content-Length: %d\r\n\r\n", len(body))
+	req = append(req, body...)
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, err
+	}
+	for {
+		code, status, resp, keepAlive, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		if code == 100 {
+			// Informational responses precede the actual response.
+			continue
+		}
+		if !keepAlive {
+			c.err = fmt.Errorf("the OTLP collector closed the connection")
+		}
+		if code != 200 {
+			return nil, fmt.Errorf("collector returned %d %s: %s", code, status, resp)
+		}
+		return resp, nil
+	}
+}
+
+// readResponse reads a response and returns its status and body, and whether
+// the connection can be used for more requests.
+func (c *httpClient) readResponse() (int, string, []byte, bool, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return 0, "", nil, false, err
+	}
+	version, rest, _ := strings.Cut(line, " ")
+	codeStr, status, _ := strings.Cut(rest, " ")
+	code, err := strconv.Atoi(codeStr)
+	if !strings.HasPrefix(version, "HTTP/1.") || err != nil || code < 100 || code > 999 {
+		return 0, "", nil, false, fmt.Errorf("malformed status line %q", line)
+	}
+	keepAlive := version != "HTTP/1.0"
+	contentLength := int64(-1)
+	chunked := false
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return 0, "", nil, false, err
+		}
+		if len(line) == 0 {
+			break
+		}
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			return 0, "", nil, false, fmt.Errorf("malformed header %q", line)
+		}
+		val = strings.TrimSpace(val)
+		switch strings.ToLower(key) {
+		case "content-length":
+			contentLength, err = strconv.ParseInt(val, 10, 64)
+			if err != nil || contentLength < 0 {
+				return 0, "", nil, false, fmt.Errorf("malformed header %q", line)
+			}
+		case "transfer-encoding":
+			chunked = strings.EqualFold(val, "chunked")
+		case "connection":
+			keepAlive = !strings.EqualFold(val, "close")
+		}
+	}
+
+	var body []byte
+	switch {
+	case code < 200 || code == 204 || code == 304:
+	case chunked:
+		body, err = c.readChunked()
+	case contentLength > maxResponseSize:
+		err = fmt.Errorf("response of %d bytes is too large", contentLength)
+	case contentLength >= 0:
+		body = make([]byte, contentLength)
+		_, err = io.ReadFull(c.r, body)
+	default:
+		// The body ends with the connection.
+		keepAlive = false
+		body, err = io.ReadAll(io.LimitReader(c.r, maxResponseSize))
+	}
+	if err != nil {
+		return 0, "", nil, false, err
+	}
+	return code, status, body, keepAlive, nil
+}
+
+// readChunked reads a body with chunked transfer encoding.
+func (c *httpClient) readChunked() ([]byte, error) {
+	var body []byte
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		sizeStr, _, _ := strings.Cut(line, ";")
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeStr), 16, 64)
+		if err != nil || size < 0 || int64(len(body))+size > maxResponseSize {
+			return nil, fmt.Errorf("malformed chunk size %q", line)
+		}
+		if size == 0 {
+			// Skip the trailer.
+			for {
+				line, err := c.readLine()
+				if err != nil {
+					return nil, err
+				}
+				if len(line) == 0 {
+					return body, nil
+				}
+			}
+		}
+		start := len(body)
+		body = append(body, make([]byte, size)...)
+		if _, err := io.ReadFull(c.r, body[start:]); err != nil {
+			return nil, err
+		}
+		if line, err := c.readLine(); err != nil || len(line) != 0 {
+			return nil, fmt.Errorf("malformed chunk")
+		}
+	}
+}
+
+// readLine reads a line terminated by CRLF, without the terminator.
+func (c *httpClient) readLine() (string, error) {
+	line, err := c.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return "", fmt.Errorf("line too long")
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r"), nil
+}
@@ -0,0 +1,741 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlp defines a seccheck.Sink that exports points as OpenTelemetry
+// log records and spans to an OTLP collector, over OTLP/HTTP with protobuf
+// payloads.
+//
+// The connection to the collector is established by runsc outside the
+// sandbox and donated to the sink, since the sentry is not allowed to create
+// host sockets. If the connection is lost, it is not reestablished and points
+// are dropped. Messages are encoded and sent by hand, so that the sentry
+// doesn't depend on a gRPC or HTTP client library.
+package otlp
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/rand"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+const name = "otlp"
+
+// scopeName is the instrumentation scope of all log records and spans.
+const scopeName = "gvisor.dev/gvisor/pkg/sentry/seccheck"
+
+// protocolHTTP is the only supported protocol: OTLP/HTTP with protobuf
+// payloads.
+const protocolHTTP = "http"
+
+// Signals that points can be exported as.
+const (
+	signalLogs   = "logs"
+	signalTraces = "traces"
+)
+
+const (
+	defaultServiceName  = "gvisor"
+	defaultBatchSize    = 512
+	defaultBatchTimeout = time.Second
+	defaultQueueSize    = 4096
+	defaultTimeout      = 10 * time.Second
+	defaultKeepalive    = 15 * time.Second
+	dialTimeout         = 10 * time.Second
+
+	// maxCASize bounds the size of the CA certificates read by the sink.
+	maxCASize = 16 << 20
+)
+
+// systemCABundles are the usual locations of the host's CA certificates,
+// used to verify the collector if "tls_ca_file" isn't set. They are the same
+// as those of the crypto/x509 package.
+var systemCABundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+}
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     name,
+		Setup:    setupSink,
+		NumFiles: numFiles,
+		New:      new,
+	})
+}
+
+// numFiles returns 2 if TLS is enabled, for the connection and the CA
+// certificates, and 1 otherwise.
+func numFiles(config map[string]any) int {
+	if useTLS, err := parseBool(config, "tls", false); err == nil && useTLS {
+		return 2
+	}
+	return 1
+}
+
+// point is a point waiting to be exported.
+type point struct {
+	timestamp time.Time
+	msgType   pb.MessageType
+	ctxData   *pb.ContextData
+	msg       proto.Message
+}
+
+// exporter batches points and sends them to the collector as log records
+// and spans.
+type exporter struct {
+	conn      net.Conn
+	client    *httpClient
+	resource  []byte
+	scope     []byte
+	logs      bool
+	traces    bool
+	batchSize int
+	timeout   time.Duration
+	keepalive time.Duration
+
+	// spanIDBase is a random value that the span IDs of the sink's spans
+	// are offset from. spanIDCount is the number of span IDs used so far.
+	spanIDBase  uint64
+	spanIDCount uint64
+
+	droppedCount atomicbitops.Uint64
+
+	// queue holds points waiting to be exported. Points are dropped when it's
+	// full.
+	queue chan point
+	// stop is closed to request the export goroutine to flush and exit.
+	stop chan struct{}
+	// done is closed when the export goroutine exits.
+	done chan struct{}
+}
+
+var _ seccheck.Sink = (*exporter)(nil)
+
+// setupSink connects to the collector configured in "endpoint" and returns
+// the connected socket, followed by the CA certificates to verify the
+// collector with if TLS is enabled. The caller is responsible to close the
+// files.
+func setupSink(config map[string]any) ([]*os.File, error) {
+	endpoint, err := parseString(config, "endpoint", "")
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoint) == 0 {
+		return nil, fmt.Errorf("endpoint not present in configuration")
+	}
+	useTLS, err := parseBool(config, "tls", false)
+	if err != nil {
+		return nil, err
+	}
+	var ca *os.File
+	if useTLS {
+		caFile, err := parseString(config, "tls_ca_file", "")
+		if err != nil {
+			return nil, err
+		}
+		if ca, err = openCABundle(caFile); err != nil {
+			return nil, err
+		}
+	}
+	f, err := setup(endpoint)
+	if err != nil {
+		if ca != nil {
+			ca.Close()
+		}
+		return nil, err
+	}
+	if ca != nil {
+		return []*os.File{f, ca}, nil
+	}
+	return []*os.File{f}, nil
+}
+
+// openCABundle opens the file holding the CA certificates used to verify the
+// collector, which is path or, if empty, the host's CA bundle. The sentry
+// can't open the bundle itself.
+func openCABundle(path string) (*os.File, error) {
+	if len(path) > 0 {
+		return os.Open(path)
+	}
+	for _, path := range systemCABundles {
+		if f, err := os.Open(path); err == nil {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("tls_ca_file not set and no CA certificates found in %v", systemCABundles)
+}
+
+func setup(endpoint string) (*os.File, error) {
+	log.Debugf("OTLP sink connecting to %q", endpoint)
+	network, addr := "tcp", endpoint
+	if path, ok := strings.CutPrefix(endpoint, "unix:"); ok {
+		network, addr = "unix", path
+	}
+	conn, err := net.DialTimeout(network, addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var f *os.File
+	switch c := conn.(type) {
+	case *net.TCPConn:
+		f, err = c.File()
+	case *net.UnixConn:
+		f, err = c.File()
+	default:
+		err = fmt.Errorf("unexpected connection type %T", conn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The sink uses blocking I/O.
+	if err := unix.SetNonblock(int(f.Fd()), false); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func parseString(config map[string]any, name, def string) (string, error) {
+	opaque, ok := config[name]
+	if !ok {
+		return def, nil
+	}
+	val, ok := opaque.(string)
+	if !ok {
+		return "", fmt.Errorf("%s %v is not a string", name, opaque)
+	}
+	return val, nil
+}
+
+func parseBool(config map[string]any, name string, def bool) (bool, error) {
+	opaque, ok := config[name]
+	if !ok {
+		return def, nil
+	}
+	val, ok := opaque.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s %v is not a boolean", name, opaque)
+	}
+	return val, nil
+}
+
+func parseInt(config map[string]any, name string, def int) (int, error) {
+	opaque, ok := config[name]
+	if !ok {
+		return def, nil
+	}
+	val, ok := opaque.(float64)
+	if !ok || float64(int(val)) != val {
+		return 0, fmt.Errorf("%s %v is not an int", name, opaque)
+	}
+	if val <= 0 {
+		return 0, fmt.Errorf("%s %v must be positive", name, opaque)
+	}
+	return int(val), nil
+}
+
+func parseDuration(config map[string]any, name string, def time.Duration) (time.Duration, error) {
+	str, err := parseString(config, name, "")
+	if err != nil || len(str) == 0 {
+		return def, err
+	}
+	val, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, err
+	}
+	if val <= 0 {
+		return 0, fmt.Errorf("%s %v must be positive", name, val)
+	}
+	return val, nil
+}
+
+func parseStringList(config map[string]any, name string, def []string) ([]string, error) {
+	opaque, ok := config[name]
+	if !ok {
+		return def, nil
+	}
+	list, ok := opaque.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s %v is not a list", name, opaque)
+	}
+	rv := make([]string, 0, len(list))
+	for i, v := range list {
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s[%d] %v is not a string", name, i, v)
+		}
+		rv = append(rv, str)
+	}
+	return rv, nil
+}
+
+func parseStringMap(config map[string]any, name string) (map[string]string, error) {
+	opaque, ok := config[name]
+	if !ok {
+		return nil, nil
+	}
+	m, ok := opaque.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s %v is not an object", name, opaque)
+	}
+	rv := make(map[string]string, len(m))
+	for k, v := range m {
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s[%q] %v is not a string", name, k, v)
+		}
+		rv[k] = str
+	}
+	return rv, nil
+}
+
+// new creates a new OTLP sink.
+func new(config map[string]any, endpoints []*fd.FD) (seccheck.Sink, error) {
+	if len(endpoints) != numFiles(config) || endpoints[0] == nil {
+		return nil, fmt.Errorf("otlp sink requires an endpoint")
+	}
+	conn := newFDConn(endpoints[0])
+	var ca []byte
+	if len(endpoints) == 2 {
+		if endpoints[1] == nil {
+			conn.Close()
+			return nil, fmt.Errorf("otlp sink requires CA certificates with TLS")
+		}
+		var err error
+		ca, err = io.ReadAll(io.LimitReader(endpoints[1], maxCASize))
+		endpoints[1].Close()
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("reading CA certificates: %w", err)
+		}
+	}
+	return newExporter(config, conn, ca)
+}
+
+// newExporter returns an exporter sending points over conn. ca holds the
+// PEM-encoded certificates to verify the collector with if TLS is enabled.
+func newExporter(config map[string]any, conn net.Conn, ca []byte) (*exporter, error) {
+	e, err := configureExporter(config, conn, ca)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go e.run() // S/R-SAFE: exports points, doesn't touch sentry state.
+	return e, nil
+}
+
+func configureExporter(config map[string]any, conn net.Conn, ca []byte) (*exporter, error) {
+	endpoint, err := parseString(config, "endpoint", "")
+	if err != nil {
+		return nil, err
+	}
+	protocol, err := parseString(config, "protocol", protocolHTTP)
+	if err != nil {
+		return nil, err
+	}
+	if protocol != protocolHTTP {
+		return nil, fmt.Errorf("invalid protocol %q, only %q (OTLP/HTTP with protobuf payloads) is supported", protocol, protocolHTTP)
+	}
+	signals, err := parseStringList(config, "signals", []string{signalLogs})
+	if err != nil {
+		return nil, err
+	}
+	headers, err := parseStringMap(config, "headers")
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		if err := validHeader(k, v); err != nil {
+			return nil, err
+		}
+	}
+	timeout, err := parseDuration(config, "timeout", defaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	keepalive, err := parseDuration(config, "keepalive", defaultKeepalive)
+	if err != nil {
+		return nil, err
+	}
+	batchTimeout, err := parseDuration(config, "batch_timeout", defaultBatchTimeout)
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := parseInt(config, "batch_size", defaultBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	queueSize, err := parseInt(config, "queue_size", defaultQueueSize)
+	if err != nil {
+		return nil, err
+	}
+	serviceName, err := parseString(config, "service_name", defaultServiceName)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := parseStringMap(config, "resource_attributes")
+	if err != nil {
+		return nil, err
+	}
+	useTLS, err := parseBool(config, "tls", false)
+	if err != nil {
+		return nil, err
+	}
+	serverName, err := parseString(config, "tls_server_name", "")
+	if err != nil {
+		return nil, err
+	}
+
+	e := &exporter{
+		scope:     encodeScope(scopeName),
+		batchSize: batchSize,
+		timeout:   batchTimeout,
+		keepalive: keepalive,
+		queue:     make(chan point, queueSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if len(signals) == 0 {
+		return nil, fmt.Errorf("signals must not be empty")
+	}
+	for _, signal := range signals {
+		switch signal {
+		case signalLogs:
+			e.logs = true
+		case signalTraces:
+			e.traces = true
+		default:
+			return nil, fmt.Errorf("invalid signal %q, must be %q or %q", signal, signalLogs, signalTraces)
+		}
+	}
+
+	// Timed out requests close raw, which doesn't wait for a blocked Write to
+	// return like closing a TLS connection does.
+	raw := conn
+	host := "localhost"
+	if !strings.HasPrefix(endpoint, "unix:") && len(endpoint) > 0 {
+		host = endpoint
+	}
+	if useTLS {
+		if len(serverName) == 0 {
+			serverName = host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				serverName = h
+			}
+		}
+		// The sentry can't load the host's CA certificates itself.
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no CA certificates to verify the collector with")
+		}
+		conn = tls.Client(conn, &tls.Config{
+			ServerName: serverName,
+			RootCAs:    roots,
+			MinVersion: tls.VersionTLS12,
+		})
+	}
+	e.conn = conn
+	e.client = newHTTPClient(conn, raw, host, headers, timeout)
+
+	resource := []attr{stringAttr("service.name", serviceName)}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		resource = append(resource, stringAttr(k, attrs[k]))
+	}
+	e.resource = encodeResource(resource)
+
+	var seed [8]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		return nil, err
+	}
+	e.spanIDBase = binary.BigEndian.Uint64(seed[:])
+
+	log.Debugf("OTLP sink created, signals: %v, TLS: %t, batch size: %d, batch timeout: %v, queue size: %d", signals, useTLS, batchSize, batchTimeout, queueSize)
+	return e, nil
+}
+
+func (*exporter) Name() string {
+	return name
+}
+
+func (e *exporter) Status() seccheck.SinkStatus {
+	return seccheck.SinkStatus{
+		DroppedCount: e.droppedCount.Load(),
+	}
+}
+
+// Stop implements seccheck.Sink. Pending points are exported before it
+// returns.
+func (e *exporter) Stop() {
+	select {
+	case <-e.stop:
+		// Already stopped.
+	default:
+		close(e.stop)
+	}
+	<-e.done
+}
+
+// run exports points in the queue until the sink is stopped. While no points
+// are exported, it sends empty requests every keepalive interval so that the
+// collector doesn't close the connection for being idle.
+func (e *exporter) run() {
+	defer close(e.done)
+	defer e.conn.Close()
+
+	lastSent := time.Now()
+	timer := time.NewTimer(e.timeout)
+	defer timer.Stop()
+	keepalive := time.NewTicker(e.keepalive)
+	defer keepalive.Stop()
+	batch := make([]point, 0, e.batchSize)
+	for {
+		select {
+		case p := <-e.queue:
+			batch = append(batch, p)
+			if len(batch) < e.batchSize {
+				continue
+			}
+		case <-timer.C:
+		case <-keepalive.C:
+			if len(batch) == 0 && time.Since(lastSent) >= e.keepalive {
+				e.ping()
+				lastSent = time.Now()
+			}
+			continue
+		case <-e.stop:
+			// Flush everything that was queued before the sink was stopped.
+			for {
+				select {
+				case p := <-e.queue:
+					batch = append(batch, p)
+					if len(batch) >= e.batchSize {
+						e.export(batch)
+						batch = batch[:0]
+					}
+					continue
+				default:
+				}
+				break
+			}
+			e.export(batch)
+			return
+		}
+		if len(batch) > 0 {
+			e.export(batch)
+			lastSent = time.Now()
+		}
+		batch = batch[:0]
+		timer.Reset(e.timeout)
+	}
+}
+
+// ping sends an empty export request.
+func (e *exporter) ping() {
+	path := logsPath
+	if !e.logs {
+		path = tracesPath
+	}
+	if _, err := e.client.post(path, nil); err != nil {
+		log.Debugf("OTLP keepalive request failed: %v", err)
+	}
+}
+
+// export sends points to the collector. Points that fail to be exported are
+// dropped.
+func (e *exporter) export(batch []point) {
+	if len(batch) == 0 {
+		return
+	}
+	records := make([]record, 0, len(batch))
+	for _, p := range batch {
+		records = append(records, e.toRecord(p))
+	}
+	failed := false
+	if e.logs {
+		failed = e.send(logsPath, encodeLogs(e.resource, e.scope, records, e.traces)) != nil
+	}
+	if e.traces {
+		failed = e.send(tracesPath, encodeTraces(e.resource, e.scope, records)) != nil || failed
+	}
+	if failed {
+		log.Debugf("OTLP export failed, dropping %d points", len(batch))
+		e.droppedCount.Add(uint64(len(batch)))
+	}
+}
+
+// send sends an export request to path.
+func (e *exporter) send(path string, req []byte) error {
+	resp, err := e.client.post(path, req)
+	if err == nil {
+		err = partialSuccessError(resp)
+	}
+	if err != nil {
+		log.Debugf("OTLP export to %s failed: %v", path, err)
+	}
+	return err
+}
+
+// toRecord converts a point into the fields of a log record and a span. The
+// point is encoded as JSON, and the fields from its context that identify the
+// container and process are added as attributes. All spans of a process
+// belong to the same trace.
+func (e *exporter) toRecord(p point) record {
+	r := record{
+		observedNs: uint64(p.timestamp.UnixNano()),
+		name:       p.msgType.String(),
+		denial:     p.msgType == pb.MessageType_MESSAGE_SENTRY_DENIAL,
+	}
+	if body, err := protojson.Marshal(p.msg); err != nil {
+		log.Debugf("protojson.Marshal(%+v): %v", p.msg, err)
+	} else {
+		r.body = string(body)
+	}
+	e.spanIDCount++
+	spanID := e.spanIDBase + e.spanIDCount
+	if spanID == 0 {
+		spanID = 1
+	}
+	binary.BigEndian.PutUint64(r.spanID[:], spanID)
+
+	ctxData := p.ctxData
+	if ctxData == nil {
+		// Points without context form a trace of their own.
+		copy(r.traceID[:], r.spanID[:])
+		binary.BigEndian.PutUint64(r.traceID[8:], e.spanIDBase)
+		return r
+	}
+	if ctxData.GetTimeNs() > 0 {
+		r.timeNs = uint64(ctxData.GetTimeNs())
+	}
+	if id := ctxData.GetContainerId(); len(id) > 0 {
+		r.attrs = append(r.attrs, stringAttr("container.id", id))
+	}
+	if pid := ctxData.GetThreadGroupId(); pid > 0 {
+		r.attrs = append(r.attrs, intAttr("process.pid", int64(pid)))
+	}
+	if tid := ctxData.GetThreadId(); tid > 0 {
+		r.attrs = append(r.attrs, intAttr("thread.id", int64(tid)))
+	}
+	if name := ctxData.GetProcessName(); len(name) > 0 {
+		r.attrs = append(r.attrs, stringAttr("process.executable.name", name))
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00%d", ctxData.GetContainerId(), ctxData.GetThreadGroupId(), ctxData.GetThreadGroupStartTimeNs())
+	copy(r.traceID[:], h.Sum(nil))
+	return r
+}
+
+// contextDataGetter is implemented by all points that have context data.
+type contextDataGetter interface {
+	GetContextData() *pb.ContextData
+}
+
+// enqueue queues a point to be exported. The point is dropped if the queue is
+// full.
+func (e *exporter) enqueue(msg proto.Message, msgType pb.MessageType, ctxData *pb.ContextData) {
+	if ctxData == nil {
+		if getter, ok := msg.(contextDataGetter); ok {
+			ctxData = getter.GetContextData()
+		}
+	}
+	p := point{
+		timestamp: time.Now(),
+		msgType:   msgType,
+		ctxData:   ctxData,
+		msg:       msg,
+	}
+	select {
+	case e.queue <- p:
+	default:
+		e.droppedCount.Add(1)
+	}
+}
+
+// Clone implements seccheck.Sink.
+func (e *exporter) Clone(_ context.Context, _ seccheck.FieldSet, info *pb.CloneInfo) error {
+	e.enqueue(info, pb.MessageType_MESSAGE_SENTRY_CLONE, nil)
+	return nil
+}
+
+// Execve implements seccheck.Sink.
+func (e *exporter) Execve(_ context.Context, _ seccheck.FieldSet, info *pb.ExecveInfo) error {
+	e.enqueue(info, pb.MessageType_MESSAGE_SENTRY_EXEC, nil)
+	return nil
+}
+
+// ExitNotifyParent implements seccheck.Sink.
+func (e *exporter) ExitNotifyParent(_ context.Context, _ seccheck.FieldSet, info *pb.ExitNotifyParentInfo) error {
+	e.enqueue(info, pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT, nil)
+	return nil
+}
+
+// TaskExit implements seccheck.Sink.
+func (e *exporter) TaskExit(_ context.Context, _ seccheck.FieldSet, info *pb.TaskExit) error {
+	e.enqueue(info, pb.MessageType_MESSAGE_SENTRY_TASK_EXIT, nil)
+	return nil
+}
+
+// ContainerStart implements seccheck.Sink.
+func (e *exporter) ContainerStart(_ context.Context, _ seccheck.FieldSet, info *pb.Start) error {
+	e.enqueue(info, pb.MessageType_MESSAGE_CONTAINER_START, nil)
+	return nil
+}
+
+// RawSyscall implements seccheck.Sink.
+func (e *exporter) RawSyscall(_ context.Context, _ seccheck.FieldSet, info *pb.Syscall) error {
+	e.enqueue(info, pb.MessageType_MESSAGE_SYSCALL_RAW, nil)
+	return nil
+}
+
+// Syscall implements seccheck.Sink.
+func (e *exporter) Syscall(_ context.Context, _ seccheck.FieldSet, ctxData *pb.ContextData, msgType pb.MessageType, msg proto.Message) error {
+	e.enqueue(msg, msgType, ctxData)
+	return nil
+}
+
+// Denial implements seccheck.Sink.
+func (e *exporter) Denial(_ context.Context, _ seccheck.FieldSet, info *pb.Denial) error {
+	e.enqueue(info, pb.MessageType_MESSAGE_SENTRY_DENIAL, nil)
+	return nil
+}
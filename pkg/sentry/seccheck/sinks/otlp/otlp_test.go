@@ -0,0 +1,403 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/fd"
+	otlppb "gvisor.dev/gvisor/pkg/otlp/otlp_go_proto"
+	"gvisor.dev/gvisor/pkg/otlp/otlptest"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func newSink(t *testing.T, config map[string]any) seccheck.Sink {
+	t.Helper()
+	files, err := setupSink(config)
+	if err != nil {
+		t.Fatalf("setupSink(): %v", err)
+	}
+	if got, want := len(files), numFiles(config); got != want {
+		t.Fatalf("setupSink() returned %d files, want: %d", got, want)
+	}
+	var endpoints []*fd.FD
+	for _, f := range files {
+		endpoint, err := fd.NewFromFile(f)
+		if err != nil {
+			t.Fatalf("fd.NewFromFile(): %v", err)
+		}
+		_ = f.Close()
+		endpoints = append(endpoints, endpoint)
+	}
+	sink, err := new(config, endpoints)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	return sink
+}
+
+// startCollector starts a collector, serving TLS if useTLS is set. Its CA
+// certificate is written to a file, whose path is returned.
+func startCollector(t *testing.T, useTLS bool) (*otlptest.Collector, string) {
+	t.Helper()
+	start := otlptest.Start
+	if useTLS {
+		start = otlptest.StartTLS
+	}
+	collector, err := start(protocolHTTP)
+	if err != nil {
+		t.Fatalf("otlptest.Start(): %v", err)
+	}
+	t.Cleanup(collector.Stop)
+	if !useTLS {
+		return collector, ""
+	}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, collector.CACert, 0644); err != nil {
+		t.Fatalf("WriteFile(): %v", err)
+	}
+	return collector, caFile
+}
+
+func findAttr(attrs []*otlppb.KeyValue, key string) *otlppb.AnyValue {
+	for _, kv := range attrs {
+		if kv.GetKey() == key {
+			return kv.GetValue()
+		}
+	}
+	return nil
+}
+
+// checkAttrs checks the attributes that identify the container and process of
+// a point.
+func checkAttrs(t *testing.T, desc string, attrs []*otlppb.KeyValue) {
+	t.Helper()
+	if got := findAttr(attrs, "container.id").GetStringValue(); got != "container" {
+		t.Errorf("%s: wrong container.id, got: %q", desc, got)
+	}
+	if got := findAttr(attrs, "process.pid").GetIntValue(); got != 1 {
+		t.Errorf("%s: wrong process.pid, got: %d", desc, got)
+	}
+	if got := findAttr(attrs, "process.executable.name").GetStringValue(); got != "cat" {
+		t.Errorf("%s: wrong process.executable.name, got: %q", desc, got)
+	}
+}
+
+func TestSink(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		signals []any
+		tls     bool
+	}{
+		{name: "logs", signals: []any{signalLogs}},
+		{name: "traces", signals: []any{signalTraces}},
+		{name: "logs-traces", signals: []any{signalLogs, signalTraces}},
+		{name: "tls", signals: []any{signalLogs, signalTraces}, tls: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			collector, caFile := startCollector(t, tc.tls)
+			config := map[string]any{
+				"endpoint":            collector.Endpoint,
+				"signals":             tc.signals,
+				"headers":             map[string]any{"authorization": "token"},
+				"batch_size":          float64(2),
+				"resource_attributes": map[string]any{"k8s.pod.name": "pod"},
+			}
+			if tc.tls {
+				config["tls"] = true
+				config["tls_ca_file"] = caFile
+			}
+			sink := newSink(t, config)
+			logs, traces := false, false
+			for _, signal := range tc.signals {
+				logs = logs || signal == signalLogs
+				traces = traces || signal == signalTraces
+			}
+			exported := func() int {
+				if logs {
+					return len(collector.LogRecords())
+				}
+				return len(collector.Spans())
+			}
+
+			ctxData := &pb.ContextData{
+				TimeNs:                 123,
+				ThreadId:               2,
+				ThreadGroupId:          1,
+				ThreadGroupStartTimeNs: 100,
+				ContainerId:            "container",
+				ProcessName:            "cat",
+			}
+			if err := sink.Execve(nil, seccheck.FieldSet{}, &pb.ExecveInfo{ContextData: ctxData, BinaryPath: "/bin/cat"}); err != nil {
+				t.Fatalf("Execve(): %v", err)
+			}
+			if err := sink.Syscall(nil, seccheck.FieldSet{}, ctxData, pb.MessageType_MESSAGE_SYSCALL_OPEN, &pb.Open{ContextData: ctxData, Pathname: "/etc/hosts"}); err != nil {
+				t.Fatalf("Syscall(): %v", err)
+			}
+			// The first batch is full and is exported right away.
+			if err := collector.WaitFor(10*time.Second, func() bool { return exported() == 2 }); err != nil {
+				t.Fatalf("waiting for points: %v", err)
+			}
+			// The last point is exported when the sink is stopped.
+			if err := sink.Denial(nil, seccheck.FieldSet{}, &pb.Denial{ContextData: ctxData, Point: "syscall/openat/enter"}); err != nil {
+				t.Fatalf("Denial(): %v", err)
+			}
+			sink.Stop()
+
+			want := []struct {
+				name string
+				body string
+			}{
+				{name: "MESSAGE_SENTRY_EXEC", body: "/bin/cat"},
+				{name: "MESSAGE_SYSCALL_OPEN", body: "/etc/hosts"},
+				{name: "MESSAGE_SENTRY_DENIAL", body: "syscall/openat/enter"},
+			}
+			records := collector.LogRecords()
+			spans := collector.Spans()
+			if logs && len(records) != len(want) {
+				t.Fatalf("wrong number of log records, got: %d, want: %d", len(records), len(want))
+			}
+			if traces && len(spans) != len(want) {
+				t.Fatalf("wrong number of spans, got: %d, want: %d", len(spans), len(want))
+			}
+			if !logs && len(records) != 0 {
+				t.Errorf("log records exported without the logs signal: %v", records)
+			}
+			if !traces && len(spans) != 0 {
+				t.Errorf("spans exported without the traces signal: %v", spans)
+			}
+			for i, want := range want {
+				if logs {
+					record := records[i]
+					if got := record.GetEventName(); got != want.name {
+						t.Errorf("record %d: wrong event name, got: %q, want: %q", i, got, want.name)
+					}
+					if got := record.GetBody().GetStringValue(); !strings.Contains(got, want.body) {
+						t.Errorf("record %d: body %q doesn't contain %q", i, got, want.body)
+					}
+					if got := record.GetTimeUnixNano(); got != 123 {
+						t.Errorf("record %d: wrong time, got: %d, want: 123", i, got)
+					}
+					checkAttrs(t, "record", record.GetAttributes())
+					if traces && (!bytes.Equal(record.GetTraceId(), spans[i].GetTraceId()) || !bytes.Equal(record.GetSpanId(), spans[i].GetSpanId())) {
+						t.Errorf("record %d doesn't refer to its span", i)
+					}
+				}
+				if traces {
+					span := spans[i]
+					if got := span.GetName(); got != want.name {
+						t.Errorf("span %d: wrong name, got: %q, want: %q", i, got, want.name)
+					}
+					if got := findAttr(span.GetAttributes(), "gvisor.point").GetStringValue(); !strings.Contains(got, want.body) {
+						t.Errorf("span %d: gvisor.point %q doesn't contain %q", i, got, want.body)
+					}
+					if span.GetStartTimeUnixNano() != 123 || span.GetEndTimeUnixNano() != 123 {
+						t.Errorf("span %d: wrong times, got: [%d, %d], want: [123, 123]", i, span.GetStartTimeUnixNano(), span.GetEndTimeUnixNano())
+					}
+					checkAttrs(t, "span", span.GetAttributes())
+					// All spans of a process are in the same trace.
+					if len(span.GetTraceId()) != 16 || !bytes.Equal(span.GetTraceId(), spans[0].GetTraceId()) {
+						t.Errorf("span %d: wrong trace ID %x, want: %x", i, span.GetTraceId(), spans[0].GetTraceId())
+					}
+					if len(span.GetSpanId()) != 8 || (i > 0 && bytes.Equal(span.GetSpanId(), spans[i-1].GetSpanId())) {
+						t.Errorf("span %d: invalid span ID %x", i, span.GetSpanId())
+					}
+					wantCode := otlppb.Status_STATUS_CODE_UNSET
+					if want.name == "MESSAGE_SENTRY_DENIAL" {
+						wantCode = otlppb.Status_STATUS_CODE_ERROR
+					}
+					if got := span.GetStatus().GetCode(); got != wantCode {
+						t.Errorf("span %d: wrong status, got: %v, want: %v", i, got, wantCode)
+					}
+				}
+			}
+			var resources []*otlppb.Resource
+			for _, rl := range collector.Logs() {
+				resources = append(resources, rl.GetResource())
+			}
+			for _, rs := range collector.Traces() {
+				resources = append(resources, rs.GetResource())
+			}
+			for _, resource := range resources {
+				if got := findAttr(resource.GetAttributes(), "service.name").GetStringValue(); got != defaultServiceName {
+					t.Errorf("wrong service.name, got: %q, want: %q", got, defaultServiceName)
+				}
+				if got := findAttr(resource.GetAttributes(), "k8s.pod.name").GetStringValue(); got != "pod" {
+					t.Errorf("wrong k8s.pod.name, got: %q, want: %q", got, "pod")
+				}
+			}
+			for _, headers := range collector.Headers() {
+				if got := headers["Authorization"]; len(got) != 1 || got[0] != "token" {
+					t.Errorf("authorization header not found: %v", headers)
+				}
+			}
+			if got := sink.Status().DroppedCount; got != 0 {
+				t.Errorf("wrong dropped count, got: %d, want: 0", got)
+			}
+		})
+	}
+}
+
+func TestStopFlushes(t *testing.T) {
+	collector, _ := startCollector(t, false /* useTLS */)
+
+	// Use a long batch timeout, so that points are only exported on Stop.
+	sink := newSink(t, map[string]any{
+		"endpoint":      collector.Endpoint,
+		"batch_size":    float64(100),
+		"batch_timeout": "1h",
+	})
+	for i := 0; i < 10; i++ {
+		if err := sink.Clone(nil, seccheck.FieldSet{}, &pb.CloneInfo{}); err != nil {
+			t.Fatalf("Clone(): %v", err)
+		}
+	}
+	if got := len(collector.LogRecords()); got != 0 {
+		t.Errorf("points exported before batch is full: %d", got)
+	}
+	sink.Stop()
+	if got := len(collector.LogRecords()); got != 10 {
+		t.Errorf("wrong number of log records, got: %d, want: 10", got)
+	}
+	if got := sink.Status().DroppedCount; got != 0 {
+		t.Errorf("wrong dropped count, got: %d, want: 0", got)
+	}
+}
+
+func TestKeepalive(t *testing.T) {
+	collector, _ := startCollector(t, false /* useTLS */)
+	sink := newSink(t, map[string]any{
+		"endpoint":  collector.Endpoint,
+		"keepalive": "10ms",
+	})
+	defer sink.Stop()
+	if err := collector.WaitFor(10*time.Second, func() bool { return len(collector.Headers()) >= 2 }); err != nil {
+		t.Fatalf("waiting for keepalive requests: %v", err)
+	}
+	if got := len(collector.LogRecords()); got != 0 {
+		t.Errorf("keepalive requests exported %d log records", got)
+	}
+}
+
+func TestUntrustedCollector(t *testing.T) {
+	collector, _ := startCollector(t, true /* useTLS */)
+	// Verify the collector with a CA that didn't sign its certificate.
+	other, caFile := startCollector(t, true /* useTLS */)
+	sink := newSink(t, map[string]any{
+		"endpoint":    collector.Endpoint,
+		"tls":         true,
+		"tls_ca_file": caFile,
+	})
+	if err := sink.Clone(nil, seccheck.FieldSet{}, &pb.CloneInfo{}); err != nil {
+		t.Fatalf("Clone(): %v", err)
+	}
+	sink.Stop()
+	if got := len(collector.LogRecords()) + len(other.LogRecords()); got != 0 {
+		t.Errorf("untrusted collector received %d log records", got)
+	}
+	if got := sink.Status().DroppedCount; got != 1 {
+		t.Errorf("wrong dropped count, got: %d, want: 1", got)
+	}
+}
+
+func TestHTTPResponses(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		resp    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "content-length",
+			resp: "HTTP/1.1 200 OK\r\nContent-Length: 3\r\n\r\nabc",
+			want: "abc",
+		},
+		{
+			name: "chunked",
+			resp: "HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nab\r\n1;ext\r\nc\r\n0\r\nTrailer: x\r\n\r\n",
+			want: "abc",
+		},
+		{
+			name:    "status",
+			resp:    "HTTP/1.1 503 Service Unavailable\r\nContent-Length: 0\r\n\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			resp:    "garbage\r\n\r\n",
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer server.Close()
+			go func() {
+				// Consume the request before responding.
+				buf := make([]byte, 4096)
+				server.Read(buf)
+				server.Write([]byte(tc.resp))
+			}()
+			c := newHTTPClient(client, client, "localhost", nil, 10*time.Second)
+			got, err := c.post(logsPath, []byte("req"))
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("post() succeeded with response %q, want error", tc.resp)
+				}
+				return
+			}
+			if err != nil || string(got) != tc.want {
+				t.Errorf("post() = %q, %v, want: %q, nil", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config map[string]any
+	}{
+		{name: "protocol-grpc", config: map[string]any{"protocol": "grpc"}},
+		{name: "protocol", config: map[string]any{"protocol": "udp"}},
+		{name: "signals", config: map[string]any{"signals": []any{"metrics"}}},
+		{name: "signals-empty", config: map[string]any{"signals": []any{}}},
+		{name: "batch_size", config: map[string]any{"batch_size": float64(0)}},
+		{name: "batch_size-float", config: map[string]any{"batch_size": 1.5}},
+		{name: "batch_timeout", config: map[string]any{"batch_timeout": "forever"}},
+		{name: "keepalive", config: map[string]any{"keepalive": "0s"}},
+		{name: "headers", config: map[string]any{"headers": "authorization"}},
+		{name: "headers-newline", config: map[string]any{"headers": map[string]any{"authorization": "token\r\nHost: evil"}}},
+		{name: "resource_attributes", config: map[string]any{"resource_attributes": map[string]any{"a": 1.0}}},
+		{name: "tls-no-ca", config: map[string]any{"tls": true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer server.Close()
+			if _, err := newExporter(tc.config, client, nil /* ca */); err == nil {
+				t.Errorf("newExporter(%v) should have failed", tc.config)
+			}
+		})
+	}
+	if _, err := setupSink(map[string]any{}); err == nil || !strings.Contains(err.Error(), "endpoint") {
+		t.Errorf("setupSink() without endpoint, got: %v, want: endpoint error", err)
+	}
+}
@@ -65,7 +65,7 @@ var _ seccheck.Sink = (*remote)(nil)
 // setupSink starts the connection to the remote process and returns a file that
 // can be used to communicate with it. The caller is responsible to close to
 // file.
-func setupSink(config map[string]any) (*os.File, error) {
+func setupSink(config map[string]any) ([]*os.File, error) {
 	addrOpaque, ok := config["endpoint"]
 	if !ok {
 		return nil, fmt.Errorf("endpoint not present in configuration")
@@ -74,7 +74,11 @@ func setupSink(config map[string]any) (*os.File, error) {
 	if !ok {
 		return nil, fmt.Errorf("endpoint %q is not a string", addrOpaque)
 	}
-	return setup(addr)
+	f, err := setup(addr)
+	if err != nil {
+		return nil, err
+	}
+	return []*os.File{f}, nil
 }
 
 func setup(path string) (*os.File, error) {
@@ -150,10 +154,11 @@ func parseDuration(config map[string]any, name string) (bool, time.Duration, err
 }
 
 // new creates a new Remote sink.
-func new(config map[string]any, endpoint *fd.FD) (seccheck.Sink, error) {
-	if endpoint == nil {
+func new(config map[string]any, endpoints []*fd.FD) (seccheck.Sink, error) {
+	if len(endpoints) != 1 || endpoints[0] == nil {
 		return nil, fmt.Errorf("remote sink requires an endpoint")
 	}
+	endpoint := endpoints[0]
 	r := &remote{
 		endpoint:       endpoint,
 		initialBackoff: 25 * time.Microsecond,
@@ -282,3 +287,9 @@ func (r *remote) Syscall(ctx context.Context, fields seccheck.FieldSet, ctxData
 	r.write(msg, msgType)
 	return nil
 }
+
+// Denial implements seccheck.Sink.
+func (r *remote) Denial(_ context.Context, _ seccheck.FieldSet, info *pb.Denial) error {
+	r.write(info, pb.MessageType_MESSAGE_SENTRY_DENIAL)
+	return nil
+}
@@ -126,7 +126,7 @@ func TestBasic(t *testing.T) {
 	}
 	_ = endpoint.Close()
 
-	r, err := new(nil, endpointFD)
+	r, err := new(nil, []*fd.FD{endpointFD})
 	if err != nil {
 		t.Fatalf("New(): %v", err)
 	}
@@ -205,7 +205,7 @@ func TestExample(t *testing.T) {
 	}
 	_ = endpoint.Close()
 
-	r, err := new(nil, endpointFD)
+	r, err := new(nil, []*fd.FD{endpointFD})
 	if err != nil {
 		t.Fatalf("New(): %v", err)
 	}
@@ -290,7 +290,7 @@ func TestConfig(t *testing.T) {
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			var endpoint fd.FD
-			sink, err := new(tc.config, &endpoint)
+			sink, err := new(tc.config, []*fd.FD{&endpoint})
 			if len(tc.err) == 0 {
 				if err != nil {
 					t.Fatalf("new(%q): %v", tc.config, err)
@@ -326,7 +326,7 @@ func BenchmarkSmall(t *testing.B) {
 	}
 	_ = endpoint.Close()
 
-	r, err := new(nil, endpointFD)
+	r, err := new(nil, []*fd.FD{endpointFD})
 	if err != nil {
 		t.Fatalf("New(): %v", err)
 	}
@@ -69,6 +69,22 @@ const (
 	FieldSyscallExecveEnvv = FieldSyscallPath + 1
 )
 
+// Fields for syscalls that operate on paths, e.g. unlink(2), rename(2).
+const (
+	// FieldSyscallResolvedPath is an optional field to collect the absolute
+	// path of the pathname arguments, resolved against the FD or the working
+	// directory. Start after FieldSyscallExecveEnvv to leave room for points
+	// that collect both.
+	FieldSyscallResolvedPath = FieldSyscallExecveEnvv + 1
+)
+
+// Fields for chmod(2) and chown(2) family of syscalls.
+const (
+	// FieldSyscallOldAttrs is an optional field to collect the file mode or
+	// owner before they are changed. It's only collected on syscall enter.
+	FieldSyscallOldAttrs = FieldSyscallResolvedPath + 1
+)
+
 // GetPointForSyscall translates the syscall number to the corresponding Point.
 func GetPointForSyscall(typ SyscallType, sysno uintptr) Point {
 	return Point(sysno)*Point(syscallTypesCount) + Point(typ) + pointLengthBeforeSyscalls
@@ -0,0 +1,529 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostinet
+
+import (
+	goContext "context"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/fdnotifier"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// Host sockets can't be saved, so they are recreated on restore from the
+// state recorded when the Stack is paused for save:
+//
+//   - Unconnected sockets are recreated with the same options and bound to
+//     the same address.
+//   - Listening sockets are also listened on again. Connections that weren't
+//     accepted before save are lost.
+//   - UDP sockets are also connected to the same peer. Datagrams that weren't
+//     received before save are lost.
+//   - Established TCP connections are restored using TCP_REPAIR, if enabled.
+//     Otherwise, they are recreated unconnected and reported as reset.
+
+// TCPRepairSockOpts are the socket options used to save and restore
+// established TCP connections with TCP_REPAIR. They are not available to
+// applications.
+var TCPRepairSockOpts = []SockOpt{
+	{linux.SOL_TCP, linux.TCP_QUEUE_SEQ, sizeofInt32, true, true},
+	{linux.SOL_TCP, linux.TCP_REPAIR, sizeofInt32, false, true},
+	{linux.SOL_TCP, linux.TCP_REPAIR_OPTIONS, 0 /* array of struct tcp_repair_opt */, false, true},
+	{linux.SOL_TCP, linux.TCP_REPAIR_QUEUE, sizeofInt32, false, true},
+	{linux.SOL_TCP, linux.TCP_REPAIR_WINDOW, linux.SizeOfTCPRepairWindow, true, true},
+	{linux.SOL_TCP, linux.TCP_TIMESTAMP, sizeofInt32, true, true},
+}
+
+// savedSocket is the state of a host socket recorded at save.
+//
+// +stateify savable
+type savedSocket struct {
+	// localAddr is the address the socket is bound to, or nil if it is
+	// unbound.
+	localAddr []byte
+
+	// peerAddr is the address the socket is connected to, or nil if it is
+	// unconnected.
+	peerAddr []byte
+
+	// listening indicates whether the socket is listening.
+	listening bool
+
+	// opts are the socket's options.
+	opts []savedSockOpt
+
+	// tcp is the state of the socket's established TCP connection, or nil
+	// if it doesn't have one.
+	tcp *tcpRepairState
+
+	// reset is the connection that couldn't be saved, if any.
+	reset *connectionID
+}
+
+// savedSockOpt is the value of a socket option.
+//
+// +stateify savable
+type savedSockOpt struct {
+	level int
+	name  int
+	val   []byte
+}
+
+// tcpRepairState is the state of an established TCP connection saved using
+// TCP_REPAIR.
+//
+// +stateify savable
+type tcpRepairState struct {
+	// sendSeq and recvSeq are the sequence numbers at the head of the send
+	// and receive queues.
+	sendSeq uint32
+	recvSeq uint32
+
+	// sendQueue and recvQueue are the contents of the send and receive
+	// queues.
+	sendQueue []byte
+	recvQueue []byte
+
+	// mss is the maximum segment size.
+	mss uint32
+
+	// options are the TCPI_OPT_* options negotiated for the connection.
+	options uint8
+
+	// sendWScale and recvWScale are the window scales negotiated for the
+	// connection.
+	sendWScale uint8
+	recvWScale uint8
+
+	// timestamp is the TCP timestamp clock.
+	timestamp uint32
+
+	// window is the struct tcp_repair_window of the connection, or nil if
+	// the host doesn't support TCP_REPAIR_WINDOW.
+	window []byte
+}
+
+// connectionID identifies a connection that couldn't be saved or restored.
+//
+// +stateify savable
+type connectionID struct {
+	localAddr []byte
+	peerAddr  []byte
+}
+
+// prepareSave records the state of the host socket in s.saved. If tcpRepair
+// is true, established TCP connections are put in TCP_REPAIR mode, which
+// they are left in until leaveRepair is called.
+//
+// Preconditions: The kernel is paused.
+func (s *Socket) prepareSave(tcpRepair bool) {
+	saved := &savedSocket{
+		opts: s.saveSockOpts(),
+	}
+	s.saved = saved
+	if s.family != unix.AF_INET && s.family != unix.AF_INET6 {
+		return
+	}
+	localAddr, err := getsockname(s.fd)
+	if err != nil {
+		log.Warningf("hostinet: getsockname on host socket %d: %v", s.fd, err)
+		return
+	}
+	peerAddr, err := getpeername(s.fd)
+	if err != nil && err != unix.ENOTCONN {
+		log.Warningf("hostinet: getpeername on host socket %d: %v", s.fd, err)
+	}
+	if s.stype != linux.SOCK_STREAM {
+		if sockaddrPort(localAddr) != 0 {
+			saved.localAddr = localAddr
+		}
+		saved.peerAddr = peerAddr
+		return
+	}
+
+	switch state := s.State(); state {
+	case uint32(linux.TCP_CLOSE):
+		if sockaddrPort(localAddr) != 0 {
+			saved.localAddr = localAddr
+		}
+	case uint32(linux.TCP_LISTEN):
+		saved.localAddr = localAddr
+		saved.listening = true
+	case uint32(linux.TCP_ESTABLISHED):
+		if tcpRepair {
+			tcp, err := s.saveTCPRepair()
+			if err == nil {
+				saved.localAddr = localAddr
+				saved.peerAddr = peerAddr
+				saved.tcp = tcp
+				return
+			}
+			log.Warningf("hostinet: saving TCP connection on host socket %d: %v", s.fd, err)
+		}
+		saved.reset = &connectionID{localAddr: localAddr, peerAddr: peerAddr}
+	default:
+		// Connections that are being established or closed can't be saved.
+		log.Infof("hostinet: not saving TCP connection in state %d on host socket %d", state, s.fd)
+		saved.reset = &connectionID{localAddr: localAddr, peerAddr: peerAddr}
+	}
+}
+
+// saveSockOpts returns the values of the options of the host socket.
+func (s *Socket) saveSockOpts() []savedSockOpt {
+	var opts []savedSockOpt
+	for _, opt := range SockOpts {
+		if !opt.AllowGet || !opt.AllowSet || opt.Size == 0 {
+			continue
+		}
+		level, name := int(opt.Level), int(opt.Name)
+		if level == linux.SOL_SOCKET && name == linux.SO_ACCEPTCONN {
+			// Restored by listening.
+			continue
+		}
+		val, err := getsockopt(s.fd, level, name, make([]byte, opt.Size))
+		if err != nil || uint64(len(val)) != opt.Size {
+			// The option doesn't apply to this socket.
+			continue
+		}
+		if level == linux.SOL_SOCKET && (name == linux.SO_RCVBUF || name == linux.SO_SNDBUF) {
+			// The host doubles the buffer sizes that are set.
+			hostarch.ByteOrder.PutUint32(val, hostarch.ByteOrder.Uint32(val)/2)
+		}
+		opts = append(opts, savedSockOpt{level: level, name: name, val: val})
+	}
+	return opts
+}
+
+// saveTCPRepair puts the host socket's established TCP connection in
+// TCP_REPAIR mode and returns its state.
+func (s *Socket) saveTCPRepair() (*tcpRepairState, error) {
+	if err := unix.SetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_REPAIR, linux.TCP_REPAIR_ON); err != nil {
+		return nil, fmt.Errorf("entering TCP_REPAIR mode: %w", err)
+	}
+	s.inRepair = true
+	tcp, err := s.saveTCPRepairState()
+	if err != nil {
+		s.leaveRepair()
+		return nil, err
+	}
+	return tcp, nil
+}
+
+// Preconditions: The host socket is in TCP_REPAIR mode.
+func (s *Socket) saveTCPRepairState() (*tcpRepairState, error) {
+	tcp := &tcpRepairState{}
+	var err error
+	if tcp.sendSeq, tcp.sendQueue, err = s.saveTCPQueue(linux.TCP_SEND_QUEUE, unix.TIOCOUTQ); err != nil {
+		return nil, fmt.Errorf("saving send queue: %w", err)
+	}
+	if tcp.recvSeq, tcp.recvQueue, err = s.saveTCPQueue(linux.TCP_RECV_QUEUE, unix.TIOCINQ); err != nil {
+		return nil, fmt.Errorf("saving receive queue: %w", err)
+	}
+	if err := unix.SetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_REPAIR_QUEUE, linux.TCP_NO_QUEUE); err != nil {
+		return nil, fmt.Errorf("deselecting repair queue: %w", err)
+	}
+
+	buf, err := getsockopt(s.fd, unix.SOL_TCP, unix.TCP_INFO, make([]byte, linux.SizeOfTCPInfo))
+	if err != nil || len(buf) != linux.SizeOfTCPInfo {
+		return nil, fmt.Errorf("getting TCP_INFO: %v", err)
+	}
+	var info linux.TCPInfo
+	info.UnmarshalUnsafe(buf)
+	tcp.options = info.Options
+	tcp.sendWScale = info.WindowScale & 0xf
+	tcp.recvWScale = info.WindowScale >> 4
+	mss, err := unix.GetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_MAXSEG)
+	if err != nil {
+		return nil, fmt.Errorf("getting TCP_MAXSEG: %w", err)
+	}
+	tcp.mss = uint32(mss)
+	if info.Options&linux.TCPI_OPT_TIMESTAMPS != 0 {
+		ts, err := unix.GetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_TIMESTAMP)
+		if err != nil {
+			return nil, fmt.Errorf("getting TCP_TIMESTAMP: %w", err)
+		}
+		tcp.timestamp = uint32(ts)
+	}
+	// TCP_REPAIR_WINDOW is only supported by Linux 4.8 and later. Without
+	// it, the windows are renegotiated after restore.
+	if window, err := getsockopt(s.fd, unix.SOL_TCP, linux.TCP_REPAIR_WINDOW, make([]byte, linux.SizeOfTCPRepairWindow)); err == nil && len(window) == linux.SizeOfTCPRepairWindow {
+		tcp.window = window
+	}
+	return tcp, nil
+}
+
+// saveTCPQueue returns the sequence number at the head of the given queue,
+// and its contents, whose length is returned by the ioctl sizeCmd.
+//
+// TCP_QUEUE_SEQ returns the sequence number at the tail of the queue, which
+// writing the queue's contents on restore advances it to again.
+//
+// Preconditions: The host socket is in TCP_REPAIR mode.
+func (s *Socket) saveTCPQueue(queue int, sizeCmd uint) (uint32, []byte, error) {
+	if err := unix.SetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_REPAIR_QUEUE, queue); err != nil {
+		return 0, nil, err
+	}
+	seq, err := unix.GetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_QUEUE_SEQ)
+	if err != nil {
+		return 0, nil, err
+	}
+	size, err := unix.IoctlGetInt(s.fd, sizeCmd)
+	if err != nil {
+		return 0, nil, err
+	}
+	head := uint32(seq) - uint32(size)
+	if size == 0 {
+		return head, nil, nil
+	}
+	data := make([]byte, size)
+	n, _, err := unix.Recvfrom(s.fd, data, unix.MSG_PEEK|unix.MSG_DONTWAIT)
+	if err != nil {
+		return 0, nil, err
+	}
+	if n != size {
+		return 0, nil, fmt.Errorf("read %d bytes of queue, want %d", n, size)
+	}
+	return head, data, nil
+}
+
+// leaveRepair takes the host socket out of TCP_REPAIR mode, if it is in it.
+func (s *Socket) leaveRepair() {
+	if !s.inRepair {
+		return
+	}
+	if err := unix.SetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_REPAIR, linux.TCP_REPAIR_OFF); err != nil {
+		log.Warningf("hostinet: leaving TCP_REPAIR mode on host socket %d: %v", s.fd, err)
+	}
+	s.inRepair = false
+}
+
+// afterLoad is invoked by stateify.
+func (s *Socket) afterLoad(ctx goContext.Context) {
+	s.stack = RestoreStackFromContext(ctx)
+	if s.stack == nil {
+		panic("hostinet socket restored without a hostinet stack")
+	}
+	fd, err := unix.Socket(s.family, int(s.stype)|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, s.protocol)
+	if err != nil {
+		panic(fmt.Sprintf("recreating host socket (family %d, type %d, protocol %d): %v", s.family, s.stype, s.protocol, err))
+	}
+	s.fd = fd
+	if saved := s.saved; saved != nil {
+		s.saved = nil
+		s.restore(saved)
+	}
+	if err := fdnotifier.AddFD(int32(s.fd), &s.queue); err != nil {
+		panic(fmt.Sprintf("adding restored host socket %d to fdnotifier: %v", s.fd, err))
+	}
+	s.stack.addSocket(s)
+}
+
+// restore restores the state of the newly created host socket from saved.
+// Failures are logged rather than failing restore, since applications must
+// handle sockets failing anyway.
+func (s *Socket) restore(saved *savedSocket) {
+	if saved.tcp != nil {
+		if err := s.restoreTCPRepair(saved); err != nil {
+			log.Warningf("hostinet: restoring TCP connection: %v", err)
+			s.recordRestoreReset(saved.localAddr, saved.peerAddr)
+			// Start over with a fresh socket, since the old one may be
+			// partially restored.
+			unix.Close(s.fd)
+			fd, err := unix.Socket(s.family, int(s.stype)|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, s.protocol)
+			if err != nil {
+				panic(fmt.Sprintf("recreating host socket (family %d, type %d, protocol %d): %v", s.family, s.stype, s.protocol, err))
+			}
+			s.fd = fd
+			s.restoreSockOpts(saved.opts)
+		}
+		return
+	}
+
+	s.restoreSockOpts(saved.opts)
+	if saved.reset != nil {
+		s.recordRestoreReset(saved.reset.localAddr, saved.reset.peerAddr)
+		return
+	}
+	if saved.localAddr != nil {
+		if err := bind(s.fd, saved.localAddr); err != nil {
+			log.Warningf("hostinet: rebinding host socket %d: %v", s.fd, err)
+			return
+		}
+	}
+	if saved.listening {
+		if err := unix.Listen(s.fd, s.backlog); err != nil {
+			log.Warningf("hostinet: listening on host socket %d: %v", s.fd, err)
+		}
+		return
+	}
+	if saved.peerAddr != nil {
+		if err := connect(s.fd, saved.peerAddr); err != nil {
+			log.Warningf("hostinet: reconnecting host socket %d: %v", s.fd, err)
+		}
+	}
+}
+
+// restoreSockOpts sets the options of the host socket to opts.
+func (s *Socket) restoreSockOpts(opts []savedSockOpt) {
+	for _, opt := range opts {
+		if err := setsockopt(s.fd, opt.level, opt.name, opt.val); err != nil {
+			log.Debugf("hostinet: restoring socket option (level %d, name %d) on host socket %d: %v", opt.level, opt.name, s.fd, err)
+		}
+	}
+}
+
+// restoreTCPRepair restores an established TCP connection using TCP_REPAIR.
+func (s *Socket) restoreTCPRepair(saved *savedSocket) error {
+	tcp := saved.tcp
+	if err := unix.SetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_REPAIR, linux.TCP_REPAIR_ON); err != nil {
+		return fmt.Errorf("entering TCP_REPAIR mode: %w", err)
+	}
+	s.restoreSockOpts(saved.opts)
+	for _, q := range []struct {
+		queue int
+		seq   uint32
+	}{
+		{linux.TCP_SEND_QUEUE, tcp.sendSeq},
+		{linux.TCP_RECV_QUEUE, tcp.recvSeq},
+	} {
+		if err := unix.SetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_REPAIR_QUEUE, q.queue); err != nil {
+			return fmt.Errorf("selecting repair queue: %w", err)
+		}
+		if err := unix.SetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_QUEUE_SEQ, int(q.seq)); err != nil {
+			return fmt.Errorf("setting TCP_QUEUE_SEQ: %w", err)
+		}
+	}
+	if err := bind(s.fd, saved.localAddr); err != nil {
+		return fmt.Errorf("binding: %w", err)
+	}
+	// In TCP_REPAIR mode, connect puts the socket in the established state
+	// without sending anything.
+	if err := connect(s.fd, saved.peerAddr); err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+
+	var opts []byte
+	addOpt := func(code, val uint32) {
+		var opt [linux.SizeOfTCPRepairOpt]byte
+		hostarch.ByteOrder.PutUint32(opt[0:], code)
+		hostarch.ByteOrder.PutUint32(opt[4:], val)
+		opts = append(opts, opt[:]...)
+	}
+	addOpt(linux.TCPOPT_MSS, tcp.mss)
+	if tcp.options&linux.TCPI_OPT_WSCALE != 0 {
+		addOpt(linux.TCPOPT_WINDOW, uint32(tcp.sendWScale)|uint32(tcp.recvWScale)<<16)
+	}
+	if tcp.options&linux.TCPI_OPT_SACK != 0 {
+		addOpt(linux.TCPOPT_SACK_PERM, 0)
+	}
+	if tcp.options&linux.TCPI_OPT_TIMESTAMPS != 0 {
+		addOpt(linux.TCPOPT_TIMESTAMP, 0)
+	}
+	if err := setsockopt(s.fd, unix.SOL_TCP, linux.TCP_REPAIR_OPTIONS, opts); err != nil {
+		return fmt.Errorf("setting TCP_REPAIR_OPTIONS: %w", err)
+	}
+	if tcp.options&linux.TCPI_OPT_TIMESTAMPS != 0 {
+		if err := unix.SetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_TIMESTAMP, int(tcp.timestamp)); err != nil {
+			return fmt.Errorf("setting TCP_TIMESTAMP: %w", err)
+		}
+	}
+
+	if err := s.restoreTCPQueue(linux.TCP_SEND_QUEUE, tcp.sendQueue); err != nil {
+		return fmt.Errorf("restoring send queue: %w", err)
+	}
+	if err := s.restoreTCPQueue(linux.TCP_RECV_QUEUE, tcp.recvQueue); err != nil {
+		return fmt.Errorf("restoring receive queue: %w", err)
+	}
+	if err := unix.SetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_REPAIR_QUEUE, linux.TCP_NO_QUEUE); err != nil {
+		return fmt.Errorf("deselecting repair queue: %w", err)
+	}
+	if tcp.window != nil {
+		if err := setsockopt(s.fd, unix.SOL_TCP, linux.TCP_REPAIR_WINDOW, tcp.window); err != nil {
+			return fmt.Errorf("setting TCP_REPAIR_WINDOW: %w", err)
+		}
+	}
+
+	if err := unix.SetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_REPAIR, linux.TCP_REPAIR_OFF); err != nil {
+		return fmt.Errorf("leaving TCP_REPAIR mode: %w", err)
+	}
+	return nil
+}
+
+// restoreTCPQueue writes data to the given queue.
+//
+// Preconditions: The host socket is in TCP_REPAIR mode.
+func (s *Socket) restoreTCPQueue(queue int, data []byte) error {
+	if err := unix.SetsockoptInt(s.fd, unix.SOL_TCP, linux.TCP_REPAIR_QUEUE, queue); err != nil {
+		return err
+	}
+	for len(data) > 0 {
+		n, err := unix.SendmsgN(s.fd, data, nil, nil, unix.MSG_DONTWAIT)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// recordRestoreReset records that the connection from localAddr to peerAddr
+// couldn't be restored.
+func (s *Socket) recordRestoreReset(localAddr, peerAddr []byte) {
+	localIP, localPort := sockaddrAddress(localAddr)
+	peerIP, peerPort := sockaddrAddress(peerAddr)
+	s.stack.recordRestoreReset(stack.RestoreResetEndpoint{
+		TransProto: tcpip.TransportProtocolNumber(s.protocol),
+		ID: stack.TransportEndpointID{
+			LocalPort:     localPort,
+			LocalAddress:  localIP,
+			RemotePort:    peerPort,
+			RemoteAddress: peerIP,
+		},
+		Err: &tcpip.ErrConnectionReset{},
+	})
+}
+
+// sockaddrPort returns the port of the AF_INET or AF_INET6 socket address
+// addr.
+func sockaddrPort(addr []byte) uint16 {
+	if len(addr) < 4 {
+		return 0
+	}
+	return uint16(addr[2])<<8 | uint16(addr[3])
+}
+
+// sockaddrAddress returns the IP address and port of the AF_INET or AF_INET6
+// socket address addr.
+func sockaddrAddress(addr []byte) (tcpip.Address, uint16) {
+	if len(addr) < 2 {
+		return tcpip.Address{}, 0
+	}
+	switch hostarch.ByteOrder.Uint16(addr) {
+	case unix.AF_INET:
+		if len(addr) >= unix.SizeofSockaddrInet4 {
+			return tcpip.AddrFrom4Slice(addr[4:8]), sockaddrPort(addr)
+		}
+	case unix.AF_INET6:
+		if len(addr) >= unix.SizeofSockaddrInet6 {
+			return tcpip.AddrFrom16Slice(addr[8:24]), sockaddrPort(addr)
+		}
+	}
+	return tcpip.Address{}, 0
+}
@@ -0,0 +1,398 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostinet
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/fdnotifier"
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+var loopback = &unix.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}
+
+// newTestSocket returns a Socket in st for a new host socket.
+func newTestSocket(t *testing.T, st *Stack, stype linux.SockType, protocol int) *Socket {
+	t.Helper()
+	fd, err := unix.Socket(unix.AF_INET, int(stype)|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, protocol)
+	if err != nil {
+		t.Fatalf("socket: %v", err)
+	}
+	s := &Socket{
+		family:   unix.AF_INET,
+		stype:    stype,
+		protocol: protocol,
+		stack:    st,
+		fd:       fd,
+	}
+	st.addSocket(s)
+	return s
+}
+
+// saveAndRestore saves s as if the sandbox were checkpointed and exited, and
+// then restores it in a new Stack, which it returns.
+func saveAndRestore(t *testing.T, st *Stack) *Stack {
+	t.Helper()
+	st.Pause()
+	st.socketsMu.Lock()
+	socks := make([]*Socket, 0, len(st.sockets))
+	for s := range st.sockets {
+		socks = append(socks, s)
+	}
+	st.socketsMu.Unlock()
+
+	// The sandbox exits after save, closing the host sockets.
+	for _, s := range socks {
+		st.removeSocket(s)
+		unix.Close(s.fd)
+	}
+
+	newStack := NewStack()
+	ctx := context.WithValue(context.Background(), CtxRestoreStack, newStack)
+	for _, s := range socks {
+		s.afterLoad(ctx)
+		t.Cleanup(func() {
+			fdnotifier.RemoveFD(int32(s.fd))
+			unix.Close(s.fd)
+		})
+	}
+	return newStack
+}
+
+// waitFD waits for events on fd.
+func waitFD(t *testing.T, fd int, events int16) {
+	t.Helper()
+	pfd := []unix.PollFd{{Fd: int32(fd), Events: events}}
+	for {
+		n, err := unix.Poll(pfd, 5000 /* ms */)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("poll: %v", err)
+		}
+		if n == 0 {
+			t.Fatalf("timed out waiting for events %#x on fd %d", events, fd)
+		}
+		return
+	}
+}
+
+// readN reads n bytes from fd.
+func readN(t *testing.T, fd, n int) []byte {
+	t.Helper()
+	var got []byte
+	for len(got) < n {
+		waitFD(t, fd, unix.POLLIN)
+		buf := make([]byte, n-len(got))
+		m, err := unix.Read(fd, buf)
+		if err == unix.EAGAIN {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if m == 0 {
+			t.Fatalf("read: EOF after %q", got)
+		}
+		got = append(got, buf[:m]...)
+	}
+	return got
+}
+
+// writeAll writes b to fd.
+func writeAll(t *testing.T, fd int, b []byte) {
+	t.Helper()
+	for len(b) > 0 {
+		waitFD(t, fd, unix.POLLOUT)
+		n, err := unix.Write(fd, b)
+		if err == unix.EAGAIN {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		b = b[n:]
+	}
+}
+
+// listenTCP returns a host socket listening on loopback, and its address.
+func listenTCP(t *testing.T) (int, *unix.SockaddrInet4) {
+	t.Helper()
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("socket: %v", err)
+	}
+	t.Cleanup(func() { unix.Close(fd) })
+	if err := unix.Bind(fd, loopback); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if err := unix.Listen(fd, 1); err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	sa, err := unix.Getsockname(fd)
+	if err != nil {
+		t.Fatalf("getsockname: %v", err)
+	}
+	return fd, sa.(*unix.SockaddrInet4)
+}
+
+// connectTCP connects s to a new host listener, and returns the accepted end
+// of the connection.
+func connectTCP(t *testing.T, s *Socket) int {
+	t.Helper()
+	lfd, addr := listenTCP(t)
+	if err := unix.Connect(s.fd, addr); err != nil && err != unix.EINPROGRESS {
+		t.Fatalf("connect: %v", err)
+	}
+	peer, _, err := unix.Accept4(lfd, unix.SOCK_CLOEXEC)
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	t.Cleanup(func() { unix.Close(peer) })
+	waitFD(t, s.fd, unix.POLLOUT)
+	return peer
+}
+
+func TestRestoreListeningSocket(t *testing.T) {
+	st := NewStack()
+	s := newTestSocket(t, st, linux.SOCK_STREAM, 0)
+	if err := unix.SetsockoptInt(s.fd, unix.SOL_SOCKET, unix.SO_REUSEADDR, 1); err != nil {
+		t.Fatalf("setsockopt(SO_REUSEADDR): %v", err)
+	}
+	if err := unix.Bind(s.fd, loopback); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if err := unix.Listen(s.fd, 5); err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s.backlog = 5
+	sa, err := unix.Getsockname(s.fd)
+	if err != nil {
+		t.Fatalf("getsockname: %v", err)
+	}
+
+	newStack := saveAndRestore(t, st)
+	if resets := newStack.RestoreResets(); len(resets) != 0 {
+		t.Errorf("RestoreResets() = %v, want none", resets)
+	}
+	if got, err := unix.GetsockoptInt(s.fd, unix.SOL_SOCKET, unix.SO_REUSEADDR); err != nil || got != 1 {
+		t.Errorf("getsockopt(SO_REUSEADDR) = %d, %v, want 1, nil", got, err)
+	}
+	if got := s.State(); got != uint32(linux.TCP_LISTEN) {
+		t.Fatalf("State() = %d, want TCP_LISTEN", got)
+	}
+
+	// The restored socket accepts connections to the same address.
+	cfd, err := unix.Socket(unix.AF_INET, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("socket: %v", err)
+	}
+	defer unix.Close(cfd)
+	if err := unix.Connect(cfd, sa); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	waitFD(t, s.fd, unix.POLLIN)
+	nfd, _, err := unix.Accept4(s.fd, unix.SOCK_CLOEXEC)
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	unix.Close(nfd)
+}
+
+func TestRestoreUDPSocket(t *testing.T) {
+	peer, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("socket: %v", err)
+	}
+	defer unix.Close(peer)
+	if err := unix.Bind(peer, loopback); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	peerAddr, err := unix.Getsockname(peer)
+	if err != nil {
+		t.Fatalf("getsockname: %v", err)
+	}
+
+	st := NewStack()
+	s := newTestSocket(t, st, linux.SOCK_DGRAM, unix.IPPROTO_UDP)
+	if err := unix.Bind(s.fd, loopback); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if err := unix.Connect(s.fd, peerAddr); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	localAddr, err := unix.Getsockname(s.fd)
+	if err != nil {
+		t.Fatalf("getsockname: %v", err)
+	}
+
+	saveAndRestore(t, st)
+	if got, err := unix.Getsockname(s.fd); err != nil || got.(*unix.SockaddrInet4).Port != localAddr.(*unix.SockaddrInet4).Port {
+		t.Errorf("getsockname() = %+v, %v, want %+v", got, err, localAddr)
+	}
+	if got, err := unix.Getpeername(s.fd); err != nil || got.(*unix.SockaddrInet4).Port != peerAddr.(*unix.SockaddrInet4).Port {
+		t.Errorf("getpeername() = %+v, %v, want %+v", got, err, peerAddr)
+	}
+
+	// Datagrams flow both ways between the restored socket and its peer.
+	if err := unix.Sendto(peer, []byte("ping"), 0, localAddr); err != nil {
+		t.Fatalf("sendto: %v", err)
+	}
+	if got := readN(t, s.fd, 4); string(got) != "ping" {
+		t.Errorf("restored socket received %q, want %q", got, "ping")
+	}
+	writeAll(t, s.fd, []byte("pong"))
+	buf := make([]byte, 4)
+	if n, _, err := unix.Recvfrom(peer, buf, 0); err != nil || string(buf[:n]) != "pong" {
+		t.Errorf("peer received %q, %v, want %q", buf[:n], err, "pong")
+	}
+}
+
+func TestRestoreTCPRepair(t *testing.T) {
+	probe, err := unix.Socket(unix.AF_INET, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("socket: %v", err)
+	}
+	err = unix.SetsockoptInt(probe, unix.SOL_TCP, linux.TCP_REPAIR, linux.TCP_REPAIR_ON)
+	unix.Close(probe)
+	if err != nil {
+		t.Skipf("TCP_REPAIR not available: %v", err)
+	}
+
+	st := NewStack()
+	st.tcpRepair = true
+	s := newTestSocket(t, st, linux.SOCK_STREAM, 0)
+	peer := connectTCP(t, s)
+	localAddr, err := unix.Getsockname(s.fd)
+	if err != nil {
+		t.Fatalf("getsockname: %v", err)
+	}
+
+	// Data that the application hasn't read yet is saved in the receive
+	// queue.
+	writeAll(t, peer, []byte("unread"))
+	waitFD(t, s.fd, unix.POLLIN)
+
+	newStack := saveAndRestore(t, st)
+	if resets := newStack.RestoreResets(); len(resets) != 0 {
+		t.Fatalf("RestoreResets() = %v, want none", resets)
+	}
+	if got := s.State(); got != uint32(linux.TCP_ESTABLISHED) {
+		t.Fatalf("State() = %d, want TCP_ESTABLISHED", got)
+	}
+	if got, err := unix.Getsockname(s.fd); err != nil || got.(*unix.SockaddrInet4).Port != localAddr.(*unix.SockaddrInet4).Port {
+		t.Errorf("getsockname() = %+v, %v, want %+v", got, err, localAddr)
+	}
+	if got := readN(t, s.fd, 6); string(got) != "unread" {
+		t.Errorf("restored socket received %q, want %q", got, "unread")
+	}
+
+	// The connection continues in both directions without the peer
+	// noticing.
+	writeAll(t, s.fd, []byte("ping"))
+	if got := readN(t, peer, 4); string(got) != "ping" {
+		t.Errorf("peer received %q, want %q", got, "ping")
+	}
+	writeAll(t, peer, []byte("pong"))
+	if got := readN(t, s.fd, 4); string(got) != "pong" {
+		t.Errorf("restored socket received %q, want %q", got, "pong")
+	}
+}
+
+// checkReset checks that the connection of s from localAddr was reported as
+// reset by st, and that s is no longer connected.
+func checkReset(t *testing.T, st *Stack, s *Socket, localAddr []byte) {
+	t.Helper()
+	resets := st.RestoreResets()
+	if len(resets) != 1 {
+		t.Fatalf("RestoreResets() = %v, want one reset", resets)
+	}
+	r := resets[0]
+	wantAddr, wantPort := sockaddrAddress(localAddr)
+	if r.ID.LocalAddress != wantAddr || r.ID.LocalPort != wantPort {
+		t.Errorf("reset local address = %v:%d, want %v:%d", r.ID.LocalAddress, r.ID.LocalPort, wantAddr, wantPort)
+	}
+	if r.TransProto != tcpip.TransportProtocolNumber(s.protocol) {
+		t.Errorf("reset protocol = %d, want %d", r.TransProto, s.protocol)
+	}
+	if _, ok := r.Err.(*tcpip.ErrConnectionReset); !ok {
+		t.Errorf("reset error = %v, want %v", r.Err, &tcpip.ErrConnectionReset{})
+	}
+	if _, err := unix.Getpeername(s.fd); err != unix.ENOTCONN {
+		t.Errorf("getpeername() = %v, want ENOTCONN", err)
+	}
+}
+
+func TestRestoreTCPWithoutRepairResets(t *testing.T) {
+	st := NewStack()
+	s := newTestSocket(t, st, linux.SOCK_STREAM, 0)
+	connectTCP(t, s)
+	localAddr, err := getsockname(s.fd)
+	if err != nil {
+		t.Fatalf("getsockname: %v", err)
+	}
+
+	newStack := saveAndRestore(t, st)
+	checkReset(t, newStack, s, localAddr)
+}
+
+func TestRestoreTCPRepairFailureResets(t *testing.T) {
+	st := NewStack()
+	s := newTestSocket(t, st, linux.SOCK_STREAM, 0)
+	connectTCP(t, s)
+	localAddr, err := getsockname(s.fd)
+	if err != nil {
+		t.Fatalf("getsockname: %v", err)
+	}
+	peerAddr, err := getpeername(s.fd)
+	if err != nil {
+		t.Fatalf("getpeername: %v", err)
+	}
+
+	// Save a connection that can't be reconnected, since its peer address
+	// is truncated.
+	if err := unix.SetsockoptInt(s.fd, unix.SOL_SOCKET, unix.SO_KEEPALIVE, 1); err != nil {
+		t.Fatalf("setsockopt(SO_KEEPALIVE): %v", err)
+	}
+	st.Pause()
+	s.saved.localAddr = localAddr
+	s.saved.peerAddr = bytes.Clone(peerAddr[:2])
+	s.saved.tcp = &tcpRepairState{}
+	s.saved.reset = nil
+	st.removeSocket(s)
+	unix.Close(s.fd)
+
+	newStack := NewStack()
+	s.afterLoad(context.WithValue(context.Background(), CtxRestoreStack, newStack))
+	t.Cleanup(func() {
+		fdnotifier.RemoveFD(int32(s.fd))
+		unix.Close(s.fd)
+	})
+	checkReset(t, newStack, s, localAddr)
+
+	// The fresh socket that replaces the partially restored one has the
+	// saved options.
+	if got, err := unix.GetsockoptInt(s.fd, unix.SOL_SOCKET, unix.SO_KEEPALIVE); err != nil || got != 1 {
+		t.Errorf("getsockopt(SO_KEEPALIVE) = %d, %v, want 1, nil", got, err)
+	}
+	if s.inRepair {
+		t.Errorf("restored socket is left in TCP_REPAIR mode")
+	}
+}
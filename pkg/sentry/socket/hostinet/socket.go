@@ -116,19 +116,33 @@ type Socket struct {
 	protocol int            // Read-only.
 	queue    waiter.Queue
 
+	// stack is the Stack that the socket belongs to.
+	stack *Stack `state:"nosave"`
+
 	// fd is the host socket fd. It must have O_NONBLOCK, so that operations
 	// will return EWOULDBLOCK instead of blocking on the host. This allows us to
 	// handle blocking behavior independently in the sentry.
-	fd int
+	//
+	// The host socket is recreated from saved on restore.
+	fd int `state:"nosave"`
 
 	// recvClosed indicates that the socket has been shutdown for reading
 	// (SHUT_RD or SHUT_RDWR).
 	recvClosed atomicbitops.Bool
+
+	// backlog is the backlog passed to the last successful listen(2).
+	backlog int
+
+	// saved is the state of the host socket recorded by the last save.
+	saved *savedSocket
+
+	// inRepair indicates that the host socket is in TCP_REPAIR mode.
+	inRepair bool `state:"nosave"`
 }
 
 var _ = socket.Socket(&Socket{})
 
-func newSocket(t *kernel.Task, family int, stype linux.SockType, protocol int, fd int, flags uint32) (*vfs.FileDescription, *syserr.Error) {
+func newSocket(t *kernel.Task, stack *Stack, family int, stype linux.SockType, protocol int, fd int, flags uint32) (*vfs.FileDescription, *syserr.Error) {
 	mnt := t.Kernel().SocketMount()
 	d := sockfs.NewDentry(t, mnt)
 	defer d.DecRef(t)
@@ -137,6 +151,7 @@ func newSocket(t *kernel.Task, family int, stype linux.SockType, protocol int, f
 		family:   family,
 		stype:    stype,
 		protocol: protocol,
+		stack:    stack,
 		fd:       fd,
 	}
 	s.LockFD.Init(&vfs.FileLocks{})
@@ -152,12 +167,14 @@ func newSocket(t *kernel.Task, family int, stype linux.SockType, protocol int, f
 		fdnotifier.RemoveFD(int32(s.fd))
 		return nil, syserr.FromError(err)
 	}
+	stack.addSocket(s)
 	return vfsfd, nil
 }
 
 // Release implements vfs.FileDescriptionImpl.Release.
 func (s *Socket) Release(ctx context.Context) {
 	kernel.KernelFromContext(ctx).DeleteSocket(&s.vfsfd)
+	s.stack.removeSocket(s)
 	fdnotifier.RemoveFD(int32(s.fd))
 	_ = unix.Close(s.fd)
 }
@@ -275,7 +292,7 @@ func (p *socketProvider) Socket(t *kernel.Task, stypeflags linux.SockType, proto
 	if err != nil {
 		return nil, syserr.FromError(err)
 	}
-	return newSocket(t, p.family, stype, protocol, fd, uint32(stypeflags&unix.SOCK_NONBLOCK))
+	return newSocket(t, stack, p.family, stype, protocol, fd, uint32(stypeflags&unix.SOCK_NONBLOCK))
 }
 
 // Pair implements socket.Provider.Pair.
@@ -414,7 +431,7 @@ func (s *Socket) Accept(t *kernel.Task, peerRequested bool, flags int, blocking
 		kfd  int32
 		kerr error
 	)
-	f, err := newSocket(t, s.family, s.stype, s.protocol, fd, uint32(flags&unix.SOCK_NONBLOCK))
+	f, err := newSocket(t, s.stack, s.family, s.stype, s.protocol, fd, uint32(flags&unix.SOCK_NONBLOCK))
 	if err != nil {
 		_ = unix.Close(fd)
 		return 0, nil, 0, err
@@ -444,7 +461,11 @@ func (s *Socket) Bind(_ *kernel.Task, sockaddr []byte) *syserr.Error {
 
 // Listen implements socket.Socket.Listen.
 func (s *Socket) Listen(_ *kernel.Task, backlog int) *syserr.Error {
-	return syserr.FromError(unix.Listen(s.fd, backlog))
+	if err := unix.Listen(s.fd, backlog); err != nil {
+		return syserr.FromError(err)
+	}
+	s.backlog = backlog
+	return nil
 }
 
 // Shutdown implements socket.Socket.Shutdown.
@@ -215,6 +215,45 @@ func getsockopt(fd int, level, name int, opt []byte) ([]byte, error) {
 	return opt[:optlen32], nil
 }
 
+func setsockopt(fd int, level, name int, opt []byte) error {
+	if _, _, errno := unix.Syscall6(unix.SYS_SETSOCKOPT, uintptr(fd), uintptr(level), uintptr(name), uintptr(firstBytePtr(opt)), uintptr(len(opt)), 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func bind(fd int, addr []byte) error {
+	if _, _, errno := unix.Syscall(unix.SYS_BIND, uintptr(fd), uintptr(firstBytePtr(addr)), uintptr(len(addr))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func connect(fd int, addr []byte) error {
+	if _, _, errno := unix.Syscall(unix.SYS_CONNECT, uintptr(fd), uintptr(firstBytePtr(addr)), uintptr(len(addr))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func getsockname(fd int) ([]byte, error) {
+	addr := make([]byte, sizeofSockaddr)
+	addrlen := uint32(len(addr))
+	if _, _, errno := unix.Syscall(unix.SYS_GETSOCKNAME, uintptr(fd), uintptr(unsafe.Pointer(&addr[0])), uintptr(unsafe.Pointer(&addrlen))); errno != 0 {
+		return nil, errno
+	}
+	return addr[:addrlen], nil
+}
+
+func getpeername(fd int) ([]byte, error) {
+	addr := make([]byte, sizeofSockaddr)
+	addrlen := uint32(len(addr))
+	if _, _, errno := unix.Syscall(unix.SYS_GETPEERNAME, uintptr(fd), uintptr(unsafe.Pointer(&addr[0])), uintptr(unsafe.Pointer(&addrlen))); errno != 0 {
+		return nil, errno
+	}
+	return addr[:addrlen], nil
+}
+
 // GetSockName implements socket.Socket.GetSockName.
 func (s *Socket) GetSockName(t *kernel.Task) (linux.SockAddr, uint32, *syserr.Error) {
 	addr := make([]byte, sizeofSockaddr)
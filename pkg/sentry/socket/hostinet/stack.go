@@ -15,6 +15,7 @@
 package hostinet
 
 import (
+	goContext "context"
 	"fmt"
 	"io"
 	"os"
@@ -28,6 +29,7 @@ import (
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
+	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
@@ -47,17 +49,37 @@ var defaultSendBufSize = inet.TCPBufferSize{
 }
 
 // Stack implements inet.Stack for host sockets.
+//
+// On restore, the Stack is replaced by one configured from the host that the
+// sandbox is restored on, so none of its state is saved.
+//
+// +stateify savable
 type Stack struct {
 	// Stack is immutable.
-	supportsIPv6   bool
-	tcpRecovery    inet.TCPLossRecovery
-	tcpRecvBufSize inet.TCPBufferSize
-	tcpSendBufSize inet.TCPBufferSize
-	tcpSACKEnabled bool
-	netDevFile     *os.File
-	netSNMPFile    *os.File
+	supportsIPv6   bool                 `state:"nosave"`
+	tcpRecovery    inet.TCPLossRecovery `state:"nosave"`
+	tcpRecvBufSize inet.TCPBufferSize   `state:"nosave"`
+	tcpSendBufSize inet.TCPBufferSize   `state:"nosave"`
+	tcpSACKEnabled bool                 `state:"nosave"`
+	netDevFile     *os.File             `state:"nosave"`
+	netSNMPFile    *os.File             `state:"nosave"`
 	// allowedSocketTypes is the list of allowed socket types
-	allowedSocketTypes []AllowedSocketType
+	allowedSocketTypes []AllowedSocketType `state:"nosave"`
+	// tcpRepair indicates whether established TCP connections are saved and
+	// restored using TCP_REPAIR.
+	tcpRepair bool `state:"nosave"`
+
+	socketsMu sync.Mutex `state:"nosave"`
+
+	// sockets is the set of live sockets created by this Stack.
+	//
+	// +checklocks:socketsMu
+	sockets map[*Socket]struct{} `state:"nosave"`
+
+	// restoreResets are the connections that couldn't be restored.
+	//
+	// +checklocks:socketsMu
+	restoreResets []stack.RestoreResetEndpoint `state:"nosave"`
 }
 
 // Destroy implements inet.Stack.Destroy.
@@ -70,7 +92,10 @@ func NewStack() *Stack {
 }
 
 // Configure sets up the stack using the current state of the host network.
-func (s *Stack) Configure(allowRawSockets bool) error {
+// If tcpRepair is true, established TCP connections are saved and restored
+// using TCP_REPAIR, which requires CAP_NET_ADMIN in the host network
+// namespace.
+func (s *Stack) Configure(allowRawSockets, tcpRepair bool) error {
 	if _, err := os.Stat("/proc/net/if_inet6"); err == nil {
 		s.supportsIPv6 = true
 	}
@@ -114,6 +139,7 @@ func (s *Stack) Configure(allowRawSockets bool) error {
 	if allowRawSockets {
 		s.allowedSocketTypes = append(s.allowedSocketTypes, AllowedRawSocketTypes...)
 	}
+	s.tcpRepair = tcpRepair
 
 	return nil
 }
@@ -393,7 +419,13 @@ func (*Stack) RemoveRoute(context.Context, *nlmsg.Message) *syserr.Error {
 }
 
 // Pause implements inet.Stack.Pause.
-func (*Stack) Pause() {}
+func (s *Stack) Pause() {
+	s.socketsMu.Lock()
+	defer s.socketsMu.Unlock()
+	for sock := range s.sockets {
+		sock.prepareSave(s.tcpRepair)
+	}
+}
 
 // Restore implements inet.Stack.Restore.
 func (*Stack) Restore() {}
@@ -404,6 +436,18 @@ func (s *Stack) ReplaceConfig(_ inet.Stack) {}
 // Resume implements inet.Stack.Resume.
 func (*Stack) Resume() {}
 
+// BeforeResume implements inet.Stack.BeforeResume.
+func (s *Stack) BeforeResume() {
+	// Sockets are left in TCP_REPAIR mode by Pause, so that they are closed
+	// without notifying the peer if the sandbox exits after save.
+	s.socketsMu.Lock()
+	defer s.socketsMu.Unlock()
+	for sock := range s.sockets {
+		sock.leaveRepair()
+		sock.saved = nil
+	}
+}
+
 // RegisteredEndpoints implements inet.Stack.RegisteredEndpoints.
 func (*Stack) RegisteredEndpoints() []stack.TransportEndpoint { return nil }
 
@@ -413,6 +457,53 @@ func (*Stack) CleanupEndpoints() []stack.TransportEndpoint { return nil }
 // RestoreCleanupEndpoints implements inet.Stack.RestoreCleanupEndpoints.
 func (*Stack) RestoreCleanupEndpoints([]stack.TransportEndpoint) {}
 
+// RestoreResets implements inet.Stack.RestoreResets.
+func (s *Stack) RestoreResets() []stack.RestoreResetEndpoint {
+	s.socketsMu.Lock()
+	defer s.socketsMu.Unlock()
+	return append([]stack.RestoreResetEndpoint(nil), s.restoreResets...)
+}
+
+// addSocket adds sock to the set of live sockets.
+func (s *Stack) addSocket(sock *Socket) {
+	s.socketsMu.Lock()
+	defer s.socketsMu.Unlock()
+	if s.sockets == nil {
+		s.sockets = make(map[*Socket]struct{})
+	}
+	s.sockets[sock] = struct{}{}
+}
+
+// removeSocket removes sock from the set of live sockets.
+func (s *Stack) removeSocket(sock *Socket) {
+	s.socketsMu.Lock()
+	defer s.socketsMu.Unlock()
+	delete(s.sockets, sock)
+}
+
+// recordRestoreReset records that a connection couldn't be restored.
+func (s *Stack) recordRestoreReset(r stack.RestoreResetEndpoint) {
+	s.socketsMu.Lock()
+	defer s.socketsMu.Unlock()
+	s.restoreResets = append(s.restoreResets, r)
+}
+
+// contextID is the hostinet package's type for context.Context.Value keys.
+type contextID int
+
+const (
+	// CtxRestoreStack is a Context.Value key for the Stack that restored
+	// sockets are recreated in.
+	CtxRestoreStack contextID = iota
+)
+
+// RestoreStackFromContext returns the Stack that restored sockets are
+// recreated in.
+func RestoreStackFromContext(ctx goContext.Context) *Stack {
+	s, _ := ctx.Value(CtxRestoreStack).(*Stack)
+	return s
+}
+
 // SetForwarding implements inet.Stack.SetForwarding.
 func (*Stack) SetForwarding(tcpip.NetworkProtocolNumber, bool) error {
 	return linuxerr.EACCES
@@ -18,8 +18,11 @@ import (
 	_ "embed"
 	"testing"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/marshal"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netfilter"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 )
 
@@ -83,3 +86,101 @@ func TestAcceptBlob(t *testing.T) {
 		t.Fatalf("ACCEPT rules shouldn't cause iptables modifications, but did")
 	}
 }
+
+// ipv4Entry returns an IPv4 iptables entry with the given protocol and target.
+func ipv4Entry(protocol uint16, target marshal.Marshallable) []byte {
+	entry := linux.IPTEntry{
+		IP:           linux.IPTIP{Protocol: protocol},
+		TargetOffset: linux.SizeOfIPTEntry,
+		NextOffset:   uint16(linux.SizeOfIPTEntry + target.SizeBytes()),
+	}
+	return append(marshal.Marshal(&entry), marshal.Marshal(target)...)
+}
+
+func acceptEntry() []byte {
+	target := linux.XTStandardTarget{
+		Target:  linux.XTEntryTarget{TargetSize: linux.SizeOfXTStandardTarget},
+		Verdict: -linux.NF_ACCEPT - 1,
+	}
+	return ipv4Entry(0, &target)
+}
+
+func tproxyEntry() []byte {
+	target := linux.XTTProxyTargetV1{
+		Target: linux.XTEntryTarget{
+			TargetSize: linux.SizeOfXTTProxyTargetV1,
+			Revision:   1,
+		},
+		LPort: 0x901f, // Port 8080 in network byte order.
+	}
+	copy(target.Target.Name[:], netfilter.TProxyTargetName)
+	return ipv4Entry(uint16(header.TCPProtocolNumber), &target)
+}
+
+// tproxyBlob returns a ruleset for table in which every built-in chain
+// accepts all packets, and hook's chain starts with a TPROXY rule.
+func tproxyBlob(table string, hook int) []byte {
+	var replace linux.IPTReplace
+	copy(replace.Name[:], table)
+	var entries []byte
+	for h := 0; h < linux.NF_INET_NUMHOOKS; h++ {
+		replace.HookEntry[h] = uint32(len(entries))
+		if h == hook {
+			entries = append(entries, tproxyEntry()...)
+			replace.NumEntries++
+		}
+		replace.Underflow[h] = uint32(len(entries))
+		entries = append(entries, acceptEntry()...)
+		replace.NumEntries++
+		replace.ValidHooks |= 1 << h
+	}
+	errTarget := linux.XTErrorTarget{
+		Target: linux.XTEntryTarget{TargetSize: linux.SizeOfXTErrorTarget},
+	}
+	copy(errTarget.Target.Name[:], netfilter.ErrorTargetName)
+	copy(errTarget.Name[:], netfilter.ErrorTargetName)
+	entries = append(entries, ipv4Entry(0, &errTarget)...)
+	replace.NumEntries++
+	replace.Size = uint32(len(entries))
+	return append(marshal.Marshal(&replace), entries...)
+}
+
+// TestTProxyRules tests that TPROXY rules are only accepted in the PREROUTING
+// chain of the mangle table.
+func TestTProxyRules(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		table   string
+		hook    int
+		wantErr bool
+	}{
+		{
+			name:  "mangle PREROUTING",
+			table: "mangle",
+			hook:  linux.NF_INET_PRE_ROUTING,
+		},
+		{
+			name:    "mangle OUTPUT",
+			table:   "mangle",
+			hook:    linux.NF_INET_LOCAL_OUT,
+			wantErr: true,
+		},
+		{
+			name:    "nat PREROUTING",
+			table:   "nat",
+			hook:    linux.NF_INET_PRE_ROUTING,
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mapper := FakeIDMapper{}
+			stk := stack.New(stack.Options{
+				DefaultIPTables: netfilter.DefaultLinuxTables,
+			})
+			err := netfilter.SetEntries(&mapper, stk, tproxyBlob(test.table, test.hook), false)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("got SetEntries(...) = %v, want error = %t", err, test.wantErr)
+			}
+		})
+	}
+}
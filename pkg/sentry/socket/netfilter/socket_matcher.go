@@ -0,0 +1,187 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netfilter
+
+import (
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/marshal"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+const matcherNameSocket = "socket"
+
+func init() {
+	registerMatchMaker(socketMarshaler{rev: 0})
+	registerMatchMaker(socketMarshaler{rev: 1})
+	registerMatchMaker(socketMarshaler{rev: 2})
+}
+
+// socketMarshaler implements matchMaker for socket matching.
+type socketMarshaler struct {
+	rev uint8
+}
+
+// name implements matchMaker.name.
+func (socketMarshaler) name() string {
+	return matcherNameSocket
+}
+
+// revision implements matchMaker.revision.
+func (sm socketMarshaler) revision() uint8 {
+	return sm.rev
+}
+
+// validFlags returns the flags supported by the revision.
+func (sm socketMarshaler) validFlags() uint8 {
+	switch sm.rev {
+	case 0:
+		return 0
+	case 1:
+		return linux.XT_SOCKET_TRANSPARENT
+	default:
+		return linux.XT_SOCKET_TRANSPARENT | linux.XT_SOCKET_NOWILDCARD
+	}
+}
+
+// marshal implements matchMaker.marshal.
+func (sm socketMarshaler) marshal(mr matcher) []byte {
+	matcher := mr.(*SocketMatcher)
+	if sm.rev == 0 {
+		return marshalEntryMatch(matcherNameSocket, nil)
+	}
+	var info linux.XTSocketMatchInfo
+	if matcher.transparent {
+		info.Flags |= linux.XT_SOCKET_TRANSPARENT
+	}
+	if matcher.noWildcard {
+		info.Flags |= linux.XT_SOCKET_NOWILDCARD
+	}
+	return marshalEntryMatch(matcherNameSocket, marshal.Marshal(&info))
+}
+
+// unmarshal implements matchMaker.unmarshal.
+func (sm socketMarshaler) unmarshal(_ IDMapper, stk *stack.Stack, buf []byte, filter stack.IPHeaderFilter) (stack.Matcher, error) {
+	matcher := SocketMatcher{
+		stack: stk,
+		rev:   sm.rev,
+	}
+	if sm.rev == 0 {
+		return &matcher, nil
+	}
+
+	if len(buf) < linux.SizeOfXTSocketMatchInfo {
+		return nil, fmt.Errorf("buf has insufficient size for socket match: %d", len(buf))
+	}
+	var info linux.XTSocketMatchInfo
+	info.UnmarshalUnsafe(buf)
+	nflog("parsed XTSocketMatchInfo: %+v", info)
+	if info.Flags&^sm.validFlags() != 0 {
+		return nil, fmt.Errorf("unsupported socket match flags %#x for revision %d", info.Flags, sm.rev)
+	}
+	matcher.transparent = info.Flags&linux.XT_SOCKET_TRANSPARENT != 0
+	matcher.noWildcard = info.Flags&linux.XT_SOCKET_NOWILDCARD != 0
+	return &matcher, nil
+}
+
+// SocketMatcher matches incoming packets that belong to a local socket:
+// either one connected to the packet's source and destination, or one
+// listening on its destination.
+type SocketMatcher struct {
+	stack *stack.Stack
+	rev   uint8
+
+	// transparent restricts matches to sockets with IP_TRANSPARENT set.
+	transparent bool
+
+	// noWildcard prevents matching sockets bound to a wildcard address.
+	noWildcard bool
+}
+
+// name implements matcher.name.
+func (*SocketMatcher) name() string {
+	return matcherNameSocket
+}
+
+// revision implements matcher.revision.
+func (sm *SocketMatcher) revision() uint8 {
+	return sm.rev
+}
+
+// Match implements Matcher.Match.
+func (sm *SocketMatcher) Match(hook stack.Hook, pkt *stack.PacketBuffer, _, _ string) (bool, bool) {
+	// Like Linux, only incoming packets are matched.
+	if hook != stack.Prerouting && hook != stack.Input {
+		return false, false
+	}
+
+	var srcPort, dstPort uint16
+	switch pkt.TransportProtocolNumber {
+	case header.TCPProtocolNumber:
+		tcpHeader := header.TCP(pkt.TransportHeader().Slice())
+		if len(tcpHeader) < header.TCPMinimumSize {
+			return false, true
+		}
+		srcPort, dstPort = tcpHeader.SourcePort(), tcpHeader.DestinationPort()
+	case header.UDPProtocolNumber:
+		udpHeader := header.UDP(pkt.TransportHeader().Slice())
+		if len(udpHeader) < header.UDPMinimumSize {
+			return false, true
+		}
+		srcPort, dstPort = udpHeader.SourcePort(), udpHeader.DestinationPort()
+	default:
+		return false, false
+	}
+
+	netHeader := pkt.Network()
+	id := stack.TransportEndpointID{
+		LocalPort:     dstPort,
+		LocalAddress:  netHeader.DestinationAddress(),
+		RemotePort:    srcPort,
+		RemoteAddress: netHeader.SourceAddress(),
+	}
+	ep := sm.stack.FindTransportEndpoint(pkt.NetworkProtocolNumber, pkt.TransportProtocolNumber, id, pkt.NICID)
+	if ep == nil {
+		return false, false
+	}
+	if sm.transparent && !socketOptions(ep).GetIPTransparent() {
+		return false, false
+	}
+	if sm.noWildcard && boundToWildcard(ep) {
+		return false, false
+	}
+	return true, false
+}
+
+// socketOptions returns the socket options of ep.
+func socketOptions(ep stack.TransportEndpoint) *tcpip.SocketOptions {
+	if e, ok := ep.(interface{ SocketOptions() *tcpip.SocketOptions }); ok {
+		return e.SocketOptions()
+	}
+	return &tcpip.SocketOptions{}
+}
+
+// boundToWildcard returns whether ep is bound to the unspecified address.
+func boundToWildcard(ep stack.TransportEndpoint) bool {
+	e, ok := ep.(interface{ Info() tcpip.EndpointInfo })
+	if !ok {
+		return false
+	}
+	info, ok := e.Info().(*stack.TransportEndpointInfo)
+	return ok && info.ID.LocalAddress.BitLen() == 0
+}
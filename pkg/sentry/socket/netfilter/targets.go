@@ -95,6 +95,17 @@ func init() {
 	registerTargetMaker(&dnatTargetMakerR2{
 		NetworkProtocol: header.IPv6ProtocolNumber,
 	})
+
+	// TPROXY targets. Revision 0 only supports IPv4.
+	registerTargetMaker(&tproxyTargetMakerV0{
+		NetworkProtocol: header.IPv4ProtocolNumber,
+	})
+	registerTargetMaker(&tproxyTargetMakerR1{
+		NetworkProtocol: header.IPv4ProtocolNumber,
+	})
+	registerTargetMaker(&tproxyTargetMakerR1{
+		NetworkProtocol: header.IPv6ProtocolNumber,
+	})
 }
 
 // The stack package provides some basic, useful targets for us. The following
@@ -0,0 +1,223 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netfilter
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/marshal"
+	"gvisor.dev/gvisor/pkg/syserr"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// TProxyTargetName is used to mark targets as TPROXY targets. TPROXY targets
+// should be reached for only the PREROUTING chain of the mangle table. These
+// targets divert packets to a local socket without changing them.
+const TProxyTargetName = "TPROXY"
+
+type tproxyTarget struct {
+	stack.TProxyTarget
+	revision uint8
+}
+
+func (tt *tproxyTarget) id() targetID {
+	return targetID{
+		name:            TProxyTargetName,
+		networkProtocol: tt.NetworkProtocol,
+		revision:        tt.revision,
+	}
+}
+
+type tproxyTargetMakerV0 struct {
+	NetworkProtocol tcpip.NetworkProtocolNumber
+}
+
+func (tt *tproxyTargetMakerV0) id() targetID {
+	return targetID{
+		name:            TProxyTargetName,
+		networkProtocol: tt.NetworkProtocol,
+	}
+}
+
+func (*tproxyTargetMakerV0) marshal(target target) []byte {
+	tt := target.(*tproxyTarget)
+	xt := linux.XTTProxyTargetV0{
+		Target: linux.XTEntryTarget{
+			TargetSize: linux.SizeOfXTTProxyTargetV0,
+		},
+		MarkMask:  tt.MarkMask,
+		MarkValue: tt.MarkValue,
+		LPort:     htons(tt.Port),
+	}
+	copy(xt.Target.Name[:], TProxyTargetName)
+	copy(xt.LAddr[:], tt.Addr.AsSlice())
+	return marshal.Marshal(&xt)
+}
+
+func (*tproxyTargetMakerV0) unmarshal(buf []byte, filter stack.IPHeaderFilter) (target, *syserr.Error) {
+	if len(buf) < linux.SizeOfXTTProxyTargetV0 {
+		nflog("tproxyTargetMakerV0: buf has insufficient size for tproxy target %d", len(buf))
+		return nil, syserr.ErrInvalidArgument
+	}
+
+	if p := filter.Protocol; p != header.TCPProtocolNumber && p != header.UDPProtocolNumber {
+		nflog("tproxyTargetMakerV0: bad proto %d", p)
+		return nil, syserr.ErrInvalidArgument
+	}
+
+	var xt linux.XTTProxyTargetV0
+	xt.UnmarshalUnsafe(buf)
+
+	target := tproxyTarget{TProxyTarget: stack.TProxyTarget{
+		Port:            ntohs(xt.LPort),
+		MarkMask:        xt.MarkMask,
+		MarkValue:       xt.MarkValue,
+		NetworkProtocol: filter.NetworkProtocol(),
+	}}
+	if xt.LAddr != (linux.InetAddr{}) {
+		target.Addr = tcpip.AddrFrom4(xt.LAddr)
+	}
+	return &target, nil
+}
+
+type tproxyTargetMakerR1 struct {
+	NetworkProtocol tcpip.NetworkProtocolNumber
+}
+
+func (tt *tproxyTargetMakerR1) id() targetID {
+	return targetID{
+		name:            TProxyTargetName,
+		networkProtocol: tt.NetworkProtocol,
+		revision:        1,
+	}
+}
+
+func (*tproxyTargetMakerR1) marshal(target target) []byte {
+	tt := target.(*tproxyTarget)
+	xt := linux.XTTProxyTargetV1{
+		Target: linux.XTEntryTarget{
+			TargetSize: linux.SizeOfXTTProxyTargetV1,
+			Revision:   1,
+		},
+		MarkMask:  tt.MarkMask,
+		MarkValue: tt.MarkValue,
+		LPort:     htons(tt.Port),
+	}
+	copy(xt.Target.Name[:], TProxyTargetName)
+	copy(xt.LAddr[:], tt.Addr.AsSlice())
+	return marshal.Marshal(&xt)
+}
+
+func (tt *tproxyTargetMakerR1) unmarshal(buf []byte, filter stack.IPHeaderFilter) (target, *syserr.Error) {
+	if size := linux.SizeOfXTTProxyTargetV1; len(buf) < size {
+		nflog("tproxyTargetMakerR1: buf has insufficient size (%d) for TPROXY target (%d)", len(buf), size)
+		return nil, syserr.ErrInvalidArgument
+	}
+
+	if p := filter.Protocol; p != header.TCPProtocolNumber && p != header.UDPProtocolNumber {
+		nflog("tproxyTargetMakerR1: bad proto %d", p)
+		return nil, syserr.ErrInvalidArgument
+	}
+
+	var xt linux.XTTProxyTargetV1
+	xt.UnmarshalUnsafe(buf)
+
+	target := tproxyTarget{
+		TProxyTarget: stack.TProxyTarget{
+			Port:            ntohs(xt.LPort),
+			MarkMask:        xt.MarkMask,
+			MarkValue:       xt.MarkValue,
+			NetworkProtocol: filter.NetworkProtocol(),
+		},
+		revision: 1,
+	}
+	if xt.LAddr != ([16]byte{}) {
+		switch tt.NetworkProtocol {
+		case header.IPv4ProtocolNumber:
+			target.Addr = tcpip.AddrFrom4Slice(xt.LAddr[:4])
+		case header.IPv6ProtocolNumber:
+			target.Addr = tcpip.AddrFrom16(xt.LAddr)
+		}
+	}
+	return &target, nil
+}
+
+// validateTProxyTargets checks that TPROXY targets in table can only be
+// reached from the PREROUTING chain of the mangle table, as Linux requires.
+func validateTProxyTargets(tableName string, table *stack.Table) *syserr.Error {
+	hasTProxy := false
+	for _, rule := range table.Rules {
+		if _, ok := rule.Target.(*tproxyTarget); ok {
+			hasTProxy = true
+			break
+		}
+	}
+	if !hasTProxy {
+		return nil
+	}
+	if tableName != mangleTable {
+		nflog("TPROXY target is only valid in the mangle table, not %q", tableName)
+		return syserr.ErrInvalidArgument
+	}
+
+	for hook, start := range table.BuiltinChains {
+		if stack.Hook(hook) == stack.Prerouting || start == stack.HookUnset {
+			continue
+		}
+		for ruleIdx := range reachableRules(table, start, table.Underflows[hook]) {
+			if _, ok := table.Rules[ruleIdx].Target.(*tproxyTarget); ok {
+				nflog("TPROXY target in rule %d is reachable from hook %v", ruleIdx, stack.Hook(hook))
+				return syserr.ErrInvalidArgument
+			}
+		}
+	}
+	return nil
+}
+
+// reachableRules returns the indices of the rules that may be traversed by a
+// packet entering table at rule start, including rules in user chains jumped
+// to. It is conservative: every rule up to the end of a chain is considered
+// reachable.
+func reachableRules(table *stack.Table, start, underflow int) map[int]struct{} {
+	reachable := make(map[int]struct{})
+	chains := []int{start}
+	for len(chains) > 0 {
+		ruleIdx := chains[len(chains)-1]
+		chains = chains[:len(chains)-1]
+		first := ruleIdx
+	chain:
+		for ; ruleIdx < len(table.Rules); ruleIdx++ {
+			if _, ok := reachable[ruleIdx]; ok {
+				break
+			}
+			switch target := table.Rules[ruleIdx].Target.(type) {
+			case *JumpTarget:
+				chains = append(chains, target.RuleNum)
+			case *errorTarget, *userChainTarget:
+				// The end of the table or the start of the next chain,
+				// unless the jump landed on the chain's head.
+				if ruleIdx != first {
+					break chain
+				}
+			}
+			reachable[ruleIdx] = struct{}{}
+			if ruleIdx == underflow {
+				break
+			}
+		}
+	}
+	return reachable
+}
@@ -932,6 +932,9 @@ func GetSockOpt(t *kernel.Task, s socket.Socket, ep commonEndpoint, family int,
 	case linux.SOL_TCP:
 		return getSockOptTCP(t, s, ep, name, outLen)
 
+	case linux.SOL_SCTP:
+		return getSockOptSCTP(t, s, ep, name, outPtr, outLen)
+
 	case linux.SOL_IPV6:
 		return getSockOptIPv6(t, s, ep, name, outPtr, outLen)
 
@@ -1373,6 +1376,135 @@ func getSockOptTCP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name, out
 	return nil, syserr.ErrProtocolNotAvailable
 }
 
+// getSockOptSCTP implements GetSockOpt when level is SOL_SCTP.
+func getSockOptSCTP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int, outPtr hostarch.Addr, outLen int) (marshal.Marshallable, *syserr.Error) {
+	if !socket.IsSCTP(s) {
+		return nil, syserr.ErrUnknownProtocolOption
+	}
+
+	switch name {
+	case linux.SCTP_NODELAY:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v := primitive.Int32(boolToInt32(!ep.SocketOptions().GetDelayOption()))
+		return &v, nil
+
+	case linux.SCTP_MAXSEG:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v, err := ep.GetSockOptInt(tcpip.MaxSegOption)
+		if err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+		if outLen >= linux.SizeOfSCTPAssocValue {
+			return &linux.SCTPAssocValue{Value: uint32(v)}, nil
+		}
+		vP := primitive.Int32(v)
+		return &vP, nil
+
+	case linux.SCTP_RTOINFO:
+		if outLen < linux.SizeOfSCTPRTOInfo {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		var info linux.SCTPRTOInfo
+		if _, err := info.CopyIn(t, outPtr); err != nil {
+			return nil, syserr.FromError(err)
+		}
+		v := tcpip.SCTPRTOInfoOption{AssocID: info.AssocID}
+		if err := ep.GetSockOpt(&v); err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+		info.Initial = uint32(v.Initial / time.Millisecond)
+		info.Max = uint32(v.Max / time.Millisecond)
+		info.Min = uint32(v.Min / time.Millisecond)
+		return &info, nil
+
+	case linux.SCTP_INITMSG:
+		if outLen < linux.SizeOfSCTPInitMsg {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		var v tcpip.SCTPInitMsgOption
+		if err := ep.GetSockOpt(&v); err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+		return &linux.SCTPInitMsg{
+			NumOStreams:  v.NumOStreams,
+			MaxInStreams: v.MaxInStreams,
+			MaxAttempts:  v.MaxAttempts,
+			MaxInitTimeo: uint16(v.MaxInitTimeout / time.Millisecond),
+		}, nil
+
+	case linux.SCTP_DEFAULT_SNDINFO:
+		if outLen < linux.SizeOfSCTPSndInfo {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		var info linux.SCTPSndInfo
+		if _, err := info.CopyIn(t, outPtr); err != nil {
+			return nil, syserr.FromError(err)
+		}
+		v := tcpip.SCTPDefaultSendInfoOption{AssocID: info.AssocID}
+		if err := ep.GetSockOpt(&v); err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+		info.SID = v.Stream
+		info.Flags = 0
+		if v.Unordered {
+			info.Flags = linux.SCTP_UNORDERED
+		}
+		info.PPID = v.PPID
+		info.Context = v.Context
+		return &info, nil
+
+	case linux.SCTP_STATUS:
+		if outLen < linux.SizeOfSCTPStatus {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		var status linux.SCTPStatus
+		if _, err := status.CopyIn(t, outPtr); err != nil {
+			return nil, syserr.FromError(err)
+		}
+		v := tcpip.SCTPStatusOption{AssocID: status.AssocID}
+		if err := ep.GetSockOpt(&v); err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+		family, _, _ := s.Type()
+		addr, addrLen := socket.ConvertAddress(family, v.Primary.Addr)
+		pathState := int32(linux.SCTP_INACTIVE)
+		if v.Primary.Active {
+			pathState = linux.SCTP_ACTIVE
+		}
+		status = linux.SCTPStatus{
+			AssocID:            v.AssocID,
+			State:              int32(v.State),
+			RWnd:               v.RWnd,
+			UnackData:          v.UnackedData,
+			PendData:           v.PendingData,
+			InStrms:            v.InStreams,
+			OutStrms:           v.OutStreams,
+			FragmentationPoint: v.FragmentationPoint,
+			Primary: linux.SCTPPAddrInfo{
+				AssocID: v.AssocID,
+				State:   pathState,
+				CWnd:    v.Primary.CWnd,
+				SRTT:    uint32(v.Primary.SRTT / time.Millisecond),
+				RTO:     uint32(v.Primary.RTO / time.Millisecond),
+				MTU:     v.Primary.MTU,
+			},
+		}
+		addr.MarshalBytes(status.Primary.Address[:addrLen])
+		return &status, nil
+	}
+	return nil, syserr.ErrProtocolNotAvailable
+}
+
 func getSockOptICMPv6(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int, outLen int) (marshal.Marshallable, *syserr.Error) {
 	if _, ok := ep.(tcpip.Endpoint); !ok {
 		log.Warningf("SOL_ICMPV6 options not supported on endpoints other than tcpip.Endpoint: option = %d", name)
@@ -1524,6 +1656,14 @@ func getSockOptIPv6(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int
 		v := primitive.Int32(boolToInt32(ep.SocketOptions().GetReceiveOriginalDstAddress()))
 		return &v, nil
 
+	case linux.IPV6_TRANSPARENT:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v := primitive.Int32(boolToInt32(ep.SocketOptions().GetIPTransparent()))
+		return &v, nil
+
 	case linux.IPV6_RECVPKTINFO:
 		if outLen < sizeOfInt32 {
 			return nil, syserr.ErrInvalidArgument
@@ -1760,6 +1900,14 @@ func getSockOptIP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int,
 		v := primitive.Int32(boolToInt32(ep.SocketOptions().GetReceiveOriginalDstAddress()))
 		return &v, nil
 
+	case linux.IP_TRANSPARENT:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v := primitive.Int32(boolToInt32(ep.SocketOptions().GetIPTransparent()))
+		return &v, nil
+
 	case linux.SO_ORIGINAL_DST:
 		if outLen < linux.SockAddrInetSize {
 			return nil, syserr.ErrInvalidArgument
@@ -1927,6 +2075,9 @@ func SetSockOpt(t *kernel.Task, s socket.Socket, ep commonEndpoint, level int, n
 	case linux.SOL_TCP:
 		return setSockOptTCP(t, s, ep, name, optVal)
 
+	case linux.SOL_SCTP:
+		return setSockOptSCTP(t, s, ep, name, optVal)
+
 	case linux.SOL_ICMPV6:
 		return setSockOptICMPv6(t, s, ep, name, optVal)
 
@@ -2392,6 +2543,89 @@ func setSockOptTCP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int,
 	return nil
 }
 
+// setSockOptSCTP implements SetSockOpt when level is SOL_SCTP.
+func setSockOptSCTP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int, optVal []byte) *syserr.Error {
+	if !socket.IsSCTP(s) {
+		return syserr.ErrUnknownProtocolOption
+	}
+
+	switch name {
+	case linux.SCTP_NODELAY:
+		if len(optVal) < sizeOfInt32 {
+			return syserr.ErrInvalidArgument
+		}
+
+		v := hostarch.ByteOrder.Uint32(optVal)
+		ep.SocketOptions().SetDelayOption(v == 0)
+		return nil
+
+	case linux.SCTP_MAXSEG:
+		var v uint32
+		switch {
+		case len(optVal) >= linux.SizeOfSCTPAssocValue:
+			var av linux.SCTPAssocValue
+			av.UnmarshalUnsafe(optVal)
+			v = av.Value
+		case len(optVal) >= sizeOfInt32:
+			v = hostarch.ByteOrder.Uint32(optVal)
+		default:
+			return syserr.ErrInvalidArgument
+		}
+		return syserr.TranslateNetstackError(ep.SetSockOptInt(tcpip.MaxSegOption, int(v)))
+
+	case linux.SCTP_RTOINFO:
+		if len(optVal) < linux.SizeOfSCTPRTOInfo {
+			return syserr.ErrInvalidArgument
+		}
+
+		var info linux.SCTPRTOInfo
+		info.UnmarshalUnsafe(optVal)
+		v := tcpip.SCTPRTOInfoOption{
+			AssocID: info.AssocID,
+			Initial: time.Duration(info.Initial) * time.Millisecond,
+			Max:     time.Duration(info.Max) * time.Millisecond,
+			Min:     time.Duration(info.Min) * time.Millisecond,
+		}
+		return syserr.TranslateNetstackError(ep.SetSockOpt(&v))
+
+	case linux.SCTP_INITMSG:
+		if len(optVal) < linux.SizeOfSCTPInitMsg {
+			return syserr.ErrInvalidArgument
+		}
+
+		var msg linux.SCTPInitMsg
+		msg.UnmarshalUnsafe(optVal)
+		v := tcpip.SCTPInitMsgOption{
+			NumOStreams:    msg.NumOStreams,
+			MaxInStreams:   msg.MaxInStreams,
+			MaxAttempts:    msg.MaxAttempts,
+			MaxInitTimeout: time.Duration(msg.MaxInitTimeo) * time.Millisecond,
+		}
+		return syserr.TranslateNetstackError(ep.SetSockOpt(&v))
+
+	case linux.SCTP_DEFAULT_SNDINFO:
+		if len(optVal) < linux.SizeOfSCTPSndInfo {
+			return syserr.ErrInvalidArgument
+		}
+
+		var info linux.SCTPSndInfo
+		info.UnmarshalUnsafe(optVal)
+		v := tcpip.SCTPDefaultSendInfoOption{
+			AssocID:   info.AssocID,
+			Stream:    info.SID,
+			Unordered: info.Flags&linux.SCTP_UNORDERED != 0,
+			PPID:      info.PPID,
+			Context:   info.Context,
+		}
+		return syserr.TranslateNetstackError(ep.SetSockOpt(&v))
+
+	case linux.SCTP_STATUS:
+		// Read-only.
+		return syserr.ErrInvalidArgument
+	}
+	return syserr.ErrProtocolNotAvailable
+}
+
 func setSockOptICMPv6(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int, optVal []byte) *syserr.Error {
 	if _, ok := ep.(tcpip.Endpoint); !ok {
 		log.Warningf("SOL_ICMPV6 options not supported on endpoints other than tcpip.Endpoint: option = %d", name)
@@ -2503,6 +2737,14 @@ func setSockOptIPv6(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int
 		ep.SocketOptions().SetReceiveOriginalDstAddress(v != 0)
 		return nil
 
+	case linux.IPV6_TRANSPARENT:
+		if len(optVal) < sizeOfInt32 {
+			return syserr.ErrInvalidArgument
+		}
+		v := int32(hostarch.ByteOrder.Uint32(optVal))
+
+		return setIPTransparent(t, ep, v != 0)
+
 	case linux.IPV6_RECVPKTINFO:
 		if len(optVal) < sizeOfInt32 {
 			return syserr.ErrInvalidArgument
@@ -2608,7 +2850,6 @@ func setSockOptIPv6(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int
 		linux.IPV6_2292DSTOPTS,
 		linux.IPV6_FLOWINFO,
 		linux.IPV6_RECVPATHMTU,
-		linux.IPV6_TRANSPARENT,
 		linux.IPV6_FREEBIND,
 		linux.IPV6_HOPOPTS,
 		linux.IPV6_RTHDRDSTOPTS,
@@ -2640,6 +2881,16 @@ var (
 	inet6MulticastRequestSize       = (*linux.Inet6MulticastRequest)(nil).SizeBytes()
 )
 
+// setIPTransparent implements IP_TRANSPARENT and IPV6_TRANSPARENT. As in
+// Linux, enabling them requires CAP_NET_RAW or CAP_NET_ADMIN.
+func setIPTransparent(t *kernel.Task, ep commonEndpoint, v bool) *syserr.Error {
+	if creds := auth.CredentialsFromContext(t); v && !creds.HasCapability(linux.CAP_NET_RAW) && !creds.HasCapability(linux.CAP_NET_ADMIN) {
+		return syserr.ErrNotPermitted
+	}
+	ep.SocketOptions().SetIPTransparent(v)
+	return nil
+}
+
 // copyInMulticastRequest copies in a variable-size multicast request. The
 // kernel determines which structure was passed by its length. IP_MULTICAST_IF
 // supports ip_mreqn, ip_mreq and in_addr, while IP_ADD_MEMBERSHIP and
@@ -2856,6 +3107,17 @@ func setSockOptIP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int,
 		ep.SocketOptions().SetReceiveOriginalDstAddress(v != 0)
 		return nil
 
+	case linux.IP_TRANSPARENT:
+		if len(optVal) == 0 {
+			return nil
+		}
+		v, err := parseIntOrChar(optVal)
+		if err != nil {
+			return err
+		}
+
+		return setIPTransparent(t, ep, v != 0)
+
 	case linux.IPT_SO_SET_REPLACE:
 		if len(optVal) < linux.SizeOfIPTReplace {
 			return syserr.ErrInvalidArgument
@@ -2901,7 +3163,6 @@ func setSockOptIP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int,
 		linux.IP_ROUTER_ALERT,
 		linux.IP_FREEBIND,
 		linux.IP_PASSSEC,
-		linux.IP_TRANSPARENT,
 		linux.IP_MINTTL,
 		linux.IP_NODEFRAG,
 		linux.IP_BIND_ADDRESS_NO_PORT,
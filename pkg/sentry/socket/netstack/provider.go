@@ -30,6 +30,7 @@ import (
 	"gvisor.dev/gvisor/pkg/tcpip/header"
 	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
 	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/sctp"
 	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
 	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
 	"gvisor.dev/gvisor/pkg/waiter"
@@ -44,16 +45,26 @@ type provider struct {
 var rawMissingLogger = log.BasicRateLimitedLogger(time.Minute)
 
 // getTransportProtocol figures out transport protocol. Currently only TCP,
-// UDP, and ICMP are supported. The bool return value is true when this socket
+// UDP, SCTP, and ICMP are supported. The bool return value is true when this socket
 // is associated with a transport protocol. This is only false for SOCK_RAW,
 // IPPROTO_IP sockets.
 func getTransportProtocol(ctx context.Context, stype linux.SockType, protocol int) (tcpip.TransportProtocolNumber, bool, *syserr.Error) {
 	switch stype {
 	case linux.SOCK_STREAM:
-		if protocol != 0 && protocol != unix.IPPROTO_TCP {
-			return 0, true, syserr.ErrInvalidArgument
+		switch protocol {
+		case 0, unix.IPPROTO_TCP:
+			return tcp.ProtocolNumber, true, nil
+		case unix.IPPROTO_SCTP:
+			return sctp.ProtocolNumber, true, nil
+		}
+		return 0, true, syserr.ErrInvalidArgument
+
+	case linux.SOCK_SEQPACKET:
+		// Only SCTP provides reliable message-oriented sockets; these are
+		// one-to-many style sockets.
+		if protocol == unix.IPPROTO_SCTP {
+			return sctp.ProtocolNumber, true, nil
 		}
-		return tcp.ProtocolNumber, true, nil
 
 	case linux.SOCK_DGRAM:
 		switch protocol {
@@ -118,6 +129,11 @@ func (p *provider) Socket(t *kernel.Task, stype linux.SockType, protocol int) (*
 	if err != nil {
 		return nil, err
 	}
+	// SCTP is optional in the stack. Without it, fail like Linux does when
+	// the sctp module isn't available.
+	if transProto == sctp.ProtocolNumber && eps.Stack.TransportProtocolInstance(sctp.ProtocolNumber) == nil {
+		return nil, syserr.ErrProtocolNotSupported
+	}
 
 	// Create the endpoint.
 	var ep tcpip.Endpoint
@@ -132,6 +148,9 @@ func (p *provider) Socket(t *kernel.Task, stype linux.SockType, protocol int) (*
 		// iptables owner matching.
 		if e == nil {
 			ep.SetOwner(t)
+			if stype == linux.SOCK_SEQPACKET {
+				ep.(*sctp.Endpoint).SetOneToMany()
+			}
 		}
 	}
 	if e != nil {
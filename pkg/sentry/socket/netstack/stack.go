@@ -15,6 +15,7 @@
 package netstack
 
 import (
+	"encoding/binary"
 	"fmt"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
@@ -29,6 +30,7 @@ import (
 	"gvisor.dev/gvisor/pkg/tcpip/header"
 	"gvisor.dev/gvisor/pkg/tcpip/link/ethernet"
 	"gvisor.dev/gvisor/pkg/tcpip/link/packetsocket"
+	"gvisor.dev/gvisor/pkg/tcpip/link/tunnel"
 	"gvisor.dev/gvisor/pkg/tcpip/link/veth"
 	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
 	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
@@ -81,6 +83,14 @@ func toLinuxARPHardwareType(t header.ARPHardwareType) uint16 {
 		return linux.ARPHRD_LOOPBACK
 	case header.ARPHardwareEther:
 		return linux.ARPHRD_ETHER
+	case header.ARPHardwareIPGRE:
+		return linux.ARPHRD_IPGRE
+	case header.ARPHardwareTunnel:
+		return linux.ARPHRD_TUNNEL
+	case header.ARPHardwareSIT:
+		return linux.ARPHRD_SIT
+	case header.ARPHardwareTunnel6:
+		return linux.ARPHRD_TUNNEL6
 	default:
 		panic(fmt.Sprintf("unknown ARPHRD type: %d", t))
 	}
@@ -357,6 +367,200 @@ func (s *Stack) newBridge(ctx context.Context, linkAttrs map[uint16]nlmsg.BytesV
 	return nil
 }
 
+// parseTunnelAddress parses an IPv4 or IPv6 address from a tunnel attribute.
+func parseTunnelAddress(v nlmsg.BytesView) (tcpip.Address, bool) {
+	switch len(v) {
+	case header.IPv4AddressSize:
+		return tcpip.AddrFrom4Slice(v), true
+	case header.IPv6AddressSize:
+		return tcpip.AddrFrom16Slice(v), true
+	}
+	return tcpip.Address{}, false
+}
+
+// parseUint8 parses a single byte attribute.
+func parseUint8(v nlmsg.BytesView) (uint8, bool) {
+	if len(v) != 1 {
+		return 0, false
+	}
+	return v[0], true
+}
+
+// parseGREAttrs parses IFLA_GRE_* attributes into cfg.
+func parseGREAttrs(ctx context.Context, cfg *tunnel.Config, attrs map[uint16]nlmsg.BytesView) *syserr.Error {
+	for t, v := range attrs {
+		var ok bool
+		switch t {
+		case linux.IFLA_GRE_LINK:
+			var link uint32
+			link, ok = v.Uint32()
+			cfg.Link = tcpip.NICID(link)
+		case linux.IFLA_GRE_IFLAGS, linux.IFLA_GRE_OFLAGS:
+			// GRE flags are in network byte order.
+			if ok = len(v) == 2; ok {
+				flags := header.GREFlags(binary.BigEndian.Uint16(v))
+				if t == linux.IFLA_GRE_IFLAGS {
+					cfg.IFlags = flags
+				} else {
+					cfg.OFlags = flags
+				}
+			}
+		case linux.IFLA_GRE_IKEY, linux.IFLA_GRE_OKEY:
+			// GRE keys are in network byte order.
+			if ok = len(v) == 4; ok {
+				key := binary.BigEndian.Uint32(v)
+				if t == linux.IFLA_GRE_IKEY {
+					cfg.IKey = key
+				} else {
+					cfg.OKey = key
+				}
+			}
+		case linux.IFLA_GRE_LOCAL:
+			cfg.Local, ok = parseTunnelAddress(v)
+		case linux.IFLA_GRE_REMOTE:
+			cfg.Remote, ok = parseTunnelAddress(v)
+		case linux.IFLA_GRE_TTL:
+			cfg.TTL, ok = parseUint8(v)
+		case linux.IFLA_GRE_TOS:
+			cfg.TOS, ok = parseUint8(v)
+		case linux.IFLA_GRE_PMTUDISC:
+			var pmtudisc uint8
+			pmtudisc, ok = parseUint8(v)
+			cfg.PMTUDisc = pmtudisc != 0
+		case linux.IFLA_GRE_ENCAP_TYPE, linux.IFLA_GRE_ENCAP_FLAGS, linux.IFLA_GRE_ENCAP_SPORT, linux.IFLA_GRE_ENCAP_DPORT, linux.IFLA_GRE_IGNORE_DF, linux.IFLA_GRE_FWMARK:
+			// iproute2 always sends these. They are only accepted if
+			// they don't request unsupported features.
+			if !isZeroAttr(v) {
+				ctx.Warningf("unsupported GRE attribute: %d", t)
+				return syserr.ErrNotSupported
+			}
+			ok = true
+		default:
+			ctx.Warningf("unsupported GRE attribute: %d", t)
+			return syserr.ErrNotSupported
+		}
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+	}
+	return nil
+}
+
+// parseIPTunAttrs parses IFLA_IPTUN_* attributes into cfg.
+func parseIPTunAttrs(ctx context.Context, cfg *tunnel.Config, attrs map[uint16]nlmsg.BytesView) *syserr.Error {
+	for t, v := range attrs {
+		var ok bool
+		switch t {
+		case linux.IFLA_IPTUN_LINK:
+			var link uint32
+			link, ok = v.Uint32()
+			cfg.Link = tcpip.NICID(link)
+		case linux.IFLA_IPTUN_LOCAL:
+			cfg.Local, ok = parseTunnelAddress(v)
+		case linux.IFLA_IPTUN_REMOTE:
+			cfg.Remote, ok = parseTunnelAddress(v)
+		case linux.IFLA_IPTUN_TTL:
+			cfg.TTL, ok = parseUint8(v)
+		case linux.IFLA_IPTUN_TOS:
+			cfg.TOS, ok = parseUint8(v)
+		case linux.IFLA_IPTUN_PROTO:
+			var proto uint8
+			proto, ok = parseUint8(v)
+			cfg.Proto = tcpip.TransportProtocolNumber(proto)
+		case linux.IFLA_IPTUN_PMTUDISC:
+			var pmtudisc uint8
+			pmtudisc, ok = parseUint8(v)
+			cfg.PMTUDisc = pmtudisc != 0
+		case linux.IFLA_IPTUN_ENCAP_LIMIT, linux.IFLA_IPTUN_FLOWINFO, linux.IFLA_IPTUN_FLAGS:
+			// ip6tnl options that only affect the outer header; the
+			// defaults are used.
+			ok = true
+		case linux.IFLA_IPTUN_ENCAP_TYPE, linux.IFLA_IPTUN_ENCAP_FLAGS, linux.IFLA_IPTUN_ENCAP_SPORT, linux.IFLA_IPTUN_ENCAP_DPORT, linux.IFLA_IPTUN_FWMARK:
+			if !isZeroAttr(v) {
+				ctx.Warningf("unsupported IP tunnel attribute: %d", t)
+				return syserr.ErrNotSupported
+			}
+			ok = true
+		default:
+			ctx.Warningf("unsupported IP tunnel attribute: %d", t)
+			return syserr.ErrNotSupported
+		}
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+	}
+	return nil
+}
+
+// isZeroAttr returns true if all bytes of v are zero.
+func isZeroAttr(v nlmsg.BytesView) bool {
+	for _, b := range v {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Stack) newTunnel(ctx context.Context, kind tunnel.Kind, linkAttrs map[uint16]nlmsg.BytesView, linkInfoAttrs map[uint16]nlmsg.BytesView) *syserr.Error {
+	ifname := ""
+	if v, ok := linkAttrs[linux.IFLA_IFNAME]; ok {
+		ifname = v.String()
+	}
+
+	// Like Linux, path MTU discovery is enabled unless it is explicitly
+	// disabled.
+	cfg := tunnel.Config{
+		Kind:     kind,
+		PMTUDisc: true,
+	}
+	if value, ok := linkInfoAttrs[linux.IFLA_INFO_DATA]; ok {
+		linkInfoData, ok := nlmsg.AttrsView(value).Parse()
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		var err *syserr.Error
+		switch kind {
+		case tunnel.KindGRE, tunnel.KindGRETap:
+			err = parseGREAttrs(ctx, &cfg, linkInfoData)
+		default:
+			err = parseIPTunAttrs(ctx, &cfg, linkInfoData)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	ep, err := tunnel.New(s.Stack, cfg)
+	if _, ok := err.(*tcpip.ErrUnknownProtocol); ok {
+		// Tunnel protocols aren't enabled, as if Linux's tunnel modules
+		// weren't loaded.
+		return syserr.ErrNotSupported
+	}
+	if err != nil {
+		return syserr.TranslateNetstackError(err)
+	}
+	var linkEP stack.LinkEndpoint = ep
+	if kind == tunnel.KindGRETap {
+		linkEP = ethernet.New(ep)
+	}
+	id := s.Stack.NextNICID()
+	if ifname == "" {
+		ifname = fmt.Sprintf("%s%d", kind, id)
+	}
+	if err := s.Stack.CreateNICWithOptions(id, packetsocket.New(linkEP), stack.NICOptions{
+		Name: ifname,
+	}); err != nil {
+		ep.Close()
+		return syserr.TranslateNetstackError(err)
+	}
+	if err := s.setLink(ctx, id, linkAttrs); err != nil {
+		s.Stack.RemoveNIC(id)
+		return err
+	}
+	return nil
+}
+
 func (s *Stack) newInterface(ctx context.Context, msg *nlmsg.Message, linkAttrs map[uint16]nlmsg.BytesView) *syserr.Error {
 	var (
 		linkInfoAttrs map[uint16]nlmsg.BytesView
@@ -389,6 +593,9 @@ func (s *Stack) newInterface(ctx context.Context, msg *nlmsg.Message, linkAttrs
 	case "veth":
 		return s.newVeth(ctx, linkAttrs, linkInfoAttrs)
 	}
+	if tunnelKind, ok := tunnel.KindFromString(kind); ok {
+		return s.newTunnel(ctx, tunnelKind, linkAttrs, linkInfoAttrs)
+	}
 	return syserr.ErrNotSupported
 }
 
@@ -938,6 +1145,9 @@ func (s *Stack) Resume() {
 	s.Stack.Resume()
 }
 
+// BeforeResume implements inet.Stack.BeforeResume.
+func (s *Stack) BeforeResume() {}
+
 // RegisteredEndpoints implements inet.Stack.RegisteredEndpoints.
 func (s *Stack) RegisteredEndpoints() []stack.TransportEndpoint {
 	return s.Stack.RegisteredEndpoints()
@@ -953,6 +1163,11 @@ func (s *Stack) RestoreCleanupEndpoints(es []stack.TransportEndpoint) {
 	s.Stack.RestoreCleanupEndpoints(es)
 }
 
+// RestoreResets implements inet.Stack.RestoreResets.
+func (s *Stack) RestoreResets() []stack.RestoreResetEndpoint {
+	return s.Stack.RestoreResets()
+}
+
 // SetForwarding implements inet.Stack.SetForwarding.
 func (s *Stack) SetForwarding(protocol tcpip.NetworkProtocolNumber, enable bool) error {
 	if err := s.Stack.SetForwardingDefaultAndAllNICs(protocol, enable); err != nil {
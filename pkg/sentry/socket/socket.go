@@ -436,6 +436,10 @@ func UnmarshalSockAddr(family int, data []byte) linux.SockAddr {
 		var addr linux.SockAddrLink
 		addr.UnmarshalUnsafe(data)
 		return &addr
+	case unix.AF_VSOCK:
+		var addr linux.SockAddrVM
+		addr.UnmarshalUnsafe(data)
+		return &addr
 	default:
 		panic(fmt.Sprintf("Unsupported socket family %v", family))
 	}
@@ -601,6 +605,15 @@ func IsUDP(s Socket) bool {
 	return typ == linux.SOCK_DGRAM && (proto == 0 || proto == linux.IPPROTO_UDP)
 }
 
+// IsSCTP returns true if the socket is an SCTP socket.
+func IsSCTP(s Socket) bool {
+	fam, typ, proto := s.Type()
+	if fam != linux.AF_INET && fam != linux.AF_INET6 {
+		return false
+	}
+	return (typ == linux.SOCK_STREAM || typ == linux.SOCK_SEQPACKET) && proto == linux.IPPROTO_SCTP
+}
+
 // IsICMP returns true if the socket is an ICMP socket.
 func IsICMP(s Socket) bool {
 	fam, typ, proto := s.Type()
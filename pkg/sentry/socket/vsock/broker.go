@@ -0,0 +1,233 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsock
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/syserr"
+)
+
+// The sandbox can't connect to host Unix sockets by path, since seccomp can't
+// restrict which paths it would reach. Instead, connections to
+// VMADDR_CID_HOST are made by a broker process running outside the sandbox,
+// which only ever connects to "<uds>_<port>" and passes the connected socket
+// back over a SOCK_SEQPACKET control socket.
+//
+// A request is a single message containing a request ID, the port and the
+// host socket type. The reply is a single message containing the request ID
+// and an errno, with the connected socket attached as SCM_RIGHTS if the
+// errno is 0.
+const (
+	brokerRequestSize = 16
+	brokerReplySize   = 12
+
+	// brokerTimeout bounds the time the sandbox waits for the broker to
+	// accept a request or reply to it.
+	brokerTimeout = 5 * time.Second
+)
+
+var (
+	// brokerMu serializes requests to the broker.
+	brokerMu sync.Mutex
+
+	// brokerID is the ID of the last request sent to the broker.
+	brokerID uint64
+)
+
+// initBroker prepares the control socket fd for brokerRequest.
+func initBroker(fd int) error {
+	if err := unix.SetNonblock(fd, false); err != nil {
+		return fmt.Errorf("setting vsock broker socket to blocking mode: %w", err)
+	}
+	tv := unix.NsecToTimeval(brokerTimeout.Nanoseconds())
+	for _, opt := range []int{unix.SO_RCVTIMEO, unix.SO_SNDTIMEO} {
+		if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, opt, &tv); err != nil {
+			return fmt.Errorf("setting vsock broker socket timeout: %w", err)
+		}
+	}
+	return nil
+}
+
+// brokerRequest asks the broker on the control socket brokerFD for a host
+// socket of type stype connected to port.
+func brokerRequest(brokerFD int, stype int, port uint32) (int, *syserr.Error) {
+	brokerMu.Lock()
+	defer brokerMu.Unlock()
+
+	brokerID++
+	id := brokerID
+	var req [brokerRequestSize]byte
+	binary.LittleEndian.PutUint64(req[0:], id)
+	binary.LittleEndian.PutUint32(req[8:], port)
+	binary.LittleEndian.PutUint32(req[12:], uint32(stype))
+	if _, err := unix.Write(brokerFD, req[:]); err != nil {
+		log.Warningf("vsock: sending request to broker failed: %v", err)
+		return -1, brokerError(err)
+	}
+
+	for {
+		replyID, errno, fd, err := recvBrokerReply(brokerFD)
+		if err != nil {
+			log.Warningf("vsock: receiving reply from broker failed: %v", err)
+			return -1, brokerError(err)
+		}
+		if replyID != id {
+			// A late reply to a request that timed out.
+			if fd >= 0 {
+				_ = unix.Close(fd)
+			}
+			continue
+		}
+		switch errno {
+		case 0:
+		case unix.ENOENT, unix.ECONNREFUSED:
+			// Nobody is listening on the host, which a virtio
+			// transport reports as a reset.
+			return -1, syserr.ErrConnectionReset
+		case unix.EAGAIN:
+			return -1, syserr.ErrTryAgain
+		default:
+			log.Warningf("vsock: broker failed to connect to host port %d: %v", port, errno)
+			return -1, syserr.ErrConnectionReset
+		}
+		if fd < 0 {
+			return -1, syserr.ErrConnectionReset
+		}
+		return fd, nil
+	}
+}
+
+// recvBrokerReply receives a reply from the broker on brokerFD. It returns
+// the request ID, the errno and the attached socket, or -1 if there is none.
+func recvBrokerReply(brokerFD int) (uint64, unix.Errno, int, error) {
+	var reply [brokerReplySize]byte
+	oob := make([]byte, unix.CmsgSpace(sizeOfInt32))
+	n, oobn, _, _, err := unix.Recvmsg(brokerFD, reply[:], oob, unix.MSG_CMSG_CLOEXEC)
+	if err != nil {
+		return 0, 0, -1, err
+	}
+	if n == 0 {
+		return 0, 0, -1, fmt.Errorf("broker closed the control socket")
+	}
+	fd := -1
+	if oobn > 0 {
+		msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+		if err != nil || len(msgs) != 1 {
+			return 0, 0, -1, fmt.Errorf("malformed control message from broker")
+		}
+		fds, err := unix.ParseUnixRights(&msgs[0])
+		if err != nil || len(fds) != 1 {
+			for _, fd := range fds {
+				_ = unix.Close(fd)
+			}
+			return 0, 0, -1, fmt.Errorf("malformed rights from broker")
+		}
+		fd = fds[0]
+	}
+	if n != brokerReplySize {
+		if fd >= 0 {
+			_ = unix.Close(fd)
+		}
+		return 0, 0, -1, fmt.Errorf("malformed reply of %d bytes from broker", n)
+	}
+	return binary.LittleEndian.Uint64(reply[0:]), unix.Errno(binary.LittleEndian.Uint32(reply[8:])), fd, nil
+}
+
+// brokerError converts an error on the broker control socket to the error
+// returned by connect(2).
+func brokerError(err error) *syserr.Error {
+	if err == unix.EAGAIN {
+		return syserr.ErrTimeout
+	}
+	return syserr.ErrConnectionReset
+}
+
+// ServeBroker serves requests from a sandbox on the control socket fd,
+// connecting to the host Unix sockets "<udsPath>_<port>". It returns nil
+// once the sandbox closes its end of the control socket.
+//
+// ServeBroker runs outside of the sandbox.
+func ServeBroker(fd int, udsPath string) error {
+	// One extra byte detects oversized requests.
+	var req [brokerRequestSize + 1]byte
+	for {
+		n, err := unix.Read(fd, req[:])
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading vsock broker request: %w", err)
+		}
+		if n == 0 {
+			return nil
+		}
+		if n != brokerRequestSize {
+			return fmt.Errorf("malformed vsock broker request of %d bytes", n)
+		}
+		id := binary.LittleEndian.Uint64(req[0:])
+		port := binary.LittleEndian.Uint32(req[8:])
+		stype := int(binary.LittleEndian.Uint32(req[12:]))
+
+		var reply [brokerReplySize]byte
+		binary.LittleEndian.PutUint64(reply[0:], id)
+		sock, errno := brokerConnect(udsPath, stype, port)
+		binary.LittleEndian.PutUint32(reply[8:], uint32(errno))
+		var rights []byte
+		if sock >= 0 {
+			rights = unix.UnixRights(sock)
+		}
+		err = unix.Sendmsg(fd, reply[:], rights, nil, 0)
+		if sock >= 0 {
+			_ = unix.Close(sock)
+		}
+		if err != nil {
+			return fmt.Errorf("sending vsock broker reply: %w", err)
+		}
+	}
+}
+
+// brokerConnect returns a non-blocking host socket of type stype connected to
+// "<udsPath>_<port>".
+func brokerConnect(udsPath string, stype int, port uint32) (int, unix.Errno) {
+	if stype != unix.SOCK_STREAM && stype != unix.SOCK_SEQPACKET {
+		return -1, unix.EINVAL
+	}
+	sock, err := unix.Socket(unix.AF_UNIX, stype|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, toErrno(err)
+	}
+	// The socket is non-blocking, so a full listen backlog fails with
+	// EAGAIN instead of stalling other requests.
+	path := fmt.Sprintf("%s_%d", udsPath, port)
+	if err := unix.Connect(sock, &unix.SockaddrUnix{Name: path}); err != nil {
+		_ = unix.Close(sock)
+		return -1, toErrno(err)
+	}
+	return sock, 0
+}
+
+// toErrno returns the errno of a failed system call.
+func toErrno(err error) unix.Errno {
+	if errno, ok := err.(unix.Errno); ok {
+		return errno
+	}
+	return unix.EINVAL
+}
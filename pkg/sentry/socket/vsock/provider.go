@@ -0,0 +1,62 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsock
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/socket"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/syserr"
+)
+
+// provider is a vsock socket provider.
+type provider struct{}
+
+// Socket implements socket.Provider.Socket.
+func (*provider) Socket(t *kernel.Task, stypeflags linux.SockType, protocol int) (*vfs.FileDescription, *syserr.Error) {
+	// Without a host configuration there is no vsock transport, and
+	// socket(2) fails with EAFNOSUPPORT as on Linux.
+	if config == nil {
+		return nil, nil
+	}
+
+	stype := stypeflags & linux.SOCK_TYPE_MASK
+	switch stype {
+	case linux.SOCK_STREAM, linux.SOCK_SEQPACKET:
+	default:
+		return nil, syserr.ErrSocketNotSupported
+	}
+	if protocol != 0 && protocol != linux.AF_VSOCK {
+		return nil, syserr.ErrProtocolNotSupported
+	}
+
+	s, err := newSocket(t, stype, uint32(stypeflags&linux.SOCK_NONBLOCK))
+	if err != nil {
+		return nil, err
+	}
+	return &s.vfsfd, nil
+}
+
+// Pair implements socket.Provider.Pair.
+func (*provider) Pair(*kernel.Task, linux.SockType, int) (*vfs.FileDescription, *vfs.FileDescription, *syserr.Error) {
+	// socketpair(2) is not supported by AF_VSOCK.
+	return nil, nil, syserr.ErrNotSupported
+}
+
+// init registers the socket provider.
+func init() {
+	socket.RegisterProvider(linux.AF_VSOCK, &provider{})
+}
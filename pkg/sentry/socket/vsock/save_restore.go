@@ -0,0 +1,38 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsock
+
+import (
+	"context"
+
+	"gvisor.dev/gvisor/pkg/log"
+)
+
+// afterLoad is invoked by stateify.
+func (s *Socket) afterLoad(context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fd = -1
+	if s.state == stateConnected || s.state == stateConnecting {
+		// The host connection did not survive the checkpoint.
+		s.state = stateReset
+	}
+	if s.bound {
+		if _, err := reservePort(s.localPort, s); err != nil {
+			log.Warningf("vsock: failed to rebind port %d after restore: %v", s.localPort, err)
+			s.bound = false
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsock
+
+import (
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/seccomp"
+)
+
+// Filters returns seccomp-bpf filters for this package. brokerFD is the
+// control socket passed as Config.BrokerFD, or -1.
+func Filters(brokerFD int) seccomp.SyscallRules {
+	// Host sockets are created by dialLocal. Connections to the host are
+	// made by the broker, since seccomp can't restrict connect(2) to
+	// particular paths.
+	socketpairs := seccomp.Or{
+		seccomp.PerArg{
+			seccomp.EqualTo(unix.AF_UNIX),
+			seccomp.EqualTo(unix.SOCK_STREAM | unix.SOCK_NONBLOCK | unix.SOCK_CLOEXEC),
+			seccomp.EqualTo(0),
+		},
+		seccomp.PerArg{
+			seccomp.EqualTo(unix.AF_UNIX),
+			seccomp.EqualTo(unix.SOCK_SEQPACKET | unix.SOCK_NONBLOCK | unix.SOCK_CLOEXEC),
+			seccomp.EqualTo(0),
+		},
+	}
+	recvmsg := seccomp.Or{
+		seccomp.PerArg{
+			seccomp.NonNegativeFD{},
+			seccomp.AnyValue{},
+			seccomp.EqualTo(unix.MSG_DONTWAIT),
+		},
+		seccomp.PerArg{
+			seccomp.NonNegativeFD{},
+			seccomp.AnyValue{},
+			seccomp.EqualTo(unix.MSG_DONTWAIT | unix.MSG_PEEK),
+		},
+		seccomp.PerArg{
+			seccomp.NonNegativeFD{},
+			seccomp.AnyValue{},
+			seccomp.EqualTo(unix.MSG_DONTWAIT | unix.MSG_TRUNC),
+		},
+		seccomp.PerArg{
+			seccomp.NonNegativeFD{},
+			seccomp.AnyValue{},
+			seccomp.EqualTo(unix.MSG_DONTWAIT | unix.MSG_TRUNC | unix.MSG_PEEK),
+		},
+	}
+	if brokerFD >= 0 {
+		// Only replies from the broker carry file descriptors.
+		recvmsg = append(recvmsg, seccomp.PerArg{
+			seccomp.EqualTo(brokerFD),
+			seccomp.AnyValue{},
+			seccomp.EqualTo(unix.MSG_CMSG_CLOEXEC),
+		})
+	}
+	return seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
+		unix.SYS_ACCEPT4: seccomp.PerArg{
+			seccomp.NonNegativeFD{},
+			seccomp.AnyValue{},
+			seccomp.AnyValue{},
+			seccomp.EqualTo(unix.SOCK_NONBLOCK | unix.SOCK_CLOEXEC),
+		},
+		unix.SYS_RECVMSG:    recvmsg,
+		unix.SYS_SOCKETPAIR: socketpairs,
+	})
+}
@@ -0,0 +1,318 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsock
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/syserr"
+)
+
+// Config configures the host side of AF_VSOCK sockets.
+type Config struct {
+	// CID is the context ID of the sandbox.
+	CID uint32
+
+	// BrokerFD is a SOCK_SEQPACKET control socket connected to the broker
+	// (see ServeBroker) that makes connections to VMADDR_CID_HOST on behalf
+	// of the sandbox, or -1 if such connections are not supported.
+	BrokerFD int
+
+	// ListenFD is a listening host Unix socket through which host
+	// processes connect to sandbox listeners, or -1 if host-initiated
+	// connections are not supported.
+	ListenFD int
+}
+
+// maxHandshakeLen is the maximum length of the "CONNECT <port>\n" line sent
+// by host processes.
+const maxHandshakeLen = 64
+
+// handshakeTimeout is the time host processes have to send the
+// "CONNECT <port>\n" line. It is only changed by tests.
+var handshakeTimeout = 5 * time.Second
+
+var (
+	// config is the host configuration. It is nil until Init is called, and
+	// read-only afterwards.
+	config *Config
+
+	// portsMu protects the fields below.
+	portsMu sync.Mutex
+
+	// ports maps bound local ports to their sockets.
+	ports = make(map[uint32]*Socket)
+
+	// nextEphemeralPort is the next candidate for ephemeral port
+	// allocation.
+	nextEphemeralPort uint32 = linux.LAST_RESERVED_PORT + 1
+
+	// nextHostPort is the next port assigned to the host end of a
+	// host-initiated connection.
+	nextHostPort uint32 = linux.LAST_RESERVED_PORT + 1
+)
+
+// Init enables AF_VSOCK sockets. It must be called before the first AF_VSOCK
+// socket is created.
+func Init(cfg Config) error {
+	if config != nil {
+		return fmt.Errorf("vsock is already initialized")
+	}
+	switch cfg.CID {
+	case linux.VMADDR_CID_HYPERVISOR, linux.VMADDR_CID_LOCAL, linux.VMADDR_CID_HOST, linux.VMADDR_CID_ANY:
+		return fmt.Errorf("invalid vsock CID %d", cfg.CID)
+	}
+	if cfg.BrokerFD >= 0 {
+		if err := initBroker(cfg.BrokerFD); err != nil {
+			return err
+		}
+	}
+	if cfg.ListenFD >= 0 {
+		// The listening socket is served by a dedicated goroutine, so
+		// it can block on the host.
+		if err := unix.SetNonblock(cfg.ListenFD, false); err != nil {
+			return fmt.Errorf("setting vsock socket to blocking mode: %w", err)
+		}
+		go serveHost(cfg.ListenFD) // S/R-SAFE: the listening socket is not saved.
+	}
+	c := cfg
+	config = &c
+	log.Infof("vsock enabled with CID %d", cfg.CID)
+	return nil
+}
+
+// localCID returns the context ID of the sandbox.
+func localCID() uint32 {
+	if config == nil {
+		return linux.VMADDR_CID_ANY
+	}
+	return config.CID
+}
+
+// reservePort reserves port for s. If port is VMADDR_PORT_ANY, an
+// ephemeral port is picked.
+func reservePort(port uint32, s *Socket) (uint32, *syserr.Error) {
+	portsMu.Lock()
+	defer portsMu.Unlock()
+	if port != linux.VMADDR_PORT_ANY {
+		if _, ok := ports[port]; ok {
+			return 0, syserr.ErrPortInUse
+		}
+		ports[port] = s
+		return port, nil
+	}
+	for i := uint32(0); i < linux.VMADDR_PORT_ANY-linux.LAST_RESERVED_PORT-1; i++ {
+		port := nextEphemeralPort
+		nextEphemeralPort++
+		if nextEphemeralPort == linux.VMADDR_PORT_ANY {
+			nextEphemeralPort = linux.LAST_RESERVED_PORT + 1
+		}
+		if _, ok := ports[port]; !ok {
+			ports[port] = s
+			return port, nil
+		}
+	}
+	return 0, syserr.ErrNoPortAvailable
+}
+
+// releasePort releases a port reserved by s.
+func releasePort(port uint32, s *Socket) {
+	portsMu.Lock()
+	defer portsMu.Unlock()
+	if ports[port] == s {
+		delete(ports, port)
+	}
+}
+
+// lookupPort returns the socket bound to port, or nil.
+func lookupPort(port uint32) *Socket {
+	portsMu.Lock()
+	defer portsMu.Unlock()
+	return ports[port]
+}
+
+// hostSockType returns the type of the host Unix socket that carries vsock
+// connections of type stype.
+func hostSockType(stype linux.SockType) int {
+	if stype == linux.SOCK_SEQPACKET {
+		return unix.SOCK_SEQPACKET
+	}
+	return unix.SOCK_STREAM
+}
+
+// dial establishes a connection from local port localPort to cid:port and
+// returns the host socket carrying it.
+func dial(stype linux.SockType, localPort, cid, port uint32) (int, *syserr.Error) {
+	switch {
+	case cid == linux.VMADDR_CID_HOST:
+		return dialHost(stype, port)
+	case cid == linux.VMADDR_CID_LOCAL || cid == localCID():
+		return dialLocal(stype, localPort, port)
+	case cid == linux.VMADDR_CID_HYPERVISOR:
+		return -1, syserr.ErrNoDevice
+	default:
+		// There are no other VMs reachable from the sandbox; behave as if
+		// the host transport rejected the connection.
+		return -1, syserr.ErrConnectionReset
+	}
+}
+
+// dialHost connects to the host Unix socket for port.
+func dialHost(stype linux.SockType, port uint32) (int, *syserr.Error) {
+	if config == nil || config.BrokerFD < 0 {
+		return -1, syserr.ErrConnectionReset
+	}
+	return brokerRequest(config.BrokerFD, hostSockType(stype), port)
+}
+
+// dialLocal connects to a listener in the sandbox.
+func dialLocal(stype linux.SockType, localPort, port uint32) (int, *syserr.Error) {
+	l := lookupPort(port)
+	if l == nil {
+		return -1, syserr.ErrConnectionReset
+	}
+	fds, err := unix.Socketpair(unix.AF_UNIX, hostSockType(stype)|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, syserr.FromError(err)
+	}
+	if l.stype != stype || !l.enqueue(pendingConn{fd: fds[1], remoteCID: localCID(), remotePort: localPort}, nil) {
+		_ = unix.Close(fds[0])
+		_ = unix.Close(fds[1])
+		return -1, syserr.ErrConnectionReset
+	}
+	return fds[0], nil
+}
+
+// serveHost accepts host-initiated connections on listenFD.
+func serveHost(listenFD int) {
+	for {
+		// Connections are non-blocking, so that the handshake can time
+		// out.
+		fd, _, err := unix.Accept4(listenFD, unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC)
+		if err != nil {
+			if err == unix.EINTR || err == unix.ECONNABORTED {
+				continue
+			}
+			log.Warningf("vsock: accepting host connection failed, host-initiated connections are disabled: %v", err)
+			return
+		}
+		go serveHostConn(fd) // S/R-SAFE: not connected to any sandbox state until queued.
+	}
+}
+
+// serveHostConn performs the connection handshake for a host-initiated
+// connection and queues it on the sandbox listener.
+func serveHostConn(fd int) {
+	port, err := readHandshake(fd, time.Now().Add(handshakeTimeout))
+	if err != nil {
+		log.Debugf("vsock: bad handshake from host: %v", err)
+		_ = unix.Close(fd)
+		return
+	}
+
+	l := lookupPort(port)
+	if l == nil || l.stype != linux.SOCK_STREAM {
+		// Host-initiated connections are always streams.
+		log.Debugf("vsock: no listener for host connection to port %d", port)
+		_ = unix.Close(fd)
+		return
+	}
+
+	portsMu.Lock()
+	hostPort := nextHostPort
+	nextHostPort++
+	if nextHostPort == linux.VMADDR_PORT_ANY {
+		nextHostPort = linux.LAST_RESERVED_PORT + 1
+	}
+	portsMu.Unlock()
+
+	// The acknowledgement is written before the connection is visible to
+	// the application, so that it can't be interleaved with application
+	// data.
+	ack := func() error {
+		// The line is much smaller than the socket buffer of a new
+		// connection, so the write does not block.
+		_, err := unix.Write(fd, []byte(fmt.Sprintf("OK %d\n", hostPort)))
+		return err
+	}
+	if !l.enqueue(pendingConn{fd: fd, remoteCID: linux.VMADDR_CID_HOST, remotePort: hostPort}, ack) {
+		log.Debugf("vsock: listener on port %d refused host connection", port)
+		_ = unix.Close(fd)
+	}
+}
+
+// readHandshake reads the "CONNECT <port>\n" line from the non-blocking
+// socket fd before deadline and returns the requested port.
+func readHandshake(fd int, deadline time.Time) (uint32, error) {
+	var line []byte
+	var b [1]byte
+	for {
+		n, err := unix.Read(fd, b[:])
+		if err == unix.EAGAIN || err == unix.EINTR {
+			if err := waitReadable(fd, deadline); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			return 0, fmt.Errorf("connection closed during handshake")
+		}
+		if b[0] == '\n' {
+			break
+		}
+		line = append(line, b[0])
+		if len(line) >= maxHandshakeLen {
+			return 0, fmt.Errorf("handshake line too long")
+		}
+	}
+	return parseHandshake(string(line))
+}
+
+// waitReadable waits until fd is readable or deadline has passed.
+func waitReadable(fd int, deadline time.Time) error {
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return fmt.Errorf("handshake timed out")
+	}
+	ts := unix.NsecToTimespec(timeout.Nanoseconds())
+	pfd := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	if _, err := unix.Ppoll(pfd, &ts, nil); err != nil && err != unix.EINTR {
+		return err
+	}
+	return nil
+}
+
+// parseHandshake parses a "CONNECT <port>" line.
+func parseHandshake(line string) (uint32, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 || fields[0] != "CONNECT" {
+		return 0, fmt.Errorf("malformed handshake %q", line)
+	}
+	port, err := strconv.ParseUint(fields[1], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("malformed port in handshake %q: %w", line, err)
+	}
+	return uint32(port), nil
+}
@@ -0,0 +1,937 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vsock provides an implementation of AF_VSOCK sockets.
+//
+// The sandbox has no virtio transport, so vsock connections are carried over
+// host Unix domain sockets following the Firecracker vsock-over-UDS
+// convention:
+//
+//   - A connection from the sandbox to VMADDR_CID_HOST on port P is made by
+//     connecting to the host Unix socket "<uds>_<P>". The sandbox can't
+//     connect to host paths itself, so it asks a broker outside the
+//     sandbox for the connected socket (see ServeBroker).
+//   - A host process connects to a sandbox listener on port P by connecting
+//     to the host Unix socket "<uds>", writing "CONNECT <P>\n" and reading
+//     back "OK <port>\n" once the connection has been queued on the
+//     listener.
+//   - Connections from the sandbox to its own CID (or VMADDR_CID_LOCAL) are
+//     served by a host socket pair.
+//
+// Established connections are backed by host file descriptors, which cannot
+// be saved. Bound and listening sockets survive save/restore, while
+// established connections are reset, as Linux does when the vsock transport
+// of a migrated VM is reset.
+package vsock
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fdnotifier"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/sockfs"
+	"gvisor.dev/gvisor/pkg/sentry/hostfd"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/ktime"
+	"gvisor.dev/gvisor/pkg/sentry/socket"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/syserr"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const sizeOfInt32 = 4
+
+// sockState is the connection state of a vsock socket.
+type sockState int
+
+const (
+	// stateUnconnected is the initial state of a socket.
+	stateUnconnected sockState = iota
+
+	// stateConnecting is the state of a socket while a connection to its
+	// peer is established.
+	stateConnecting
+
+	// stateListening is the state of a socket after listen(2).
+	stateListening
+
+	// stateConnected is the state of a socket backed by a host connection.
+	stateConnected
+
+	// stateReset is the state of a connected socket whose host connection
+	// was lost, e.g. because the sandbox was restored from a checkpoint.
+	stateReset
+)
+
+// pendingConn is an established connection waiting to be accepted.
+type pendingConn struct {
+	fd         int
+	remoteCID  uint32
+	remotePort uint32
+}
+
+// Socket implements socket.Socket (and by extension,
+// vfs.FileDescriptionImpl) for AF_VSOCK sockets.
+//
+// +stateify savable
+type Socket struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.LockFD
+	vfs.DentryMetadataFileDescriptionImpl
+	socket.SendReceiveTimeout
+
+	stype linux.SockType // Read-only.
+	queue waiter.Queue
+
+	mu sync.Mutex `state:"nosave"`
+
+	// state is the connection state of the socket.
+	//
+	// +checklocks:mu
+	state sockState
+
+	// bound indicates that localPort is reserved for this socket in the
+	// port table.
+	//
+	// +checklocks:mu
+	bound bool
+
+	// localCID and localPort are the local address of the socket.
+	//
+	// +checklocks:mu
+	localCID uint32
+	// +checklocks:mu
+	localPort uint32
+
+	// remoteCID and remotePort are the address of the peer of a connected
+	// socket.
+	//
+	// +checklocks:mu
+	remoteCID uint32
+	// +checklocks:mu
+	remotePort uint32
+
+	// backlog is the listen(2) backlog.
+	//
+	// +checklocks:mu
+	backlog int
+
+	// pending holds connections that are ready to be accepted.
+	//
+	// +checklocks:mu
+	pending []pendingConn `state:"nosave"`
+
+	// fd is the host socket backing an established connection, or -1. It
+	// must have O_NONBLOCK, so that operations will return EWOULDBLOCK
+	// instead of blocking on the host. Once set, fd does not change until
+	// the socket is released.
+	//
+	// +checklocks:mu
+	fd int `state:"nosave"`
+
+	// bufferSize, bufferMinSize and bufferMaxSize are the values of the
+	// SO_VM_SOCKETS_BUFFER_* options. They are reported to the application
+	// but otherwise ignored, since buffering is done by the host.
+	//
+	// +checklocks:mu
+	bufferSize uint64
+	// +checklocks:mu
+	bufferMinSize uint64
+	// +checklocks:mu
+	bufferMaxSize uint64
+
+	// connErr is the error of the last failed non-blocking connect(2),
+	// reported by SO_ERROR.
+	//
+	// +checklocks:mu
+	connErr *syserr.Error `state:"nosave"`
+
+	// recvClosed indicates that the socket has been shutdown for reading
+	// (SHUT_RD or SHUT_RDWR).
+	recvClosed atomicbitops.Bool
+}
+
+var _ = socket.Socket(&Socket{})
+
+func newSocket(t *kernel.Task, stype linux.SockType, flags uint32) (*Socket, *syserr.Error) {
+	mnt := t.Kernel().SocketMount()
+	d := sockfs.NewDentry(t, mnt)
+	defer d.DecRef(t)
+
+	s := &Socket{
+		stype:         stype,
+		localCID:      linux.VMADDR_CID_ANY,
+		localPort:     linux.VMADDR_PORT_ANY,
+		remoteCID:     linux.VMADDR_CID_ANY,
+		remotePort:    linux.VMADDR_PORT_ANY,
+		fd:            -1,
+		bufferSize:    linux.VSOCK_DEFAULT_BUFFER_SIZE,
+		bufferMinSize: linux.VSOCK_DEFAULT_BUFFER_MIN_SIZE,
+		bufferMaxSize: linux.VSOCK_DEFAULT_BUFFER_MAX_SIZE,
+	}
+	s.LockFD.Init(&vfs.FileLocks{})
+	if err := s.vfsfd.Init(s, linux.O_RDWR|(flags&linux.O_NONBLOCK), mnt, d, &vfs.FileDescriptionOptions{
+		DenyPRead:         true,
+		DenyPWrite:        true,
+		UseDentryMetadata: true,
+	}); err != nil {
+		return nil, syserr.FromError(err)
+	}
+	return s, nil
+}
+
+// attachLocked makes s a connected socket backed by the host socket fd.
+//
+// Preconditions: s.mu is locked. s.fd < 0.
+func (s *Socket) attachLocked(fd int, remoteCID, remotePort uint32) *syserr.Error {
+	if err := fdnotifier.AddFD(int32(fd), &s.queue); err != nil {
+		return syserr.FromError(err)
+	}
+	s.fd = fd
+	s.state = stateConnected
+	s.remoteCID = remoteCID
+	s.remotePort = remotePort
+	s.localCID = localCID()
+	return nil
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (s *Socket) Release(ctx context.Context) {
+	kernel.KernelFromContext(ctx).DeleteSocket(&s.vfsfd)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bound {
+		releasePort(s.localPort, s)
+		s.bound = false
+	}
+	for _, pc := range s.pending {
+		_ = unix.Close(pc.fd)
+	}
+	s.pending = nil
+	if s.fd >= 0 {
+		fdnotifier.RemoveFD(int32(s.fd))
+		_ = unix.Close(s.fd)
+		s.fd = -1
+	}
+}
+
+// Epollable implements FileDescriptionImpl.Epollable.
+func (s *Socket) Epollable() bool {
+	return true
+}
+
+// PRead implements vfs.FileDescriptionImpl.PRead.
+func (s *Socket) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
+	return 0, linuxerr.ESPIPE
+}
+
+// Read implements vfs.FileDescriptionImpl.
+func (s *Socket) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	// All flags other than RWF_NOWAIT should be ignored.
+	// TODO(gvisor.dev/issue/2601): Support RWF_NOWAIT.
+	if opts.Flags != 0 {
+		return 0, linuxerr.EOPNOTSUPP
+	}
+
+	fd, serr := s.connectedFD()
+	if serr != nil {
+		return 0, serr.ToError()
+	}
+	reader := hostfd.GetReadWriterAt(int32(fd), -1, opts.Flags)
+	defer hostfd.PutReadWriterAt(reader)
+	n, err := dst.CopyOutFrom(ctx, reader)
+	return int64(n), err
+}
+
+// PWrite implements vfs.FileDescriptionImpl.
+func (s *Socket) PWrite(ctx context.Context, src usermem.IOSequence, offset int64, opts vfs.WriteOptions) (int64, error) {
+	return 0, linuxerr.ESPIPE
+}
+
+// Write implements vfs.FileDescriptionImpl.
+func (s *Socket) Write(ctx context.Context, src usermem.IOSequence, opts vfs.WriteOptions) (int64, error) {
+	// All flags other than RWF_NOWAIT should be ignored.
+	// TODO(gvisor.dev/issue/2601): Support RWF_NOWAIT.
+	if opts.Flags != 0 {
+		return 0, linuxerr.EOPNOTSUPP
+	}
+
+	fd, serr := s.connectedFD()
+	if serr != nil {
+		return 0, serr.ToError()
+	}
+	writer := hostfd.GetReadWriterAt(int32(fd), -1, opts.Flags)
+	defer hostfd.PutReadWriterAt(writer)
+	n, err := src.CopyInTo(ctx, writer)
+	return int64(n), err
+}
+
+// connectedFD returns the host socket backing an established connection.
+func (s *Socket) connectedFD() (int, *syserr.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch s.state {
+	case stateConnected:
+		return s.fd, nil
+	case stateReset:
+		return -1, syserr.ErrConnectionReset
+	default:
+		return -1, syserr.ErrNotConnected
+	}
+}
+
+// Readiness implements waiter.Waitable.Readiness.
+func (s *Socket) Readiness(mask waiter.EventMask) waiter.EventMask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch s.state {
+	case stateListening:
+		if len(s.pending) > 0 {
+			return mask & waiter.ReadableEvents
+		}
+		return 0
+	case stateConnected:
+		return fdnotifier.NonBlockingPoll(int32(s.fd), mask)
+	case stateReset:
+		return mask & (waiter.ReadableEvents | waiter.WritableEvents | waiter.EventHUp | waiter.EventErr)
+	case stateUnconnected:
+		if s.connErr != nil {
+			// A failed non-blocking connect(2) is reported as writable.
+			return mask & (waiter.WritableEvents | waiter.EventErr)
+		}
+		return 0
+	default:
+		return 0
+	}
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (s *Socket) EventRegister(e *waiter.Entry) error {
+	s.queue.EventRegister(e)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fd >= 0 {
+		if err := fdnotifier.UpdateFD(int32(s.fd)); err != nil {
+			s.queue.EventUnregister(e)
+			return err
+		}
+	}
+	return nil
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (s *Socket) EventUnregister(e *waiter.Entry) {
+	s.queue.EventUnregister(e)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fd >= 0 {
+		if err := fdnotifier.UpdateFD(int32(s.fd)); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// ExtractSockAddr extracts the SockAddrVM from b.
+func ExtractSockAddr(b []byte) (*linux.SockAddrVM, *syserr.Error) {
+	if len(b) < linux.SockAddrVMSize {
+		return nil, syserr.ErrInvalidArgument
+	}
+
+	var sa linux.SockAddrVM
+	sa.UnmarshalUnsafe(b)
+
+	if sa.Family != linux.AF_VSOCK {
+		return nil, syserr.ErrAddressFamilyNotSupported
+	}
+
+	return &sa, nil
+}
+
+// Bind implements socket.Socket.Bind.
+func (s *Socket) Bind(t *kernel.Task, sockaddr []byte) *syserr.Error {
+	addr, err := ExtractSockAddr(sockaddr)
+	if err != nil {
+		return err
+	}
+	if addr.CID != linux.VMADDR_CID_ANY && addr.CID != linux.VMADDR_CID_LOCAL && addr.CID != localCID() {
+		return syserr.ErrAddressNotAvailable
+	}
+	if addr.Port != linux.VMADDR_PORT_ANY && addr.Port <= linux.LAST_RESERVED_PORT {
+		if creds := auth.CredentialsFromContext(t); !creds.HasCapability(linux.CAP_NET_BIND_SERVICE) {
+			return syserr.ErrPermissionDenied
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bound || s.state != stateUnconnected {
+		return syserr.ErrInvalidArgument
+	}
+	return s.bindLocked(addr.CID, addr.Port)
+}
+
+// bindLocked reserves port, or an ephemeral port if port is
+// VMADDR_PORT_ANY, for s.
+//
+// Preconditions: s.mu is locked. !s.bound.
+func (s *Socket) bindLocked(cid, port uint32) *syserr.Error {
+	port, err := reservePort(port, s)
+	if err != nil {
+		return err
+	}
+	s.bound = true
+	s.localCID = cid
+	s.localPort = port
+	return nil
+}
+
+// Listen implements socket.Socket.Listen.
+func (s *Socket) Listen(_ *kernel.Task, backlog int) *syserr.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Unlike other families, vsock sockets are not automatically bound by
+	// listen(2).
+	if !s.bound {
+		return syserr.ErrInvalidArgument
+	}
+	switch s.state {
+	case stateUnconnected, stateListening:
+	default:
+		return syserr.ErrInvalidArgument
+	}
+	if backlog < 0 {
+		backlog = 0
+	}
+	s.backlog = backlog
+	s.state = stateListening
+	return nil
+}
+
+// enqueue queues a connection on a listening socket. ack, if not nil, is
+// called before the connection becomes visible to the application; if it
+// fails, the connection is not queued.
+//
+// enqueue returns false if the connection was not queued, in which case the
+// caller retains ownership of pc.fd.
+func (s *Socket) enqueue(pc pendingConn, ack func() error) bool {
+	s.mu.Lock()
+	// The accept queue is full once it holds more than backlog
+	// connections, as in Linux.
+	if s.state != stateListening || len(s.pending) > s.backlog {
+		s.mu.Unlock()
+		return false
+	}
+	if ack != nil {
+		if err := ack(); err != nil {
+			s.mu.Unlock()
+			return false
+		}
+	}
+	s.pending = append(s.pending, pc)
+	s.mu.Unlock()
+	s.queue.Notify(waiter.ReadableEvents)
+	return true
+}
+
+// dequeue removes the oldest connection from the accept queue.
+func (s *Socket) dequeue() (pendingConn, uint32, *syserr.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != stateListening {
+		return pendingConn{}, 0, syserr.ErrInvalidArgument
+	}
+	if len(s.pending) == 0 {
+		return pendingConn{}, 0, syserr.ErrTryAgain
+	}
+	pc := s.pending[0]
+	s.pending = s.pending[1:]
+	return pc, s.localPort, nil
+}
+
+// Accept implements socket.Socket.Accept.
+func (s *Socket) Accept(t *kernel.Task, peerRequested bool, flags int, blocking bool) (int32, linux.SockAddr, uint32, *syserr.Error) {
+	pc, localPort, err := s.dequeue()
+	if err == syserr.ErrTryAgain && blocking {
+		e, ch := waiter.NewChannelEntry(waiter.ReadableEvents)
+		s.EventRegister(&e)
+		defer s.EventUnregister(&e)
+		for err == syserr.ErrTryAgain {
+			if blockErr := t.Block(ch); blockErr != nil {
+				return 0, nil, 0, syserr.FromError(blockErr)
+			}
+			pc, localPort, err = s.dequeue()
+		}
+	}
+	if err != nil {
+		return 0, nil, 0, err
+	}
+
+	ns, err := newSocket(t, s.stype, uint32(flags&linux.SOCK_NONBLOCK))
+	if err != nil {
+		_ = unix.Close(pc.fd)
+		return 0, nil, 0, err
+	}
+	defer ns.vfsfd.DecRef(t)
+
+	ns.mu.Lock()
+	ns.localPort = localPort
+	err = ns.attachLocked(pc.fd, pc.remoteCID, pc.remotePort)
+	ns.mu.Unlock()
+	if err != nil {
+		_ = unix.Close(pc.fd)
+		return 0, nil, 0, err
+	}
+
+	kfd, kerr := t.NewFDFrom(0, &ns.vfsfd, kernel.FDFlags{
+		CloseOnExec: flags&linux.SOCK_CLOEXEC != 0,
+	})
+	if kerr != nil {
+		return 0, nil, 0, syserr.FromError(kerr)
+	}
+	t.Kernel().RecordSocket(&ns.vfsfd)
+
+	var peerAddr linux.SockAddr
+	var peerAddrLen uint32
+	if peerRequested {
+		peerAddr, peerAddrLen = sockAddr(pc.remoteCID, pc.remotePort)
+	}
+	return kfd, peerAddr, peerAddrLen, nil
+}
+
+// Connect implements socket.Socket.Connect.
+func (s *Socket) Connect(t *kernel.Task, sockaddr []byte, blocking bool) *syserr.Error {
+	addr, err := ExtractSockAddr(sockaddr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	switch s.state {
+	case stateConnected:
+		s.mu.Unlock()
+		return syserr.ErrAlreadyConnected
+	case stateConnecting:
+		s.mu.Unlock()
+		return syserr.ErrAlreadyInProgress
+	case stateListening:
+		s.mu.Unlock()
+		return syserr.ErrInvalidArgument
+	}
+	if !s.bound {
+		if err := s.bindLocked(linux.VMADDR_CID_ANY, linux.VMADDR_PORT_ANY); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+	}
+	prevState := s.state
+	s.state = stateConnecting
+	s.connErr = nil
+	localPort := s.localPort
+	s.mu.Unlock()
+
+	if blocking {
+		return s.finishConnect(localPort, addr, prevState)
+	}
+
+	// Hold a reference so that s is not released before the connection
+	// attempt completes.
+	s.vfsfd.IncRef()
+	ctx := t.Kernel().SupervisorContext()
+	go func() { // S/R-SAFE: connecting sockets are reset on restore.
+		defer s.vfsfd.DecRef(ctx)
+		if err := s.finishConnect(localPort, addr, prevState); err != nil {
+			s.mu.Lock()
+			s.connErr = err
+			s.mu.Unlock()
+			s.queue.Notify(waiter.WritableEvents | waiter.EventErr)
+			return
+		}
+		s.queue.Notify(waiter.WritableEvents)
+	}()
+	return syserr.ErrInProgress
+}
+
+// finishConnect establishes the connection started by Connect, and returns
+// s to prevState if that fails.
+func (s *Socket) finishConnect(localPort uint32, addr *linux.SockAddrVM, prevState sockState) *syserr.Error {
+	// The connection is established without holding s.mu, since a loopback
+	// connection locks the listening socket.
+	fd, err := dial(s.stype, localPort, addr.CID, addr.Port)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		err = s.attachLocked(fd, addr.CID, addr.Port)
+		if err != nil {
+			_ = unix.Close(fd)
+		}
+	}
+	if err != nil {
+		s.state = prevState
+		return err
+	}
+	return nil
+}
+
+// Shutdown implements socket.Socket.Shutdown.
+func (s *Socket) Shutdown(_ *kernel.Task, how int) *syserr.Error {
+	fd, err := s.connectedFD()
+	if err != nil {
+		return err
+	}
+	switch how {
+	case linux.SHUT_RD, linux.SHUT_RDWR:
+		// Mark the socket as closed for reading.
+		s.recvClosed.Store(true)
+		fallthrough
+	case linux.SHUT_WR:
+		return syserr.FromError(unix.Shutdown(fd, how))
+	default:
+		return syserr.ErrInvalidArgument
+	}
+}
+
+// GetSockOpt implements socket.Socket.GetSockOpt.
+func (s *Socket) GetSockOpt(t *kernel.Task, level int, name int, outPtr hostarch.Addr, outLen int) (marshal.Marshallable, *syserr.Error) {
+	switch level {
+	case linux.SOL_SOCKET:
+		switch name {
+		case linux.SO_ERROR:
+			if outLen < sizeOfInt32 {
+				return nil, syserr.ErrInvalidArgument
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			var v primitive.Int32
+			if s.connErr != nil {
+				v = primitive.Int32(s.connErr.ToLinux())
+				s.connErr = nil
+			}
+			return &v, nil
+
+		case linux.SO_ACCEPTCONN:
+			if outLen < sizeOfInt32 {
+				return nil, syserr.ErrInvalidArgument
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			var v primitive.Int32
+			if s.state == stateListening {
+				v = 1
+			}
+			return &v, nil
+
+		case linux.SO_SNDBUF, linux.SO_RCVBUF:
+			if outLen < sizeOfInt32 {
+				return nil, syserr.ErrInvalidArgument
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return primitive.AllocateInt32(int32(min(s.bufferSize, math.MaxInt32))), nil
+
+		case linux.SO_SNDTIMEO:
+			if outLen < linux.SizeOfTimeval {
+				return nil, syserr.ErrInvalidArgument
+			}
+			sendTimeout := linux.NsecToTimeval(s.SendTimeout())
+			return &sendTimeout, nil
+
+		case linux.SO_RCVTIMEO:
+			if outLen < linux.SizeOfTimeval {
+				return nil, syserr.ErrInvalidArgument
+			}
+			recvTimeout := linux.NsecToTimeval(s.RecvTimeout())
+			return &recvTimeout, nil
+		}
+
+	case linux.AF_VSOCK:
+		switch name {
+		case linux.SO_VM_SOCKETS_BUFFER_SIZE, linux.SO_VM_SOCKETS_BUFFER_MIN_SIZE, linux.SO_VM_SOCKETS_BUFFER_MAX_SIZE:
+			if outLen < 8 {
+				return nil, syserr.ErrInvalidArgument
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			var v primitive.Uint64
+			switch name {
+			case linux.SO_VM_SOCKETS_BUFFER_SIZE:
+				v = primitive.Uint64(s.bufferSize)
+			case linux.SO_VM_SOCKETS_BUFFER_MIN_SIZE:
+				v = primitive.Uint64(s.bufferMinSize)
+			case linux.SO_VM_SOCKETS_BUFFER_MAX_SIZE:
+				v = primitive.Uint64(s.bufferMaxSize)
+			}
+			return &v, nil
+		}
+	}
+	return nil, syserr.ErrProtocolNotAvailable
+}
+
+// SetSockOpt implements socket.Socket.SetSockOpt.
+func (s *Socket) SetSockOpt(t *kernel.Task, level int, name int, opt []byte) *syserr.Error {
+	switch level {
+	case linux.SOL_SOCKET:
+		switch name {
+		case linux.SO_SNDBUF, linux.SO_RCVBUF:
+			if len(opt) < sizeOfInt32 {
+				return syserr.ErrInvalidArgument
+			}
+			// Buffering is done by the host, so just accept anything as
+			// valid for compatibility.
+			return nil
+
+		case linux.SO_SNDTIMEO:
+			if len(opt) < linux.SizeOfTimeval {
+				return syserr.ErrInvalidArgument
+			}
+			var v linux.Timeval
+			v.UnmarshalBytes(opt)
+			if v.Usec < 0 || v.Usec >= int64(time.Second/time.Microsecond) {
+				return syserr.ErrDomain
+			}
+			s.SetSendTimeout(v.ToNsecCapped())
+			return nil
+
+		case linux.SO_RCVTIMEO:
+			if len(opt) < linux.SizeOfTimeval {
+				return syserr.ErrInvalidArgument
+			}
+			var v linux.Timeval
+			v.UnmarshalBytes(opt)
+			if v.Usec < 0 || v.Usec >= int64(time.Second/time.Microsecond) {
+				return syserr.ErrDomain
+			}
+			s.SetRecvTimeout(v.ToNsecCapped())
+			return nil
+		}
+
+	case linux.AF_VSOCK:
+		switch name {
+		case linux.SO_VM_SOCKETS_BUFFER_SIZE, linux.SO_VM_SOCKETS_BUFFER_MIN_SIZE, linux.SO_VM_SOCKETS_BUFFER_MAX_SIZE:
+			if len(opt) < 8 {
+				return syserr.ErrInvalidArgument
+			}
+			v := hostarch.ByteOrder.Uint64(opt)
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			// Keep min <= size <= max, as vsock_update_buffer_size() does.
+			switch name {
+			case linux.SO_VM_SOCKETS_BUFFER_SIZE:
+				s.bufferSize = v
+			case linux.SO_VM_SOCKETS_BUFFER_MIN_SIZE:
+				s.bufferMinSize = v
+			case linux.SO_VM_SOCKETS_BUFFER_MAX_SIZE:
+				s.bufferMaxSize = v
+			}
+			if s.bufferSize < s.bufferMinSize {
+				s.bufferSize = s.bufferMinSize
+			}
+			if s.bufferSize > s.bufferMaxSize {
+				s.bufferSize = s.bufferMaxSize
+			}
+			return nil
+		}
+	}
+	return syserr.ErrProtocolNotAvailable
+}
+
+// GetSockName implements socket.Socket.GetSockName.
+func (s *Socket) GetSockName(t *kernel.Task) (linux.SockAddr, uint32, *syserr.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addr, addrLen := sockAddr(s.localCID, s.localPort)
+	return addr, addrLen, nil
+}
+
+// GetPeerName implements socket.Socket.GetPeerName.
+func (s *Socket) GetPeerName(t *kernel.Task) (linux.SockAddr, uint32, *syserr.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != stateConnected {
+		return nil, 0, syserr.ErrNotConnected
+	}
+	addr, addrLen := sockAddr(s.remoteCID, s.remotePort)
+	return addr, addrLen, nil
+}
+
+// sockAddr returns the sockaddr_vm for the given address.
+func sockAddr(cid, port uint32) (linux.SockAddr, uint32) {
+	return &linux.SockAddrVM{
+		Family: linux.AF_VSOCK,
+		Port:   port,
+		CID:    cid,
+	}, linux.SockAddrVMSize
+}
+
+const allowedRecvMsgFlags = linux.MSG_DONTWAIT |
+	linux.MSG_PEEK |
+	linux.MSG_TRUNC |
+	linux.MSG_WAITALL
+
+// hostRecvMsgFlags are the flags that are passed to the host on recvmsg(2).
+// Other flags are implemented by the sentry.
+const hostRecvMsgFlags = linux.MSG_PEEK | linux.MSG_TRUNC
+
+// RecvMsg implements socket.Socket.RecvMsg.
+func (s *Socket) RecvMsg(t *kernel.Task, dst usermem.IOSequence, flags int, haveDeadline bool, deadline ktime.Time, senderRequested bool, controlLen uint64) (int, int, linux.SockAddr, uint32, socket.ControlMessages, *syserr.Error) {
+	// Only allow known and safe flags.
+	if flags&^allowedRecvMsgFlags != 0 {
+		return 0, 0, nil, 0, socket.ControlMessages{}, syserr.ErrInvalidArgument
+	}
+	fd, serr := s.connectedFD()
+	if serr != nil {
+		return 0, 0, nil, 0, socket.ControlMessages{}, serr
+	}
+
+	sysflags := flags&hostRecvMsgFlags | unix.MSG_DONTWAIT
+	var msgFlags int
+	recvmsgToBlocks := safemem.ReaderFunc(func(dsts safemem.BlockSeq) (uint64, error) {
+		// Refuse to do anything if any part of dst.Addrs was unusable.
+		if uint64(dst.NumBytes()) != dsts.NumBytes() {
+			return 0, nil
+		}
+		var n uint64
+		var err error
+		n, msgFlags, err = recvmsg(fd, safemem.IovecsFromBlockSeq(dsts), sysflags)
+		return n, err
+	})
+
+	var ch chan struct{}
+	n, err := dst.CopyOutFrom(t, recvmsgToBlocks)
+	if flags&linux.MSG_DONTWAIT == 0 {
+		for linuxerr.Equals(linuxerr.ErrWouldBlock, err) {
+			// We only expect blocking to come from the actual syscall, in which
+			// case it can't have returned any data.
+			if n != 0 {
+				panic(fmt.Sprintf("CopyOutFrom: got (%d, %v), wanted (0, %v)", n, err, err))
+			}
+			// Are we closed for reading? No sense in trying to read if so.
+			if s.recvClosed.Load() {
+				break
+			}
+			if ch != nil {
+				if err = t.BlockWithDeadline(ch, haveDeadline, deadline); err != nil {
+					if linuxerr.Equals(linuxerr.ETIMEDOUT, err) {
+						err = linuxerr.ErrWouldBlock
+					}
+					break
+				}
+			} else {
+				var e waiter.Entry
+				e, ch = waiter.NewChannelEntry(waiter.ReadableEvents | waiter.EventRdHUp | waiter.EventHUp | waiter.EventErr)
+				s.EventRegister(&e)
+				defer s.EventUnregister(&e)
+			}
+			n, err = dst.CopyOutFrom(t, recvmsgToBlocks)
+		}
+	}
+	if err != nil {
+		return 0, 0, nil, 0, socket.ControlMessages{}, syserr.FromError(err)
+	}
+	// Connection-oriented vsock sockets do not report a sender address.
+	return int(n), msgFlags & linux.MSG_TRUNC, nil, 0, socket.ControlMessages{}, nil
+}
+
+const allowedSendMsgFlags = linux.MSG_DONTWAIT |
+	linux.MSG_EOR |
+	linux.MSG_NOSIGNAL
+
+// SendMsg implements socket.Socket.SendMsg.
+func (s *Socket) SendMsg(t *kernel.Task, src usermem.IOSequence, to []byte, flags int, haveDeadline bool, deadline ktime.Time, controlMessages socket.ControlMessages) (int, *syserr.Error) {
+	// Only allow known and safe flags.
+	if flags&^allowedSendMsgFlags != 0 {
+		return 0, syserr.ErrInvalidArgument
+	}
+	if len(to) != 0 {
+		return 0, syserr.ErrEndpointOperation
+	}
+	fd, serr := s.connectedFD()
+	if serr != nil {
+		return 0, serr
+	}
+
+	sendmsgFromBlocks := safemem.WriterFunc(func(srcs safemem.BlockSeq) (uint64, error) {
+		// Refuse to do anything if any part of src.Addrs was unusable.
+		if uint64(src.NumBytes()) != srcs.NumBytes() {
+			return 0, nil
+		}
+		if srcs.IsEmpty() {
+			return 0, nil
+		}
+		// SIGPIPE is generated by the syscall layer, not by the host.
+		return sendmsg(fd, safemem.IovecsFromBlockSeq(srcs), unix.MSG_DONTWAIT|unix.MSG_NOSIGNAL)
+	})
+
+	var ch chan struct{}
+	n, err := src.CopyInTo(t, sendmsgFromBlocks)
+	if flags&linux.MSG_DONTWAIT == 0 {
+		for linuxerr.Equals(linuxerr.ErrWouldBlock, err) {
+			// We only expect blocking to come from the actual syscall, in which
+			// case it can't have returned any data.
+			if n != 0 {
+				panic(fmt.Sprintf("CopyInTo: got (%d, %v), wanted (0, %v)", n, err, err))
+			}
+			if ch != nil {
+				if err = t.BlockWithDeadline(ch, haveDeadline, deadline); err != nil {
+					if linuxerr.Equals(linuxerr.ETIMEDOUT, err) {
+						err = linuxerr.ErrWouldBlock
+					}
+					break
+				}
+			} else {
+				var e waiter.Entry
+				e, ch = waiter.NewChannelEntry(waiter.WritableEvents | waiter.EventHUp | waiter.EventErr)
+				s.EventRegister(&e)
+				defer s.EventUnregister(&e)
+			}
+			n, err = src.CopyInTo(t, sendmsgFromBlocks)
+		}
+	}
+
+	return int(n), syserr.FromError(err)
+}
+
+// State implements socket.Socket.State.
+func (s *Socket) State() uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch s.state {
+	case stateConnecting:
+		return linux.SS_CONNECTING
+	case stateConnected:
+		return linux.SS_CONNECTED
+	default:
+		return linux.SS_UNCONNECTED
+	}
+}
+
+// Type implements socket.Socket.Type.
+func (s *Socket) Type() (family int, skType linux.SockType, protocol int) {
+	return linux.AF_VSOCK, s.stype, 0
+}
@@ -0,0 +1,693 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fdnotifier"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/testutil"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/syserr"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	// testCID is the CID of the test sandbox.
+	testCID = 3
+
+	// testUDSName is the base name of the host Unix sockets.
+	testUDSName = "vsock"
+)
+
+// testDir is the host directory containing the Unix sockets of the test
+// configuration.
+var testDir string
+
+// initTestConfig initializes the package with a host configuration using
+// Unix sockets in dir.
+func initTestConfig(dir string) error {
+	brokerFDs, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("creating broker socket: %w", err)
+	}
+	go func() {
+		if err := ServeBroker(brokerFDs[1], filepath.Join(dir, testUDSName)); err != nil {
+			panic(fmt.Sprintf("ServeBroker() failed: %v", err))
+		}
+	}()
+	listenFD, err := unix.Socket(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("creating listening socket: %w", err)
+	}
+	if err := unix.Bind(listenFD, &unix.SockaddrUnix{Name: filepath.Join(dir, testUDSName)}); err != nil {
+		return fmt.Errorf("binding listening socket: %w", err)
+	}
+	if err := unix.Listen(listenFD, 16); err != nil {
+		return fmt.Errorf("listening: %w", err)
+	}
+	return Init(Config{
+		CID:      testCID,
+		BrokerFD: brokerFDs[0],
+		ListenFD: listenFD,
+	})
+}
+
+// newTask returns a task in a new test kernel.
+func newTask(t *testing.T) *kernel.Task {
+	t.Helper()
+	k, err := testutil.Boot()
+	if err != nil {
+		t.Fatalf("testutil.Boot() failed: %v", err)
+	}
+	ctx := k.SupervisorContext()
+	creds := auth.CredentialsFromContext(ctx)
+	mntns, err := k.VFS().NewMountNamespace(ctx, creds, "", "tmpfs", &vfs.MountOptions{}, nil)
+	if err != nil {
+		t.Fatalf("NewMountNamespace() failed: %v", err)
+	}
+	root := mntns.Root(ctx)
+	tg := k.NewThreadGroup(k.RootPIDNamespace(), kernel.NewSignalHandlers(), linux.SIGCHLD, k.GlobalInit().Limits())
+	task, err := testutil.CreateTask(ctx, "vsock-test", tg, mntns, root, root)
+	if err != nil {
+		t.Fatalf("CreateTask() failed: %v", err)
+	}
+	return task
+}
+
+// newTestSocket returns a new vsock socket of type stype, which is released
+// when the test ends.
+func newTestSocket(t *testing.T, task *kernel.Task, stype linux.SockType) *Socket {
+	t.Helper()
+	fd, err := (&provider{}).Socket(task, stype, 0)
+	if err != nil {
+		t.Fatalf("Socket(%d) failed: %v", stype, err)
+	}
+	t.Cleanup(func() { fd.DecRef(task) })
+	return fd.Impl().(*Socket)
+}
+
+// newListener returns a socket of type stype listening on port.
+func newListener(t *testing.T, task *kernel.Task, stype linux.SockType, port uint32) *Socket {
+	t.Helper()
+	s := newTestSocket(t, task, stype)
+	if err := s.Bind(task, vmAddr(linux.VMADDR_CID_ANY, port)); err != nil {
+		t.Fatalf("Bind(%d) failed: %v", port, err)
+	}
+	if err := s.Listen(task, 1); err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	return s
+}
+
+// accept accepts a connection on the listening socket l, waiting for one to
+// be queued if necessary.
+func accept(t *testing.T, task *kernel.Task, l *Socket) (*Socket, *linux.SockAddrVM) {
+	t.Helper()
+	e, ch := waiter.NewChannelEntry(waiter.ReadableEvents)
+	if err := l.EventRegister(&e); err != nil {
+		t.Fatalf("EventRegister() failed: %v", err)
+	}
+	defer l.EventUnregister(&e)
+	for {
+		kfd, peer, _, err := l.Accept(task, true /* peerRequested */, 0, false /* blocking */)
+		if err == syserr.ErrTryAgain {
+			select {
+			case <-ch:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for a connection")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Accept() failed: %v", err)
+		}
+		fd := task.FDTable().Remove(task, kfd)
+		t.Cleanup(func() { fd.DecRef(task) })
+		return fd.Impl().(*Socket), peer.(*linux.SockAddrVM)
+	}
+}
+
+// vmAddr returns the marshalled sockaddr_vm for cid:port.
+func vmAddr(cid, port uint32) []byte {
+	addr := linux.SockAddrVM{
+		Family: linux.AF_VSOCK,
+		Port:   port,
+		CID:    cid,
+	}
+	b := make([]byte, addr.SizeBytes())
+	addr.MarshalUnsafe(b)
+	return b
+}
+
+// write writes data to s.
+func write(t *testing.T, task *kernel.Task, s *Socket, data []byte) {
+	t.Helper()
+	n, err := s.Write(task, usermem.BytesIOSequence(data), vfs.WriteOptions{})
+	if err != nil || int(n) != len(data) {
+		t.Fatalf("Write(%q) = %d, %v, want %d, nil", data, n, err, len(data))
+	}
+}
+
+// readN reads n bytes from s, waiting for them to arrive if necessary.
+func readN(t *testing.T, task *kernel.Task, s *Socket, n int) []byte {
+	t.Helper()
+	e, ch := waiter.NewChannelEntry(waiter.ReadableEvents)
+	if err := s.EventRegister(&e); err != nil {
+		t.Fatalf("EventRegister() failed: %v", err)
+	}
+	defer s.EventUnregister(&e)
+	buf := make([]byte, n)
+	for got := 0; got < n; {
+		m, err := s.Read(task, usermem.BytesIOSequence(buf[got:]), vfs.ReadOptions{})
+		got += int(m)
+		if linuxerr.Equals(linuxerr.ErrWouldBlock, err) {
+			select {
+			case <-ch:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out reading %d bytes, got %q", n, buf[:got])
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Read() failed: %v", err)
+		}
+		if m == 0 {
+			t.Fatalf("Read() got EOF after %q", buf[:got])
+		}
+	}
+	return buf
+}
+
+// hostReadN reads n bytes from the blocking host socket fd.
+func hostReadN(t *testing.T, fd int, n int) []byte {
+	t.Helper()
+	buf := make([]byte, n)
+	for got := 0; got < n; {
+		m, err := unix.Read(fd, buf[got:])
+		if err != nil {
+			t.Fatalf("host read failed: %v", err)
+		}
+		if m == 0 {
+			t.Fatalf("host read got EOF after %q", buf[:got])
+		}
+		got += m
+	}
+	return buf
+}
+
+// hostListen returns a blocking host socket listening on path.
+func hostListen(t *testing.T, path string) int {
+	t.Helper()
+	fd, err := unix.Socket(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("unix.Socket() failed: %v", err)
+	}
+	t.Cleanup(func() { unix.Close(fd) })
+	if err := unix.Bind(fd, &unix.SockaddrUnix{Name: path}); err != nil {
+		t.Fatalf("unix.Bind(%q) failed: %v", path, err)
+	}
+	t.Cleanup(func() { os.Remove(path) })
+	if err := unix.Listen(fd, 1); err != nil {
+		t.Fatalf("unix.Listen() failed: %v", err)
+	}
+	return fd
+}
+
+// hostConnect connects a host process to the sandbox listener on port and
+// returns the host end of the connection and the port assigned to it.
+func hostConnect(t *testing.T, port uint32) (int, uint32) {
+	t.Helper()
+	fd, err := unix.Socket(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("unix.Socket() failed: %v", err)
+	}
+	t.Cleanup(func() { unix.Close(fd) })
+	if err := unix.Connect(fd, &unix.SockaddrUnix{Name: filepath.Join(testDir, testUDSName)}); err != nil {
+		t.Fatalf("unix.Connect() failed: %v", err)
+	}
+	if _, err := unix.Write(fd, []byte(fmt.Sprintf("CONNECT %d\n", port))); err != nil {
+		t.Fatalf("writing handshake failed: %v", err)
+	}
+	var line []byte
+	for {
+		b := hostReadN(t, fd, 1)
+		if b[0] == '\n' {
+			break
+		}
+		line = append(line, b[0])
+	}
+	var hostPort uint32
+	if _, err := fmt.Sscanf(string(line), "OK %d", &hostPort); err != nil {
+		t.Fatalf("bad handshake reply %q: %v", line, err)
+	}
+	return fd, hostPort
+}
+
+// waitConnected waits for a non-blocking connect(2) on s to complete and
+// returns the value of SO_ERROR.
+func waitConnected(t *testing.T, task *kernel.Task, s *Socket) int32 {
+	t.Helper()
+	e, ch := waiter.NewChannelEntry(waiter.WritableEvents | waiter.EventErr)
+	if err := s.EventRegister(&e); err != nil {
+		t.Fatalf("EventRegister() failed: %v", err)
+	}
+	defer s.EventUnregister(&e)
+	for s.Readiness(waiter.WritableEvents|waiter.EventErr) == 0 {
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for connect to complete")
+		}
+	}
+	v, err := s.GetSockOpt(task, linux.SOL_SOCKET, linux.SO_ERROR, 0, sizeOfInt32)
+	if err != nil {
+		t.Fatalf("GetSockOpt(SO_ERROR) failed: %v", err)
+	}
+	return int32(*v.(*primitive.Int32))
+}
+
+// simulateRestore puts s in the state it has after the sandbox is restored
+// from a checkpoint: its host socket and port reservation are gone, and
+// afterLoad has run.
+func simulateRestore(s *Socket) {
+	s.mu.Lock()
+	if s.fd >= 0 {
+		fdnotifier.RemoveFD(int32(s.fd))
+		_ = unix.Close(s.fd)
+	}
+	for _, pc := range s.pending {
+		_ = unix.Close(pc.fd)
+	}
+	s.pending = nil
+	if s.bound {
+		releasePort(s.localPort, s)
+	}
+	s.mu.Unlock()
+	s.afterLoad(context.Background())
+}
+
+func TestParseHandshake(t *testing.T) {
+	for _, tc := range []struct {
+		line    string
+		want    uint32
+		wantErr bool
+	}{
+		{line: "CONNECT 52", want: 52},
+		{line: "CONNECT 4294967294", want: 4294967294},
+		{line: "CONNECT  1024 ", want: 1024},
+		{line: "CONNECT", wantErr: true},
+		{line: "CONNECT 52 53", wantErr: true},
+		{line: "connect 52", wantErr: true},
+		{line: "CONNECT -1", wantErr: true},
+		{line: "CONNECT 4294967296", wantErr: true},
+		{line: "", wantErr: true},
+	} {
+		t.Run(tc.line, func(t *testing.T) {
+			got, err := parseHandshake(tc.line)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseHandshake(%q) = %d, want error", tc.line, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseHandshake(%q) failed: %v", tc.line, err)
+			}
+			if got != tc.want {
+				t.Errorf("parseHandshake(%q) = %d, want %d", tc.line, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReservePort(t *testing.T) {
+	a, b := &Socket{}, &Socket{}
+
+	port, err := reservePort(5000, a)
+	if err != nil || port != 5000 {
+		t.Fatalf("reservePort(5000) = %d, %v, want 5000, nil", port, err)
+	}
+	defer releasePort(5000, a)
+	if _, err := reservePort(5000, b); err != syserr.ErrPortInUse {
+		t.Errorf("reservePort(5000) for second socket got err %v, want %v", err, syserr.ErrPortInUse)
+	}
+
+	// Releasing a port held by another socket is a no-op.
+	releasePort(5000, b)
+	if got := lookupPort(5000); got != a {
+		t.Errorf("lookupPort(5000) = %p, want %p", got, a)
+	}
+
+	eph, err := reservePort(linux.VMADDR_PORT_ANY, b)
+	if err != nil {
+		t.Fatalf("reservePort(VMADDR_PORT_ANY) failed: %v", err)
+	}
+	defer releasePort(eph, b)
+	if eph <= linux.LAST_RESERVED_PORT || eph == linux.VMADDR_PORT_ANY || eph == 5000 {
+		t.Errorf("reservePort(VMADDR_PORT_ANY) = %d, want an unused unprivileged port", eph)
+	}
+}
+
+func TestExtractSockAddr(t *testing.T) {
+	addr := linux.SockAddrVM{
+		Family: linux.AF_VSOCK,
+		Port:   1234,
+		CID:    linux.VMADDR_CID_HOST,
+	}
+	b := make([]byte, addr.SizeBytes())
+	addr.MarshalUnsafe(b)
+
+	got, err := ExtractSockAddr(b)
+	if err != nil {
+		t.Fatalf("ExtractSockAddr failed: %v", err)
+	}
+	if *got != addr {
+		t.Errorf("ExtractSockAddr = %+v, want %+v", *got, addr)
+	}
+
+	if _, err := ExtractSockAddr(b[:linux.SockAddrVMSize-1]); err != syserr.ErrInvalidArgument {
+		t.Errorf("ExtractSockAddr of short address got err %v, want %v", err, syserr.ErrInvalidArgument)
+	}
+
+	addr.Family = linux.AF_UNIX
+	addr.MarshalUnsafe(b)
+	if _, err := ExtractSockAddr(b); err != syserr.ErrAddressFamilyNotSupported {
+		t.Errorf("ExtractSockAddr of AF_UNIX address got err %v, want %v", err, syserr.ErrAddressFamilyNotSupported)
+	}
+}
+
+func TestConnectHost(t *testing.T) {
+	task := newTask(t)
+	const port = 5001
+	lfd := hostListen(t, filepath.Join(testDir, fmt.Sprintf("%s_%d", testUDSName, port)))
+
+	s := newTestSocket(t, task, linux.SOCK_STREAM)
+	if err := s.Connect(task, vmAddr(linux.VMADDR_CID_HOST, port), true /* blocking */); err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	hfd, _, err := unix.Accept4(lfd, unix.SOCK_CLOEXEC)
+	if err != nil {
+		t.Fatalf("host accept failed: %v", err)
+	}
+	defer unix.Close(hfd)
+
+	if got := s.State(); got != linux.SS_CONNECTED {
+		t.Errorf("State() = %d, want %d", got, linux.SS_CONNECTED)
+	}
+	peer, _, serr := s.GetPeerName(task)
+	if serr != nil {
+		t.Fatalf("GetPeerName() failed: %v", serr)
+	}
+	if want := (&linux.SockAddrVM{Family: linux.AF_VSOCK, Port: port, CID: linux.VMADDR_CID_HOST}); *peer.(*linux.SockAddrVM) != *want {
+		t.Errorf("GetPeerName() = %+v, want %+v", peer, want)
+	}
+
+	write(t, task, s, []byte("ping"))
+	if got := hostReadN(t, hfd, 4); !bytes.Equal(got, []byte("ping")) {
+		t.Errorf("host read %q, want %q", got, "ping")
+	}
+	if _, err := unix.Write(hfd, []byte("pong")); err != nil {
+		t.Fatalf("host write failed: %v", err)
+	}
+	if got := readN(t, task, s, 4); !bytes.Equal(got, []byte("pong")) {
+		t.Errorf("read %q, want %q", got, "pong")
+	}
+
+	// Nobody listens on the host for other ports.
+	s2 := newTestSocket(t, task, linux.SOCK_STREAM)
+	if err := s2.Connect(task, vmAddr(linux.VMADDR_CID_HOST, port+1), true /* blocking */); err != syserr.ErrConnectionReset {
+		t.Errorf("Connect() to port without host listener got err %v, want %v", err, syserr.ErrConnectionReset)
+	}
+}
+
+func TestConnectHostNonBlocking(t *testing.T) {
+	task := newTask(t)
+	const port = 5008
+	lfd := hostListen(t, filepath.Join(testDir, fmt.Sprintf("%s_%d", testUDSName, port)))
+
+	s := newTestSocket(t, task, linux.SOCK_STREAM)
+	if err := s.Connect(task, vmAddr(linux.VMADDR_CID_HOST, port), false /* blocking */); err != syserr.ErrInProgress {
+		t.Fatalf("Connect() got err %v, want %v", err, syserr.ErrInProgress)
+	}
+	if got := waitConnected(t, task, s); got != 0 {
+		t.Fatalf("SO_ERROR = %d, want 0", got)
+	}
+	if got := s.State(); got != linux.SS_CONNECTED {
+		t.Errorf("State() = %d, want %d", got, linux.SS_CONNECTED)
+	}
+	hfd, _, err := unix.Accept4(lfd, unix.SOCK_CLOEXEC)
+	if err != nil {
+		t.Fatalf("host accept failed: %v", err)
+	}
+	defer unix.Close(hfd)
+	write(t, task, s, []byte("ping"))
+	if got := hostReadN(t, hfd, 4); !bytes.Equal(got, []byte("ping")) {
+		t.Errorf("host read %q, want %q", got, "ping")
+	}
+
+	// A failed connection is reported through SO_ERROR, once.
+	s2 := newTestSocket(t, task, linux.SOCK_STREAM)
+	if err := s2.Connect(task, vmAddr(linux.VMADDR_CID_HOST, port+1), false /* blocking */); err != syserr.ErrInProgress {
+		t.Fatalf("Connect() got err %v, want %v", err, syserr.ErrInProgress)
+	}
+	if got, want := waitConnected(t, task, s2), int32(unix.ECONNRESET); got != want {
+		t.Errorf("SO_ERROR = %d, want %d", got, want)
+	}
+	if got := s2.State(); got != linux.SS_UNCONNECTED {
+		t.Errorf("State() = %d, want %d", got, linux.SS_UNCONNECTED)
+	}
+	v, serr := s2.GetSockOpt(task, linux.SOL_SOCKET, linux.SO_ERROR, 0, sizeOfInt32)
+	if serr != nil || *v.(*primitive.Int32) != 0 {
+		t.Errorf("second GetSockOpt(SO_ERROR) = %v, %v, want 0, nil", v, serr)
+	}
+}
+
+func TestServeBrokerMalformedRequest(t *testing.T) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("unix.Socketpair() failed: %v", err)
+	}
+	defer unix.Close(fds[0])
+	defer unix.Close(fds[1])
+	if _, err := unix.Write(fds[0], []byte("CONNECT 52")); err != nil {
+		t.Fatalf("unix.Write() failed: %v", err)
+	}
+	if err := ServeBroker(fds[1], filepath.Join(testDir, testUDSName)); err == nil {
+		t.Errorf("ServeBroker() succeeded with a malformed request, want error")
+	}
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	task := newTask(t)
+	const port = 5009
+	newListener(t, task, linux.SOCK_STREAM, port)
+
+	old := handshakeTimeout
+	handshakeTimeout = 100 * time.Millisecond
+	defer func() { handshakeTimeout = old }()
+
+	fd, err := unix.Socket(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("unix.Socket() failed: %v", err)
+	}
+	defer unix.Close(fd)
+	if err := unix.Connect(fd, &unix.SockaddrUnix{Name: filepath.Join(testDir, testUDSName)}); err != nil {
+		t.Fatalf("unix.Connect() failed: %v", err)
+	}
+	// Without a handshake, the connection is closed once the timeout
+	// expires.
+	tv := unix.NsecToTimeval((5 * time.Second).Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		t.Fatalf("setting SO_RCVTIMEO failed: %v", err)
+	}
+	if n, err := unix.Read(fd, make([]byte, 1)); n != 0 || err != nil {
+		t.Errorf("host read = %d, %v, want EOF", n, err)
+	}
+}
+
+func TestHostConnectAccept(t *testing.T) {
+	task := newTask(t)
+	const port = 5002
+	l := newListener(t, task, linux.SOCK_STREAM, port)
+
+	hfd, hostPort := hostConnect(t, port)
+	s, peer := accept(t, task, l)
+	if want := (linux.SockAddrVM{Family: linux.AF_VSOCK, Port: hostPort, CID: linux.VMADDR_CID_HOST}); *peer != want {
+		t.Errorf("Accept() peer = %+v, want %+v", *peer, want)
+	}
+
+	if _, err := unix.Write(hfd, []byte("hello")); err != nil {
+		t.Fatalf("host write failed: %v", err)
+	}
+	if got := readN(t, task, s, 5); !bytes.Equal(got, []byte("hello")) {
+		t.Errorf("read %q, want %q", got, "hello")
+	}
+	write(t, task, s, []byte("world"))
+	if got := hostReadN(t, hfd, 5); !bytes.Equal(got, []byte("world")) {
+		t.Errorf("host read %q, want %q", got, "world")
+	}
+}
+
+func TestLocalConnectAccept(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		stype linux.SockType
+		cid   uint32
+		port  uint32
+	}{
+		{name: "stream", stype: linux.SOCK_STREAM, cid: testCID, port: 5003},
+		{name: "stream local CID", stype: linux.SOCK_STREAM, cid: linux.VMADDR_CID_LOCAL, port: 5004},
+		{name: "seqpacket", stype: linux.SOCK_SEQPACKET, cid: testCID, port: 5005},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			task := newTask(t)
+			l := newListener(t, task, test.stype, test.port)
+
+			c := newTestSocket(t, task, test.stype)
+			if err := c.Connect(task, vmAddr(test.cid, test.port), true /* blocking */); err != nil {
+				t.Fatalf("Connect() failed: %v", err)
+			}
+			s, peer := accept(t, task, l)
+			name, _, _ := c.GetSockName(task)
+			if want := (linux.SockAddrVM{Family: linux.AF_VSOCK, Port: name.(*linux.SockAddrVM).Port, CID: testCID}); *peer != want {
+				t.Errorf("Accept() peer = %+v, want %+v", *peer, want)
+			}
+
+			write(t, task, c, []byte("request"))
+			if got := readN(t, task, s, 7); !bytes.Equal(got, []byte("request")) {
+				t.Errorf("read %q, want %q", got, "request")
+			}
+			write(t, task, s, []byte("reply"))
+			if got := readN(t, task, c, 5); !bytes.Equal(got, []byte("reply")) {
+				t.Errorf("read %q, want %q", got, "reply")
+			}
+
+			// Connections are only accepted by listeners of the same type.
+			other := linux.SOCK_STREAM
+			if test.stype == linux.SOCK_STREAM {
+				other = linux.SOCK_SEQPACKET
+			}
+			c2 := newTestSocket(t, task, other)
+			if err := c2.Connect(task, vmAddr(test.cid, test.port), true /* blocking */); err != syserr.ErrConnectionReset {
+				t.Errorf("Connect() with type %d got err %v, want %v", other, err, syserr.ErrConnectionReset)
+			}
+		})
+	}
+}
+
+func TestSaveRestore(t *testing.T) {
+	task := newTask(t)
+	const port = 5006
+	l := newListener(t, task, linux.SOCK_STREAM, port)
+	c := newTestSocket(t, task, linux.SOCK_STREAM)
+	if err := c.Connect(task, vmAddr(testCID, port), true /* blocking */); err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	s, _ := accept(t, task, l)
+
+	for _, sock := range []*Socket{l, c, s} {
+		simulateRestore(sock)
+	}
+
+	// Established connections are reset.
+	for _, sock := range []*Socket{c, s} {
+		if _, err := sock.Read(task, usermem.BytesIOSequence(make([]byte, 1)), vfs.ReadOptions{}); !linuxerr.Equals(linuxerr.ECONNRESET, err) {
+			t.Errorf("Read() after restore got err %v, want %v", err, linuxerr.ECONNRESET)
+		}
+		if _, err := sock.Write(task, usermem.BytesIOSequence([]byte("x")), vfs.WriteOptions{}); !linuxerr.Equals(linuxerr.ECONNRESET, err) {
+			t.Errorf("Write() after restore got err %v, want %v", err, linuxerr.ECONNRESET)
+		}
+		if got := sock.Readiness(waiter.EventHUp); got != waiter.EventHUp {
+			t.Errorf("Readiness(EventHUp) after restore = %v, want %v", got, waiter.EventHUp)
+		}
+		if _, _, err := sock.GetPeerName(task); err != syserr.ErrNotConnected {
+			t.Errorf("GetPeerName() after restore got err %v, want %v", err, syserr.ErrNotConnected)
+		}
+	}
+
+	// The listener keeps its port and accepts new connections.
+	if got := lookupPort(port); got != l {
+		t.Fatalf("lookupPort(%d) after restore = %p, want %p", port, got, l)
+	}
+	c2 := newTestSocket(t, task, linux.SOCK_STREAM)
+	if err := c2.Connect(task, vmAddr(testCID, port), true /* blocking */); err != nil {
+		t.Fatalf("Connect() after restore failed: %v", err)
+	}
+	s2, _ := accept(t, task, l)
+	write(t, task, c2, []byte("again"))
+	if got := readN(t, task, s2, 5); !bytes.Equal(got, []byte("again")) {
+		t.Errorf("read %q, want %q", got, "again")
+	}
+}
+
+func TestRestorePortInUse(t *testing.T) {
+	task := newTask(t)
+	const port = 5007
+	s := newTestSocket(t, task, linux.SOCK_STREAM)
+	if err := s.Bind(task, vmAddr(linux.VMADDR_CID_ANY, port)); err != nil {
+		t.Fatalf("Bind(%d) failed: %v", port, err)
+	}
+
+	// Take the port while s is "saved", as a socket restored earlier
+	// might.
+	releasePort(port, s)
+	other := &Socket{}
+	if _, err := reservePort(port, other); err != nil {
+		t.Fatalf("reservePort(%d) failed: %v", port, err)
+	}
+	defer releasePort(port, other)
+	s.afterLoad(context.Background())
+
+	s.mu.Lock()
+	bound := s.bound
+	s.mu.Unlock()
+	if bound {
+		t.Errorf("socket is still bound after restore with port %d in use", port)
+	}
+	if got := lookupPort(port); got != other {
+		t.Errorf("lookupPort(%d) = %p, want %p", port, got, other)
+	}
+}
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "vsock-test-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "creating test directory: %v\n", err)
+		os.Exit(1)
+	}
+	testDir = dir
+	if err := initTestConfig(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "initializing vsock: %v\n", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,60 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsock
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+)
+
+func recvmsg(fd int, iovs []unix.Iovec, flags int) (uint64, int, error) {
+	var msg unix.Msghdr
+	if len(iovs) > 0 {
+		msg.Iov = &iovs[0]
+		msg.Iovlen = uint64(len(iovs))
+	}
+	n, _, errno := unix.Syscall(unix.SYS_RECVMSG, uintptr(fd), uintptr(unsafe.Pointer(&msg)), uintptr(flags))
+	if errno != 0 {
+		return 0, 0, translateIOSyscallError(errno)
+	}
+	return uint64(n), int(msg.Flags), nil
+}
+
+func sendmsg(fd int, iovs []unix.Iovec, flags int) (uint64, error) {
+	var msg unix.Msghdr
+	if len(iovs) > 0 {
+		msg.Iov = &iovs[0]
+		msg.Iovlen = uint64(len(iovs))
+	}
+	n, _, errno := unix.Syscall(unix.SYS_SENDMSG, uintptr(fd), uintptr(unsafe.Pointer(&msg)), uintptr(flags))
+	if errno != 0 {
+		return 0, translateIOSyscallError(errno)
+	}
+	return uint64(n), nil
+}
+
+func translateIOSyscallError(err error) error {
+	if err == unix.EAGAIN || err == unix.EWOULDBLOCK {
+		return linuxerr.ErrWouldBlock
+	}
+	if err == unix.EPIPE {
+		// The host connection is gone; report it as a reset, like a
+		// virtio transport would.
+		return linuxerr.ECONNRESET
+	}
+	return err
+}
@@ -60,6 +60,10 @@ type SaveOpts struct {
 	// Key is used for state integrity check.
 	Key []byte
 
+	// If EncryptionKey is not nil, the state file, pages metadata and pages
+	// are encrypted with it. See statefile.NewEncryptedWriter.
+	EncryptionKey []byte
+
 	// Metadata is save metadata.
 	Metadata map[string]string
 
@@ -96,27 +100,51 @@ func (opts SaveOpts) Save(ctx context.Context, k *kernel.Kernel, w *watchdog.Wat
 	addSaveMetadata(opts.Metadata)
 
 	// Open the statefile.
-	wc, err := statefile.NewWriter(opts.Destination, opts.Key, opts.Metadata)
+	wc, enc, err := statefile.NewEncryptedWriter(opts.Destination, opts.Key, opts.EncryptionKey, opts.Metadata)
 	if err != nil {
 		err = ErrStateFile{err}
 	} else {
-		var pagesMetadata io.Writer
+		var (
+			pagesMetadata    io.Writer
+			pagesMetadataEnc io.WriteCloser
+		)
 		if opts.PagesMetadata != nil {
+			pagesMetadata = opts.PagesMetadata
+			if enc != nil {
+				// Pages are only encrypted by MemoryFile.SaveTo() if they're
+				// stored separately; otherwise, they're encrypted along with
+				// the state file.
+				pagesMetadataEnc, err = enc.NewStreamWriter(pagesMetadata, statefile.EncryptionPurposePagesMetadata)
+				if err == nil {
+					pagesMetadata = pagesMetadataEnc
+					opts.MemoryFileSaveOpts.PagesAEAD, err = enc.PagesAEAD(opts.MemoryFileSaveOpts.SaveID)
+				}
+			}
+			if err == nil && opts.MemoryFileSaveOpts.ChunkStore == nil {
+				// Pages saved to a chunk store are compressed by the
+				// chunk store.
+				opts.MemoryFileSaveOpts.PagesCodec, err = statefile.PagesCodec(opts.Metadata)
+			}
+			if err != nil {
+				err = ErrStateFile{err}
+			}
 			// //pkg/state/wire writes one byte at a time; buffer these writes
 			// to avoid making one syscall per write. For the "main" state
 			// file, this buffering is handled by statefile.NewWriter() =>
 			// compressio.Writer or compressio.NewSimpleWriter().
-			pagesMetadata = bufio.NewWriter(opts.PagesMetadata)
+			pagesMetadata = bufio.NewWriter(pagesMetadata)
 		}
 
-		// Save the kernel.
-		err = k.SaveTo(ctx, wc, pagesMetadata, opts.PagesFile, opts.MemoryFileSaveOpts)
+		if err == nil {
+			// Save the kernel.
+			err = k.SaveTo(ctx, wc, pagesMetadata, opts.PagesFile, opts.MemoryFileSaveOpts)
 
-		// ENOSPC is a state file error. This error can only come from
-		// writing the state file, and not from fs.FileOperations.Fsync
-		// because we wrap those in kernel.TaskSet.flushWritesToFiles.
-		if linuxerr.Equals(linuxerr.ENOSPC, err) {
-			err = ErrStateFile{err}
+			// ENOSPC is a state file error. This error can only come from
+			// writing the state file, and not from fs.FileOperations.Fsync
+			// because we wrap those in kernel.TaskSet.flushWritesToFiles.
+			if linuxerr.Equals(linuxerr.ENOSPC, err) {
+				err = ErrStateFile{err}
+			}
 		}
 
 		if closeErr := wc.Close(); err == nil && closeErr != nil {
@@ -127,6 +155,11 @@ func (opts SaveOpts) Save(ctx context.Context, k *kernel.Kernel, w *watchdog.Wat
 				err = ErrStateFile{flushErr}
 			}
 		}
+		if pagesMetadataEnc != nil {
+			if closeErr := pagesMetadataEnc.Close(); err == nil && closeErr != nil {
+				err = ErrStateFile{closeErr}
+			}
+		}
 	}
 
 	t1, _ := CPUTime()
@@ -150,10 +183,19 @@ func (opts SaveOpts) Save(ctx context.Context, k *kernel.Kernel, w *watchdog.Wat
 // NewStatefileReader returns the statefile's metadata and a reader for it.
 // The ownership of source is transferred to the returned reader.
 func NewStatefileReader(source io.ReadCloser, key []byte) (io.ReadCloser, map[string]string, error) {
-	r, m, err := statefile.NewReader(source, key)
+	r, m, _, err := NewEncryptedStatefileReader(source, key, nil)
+	return r, m, err
+}
+
+// NewEncryptedStatefileReader is equivalent to NewStatefileReader, but also
+// decrypts the statefile with encryptionKey. It returns the
+// statefile.Encryption used to decrypt the pages metadata and pages files,
+// which is nil if encryptionKey is nil.
+func NewEncryptedStatefileReader(source io.ReadCloser, key, encryptionKey []byte) (io.ReadCloser, map[string]string, *statefile.Encryption, error) {
+	r, m, enc, err := statefile.NewEncryptedReader(source, key, encryptionKey)
 	if err != nil {
-		return nil, nil, ErrStateFile{err}
+		return nil, nil, nil, ErrStateFile{err}
 	}
 	previousMetadata = m
-	return r, m, nil
+	return r, m, enc, nil
 }
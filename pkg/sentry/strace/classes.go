@@ -0,0 +1,145 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package strace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// classPrefix is the prefix that distinguishes syscall classes from syscall
+// names in allowlists, e.g. "%net".
+const classPrefix = "%"
+
+// syscallClasses maps syscall classes to the syscalls that belong to them,
+// following strace(1) "-e trace=%class" naming. Syscalls that don't exist in
+// a given architecture are ignored.
+var syscallClasses = map[string][]string{
+	// file contains syscalls that take a file name as argument.
+	"file": {
+		"access", "acct", "chdir", "chmod", "chown", "chroot", "creat",
+		"execve", "execveat", "faccessat", "faccessat2", "fanotify_mark",
+		"fchmodat", "fchownat", "fsconfig", "fspick", "futimesat",
+		"getxattr", "inotify_add_watch", "lchown", "lgetxattr", "link",
+		"linkat", "listxattr", "llistxattr", "lremovexattr", "lsetxattr",
+		"lstat", "mkdir", "mkdirat", "mknod", "mknodat", "mount",
+		"move_mount", "name_to_handle_at", "newfstatat", "open", "open_tree",
+		"openat", "openat2", "pivot_root", "quotactl", "readlink",
+		"readlinkat", "removexattr", "rename", "renameat", "renameat2",
+		"rmdir", "setxattr", "stat", "statfs", "statx", "swapoff", "swapon",
+		"symlink", "symlinkat", "truncate", "umount2", "unlink", "unlinkat",
+		"uselib", "utime", "utimensat", "utimes",
+	},
+	// desc contains syscalls that take or return a file descriptor.
+	"desc": {
+		"close", "close_range", "copy_file_range", "dup", "dup2", "dup3",
+		"epoll_create", "epoll_create1", "epoll_ctl", "epoll_pwait",
+		"epoll_pwait2", "epoll_wait", "eventfd", "eventfd2", "fadvise64",
+		"fallocate", "fchdir", "fchmod", "fchown", "fcntl", "fdatasync",
+		"fgetxattr", "flistxattr", "flock", "fremovexattr", "fsetxattr",
+		"fstat", "fstatfs", "fsync", "ftruncate", "getdents", "getdents64",
+		"inotify_init", "inotify_init1", "io_uring_enter",
+		"io_uring_register", "io_uring_setup", "ioctl", "lseek",
+		"memfd_create", "pidfd_open", "pipe", "pipe2", "poll", "ppoll",
+		"pread64", "preadv", "preadv2", "pselect6", "pwrite64", "pwritev",
+		"pwritev2", "read", "readahead", "readv", "select", "sendfile",
+		"signalfd", "signalfd4", "splice", "sync_file_range", "syncfs",
+		"tee", "timerfd_create", "timerfd_gettime", "timerfd_settime",
+		"vmsplice", "write", "writev",
+	},
+	// network contains network related syscalls.
+	"network": {
+		"accept", "accept4", "bind", "connect", "getpeername",
+		"getsockname", "getsockopt", "listen", "recvfrom", "recvmmsg",
+		"recvmsg", "sendmmsg", "sendmsg", "sendto", "setsockopt",
+		"shutdown", "socket", "socketpair",
+	},
+	// process contains process management syscalls.
+	"process": {
+		"clone", "clone3", "execve", "execveat", "exit", "exit_group",
+		"fork", "kill", "pidfd_open", "pidfd_send_signal", "rt_sigqueueinfo",
+		"rt_tgsigqueueinfo", "tgkill", "tkill", "unshare", "vfork", "wait4",
+		"waitid",
+	},
+	// signal contains signal related syscalls.
+	"signal": {
+		"kill", "pause", "pidfd_send_signal", "rt_sigaction",
+		"rt_sigpending", "rt_sigprocmask", "rt_sigqueueinfo",
+		"rt_sigreturn", "rt_sigsuspend", "rt_sigtimedwait",
+		"rt_tgsigqueueinfo", "sigaltstack", "signalfd", "signalfd4",
+		"tgkill", "tkill",
+	},
+	// ipc contains SysV IPC related syscalls.
+	"ipc": {
+		"msgctl", "msgget", "msgrcv", "msgsnd", "semctl", "semget", "semop",
+		"semtimedop", "shmat", "shmctl", "shmdt", "shmget",
+	},
+	// memory contains memory mapping related syscalls.
+	"memory": {
+		"brk", "get_mempolicy", "madvise", "mbind", "membarrier", "mincore",
+		"mlock", "mlock2", "mlockall", "mmap", "mprotect", "mremap", "msync",
+		"munlock", "munlockall", "munmap", "pkey_alloc", "pkey_free",
+		"pkey_mprotect", "remap_file_pages", "set_mempolicy",
+	},
+}
+
+// classAliases maps alternative class names to the names in syscallClasses.
+var classAliases = map[string]string{
+	"net": "network",
+}
+
+// expandAllowlist replaces syscall classes in allowlist with the syscalls
+// that belong to them and exist in s.
+func (s SyscallMap) expandAllowlist(allowlist []string) ([]string, error) {
+	if allowlist == nil {
+		return nil, nil
+	}
+	expanded := make([]string, 0, len(allowlist))
+	for _, name := range allowlist {
+		class, ok := strings.CutPrefix(name, classPrefix)
+		if !ok {
+			expanded = append(expanded, name)
+			continue
+		}
+		if alias, ok := classAliases[class]; ok {
+			class = alias
+		}
+		syscalls, ok := syscallClasses[class]
+		if !ok {
+			return nil, fmt.Errorf("unknown syscall class %q, valid classes are: %s", name, strings.Join(SyscallClasses(), ", "))
+		}
+		for _, sc := range syscalls {
+			if _, ok := s.ConvertToSysno(sc); ok {
+				expanded = append(expanded, sc)
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// SyscallClasses returns the names of all syscall classes that can be used in
+// allowlists, including the class prefix.
+func SyscallClasses() []string {
+	classes := make([]string, 0, len(syscallClasses)+len(classAliases))
+	for class := range syscallClasses {
+		classes = append(classes, classPrefix+class)
+	}
+	for alias := range classAliases {
+		classes = append(classes, classPrefix+alias)
+	}
+	sort.Strings(classes)
+	return classes
+}
@@ -0,0 +1,142 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package strace
+
+import (
+	"slices"
+	"sort"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+)
+
+func hostSyscallMap(t *testing.T) SyscallMap {
+	t.Helper()
+	s, ok := Lookup(abi.Linux, arch.Host)
+	if !ok {
+		t.Fatalf("no syscall map for %v", arch.Host)
+	}
+	return s
+}
+
+func TestExpandAllowlist(t *testing.T) {
+	s := hostSyscallMap(t)
+	network := []string{
+		"accept", "accept4", "bind", "connect", "getpeername",
+		"getsockname", "getsockopt", "listen", "recvfrom", "recvmmsg",
+		"recvmsg", "sendmmsg", "sendmsg", "sendto", "setsockopt",
+		"shutdown", "socket", "socketpair",
+	}
+	for _, tc := range []struct {
+		name      string
+		allowlist []string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name:      "empty",
+			allowlist: []string{},
+			want:      []string{},
+		},
+		{
+			name:      "syscalls",
+			allowlist: []string{"read", "write"},
+			want:      []string{"read", "write"},
+		},
+		{
+			name:      "class",
+			allowlist: []string{"%ipc"},
+			want: []string{
+				"msgctl", "msgget", "msgrcv", "msgsnd", "semctl", "semget",
+				"semop", "semtimedop", "shmat", "shmctl", "shmdt", "shmget",
+			},
+		},
+		{
+			name:      "class_and_syscalls",
+			allowlist: []string{"read", "%network", "write"},
+			want:      slices.Concat([]string{"read"}, network, []string{"write"}),
+		},
+		{
+			name:      "alias",
+			allowlist: []string{"%net"},
+			want:      network,
+		},
+		{
+			name:      "unknown_class",
+			allowlist: []string{"read", "%bogus"},
+			wantErr:   true,
+		},
+		{
+			name:      "empty_class",
+			allowlist: []string{"%"},
+			wantErr:   true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := s.expandAllowlist(tc.allowlist)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expandAllowlist(%q) succeeded with %q, want error", tc.allowlist, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandAllowlist(%q) failed: %v", tc.allowlist, err)
+			}
+			if (got == nil) != (tc.want == nil) || !slices.Equal(got, tc.want) {
+				t.Errorf("expandAllowlist(%q) = %q, want %q", tc.allowlist, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestExpandAllowlistArch checks that classes only expand to syscalls that
+// exist in the syscall map.
+func TestExpandAllowlistArch(t *testing.T) {
+	s := hostSyscallMap(t)
+	for class, syscalls := range syscallClasses {
+		t.Run(class, func(t *testing.T) {
+			got, err := s.expandAllowlist([]string{classPrefix + class})
+			if err != nil {
+				t.Fatalf("expandAllowlist failed: %v", err)
+			}
+			for _, sc := range syscalls {
+				_, exists := s.ConvertToSysno(sc)
+				if slices.Contains(got, sc) != exists {
+					t.Errorf("expandAllowlist returned %q, which contains %q: %t, want %t", got, sc, !exists, exists)
+				}
+			}
+			if len(got) == 0 {
+				t.Errorf("class %q has no syscalls on %v", class, arch.Host)
+			}
+		})
+	}
+}
+
+func TestSyscallClasses(t *testing.T) {
+	classes := SyscallClasses()
+	if !sort.StringsAreSorted(classes) {
+		t.Errorf("SyscallClasses() = %q, want sorted", classes)
+	}
+	for _, class := range []string{"%desc", "%file", "%ipc", "%memory", "%net", "%network", "%process", "%signal"} {
+		if !slices.Contains(classes, class) {
+			t.Errorf("SyscallClasses() = %q, want it to contain %q", classes, class)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package strace
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/socket"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink"
+	sunix "gvisor.dev/gvisor/pkg/sentry/socket/unix"
+	"gvisor.dev/gvisor/pkg/sentry/socket/vsock"
+	slinux "gvisor.dev/gvisor/pkg/sentry/syscalls/linux"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// JSONOptions configures the JSON strace output.
+type JSONOptions struct {
+	// PIDs restricts tracing to the given processes, identified by their
+	// thread group ID in the root PID namespace. Empty means all processes.
+	PIDs []int32
+
+	// ContainerIDs restricts tracing to tasks that belong to the given
+	// containers. Empty means all containers.
+	ContainerIDs []string
+
+	// MaxDataSize is the maximum number of bytes of application data, e.g.
+	// read and write buffers, included with each argument. Application data
+	// is only included if LogAppDataAllowed is set.
+	MaxDataSize uint
+}
+
+var (
+	// jsonMu protects the fields below and serializes writes to jsonOutput.
+	jsonMu sync.Mutex
+
+	// jsonOutput receives the JSON records, one per line.
+	//
+	// +checklocks:jsonMu
+	jsonOutput io.WriteCloser
+
+	// jsonOptions are the options set with jsonOutput.
+	//
+	// +checklocks:jsonMu
+	jsonOptions JSONOptions
+)
+
+// jsonLogger logs errors writing to jsonOutput.
+var jsonLogger = log.BasicRateLimitedLogger(time.Minute)
+
+// jsonRecord is written for every traced syscall once it returns.
+type jsonRecord struct {
+	Time        time.Time `json:"time"`
+	PID         int32     `json:"pid"`
+	TID         int32     `json:"tid"`
+	ContainerID string    `json:"container_id,omitempty"`
+	Comm        string    `json:"comm"`
+	Syscall     string    `json:"syscall"`
+	Sysno       uintptr   `json:"sysno"`
+	Args        []jsonArg `json:"args"`
+	Return      int64     `json:"return"`
+	Errno       int       `json:"errno,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationNs  int64     `json:"duration_ns"`
+}
+
+// jsonArg is a syscall argument. Value is the decoded argument, which is a
+// string for arguments that are not decoded into a structure, and is omitted
+// for arguments that are not decoded at all.
+type jsonArg struct {
+	Raw   string `json:"raw"`
+	Value any    `json:"value,omitempty"`
+}
+
+// jsonFD is a decoded file descriptor argument.
+type jsonFD struct {
+	FD   int32  `json:"fd"`
+	Path string `json:"path,omitempty"`
+	Bad  bool   `json:"bad,omitempty"`
+}
+
+// jsonBuffer is a decoded buffer of application data.
+type jsonBuffer struct {
+	Base      string `json:"base"`
+	Len       uint64 `json:"len"`
+	Data      []byte `json:"data,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// jsonSockAddr is a decoded socket address.
+type jsonSockAddr struct {
+	Family string `json:"family"`
+	Addr   string `json:"addr,omitempty"`
+	Port   uint16 `json:"port,omitempty"`
+	PortID uint32 `json:"port_id,omitempty"`
+	Groups uint32 `json:"groups,omitempty"`
+	CID    uint32 `json:"cid,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// jsonError is used for arguments that failed to be decoded.
+type jsonError struct {
+	Error string `json:"error"`
+}
+
+// SetJSONOutput sets the destination for syscalls traced with SinkTypeJSON,
+// closing the previous one. If w is nil, JSON records are discarded.
+func SetJSONOutput(w io.WriteCloser, opts JSONOptions) {
+	jsonMu.Lock()
+	defer jsonMu.Unlock()
+	if jsonOutput != nil {
+		_ = jsonOutput.Close()
+	}
+	jsonOutput = w
+	jsonOptions = opts
+}
+
+// EnableJSON sets the JSON output and enables the syscalls in allowlist to be
+// traced to it, or all syscalls if allowlist is empty.
+//
+// Preconditions: Initialize has been called.
+func EnableJSON(allowlist []string, w io.WriteCloser, opts JSONOptions) error {
+	SetJSONOutput(w, opts)
+	if len(allowlist) == 0 {
+		EnableAll(SinkTypeJSON)
+		return nil
+	}
+	if err := Enable(allowlist, SinkTypeJSON); err != nil {
+		DisableJSON()
+		return err
+	}
+	return nil
+}
+
+// DisableJSON disables JSON tracing for all syscalls and closes the JSON
+// output.
+//
+// Preconditions: Initialize has been called.
+func DisableJSON() {
+	Disable(SinkTypeJSON)
+	SetJSONOutput(nil, JSONOptions{})
+}
+
+// jsonMatch returns whether t matches the JSON output filters, and the
+// maximum data size to decode.
+func jsonMatch(t *kernel.Task) (uint, bool) {
+	jsonMu.Lock()
+	defer jsonMu.Unlock()
+	if jsonOutput == nil {
+		return 0, false
+	}
+	if len(jsonOptions.ContainerIDs) > 0 && !slices.Contains(jsonOptions.ContainerIDs, t.ContainerID()) {
+		return 0, false
+	}
+	if len(jsonOptions.PIDs) > 0 {
+		pid := int32(t.Kernel().RootPIDNamespace().IDOfThreadGroup(t.ThreadGroup()))
+		if !slices.Contains(jsonOptions.PIDs, pid) {
+			return 0, false
+		}
+	}
+	return jsonOptions.MaxDataSize, true
+}
+
+// writeJSON writes rec to the JSON output.
+func writeJSON(rec *jsonRecord) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		jsonLogger.Warningf("Failed to marshal strace record: %v", err)
+		return
+	}
+	b = append(b, '\n')
+
+	jsonMu.Lock()
+	defer jsonMu.Unlock()
+	if jsonOutput == nil {
+		return
+	}
+	if _, err := jsonOutput.Write(b); err != nil {
+		jsonLogger.Warningf("Failed to write strace record: %v", err)
+	}
+}
+
+// flagList splits flags formatted by abi.FlagSet.Parse into a list.
+func flagList(flags string) []string {
+	if len(flags) == 0 {
+		return []string{}
+	}
+	return strings.Split(flags, "|")
+}
+
+func jsonFDArg(t *kernel.Task, fd int32) jsonFD {
+	name, ok := fdName(t, fd)
+	return jsonFD{FD: fd, Path: name, Bad: !ok}
+}
+
+func jsonPath(t *kernel.Task, addr hostarch.Addr) any {
+	if addr == 0 {
+		return nil
+	}
+	path, err := t.CopyInString(addr, linux.PATH_MAX)
+	if err != nil {
+		return jsonError{Error: err.Error()}
+	}
+	return path
+}
+
+func jsonDump(t *kernel.Task, addr hostarch.Addr, size uint64, maxSize uint64, content bool) jsonBuffer {
+	buf := jsonBuffer{Base: hexNum(uint64(addr)), Len: size}
+	if !content || size == 0 || maxSize == 0 {
+		return buf
+	}
+	if size > maxSize {
+		size = maxSize
+		buf.Truncated = true
+	}
+	b := make([]byte, size)
+	amt, err := t.CopyInBytes(addr, b)
+	buf.Data = b[:amt]
+	if err != nil {
+		buf.Error = err.Error()
+	}
+	return buf
+}
+
+func jsonIovecs(t *kernel.Task, addr hostarch.Addr, iovcnt int, content bool, maxSize uint64) any {
+	if iovcnt < 0 || iovcnt > linux.UIO_MAXIOV {
+		return jsonError{Error: "invalid iovcnt"}
+	}
+	ars, err := t.CopyInIovecs(addr, iovcnt)
+	if err != nil {
+		return jsonError{Error: err.Error()}
+	}
+
+	iovs := make([]jsonBuffer, 0, iovcnt)
+	for ; !ars.IsEmpty(); ars = ars.Tail() {
+		ar := ars.Head()
+		iov := jsonDump(t, ar.Start, uint64(ar.Length()), maxSize, content)
+		maxSize -= uint64(len(iov.Data))
+		iovs = append(iovs, iov)
+	}
+	return iovs
+}
+
+func jsonSockAddrArg(t *kernel.Task, addr hostarch.Addr, length uint32) any {
+	if addr == 0 {
+		return nil
+	}
+
+	b, err := slinux.CaptureAddress(t, addr, length)
+	if err != nil {
+		return jsonError{Error: err.Error()}
+	}
+	if len(b) < 2 {
+		return jsonError{Error: "address too short"}
+	}
+	family := hostarch.ByteOrder.Uint16(b)
+
+	sa := jsonSockAddr{Family: SocketFamily.Parse(uint64(family))}
+	switch family {
+	case linux.AF_INET, linux.AF_INET6, linux.AF_PACKET:
+		fa, _, err := socket.AddressAndFamily(b)
+		if err != nil {
+			sa.Error = err.String()
+			break
+		}
+		sa.Addr = fa.Addr.String()
+		sa.Port = fa.Port
+	case linux.AF_UNIX:
+		fa, _, err := sunix.AddressAndFamily(b)
+		if err != nil {
+			sa.Error = err.String()
+			break
+		}
+		sa.Addr = fa.Addr
+	case linux.AF_NETLINK:
+		nsa, err := netlink.ExtractSockAddr(b)
+		if err != nil {
+			sa.Error = err.String()
+			break
+		}
+		sa.PortID = nsa.PortID
+		sa.Groups = nsa.Groups
+	case linux.AF_VSOCK:
+		vsa, err := vsock.ExtractSockAddr(b)
+		if err != nil {
+			sa.Error = err.String()
+			break
+		}
+		sa.CID = vsa.CID
+		sa.Port = uint16(vsa.Port)
+	}
+	return sa
+}
+
+func jsonPostSockAddr(t *kernel.Task, addr hostarch.Addr, lengthPtr hostarch.Addr) any {
+	if addr == 0 || lengthPtr == 0 {
+		return nil
+	}
+	l, err := copySockLen(t, lengthPtr)
+	if err != nil {
+		return jsonError{Error: err.Error()}
+	}
+	return jsonSockAddrArg(t, addr, l)
+}
+
+func jsonStringVector(t *kernel.Task, addr hostarch.Addr) any {
+	vec, err := t.CopyInVector(addr, slinux.ExecMaxElemSize, slinux.ExecMaxTotalSize)
+	if err != nil {
+		return jsonError{Error: err.Error()}
+	}
+	return vec
+}
+
+// jsonPre decodes the arguments of a system call before it's executed,
+// similarly to pre.
+func (i *SyscallInfo) jsonPre(t *kernel.Task, args arch.SyscallArguments, maxSize uint) []jsonArg {
+	var output []jsonArg
+	for arg := range args {
+		if arg >= len(i.format) {
+			break
+		}
+		a := jsonArg{Raw: hexArg(args[arg])}
+		switch i.format[arg] {
+		case FD:
+			a.Value = jsonFDArg(t, args[arg].Int())
+		case WriteBuffer:
+			a.Value = jsonDump(t, args[arg].Pointer(), uint64(args[arg+1].SizeT()), uint64(maxSize), LogAppDataAllowed /* content */)
+		case WriteIOVec:
+			a.Value = jsonIovecs(t, args[arg].Pointer(), int(args[arg+1].Int()), LogAppDataAllowed /* content */, uint64(maxSize))
+		case ReadIOVec, IOVec:
+			a.Value = jsonIovecs(t, args[arg].Pointer(), int(args[arg+1].Int()), false /* content */, uint64(maxSize))
+		case Path:
+			a.Value = jsonPath(t, args[arg].Pointer())
+		case ExecveStringVector:
+			a.Value = jsonStringVector(t, args[arg].Pointer())
+		case SockAddr:
+			a.Value = jsonSockAddrArg(t, args[arg].Pointer(), uint32(args[arg+1].Uint64()))
+		case SockType:
+			a.Value = flagList(sockType(args[arg].Int()))
+		case SockFlags:
+			a.Value = flagList(SocketFlagSet.Parse(uint64(args[arg].Int())))
+		case CloneFlags:
+			a.Value = flagList(CloneFlagSet.Parse(uint64(args[arg].Uint())))
+		case OpenFlags:
+			a.Value = flagList(open(uint64(args[arg].Uint())))
+		case MmapProt:
+			a.Value = flagList(ProtectionFlagSet.Parse(uint64(args[arg].Uint())))
+		case MmapFlags:
+			a.Value = flagList(MmapFlagSet.Parse(uint64(args[arg].Uint())))
+		case CloseRangeFlags:
+			a.Value = flagList(CloseRangeFlagSet.Parse(uint64(args[arg].Uint())))
+		case Hex:
+		default:
+			if s := i.preArg(t, args, arg, maxSize); s != a.Raw {
+				a.Value = s
+			}
+		}
+		output = append(output, a)
+	}
+	return output
+}
+
+// jsonPost updates the arguments decoded by jsonPre after the system call
+// has been executed, similarly to post.
+func (i *SyscallInfo) jsonPost(t *kernel.Task, args arch.SyscallArguments, rval uintptr, output []jsonArg, maxSize uint) {
+	for arg := range output {
+		if arg >= len(i.format) {
+			break
+		}
+		switch i.format[arg] {
+		case ReadBuffer:
+			output[arg].Value = jsonDump(t, args[arg].Pointer(), uint64(rval), uint64(maxSize), LogAppDataAllowed /* content */)
+		case ReadIOVec:
+			printLength := uint64(rval)
+			if printLength > uint64(maxSize) {
+				printLength = uint64(maxSize)
+			}
+			output[arg].Value = jsonIovecs(t, args[arg].Pointer(), int(args[arg+1].Int()), LogAppDataAllowed /* content */, printLength)
+		case PostPath:
+			output[arg].Value = jsonPath(t, args[arg].Pointer())
+		case PostSockAddr:
+			output[arg].Value = jsonPostSockAddr(t, args[arg].Pointer(), args[arg+1].Pointer())
+		case WriteIOVec, IOVec, WriteBuffer, SendMsgHdr, SetSockOptVal:
+			// Already decoded before the syscall.
+		default:
+			if s, ok := i.postArg(t, args, arg, rval, maxSize); ok {
+				output[arg].Value = s
+			}
+		}
+	}
+}
+
+// jsonExit builds the JSON record for a system call that has returned.
+func (i *SyscallInfo) jsonExit(t *kernel.Task, sysno uintptr, start time.Time, elapsed time.Duration, output []jsonArg, args arch.SyscallArguments, rval uintptr, err error, errno int, maxSize uint) *jsonRecord {
+	pidns := t.Kernel().RootPIDNamespace()
+	rec := &jsonRecord{
+		Time:        start,
+		PID:         int32(pidns.IDOfThreadGroup(t.ThreadGroup())),
+		TID:         int32(pidns.IDOfTask(t)),
+		ContainerID: t.ContainerID(),
+		Comm:        t.Name(),
+		Syscall:     i.name,
+		Sysno:       sysno,
+		Args:        output,
+		Return:      int64(rval),
+		DurationNs:  elapsed.Nanoseconds(),
+	}
+	if err == nil {
+		// Fill in the output after successful execution.
+		i.jsonPost(t, args, rval, output, maxSize)
+	} else {
+		rec.Return = -int64(errno)
+		rec.Errno = errno
+		rec.Error = unix.ErrnoName(unix.Errno(errno))
+		if len(rec.Error) == 0 {
+			rec.Error = err.Error()
+		}
+	}
+	if rec.Args == nil {
+		rec.Args = []jsonArg{}
+	}
+	return rec
+}
@@ -0,0 +1,238 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package strace
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+)
+
+func TestJSONArgEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		arg  jsonArg
+		want string
+	}{
+		{
+			name: "raw",
+			arg:  jsonArg{Raw: "0x10"},
+			want: `{"raw":"0x10"}`,
+		},
+		{
+			name: "string",
+			arg:  jsonArg{Raw: "0x7f0000001000", Value: "/etc/passwd"},
+			want: `{"raw":"0x7f0000001000","value":"/etc/passwd"}`,
+		},
+		{
+			name: "flags",
+			arg:  jsonArg{Raw: "0x42", Value: []string{"O_RDWR", "O_CREAT"}},
+			want: `{"raw":"0x42","value":["O_RDWR","O_CREAT"]}`,
+		},
+		{
+			name: "no_flags",
+			arg:  jsonArg{Raw: "0x0", Value: flagList("")},
+			want: `{"raw":"0x0","value":[]}`,
+		},
+		{
+			name: "fd",
+			arg:  jsonArg{Raw: "0x3", Value: jsonFD{FD: 3, Path: "/tmp/foo"}},
+			want: `{"raw":"0x3","value":{"fd":3,"path":"/tmp/foo"}}`,
+		},
+		{
+			name: "bad_fd",
+			arg:  jsonArg{Raw: "0x64", Value: jsonFD{FD: 100, Bad: true}},
+			want: `{"raw":"0x64","value":{"fd":100,"bad":true}}`,
+		},
+		{
+			name: "buffer",
+			arg:  jsonArg{Raw: "0x1000", Value: jsonBuffer{Base: "0x1000", Len: 8, Data: []byte("hell"), Truncated: true}},
+			want: `{"raw":"0x1000","value":{"base":"0x1000","len":8,"data":"aGVsbA==","truncated":true}}`,
+		},
+		{
+			name: "buffer_without_data",
+			arg:  jsonArg{Raw: "0x1000", Value: jsonBuffer{Base: "0x1000", Len: 8}},
+			want: `{"raw":"0x1000","value":{"base":"0x1000","len":8}}`,
+		},
+		{
+			name: "buffer_error",
+			arg:  jsonArg{Raw: "0x1000", Value: jsonBuffer{Base: "0x1000", Len: 8, Error: "bad address"}},
+			want: `{"raw":"0x1000","value":{"base":"0x1000","len":8,"error":"bad address"}}`,
+		},
+		{
+			name: "iovecs",
+			arg:  jsonArg{Raw: "0x2000", Value: []jsonBuffer{{Base: "0x1000", Len: 2, Data: []byte("hi")}, {Base: "0x3000", Len: 0}}},
+			want: `{"raw":"0x2000","value":[{"base":"0x1000","len":2,"data":"aGk="},{"base":"0x3000","len":0}]}`,
+		},
+		{
+			name: "inet_sockaddr",
+			arg:  jsonArg{Raw: "0x1000", Value: jsonSockAddr{Family: "AF_INET", Addr: "127.0.0.1", Port: 80}},
+			want: `{"raw":"0x1000","value":{"family":"AF_INET","addr":"127.0.0.1","port":80}}`,
+		},
+		{
+			name: "netlink_sockaddr",
+			arg:  jsonArg{Raw: "0x1000", Value: jsonSockAddr{Family: "AF_NETLINK", PortID: 42, Groups: 1}},
+			want: `{"raw":"0x1000","value":{"family":"AF_NETLINK","port_id":42,"groups":1}}`,
+		},
+		{
+			name: "vsock_sockaddr",
+			arg:  jsonArg{Raw: "0x1000", Value: jsonSockAddr{Family: "AF_VSOCK", CID: 3, Port: 1024}},
+			want: `{"raw":"0x1000","value":{"family":"AF_VSOCK","port":1024,"cid":3}}`,
+		},
+		{
+			name: "string_vector",
+			arg:  jsonArg{Raw: "0x1000", Value: []string{"/bin/sh", "-c", "true"}},
+			want: `{"raw":"0x1000","value":["/bin/sh","-c","true"]}`,
+		},
+		{
+			name: "decode_error",
+			arg:  jsonArg{Raw: "0x1000", Value: jsonError{Error: "bad address"}},
+			want: `{"raw":"0x1000","value":{"error":"bad address"}}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := json.Marshal(tc.arg)
+			if err != nil {
+				t.Fatalf("json.Marshal failed: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJSONPre(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		format []FormatSpecifier
+		args   []uintptr
+		want   string
+	}{
+		{
+			name:   "hex",
+			format: []FormatSpecifier{Hex},
+			args:   []uintptr{0x10},
+			want:   `[{"raw":"0x10"}]`,
+		},
+		{
+			name:   "oct",
+			format: []FormatSpecifier{Oct},
+			args:   []uintptr{0755},
+			want:   `[{"raw":"0x1ed","value":"0o755"}]`,
+		},
+		{
+			name:   "open_flags",
+			format: []FormatSpecifier{OpenFlags},
+			args:   []uintptr{linux.O_RDWR | linux.O_CREAT},
+			want:   `[{"raw":"0x42","value":["O_RDWR","O_CREAT"]}]`,
+		},
+		{
+			name:   "mmap",
+			format: []FormatSpecifier{MmapProt, MmapFlags},
+			args:   []uintptr{linux.PROT_READ | linux.PROT_WRITE, linux.MAP_PRIVATE | linux.MAP_ANONYMOUS},
+			want:   `[{"raw":"0x3","value":["PROT_READ","PROT_WRITE"]},{"raw":"0x22","value":["MAP_PRIVATE","MAP_ANONYMOUS"]}]`,
+		},
+		{
+			name:   "clone_flags",
+			format: []FormatSpecifier{CloneFlags},
+			args:   []uintptr{linux.CLONE_VM | linux.CLONE_FILES},
+			want:   `[{"raw":"0x500","value":["CLONE_VM","CLONE_FILES"]}]`,
+		},
+		{
+			name:   "socket",
+			format: []FormatSpecifier{SockFamily, SockType, Hex},
+			args:   []uintptr{linux.AF_INET, uintptr(linux.SOCK_STREAM | linux.SOCK_CLOEXEC), 0},
+			want:   `[{"raw":"0x2","value":"AF_INET"},{"raw":"0x80001","value":["SOCK_STREAM","SOCK_CLOEXEC"]},{"raw":"0x0"}]`,
+		},
+		{
+			name:   "sock_flags",
+			format: []FormatSpecifier{SockFlags},
+			args:   []uintptr{linux.SOCK_NONBLOCK},
+			want:   `[{"raw":"0x800","value":["SOCK_NONBLOCK"]}]`,
+		},
+		{
+			name:   "close_range_flags",
+			format: []FormatSpecifier{CloseRangeFlags},
+			args:   []uintptr{uintptr(linux.CLOSE_RANGE_CLOEXEC)},
+			want:   `[{"raw":"0x4","value":["CLOSE_RANGE_CLOEXEC"]}]`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			info := makeSyscallInfo(tc.name, tc.format...)
+			var args arch.SyscallArguments
+			for i, v := range tc.args {
+				args[i].Value = v
+			}
+			// None of the formats above need a task to be decoded.
+			got, err := json.Marshal(info.jsonPre(nil /* t */, args, 0 /* maxSize */))
+			if err != nil {
+				t.Fatalf("json.Marshal failed: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+// testJSONOutput is a JSON output that records whether it was closed.
+type testJSONOutput struct {
+	bytes.Buffer
+	closed bool
+}
+
+// Close implements io.Closer.Close.
+func (o *testJSONOutput) Close() error {
+	o.closed = true
+	return nil
+}
+
+func TestWriteJSON(t *testing.T) {
+	out := &testJSONOutput{}
+	SetJSONOutput(out, JSONOptions{})
+	defer SetJSONOutput(nil, JSONOptions{})
+	rec := &jsonRecord{
+		Time:       time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		PID:        1,
+		TID:        2,
+		Comm:       "sh",
+		Syscall:    "close",
+		Sysno:      3,
+		Args:       []jsonArg{{Raw: "0x64", Value: jsonFD{FD: 100, Bad: true}}},
+		Return:     -int64(unix.EBADF),
+		Errno:      int(unix.EBADF),
+		Error:      "EBADF",
+		DurationNs: 1000,
+	}
+	writeJSON(rec)
+	writeJSON(rec)
+	want := `{"time":"2026-01-02T03:04:05Z","pid":1,"tid":2,"comm":"sh","syscall":"close","sysno":3,"args":[{"raw":"0x64","value":{"fd":100,"bad":true}}],"return":-9,"errno":9,"error":"EBADF","duration_ns":1000}` + "\n"
+	if got := out.String(); got != want+want {
+		t.Errorf("got output %q, want two records %q", got, want)
+	}
+
+	SetJSONOutput(nil, JSONOptions{})
+	if !out.closed {
+		t.Errorf("SetJSONOutput didn't close the previous output")
+	}
+	// Records are discarded without an output.
+	writeJSON(rec)
+}
@@ -27,6 +27,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/socket"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netlink"
 	"gvisor.dev/gvisor/pkg/sentry/socket/unix"
+	"gvisor.dev/gvisor/pkg/sentry/socket/vsock"
 	slinux "gvisor.dev/gvisor/pkg/sentry/syscalls/linux"
 )
 
@@ -364,6 +365,12 @@ func sockAddr(t *kernel.Task, addr hostarch.Addr, length uint32) string {
 			return fmt.Sprintf("%#x {Family: %s, error extracting address: %v}", addr, familyStr, err)
 		}
 		return fmt.Sprintf("%#x {Family: %s, PortID: %d, Groups: %d}", addr, familyStr, sa.PortID, sa.Groups)
+	case linux.AF_VSOCK:
+		sa, err := vsock.ExtractSockAddr(b)
+		if err != nil {
+			return fmt.Sprintf("%#x {Family: %s, error extracting address: %v}", addr, familyStr, err)
+		}
+		return fmt.Sprintf("%#x {Family: %s, CID: %d, Port: %d}", addr, familyStr, sa.CID, sa.Port)
 	default:
 		return fmt.Sprintf("%#x {Family: %s, family addr format unknown}", addr, familyStr)
 	}
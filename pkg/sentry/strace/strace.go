@@ -151,6 +151,20 @@ func path(t *kernel.Task, addr hostarch.Addr) string {
 }
 
 func fd(t *kernel.Task, fd int32) string {
+	name, ok := fdName(t, fd)
+	if fd == linux.AT_FDCWD {
+		return fmt.Sprintf("AT_FDCWD %s", name)
+	}
+	if !ok {
+		// Cast FD to uint64 to avoid printing negative hex.
+		return fmt.Sprintf("%#x (bad FD)", uint64(fd))
+	}
+	return fmt.Sprintf("%#x %s", fd, name)
+}
+
+// fdName returns the path of the file referred to by fd, or of the working
+// directory for AT_FDCWD. It returns false if fd is not a valid FD.
+func fdName(t *kernel.Task, fd int32) (string, bool) {
 	root := t.FSContext().RootDirectory()
 	defer root.DecRef(t)
 
@@ -160,18 +174,17 @@ func fd(t *kernel.Task, fd int32) string {
 		defer wd.DecRef(t)
 
 		name, _ := vfsObj.PathnameWithDeleted(t, root, wd)
-		return fmt.Sprintf("AT_FDCWD %s", name)
+		return name, true
 	}
 
 	file := t.GetFile(fd)
 	if file == nil {
-		// Cast FD to uint64 to avoid printing negative hex.
-		return fmt.Sprintf("%#x (bad FD)", uint64(fd))
+		return "", false
 	}
 	defer file.DecRef(t)
 
 	name, _ := vfsObj.PathnameWithDeleted(t, root, file.VirtualDentry())
-	return fmt.Sprintf("%#x %s", fd, name)
+	return name, true
 }
 
 func fdpair(t *kernel.Task, addr hostarch.Addr) string {
@@ -385,105 +398,110 @@ func (i *SyscallInfo) pre(t *kernel.Task, args arch.SyscallArguments, maximumBlo
 		if arg >= len(i.format) {
 			break
 		}
-		switch i.format[arg] {
-		case FD:
-			output = append(output, fd(t, args[arg].Int()))
-		case WriteBuffer:
-			output = append(output, dump(t, args[arg].Pointer(), args[arg+1].SizeT(), maximumBlobSize, LogAppDataAllowed /* content */))
-		case WriteIOVec:
-			output = append(output, iovecs(t, args[arg].Pointer(), int(args[arg+1].Int()), LogAppDataAllowed /* content */, uint64(maximumBlobSize)))
-		case ReadIOVec, IOVec:
-			output = append(output, iovecs(t, args[arg].Pointer(), int(args[arg+1].Int()), false /* content */, uint64(maximumBlobSize)))
-		case SendMsgHdr:
-			output = append(output, msghdr(t, args[arg].Pointer(), LogAppDataAllowed /* content */, uint64(maximumBlobSize)))
-		case RecvMsgHdr:
-			output = append(output, msghdr(t, args[arg].Pointer(), false /* content */, uint64(maximumBlobSize)))
-		case Path:
-			output = append(output, path(t, args[arg].Pointer()))
-		case ExecveStringVector:
-			output = append(output, stringVector(t, args[arg].Pointer()))
-		case SetSockOptVal:
-			output = append(output, sockOptVal(t, args[arg-2].Uint64() /* level */, args[arg-1].Uint64() /* optName */, args[arg].Pointer() /* optVal */, args[arg+1].Uint64() /* optLen */, maximumBlobSize))
-		case SockOptLevel:
-			output = append(output, sockOptLevels.Parse(args[arg].Uint64()))
-		case SockOptName:
-			output = append(output, sockOptNames[args[arg-1].Uint64() /* level */].Parse(args[arg].Uint64()))
-		case SockAddr:
-			output = append(output, sockAddr(t, args[arg].Pointer(), uint32(args[arg+1].Uint64())))
-		case SockLen:
-			output = append(output, sockLenPointer(t, args[arg].Pointer()))
-		case SockFamily:
-			output = append(output, SocketFamily.Parse(uint64(args[arg].Int())))
-		case SockType:
-			output = append(output, sockType(args[arg].Int()))
-		case SockProtocol:
-			output = append(output, sockProtocol(args[arg-2].Int(), args[arg].Int()))
-		case SockFlags:
-			output = append(output, sockFlags(args[arg].Int()))
-		case Timespec:
-			output = append(output, timespec(t, args[arg].Pointer()))
-		case UTimeTimespec:
-			output = append(output, utimensTimespec(t, args[arg].Pointer()))
-		case ItimerVal:
-			output = append(output, itimerval(t, args[arg].Pointer()))
-		case ItimerSpec:
-			output = append(output, itimerspec(t, args[arg].Pointer()))
-		case Timeval:
-			output = append(output, timeval(t, args[arg].Pointer()))
-		case Utimbuf:
-			output = append(output, utimbuf(t, args[arg].Pointer()))
-		case CloneFlags:
-			output = append(output, CloneFlagSet.Parse(uint64(args[arg].Uint())))
-		case OpenFlags:
-			output = append(output, open(uint64(args[arg].Uint())))
-		case Mode:
-			output = append(output, linux.FileMode(args[arg].ModeT()).String())
-		case FutexOp:
-			output = append(output, futex(uint64(args[arg].Uint())))
-		case PtraceRequest:
-			output = append(output, PtraceRequestSet.Parse(args[arg].Uint64()))
-		case ItimerType:
-			output = append(output, ItimerTypes.Parse(uint64(args[arg].Int())))
-		case Signal:
-			output = append(output, signalNames.ParseDecimal(args[arg].Uint64()))
-		case SignalMaskAction:
-			output = append(output, signalMaskActions.Parse(uint64(args[arg].Int())))
-		case SigSet:
-			output = append(output, sigSet(t, args[arg].Pointer()))
-		case SigAction:
-			output = append(output, sigAction(t, args[arg].Pointer()))
-		case CapHeader:
-			output = append(output, capHeader(t, args[arg].Pointer()))
-		case CapData:
-			output = append(output, capData(t, args[arg-1].Pointer(), args[arg].Pointer()))
-		case PollFDs:
-			output = append(output, pollFDs(t, args[arg].Pointer(), uint(args[arg+1].Uint()), false))
-		case EpollCtlOp:
-			output = append(output, epollCtlOps.Parse(uint64(args[arg].Int())))
-		case EpollEvent:
-			output = append(output, epollEvent(t, args[arg].Pointer()))
-		case EpollEvents:
-			output = append(output, epollEvents(t, args[arg].Pointer(), 0 /* numEvents */, uint64(maximumBlobSize)))
-		case SelectFDSet:
-			output = append(output, fdSet(t, int(args[0].Int()), args[arg].Pointer()))
-		case MmapProt:
-			output = append(output, ProtectionFlagSet.Parse(uint64(args[arg].Uint())))
-		case MmapFlags:
-			output = append(output, MmapFlagSet.Parse(uint64(args[arg].Uint())))
-		case CloseRangeFlags:
-			output = append(output, CloseRangeFlagSet.Parse(uint64(args[arg].Uint())))
-		case Oct:
-			output = append(output, "0o"+strconv.FormatUint(args[arg].Uint64(), 8))
-		case Hex:
-			fallthrough
-		default:
-			output = append(output, hexArg(args[arg]))
-		}
+		output = append(output, i.preArg(t, args, arg, maximumBlobSize))
 	}
 
 	return output
 }
 
+// preArg formats argument arg of a system call before it is executed.
+func (i *SyscallInfo) preArg(t *kernel.Task, args arch.SyscallArguments, arg int, maximumBlobSize uint) string {
+	switch i.format[arg] {
+	case FD:
+		return fd(t, args[arg].Int())
+	case WriteBuffer:
+		return dump(t, args[arg].Pointer(), args[arg+1].SizeT(), maximumBlobSize, LogAppDataAllowed /* content */)
+	case WriteIOVec:
+		return iovecs(t, args[arg].Pointer(), int(args[arg+1].Int()), LogAppDataAllowed /* content */, uint64(maximumBlobSize))
+	case ReadIOVec, IOVec:
+		return iovecs(t, args[arg].Pointer(), int(args[arg+1].Int()), false /* content */, uint64(maximumBlobSize))
+	case SendMsgHdr:
+		return msghdr(t, args[arg].Pointer(), LogAppDataAllowed /* content */, uint64(maximumBlobSize))
+	case RecvMsgHdr:
+		return msghdr(t, args[arg].Pointer(), false /* content */, uint64(maximumBlobSize))
+	case Path:
+		return path(t, args[arg].Pointer())
+	case ExecveStringVector:
+		return stringVector(t, args[arg].Pointer())
+	case SetSockOptVal:
+		return sockOptVal(t, args[arg-2].Uint64() /* level */, args[arg-1].Uint64() /* optName */, args[arg].Pointer() /* optVal */, args[arg+1].Uint64() /* optLen */, maximumBlobSize)
+	case SockOptLevel:
+		return sockOptLevels.Parse(args[arg].Uint64())
+	case SockOptName:
+		return sockOptNames[args[arg-1].Uint64() /* level */].Parse(args[arg].Uint64())
+	case SockAddr:
+		return sockAddr(t, args[arg].Pointer(), uint32(args[arg+1].Uint64()))
+	case SockLen:
+		return sockLenPointer(t, args[arg].Pointer())
+	case SockFamily:
+		return SocketFamily.Parse(uint64(args[arg].Int()))
+	case SockType:
+		return sockType(args[arg].Int())
+	case SockProtocol:
+		return sockProtocol(args[arg-2].Int(), args[arg].Int())
+	case SockFlags:
+		return sockFlags(args[arg].Int())
+	case Timespec:
+		return timespec(t, args[arg].Pointer())
+	case UTimeTimespec:
+		return utimensTimespec(t, args[arg].Pointer())
+	case ItimerVal:
+		return itimerval(t, args[arg].Pointer())
+	case ItimerSpec:
+		return itimerspec(t, args[arg].Pointer())
+	case Timeval:
+		return timeval(t, args[arg].Pointer())
+	case Utimbuf:
+		return utimbuf(t, args[arg].Pointer())
+	case CloneFlags:
+		return CloneFlagSet.Parse(uint64(args[arg].Uint()))
+	case OpenFlags:
+		return open(uint64(args[arg].Uint()))
+	case Mode:
+		return linux.FileMode(args[arg].ModeT()).String()
+	case FutexOp:
+		return futex(uint64(args[arg].Uint()))
+	case PtraceRequest:
+		return PtraceRequestSet.Parse(args[arg].Uint64())
+	case ItimerType:
+		return ItimerTypes.Parse(uint64(args[arg].Int()))
+	case Signal:
+		return signalNames.ParseDecimal(args[arg].Uint64())
+	case SignalMaskAction:
+		return signalMaskActions.Parse(uint64(args[arg].Int()))
+	case SigSet:
+		return sigSet(t, args[arg].Pointer())
+	case SigAction:
+		return sigAction(t, args[arg].Pointer())
+	case CapHeader:
+		return capHeader(t, args[arg].Pointer())
+	case CapData:
+		return capData(t, args[arg-1].Pointer(), args[arg].Pointer())
+	case PollFDs:
+		return pollFDs(t, args[arg].Pointer(), uint(args[arg+1].Uint()), false)
+	case EpollCtlOp:
+		return epollCtlOps.Parse(uint64(args[arg].Int()))
+	case EpollEvent:
+		return epollEvent(t, args[arg].Pointer())
+	case EpollEvents:
+		return epollEvents(t, args[arg].Pointer(), 0 /* numEvents */, uint64(maximumBlobSize))
+	case SelectFDSet:
+		return fdSet(t, int(args[0].Int()), args[arg].Pointer())
+	case MmapProt:
+		return ProtectionFlagSet.Parse(uint64(args[arg].Uint()))
+	case MmapFlags:
+		return MmapFlagSet.Parse(uint64(args[arg].Uint()))
+	case CloseRangeFlags:
+		return CloseRangeFlagSet.Parse(uint64(args[arg].Uint()))
+	case Oct:
+		return "0o" + strconv.FormatUint(args[arg].Uint64(), 8)
+	case Hex:
+		fallthrough
+	default:
+		return hexArg(args[arg])
+	}
+}
+
 // post fills in the post-execution arguments for a system call. This modifies
 // the given output slice in place with arguments that may only be interpreted
 // after the system call has been executed.
@@ -492,64 +510,73 @@ func (i *SyscallInfo) post(t *kernel.Task, args arch.SyscallArguments, rval uint
 		if arg >= len(i.format) {
 			break
 		}
-		switch i.format[arg] {
-		case ReadBuffer:
-			output[arg] = dump(t, args[arg].Pointer(), uint(rval), maximumBlobSize, LogAppDataAllowed /* content */)
-		case ReadIOVec:
-			printLength := uint64(rval)
-			if printLength > uint64(maximumBlobSize) {
-				printLength = uint64(maximumBlobSize)
-			}
-			output[arg] = iovecs(t, args[arg].Pointer(), int(args[arg+1].Int()), LogAppDataAllowed /* content */, printLength)
-		case WriteIOVec, IOVec, WriteBuffer:
-			// We already have a big blast from write.
-			output[arg] = "..."
-		case SendMsgHdr:
-			output[arg] = msghdr(t, args[arg].Pointer(), false /* content */, uint64(maximumBlobSize))
-		case RecvMsgHdr:
-			output[arg] = msghdr(t, args[arg].Pointer(), LogAppDataAllowed /* content */, uint64(maximumBlobSize))
-		case PostPath:
-			output[arg] = path(t, args[arg].Pointer())
-		case PipeFDs:
-			output[arg] = fdpair(t, args[arg].Pointer())
-		case Uname:
-			output[arg] = uname(t, args[arg].Pointer())
-		case Stat:
-			output[arg] = stat(t, args[arg].Pointer())
-		case PostSockAddr:
-			output[arg] = postSockAddr(t, args[arg].Pointer(), args[arg+1].Pointer())
-		case SockLen:
-			output[arg] = sockLenPointer(t, args[arg].Pointer())
-		case PostTimespec:
-			output[arg] = timespec(t, args[arg].Pointer())
-		case PostItimerVal:
-			output[arg] = itimerval(t, args[arg].Pointer())
-		case PostItimerSpec:
-			output[arg] = itimerspec(t, args[arg].Pointer())
-		case Timeval:
-			output[arg] = timeval(t, args[arg].Pointer())
-		case Rusage:
-			output[arg] = rusage(t, args[arg].Pointer())
-		case PostSigSet:
-			output[arg] = sigSet(t, args[arg].Pointer())
-		case PostSigAction:
-			output[arg] = sigAction(t, args[arg].Pointer())
-		case PostCapData:
-			output[arg] = capData(t, args[arg-1].Pointer(), args[arg].Pointer())
-		case PollFDs:
-			output[arg] = pollFDs(t, args[arg].Pointer(), uint(args[arg+1].Uint()), true)
-		case EpollEvents:
-			output[arg] = epollEvents(t, args[arg].Pointer(), uint64(rval), uint64(maximumBlobSize))
-		case GetSockOptVal:
-			output[arg] = getSockOptVal(t, args[arg-2].Uint64() /* level */, args[arg-1].Uint64() /* optName */, args[arg].Pointer() /* optVal */, args[arg+1].Pointer() /* optLen */, maximumBlobSize, rval)
-		case SetSockOptVal:
-			// No need to print the value again. While it usually
-			// isn't, the string version of this arg can be long.
-			output[arg] = hexArg(args[arg])
+		if s, ok := i.postArg(t, args, arg, rval, maximumBlobSize); ok {
+			output[arg] = s
 		}
 	}
 }
 
+// postArg formats argument arg of a system call after it has been executed.
+// It returns false if the argument doesn't need to be formatted again.
+func (i *SyscallInfo) postArg(t *kernel.Task, args arch.SyscallArguments, arg int, rval uintptr, maximumBlobSize uint) (string, bool) {
+	switch i.format[arg] {
+	case ReadBuffer:
+		return dump(t, args[arg].Pointer(), uint(rval), maximumBlobSize, LogAppDataAllowed /* content */), true
+	case ReadIOVec:
+		printLength := uint64(rval)
+		if printLength > uint64(maximumBlobSize) {
+			printLength = uint64(maximumBlobSize)
+		}
+		return iovecs(t, args[arg].Pointer(), int(args[arg+1].Int()), LogAppDataAllowed /* content */, printLength), true
+	case WriteIOVec, IOVec, WriteBuffer:
+		// We already have a big blast from write.
+		return "...", true
+	case SendMsgHdr:
+		return msghdr(t, args[arg].Pointer(), false /* content */, uint64(maximumBlobSize)), true
+	case RecvMsgHdr:
+		return msghdr(t, args[arg].Pointer(), LogAppDataAllowed /* content */, uint64(maximumBlobSize)), true
+	case PostPath:
+		return path(t, args[arg].Pointer()), true
+	case PipeFDs:
+		return fdpair(t, args[arg].Pointer()), true
+	case Uname:
+		return uname(t, args[arg].Pointer()), true
+	case Stat:
+		return stat(t, args[arg].Pointer()), true
+	case PostSockAddr:
+		return postSockAddr(t, args[arg].Pointer(), args[arg+1].Pointer()), true
+	case SockLen:
+		return sockLenPointer(t, args[arg].Pointer()), true
+	case PostTimespec:
+		return timespec(t, args[arg].Pointer()), true
+	case PostItimerVal:
+		return itimerval(t, args[arg].Pointer()), true
+	case PostItimerSpec:
+		return itimerspec(t, args[arg].Pointer()), true
+	case Timeval:
+		return timeval(t, args[arg].Pointer()), true
+	case Rusage:
+		return rusage(t, args[arg].Pointer()), true
+	case PostSigSet:
+		return sigSet(t, args[arg].Pointer()), true
+	case PostSigAction:
+		return sigAction(t, args[arg].Pointer()), true
+	case PostCapData:
+		return capData(t, args[arg-1].Pointer(), args[arg].Pointer()), true
+	case PollFDs:
+		return pollFDs(t, args[arg].Pointer(), uint(args[arg+1].Uint()), true), true
+	case EpollEvents:
+		return epollEvents(t, args[arg].Pointer(), uint64(rval), uint64(maximumBlobSize)), true
+	case GetSockOptVal:
+		return getSockOptVal(t, args[arg-2].Uint64() /* level */, args[arg-1].Uint64() /* optName */, args[arg].Pointer() /* optVal */, args[arg+1].Pointer() /* optLen */, maximumBlobSize, rval), true
+	case SetSockOptVal:
+		// No need to print the value again. While it usually
+		// isn't, the string version of this arg can be long.
+		return hexArg(args[arg]), true
+	}
+	return "", false
+}
+
 // printEntry prints the given system call entry.
 func (i *SyscallInfo) printEnter(t *kernel.Task, args arch.SyscallArguments) []string {
 	output := i.pre(t, args, LogMaximumSize)
@@ -668,6 +695,13 @@ type syscallContext struct {
 	logOutput   []string
 	eventOutput []string
 	flags       uint32
+
+	// json is true if the syscall is traced in JSON format, in which case
+	// jsonOutput holds the arguments decoded before the syscall and
+	// jsonMaxSize the maximum data size decoded for each argument.
+	json        bool
+	jsonOutput  []jsonArg
+	jsonMaxSize uint
 }
 
 // SyscallEnter implements kernel.Stracer.SyscallEnter. It logs the syscall
@@ -688,6 +722,16 @@ func (s SyscallMap) SyscallEnter(t *kernel.Task, sysno uintptr, args arch.Syscal
 	if bits.IsOn32(flags, kernel.StraceEnableEvent) {
 		eventOutput = info.sendEnter(t, args)
 	}
+	var (
+		jsonOutput  []jsonArg
+		jsonMaxSize uint
+		json        bool
+	)
+	if bits.IsOn32(flags, kernel.StraceEnableJSON) {
+		if jsonMaxSize, json = jsonMatch(t); json {
+			jsonOutput = info.jsonPre(t, args, jsonMaxSize)
+		}
+	}
 
 	return &syscallContext{
 		info:        info,
@@ -696,6 +740,9 @@ func (s SyscallMap) SyscallEnter(t *kernel.Task, sysno uintptr, args arch.Syscal
 		logOutput:   output,
 		eventOutput: eventOutput,
 		flags:       flags,
+		json:        json,
+		jsonOutput:  jsonOutput,
+		jsonMaxSize: jsonMaxSize,
 	}
 }
 
@@ -712,6 +759,9 @@ func (s SyscallMap) SyscallExit(context any, t *kernel.Task, sysno, rval uintptr
 	if bits.IsOn32(c.flags, kernel.StraceEnableEvent) {
 		c.info.sendExit(t, elapsed, c.eventOutput, c.args, rval, err, errno)
 	}
+	if c.json {
+		writeJSON(c.info.jsonExit(t, sysno, c.start, elapsed, c.jsonOutput, c.args, rval, err, errno, c.jsonMaxSize))
+	}
 }
 
 // ConvertToSysnoMap converts the names to a map keyed on the syscall number
@@ -782,6 +832,9 @@ const (
 
 	// SinkTypeEvent sends strace to event log
 	SinkTypeEvent
+
+	// SinkTypeJSON sends strace to the JSON output, see SetJSONOutput.
+	SinkTypeJSON
 )
 
 func convertToSyscallFlag(sinks SinkType) uint32 {
@@ -792,10 +845,14 @@ func convertToSyscallFlag(sinks SinkType) uint32 {
 	if bits.IsOn32(uint32(sinks), uint32(SinkTypeEvent)) {
 		ret |= kernel.StraceEnableEvent
 	}
+	if bits.IsOn32(uint32(sinks), uint32(SinkTypeJSON)) {
+		ret |= kernel.StraceEnableJSON
+	}
 	return ret
 }
 
-// Enable enables the syscalls in allowlist in all syscall tables.
+// Enable enables the syscalls in allowlist in all syscall tables. The
+// allowlist may contain syscall classes, e.g. "%net", see SyscallClasses.
 //
 // Preconditions: Initialize has been called.
 func Enable(allowlist []string, sinks SinkType) error {
@@ -808,7 +865,11 @@ func Enable(allowlist []string, sinks SinkType) error {
 		}
 
 		// Convert to a set of system calls numbers.
-		wl, err := sys.ConvertToSysnoMap(allowlist)
+		names, err := sys.expandAllowlist(allowlist)
+		if err != nil {
+			return err
+		}
+		wl, err := sys.ConvertToSysnoMap(names)
 		if err != nil {
 			return err
 		}
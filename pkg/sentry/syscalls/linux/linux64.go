@@ -61,8 +61,8 @@ var AMD64 = &kernel.SyscallTable{
 		6:   syscalls.Supported("lstat", Lstat),
 		7:   syscalls.Supported("poll", Poll),
 		8:   syscalls.Supported("lseek", Lseek),
-		9:   syscalls.Supported("mmap", Mmap),
-		10:  syscalls.Supported("mprotect", Mprotect),
+		9:   syscalls.SupportedPoint("mmap", Mmap, PointMmap),
+		10:  syscalls.SupportedPoint("mprotect", Mprotect, PointMprotect),
 		11:  syscalls.Supported("munmap", Munmap),
 		12:  syscalls.Supported("brk", Brk),
 		13:  syscalls.Supported("rt_sigaction", RtSigaction),
@@ -106,7 +106,7 @@ var AMD64 = &kernel.SyscallTable{
 		51:  syscalls.Supported("getsockname", GetSockName),
 		52:  syscalls.Supported("getpeername", GetPeerName),
 		53:  syscalls.SupportedPoint("socketpair", SocketPair, PointSocketpair),
-		54:  syscalls.Supported("setsockopt", SetSockOpt),
+		54:  syscalls.SupportedPoint("setsockopt", SetSockOpt, PointSetsockopt),
 		55:  syscalls.Supported("getsockopt", GetSockOpt),
 		56:  syscalls.PartiallySupportedPoint("clone", Clone, PointClone, "Options CLONE_PIDFD, CLONE_NEWCGROUP, CLONE_PARENT, CLONE_NEWTIME, CLONE_CLEAR_SIGHAND, and CLONE_SYSVSEM not supported.", nil),
 		57:  syscalls.SupportedPoint("fork", Fork, PointFork),
@@ -134,26 +134,26 @@ var AMD64 = &kernel.SyscallTable{
 		79:  syscalls.Supported("getcwd", Getcwd),
 		80:  syscalls.SupportedPoint("chdir", Chdir, PointChdir),
 		81:  syscalls.SupportedPoint("fchdir", Fchdir, PointFchdir),
-		82:  syscalls.Supported("rename", Rename),
+		82:  syscalls.SupportedPoint("rename", Rename, PointRename),
 		83:  syscalls.Supported("mkdir", Mkdir),
-		84:  syscalls.Supported("rmdir", Rmdir),
+		84:  syscalls.SupportedPoint("rmdir", Rmdir, PointRmdir),
 		85:  syscalls.SupportedPoint("creat", Creat, PointCreat),
-		86:  syscalls.Supported("link", Link),
-		87:  syscalls.Supported("unlink", Unlink),
-		88:  syscalls.Supported("symlink", Symlink),
+		86:  syscalls.SupportedPoint("link", Link, PointLink),
+		87:  syscalls.SupportedPoint("unlink", Unlink, PointUnlink),
+		88:  syscalls.SupportedPoint("symlink", Symlink, PointSymlink),
 		89:  syscalls.Supported("readlink", Readlink),
-		90:  syscalls.Supported("chmod", Chmod),
-		91:  syscalls.Supported("fchmod", Fchmod),
-		92:  syscalls.Supported("chown", Chown),
-		93:  syscalls.Supported("fchown", Fchown),
-		94:  syscalls.Supported("lchown", Lchown),
+		90:  syscalls.SupportedPoint("chmod", Chmod, PointChmod),
+		91:  syscalls.SupportedPoint("fchmod", Fchmod, PointFchmod),
+		92:  syscalls.SupportedPoint("chown", Chown, PointChown),
+		93:  syscalls.SupportedPoint("fchown", Fchown, PointFchown),
+		94:  syscalls.SupportedPoint("lchown", Lchown, PointLchown),
 		95:  syscalls.Supported("umask", Umask),
 		96:  syscalls.Supported("gettimeofday", Gettimeofday),
 		97:  syscalls.Supported("getrlimit", Getrlimit),
 		98:  syscalls.PartiallySupported("getrusage", Getrusage, "Fields ru_maxrss, ru_minflt, ru_majflt, ru_inblock, ru_oublock are not supported. Fields ru_utime and ru_stime have low precision.", nil),
 		99:  syscalls.PartiallySupported("sysinfo", Sysinfo, "Fields loads, sharedram, bufferram, totalswap, freeswap, totalhigh, freehigh not supported.", nil),
 		100: syscalls.Supported("times", Times),
-		101: syscalls.PartiallySupported("ptrace", Ptrace, "Options PTRACE_PEEKSIGINFO, PTRACE_SECCOMP_GET_FILTER not supported.", nil),
+		101: syscalls.PartiallySupportedPoint("ptrace", Ptrace, PointPtrace, "Options PTRACE_PEEKSIGINFO, PTRACE_SECCOMP_GET_FILTER not supported.", nil),
 		102: syscalls.Supported("getuid", Getuid),
 		103: syscalls.PartiallySupported("syslog", Syslog, "Outputs a dummy message for security reasons.", nil),
 		104: syscalls.Supported("getgid", Getgid),
@@ -178,7 +178,7 @@ var AMD64 = &kernel.SyscallTable{
 		123: syscalls.ErrorWithEvent("setfsgid", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/260"}), // TODO(b/112851702)
 		124: syscalls.Supported("getsid", Getsid),
 		125: syscalls.Supported("capget", Capget),
-		126: syscalls.Supported("capset", Capset),
+		126: syscalls.SupportedPoint("capset", Capset, PointCapset),
 		127: syscalls.Supported("rt_sigpending", RtSigpending),
 		128: syscalls.Supported("rt_sigtimedwait", RtSigtimedwait),
 		129: syscalls.Supported("rt_sigqueueinfo", RtSigqueueinfo),
@@ -217,8 +217,8 @@ var AMD64 = &kernel.SyscallTable{
 		162: syscalls.Supported("sync", Sync),
 		163: syscalls.CapError("acct", linux.CAP_SYS_PACCT, "", nil),
 		164: syscalls.CapError("settimeofday", linux.CAP_SYS_TIME, "", nil),
-		165: syscalls.Supported("mount", Mount),
-		166: syscalls.Supported("umount2", Umount2),
+		165: syscalls.SupportedPoint("mount", Mount, PointMount),
+		166: syscalls.SupportedPoint("umount2", Umount2, PointUmount2),
 		167: syscalls.CapError("swapon", linux.CAP_SYS_ADMIN, "", nil),
 		168: syscalls.CapError("swapoff", linux.CAP_SYS_ADMIN, "", nil),
 		169: syscalls.CapError("reboot", linux.CAP_SYS_BOOT, "", nil),
@@ -227,8 +227,8 @@ var AMD64 = &kernel.SyscallTable{
 		172: syscalls.CapError("iopl", linux.CAP_SYS_RAWIO, "", nil),
 		173: syscalls.CapError("ioperm", linux.CAP_SYS_RAWIO, "", nil),
 		174: syscalls.CapError("create_module", linux.CAP_SYS_MODULE, "", nil),
-		175: syscalls.CapError("init_module", linux.CAP_SYS_MODULE, "", nil),
-		176: syscalls.CapError("delete_module", linux.CAP_SYS_MODULE, "", nil),
+		175: syscalls.CapErrorPoint("init_module", linux.CAP_SYS_MODULE, PointInitModule, "", nil),
+		176: syscalls.CapErrorPoint("delete_module", linux.CAP_SYS_MODULE, PointDeleteModule, "", nil),
 		177: syscalls.Error("get_kernel_syms", linuxerr.ENOSYS, "Not supported in Linux > 2.6.", nil),
 		178: syscalls.Error("query_module", linuxerr.ENOSYS, "Not supported in Linux > 2.6.", nil),
 		179: syscalls.CapError("quotactl", linux.CAP_SYS_ADMIN, "", nil), // requires cap_sys_admin for most operations
@@ -312,15 +312,15 @@ var AMD64 = &kernel.SyscallTable{
 		257: syscalls.SupportedPoint("openat", Openat, PointOpenat),
 		258: syscalls.Supported("mkdirat", Mkdirat),
 		259: syscalls.Supported("mknodat", Mknodat),
-		260: syscalls.Supported("fchownat", Fchownat),
+		260: syscalls.SupportedPoint("fchownat", Fchownat, PointFchownat),
 		261: syscalls.Supported("futimesat", Futimesat),
 		262: syscalls.Supported("newfstatat", Newfstatat),
-		263: syscalls.Supported("unlinkat", Unlinkat),
-		264: syscalls.Supported("renameat", Renameat),
-		265: syscalls.Supported("linkat", Linkat),
-		266: syscalls.Supported("symlinkat", Symlinkat),
+		263: syscalls.SupportedPoint("unlinkat", Unlinkat, PointUnlinkat),
+		264: syscalls.SupportedPoint("renameat", Renameat, PointRenameat),
+		265: syscalls.SupportedPoint("linkat", Linkat, PointLinkat),
+		266: syscalls.SupportedPoint("symlinkat", Symlinkat, PointSymlinkat),
 		267: syscalls.Supported("readlinkat", Readlinkat),
-		268: syscalls.Supported("fchmodat", Fchmodat),
+		268: syscalls.SupportedPoint("fchmodat", Fchmodat, PointFchmodat),
 		269: syscalls.Supported("faccessat", Faccessat),
 		270: syscalls.Supported("pselect6", Pselect6),
 		271: syscalls.Supported("ppoll", Ppoll),
@@ -350,7 +350,7 @@ var AMD64 = &kernel.SyscallTable{
 		295: syscalls.SupportedPoint("preadv", Preadv, PointPreadv),
 		296: syscalls.SupportedPoint("pwritev", Pwritev, PointPwritev),
 		297: syscalls.Supported("rt_tgsigqueueinfo", RtTgsigqueueinfo),
-		298: syscalls.ErrorWithEvent("perf_event_open", linuxerr.ENODEV, "No support for perf counters", nil),
+		298: syscalls.PartiallySupported("perf_event_open", PerfEventOpen, "Only software events (cpu-clock, task-clock, page-faults, context-switches) observing specific tasks are supported.", nil),
 		299: syscalls.Supported("recvmmsg", RecvMMsg),
 		300: syscalls.ErrorWithEvent("fanotify_init", linuxerr.ENOSYS, "Needs CONFIG_FANOTIFY", nil),
 		301: syscalls.ErrorWithEvent("fanotify_mark", linuxerr.ENOSYS, "Needs CONFIG_FANOTIFY", nil),
@@ -365,10 +365,10 @@ var AMD64 = &kernel.SyscallTable{
 		310: syscalls.Supported("process_vm_readv", ProcessVMReadv),
 		311: syscalls.Supported("process_vm_writev", ProcessVMWritev),
 		312: syscalls.CapError("kcmp", linux.CAP_SYS_PTRACE, "", nil),
-		313: syscalls.CapError("finit_module", linux.CAP_SYS_MODULE, "", nil),
+		313: syscalls.CapErrorPoint("finit_module", linux.CAP_SYS_MODULE, PointFinitModule, "", nil),
 		314: syscalls.ErrorWithEvent("sched_setattr", linuxerr.ENOSYS, "gVisor does not implement a scheduler.", []string{"gvisor.dev/issue/264"}), // TODO(b/118902272)
 		315: syscalls.ErrorWithEvent("sched_getattr", linuxerr.ENOSYS, "gVisor does not implement a scheduler.", []string{"gvisor.dev/issue/264"}), // TODO(b/118902272)
-		316: syscalls.Supported("renameat2", Renameat2),
+		316: syscalls.SupportedPoint("renameat2", Renameat2, PointRenameat2),
 		317: syscalls.Supported("seccomp", Seccomp),
 		318: syscalls.Supported("getrandom", GetRandom),
 		319: syscalls.Supported("memfd_create", MemfdCreate),
@@ -466,12 +466,12 @@ var ARM64 = &kernel.SyscallTable{
 		32:  syscalls.Supported("flock", Flock),
 		33:  syscalls.Supported("mknodat", Mknodat),
 		34:  syscalls.Supported("mkdirat", Mkdirat),
-		35:  syscalls.Supported("unlinkat", Unlinkat),
-		36:  syscalls.Supported("symlinkat", Symlinkat),
-		37:  syscalls.Supported("linkat", Linkat),
-		38:  syscalls.Supported("renameat", Renameat),
-		39:  syscalls.Supported("umount2", Umount2),
-		40:  syscalls.Supported("mount", Mount),
+		35:  syscalls.SupportedPoint("unlinkat", Unlinkat, PointUnlinkat),
+		36:  syscalls.SupportedPoint("symlinkat", Symlinkat, PointSymlinkat),
+		37:  syscalls.SupportedPoint("linkat", Linkat, PointLinkat),
+		38:  syscalls.SupportedPoint("renameat", Renameat, PointRenameat),
+		39:  syscalls.SupportedPoint("umount2", Umount2, PointUmount2),
+		40:  syscalls.SupportedPoint("mount", Mount, PointMount),
 		41:  syscalls.Supported("pivot_root", PivotRoot),
 		42:  syscalls.Error("nfsservctl", linuxerr.ENOSYS, "Removed after Linux 3.1.", nil),
 		43:  syscalls.Supported("statfs", Statfs),
@@ -483,10 +483,10 @@ var ARM64 = &kernel.SyscallTable{
 		49:  syscalls.SupportedPoint("chdir", Chdir, PointChdir),
 		50:  syscalls.SupportedPoint("fchdir", Fchdir, PointFchdir),
 		51:  syscalls.SupportedPoint("chroot", Chroot, PointChroot),
-		52:  syscalls.Supported("fchmod", Fchmod),
-		53:  syscalls.Supported("fchmodat", Fchmodat),
-		54:  syscalls.Supported("fchownat", Fchownat),
-		55:  syscalls.Supported("fchown", Fchown),
+		52:  syscalls.SupportedPoint("fchmod", Fchmod, PointFchmod),
+		53:  syscalls.SupportedPoint("fchmodat", Fchmodat, PointFchmodat),
+		54:  syscalls.SupportedPoint("fchownat", Fchownat, PointFchownat),
+		55:  syscalls.SupportedPoint("fchown", Fchown, PointFchown),
 		56:  syscalls.SupportedPoint("openat", Openat, PointOpenat),
 		57:  syscalls.SupportedPoint("close", Close, PointClose),
 		58:  syscalls.CapError("vhangup", linux.CAP_SYS_TTY_CONFIG, "", nil),
@@ -522,7 +522,7 @@ var ARM64 = &kernel.SyscallTable{
 		88:  syscalls.Supported("utimensat", Utimensat),
 		89:  syscalls.CapError("acct", linux.CAP_SYS_PACCT, "", nil),
 		90:  syscalls.Supported("capget", Capget),
-		91:  syscalls.Supported("capset", Capset),
+		91:  syscalls.SupportedPoint("capset", Capset, PointCapset),
 		92:  syscalls.ErrorWithEvent("personality", linuxerr.EINVAL, "Unable to change personality.", nil),
 		93:  syscalls.Supported("exit", Exit),
 		94:  syscalls.Supported("exit_group", ExitGroup),
@@ -536,8 +536,8 @@ var ARM64 = &kernel.SyscallTable{
 		102: syscalls.Supported("getitimer", Getitimer),
 		103: syscalls.Supported("setitimer", Setitimer),
 		104: syscalls.CapError("kexec_load", linux.CAP_SYS_BOOT, "", nil),
-		105: syscalls.CapError("init_module", linux.CAP_SYS_MODULE, "", nil),
-		106: syscalls.CapError("delete_module", linux.CAP_SYS_MODULE, "", nil),
+		105: syscalls.CapErrorPoint("init_module", linux.CAP_SYS_MODULE, PointInitModule, "", nil),
+		106: syscalls.CapErrorPoint("delete_module", linux.CAP_SYS_MODULE, PointDeleteModule, "", nil),
 		107: syscalls.Supported("timer_create", TimerCreate),
 		108: syscalls.Supported("timer_gettime", TimerGettime),
 		109: syscalls.Supported("timer_getoverrun", TimerGetoverrun),
@@ -548,7 +548,7 @@ var ARM64 = &kernel.SyscallTable{
 		114: syscalls.Supported("clock_getres", ClockGetres),
 		115: syscalls.Supported("clock_nanosleep", ClockNanosleep),
 		116: syscalls.PartiallySupported("syslog", Syslog, "Outputs a dummy message for security reasons.", nil),
-		117: syscalls.PartiallySupported("ptrace", Ptrace, "Options PTRACE_PEEKSIGINFO, PTRACE_SECCOMP_GET_FILTER not supported.", nil),
+		117: syscalls.PartiallySupportedPoint("ptrace", Ptrace, PointPtrace, "Options PTRACE_PEEKSIGINFO, PTRACE_SECCOMP_GET_FILTER not supported.", nil),
 		118: syscalls.CapError("sched_setparam", linux.CAP_SYS_NICE, "", nil),
 		119: syscalls.PartiallySupported("sched_setscheduler", SchedSetscheduler, "Stub implementation.", nil),
 		120: syscalls.PartiallySupported("sched_getscheduler", SchedGetscheduler, "Stub implementation.", nil),
@@ -639,7 +639,7 @@ var ARM64 = &kernel.SyscallTable{
 		205: syscalls.Supported("getpeername", GetPeerName),
 		206: syscalls.Supported("sendto", SendTo),
 		207: syscalls.Supported("recvfrom", RecvFrom),
-		208: syscalls.Supported("setsockopt", SetSockOpt),
+		208: syscalls.SupportedPoint("setsockopt", SetSockOpt, PointSetsockopt),
 		209: syscalls.Supported("getsockopt", GetSockOpt),
 		210: syscalls.Supported("shutdown", Shutdown),
 		211: syscalls.Supported("sendmsg", SendMsg),
@@ -653,11 +653,11 @@ var ARM64 = &kernel.SyscallTable{
 		219: syscalls.PartiallySupported("keyctl", Keyctl, "Only supports session keyrings with zero keys in them.", nil),
 		220: syscalls.PartiallySupportedPoint("clone", Clone, PointClone, "Options CLONE_PIDFD, CLONE_NEWCGROUP, CLONE_PARENT, CLONE_NEWTIME, CLONE_CLEAR_SIGHAND, and CLONE_SYSVSEM not supported.", nil),
 		221: syscalls.SupportedPoint("execve", Execve, PointExecve),
-		222: syscalls.Supported("mmap", Mmap),
+		222: syscalls.SupportedPoint("mmap", Mmap, PointMmap),
 		223: syscalls.PartiallySupported("fadvise64", Fadvise64, "Not all options are supported.", nil),
 		224: syscalls.CapError("swapon", linux.CAP_SYS_ADMIN, "", nil),
 		225: syscalls.CapError("swapoff", linux.CAP_SYS_ADMIN, "", nil),
-		226: syscalls.Supported("mprotect", Mprotect),
+		226: syscalls.SupportedPoint("mprotect", Mprotect, PointMprotect),
 		227: syscalls.PartiallySupported("msync", Msync, "Full data flush is not guaranteed at this time.", nil),
 		228: syscalls.PartiallySupported("mlock", Mlock, "Stub implementation. The sandbox lacks appropriate permissions.", nil),
 		229: syscalls.PartiallySupported("munlock", Munlock, "Stub implementation. The sandbox lacks appropriate permissions.", nil),
@@ -672,7 +672,7 @@ var ARM64 = &kernel.SyscallTable{
 		238: syscalls.CapError("migrate_pages", linux.CAP_SYS_NICE, "", nil),
 		239: syscalls.CapError("move_pages", linux.CAP_SYS_NICE, "", nil), // requires cap_sys_nice (mostly)
 		240: syscalls.Supported("rt_tgsigqueueinfo", RtTgsigqueueinfo),
-		241: syscalls.ErrorWithEvent("perf_event_open", linuxerr.ENODEV, "No support for perf counters", nil),
+		241: syscalls.PartiallySupported("perf_event_open", PerfEventOpen, "Only software events (cpu-clock, task-clock, page-faults, context-switches) observing specific tasks are supported.", nil),
 		242: syscalls.SupportedPoint("accept4", Accept4, PointAccept4),
 		243: syscalls.Supported("recvmmsg", RecvMMsg),
 		260: syscalls.Supported("wait4", Wait4),
@@ -688,10 +688,10 @@ var ARM64 = &kernel.SyscallTable{
 		270: syscalls.Supported("process_vm_readv", ProcessVMReadv),
 		271: syscalls.Supported("process_vm_writev", ProcessVMWritev),
 		272: syscalls.CapError("kcmp", linux.CAP_SYS_PTRACE, "", nil),
-		273: syscalls.CapError("finit_module", linux.CAP_SYS_MODULE, "", nil),
+		273: syscalls.CapErrorPoint("finit_module", linux.CAP_SYS_MODULE, PointFinitModule, "", nil),
 		274: syscalls.ErrorWithEvent("sched_setattr", linuxerr.ENOSYS, "gVisor does not implement a scheduler.", []string{"gvisor.dev/issue/264"}), // TODO(b/118902272)
 		275: syscalls.ErrorWithEvent("sched_getattr", linuxerr.ENOSYS, "gVisor does not implement a scheduler.", []string{"gvisor.dev/issue/264"}), // TODO(b/118902272)
-		276: syscalls.Supported("renameat2", Renameat2),
+		276: syscalls.SupportedPoint("renameat2", Renameat2, PointRenameat2),
 		277: syscalls.Supported("seccomp", Seccomp),
 		278: syscalls.Supported("getrandom", GetRandom),
 		279: syscalls.Supported("memfd_create", MemfdCreate),
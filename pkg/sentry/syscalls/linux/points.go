@@ -16,14 +16,19 @@ package linux
 
 import (
 	"fmt"
+	"path"
 
 	"google.golang.org/protobuf/proto"
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/usermem"
 )
 
@@ -992,3 +997,515 @@ func PointSocketpair(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.Conte
 	p.Exit = newExitMaybe(info)
 	return p, pb.MessageType_MESSAGE_SYSCALL_SOCKETPAIR
 }
+
+// getResolvedPath returns pathname as an absolute path, relative to dirfd or
+// the working directory for AT_FDCWD. Symbolic links are not followed.
+func getResolvedPath(t *kernel.Task, dirfd int32, pathname string) string {
+	if path.IsAbs(pathname) {
+		return path.Clean(pathname)
+	}
+	if dirfd != linux.AT_FDCWD {
+		return path.Join(getFilePath(t, dirfd), pathname)
+	}
+	wd := t.FSContext().WorkingDirectory()
+	if !wd.Ok() {
+		return "[err: no working directory]"
+	}
+	defer wd.DecRef(t)
+
+	root := t.MountNamespace().Root(t)
+	defer root.DecRef(t)
+	dir, err := t.Kernel().VFS().PathnameWithDeleted(t, root, wd)
+	if err != nil {
+		return fmt.Sprintf("[err: %v]", err)
+	}
+	return path.Join(dir, pathname)
+}
+
+// statAt returns the attributes of the file at pathname relative to dirfd, or
+// of the file referred by dirfd if pathname is empty.
+func statAt(t *kernel.Task, dirfd int32, pathname string, follow shouldFollowFinalSymlink, mask uint32) (linux.Statx, error) {
+	if pathname == "" {
+		file := t.GetFile(dirfd)
+		if file == nil {
+			return linux.Statx{}, linuxerr.EBADF
+		}
+		defer file.DecRef(t)
+		return file.Stat(t, vfs.StatOptions{Mask: mask})
+	}
+	tpop, err := getTaskPathOperation(t, dirfd, fspath.Parse(pathname), disallowEmptyPath, follow)
+	if err != nil {
+		return linux.Statx{}, err
+	}
+	defer tpop.Release(t)
+	return t.Kernel().VFS().StatAt(t, t.Credentials(), &tpop.pop, &vfs.StatOptions{Mask: mask})
+}
+
+// copyInPathMaybe returns the path at addr, or an empty string if it can't be
+// read.
+func copyInPathMaybe(t *kernel.Task, addr hostarch.Addr) string {
+	if addr == 0 {
+		return ""
+	}
+	pathname, _ := t.CopyInString(addr, linux.PATH_MAX)
+	return pathname
+}
+
+// pointUnlinkHelper converts unlink(2), unlinkat(2) and rmdir(2) syscalls to
+// proto.
+func pointUnlinkHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo, dirfd int32, pathAddr hostarch.Addr, flags int32) (proto.Message, pb.MessageType) {
+	p := &pb.Unlink{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          int64(dirfd),
+		Pathname:    copyInPathMaybe(t, pathAddr),
+		Flags:       flags,
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, dirfd)
+	}
+	if fields.Local.Contains(seccheck.FieldSyscallResolvedPath) {
+		p.ResolvedPath = getResolvedPath(t, dirfd, p.Pathname)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_UNLINK
+}
+
+// PointUnlink calls pointUnlinkHelper to convert unlink(2) syscall to proto.
+func PointUnlink(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointUnlinkHelper(t, fields, cxtData, info, linux.AT_FDCWD, info.Args[0].Pointer(), 0)
+}
+
+// PointUnlinkat calls pointUnlinkHelper to convert unlinkat(2) syscall to
+// proto.
+func PointUnlinkat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointUnlinkHelper(t, fields, cxtData, info, info.Args[0].Int(), info.Args[1].Pointer(), info.Args[2].Int())
+}
+
+// PointRmdir calls pointUnlinkHelper to convert rmdir(2) syscall to proto.
+func PointRmdir(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointUnlinkHelper(t, fields, cxtData, info, linux.AT_FDCWD, info.Args[0].Pointer(), linux.AT_REMOVEDIR)
+}
+
+// pointRenameHelper converts rename(2), renameat(2) and renameat2(2) syscalls
+// to proto.
+func pointRenameHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo, oldDirfd int32, oldPathAddr hostarch.Addr, newDirfd int32, newPathAddr hostarch.Addr, flags uint32) (proto.Message, pb.MessageType) {
+	p := &pb.Rename{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		OldFd:       int64(oldDirfd),
+		OldPathname: copyInPathMaybe(t, oldPathAddr),
+		NewFd:       int64(newDirfd),
+		NewPathname: copyInPathMaybe(t, newPathAddr),
+		Flags:       flags,
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.OldFdPath = getFilePath(t, oldDirfd)
+		p.NewFdPath = getFilePath(t, newDirfd)
+	}
+	if fields.Local.Contains(seccheck.FieldSyscallResolvedPath) {
+		p.OldResolvedPath = getResolvedPath(t, oldDirfd, p.OldPathname)
+		p.NewResolvedPath = getResolvedPath(t, newDirfd, p.NewPathname)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_RENAME
+}
+
+// PointRename calls pointRenameHelper to convert rename(2) syscall to proto.
+func PointRename(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointRenameHelper(t, fields, cxtData, info, linux.AT_FDCWD, info.Args[0].Pointer(), linux.AT_FDCWD, info.Args[1].Pointer(), 0)
+}
+
+// PointRenameat calls pointRenameHelper to convert renameat(2) syscall to
+// proto.
+func PointRenameat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointRenameHelper(t, fields, cxtData, info, info.Args[0].Int(), info.Args[1].Pointer(), info.Args[2].Int(), info.Args[3].Pointer(), 0)
+}
+
+// PointRenameat2 calls pointRenameHelper to convert renameat2(2) syscall to
+// proto.
+func PointRenameat2(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointRenameHelper(t, fields, cxtData, info, info.Args[0].Int(), info.Args[1].Pointer(), info.Args[2].Int(), info.Args[3].Pointer(), info.Args[4].Uint())
+}
+
+// pointChmodHelper converts chmod(2), fchmod(2) and fchmodat(2) syscalls to
+// proto. If pathAddr is 0, the file is referred by fd.
+func pointChmodHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo, fd int32, pathAddr hostarch.Addr, mode uint32) (proto.Message, pb.MessageType) {
+	p := &pb.Chmod{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          int64(fd),
+		Pathname:    copyInPathMaybe(t, pathAddr),
+		Mode:        mode,
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, fd)
+	}
+	if fields.Local.Contains(seccheck.FieldSyscallResolvedPath) {
+		p.ResolvedPath = getResolvedPath(t, fd, p.Pathname)
+	}
+	// The old mode is only available before the syscall executes.
+	if !info.Exit && fields.Local.Contains(seccheck.FieldSyscallOldAttrs) {
+		if stat, err := statAt(t, fd, p.Pathname, followFinalSymlink, linux.STATX_MODE); err == nil { // if NO error
+			p.HasOldMode = true
+			p.OldMode = uint32(stat.Mode)
+		}
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_CHMOD
+}
+
+// PointChmod calls pointChmodHelper to convert chmod(2) syscall to proto.
+func PointChmod(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointChmodHelper(t, fields, cxtData, info, linux.AT_FDCWD, info.Args[0].Pointer(), uint32(info.Args[1].ModeT()))
+}
+
+// PointFchmod calls pointChmodHelper to convert fchmod(2) syscall to proto.
+func PointFchmod(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointChmodHelper(t, fields, cxtData, info, info.Args[0].Int(), 0, uint32(info.Args[1].ModeT()))
+}
+
+// PointFchmodat calls pointChmodHelper to convert fchmodat(2) syscall to
+// proto.
+func PointFchmodat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointChmodHelper(t, fields, cxtData, info, info.Args[0].Int(), info.Args[1].Pointer(), uint32(info.Args[2].ModeT()))
+}
+
+// pointChownHelper converts chown(2), fchown(2), lchown(2) and fchownat(2)
+// syscalls to proto. If pathAddr is 0, the file is referred by fd.
+func pointChownHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo, fd int32, pathAddr hostarch.Addr, uid, gid uint32, flags int32) (proto.Message, pb.MessageType) {
+	p := &pb.Chown{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          int64(fd),
+		Pathname:    copyInPathMaybe(t, pathAddr),
+		Uid:         uid,
+		Gid:         gid,
+		Flags:       flags,
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, fd)
+	}
+	if fields.Local.Contains(seccheck.FieldSyscallResolvedPath) {
+		p.ResolvedPath = getResolvedPath(t, fd, p.Pathname)
+	}
+	// The old owner is only available before the syscall executes.
+	if !info.Exit && fields.Local.Contains(seccheck.FieldSyscallOldAttrs) {
+		follow := shouldFollowFinalSymlink(flags&linux.AT_SYMLINK_NOFOLLOW == 0)
+		if stat, err := statAt(t, fd, p.Pathname, follow, linux.STATX_UID|linux.STATX_GID); err == nil { // if NO error
+			userns := t.UserNamespace()
+			p.HasOldOwner = true
+			p.OldUid = uint32(auth.KUID(stat.UID).In(userns).OrOverflow())
+			p.OldGid = uint32(auth.KGID(stat.GID).In(userns).OrOverflow())
+		}
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_CHOWN
+}
+
+// PointChown calls pointChownHelper to convert chown(2) syscall to proto.
+func PointChown(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointChownHelper(t, fields, cxtData, info, linux.AT_FDCWD, info.Args[0].Pointer(), info.Args[1].Uint(), info.Args[2].Uint(), 0)
+}
+
+// PointFchown calls pointChownHelper to convert fchown(2) syscall to proto.
+func PointFchown(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointChownHelper(t, fields, cxtData, info, info.Args[0].Int(), 0, info.Args[1].Uint(), info.Args[2].Uint(), 0)
+}
+
+// PointLchown calls pointChownHelper to convert lchown(2) syscall to proto.
+func PointLchown(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointChownHelper(t, fields, cxtData, info, linux.AT_FDCWD, info.Args[0].Pointer(), info.Args[1].Uint(), info.Args[2].Uint(), linux.AT_SYMLINK_NOFOLLOW)
+}
+
+// PointFchownat calls pointChownHelper to convert fchownat(2) syscall to
+// proto.
+func PointFchownat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointChownHelper(t, fields, cxtData, info, info.Args[0].Int(), info.Args[1].Pointer(), info.Args[2].Uint(), info.Args[3].Uint(), info.Args[4].Int())
+}
+
+// pointLinkHelper converts link(2) and linkat(2) syscalls to proto.
+func pointLinkHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo, oldDirfd int32, oldPathAddr hostarch.Addr, newDirfd int32, newPathAddr hostarch.Addr, flags int32) (proto.Message, pb.MessageType) {
+	p := &pb.Link{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		OldFd:       int64(oldDirfd),
+		OldPathname: copyInPathMaybe(t, oldPathAddr),
+		NewFd:       int64(newDirfd),
+		NewPathname: copyInPathMaybe(t, newPathAddr),
+		Flags:       flags,
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.OldFdPath = getFilePath(t, oldDirfd)
+		p.NewFdPath = getFilePath(t, newDirfd)
+	}
+	if fields.Local.Contains(seccheck.FieldSyscallResolvedPath) {
+		p.OldResolvedPath = getResolvedPath(t, oldDirfd, p.OldPathname)
+		p.NewResolvedPath = getResolvedPath(t, newDirfd, p.NewPathname)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_LINK
+}
+
+// PointLink calls pointLinkHelper to convert link(2) syscall to proto.
+func PointLink(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointLinkHelper(t, fields, cxtData, info, linux.AT_FDCWD, info.Args[0].Pointer(), linux.AT_FDCWD, info.Args[1].Pointer(), 0)
+}
+
+// PointLinkat calls pointLinkHelper to convert linkat(2) syscall to proto.
+func PointLinkat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointLinkHelper(t, fields, cxtData, info, info.Args[0].Int(), info.Args[1].Pointer(), info.Args[2].Int(), info.Args[3].Pointer(), info.Args[4].Int())
+}
+
+// pointSymlinkHelper converts symlink(2) and symlinkat(2) syscalls to proto.
+func pointSymlinkHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo, targetAddr hostarch.Addr, newDirfd int32, newPathAddr hostarch.Addr) (proto.Message, pb.MessageType) {
+	p := &pb.Symlink{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Target:      copyInPathMaybe(t, targetAddr),
+		NewFd:       int64(newDirfd),
+		NewPathname: copyInPathMaybe(t, newPathAddr),
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.NewFdPath = getFilePath(t, newDirfd)
+	}
+	if fields.Local.Contains(seccheck.FieldSyscallResolvedPath) {
+		p.NewResolvedPath = getResolvedPath(t, newDirfd, p.NewPathname)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_SYMLINK
+}
+
+// PointSymlink calls pointSymlinkHelper to convert symlink(2) syscall to proto.
+func PointSymlink(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointSymlinkHelper(t, fields, cxtData, info, info.Args[0].Pointer(), linux.AT_FDCWD, info.Args[1].Pointer())
+}
+
+// PointSymlinkat calls pointSymlinkHelper to convert symlinkat(2) syscall to
+// proto.
+func PointSymlinkat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointSymlinkHelper(t, fields, cxtData, info, info.Args[0].Pointer(), info.Args[1].Int(), info.Args[2].Pointer())
+}
+
+// PointMmap converts mmap(2) syscall to proto.
+func PointMmap(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Mmap{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Addr:        uint64(info.Args[0].Pointer()),
+		Length:      info.Args[1].Uint64(),
+		Prot:        info.Args[2].Int(),
+		Flags:       info.Args[3].Int(),
+		Fd:          int64(info.Args[4].Int()),
+		Offset:      info.Args[5].Uint64(),
+	}
+
+	if p.Flags&linux.MAP_ANONYMOUS == 0 && fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, int32(p.Fd))
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_MMAP
+}
+
+// PointMprotect converts mprotect(2) syscall to proto.
+func PointMprotect(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Mprotect{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Addr:        uint64(info.Args[0].Pointer()),
+		Length:      info.Args[1].Uint64(),
+		Prot:        info.Args[2].Int(),
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_MPROTECT
+}
+
+// PointMount converts mount(2) syscall to proto.
+func PointMount(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Mount{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Flags:       info.Args[3].Uint64(),
+	}
+	// For null-terminated strings related to mount(2), Linux copies in at most
+	// a page worth of data.
+	if addr := info.Args[0].Pointer(); addr != 0 {
+		p.Source, _ = t.CopyInString(addr, hostarch.PageSize)
+	}
+	if addr := info.Args[1].Pointer(); addr != 0 {
+		p.Target, _ = t.CopyInString(addr, hostarch.PageSize)
+	}
+	if addr := info.Args[2].Pointer(); addr != 0 {
+		p.Fstype, _ = t.CopyInString(addr, hostarch.PageSize)
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallResolvedPath) {
+		p.ResolvedPath = getResolvedPath(t, linux.AT_FDCWD, p.Target)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_MOUNT
+}
+
+// PointUmount2 converts umount2(2) syscall to proto.
+func PointUmount2(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Umount{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Target:      copyInPathMaybe(t, info.Args[0].Pointer()),
+		Flags:       info.Args[1].Int(),
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallResolvedPath) {
+		p.ResolvedPath = getResolvedPath(t, linux.AT_FDCWD, p.Target)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_UMOUNT
+}
+
+// PointPtrace converts ptrace(2) syscall to proto.
+func PointPtrace(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Ptrace{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Request:     info.Args[0].Int64(),
+		Pid:         info.Args[1].Int(),
+		Addr:        uint64(info.Args[2].Pointer()),
+		Data:        uint64(info.Args[3].Pointer()),
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_PTRACE
+}
+
+// setsockoptMaxOptval is the maximum number of bytes of the option value that
+// is collected by PointSetsockopt.
+const setsockoptMaxOptval = 64
+
+// PointSetsockopt converts setsockopt(2) syscall to proto.
+func PointSetsockopt(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Setsockopt{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          info.Args[0].Int(),
+		Level:       info.Args[1].Int(),
+		Optname:     info.Args[2].Int(),
+		Optlen:      info.Args[4].Uint(),
+	}
+	if addr := info.Args[3].Pointer(); addr != 0 && p.Optlen > 0 {
+		optval := make([]byte, min(p.Optlen, setsockoptMaxOptval))
+		if _, err := t.CopyInBytes(addr, optval); err == nil { // if NO error
+			p.Optval = optval
+		}
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, p.Fd)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_SETSOCKOPT
+}
+
+// PointInitModule converts init_module(2) syscall to proto.
+func PointInitModule(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.InitModule{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          -1,
+		Length:      info.Args[1].Uint64(),
+	}
+	if addr := info.Args[2].Pointer(); addr != 0 {
+		p.ParamValues, _ = t.CopyInString(addr, hostarch.PageSize)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_INIT_MODULE
+}
+
+// PointFinitModule converts finit_module(2) syscall to proto.
+func PointFinitModule(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.InitModule{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          int64(info.Args[0].Int()),
+		Flags:       info.Args[2].Int(),
+	}
+	if addr := info.Args[1].Pointer(); addr != 0 {
+		p.ParamValues, _ = t.CopyInString(addr, hostarch.PageSize)
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, int32(p.Fd))
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_INIT_MODULE
+}
+
+// moduleNameLen is MODULE_NAME_LEN from include/linux/module.h.
+const moduleNameLen = 56
+
+// PointDeleteModule converts delete_module(2) syscall to proto.
+func PointDeleteModule(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.DeleteModule{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Flags:       info.Args[1].Uint(),
+	}
+	if addr := info.Args[0].Pointer(); addr != 0 {
+		p.Name, _ = t.CopyInString(addr, moduleNameLen)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_DELETE_MODULE
+}
+
+// PointCapset converts capset(2) syscall to proto.
+func PointCapset(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Capset{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+	}
+	var hdr linux.CapUserHeader
+	if _, err := hdr.CopyIn(t, info.Args[0].Pointer()); err == nil { // if NO error
+		p.Version = hdr.Version
+		p.Pid = hdr.Pid
+
+		dataAddr := info.Args[1].Pointer()
+		switch hdr.Version {
+		case linux.LINUX_CAPABILITY_VERSION_1:
+			var data linux.CapUserData
+			if _, err := data.CopyIn(t, dataAddr); err == nil { // if NO error
+				p.Effective = uint64(data.Effective)
+				p.Permitted = uint64(data.Permitted)
+				p.Inheritable = uint64(data.Inheritable)
+			}
+		case linux.LINUX_CAPABILITY_VERSION_2, linux.LINUX_CAPABILITY_VERSION_3:
+			var data [2]linux.CapUserData
+			if _, err := linux.CopyCapUserDataSliceIn(t, dataAddr, data[:]); err == nil { // if NO error
+				p.Effective = uint64(data[0].Effective) | uint64(data[1].Effective)<<32
+				p.Permitted = uint64(data[0].Permitted) | uint64(data[1].Permitted)<<32
+				p.Inheritable = uint64(data[0].Inheritable) | uint64(data[1].Inheritable)<<32
+			}
+		}
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_CAPSET
+}
@@ -126,6 +126,11 @@ func Mmap(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *
 	}
 
 	rv, err := t.MemoryManager().MMap(t, opts)
+	if err == nil && opts.Perms.Execute {
+		if ar, ok := rv.ToRange(opts.Length); ok {
+			t.PerfEventMmap(ar)
+		}
+	}
 	return uintptr(rv), nil, err
 }
 
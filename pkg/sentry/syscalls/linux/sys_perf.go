@@ -0,0 +1,137 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/perf"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+)
+
+// PerfEventOpen implements linux syscall perf_event_open(2).
+func PerfEventOpen(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	attrAddr := args[0].Pointer()
+	pid := kernel.ThreadID(args[1].Int())
+	cpu := args[2].Int()
+	groupFD := args[3].Int()
+	flags := args[4].Uint()
+
+	if flags&^(linux.PERF_FLAG_FD_NO_GROUP|linux.PERF_FLAG_FD_OUTPUT|linux.PERF_FLAG_PID_CGROUP|linux.PERF_FLAG_FD_CLOEXEC) != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	attr, err := copyInPerfEventAttr(t, attrAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	if attr.Type != linux.PERF_TYPE_SOFTWARE {
+		t.Kernel().EmitUnimplementedEvent(t, sysno)
+		return 0, nil, linuxerr.ENOENT
+	}
+	if flags&linux.PERF_FLAG_FD_OUTPUT != 0 {
+		// Broken since Linux 2.6.35.
+		return 0, nil, linuxerr.EINVAL
+	}
+	if flags&linux.PERF_FLAG_PID_CGROUP != 0 {
+		return 0, nil, linuxerr.EOPNOTSUPP
+	}
+	if pid == -1 && cpu == -1 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if cpu < -1 || cpu >= int32(t.Kernel().ApplicationCores()) {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if pid == -1 {
+		// Events measuring all tasks on a CPU are not supported.
+		return 0, nil, linuxerr.EOPNOTSUPP
+	}
+	if groupFD != -1 {
+		// Event groups are not supported.
+		return 0, nil, linuxerr.EOPNOTSUPP
+	}
+
+	target := t
+	if pid != 0 {
+		if target = t.PIDNamespace().TaskWithID(pid); target == nil {
+			return 0, nil, linuxerr.ESRCH
+		}
+	}
+	if !t.CanTrace(target, false) {
+		return 0, nil, linuxerr.EACCES
+	}
+
+	file, err := perf.New(t, t.Kernel().VFS(), &perf.Options{
+		Target:       target,
+		PIDNamespace: t.PIDNamespace(),
+		CPU:          cpu,
+		Attr:         attr,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+
+	fd, err := t.NewFDFrom(0, file, kernel.FDFlags{
+		CloseOnExec: flags&linux.PERF_FLAG_FD_CLOEXEC != 0,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return uintptr(fd), nil, nil
+}
+
+// copyInPerfEventAttr copies in the struct perf_event_attr at addr, whose
+// size is given by its size field. See kernel/events/core.c:perf_copy_attr().
+func copyInPerfEventAttr(t *kernel.Task, addr hostarch.Addr) (linux.PerfEventAttr, error) {
+	var attr linux.PerfEventAttr
+	var size primitive.Uint32
+	if _, err := size.CopyIn(t, addr+4); err != nil {
+		return attr, err
+	}
+	if size == 0 {
+		size = linux.PERF_ATTR_SIZE_VER0
+	}
+	if size < linux.PERF_ATTR_SIZE_VER0 || size > hostarch.PageSize {
+		return attr, perfEventAttrSizeError(t, addr)
+	}
+
+	buf := make([]byte, max(int(size), attr.SizeBytes()))
+	if _, err := t.CopyInBytes(addr, buf[:size]); err != nil {
+		return attr, err
+	}
+	// Newer versions of the struct are accepted as long as the fields that
+	// we don't know about are zero.
+	for _, b := range buf[attr.SizeBytes():] {
+		if b != 0 {
+			return attr, perfEventAttrSizeError(t, addr)
+		}
+	}
+	attr.UnmarshalUnsafe(buf)
+	attr.Size = uint32(size)
+	return attr, nil
+}
+
+// perfEventAttrSizeError reports the size of struct perf_event_attr supported
+// by the sentry to the application, and returns E2BIG.
+func perfEventAttrSizeError(t *kernel.Task, addr hostarch.Addr) error {
+	size := primitive.Uint32(linux.PERF_ATTR_SIZE_VER8)
+	if _, err := size.CopyOut(t, addr+4); err != nil {
+		return err
+	}
+	return linuxerr.E2BIG
+}
@@ -126,3 +126,11 @@ func CapError(name string, c linux.Capability, note string, urls []string) kerne
 		URLs:         urls,
 	}
 }
+
+// CapErrorPoint gives a syscall function that checks for capability c with a
+// corresponding seccheck.Point. See CapError.
+func CapErrorPoint(name string, c linux.Capability, cb kernel.SyscallToProto, note string, urls []string) kernel.Syscall {
+	sys := CapError(name, c, note, urls)
+	sys.PointCallback = cb
+	return sys
+}
@@ -76,6 +76,26 @@ const (
 	Mapped
 )
 
+// String implements fmt.Stringer.String.
+func (k MemoryKind) String() string {
+	switch k {
+	case System:
+		return "system"
+	case Anonymous:
+		return "anonymous"
+	case PageCache:
+		return "page cache"
+	case Tmpfs:
+		return "tmpfs"
+	case Ramdiskfs:
+		return "ramdiskfs"
+	case Mapped:
+		return "mapped"
+	default:
+		return fmt.Sprintf("MemoryKind(%d)", int(k))
+	}
+}
+
 // memoryStats tracks application memory usage in bytes. All fields correspond to the
 // memory category with the same name. This object is thread-safe if accessed
 // through the provided methods. The public fields may be safely accessed
@@ -340,8 +340,18 @@ func (od *objectDecoder) load(slot int, objPtr reflect.Value, wait bool, fn func
 	// to match what's expected by the decoder. The "slot" parameter here
 	// is in terms of the local type, where the fields in the encoded
 	// object are in terms of the wire object's type, which might be in a
-	// different order (but will have the same fields).
-	v := *od.encoded.Field(od.rte.FieldOrder[slot])
+	// different order, or lack some fields if the type has changed.
+	encodedSlot := od.rte.FieldOrder[slot]
+	if encodedSlot < 0 {
+		// The field wasn't saved; leave its default value.
+		return
+	}
+	od.loadEncoded(encodedSlot, objPtr, wait, fn)
+}
+
+// loadEncoded loads the field in the given slot of the encoded object.
+func (od *objectDecoder) loadEncoded(slot int, objPtr reflect.Value, wait bool, fn func()) {
+	v := *od.encoded.Field(slot)
 	od.ds.decodeObject(od.ods, objPtr.Elem(), v)
 	if wait {
 		// Mark this individual object a blocker.
@@ -349,6 +359,17 @@ func (od *objectDecoder) load(slot int, objPtr reflect.Value, wait bool, fn func
 	}
 }
 
+// encodedSlot returns the slot of the field with the given name in the encoded
+// object, or -1 if the object was encoded without it.
+func (od *objectDecoder) encodedSlot(name string) int {
+	for i, field := range od.rte.Encoded.Fields {
+		if field == name {
+			return i
+		}
+	}
+	return -1
+}
+
 // aterLoad implements Source.AfterLoad.
 func (od *objectDecoder) afterLoad(fn func()) {
 	// Queue the local callback; this will execute when all of the above
@@ -0,0 +1,434 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pretty
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+
+	"gvisor.dev/gvisor/pkg/state/wire"
+)
+
+// objectPair is a pair of objects, one from each graph being compared.
+type objectPair struct {
+	a, b uint64
+}
+
+// differ finds structural differences between two object graphs.
+//
+// Object IDs are assigned in traversal order when saving, so they aren't
+// stable across images. Instead, objects are matched by walking both graphs
+// in parallel from their roots: objects referred to by corresponding fields
+// of matched objects are matched themselves.
+type differ struct {
+	ga, gb *Graph
+
+	// diffs is the list of differences found.
+	diffs []string
+
+	// queue is the set of matched object pairs left to compare.
+	queue []objectPair
+
+	// matched is the set of object pairs that have been queued.
+	matched map[objectPair]struct{}
+}
+
+// newDiffer returns a differ comparing ga and gb.
+func newDiffer(ga, gb *Graph) *differ {
+	return &differ{
+		ga:      ga,
+		gb:      gb,
+		matched: make(map[objectPair]struct{}),
+	}
+}
+
+func (d *differ) report(path, format string, args ...any) {
+	d.diffs = append(d.diffs, path+": "+fmt.Sprintf(format, args...))
+}
+
+// match queues the given objects for comparison if they haven't been matched
+// already.
+func (d *differ) match(a, b uint64) {
+	p := objectPair{a, b}
+	if _, ok := d.matched[p]; ok {
+		return
+	}
+	d.matched[p] = struct{}{}
+	d.queue = append(d.queue, p)
+}
+
+// run compares all objects reachable from the roots of both graphs and
+// returns the differences found.
+func (d *differ) run() []string {
+	if d.ga.Root() == nil || d.gb.Root() == nil {
+		return d.diffs
+	}
+	d.match(1, 1)
+	for len(d.queue) > 0 {
+		p := d.queue[0]
+		d.queue = d.queue[1:]
+		a, b := d.ga.Objects[p.a], d.gb.Objects[p.b]
+		path := fmt.Sprintf("%s#%d", d.ga.TypeName(a), p.a)
+		d.diff(path, a, b)
+	}
+	return d.diffs
+}
+
+// short returns a short description of obj in g.
+func short(g *Graph, obj wire.Object) string {
+	switch x := obj.(type) {
+	case wire.Nil:
+		return "zero"
+	case *wire.String:
+		return fmt.Sprintf("%q", string(*x))
+	case *wire.Complex64:
+		return fmt.Sprintf("%v", complex128(*x))
+	case *wire.Complex128:
+		return fmt.Sprintf("%v", complex128(*x))
+	case *wire.Ref:
+		if x.Root == 0 {
+			return "nil"
+		}
+		return "&" + g.TypeName(g.Objects[uint64(x.Root)])
+	case *wire.Slice:
+		return fmt.Sprintf("slice{len:%d}", x.Length)
+	case *wire.Array:
+		return fmt.Sprintf("%s{...}", g.TypeName(x))
+	case *wire.Map:
+		return fmt.Sprintf("map{len:%d}", len(x.Keys))
+	case *wire.Struct:
+		return fmt.Sprintf("%s{...}", g.TypeName(x))
+	case *wire.Interface:
+		return fmt.Sprintf("interface{%s}", typeSpecName(g, x.Type))
+	default:
+		return fmt.Sprintf("%v", obj)
+	}
+}
+
+// typeSpecName returns the name of the type described by t in g.
+func typeSpecName(g *Graph, t wire.TypeSpec) string {
+	switch x := t.(type) {
+	case wire.TypeID:
+		if typ := g.Type(x); typ != nil {
+			return typ.Name
+		}
+		return fmt.Sprintf("!missing-type-%d", x)
+	case wire.TypeSpecNil:
+		return "nil"
+	case *wire.TypeSpecPointer:
+		return "*" + typeSpecName(g, x.Type)
+	case *wire.TypeSpecArray:
+		return fmt.Sprintf("[%d]%s", x.Count, typeSpecName(g, x.Type))
+	case *wire.TypeSpecSlice:
+		return "[]" + typeSpecName(g, x.Type)
+	case *wire.TypeSpecMap:
+		return fmt.Sprintf("map[%s]%s", typeSpecName(g, x.Key), typeSpecName(g, x.Value))
+	default:
+		return fmt.Sprintf("%T", t)
+	}
+}
+
+// dotsString returns the path of fields and indices in ref.
+func dotsString(ref *wire.Ref) string {
+	var s string
+	// See wire.Ref.Dots. The path is specified in reverse order.
+	for i := len(ref.Dots) - 1; i >= 0; i-- {
+		switch dot := ref.Dots[i].(type) {
+		case *wire.FieldName:
+			s += "." + string(*dot)
+		case wire.Index:
+			s += fmt.Sprintf("[%d]", dot)
+		}
+	}
+	return s
+}
+
+// isPrimitive returns true if obj doesn't refer to or contain other objects.
+func isPrimitive(obj wire.Object) bool {
+	switch obj.(type) {
+	case *wire.Ref, *wire.Slice, *wire.Array, *wire.Map, *wire.Struct, *wire.Interface:
+		return false
+	default:
+		return true
+	}
+}
+
+// equalPrimitives returns true if the primitive objects a and b are equal.
+func equalPrimitives(a, b wire.Object) bool {
+	switch x := a.(type) {
+	case *wire.String:
+		y, ok := b.(*wire.String)
+		return ok && *x == *y
+	case wire.Float32:
+		y, ok := b.(wire.Float32)
+		return ok && equalFloats(complex(float64(x), 0), complex(float64(y), 0))
+	case wire.Float64:
+		y, ok := b.(wire.Float64)
+		return ok && equalFloats(complex(float64(x), 0), complex(float64(y), 0))
+	case *wire.Complex64:
+		y, ok := b.(*wire.Complex64)
+		return ok && equalFloats(complex128(*x), complex128(*y))
+	case *wire.Complex128:
+		y, ok := b.(*wire.Complex128)
+		return ok && equalFloats(complex128(*x), complex128(*y))
+	default:
+		return a == b
+	}
+}
+
+// equalFloats returns true if x and y are equal or have the same
+// representation, as identical NaNs do.
+func equalFloats(x, y complex128) bool {
+	return x == y || math.Float64bits(real(x)) == math.Float64bits(real(y)) && math.Float64bits(imag(x)) == math.Float64bits(imag(y))
+}
+
+// diff compares a, from d.ga, and b, from d.gb, and records differences under
+// path.
+func (d *differ) diff(path string, a, b wire.Object) {
+	if isPrimitive(a) && isPrimitive(b) {
+		if !equalPrimitives(a, b) {
+			d.report(path, "%s -> %s", short(d.ga, a), short(d.gb, b))
+		}
+		return
+	}
+	switch x := a.(type) {
+	case *wire.Ref:
+		y, ok := b.(*wire.Ref)
+		if !ok {
+			break
+		}
+		if x.Root == 0 || y.Root == 0 {
+			if x.Root != y.Root {
+				d.report(path, "%s -> %s", short(d.ga, x), short(d.gb, y))
+			}
+			return
+		}
+		if dx, dy := dotsString(x), dotsString(y); dx != dy {
+			d.report(path, "reference to %s -> reference to %s", dx, dy)
+		}
+		d.match(uint64(x.Root), uint64(y.Root))
+		return
+	case *wire.Slice:
+		y, ok := b.(*wire.Slice)
+		if !ok {
+			break
+		}
+		if x.Length != y.Length {
+			d.report(path, "len %d -> len %d", x.Length, y.Length)
+		}
+		if x.Ref.Root == 0 || y.Ref.Root == 0 {
+			return
+		}
+		d.diffElements(path, d.ga.Elements(x), d.gb.Elements(y))
+		return
+	case *wire.Array:
+		y, ok := b.(*wire.Array)
+		if !ok {
+			break
+		}
+		if len(x.Contents) != len(y.Contents) {
+			d.report(path, "len %d -> len %d", len(x.Contents), len(y.Contents))
+		}
+		d.diffElements(path, x.Contents, y.Contents)
+		return
+	case *wire.Map:
+		y, ok := b.(*wire.Map)
+		if !ok {
+			break
+		}
+		d.diffMaps(path, x, y)
+		return
+	case *wire.Struct:
+		y, ok := b.(*wire.Struct)
+		if !ok {
+			break
+		}
+		d.diffStructs(path, x, y)
+		return
+	case *wire.Interface:
+		y, ok := b.(*wire.Interface)
+		if !ok {
+			break
+		}
+		if tx, ty := typeSpecName(d.ga, x.Type), typeSpecName(d.gb, y.Type); tx != ty {
+			d.report(path, "%s -> %s", short(d.ga, x), short(d.gb, y))
+			return
+		}
+		d.diff(path, x.Value, y.Value)
+		return
+	}
+	d.report(path, "%s -> %s", short(d.ga, a), short(d.gb, b))
+}
+
+// diffElements compares the elements of two arrays. Differences between
+// primitive elements are summarized, since large arrays of bytes are common.
+func (d *differ) diffElements(path string, a, b []wire.Object) {
+	n := min(len(a), len(b))
+	var (
+		changed int
+		first   int
+	)
+	for i := 0; i < n; i++ {
+		if isPrimitive(a[i]) && isPrimitive(b[i]) {
+			if !equalPrimitives(a[i], b[i]) {
+				if changed == 0 {
+					first = i
+				}
+				changed++
+			}
+			continue
+		}
+		d.diff(fmt.Sprintf("%s[%d]", path, i), a[i], b[i])
+	}
+	switch {
+	case changed == 1:
+		d.report(fmt.Sprintf("%s[%d]", path, first), "%s -> %s", short(d.ga, a[first]), short(d.gb, b[first]))
+	case changed > 1:
+		d.report(path, "%d of %d elements differ, first at index %d", changed, n, first)
+	}
+}
+
+// diffMaps compares two maps, matching entries by key.
+func (d *differ) diffMaps(path string, a, b *wire.Map) {
+	// Keys are matched by their formatted value, which is only meaningful
+	// for keys that don't refer to other objects.
+	p := &printer{}
+	bKeys := make(map[string]int, len(b.Keys))
+	for i, key := range b.Keys {
+		s, _ := p.format(0, 0, key)
+		bKeys[s] = i
+	}
+	for i, key := range a.Keys {
+		s, _ := p.format(0, 0, key)
+		j, ok := bKeys[s]
+		if !ok {
+			d.report(fmt.Sprintf("%s[%s]", path, s), "removed")
+			continue
+		}
+		delete(bKeys, s)
+		d.diff(fmt.Sprintf("%s[%s]", path, s), a.Values[i], b.Values[j])
+	}
+	for _, key := range b.Keys {
+		s, _ := p.format(0, 0, key)
+		if _, ok := bKeys[s]; ok {
+			d.report(fmt.Sprintf("%s[%s]", path, s), "added")
+		}
+	}
+}
+
+// diffStructs compares two structs, matching fields by name.
+func (d *differ) diffStructs(path string, a, b *wire.Struct) {
+	ta, tb := d.ga.Type(a.TypeID), d.gb.Type(b.TypeID)
+	if ta == nil || tb == nil {
+		if (ta == nil) != (tb == nil) {
+			d.report(path, "%s -> %s", short(d.ga, a), short(d.gb, b))
+		}
+		return
+	}
+	if ta.Name != tb.Name {
+		d.report(path, "%s -> %s", short(d.ga, a), short(d.gb, b))
+		return
+	}
+	for _, name := range ta.Fields {
+		fb := d.gb.Field(b, name)
+		if fb == nil {
+			d.report(path+"."+name, "field removed")
+			continue
+		}
+		d.diff(path+"."+name, d.ga.Field(a, name), fb)
+	}
+	for _, name := range tb.Fields {
+		if d.ga.Field(a, name) == nil {
+			d.report(path+"."+name, "field added")
+		}
+	}
+}
+
+// PrintDiff reads the streams from a and b and prints the differences between
+// them to w: changes in the number and size of objects of each type, followed
+// by structural differences between corresponding object graphs.
+func PrintDiff(w io.Writer, a, b io.Reader) error {
+	graphsA, err := ReadGraphs(a)
+	if err != nil {
+		return fmt.Errorf("reading first stream: %w", err)
+	}
+	graphsB, err := ReadGraphs(b)
+	if err != nil {
+		return fmt.Errorf("reading second stream: %w", err)
+	}
+
+	// Compare type statistics.
+	sa, sb := Summarize(graphsA), Summarize(graphsB)
+	statsB := make(map[string]TypeStats, len(sb.Types))
+	for _, ts := range sb.Types {
+		statsB[ts.Name] = ts
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "OBJECTS\tDELTA\tBYTES\tDELTA\tTYPE\n")
+	printDelta := func(ta, tb TypeStats) {
+		fmt.Fprintf(tw, "%d\t%+d\t%d\t%+d\t%s\n", tb.Objects, int64(tb.Objects-ta.Objects), tb.Bytes, int64(tb.Bytes-ta.Bytes), tb.Name)
+	}
+	for _, ta := range sa.Types {
+		tb, ok := statsB[ta.Name]
+		delete(statsB, ta.Name)
+		if !ok {
+			tb.Name = ta.Name
+		}
+		if ta != tb {
+			printDelta(ta, tb)
+		}
+	}
+	var added []TypeStats
+	for _, tb := range statsB {
+		added = append(added, tb)
+	}
+	sortTypeStats(added)
+	for _, tb := range added {
+		printDelta(TypeStats{}, tb)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\n%d -> %d objects, %d -> %d object bytes, %d -> %d data bytes\n", sa.Objects, sb.Objects, sa.ObjectBytes, sb.ObjectBytes, sa.DataBytes, sb.DataBytes)
+
+	// Compare graphs.
+	for i := 0; i < max(len(graphsA), len(graphsB)); i++ {
+		if i >= len(graphsA) {
+			fmt.Fprintf(w, "\ngraph %d: only in second stream\n", i)
+			continue
+		}
+		if i >= len(graphsB) {
+			fmt.Fprintf(w, "\ngraph %d: only in first stream\n", i)
+			continue
+		}
+		ga, gb := graphsA[i], graphsB[i]
+		diffs := newDiffer(ga, gb).run()
+		if da, db := ga.DataBytes(), gb.DataBytes(); da != db {
+			diffs = append(diffs, fmt.Sprintf("data: %d bytes -> %d bytes", da, db))
+		}
+		if len(diffs) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\ngraph %d (%s):\n", i, ga.TypeName(ga.Root()))
+		for _, diff := range diffs {
+			if _, err := fmt.Fprintf(w, "\t%s\n", diff); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
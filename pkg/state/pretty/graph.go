@@ -0,0 +1,241 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pretty
+
+import (
+	"fmt"
+	"io"
+
+	"gvisor.dev/gvisor/pkg/state"
+	"gvisor.dev/gvisor/pkg/state/wire"
+)
+
+// Graph is a single object graph read from a state stream, i.e. the result of
+// one call to state.Save, along with the non-object data that follows it.
+type Graph struct {
+	// Types are the types registered in the graph, indexed by type ID - 1.
+	Types []*wire.Type
+
+	// Objects are the objects in the graph, indexed by object ID.
+	Objects map[uint64]wire.Object
+
+	// Sizes are the encoded sizes of objects in bytes, indexed by object ID.
+	Sizes map[uint64]uint64
+
+	// TypeBytes is the number of bytes used to encode Types.
+	TypeBytes uint64
+
+	// Data are the lengths of the non-object data records that follow the
+	// graph in the stream.
+	Data []uint64
+}
+
+// Root returns the root object of the graph, or nil if the graph is empty.
+func (g *Graph) Root() wire.Object {
+	return g.Objects[1]
+}
+
+// DataBytes returns the total length of g.Data.
+func (g *Graph) DataBytes() uint64 {
+	var total uint64
+	for _, l := range g.Data {
+		total += l
+	}
+	return total
+}
+
+// Type returns the type with the given ID, or nil if no such type exists.
+func (g *Graph) Type(id wire.TypeID) *wire.Type {
+	if id == 0 || uint64(id) > uint64(len(g.Types)) {
+		return nil
+	}
+	return g.Types[id-1]
+}
+
+// TypeName returns a description of the type of obj. Types of composite
+// values that are not structs are inferred from their contents, since the
+// stream does not record them.
+func (g *Graph) TypeName(obj wire.Object) string {
+	switch x := obj.(type) {
+	case wire.Nil:
+		return "nil"
+	case wire.Bool:
+		return "bool"
+	case wire.Int:
+		return "int"
+	case wire.Uint:
+		return "uint"
+	case wire.Float32:
+		return "float32"
+	case wire.Float64:
+		return "float64"
+	case *wire.Complex64:
+		return "complex64"
+	case *wire.Complex128:
+		return "complex128"
+	case *wire.String:
+		return "string"
+	case *wire.Ref:
+		return "pointer"
+	case *wire.Slice:
+		return "slice"
+	case *wire.Interface:
+		return "interface"
+	case *wire.Array:
+		for _, elem := range x.Contents {
+			if _, ok := elem.(wire.Nil); !ok {
+				return fmt.Sprintf("[%d]%s", len(x.Contents), g.TypeName(elem))
+			}
+		}
+		return fmt.Sprintf("[%d]?", len(x.Contents))
+	case *wire.Map:
+		if len(x.Keys) == 0 {
+			return "map"
+		}
+		return fmt.Sprintf("map[%s]%s", g.TypeName(x.Keys[0]), g.TypeName(x.Values[0]))
+	case *wire.Struct:
+		if t := g.Type(x.TypeID); t != nil {
+			return t.Name
+		}
+		return "struct{}"
+	default:
+		return fmt.Sprintf("%T", obj)
+	}
+}
+
+// Field returns the value of the field with the given name in s, or nil if s
+// has no such field.
+func (g *Graph) Field(s *wire.Struct, name string) wire.Object {
+	t := g.Type(s.TypeID)
+	if t == nil {
+		return nil
+	}
+	for i, field := range t.Fields {
+		if field == name && i < s.Fields() {
+			return *s.Field(i)
+		}
+	}
+	return nil
+}
+
+// Deref returns the object referred to by ref, or nil if ref is nil or can't
+// be resolved.
+func (g *Graph) Deref(ref *wire.Ref) wire.Object {
+	obj, ok := g.Objects[uint64(ref.Root)]
+	if !ok {
+		return nil
+	}
+	// See wire.Ref.Dots. The path is specified in reverse order.
+	for i := len(ref.Dots) - 1; i >= 0 && obj != nil; i-- {
+		switch dot := ref.Dots[i].(type) {
+		case *wire.FieldName:
+			s, ok := obj.(*wire.Struct)
+			if !ok {
+				return nil
+			}
+			obj = g.Field(s, string(*dot))
+		case wire.Index:
+			a, ok := obj.(*wire.Array)
+			if !ok || int(dot) >= len(a.Contents) {
+				return nil
+			}
+			obj = a.Contents[dot]
+		}
+	}
+	return obj
+}
+
+// Elements returns the elements of the given slice, or nil if the slice is
+// nil or can't be resolved.
+func (g *Graph) Elements(s *wire.Slice) []wire.Object {
+	a, ok := g.Deref(&s.Ref).(*wire.Array)
+	if !ok || uint64(s.Length) > uint64(len(a.Contents)) {
+		return nil
+	}
+	return a.Contents[:s.Length]
+}
+
+// countingReader counts the bytes read from an io.Reader.
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+// Read implements io.Reader.Read.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += uint64(n)
+	return n, err
+}
+
+// ReadGraphs reads all object graphs from the state stream r.
+//
+// Note that the structure of the loop below must match the general structure
+// of the loop in decode.go, as in printer.printStream.
+func ReadGraphs(r io.Reader) (graphs []*Graph, err error) {
+	cr := &countingReader{r: r}
+	wr := wire.Reader{Reader: cr}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if rErr, ok := r.(error); ok {
+				err = rErr // Override return.
+				return
+			}
+			panic(r) // Propagate.
+		}
+	}()
+
+	for {
+		length, object, err := state.ReadHeader(&wr)
+		if err == io.EOF {
+			return graphs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if !object {
+			if len(graphs) == 0 {
+				graphs = append(graphs, &Graph{})
+			}
+			g := graphs[len(graphs)-1]
+			g.Data = append(g.Data, length)
+			if _, err := io.CopyN(io.Discard, cr, int64(length)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		g := &Graph{
+			Objects: make(map[uint64]wire.Object),
+			Sizes:   make(map[uint64]uint64),
+		}
+		for i := uint64(0); i < length; {
+			start := cr.n
+			encoded := wire.Load(&wr)
+			switch we := encoded.(type) {
+			case *wire.Type:
+				g.Types = append(g.Types, we)
+				g.TypeBytes += cr.n - start
+			case wire.Uint:
+				g.Objects[uint64(we)] = wire.Load(&wr)
+				g.Sizes[uint64(we)] = cr.n - start
+				i++
+			default:
+				return nil, fmt.Errorf("wanted type or object ID, got %#v", encoded)
+			}
+		}
+		graphs = append(graphs, g)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pretty
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// TypeStats are the statistics for objects of a single type.
+type TypeStats struct {
+	// Name is the name of the type, as returned by Graph.TypeName.
+	Name string
+
+	// Objects is the number of objects of the type.
+	Objects uint64
+
+	// Bytes is the number of bytes used to encode objects of the type.
+	Bytes uint64
+}
+
+// Summary summarizes the contents of a state stream.
+type Summary struct {
+	// Graphs is the number of object graphs in the stream.
+	Graphs int
+
+	// Types are statistics for each type of object, in descending order of
+	// Bytes.
+	Types []TypeStats
+
+	// Objects is the total number of objects.
+	Objects uint64
+
+	// ObjectBytes is the total number of bytes used to encode objects.
+	ObjectBytes uint64
+
+	// TypeBytes is the total number of bytes used to encode type
+	// information.
+	TypeBytes uint64
+
+	// DataBytes is the total length of non-object data.
+	DataBytes uint64
+}
+
+// Summarize returns a summary of the given graphs.
+func Summarize(graphs []*Graph) *Summary {
+	s := &Summary{Graphs: len(graphs)}
+	byName := make(map[string]*TypeStats)
+	for _, g := range graphs {
+		for id, obj := range g.Objects {
+			name := g.TypeName(obj)
+			ts, ok := byName[name]
+			if !ok {
+				ts = &TypeStats{Name: name}
+				byName[name] = ts
+			}
+			ts.Objects++
+			ts.Bytes += g.Sizes[id]
+			s.Objects++
+			s.ObjectBytes += g.Sizes[id]
+		}
+		s.TypeBytes += g.TypeBytes
+		s.DataBytes += g.DataBytes()
+	}
+	for _, ts := range byName {
+		s.Types = append(s.Types, *ts)
+	}
+	sortTypeStats(s.Types)
+	return s
+}
+
+// sortTypeStats sorts stats in descending order of size.
+func sortTypeStats(stats []TypeStats) {
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Bytes != stats[j].Bytes {
+			return stats[i].Bytes > stats[j].Bytes
+		}
+		return stats[i].Name < stats[j].Name
+	})
+}
+
+// PrintSummary reads the stream from r and prints a summary of its contents
+// by type to w.
+func PrintSummary(w io.Writer, r io.Reader) error {
+	graphs, err := ReadGraphs(r)
+	if err != nil {
+		return err
+	}
+	s := Summarize(graphs)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "OBJECTS\tBYTES\tTYPE\n")
+	for _, ts := range s.Types {
+		fmt.Fprintf(tw, "%d\t%d\t%s\n", ts.Objects, ts.Bytes, ts.Name)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "\n%d graphs, %d objects, %d object bytes, %d type bytes, %d data bytes\n", s.Graphs, s.Objects, s.ObjectBytes, s.TypeBytes, s.DataBytes)
+	return err
+}
@@ -57,6 +57,11 @@ import (
 	"gvisor.dev/gvisor/pkg/state/wire"
 )
 
+// FormatVersion is the version of the encoding produced by Save. It must be
+// incremented when the encoding changes in a way that Load can't handle, but
+// not when types change, since types are reconciled by Load; see Versioned.
+const FormatVersion = 1
+
 // objectID is a unique identifier assigned to each object to be serialized.
 // Each instance of an object is considered separately, i.e. if there are two
 // objects of the same type in the object graph being serialized, they'll be
@@ -206,6 +211,23 @@ type Type interface {
 	StateFields() []string
 }
 
+// Versioned may be implemented by Struct objects whose encoding is versioned.
+// It can be generated by the go_stateify tool for types annotated with
+// "+stateify version N".
+//
+// Objects may be loaded from an encoding with a different version, or no
+// version at all, e.g. a checkpoint taken by an older build. Fields are
+// matched by name: fields that weren't encoded keep their default values, and
+// encoded fields that no longer exist are ignored. Types may migrate such
+// fields explicitly in StateLoad using Source.Version and Source.LoadField.
+// Objects of types that are versioned neither locally nor in the encoding can
+// only be loaded if their fields are unchanged.
+type Versioned interface {
+	// StateTypeVersion returns the version of the type's encoding. Version
+	// 0 is the version of types that don't implement Versioned.
+	StateTypeVersion() uint32
+}
+
 // SaverLoader must be implemented by struct types.
 type SaverLoader interface {
 	// StateSave saves the state of the object to the given Map.
@@ -251,6 +273,32 @@ func (s Source) AfterLoad(fn func()) {
 	s.internal.afterLoad(fn)
 }
 
+// Version returns the version of the object's type when it was saved, as
+// returned by Versioned.StateTypeVersion, or 0 if it wasn't versioned.
+func (s Source) Version() uint32 {
+	return s.internal.rte.Encoded.Version
+}
+
+// HasField returns true if the object was saved with the given field.
+func (s Source) HasField(name string) bool {
+	return s.internal.encodedSlot(name) >= 0
+}
+
+// LoadField loads the saved field with the given name, passed as a pointer,
+// and returns true. If the object was saved without the field, LoadField
+// returns false and leaves objPtr unchanged.
+//
+// Unlike Load, LoadField refers to fields by their names at save time. This
+// allows types to migrate fields that have been renamed or removed since.
+func (s Source) LoadField(name string, objPtr any) bool {
+	slot := s.internal.encodedSlot(name)
+	if slot < 0 {
+		return false
+	}
+	s.internal.loadEncoded(slot, reflect.ValueOf(objPtr), false, nil)
+	return true
+}
+
 // Context returns the context object provided at load time.
 func (s Source) Context() context.Context {
 	return s.internal.ds.ctx
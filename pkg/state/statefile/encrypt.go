@@ -0,0 +1,346 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statefile
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted images record the following in their metadata.
+const (
+	// EncryptionKey is the key for the encryption algorithm in the metadata.
+	// It's absent from the metadata of images that aren't encrypted.
+	EncryptionKey = "encryption"
+
+	// EncryptionSaltKey is the key for the salt, from which the keys
+	// encrypting the image are derived, in the metadata.
+	EncryptionSaltKey = "encryption_salt"
+
+	// EncryptionAES256GCM is the only supported encryption algorithm.
+	EncryptionAES256GCM = "aes-256-gcm"
+)
+
+// Encryption purposes, which select the key encrypting a stream.
+const (
+	// EncryptionPurposeState is the purpose of the state file's data.
+	EncryptionPurposeState = "state"
+
+	// EncryptionPurposePagesMetadata is the purpose of the pages metadata file.
+	EncryptionPurposePagesMetadata = "pages_metadata"
+
+	// EncryptionPurposePages is the purpose of the pages file. See
+	// Encryption.PagesAEAD.
+	EncryptionPurposePages = "pages"
+)
+
+// EncryptionKeySize is the size of encryption keys.
+const EncryptionKeySize = 32
+
+// encryptionSaltSize is the size of the salt stored in metadata.
+const encryptionSaltSize = 32
+
+// encryptedRecordFinal is set in the header of the last record of an
+// encrypted stream.
+const encryptedRecordFinal = 1 << 31
+
+// ErrEncryptionKeyMissing is returned when reading an encrypted state file
+// without an encryption key.
+var ErrEncryptionKeyMissing = errors.New("state file is encrypted, but no encryption key was provided")
+
+// ErrNotEncrypted is returned when reading a state file that isn't encrypted
+// with an encryption key.
+var ErrNotEncrypted = errors.New("state file isn't encrypted, but an encryption key was provided")
+
+// ErrDecryption is returned when encrypted data fails authentication, e.g.
+// because it was corrupted or the encryption key is wrong.
+var ErrDecryption = errors.New("failed to decrypt state file: wrong key or corrupted data")
+
+// Encryption derives the keys encrypting the files of an image from the key
+// passed to NewEncryptedWriter or NewEncryptedReader.
+//
+// Streams, i.e. files written and read sequentially, are split into records
+// of up to stateFileChunkSize bytes, each sealed with AES-256-GCM using the
+// record's index as nonce. The header of each record holds its length and
+// marks the last record, so that truncation is detected. Records are
+// authenticated along with the state file's metadata, so that images can't
+// be spliced together.
+type Encryption struct {
+	key  []byte
+	salt []byte
+
+	// metadataDigest is the SHA-256 digest of the state file's header and
+	// metadata.
+	metadataDigest []byte
+}
+
+func newEncryption(key, salt []byte) (*Encryption, error) {
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("invalid encryption key size: got %d bytes, want %d", len(key), EncryptionKeySize)
+	}
+	return &Encryption{key: key, salt: salt}, nil
+}
+
+// NewStreamEncryption returns an Encryption for streams that aren't part of
+// an image, e.g. because they're sent over a connection, deriving their keys
+// from key and salt. Streams are only authenticated along with the salt, so
+// callers must never reuse a salt with the same key.
+func NewStreamEncryption(key, salt []byte) (*Encryption, error) {
+	return newEncryption(key, salt)
+}
+
+// encryptionFromMetadata returns the Encryption of an image with the given
+// metadata, or nil if the image isn't encrypted.
+func encryptionFromMetadata(key []byte, metadata map[string]string) (*Encryption, error) {
+	alg, ok := metadata[EncryptionKey]
+	if !ok {
+		if key != nil {
+			return nil, ErrNotEncrypted
+		}
+		return nil, nil
+	}
+	if key == nil {
+		return nil, ErrEncryptionKeyMissing
+	}
+	if alg != EncryptionAES256GCM {
+		return nil, fmt.Errorf("unsupported encryption algorithm %q", alg)
+	}
+	salt, err := hex.DecodeString(metadata[EncryptionSaltKey])
+	if err != nil || len(salt) != encryptionSaltSize {
+		return nil, fmt.Errorf("invalid encryption salt %q", metadata[EncryptionSaltKey])
+	}
+	return newEncryption(key, salt)
+}
+
+// AEAD returns the AEAD encrypting data for the given purpose. Callers that
+// seal data with it directly are responsible for never reusing a nonce.
+func (e *Encryption) AEAD(purpose string) (cipher.AEAD, error) {
+	h := hmac.New(sha256.New, e.key)
+	h.Write(e.salt)
+	h.Write([]byte(purpose))
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// PagesAEAD returns the AEAD encrypting the pages file of the MemoryFiles
+// saved with the given save ID, which may be empty. Pages are keyed per save
+// ID, so that the images of an incremental chain, which share a key and are
+// each sealed with partly random nonces, never share a nonce space.
+func (e *Encryption) PagesAEAD(saveID string) (cipher.AEAD, error) {
+	if saveID == "" {
+		return e.AEAD(EncryptionPurposePages)
+	}
+	return e.AEAD(EncryptionPurposePages + "/" + saveID)
+}
+
+func (e *Encryption) recordAAD(purpose string, hdr []byte) []byte {
+	aad := make([]byte, 0, len(e.metadataDigest)+len(purpose)+len(hdr))
+	aad = append(aad, e.metadataDigest...)
+	aad = append(aad, purpose...)
+	return append(aad, hdr...)
+}
+
+func recordNonce(aead cipher.AEAD, index uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], index)
+	return nonce
+}
+
+// encryptedWriter implements io.WriteCloser for encrypted streams.
+type encryptedWriter struct {
+	e       *Encryption
+	purpose string
+	aead    cipher.AEAD
+	out     io.Writer
+	buf     []byte
+	sealed  []byte
+	index   uint64
+	closed  bool
+}
+
+// NewStreamWriter returns a writer that encrypts data for the given purpose
+// to out. The returned writer must be closed to write the end of the stream;
+// closing it closes out if it's an io.Closer.
+func (e *Encryption) NewStreamWriter(out io.Writer, purpose string) (io.WriteCloser, error) {
+	aead, err := e.AEAD(purpose)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedWriter{
+		e:       e,
+		purpose: purpose,
+		aead:    aead,
+		out:     out,
+		buf:     make([]byte, 0, stateFileChunkSize),
+	}, nil
+}
+
+// Write implements io.Writer.Write.
+func (w *encryptedWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+	done := 0
+	for len(p) > 0 {
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flush(false); err != nil {
+				return done, err
+			}
+		}
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		done += n
+	}
+	return done, nil
+}
+
+func (w *encryptedWriter) flush(final bool) error {
+	var hdr [4]byte
+	v := uint32(len(w.buf))
+	if final {
+		v |= encryptedRecordFinal
+	}
+	binary.BigEndian.PutUint32(hdr[:], v)
+	w.sealed = w.aead.Seal(append(w.sealed[:0], hdr[:]...), recordNonce(w.aead, w.index), w.buf, w.e.recordAAD(w.purpose, hdr[:]))
+	w.index++
+	w.buf = w.buf[:0]
+	_, err := w.out.Write(w.sealed)
+	return err
+}
+
+// Close implements io.Closer.Close.
+func (w *encryptedWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if err := w.flush(true); err != nil {
+		return err
+	}
+	if closer, ok := w.out.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// encryptedReader implements io.ReadCloser for encrypted streams.
+type encryptedReader struct {
+	e       *Encryption
+	purpose string
+	aead    cipher.AEAD
+	in      io.Reader
+	sealed  []byte
+	buf     []byte
+	index   uint64
+	done    bool
+}
+
+// NewStreamReader returns a reader that decrypts data written by a writer
+// returned by NewStreamWriter for the same purpose from in. Closing the
+// returned reader closes in if it's an io.Closer.
+func (e *Encryption) NewStreamReader(in io.Reader, purpose string) (io.ReadCloser, error) {
+	aead, err := e.AEAD(purpose)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedReader{
+		e:       e,
+		purpose: purpose,
+		aead:    aead,
+		in:      in,
+	}, nil
+}
+
+// Read implements io.Reader.Read.
+func (r *encryptedReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *encryptedReader) next() error {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r.in, hdr[:]); err != nil {
+		if err == io.EOF {
+			// The final record is missing.
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	v := binary.BigEndian.Uint32(hdr[:])
+	length := v &^ encryptedRecordFinal
+	if length > stateFileChunkSize {
+		return ErrDecryption
+	}
+	size := int(length) + r.aead.Overhead()
+	if cap(r.sealed) < size {
+		r.sealed = make([]byte, size)
+	}
+	r.sealed = r.sealed[:size]
+	if _, err := io.ReadFull(r.in, r.sealed); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	plain, err := r.aead.Open(r.sealed[:0], recordNonce(r.aead, r.index), r.sealed, r.e.recordAAD(r.purpose, hdr[:]))
+	if err != nil {
+		return ErrDecryption
+	}
+	r.index++
+	r.buf = plain
+	r.done = v&encryptedRecordFinal != 0
+	return nil
+}
+
+// Close implements io.Closer.Close.
+func (r *encryptedReader) Close() error {
+	if closer, ok := r.in.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// newEncryptionSalt returns a random salt, and records it in metadata along
+// with the encryption algorithm.
+func newEncryptionSalt(metadata map[string]string) ([]byte, error) {
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	metadata[EncryptionKey] = EncryptionAES256GCM
+	metadata[EncryptionSaltKey] = hex.EncodeToString(salt)
+	return salt, nil
+}
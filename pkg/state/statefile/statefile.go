@@ -99,6 +99,10 @@ const (
 	CompressionLevelFlateBestSpeed = CompressionLevel("flate-best-speed")
 	// CompressionLevelNone represents the absence of any compression on an image.
 	CompressionLevelNone = CompressionLevel("none")
+	// CompressionLevelZstd represents zstd algorithm at its default level.
+	CompressionLevelZstd = CompressionLevel("zstd")
+	// CompressionLevelLZ4 represents lz4 algorithm.
+	CompressionLevelLZ4 = CompressionLevel("lz4")
 	// CompressionLevelDefault represents the default compression level.
 	CompressionLevelDefault = CompressionLevelFlateBestSpeed
 )
@@ -107,6 +111,29 @@ func (c CompressionLevel) String() string {
 	return string(c)
 }
 
+// Codec returns the codec compressing images at level c, or nil if images at
+// level c aren't compressed with a compressio.Codec.
+func (c CompressionLevel) Codec() (compressio.Codec, error) {
+	switch c {
+	case CompressionLevelZstd:
+		return compressio.NewZstdCodec(0)
+	case CompressionLevelLZ4:
+		return compressio.NewLZ4Codec(), nil
+	default:
+		return nil, nil
+	}
+}
+
+// CompressesPages returns true if the pages file of images at level c is
+// compressed. Pages files are compressed in frames that can be decompressed
+// independently, so that restore can load pages in the background.
+//
+// Images compressed with flate don't have a pages file; pages are saved in the
+// state file instead.
+func (c CompressionLevel) CompressesPages() bool {
+	return c == CompressionLevelZstd || c == CompressionLevelLZ4
+}
+
 // Options is statefile options.
 type Options struct {
 	// Compression is an image compression type/level.
@@ -115,6 +142,21 @@ type Options struct {
 	// Resume indicates if the sandbox process should continue running
 	// after checkpointing.
 	Resume bool
+
+	// If EncryptionKey is not nil, the image is encrypted with it. See
+	// NewEncryptedWriter.
+	EncryptionKey []byte
+
+	// If ChunkStore is not empty, it is the absolute path of a directory
+	// holding a chunk store, which may be shared by multiple images, to
+	// which the image's pages are saved.
+	ChunkStore string
+}
+
+// String implements fmt.Stringer.String. It omits the encryption key, so
+// that Options can be logged.
+func (o Options) String() string {
+	return fmt.Sprintf("{Compression:%s Resume:%t Encrypted:%t ChunkStore:%q}", o.Compression, o.Resume, o.EncryptionKey != nil, o.ChunkStore)
 }
 
 // WriteToMetadata save options to the metadata storage.  Method returns the
@@ -131,6 +173,10 @@ func CompressionLevelFromString(val string) (CompressionLevel, error) {
 		return CompressionLevelFlateBestSpeed, nil
 	case string(CompressionLevelNone):
 		return CompressionLevelNone, nil
+	case string(CompressionLevelZstd):
+		return CompressionLevelZstd, nil
+	case string(CompressionLevelLZ4):
+		return CompressionLevelLZ4, nil
 	case "":
 		return CompressionLevelDefault, nil
 	default:
@@ -157,6 +203,17 @@ func CompressionLevelFromMetadata(metadata map[string]string) (CompressionLevel,
 	return compression, nil
 }
 
+// PagesCodec returns the codec compressing the pages file of an image with the
+// given metadata, or nil if its pages file isn't compressed. The pages of
+// encrypted images are compressed before they're encrypted.
+func PagesCodec(metadata map[string]string) (compressio.Codec, error) {
+	compression, err := CompressionLevelFromMetadata(metadata)
+	if err != nil || !compression.CompressesPages() {
+		return nil, err
+	}
+	return compression.Codec()
+}
+
 func writeMetadataLen(w io.Writer, val uint64) error {
 	var buf [8]byte
 	binary.BigEndian.PutUint64(buf[:], val)
@@ -168,22 +225,45 @@ func writeMetadataLen(w io.Writer, val uint64) error {
 //
 // Note that the returned WriteCloser must be closed.
 func NewWriter(w io.Writer, key []byte, metadata map[string]string) (io.WriteCloser, error) {
+	wc, _, err := NewEncryptedWriter(w, key, nil, metadata)
+	return wc, err
+}
+
+// NewEncryptedWriter is equivalent to NewWriter, but also encrypts the state
+// data with encryptionKey, which must be EncryptionKeySize bytes long. It
+// returns the Encryption used to encrypt the image's other files. If
+// encryptionKey is nil, the state data isn't encrypted and the returned
+// Encryption is nil.
+func NewEncryptedWriter(w io.Writer, key, encryptionKey []byte, metadata map[string]string) (io.WriteCloser, *Encryption, error) {
 	if metadata == nil {
 		metadata = make(map[string]string)
 	}
 	for k := range metadata {
-		if strings.HasPrefix(k, "_") {
-			return nil, ErrMetadataInvalid
+		if strings.HasPrefix(k, "_") || k == EncryptionKey || k == EncryptionSaltKey {
+			return nil, nil, ErrMetadataInvalid
+		}
+	}
+	var enc *Encryption
+	if encryptionKey != nil {
+		salt, err := newEncryptionSalt(metadata)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer delete(metadata, EncryptionKey)
+		defer delete(metadata, EncryptionSaltKey)
+		if enc, err = newEncryption(encryptionKey, salt); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	// Create our HMAC function.
+	// Create our HMAC function, and the digest authenticated by encryption.
 	h := hmac.New(sha256.New, key)
-	mw := io.MultiWriter(w, h)
+	d := sha256.New()
+	mw := io.MultiWriter(w, h, d)
 
 	// First, write the header.
 	if _, err := mw.Write(magicHeader); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Generate a timestamp, for convenience only.
@@ -193,35 +273,42 @@ func NewWriter(w io.Writer, key []byte, metadata map[string]string) (io.WriteClo
 	// Save compression state
 	compression, err := CompressionLevelFromMetadata(metadata)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Write the metadata.
 	b, err := json.Marshal(metadata)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if len(b) > maxMetadataSize {
-		return nil, ErrInvalidMetadataLength
+		return nil, nil, ErrInvalidMetadataLength
 	}
 
 	// Metadata length.
 	if err := writeMetadataLen(mw, uint64(len(b))); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	// Metadata bytes; io.MultiWriter will return a short write error if
 	// any of the writers returns < n.
 	if _, err := mw.Write(b); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	// Write the current hash.
 	cur := h.Sum(nil)
 	for done := 0; done < len(cur); {
-		n, err := mw.Write(cur[done:])
+		n, err := w.Write(cur[done:])
 		done += n
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+	}
+
+	if enc != nil {
+		enc.metadataDigest = d.Sum(nil)
+		if w, err = enc.NewStreamWriter(w, EncryptionPurposeState); err != nil {
+			return nil, nil, err
 		}
 	}
 
@@ -230,16 +317,25 @@ func NewWriter(w io.Writer, key []byte, metadata map[string]string) (io.WriteClo
 	// gain in restore latency reduction, while incurring much more CPU usage at
 	// save time.
 	if compression == CompressionLevelFlateBestSpeed {
-		return compressio.NewWriter(w, key, stateFileChunkSize, flate.BestSpeed)
+		cw, err := compressio.NewWriter(w, key, stateFileChunkSize, flate.BestSpeed)
+		return cw, enc, err
+	}
+	codec, err := compression.Codec()
+	if err != nil {
+		return nil, nil, err
+	}
+	if codec != nil {
+		cw, err := compressio.NewCodecWriter(w, key, stateFileChunkSize, codec)
+		return cw, enc, err
 	}
 
-	return compressio.NewSimpleWriter(w, key, stateFileChunkSize), nil
+	return compressio.NewSimpleWriter(w, key, stateFileChunkSize), enc, nil
 }
 
 // MetadataUnsafe reads out the metadata from a state file without verifying any
 // HMAC. This function shouldn't be called for untrusted input files.
 func MetadataUnsafe(r io.Reader) (map[string]string, error) {
-	return metadata(r, nil)
+	return metadata(r, nil, nil)
 }
 
 func readMetadataLen(r io.Reader) (uint64, error) {
@@ -251,8 +347,9 @@ func readMetadataLen(r io.Reader) (uint64, error) {
 }
 
 // metadata validates the magic header and reads out the metadata from a state
-// data stream.
-func metadata(r io.Reader, h hash.Hash) (map[string]string, error) {
+// data stream. If digest is not nil, it's set to the SHA-256 digest of the
+// header and metadata.
+func metadata(r io.Reader, h hash.Hash, digest *[]byte) (map[string]string, error) {
 	if h != nil {
 		r = io.TeeReader(r, h)
 	}
@@ -291,6 +388,13 @@ func metadata(r io.Reader, h hash.Hash) (map[string]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	if digest != nil {
+		d := sha256.New()
+		d.Write(magicHeader)
+		writeMetadataLen(d, uint64(len(b)))
+		d.Write(b)
+		*digest = d.Sum(nil)
+	}
 
 	if h != nil {
 		// Check the hash prior to decoding.
@@ -315,11 +419,32 @@ func metadata(r io.Reader, h hash.Hash) (map[string]string, error) {
 
 // NewReader returns a reader for a statefile.
 func NewReader(r io.ReadCloser, key []byte) (io.ReadCloser, map[string]string, error) {
+	cr, metadata, _, err := NewEncryptedReader(r, key, nil)
+	return cr, metadata, err
+}
+
+// NewEncryptedReader is equivalent to NewReader, but also decrypts state data
+// written by NewEncryptedWriter with the same encryptionKey. It returns the
+// Encryption used to decrypt the image's other files, which is nil if
+// encryptionKey is nil.
+func NewEncryptedReader(r io.ReadCloser, key, encryptionKey []byte) (io.ReadCloser, map[string]string, *Encryption, error) {
 	// Read the metadata with the hash.
 	h := hmac.New(sha256.New, key)
-	metadata, err := metadata(r, h)
+	var digest []byte
+	metadata, err := metadata(r, h, &digest)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	enc, err := encryptionFromMetadata(encryptionKey, metadata)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if enc != nil {
+		enc.metadataDigest = digest
+		if r, err = enc.NewStreamReader(r, EncryptionPurposeState); err != nil {
+			return nil, nil, nil, err
+		}
 	}
 
 	// Determine image compression state. If the metadata doesn't contain
@@ -327,7 +452,7 @@ func NewReader(r io.ReadCloser, key []byte) (io.ReadCloser, map[string]string, e
 	// because the default behavior used to be to always compress.
 	compression, err := CompressionLevelFromMetadata(metadata)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Pick correct reader
@@ -337,14 +462,18 @@ func NewReader(r io.ReadCloser, key []byte) (io.ReadCloser, map[string]string, e
 		cr, err = compressio.NewReader(r, key)
 	} else if compression == CompressionLevelNone {
 		cr = compressio.NewSimpleReader(r, key)
+	} else if codec, codecErr := compression.Codec(); codecErr != nil {
+		return nil, nil, nil, codecErr
+	} else if codec != nil {
+		cr, err = compressio.NewCodecReader(r, key, codec)
 	} else {
 		// Should never occur, as it has the default path.
-		return nil, nil, fmt.Errorf("metadata contains invalid compression flag value: %v", compression)
+		return nil, nil, nil, fmt.Errorf("metadata contains invalid compression flag value: %v", compression)
 	}
 
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	return cr, metadata, nil
+	return cr, metadata, enc, nil
 }
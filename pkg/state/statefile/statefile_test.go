@@ -46,6 +46,8 @@ func TestStatefile(t *testing.T) {
 	compression := map[string]CompressionLevel{
 		"none":       CompressionLevelNone,
 		"compressed": CompressionLevelFlateBestSpeed,
+		"zstd":       CompressionLevelZstd,
+		"lz4":        CompressionLevelLZ4,
 	}
 
 	cases := []testCase{
@@ -177,6 +179,118 @@ func TestStatefile(t *testing.T) {
 	}
 }
 
+func TestEncryptedStatefile(t *testing.T) {
+	integrityKey, err := randomKey()
+	if err != nil {
+		t.Fatalf("can't generate key: %v", err)
+	}
+	encryptionKey := make([]byte, EncryptionKeySize)
+	if _, err := crand.Read(encryptionKey); err != nil {
+		t.Fatalf("can't generate encryption key: %v", err)
+	}
+	data := make([]byte, 3*stateFileChunkSize+1)
+	if _, err := crand.Read(data); err != nil {
+		t.Fatalf("can't generate data: %v", err)
+	}
+
+	var bufEncoded bytes.Buffer
+	w, enc, err := NewEncryptedWriter(&bufEncoded, integrityKey, encryptionKey, map[string]string{"foo": "bar"})
+	if err != nil {
+		t.Fatalf("error creating writer: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("error during write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("error during close: %v", err)
+	}
+	if bytes.Contains(bufEncoded.Bytes(), data[:64]) {
+		t.Errorf("encoded state file contains plaintext data")
+	}
+
+	// Encrypt a separate stream, as done for the pages metadata file.
+	var bufPages bytes.Buffer
+	pw, err := enc.NewStreamWriter(&bufPages, EncryptionPurposePagesMetadata)
+	if err != nil {
+		t.Fatalf("error creating stream writer: %v", err)
+	}
+	if _, err := pw.Write(data); err != nil {
+		t.Fatalf("error during stream write: %v", err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatalf("error during stream close: %v", err)
+	}
+
+	read := func(state []byte, key []byte) ([]byte, *Encryption, error) {
+		r, metadata, enc, err := NewEncryptedReader(io.NopCloser(bytes.NewReader(state)), integrityKey, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if got := metadata["foo"]; got != "bar" {
+			t.Errorf("mismatched metadata for foo: got %q, expected %q", got, "bar")
+		}
+		var bufDecoded bytes.Buffer
+		_, err = io.Copy(&bufDecoded, r)
+		return bufDecoded.Bytes(), enc, err
+	}
+
+	got, renc, err := read(bufEncoded.Bytes(), encryptionKey)
+	if err != nil {
+		t.Fatalf("error during read: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("data didn't match (%d vs %d bytes)", len(got), len(data))
+	}
+	r, err := renc.NewStreamReader(bytes.NewReader(bufPages.Bytes()), EncryptionPurposePagesMetadata)
+	if err != nil {
+		t.Fatalf("error creating stream reader: %v", err)
+	}
+	if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("stream data didn't match: %d bytes, err %v", len(got), err)
+	}
+
+	// Reading with the wrong purpose fails.
+	r, err = renc.NewStreamReader(bytes.NewReader(bufPages.Bytes()), EncryptionPurposeState)
+	if err != nil {
+		t.Fatalf("error creating stream reader: %v", err)
+	}
+	if _, err := io.ReadAll(r); err != ErrDecryption {
+		t.Errorf("got error %v, expected ErrDecryption with wrong purpose", err)
+	}
+
+	// Truncating the stream at a record boundary fails.
+	r, err = renc.NewStreamReader(bytes.NewReader(bufPages.Bytes()[:4+stateFileChunkSize+16]), EncryptionPurposePagesMetadata)
+	if err != nil {
+		t.Fatalf("error creating stream reader: %v", err)
+	}
+	if _, err := io.ReadAll(r); err != io.ErrUnexpectedEOF {
+		t.Errorf("got error %v, expected ErrUnexpectedEOF on truncation", err)
+	}
+
+	// Reading with the wrong key or without a key fails.
+	wrongKey := append([]byte(nil), encryptionKey...)
+	wrongKey[rand.Intn(len(wrongKey))]++
+	if _, _, err := read(bufEncoded.Bytes(), wrongKey); err != ErrDecryption {
+		t.Errorf("got error %v, expected ErrDecryption with wrong key", err)
+	}
+	if _, _, err := read(bufEncoded.Bytes(), nil); err != ErrEncryptionKeyMissing {
+		t.Errorf("got error %v, expected ErrEncryptionKeyMissing without key", err)
+	}
+
+	// Reading an unencrypted state file with a key fails.
+	var bufPlain bytes.Buffer
+	w, err = NewWriter(&bufPlain, integrityKey, nil)
+	if err != nil {
+		t.Fatalf("error creating writer: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("error during close: %v", err)
+	}
+	if _, _, err := read(bufPlain.Bytes(), encryptionKey); err != ErrNotEncrypted {
+		t.Errorf("got error %v, expected ErrNotEncrypted", err)
+	}
+}
+
 const benchmarkDataSize = 100 * 1024 * 1024
 
 func benchmark(b *testing.B, size int, write bool, compressible bool) {
@@ -0,0 +1,124 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"context"
+	"strconv"
+
+	"gvisor.dev/gvisor/pkg/state"
+)
+
+// +stateify savable
+// +stateify version 2
+type versionedStruct struct {
+	a int
+
+	// b replaced old in version 2.
+	b string
+
+	// added was added in version 2.
+	added int
+}
+
+func (v *versionedStruct) migrate(_ context.Context, src state.Source) {
+	if src.Version() >= 2 {
+		return
+	}
+	var old int
+	if src.LoadField("old", &old) {
+		v.b = strconv.Itoa(old)
+	}
+}
+
+// oldVersionedStruct is versionedStruct before it was versioned. Its methods
+// are implemented manually, since it must be encoded with the name of
+// versionedStruct, which can't be registered twice; see oldVersionedStructName.
+type oldVersionedStruct struct {
+	a   int
+	old int
+}
+
+// oldVersionedStructName is the type name of oldVersionedStruct. It is set to
+// the name of versionedStruct by tests after the type is registered.
+var oldVersionedStructName = "tests.oldVersionedStruct"
+
+// StateTypeName implements state.Type.StateTypeName.
+func (o *oldVersionedStruct) StateTypeName() string {
+	return oldVersionedStructName
+}
+
+// StateFields implements state.Type.StateFields.
+func (o *oldVersionedStruct) StateFields() []string {
+	return []string{
+		"a",
+		"old",
+	}
+}
+
+// StateSave implements state.SaverLoader.StateSave.
+func (o *oldVersionedStruct) StateSave(stateSinkObject state.Sink) {
+	stateSinkObject.Save(0, &o.a)
+	stateSinkObject.Save(1, &o.old)
+}
+
+// StateLoad implements state.SaverLoader.StateLoad.
+func (o *oldVersionedStruct) StateLoad(ctx context.Context, stateSourceObject state.Source) {
+	stateSourceObject.Load(0, &o.a)
+	stateSourceObject.Load(1, &o.old)
+}
+
+// +stateify savable
+type unversionedStruct struct {
+	a int
+	b int
+}
+
+// oldUnversionedStruct is unversionedStruct before b was added. See
+// oldVersionedStruct.
+type oldUnversionedStruct struct {
+	a int
+}
+
+// oldUnversionedStructName is the type name of oldUnversionedStruct. It is set
+// to the name of unversionedStruct by tests after the type is registered.
+var oldUnversionedStructName = "tests.oldUnversionedStruct"
+
+// StateTypeName implements state.Type.StateTypeName.
+func (o *oldUnversionedStruct) StateTypeName() string {
+	return oldUnversionedStructName
+}
+
+// StateFields implements state.Type.StateFields.
+func (o *oldUnversionedStruct) StateFields() []string {
+	return []string{
+		"a",
+	}
+}
+
+// StateSave implements state.SaverLoader.StateSave.
+func (o *oldUnversionedStruct) StateSave(stateSinkObject state.Sink) {
+	stateSinkObject.Save(0, &o.a)
+}
+
+// StateLoad implements state.SaverLoader.StateLoad.
+func (o *oldUnversionedStruct) StateLoad(ctx context.Context, stateSourceObject state.Source) {
+	stateSourceObject.Load(0, &o.a)
+}
+
+func init() {
+	state.Register((*oldVersionedStruct)(nil))
+	state.Register((*oldUnversionedStruct)(nil))
+}
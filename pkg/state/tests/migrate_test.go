@@ -0,0 +1,141 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/state"
+	"gvisor.dev/gvisor/pkg/state/statefile"
+)
+
+func TestVersioned(t *testing.T) {
+	runTestCases(t, false, "versioned", []any{
+		versionedStruct{a: 1, b: "2", added: 3},
+		&versionedStruct{a: 1, b: "2", added: 3},
+		genericContainer{v: &versionedStruct{a: 1, b: "2", added: 3}},
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	name := oldVersionedStructName
+	oldVersionedStructName = (*versionedStruct)(nil).StateTypeName()
+	defer func() { oldVersionedStructName = name }()
+
+	ctx := context.Background()
+	var buf bytes.Buffer
+	if _, err := state.Save(ctx, &buf, &oldVersionedStruct{a: 1, old: 2}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	var got versionedStruct
+	if _, err := state.Load(ctx, &buf, &got); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	// a is loaded unchanged, b is migrated from old, and added keeps its
+	// default value.
+	if want := (versionedStruct{a: 1, b: "2"}); got != want {
+		t.Errorf("Load got %+v, want %+v", got, want)
+	}
+
+	// Loading the current version into the old one ignores the fields it
+	// doesn't have.
+	buf.Reset()
+	if _, err := state.Save(ctx, &buf, &versionedStruct{a: 1, b: "2", added: 3}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	var gotOld oldVersionedStruct
+	if _, err := state.Load(ctx, &buf, &gotOld); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if want := (oldVersionedStruct{a: 1}); gotOld != want {
+		t.Errorf("Load got %+v, want %+v", gotOld, want)
+	}
+}
+
+func TestUnversionedMismatch(t *testing.T) {
+	name := oldUnversionedStructName
+	oldUnversionedStructName = (*unversionedStruct)(nil).StateTypeName()
+	defer func() { oldUnversionedStructName = name }()
+
+	ctx := context.Background()
+	var buf bytes.Buffer
+	if _, err := state.Save(ctx, &buf, &oldUnversionedStruct{a: 1}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	var got unversionedStruct
+	if _, err := state.Load(ctx, &buf, &got); err == nil {
+		t.Errorf("Load of unversioned type with different fields succeeded: got %+v", got)
+	}
+}
+
+// TestMigrateStatefile saves an object graph with the old field sets through
+// a state file, as in a checkpoint, and restores it with the new ones.
+func TestMigrateStatefile(t *testing.T) {
+	name := oldVersionedStructName
+	oldVersionedStructName = (*versionedStruct)(nil).StateTypeName()
+	defer func() { oldVersionedStructName = name }()
+
+	ctx := context.Background()
+	key := []byte("key")
+	var buf bytes.Buffer
+	w, err := statefile.NewWriter(&buf, key, map[string]string{"version": "old"})
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	shared := &oldVersionedStruct{a: 1, old: 2}
+	saved := sliceContainer{v: []any{
+		shared,
+		&unversionedStruct{a: 3, b: 4},
+		genericContainer{v: shared},
+	}}
+	if _, err := state.Save(ctx, w, &saved); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	r, metadata, err := statefile.NewReader(io.NopCloser(&buf), key)
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	if got := metadata["version"]; got != "old" {
+		t.Errorf("metadata version got %q, want %q", got, "old")
+	}
+	var loaded sliceContainer
+	if _, err := state.Load(ctx, r, &loaded); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.v) != 3 {
+		t.Fatalf("Load got %d objects, want 3", len(loaded.v))
+	}
+	v, ok := loaded.v[0].(*versionedStruct)
+	if !ok {
+		t.Fatalf("Load got %T, want *versionedStruct", loaded.v[0])
+	}
+	if want := (versionedStruct{a: 1, b: "2"}); *v != want {
+		t.Errorf("Load got %+v, want %+v", *v, want)
+	}
+	if u, ok := loaded.v[1].(*unversionedStruct); !ok || *u != (unversionedStruct{a: 3, b: 4}) {
+		t.Errorf("Load got %+v, want &{a:3 b:4}", loaded.v[1])
+	}
+	// References to the migrated object are preserved.
+	if c, ok := loaded.v[2].(genericContainer); !ok || c.v != any(v) {
+		t.Errorf("Load got %+v, want a container of %p", loaded.v[2], v)
+	}
+}
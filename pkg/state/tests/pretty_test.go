@@ -0,0 +1,96 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/state"
+	"gvisor.dev/gvisor/pkg/state/pretty"
+)
+
+func saveToBuffer(t *testing.T, obj any) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := state.Save(context.Background(), &buf, obj); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	return &buf
+}
+
+func TestSummary(t *testing.T) {
+	shared := &inner{v: 1}
+	buf := saveToBuffer(t, &[]*inner{shared, shared, {v: 2}})
+	graphs, err := pretty.ReadGraphs(buf)
+	if err != nil {
+		t.Fatalf("ReadGraphs failed: %v", err)
+	}
+	s := pretty.Summarize(graphs)
+	if s.Graphs != 1 {
+		t.Errorf("got %d graphs, want 1", s.Graphs)
+	}
+	var found bool
+	for _, ts := range s.Types {
+		if ts.Name != "pkg/state/tests.inner" {
+			continue
+		}
+		found = true
+		if ts.Objects != 2 {
+			t.Errorf("got %d inner objects, want 2", ts.Objects)
+		}
+		if ts.Bytes == 0 {
+			t.Errorf("got 0 bytes of inner objects")
+		}
+	}
+	if !found {
+		t.Errorf("no stats for inner in %+v", s.Types)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := saveToBuffer(t, &outerFieldFirst{inner: inner{v: 1}, v: 2})
+	b := saveToBuffer(t, &outerFieldFirst{inner: inner{v: 3}, v: 2})
+	var out bytes.Buffer
+	if err := pretty.PrintDiff(&out, a, b); err != nil {
+		t.Fatalf("PrintDiff failed: %v", err)
+	}
+	const want = "pkg/state/tests.outerFieldFirst#1.inner.v: 1 -> 3"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("PrintDiff output doesn't contain %q:\n%s", want, out.String())
+	}
+	if strings.Contains(out.String(), ".v: 2") {
+		t.Errorf("PrintDiff reported unchanged field:\n%s", out.String())
+	}
+}
+
+func TestDiffSlices(t *testing.T) {
+	a := saveToBuffer(t, &outerSlice{inner: []inner{{v: 1}, {v: 2}}})
+	b := saveToBuffer(t, &outerSlice{inner: []inner{{v: 1}, {v: 4}, {v: 5}}})
+	var out bytes.Buffer
+	if err := pretty.PrintDiff(&out, a, b); err != nil {
+		t.Fatalf("PrintDiff failed: %v", err)
+	}
+	for _, want := range []string{
+		"pkg/state/tests.outerSlice#1.inner: len 2 -> len 3",
+		"pkg/state/tests.outerSlice#1.inner[1].v: 2 -> 4",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("PrintDiff output doesn't contain %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"io"
 	"math"
 	"reflect"
+	"strings"
 	"testing"
 
 	"gvisor.dev/gvisor/pkg/state"
@@ -101,6 +102,17 @@ func runTestCases(t *testing.T, shouldFail bool, prefix string, objects []any) {
 					t.Errorf("PrettyPrint(html=true) failed unexpected: %v", err)
 				}
 			}
+			if err := pretty.PrintSummary(io.Discard, bytes.NewReader(saveBuffer.Bytes())); err != nil && !shouldFail {
+				t.Errorf("PrintSummary failed unexpectedly: %v", err)
+			}
+			var diffBuf bytes.Buffer
+			if err := pretty.PrintDiff(&diffBuf, bytes.NewReader(saveBuffer.Bytes()), bytes.NewReader(saveBuffer.Bytes())); err != nil {
+				if !shouldFail {
+					t.Errorf("PrintDiff failed unexpectedly: %v", err)
+				}
+			} else if strings.Contains(diffBuf.String(), "graph") {
+				t.Errorf("PrintDiff found differences between identical streams:\n%s", diffBuf.String())
+			}
 			t.Logf("Encoded state:\n%s", ppBuf.String())
 			t.Logf("Save stats:\n%s", saveStats.String())
 
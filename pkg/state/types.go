@@ -52,7 +52,13 @@ type typeEntry struct {
 // reconciledTypeEntry is a reconciled entry in the typeDatabase.
 type reconciledTypeEntry struct {
 	wire.Type
-	LocalType  reflect.Type
+	LocalType reflect.Type
+
+	// Encoded is the type information of the encoded object.
+	Encoded *wire.Type
+
+	// FieldOrder maps local field slots to encoded field slots. Local fields
+	// that weren't encoded map to -1.
 	FieldOrder []int
 }
 
@@ -122,6 +128,14 @@ func lookupNameFields(typ reflect.Type) (string, []string, bool) {
 	return name, fields, true
 }
 
+// lookupVersion returns the version of the type's encoding.
+func lookupVersion(typ reflect.Type) uint32 {
+	if v, ok := reflect.Zero(reflect.PtrTo(typ)).Interface().(Versioned); ok {
+		return v.StateTypeVersion()
+	}
+	return 0
+}
+
 // Lookup looks up or registers the given object.
 //
 // The bool indicates whether this is an existing entry: false means the entry
@@ -144,8 +158,9 @@ func (tdb *typeEncodeDatabase) Lookup(typ reflect.Type) (*typeEntry, bool) {
 		te = &typeEntry{
 			ID: tdb.lastID,
 			Type: wire.Type{
-				Name:   name,
-				Fields: fields,
+				Name:    name,
+				Fields:  fields,
+				Version: lookupVersion(typ),
 			},
 		}
 
@@ -235,16 +250,22 @@ func (tbd *typeDecodeDatabase) Lookup(id typeID, typ reflect.Type) *reconciledTy
 	}
 	rte := &reconciledTypeEntry{
 		Type: wire.Type{
-			Name:   name,
-			Fields: fields,
+			Name:    name,
+			Fields:  fields,
+			Version: lookupVersion(typ),
 		},
 		LocalType: typ,
+		Encoded:   pending,
 	}
+	// Only versioned types may change their fields. Objects of types that
+	// are versioned either locally or in the encoding are decoded even if
+	// the fields differ; see Versioned.
+	versioned := rte.Version != 0 || pending.Version != 0
 	// If there are zero or one fields, then we skip allocating the field
 	// slice. There is special handling for decoding in this case. If the
 	// field name does not match, it will be caught in the general purpose
 	// code below.
-	if len(fields) != len(pending.Fields) {
+	if !versioned && len(fields) != len(pending.Fields) {
 		Failf("type %q contains different fields: %v (decode) and %v (encode)",
 			name, fields, pending.Fields)
 	}
@@ -252,20 +273,26 @@ func (tbd *typeDecodeDatabase) Lookup(id typeID, typ reflect.Type) *reconciledTy
 		tbd.byID[id-1] = rte // Save.
 		return rte
 	}
-	if len(fields) == 1 && fields[0] == pending.Fields[0] {
+	if len(fields) == 1 && len(pending.Fields) == 1 && fields[0] == pending.Fields[0] {
 		tbd.byID[id-1] = rte // Save.
 		rte.FieldOrder = singleFieldOrder
 		return rte
 	}
 	// For each field in the current object's information, match it to a
-	// field in the destination object. We know from the assertion above
-	// and the insertion on insertion to pending that neither field
-	// contains any duplicates.
+	// field in the encoded object. We know from the assertion above and the
+	// insertion on insertion to pending that neither field contains any
+	// duplicates.
+	//
+	// The fields of versioned types may differ if the type changed since
+	// the object was encoded. Fields that weren't encoded are left
+	// unmatched, and keep their default values unless set by a migration.
+	// Encoded fields that no longer exist are ignored, but may still be
+	// loaded by a migration; see Source.LoadField.
 	fieldOrder := make([]int, len(fields))
 	for i, name := range fields {
 		fieldOrder[i] = -1 // Sentinel.
 		// Is it an exact match?
-		if pending.Fields[i] == name {
+		if i < len(pending.Fields) && pending.Fields[i] == name {
 			fieldOrder[i] = i
 			continue
 		}
@@ -276,7 +303,7 @@ func (tbd *typeDecodeDatabase) Lookup(id typeID, typ reflect.Type) *reconciledTy
 				break
 			}
 		}
-		if fieldOrder[i] == -1 {
+		if fieldOrder[i] == -1 && !versioned {
 			// The type name matches but we are lacking some common fields.
 			Failf("type %q has mismatched fields: %v (decode) and %v (encode)",
 				name, fields, pending.Fields)
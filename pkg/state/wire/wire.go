@@ -679,6 +679,11 @@ func (*Interface) load(r *Reader) Object {
 type Type struct {
 	Name   string
 	Fields []string
+
+	// Version is the version of the type's encoding. Types with version 0
+	// are encoded without a version, as they were before versions were
+	// introduced.
+	Version uint32
 }
 
 // loadType loads an object of type Type.
@@ -713,6 +718,27 @@ func (*Type) load(r *Reader) Object {
 	return &t
 }
 
+// loadVersionedType loads an object of type Type with a non-zero version.
+func loadVersionedType(r *Reader) Type {
+	version := loadUint(r)
+	t := loadType(r)
+	t.Version = uint32(version)
+	return t
+}
+
+// saveVersioned saves t, including its version.
+func (t *Type) saveVersioned(w *Writer) {
+	v := Uint(t.Version)
+	v.save(w)
+	t.save(w)
+}
+
+// loadVersioned loads an object of type Type with a non-zero version.
+func (*Type) loadVersioned(r *Reader) Object {
+	t := loadVersionedType(r)
+	return &t
+}
+
 // multipleObjects is a special type for serializing multiple objects.
 type multipleObjects []Object
 
@@ -851,6 +877,7 @@ const (
 	typeComplex64
 	typeComplex128
 	typeType
+	typeVersionedType
 )
 
 // Save saves the given object.
@@ -906,8 +933,13 @@ func Save(w *Writer, obj Object) {
 		typeInterface.save(w)
 		x.save(w)
 	case *Type:
-		typeType.save(w)
-		x.save(w)
+		if x.Version == 0 {
+			typeType.save(w)
+			x.save(w)
+		} else {
+			typeVersionedType.save(w)
+			x.saveVersioned(w)
+		}
 	case *Complex64:
 		typeComplex64.save(w)
 		x.save(w)
@@ -962,6 +994,8 @@ func Load(r *Reader) Object {
 		return ((*Complex128)(nil)).load(r) // Escapes.
 	case typeType:
 		return ((*Type)(nil)).load(r) // Escapes.
+	case typeVersionedType:
+		return ((*Type)(nil)).loadVersioned(r) // Escapes.
 	default:
 		// This is not a valid stream?
 		panic(fmt.Errorf("unknown header: %d", hdr))
@@ -41,6 +41,14 @@ const (
 	// https://www.iana.org/assignments/arp-parameters/arp-parameters.xhtml#arp-parameters-2
 	ARPHardwareEther    ARPHardwareType = 1
 	ARPHardwareLoopback ARPHardwareType = 2
+	// ARPHardwareIPGRE is the hardware type of GRE over IPv4 devices.
+	ARPHardwareIPGRE ARPHardwareType = 3
+	// ARPHardwareTunnel is the hardware type of IPv4 over IPv4 devices.
+	ARPHardwareTunnel ARPHardwareType = 4
+	// ARPHardwareSIT is the hardware type of IPv6 over IPv4 devices.
+	ARPHardwareSIT ARPHardwareType = 5
+	// ARPHardwareTunnel6 is the hardware type of IP over IPv6 devices.
+	ARPHardwareTunnel6 ARPHardwareType = 6
 )
 
 // ARPOp is an ARP opcode.
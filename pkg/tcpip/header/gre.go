@@ -0,0 +1,230 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"encoding/binary"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/checksum"
+)
+
+// RFC 2890 defines the GRE header with the key and sequence number
+// extensions as follows:
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|C| |K|S| Reserved0       | Ver |         Protocol Type         |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|      Checksum (optional)      |       Reserved1 (Optional)    |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                         Key (optional)                        |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                 Sequence Number (Optional)                    |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+const (
+	greFlagsVersion = 0
+	greProtocolType = 2
+	greOptional     = 4
+)
+
+// GREFlags are the flag bits of a GRE header. The values match the wire
+// encoding of the first 16 bits of the header, and Linux's GRE_* flags from
+// include/uapi/linux/if_tunnel.h (in host byte order).
+type GREFlags uint16
+
+const (
+	// GREFlagChecksum indicates that the checksum field is present.
+	GREFlagChecksum GREFlags = 0x8000
+
+	// GREFlagRouting is the deprecated routing present bit of RFC 1701. RFC
+	// 2784 requires packets with it set to be discarded.
+	GREFlagRouting GREFlags = 0x4000
+
+	// GREFlagKey indicates that the key field is present.
+	GREFlagKey GREFlags = 0x2000
+
+	// GREFlagSequence indicates that the sequence number field is present.
+	GREFlagSequence GREFlags = 0x1000
+
+	// GREVersionMask masks the version bits of the first 16 bits of the
+	// header.
+	GREVersionMask GREFlags = 0x0007
+
+	// GREFlagsMask masks the flags that are supported by GRE version 0.
+	GREFlagsMask = GREFlagChecksum | GREFlagKey | GREFlagSequence
+)
+
+const (
+	// GREMinimumSize is the size of a GRE header without any of the optional
+	// fields.
+	GREMinimumSize = 4
+
+	// GREMaximumSize is the size of a GRE header with all of the optional
+	// fields present.
+	GREMaximumSize = 16
+
+	// GREProtocolNumber is GRE's transport protocol number.
+	GREProtocolNumber tcpip.TransportProtocolNumber = 47
+
+	// GREProtocolTransparentEthernetBridging is the GRE protocol type used
+	// for ethernet frames carried by gretap devices.
+	GREProtocolTransparentEthernetBridging tcpip.NetworkProtocolNumber = 0x6558
+)
+
+// GREFields contains the fields of a GRE header. It is used to describe the
+// fields of a packet that needs to be encoded.
+type GREFields struct {
+	// Flags is the set of optional fields present in the header.
+	Flags GREFlags
+
+	// Protocol is the ethertype of the encapsulated payload.
+	Protocol tcpip.NetworkProtocolNumber
+
+	// Key is the "key" field of the GRE header. It is only encoded if
+	// GREFlagKey is set.
+	Key uint32
+
+	// Sequence is the "sequence number" field of the GRE header. It is only
+	// encoded if GREFlagSequence is set.
+	Sequence uint32
+}
+
+// GRE represents a GRE header stored in a byte array.
+type GRE []byte
+
+// GREHeaderLength returns the length of a GRE header with the given flags.
+func GREHeaderLength(flags GREFlags) int {
+	l := GREMinimumSize
+	if flags&GREFlagChecksum != 0 {
+		l += 4
+	}
+	if flags&GREFlagKey != 0 {
+		l += 4
+	}
+	if flags&GREFlagSequence != 0 {
+		l += 4
+	}
+	return l
+}
+
+// Flags returns the flags of the GRE header.
+func (b GRE) Flags() GREFlags {
+	return GREFlags(binary.BigEndian.Uint16(b[greFlagsVersion:])) &^ GREVersionMask
+}
+
+// Version returns the version of the GRE header.
+func (b GRE) Version() uint8 {
+	return uint8(GREFlags(binary.BigEndian.Uint16(b[greFlagsVersion:])) & GREVersionMask)
+}
+
+// Protocol returns the ethertype of the encapsulated payload.
+func (b GRE) Protocol() tcpip.NetworkProtocolNumber {
+	return tcpip.NetworkProtocolNumber(binary.BigEndian.Uint16(b[greProtocolType:]))
+}
+
+// HeaderLength returns the length of the GRE header, including all of the
+// optional fields indicated by its flags.
+func (b GRE) HeaderLength() int {
+	return GREHeaderLength(b.Flags())
+}
+
+// Checksum returns the "checksum" field of the GRE header. It must only be
+// called if GREFlagChecksum is set.
+func (b GRE) Checksum() uint16 {
+	return binary.BigEndian.Uint16(b[greOptional:])
+}
+
+// Key returns the "key" field of the GRE header. The second return value is
+// false if the header doesn't carry a key.
+func (b GRE) Key() (uint32, bool) {
+	flags := b.Flags()
+	if flags&GREFlagKey == 0 {
+		return 0, false
+	}
+	off := greOptional
+	if flags&GREFlagChecksum != 0 {
+		off += 4
+	}
+	return binary.BigEndian.Uint32(b[off:]), true
+}
+
+// Sequence returns the "sequence number" field of the GRE header. The second
+// return value is false if the header doesn't carry a sequence number.
+func (b GRE) Sequence() (uint32, bool) {
+	flags := b.Flags()
+	if flags&GREFlagSequence == 0 {
+		return 0, false
+	}
+	off := greOptional
+	if flags&GREFlagChecksum != 0 {
+		off += 4
+	}
+	if flags&GREFlagKey != 0 {
+		off += 4
+	}
+	return binary.BigEndian.Uint32(b[off:]), true
+}
+
+// SetChecksum sets the "checksum" field of the GRE header. It must only be
+// called if GREFlagChecksum is set.
+func (b GRE) SetChecksum(xsum uint16) {
+	checksum.Put(b[greOptional:], xsum)
+}
+
+// CalculateChecksum calculates the checksum of the GRE packet, given the
+// checksum of the payload.
+func (b GRE) CalculateChecksum(payloadChecksum uint16) uint16 {
+	return checksum.Checksum(b[:b.HeaderLength()], payloadChecksum)
+}
+
+// IsChecksumValid returns true iff the GRE header doesn't carry a checksum or
+// its checksum is valid.
+func (b GRE) IsChecksumValid(payloadChecksum uint16) bool {
+	if b.Flags()&GREFlagChecksum == 0 {
+		return true
+	}
+	return b.CalculateChecksum(payloadChecksum) == 0xffff
+}
+
+// IsValid returns true iff b holds a complete GRE version 0 header without
+// the routing bit set.
+func (b GRE) IsValid() bool {
+	if len(b) < GREMinimumSize || b.Version() != 0 || b.Flags()&GREFlagRouting != 0 {
+		return false
+	}
+	return len(b) >= b.HeaderLength()
+}
+
+// Encode encodes all the fields of the GRE header. The checksum field, if
+// present, is zeroed and must be set by the caller once the payload is known.
+func (b GRE) Encode(g *GREFields) {
+	flags := g.Flags & GREFlagsMask
+	binary.BigEndian.PutUint16(b[greFlagsVersion:], uint16(flags))
+	binary.BigEndian.PutUint16(b[greProtocolType:], uint16(g.Protocol))
+	off := greOptional
+	if flags&GREFlagChecksum != 0 {
+		binary.BigEndian.PutUint32(b[off:], 0)
+		off += 4
+	}
+	if flags&GREFlagKey != 0 {
+		binary.BigEndian.PutUint32(b[off:], g.Key)
+		off += 4
+	}
+	if flags&GREFlagSequence != 0 {
+		binary.BigEndian.PutUint32(b[off:], g.Sequence)
+	}
+}
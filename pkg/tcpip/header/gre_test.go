@@ -0,0 +1,137 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header_test
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/tcpip/checksum"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+)
+
+func TestGREEncode(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields header.GREFields
+		length int
+	}{
+		{
+			name: "no optional fields",
+			fields: header.GREFields{
+				Protocol: header.IPv4ProtocolNumber,
+			},
+			length: 4,
+		},
+		{
+			name: "key",
+			fields: header.GREFields{
+				Flags:    header.GREFlagKey,
+				Protocol: header.IPv6ProtocolNumber,
+				Key:      0xdeadbeef,
+			},
+			length: 8,
+		},
+		{
+			name: "all optional fields",
+			fields: header.GREFields{
+				Flags:    header.GREFlagChecksum | header.GREFlagKey | header.GREFlagSequence,
+				Protocol: header.GREProtocolTransparentEthernetBridging,
+				Key:      5,
+				Sequence: 42,
+			},
+			length: header.GREMaximumSize,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := header.GREHeaderLength(test.fields.Flags); got != test.length {
+				t.Fatalf("got header.GREHeaderLength(%#x) = %d, want = %d", test.fields.Flags, got, test.length)
+			}
+			b := header.GRE(make([]byte, test.length))
+			b.Encode(&test.fields)
+			if !b.IsValid() {
+				t.Fatalf("got b.IsValid() = false, want = true")
+			}
+			if got := b.Flags(); got != test.fields.Flags {
+				t.Errorf("got b.Flags() = %#x, want = %#x", got, test.fields.Flags)
+			}
+			if got := b.Protocol(); got != test.fields.Protocol {
+				t.Errorf("got b.Protocol() = %#x, want = %#x", got, test.fields.Protocol)
+			}
+			if got := b.HeaderLength(); got != test.length {
+				t.Errorf("got b.HeaderLength() = %d, want = %d", got, test.length)
+			}
+			key, ok := b.Key()
+			if wantOK := test.fields.Flags&header.GREFlagKey != 0; ok != wantOK || key != test.fields.Key {
+				t.Errorf("got b.Key() = (%d, %t), want = (%d, %t)", key, ok, test.fields.Key, wantOK)
+			}
+			seq, ok := b.Sequence()
+			if wantOK := test.fields.Flags&header.GREFlagSequence != 0; ok != wantOK || seq != test.fields.Sequence {
+				t.Errorf("got b.Sequence() = (%d, %t), want = (%d, %t)", seq, ok, test.fields.Sequence, wantOK)
+			}
+		})
+	}
+}
+
+func TestGREChecksum(t *testing.T) {
+	payload := []byte{1, 2, 3, 4, 5, 6, 7}
+	b := header.GRE(make([]byte, header.GREHeaderLength(header.GREFlagChecksum)))
+	b.Encode(&header.GREFields{
+		Flags:    header.GREFlagChecksum,
+		Protocol: header.IPv4ProtocolNumber,
+	})
+	payloadXsum := checksum.Checksum(payload, 0)
+	b.SetChecksum(^b.CalculateChecksum(payloadXsum))
+	if !b.IsChecksumValid(payloadXsum) {
+		t.Fatalf("got b.IsChecksumValid(_) = false, want = true")
+	}
+	payload[0]++
+	if b.IsChecksumValid(checksum.Checksum(payload, 0)) {
+		t.Fatalf("got b.IsChecksumValid(_) = true after modifying the payload, want = false")
+	}
+}
+
+func TestGREInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{
+			name: "too short",
+			b:    []byte{0, 0, 0x08},
+		},
+		{
+			name: "bad version",
+			b:    []byte{0, 1, 0x08, 0},
+		},
+		{
+			name: "truncated key",
+			b:    []byte{0x20, 0, 0x08, 0, 0, 0},
+		},
+		{
+			name: "routing",
+			b:    []byte{0x40, 0, 0x08, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if header.GRE(test.b).IsValid() {
+				t.Errorf("got header.GRE(%x).IsValid() = true, want = false", test.b)
+			}
+		})
+	}
+}
@@ -163,6 +163,16 @@ func UDP(pkt *stack.PacketBuffer) bool {
 	return ok
 }
 
+// SCTP parses an SCTP packet found in pkt.Data and populates pkt's transport
+// header with the SCTP common header. The chunks are left in pkt.Data.
+//
+// Returns true if the header was successfully parsed.
+func SCTP(pkt *stack.PacketBuffer) bool {
+	_, ok := pkt.TransportHeader().Consume(header.SCTPMinimumSize)
+	pkt.TransportProtocolNumber = header.SCTPProtocolNumber
+	return ok
+}
+
 // TCP parses a TCP packet found in pkt.Data and populates pkt's transport
 // header with the TCP header.
 //
@@ -0,0 +1,504 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+// RFC 9260 section 3.1 defines the SCTP common header as follows:
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|     Source Port Number        |     Destination Port Number   |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                      Verification Tag                         |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                           Checksum                            |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//
+// The common header is followed by one or more chunks, each of which starts
+// with a chunk header and is padded to a multiple of 4 bytes:
+//
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|   Chunk Type  | Chunk  Flags  |        Chunk Length           |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	\                          Chunk Value                          \
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+const (
+	sctpSrcPort         = 0
+	sctpDstPort         = 2
+	sctpVerificationTag = 4
+	sctpChecksum        = 8
+)
+
+const (
+	// SCTPMinimumSize is the size of the SCTP common header.
+	SCTPMinimumSize = 12
+
+	// SCTPChunkHeaderSize is the size of an SCTP chunk header.
+	SCTPChunkHeaderSize = 4
+
+	// SCTPParameterHeaderSize is the size of the header of an SCTP chunk
+	// parameter or error cause.
+	SCTPParameterHeaderSize = 4
+
+	// SCTPProtocolNumber is SCTP's transport protocol number.
+	SCTPProtocolNumber tcpip.TransportProtocolNumber = 132
+)
+
+// SCTPChunkType is the type of an SCTP chunk.
+type SCTPChunkType uint8
+
+// SCTP chunk types, from RFC 9260 section 3.2.
+const (
+	SCTPChunkData             SCTPChunkType = 0
+	SCTPChunkInit             SCTPChunkType = 1
+	SCTPChunkInitAck          SCTPChunkType = 2
+	SCTPChunkSack             SCTPChunkType = 3
+	SCTPChunkHeartbeat        SCTPChunkType = 4
+	SCTPChunkHeartbeatAck     SCTPChunkType = 5
+	SCTPChunkAbort            SCTPChunkType = 6
+	SCTPChunkShutdown         SCTPChunkType = 7
+	SCTPChunkShutdownAck      SCTPChunkType = 8
+	SCTPChunkError            SCTPChunkType = 9
+	SCTPChunkCookieEcho       SCTPChunkType = 10
+	SCTPChunkCookieAck        SCTPChunkType = 11
+	SCTPChunkShutdownComplete SCTPChunkType = 14
+)
+
+// SkipUnrecognized returns true if an unrecognized chunk or parameter of
+// this type must be skipped rather than stopping the processing of the
+// packet. This is encoded in the highest order bit of the type.
+func (t SCTPChunkType) SkipUnrecognized() bool {
+	return t&0x80 != 0
+}
+
+// ReportUnrecognized returns true if an unrecognized chunk of this type must
+// be reported to the peer. This is encoded in the second highest order bit of
+// the type.
+func (t SCTPChunkType) ReportUnrecognized() bool {
+	return t&0x40 != 0
+}
+
+// SCTP chunk flags.
+const (
+	// SCTPDataFlagEnd marks the last fragment of a user message.
+	SCTPDataFlagEnd uint8 = 1 << 0
+
+	// SCTPDataFlagBeginning marks the first fragment of a user message.
+	SCTPDataFlagBeginning uint8 = 1 << 1
+
+	// SCTPDataFlagUnordered marks an unordered user message.
+	SCTPDataFlagUnordered uint8 = 1 << 2
+
+	// SCTPDataFlagImmediate asks the receiver to acknowledge the chunk
+	// without delay (RFC 7053).
+	SCTPDataFlagImmediate uint8 = 1 << 3
+
+	// SCTPFlagTagReflected is set in ABORT and SHUTDOWN COMPLETE chunks whose
+	// verification tag is the sender's own tag rather than the receiver's.
+	SCTPFlagTagReflected uint8 = 1 << 0
+)
+
+// SCTPParameterType is the type of an SCTP chunk parameter.
+type SCTPParameterType uint16
+
+// SCTP parameter types used by INIT, INIT ACK and HEARTBEAT chunks, from RFC
+// 9260 section 3.3.
+const (
+	SCTPParamHeartbeatInfo         SCTPParameterType = 1
+	SCTPParamIPv4Address           SCTPParameterType = 5
+	SCTPParamIPv6Address           SCTPParameterType = 6
+	SCTPParamStateCookie           SCTPParameterType = 7
+	SCTPParamUnrecognizedParameter SCTPParameterType = 8
+	SCTPParamCookiePreservative    SCTPParameterType = 9
+	SCTPParamSupportedAddressTypes SCTPParameterType = 12
+)
+
+// SkipUnrecognized returns true if an unrecognized parameter of this type
+// must be skipped rather than stopping the processing of the chunk.
+func (t SCTPParameterType) SkipUnrecognized() bool {
+	return t&0x8000 != 0
+}
+
+// SCTPErrorCause is the cause code of an SCTP error cause, carried by ERROR
+// and ABORT chunks.
+type SCTPErrorCause uint16
+
+// SCTP error causes, from RFC 9260 section 3.3.10.
+const (
+	SCTPCauseInvalidStreamIdentifier    SCTPErrorCause = 1
+	SCTPCauseMissingMandatoryParameter  SCTPErrorCause = 2
+	SCTPCauseStaleCookie                SCTPErrorCause = 3
+	SCTPCauseOutOfResource              SCTPErrorCause = 4
+	SCTPCauseUnrecognizedChunkType      SCTPErrorCause = 6
+	SCTPCauseInvalidMandatoryParameter  SCTPErrorCause = 7
+	SCTPCauseNoUserData                 SCTPErrorCause = 9
+	SCTPCauseCookieReceivedWhileClosing SCTPErrorCause = 10
+	SCTPCauseUserInitiatedAbort         SCTPErrorCause = 12
+	SCTPCauseProtocolViolation          SCTPErrorCause = 13
+)
+
+// Sizes of the fixed parts of chunk values.
+const (
+	// SCTPInitSize is the size of the fixed part of an INIT or INIT ACK
+	// chunk, including the chunk header.
+	SCTPInitSize = SCTPChunkHeaderSize + 16
+
+	// SCTPDataHeaderSize is the size of the header of a DATA chunk,
+	// including the chunk header.
+	SCTPDataHeaderSize = SCTPChunkHeaderSize + 12
+
+	// SCTPSackSize is the size of the fixed part of a SACK chunk, including
+	// the chunk header.
+	SCTPSackSize = SCTPChunkHeaderSize + 12
+
+	// SCTPShutdownSize is the size of a SHUTDOWN chunk.
+	SCTPShutdownSize = SCTPChunkHeaderSize + 4
+)
+
+var sctpCRC32CTable = crc32.MakeTable(crc32.Castagnoli)
+
+// SCTP represents an SCTP packet stored in a byte array, starting with the
+// common header.
+type SCTP []byte
+
+// SourcePort returns the "source port" field of the SCTP header.
+func (b SCTP) SourcePort() uint16 {
+	return binary.BigEndian.Uint16(b[sctpSrcPort:])
+}
+
+// DestinationPort returns the "destination port" field of the SCTP header.
+func (b SCTP) DestinationPort() uint16 {
+	return binary.BigEndian.Uint16(b[sctpDstPort:])
+}
+
+// VerificationTag returns the "verification tag" field of the SCTP header.
+func (b SCTP) VerificationTag() uint32 {
+	return binary.BigEndian.Uint32(b[sctpVerificationTag:])
+}
+
+// Checksum returns the "checksum" field of the SCTP header.
+func (b SCTP) Checksum() uint32 {
+	// The CRC32c is transmitted least significant byte first, see RFC 9260
+	// appendix A.
+	return binary.LittleEndian.Uint32(b[sctpChecksum:])
+}
+
+// EncodeCommonHeader encodes the common header of an SCTP packet. The
+// checksum is set to zero.
+func (b SCTP) EncodeCommonHeader(srcPort, dstPort uint16, vtag uint32) {
+	binary.BigEndian.PutUint16(b[sctpSrcPort:], srcPort)
+	binary.BigEndian.PutUint16(b[sctpDstPort:], dstPort)
+	binary.BigEndian.PutUint32(b[sctpVerificationTag:], vtag)
+	binary.LittleEndian.PutUint32(b[sctpChecksum:], 0)
+}
+
+// CalculateChecksum calculates the CRC32c checksum of the whole packet,
+// treating the checksum field as zero.
+func (b SCTP) CalculateChecksum() uint32 {
+	var zero [4]byte
+	crc := crc32.Update(0, sctpCRC32CTable, b[:sctpChecksum])
+	crc = crc32.Update(crc, sctpCRC32CTable, zero[:])
+	return crc32.Update(crc, sctpCRC32CTable, b[sctpChecksum+4:])
+}
+
+// SetChecksum sets the "checksum" field of the SCTP header to the checksum of
+// the whole packet.
+func (b SCTP) SetChecksum() {
+	binary.LittleEndian.PutUint32(b[sctpChecksum:], b.CalculateChecksum())
+}
+
+// IsChecksumValid returns true iff the checksum of the packet is valid.
+func (b SCTP) IsChecksumValid() bool {
+	return b.Checksum() == b.CalculateChecksum()
+}
+
+// Chunks returns the chunks of the SCTP packet. It returns false if the
+// packet is malformed.
+func (b SCTP) Chunks() ([]SCTPChunk, bool) {
+	if len(b) < SCTPMinimumSize {
+		return nil, false
+	}
+	var chunks []SCTPChunk
+	rest := b[SCTPMinimumSize:]
+	for len(rest) > 0 {
+		if len(rest) < SCTPChunkHeaderSize {
+			return nil, false
+		}
+		l := int(binary.BigEndian.Uint16(rest[2:]))
+		if l < SCTPChunkHeaderSize || l > len(rest) {
+			return nil, false
+		}
+		chunks = append(chunks, SCTPChunk(rest[:l]))
+		// The last chunk may omit its padding.
+		rest = rest[min(SCTPPadded(l), len(rest)):]
+	}
+	if len(chunks) == 0 {
+		return nil, false
+	}
+	return chunks, true
+}
+
+// SCTPPadded returns l rounded up to a multiple of 4.
+func SCTPPadded(l int) int {
+	return (l + 3) &^ 3
+}
+
+// SCTPChunk represents a single SCTP chunk, including its header and
+// excluding its padding.
+type SCTPChunk []byte
+
+// Type returns the type of the chunk.
+func (c SCTPChunk) Type() SCTPChunkType {
+	return SCTPChunkType(c[0])
+}
+
+// Flags returns the flags of the chunk.
+func (c SCTPChunk) Flags() uint8 {
+	return c[1]
+}
+
+// Value returns the value of the chunk.
+func (c SCTPChunk) Value() []byte {
+	return c[SCTPChunkHeaderSize:]
+}
+
+// EncodeSCTPChunkHeader encodes a chunk header for a chunk with a value of
+// length valueLen into b.
+func EncodeSCTPChunkHeader(b []byte, typ SCTPChunkType, flags uint8, valueLen int) {
+	b[0] = uint8(typ)
+	b[1] = flags
+	binary.BigEndian.PutUint16(b[2:], uint16(SCTPChunkHeaderSize+valueLen))
+}
+
+// SCTPInit represents an INIT or INIT ACK chunk.
+type SCTPInit SCTPChunk
+
+// InitiateTag returns the "initiate tag" field of the chunk.
+func (c SCTPInit) InitiateTag() uint32 {
+	return binary.BigEndian.Uint32(c[4:])
+}
+
+// AdvertisedReceiverWindow returns the "a_rwnd" field of the chunk.
+func (c SCTPInit) AdvertisedReceiverWindow() uint32 {
+	return binary.BigEndian.Uint32(c[8:])
+}
+
+// OutboundStreams returns the "number of outbound streams" field of the
+// chunk.
+func (c SCTPInit) OutboundStreams() uint16 {
+	return binary.BigEndian.Uint16(c[12:])
+}
+
+// InboundStreams returns the "number of inbound streams" field of the chunk.
+func (c SCTPInit) InboundStreams() uint16 {
+	return binary.BigEndian.Uint16(c[14:])
+}
+
+// InitialTSN returns the "initial TSN" field of the chunk.
+func (c SCTPInit) InitialTSN() uint32 {
+	return binary.BigEndian.Uint32(c[16:])
+}
+
+// Parameters returns the variable-length parameters of the chunk.
+func (c SCTPInit) Parameters() ([]SCTPParameter, bool) {
+	return SCTPParameters(c[SCTPInitSize:])
+}
+
+// SCTPInitFields contains the fixed fields of an INIT or INIT ACK chunk.
+type SCTPInitFields struct {
+	InitiateTag              uint32
+	AdvertisedReceiverWindow uint32
+	OutboundStreams          uint16
+	InboundStreams           uint16
+	InitialTSN               uint32
+}
+
+// Encode encodes the fixed part of an INIT or INIT ACK chunk whose
+// parameters have a length of paramsLen into c.
+func (c SCTPInit) Encode(typ SCTPChunkType, f *SCTPInitFields, paramsLen int) {
+	EncodeSCTPChunkHeader(c, typ, 0, SCTPInitSize-SCTPChunkHeaderSize+paramsLen)
+	binary.BigEndian.PutUint32(c[4:], f.InitiateTag)
+	binary.BigEndian.PutUint32(c[8:], f.AdvertisedReceiverWindow)
+	binary.BigEndian.PutUint16(c[12:], f.OutboundStreams)
+	binary.BigEndian.PutUint16(c[14:], f.InboundStreams)
+	binary.BigEndian.PutUint32(c[16:], f.InitialTSN)
+}
+
+// SCTPData represents a DATA chunk.
+type SCTPData SCTPChunk
+
+// TSN returns the "TSN" field of the chunk.
+func (c SCTPData) TSN() uint32 {
+	return binary.BigEndian.Uint32(c[4:])
+}
+
+// StreamIdentifier returns the "stream identifier" field of the chunk.
+func (c SCTPData) StreamIdentifier() uint16 {
+	return binary.BigEndian.Uint16(c[8:])
+}
+
+// StreamSequenceNumber returns the "stream sequence number" field of the
+// chunk.
+func (c SCTPData) StreamSequenceNumber() uint16 {
+	return binary.BigEndian.Uint16(c[10:])
+}
+
+// PayloadProtocolIdentifier returns the "payload protocol identifier" field
+// of the chunk.
+func (c SCTPData) PayloadProtocolIdentifier() uint32 {
+	return binary.BigEndian.Uint32(c[12:])
+}
+
+// Payload returns the user data carried by the chunk.
+func (c SCTPData) Payload() []byte {
+	return c[SCTPDataHeaderSize:]
+}
+
+// SCTPDataFields contains the header fields of a DATA chunk.
+type SCTPDataFields struct {
+	Flags                     uint8
+	TSN                       uint32
+	StreamIdentifier          uint16
+	StreamSequenceNumber      uint16
+	PayloadProtocolIdentifier uint32
+}
+
+// Encode encodes the header of a DATA chunk carrying payloadLen bytes of user
+// data into c.
+func (c SCTPData) Encode(f *SCTPDataFields, payloadLen int) {
+	EncodeSCTPChunkHeader(c, SCTPChunkData, f.Flags, SCTPDataHeaderSize-SCTPChunkHeaderSize+payloadLen)
+	binary.BigEndian.PutUint32(c[4:], f.TSN)
+	binary.BigEndian.PutUint16(c[8:], f.StreamIdentifier)
+	binary.BigEndian.PutUint16(c[10:], f.StreamSequenceNumber)
+	binary.BigEndian.PutUint32(c[12:], f.PayloadProtocolIdentifier)
+}
+
+// SCTPGapAckBlock is a gap ack block of a SACK chunk. Start and End are
+// offsets relative to the cumulative TSN ack.
+type SCTPGapAckBlock struct {
+	Start uint16
+	End   uint16
+}
+
+// SCTPSack represents a SACK chunk.
+type SCTPSack SCTPChunk
+
+// CumulativeTSNAck returns the "cumulative TSN ack" field of the chunk.
+func (c SCTPSack) CumulativeTSNAck() uint32 {
+	return binary.BigEndian.Uint32(c[4:])
+}
+
+// AdvertisedReceiverWindow returns the "a_rwnd" field of the chunk.
+func (c SCTPSack) AdvertisedReceiverWindow() uint32 {
+	return binary.BigEndian.Uint32(c[8:])
+}
+
+// GapAckBlocks returns the gap ack blocks of the chunk. It returns false if
+// the chunk is too short to hold them.
+func (c SCTPSack) GapAckBlocks() ([]SCTPGapAckBlock, bool) {
+	n := int(binary.BigEndian.Uint16(c[12:]))
+	dups := int(binary.BigEndian.Uint16(c[14:]))
+	if len(c) < SCTPSackSize+4*n+4*dups {
+		return nil, false
+	}
+	blocks := make([]SCTPGapAckBlock, n)
+	for i := range blocks {
+		off := SCTPSackSize + 4*i
+		blocks[i] = SCTPGapAckBlock{
+			Start: binary.BigEndian.Uint16(c[off:]),
+			End:   binary.BigEndian.Uint16(c[off+2:]),
+		}
+	}
+	return blocks, true
+}
+
+// SCTPSackSizeWith returns the size of a SACK chunk with the given number of
+// gap ack blocks and duplicate TSNs.
+func SCTPSackSizeWith(gaps, dups int) int {
+	return SCTPSackSize + 4*gaps + 4*dups
+}
+
+// Encode encodes a SACK chunk into c. c must be SCTPSackSizeWith(len(gaps),
+// len(dups)) bytes long.
+func (c SCTPSack) Encode(cumTSN, rwnd uint32, gaps []SCTPGapAckBlock, dups []uint32) {
+	EncodeSCTPChunkHeader(c, SCTPChunkSack, 0, SCTPSackSizeWith(len(gaps), len(dups))-SCTPChunkHeaderSize)
+	binary.BigEndian.PutUint32(c[4:], cumTSN)
+	binary.BigEndian.PutUint32(c[8:], rwnd)
+	binary.BigEndian.PutUint16(c[12:], uint16(len(gaps)))
+	binary.BigEndian.PutUint16(c[14:], uint16(len(dups)))
+	off := SCTPSackSize
+	for _, g := range gaps {
+		binary.BigEndian.PutUint16(c[off:], g.Start)
+		binary.BigEndian.PutUint16(c[off+2:], g.End)
+		off += 4
+	}
+	for _, d := range dups {
+		binary.BigEndian.PutUint32(c[off:], d)
+		off += 4
+	}
+}
+
+// SCTPParameter represents a chunk parameter or an error cause, including
+// its header and excluding its padding.
+type SCTPParameter []byte
+
+// Type returns the type of the parameter.
+func (p SCTPParameter) Type() SCTPParameterType {
+	return SCTPParameterType(binary.BigEndian.Uint16(p[0:]))
+}
+
+// Value returns the value of the parameter.
+func (p SCTPParameter) Value() []byte {
+	return p[SCTPParameterHeaderSize:]
+}
+
+// SCTPParameters parses a sequence of parameters or error causes. It returns
+// false if they are malformed.
+func SCTPParameters(b []byte) ([]SCTPParameter, bool) {
+	var params []SCTPParameter
+	for len(b) > 0 {
+		if len(b) < SCTPParameterHeaderSize {
+			return nil, false
+		}
+		l := int(binary.BigEndian.Uint16(b[2:]))
+		if l < SCTPParameterHeaderSize || l > len(b) {
+			return nil, false
+		}
+		params = append(params, SCTPParameter(b[:l]))
+		b = b[min(SCTPPadded(l), len(b)):]
+	}
+	return params, true
+}
+
+// AppendSCTPParameter appends a parameter with the given type and value,
+// padded to a multiple of 4 bytes, to b.
+func AppendSCTPParameter(b []byte, typ SCTPParameterType, value []byte) []byte {
+	var hdr [SCTPParameterHeaderSize]byte
+	binary.BigEndian.PutUint16(hdr[0:], uint16(typ))
+	binary.BigEndian.PutUint16(hdr[2:], uint16(SCTPParameterHeaderSize+len(value)))
+	b = append(b, hdr[:]...)
+	b = append(b, value...)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
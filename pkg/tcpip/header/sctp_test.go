@@ -0,0 +1,144 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+)
+
+// sctpPacket returns a packet with a DATA chunk carrying payload, followed by
+// a SACK chunk.
+func sctpPacket(payload []byte) []byte {
+	b := make([]byte, header.SCTPMinimumSize)
+	header.SCTP(b).EncodeCommonHeader(3868, 5000, 0xdeadbeef)
+
+	data := make([]byte, header.SCTPDataHeaderSize+len(payload), header.SCTPPadded(header.SCTPDataHeaderSize+len(payload)))
+	header.SCTPData(data).Encode(&header.SCTPDataFields{
+		Flags:                     header.SCTPDataFlagBeginning | header.SCTPDataFlagEnd,
+		TSN:                       100,
+		StreamIdentifier:          2,
+		StreamSequenceNumber:      7,
+		PayloadProtocolIdentifier: 46,
+	}, len(payload))
+	copy(data[header.SCTPDataHeaderSize:], payload)
+	data = data[:cap(data)]
+	b = append(b, data...)
+
+	sack := make([]byte, header.SCTPSackSizeWith(1, 0))
+	header.SCTPSack(sack).Encode(99, 1000, []header.SCTPGapAckBlock{{Start: 2, End: 3}}, nil)
+	b = append(b, sack...)
+	header.SCTP(b).SetChecksum()
+	return b
+}
+
+func TestSCTPChecksum(t *testing.T) {
+	b := sctpPacket([]byte("hello"))
+	h := header.SCTP(b)
+	if !h.IsChecksumValid() {
+		t.Fatalf("checksum %#x of encoded packet is invalid", h.Checksum())
+	}
+	if got, want := h.Checksum(), h.CalculateChecksum(); got != want {
+		t.Errorf("got checksum %#x, want %#x", got, want)
+	}
+	b[len(b)-1] ^= 1
+	if h.IsChecksumValid() {
+		t.Errorf("checksum of corrupted packet is valid")
+	}
+}
+
+func TestSCTPChunks(t *testing.T) {
+	payload := []byte("hello")
+	h := header.SCTP(sctpPacket(payload))
+	if got, want := h.SourcePort(), uint16(3868); got != want {
+		t.Errorf("got source port %d, want %d", got, want)
+	}
+	if got, want := h.DestinationPort(), uint16(5000); got != want {
+		t.Errorf("got destination port %d, want %d", got, want)
+	}
+	if got, want := h.VerificationTag(), uint32(0xdeadbeef); got != want {
+		t.Errorf("got verification tag %#x, want %#x", got, want)
+	}
+
+	chunks, ok := h.Chunks()
+	if !ok {
+		t.Fatalf("Chunks() failed")
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+	if got := chunks[0].Type(); got != header.SCTPChunkData {
+		t.Fatalf("got first chunk type %d, want %d", got, header.SCTPChunkData)
+	}
+	data := header.SCTPData(chunks[0])
+	if data.TSN() != 100 || data.StreamIdentifier() != 2 || data.StreamSequenceNumber() != 7 || data.PayloadProtocolIdentifier() != 46 {
+		t.Errorf("got DATA TSN %d, stream %d, SSN %d, PPID %d; want 100, 2, 7, 46", data.TSN(), data.StreamIdentifier(), data.StreamSequenceNumber(), data.PayloadProtocolIdentifier())
+	}
+	if got := data.Payload(); !bytes.Equal(got, payload) {
+		t.Errorf("got payload %q, want %q", got, payload)
+	}
+
+	if got := chunks[1].Type(); got != header.SCTPChunkSack {
+		t.Fatalf("got second chunk type %d, want %d", got, header.SCTPChunkSack)
+	}
+	sack := header.SCTPSack(chunks[1])
+	if sack.CumulativeTSNAck() != 99 || sack.AdvertisedReceiverWindow() != 1000 {
+		t.Errorf("got SACK cumulative TSN %d and window %d, want 99 and 1000", sack.CumulativeTSNAck(), sack.AdvertisedReceiverWindow())
+	}
+	gaps, ok := sack.GapAckBlocks()
+	if !ok {
+		t.Fatalf("GapAckBlocks() failed")
+	}
+	if diff := cmp.Diff([]header.SCTPGapAckBlock{{Start: 2, End: 3}}, gaps); diff != "" {
+		t.Errorf("gap ack blocks mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSCTPMalformedChunks(t *testing.T) {
+	b := sctpPacket([]byte("hello"))
+	// Make the first chunk claim to extend past the end of the packet.
+	b[header.SCTPMinimumSize+2] = 0xff
+	if _, ok := header.SCTP(b).Chunks(); ok {
+		t.Errorf("Chunks() succeeded on a truncated chunk")
+	}
+	if _, ok := header.SCTP(b[:header.SCTPMinimumSize]).Chunks(); ok {
+		t.Errorf("Chunks() succeeded on a packet without chunks")
+	}
+}
+
+func TestSCTPParameters(t *testing.T) {
+	var b []byte
+	b = header.AppendSCTPParameter(b, header.SCTPParamIPv4Address, []byte{10, 0, 0, 1})
+	b = header.AppendSCTPParameter(b, header.SCTPParamStateCookie, []byte{1, 2, 3})
+	if len(b)%4 != 0 {
+		t.Fatalf("got unpadded parameters of length %d", len(b))
+	}
+	params, ok := header.SCTPParameters(b)
+	if !ok {
+		t.Fatalf("SCTPParameters() failed")
+	}
+	if len(params) != 2 {
+		t.Fatalf("got %d parameters, want 2", len(params))
+	}
+	if params[0].Type() != header.SCTPParamIPv4Address || !bytes.Equal(params[0].Value(), []byte{10, 0, 0, 1}) {
+		t.Errorf("got first parameter type %d value %v", params[0].Type(), params[0].Value())
+	}
+	if params[1].Type() != header.SCTPParamStateCookie || !bytes.Equal(params[1].Value(), []byte{1, 2, 3}) {
+		t.Errorf("got second parameter type %d value %v", params[1].Type(), params[1].Value())
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sniffer
+
+import (
+	"io"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// captureQueueLen is the number of packets a Capture buffers before dropping
+// packets.
+const captureQueueLen = 1024
+
+// CaptureOptions configures a Capture.
+type CaptureOptions struct {
+	// Comment is stored in the capture's section header, e.g. to identify
+	// the sandbox.
+	Comment string
+
+	// SnapLen is the maximum number of bytes captured from each packet.
+	SnapLen uint32
+
+	// Filter selects the packets to capture. If nil, all packets are
+	// captured.
+	Filter *Filter
+}
+
+// Capture captures the packets of one or more NICs to a pcapng stream. It
+// is a stack.PacketEndpoint, and must be registered with the stack for each
+// captured NIC after the NIC is added with AddNIC.
+//
+// Packets are written by a separate goroutine so that a slow writer doesn't
+// hold up the network stack; packets are dropped if the writer falls too far
+// behind.
+type Capture struct {
+	opts   CaptureOptions
+	writer *PCAPNGWriter
+
+	mu sync.Mutex
+	// interfaces maps captured NICs to their pcapng interface ID.
+	//
+	// +checklocks:mu
+	interfaces map[tcpip.NICID]uint32
+
+	queue  chan []byte
+	done   chan struct{}
+	failed chan struct{}
+
+	// err is the first error returned by the writer. It is only accessed by
+	// the writer goroutine until done is closed.
+	err error
+
+	// dropped is the number of packets dropped because the queue was full.
+	dropped atomicbitops.Uint64
+}
+
+var _ stack.PacketEndpoint = (*Capture)(nil)
+
+// NewCapture starts a capture writing to w. The caller must call Close to
+// stop the capture.
+func NewCapture(w io.Writer, opts CaptureOptions) (*Capture, error) {
+	writer, err := NewPCAPNGWriter(w, opts.Comment)
+	if err != nil {
+		return nil, err
+	}
+	c := &Capture{
+		opts:       opts,
+		writer:     writer,
+		interfaces: make(map[tcpip.NICID]uint32),
+		queue:      make(chan []byte, captureQueueLen),
+		done:       make(chan struct{}),
+		failed:     make(chan struct{}),
+	}
+	go c.run() // S/R-SAFE: captures are not saved.
+	return c, nil
+}
+
+func (c *Capture) run() {
+	defer close(c.done)
+	for b := range c.queue {
+		if c.err != nil {
+			continue
+		}
+		if err := c.writer.writeBlock(b); err != nil {
+			c.err = err
+			close(c.failed)
+		}
+	}
+}
+
+// Failed returns a channel that is closed when writing the capture fails,
+// e.g. because its reader went away. Packets are no longer written after
+// that.
+func (c *Capture) Failed() <-chan struct{} {
+	return c.failed
+}
+
+// AddNIC describes a NIC in the capture. It must be called before the NIC's
+// packets are delivered to the Capture.
+func (c *Capture) AddNIC(id tcpip.NICID, name string) error {
+	ifaceID, err := c.writer.AddInterface(name, "", c.opts.SnapLen)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interfaces[id] = ifaceID
+	return nil
+}
+
+// HandlePacket implements stack.PacketEndpoint.HandlePacket.
+func (c *Capture) HandlePacket(nicID tcpip.NICID, _ tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) {
+	c.mu.Lock()
+	ifaceID, ok := c.interfaces[nicID]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	clone := trimmedClone(pkt)
+	defer clone.DecRef()
+	if !c.opts.Filter.Match(clone) {
+		return
+	}
+	dir := Direction(DirectionRecv)
+	if pkt.PktType == tcpip.PacketOutgoing {
+		dir = DirectionSend
+	}
+	b := marshalPCAPNGPacket(ifaceID, dir, time.Now(), clone, c.opts.SnapLen)
+	select {
+	case c.queue <- b:
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of packets that were not captured because the
+// writer couldn't keep up.
+func (c *Capture) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// Close stops the capture. All packets must have been delivered, i.e. the
+// Capture must have been unregistered from the stack. It returns the first
+// error encountered while writing.
+func (c *Capture) Close() error {
+	close(c.queue)
+	<-c.done
+	return c.err
+}
@@ -0,0 +1,105 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sniffer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/bpf"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// Filter selects the packets to capture with a classic BPF program, as
+// tcpdump does. The program runs on packets starting at their network header,
+// i.e. with the LINKTYPE_RAW link type, and a packet is captured if the
+// program returns a non-zero value.
+//
+// +stateify savable
+type Filter struct {
+	program bpf.Program
+}
+
+// NewFilter compiles insns into a Filter.
+func NewFilter(insns []bpf.Instruction) (*Filter, error) {
+	program, err := bpf.Compile(insns, true /* optimize */)
+	if err != nil {
+		return nil, fmt.Errorf("invalid packet filter: %w", err)
+	}
+	return &Filter{program: program}, nil
+}
+
+// Match returns whether pkt, which must not have a link header, is selected
+// by the filter. A nil Filter selects all packets.
+func (f *Filter) Match(pkt *stack.PacketBuffer) bool {
+	if f == nil {
+		return true
+	}
+	data := pkt.ToView()
+	defer data.Release()
+	// Packet data is in network byte order.
+	ret, err := bpf.Exec[bpf.BigEndian](f.program, bpf.Input(data.AsSlice()))
+	// Like Linux, reject the packet if the program fails, e.g. due to an out
+	// of bounds load.
+	return err == nil && ret != 0
+}
+
+// ParseFilter parses a classic BPF program in the format printed by
+// `tcpdump -ddd`: the number of instructions, followed by one instruction per
+// line as four decimal numbers ("code jt jf k"). Commas may be used instead of
+// newlines so that programs fit in a command line flag.
+//
+// Filters apply to packets without a link header, so programs should be
+// compiled for raw IP packets, e.g. with `tcpdump -y RAW -ddd <expression>`.
+func ParseFilter(s string) ([]bpf.Instruction, error) {
+	lines := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '\n' || r == ','
+	})
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("empty packet filter")
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid instruction count %q: %w", lines[0], err)
+	}
+	lines = lines[1:]
+	if count != len(lines) {
+		return nil, fmt.Errorf("packet filter has %d instructions, but %d are declared", len(lines), count)
+	}
+
+	insns := make([]bpf.Instruction, 0, count)
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("instruction %d: got %d fields, want 4", i, len(fields))
+		}
+		var vals [4]uint64
+		for j, bits := range [4]int{16, 8, 8, 32} {
+			v, err := strconv.ParseUint(fields[j], 10, bits)
+			if err != nil {
+				return nil, fmt.Errorf("instruction %d: %w", i, err)
+			}
+			vals[j] = v
+		}
+		insns = append(insns, bpf.Instruction{
+			OpCode:      uint16(vals[0]),
+			JumpIfTrue:  uint8(vals[1]),
+			JumpIfFalse: uint8(vals[2]),
+			K:           uint32(vals[3]),
+		})
+	}
+	return insns, nil
+}
@@ -0,0 +1,212 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sniffer
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// Block types and options from the pcapng specification, see
+// https://www.ietf.org/archive/id/draft-ietf-opsawg-pcapng-02.html.
+const (
+	pcapngSectionHeaderBlock        = 0x0a0d0d0a
+	pcapngInterfaceDescriptionBlock = 0x00000001
+	pcapngEnhancedPacketBlock       = 0x00000006
+
+	pcapngByteOrderMagic = 0x1a2b3c4d
+
+	// Options of all blocks.
+	pcapngOptEndOfOpt = 0
+	pcapngOptComment  = 1
+
+	// Section header block options.
+	pcapngOptUserAppl = 4
+
+	// Interface description block options.
+	pcapngOptIfName    = 2
+	pcapngOptIfDesc    = 3
+	pcapngOptIfTSResol = 9
+
+	// Enhanced packet block options and flags.
+	pcapngOptEPBFlags     = 2
+	pcapngEPBFlagInbound  = 1
+	pcapngEPBFlagOutbound = 2
+
+	// pcapngBlockOverhead is the size of a block's type and two length
+	// fields.
+	pcapngBlockOverhead = 12
+
+	// linkTypeRaw is LINKTYPE_RAW: packets start with an IPv4 or IPv6
+	// header.
+	linkTypeRaw = 101
+)
+
+// pcapngOption is a pcapng block option.
+type pcapngOption struct {
+	code  uint16
+	value []byte
+}
+
+func pcapngPadding(n int) int {
+	return (4 - n%4) % 4
+}
+
+// appendPCAPNGOptions appends opts, followed by the end of options marker, to
+// b. Options with an empty value are skipped.
+func appendPCAPNGOptions(b []byte, opts []pcapngOption) []byte {
+	written := false
+	for _, opt := range opts {
+		if len(opt.value) == 0 {
+			continue
+		}
+		b = binary.LittleEndian.AppendUint16(b, opt.code)
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(opt.value)))
+		b = append(b, opt.value...)
+		b = append(b, make([]byte, pcapngPadding(len(opt.value)))...)
+		written = true
+	}
+	if written {
+		b = binary.LittleEndian.AppendUint32(b, pcapngOptEndOfOpt)
+	}
+	return b
+}
+
+// pcapngBlock returns a block of type blockType holding body, which must be
+// 32-bit aligned.
+func pcapngBlock(blockType uint32, body []byte) []byte {
+	length := uint32(pcapngBlockOverhead + len(body))
+	b := make([]byte, 0, length)
+	b = binary.LittleEndian.AppendUint32(b, blockType)
+	b = binary.LittleEndian.AppendUint32(b, length)
+	b = append(b, body...)
+	return binary.LittleEndian.AppendUint32(b, length)
+}
+
+// PCAPNGWriter writes packets from one or more interfaces to a single stream
+// in the pcapng format. Each interface is described by an interface
+// description block that carries its name, so that packets can be told apart
+// by interface when the stream is read.
+//
+// PCAPNGWriter is safe for concurrent use; each block is written to the
+// underlying writer with a single Write call.
+type PCAPNGWriter struct {
+	mu sync.Mutex
+
+	// +checklocks:mu
+	w io.Writer
+
+	// +checklocks:mu
+	nextInterfaceID uint32
+}
+
+// NewPCAPNGWriter returns a PCAPNGWriter writing to w. It writes a section
+// header block carrying comment, which may be empty.
+func NewPCAPNGWriter(w io.Writer, comment string) (*PCAPNGWriter, error) {
+	body := binary.LittleEndian.AppendUint32(nil, pcapngByteOrderMagic)
+	body = binary.LittleEndian.AppendUint16(body, 1) // Major version.
+	body = binary.LittleEndian.AppendUint16(body, 0) // Minor version.
+	// The section length is unspecified.
+	body = binary.LittleEndian.AppendUint64(body, ^uint64(0))
+	body = appendPCAPNGOptions(body, []pcapngOption{
+		{code: pcapngOptComment, value: []byte(comment)},
+		{code: pcapngOptUserAppl, value: []byte("gVisor")},
+	})
+	if _, err := w.Write(pcapngBlock(pcapngSectionHeaderBlock, body)); err != nil {
+		return nil, err
+	}
+	return &PCAPNGWriter{w: w}, nil
+}
+
+// AddInterface describes a new interface named name in the stream and returns
+// its ID, which is used to write the interface's packets. Packets are
+// truncated to snapLen bytes.
+func (pw *PCAPNGWriter) AddInterface(name, description string, snapLen uint32) (uint32, error) {
+	body := binary.LittleEndian.AppendUint16(nil, linkTypeRaw)
+	body = binary.LittleEndian.AppendUint16(body, 0) // Reserved.
+	body = binary.LittleEndian.AppendUint32(body, snapLen)
+	body = appendPCAPNGOptions(body, []pcapngOption{
+		{code: pcapngOptIfName, value: []byte(name)},
+		{code: pcapngOptIfDesc, value: []byte(description)},
+		// Timestamps are in nanoseconds.
+		{code: pcapngOptIfTSResol, value: []byte{9}},
+	})
+
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	if _, err := pw.w.Write(pcapngBlock(pcapngInterfaceDescriptionBlock, body)); err != nil {
+		return 0, err
+	}
+	id := pw.nextInterfaceID
+	pw.nextInterfaceID++
+	return id, nil
+}
+
+// WritePacket writes pkt, which must not have a link header, as a packet of
+// interface id.
+func (pw *PCAPNGWriter) WritePacket(id uint32, dir Direction, ts time.Time, pkt *stack.PacketBuffer, snapLen uint32) error {
+	return pw.writeBlock(marshalPCAPNGPacket(id, dir, ts, pkt, snapLen))
+}
+
+// writeBlock writes an already marshalled block.
+func (pw *PCAPNGWriter) writeBlock(b []byte) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	_, err := pw.w.Write(b)
+	return err
+}
+
+// marshalPCAPNGPacket returns an enhanced packet block holding pkt, which
+// must not have a link header.
+func marshalPCAPNGPacket(id uint32, dir Direction, ts time.Time, pkt *stack.PacketBuffer, snapLen uint32) []byte {
+	packetSize := pkt.Size()
+	captureLen := packetSize
+	if captureLen > int(snapLen) {
+		captureLen = int(snapLen)
+	}
+	nsec := uint64(ts.UnixNano())
+
+	body := make([]byte, 0, 20+captureLen+pcapngPadding(captureLen)+16)
+	body = binary.LittleEndian.AppendUint32(body, id)
+	body = binary.LittleEndian.AppendUint32(body, uint32(nsec>>32))
+	body = binary.LittleEndian.AppendUint32(body, uint32(nsec))
+	body = binary.LittleEndian.AppendUint32(body, uint32(captureLen))
+	body = binary.LittleEndian.AppendUint32(body, uint32(packetSize))
+	remaining := captureLen
+	for _, v := range pkt.AsSlices() {
+		if remaining == 0 {
+			break
+		}
+		if len(v) > remaining {
+			v = v[:remaining]
+		}
+		body = append(body, v...)
+		remaining -= len(v)
+	}
+	body = append(body, make([]byte, pcapngPadding(captureLen))...)
+
+	flags := uint32(pcapngEPBFlagInbound)
+	if dir == DirectionSend {
+		flags = pcapngEPBFlagOutbound
+	}
+	body = appendPCAPNGOptions(body, []pcapngOption{
+		{code: pcapngOptEPBFlags, value: binary.LittleEndian.AppendUint32(nil, flags)},
+	})
+	return pcapngBlock(pcapngEnhancedPacketBlock, body)
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sniffer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// RotatingWriter splits a pcapng stream, as written by PCAPNGWriter, into
+// multiple files. A new file is started when the current one reaches a size or
+// age limit. Each file begins with the section header and interface
+// description blocks seen so far, so that every file can be read on its own.
+//
+// Data written to a RotatingWriter doesn't need to be aligned to blocks.
+type RotatingWriter struct {
+	open     func(index int) (io.WriteCloser, error)
+	maxSize  int64
+	interval time.Duration
+	now      func() time.Time
+
+	// headers holds the section header block and interface description
+	// blocks of the current section.
+	headers []byte
+
+	// pending holds a partially written block.
+	pending []byte
+
+	cur      io.WriteCloser
+	curSize  int64
+	curStart time.Time
+	index    int
+}
+
+// NewRotatingWriter returns a RotatingWriter that calls open to create its
+// files, with increasing indices starting at 0. A new file is started once the
+// current one holds at least maxSize bytes, or was started at least interval
+// ago. A zero maxSize or interval disables the corresponding limit.
+func NewRotatingWriter(open func(index int) (io.WriteCloser, error), maxSize int64, interval time.Duration) *RotatingWriter {
+	return &RotatingWriter{
+		open:     open,
+		maxSize:  maxSize,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Write implements io.Writer.Write.
+func (rw *RotatingWriter) Write(b []byte) (int, error) {
+	rw.pending = append(rw.pending, b...)
+	for len(rw.pending) >= 8 {
+		length := binary.LittleEndian.Uint32(rw.pending[4:8])
+		if length < pcapngBlockOverhead || length%4 != 0 {
+			return 0, fmt.Errorf("invalid pcapng block length %d", length)
+		}
+		if len(rw.pending) < int(length) {
+			break
+		}
+		if err := rw.writeBlock(rw.pending[:length]); err != nil {
+			return 0, err
+		}
+		rw.pending = rw.pending[length:]
+	}
+	// Don't hold on to the memory of large writes.
+	rw.pending = append([]byte(nil), rw.pending...)
+	return len(b), nil
+}
+
+func (rw *RotatingWriter) writeBlock(block []byte) error {
+	switch binary.LittleEndian.Uint32(block[:4]) {
+	case pcapngSectionHeaderBlock:
+		rw.headers = append(rw.headers[:0], block...)
+	case pcapngInterfaceDescriptionBlock:
+		rw.headers = append(rw.headers, block...)
+	default:
+		if rw.cur != nil && rw.shouldRotate() {
+			if err := rw.cur.Close(); err != nil {
+				return err
+			}
+			rw.cur = nil
+			rw.index++
+		}
+		if rw.cur == nil {
+			if err := rw.openNext(); err != nil {
+				return err
+			}
+			if err := rw.write(rw.headers); err != nil {
+				return err
+			}
+		}
+		return rw.write(block)
+	}
+	// Headers are written as they arrive to the current file, if any, or
+	// when the first packet creates it.
+	if rw.cur == nil {
+		return nil
+	}
+	return rw.write(block)
+}
+
+func (rw *RotatingWriter) shouldRotate() bool {
+	return (rw.maxSize > 0 && rw.curSize >= rw.maxSize) ||
+		(rw.interval > 0 && rw.now().Sub(rw.curStart) >= rw.interval)
+}
+
+func (rw *RotatingWriter) openNext() error {
+	f, err := rw.open(rw.index)
+	if err != nil {
+		return err
+	}
+	rw.cur = f
+	rw.curSize = 0
+	rw.curStart = rw.now()
+	return nil
+}
+
+func (rw *RotatingWriter) write(b []byte) error {
+	n, err := rw.cur.Write(b)
+	rw.curSize += int64(n)
+	return err
+}
+
+// Close closes the current file. If no packet was written, a file holding
+// only the headers is created so that the output isn't missing.
+func (rw *RotatingWriter) Close() error {
+	if rw.cur == nil {
+		if err := rw.openNext(); err != nil {
+			return err
+		}
+		if err := rw.write(rw.headers); err != nil {
+			rw.cur.Close()
+			return err
+		}
+	}
+	err := rw.cur.Close()
+	rw.cur = nil
+	return err
+}
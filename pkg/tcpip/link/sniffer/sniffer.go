@@ -47,6 +47,14 @@ type Endpoint struct {
 	writer     io.Writer
 	maxPCAPLen uint32
 	logPrefix  string
+
+	// pcapng, if set, receives packets as interface pcapngID instead of
+	// writer.
+	pcapng   *PCAPNGWriter `state:"nosave"`
+	pcapngID uint32
+
+	// filter selects the packets written to writer or pcapng.
+	filter *Filter
 }
 
 var _ stack.GSOEndpoint = (*Endpoint)(nil)
@@ -128,12 +136,52 @@ func writePCAPHeader(w io.Writer, maxLen uint32) error {
 // less than or equal to snapLen will be saved in their entirety. Longer
 // packets will be truncated to snapLen.
 func NewWithWriter(lower stack.LinkEndpoint, writer io.Writer, snapLen uint32) (*Endpoint, error) {
-	if err := writePCAPHeader(writer, snapLen); err != nil {
-		return nil, err
-	}
+	return NewWithOptions(lower, Options{
+		Writer:  writer,
+		SnapLen: snapLen,
+	})
+}
+
+// Options configures a sniffer created with NewWithOptions.
+type Options struct {
+	// Writer receives packets in the pcap format, as with NewWithWriter.
+	Writer io.Writer
+
+	// PCAPNG receives packets in the pcapng format instead of Writer. The
+	// endpoint is described in it as an interface named Name. The same
+	// PCAPNGWriter may be shared by multiple endpoints.
+	PCAPNG *PCAPNGWriter
+
+	// Name is the name of the endpoint's interface in PCAPNG.
+	Name string
+
+	// SnapLen is the maximum amount of a packet to be saved.
+	SnapLen uint32
+
+	// Filter selects the packets to save. If nil, all packets are saved.
+	Filter *Filter
+}
+
+// NewWithOptions creates a new sniffer link-layer endpoint that saves packets
+// as configured by opts. Like with NewWithWriter, packets are not emitted
+// using the standard log package.
+func NewWithOptions(lower stack.LinkEndpoint, opts Options) (*Endpoint, error) {
 	sniffer := &Endpoint{
-		writer:     writer,
-		maxPCAPLen: snapLen,
+		maxPCAPLen: opts.SnapLen,
+		filter:     opts.Filter,
+	}
+	if opts.PCAPNG != nil {
+		id, err := opts.PCAPNG.AddInterface(opts.Name, "", opts.SnapLen)
+		if err != nil {
+			return nil, err
+		}
+		sniffer.pcapng = opts.PCAPNG
+		sniffer.pcapngID = id
+	} else {
+		if err := writePCAPHeader(opts.Writer, opts.SnapLen); err != nil {
+			return nil, err
+		}
+		sniffer.writer = opts.Writer
 	}
 	sniffer.Endpoint.Init(lower, sniffer)
 	return sniffer, nil
@@ -148,28 +196,45 @@ func (e *Endpoint) DeliverNetworkPacket(protocol tcpip.NetworkProtocolNumber, pk
 }
 
 // DumpPacket logs a packet, depending on configuration, to stderr and/or a
-// pcap file. ts is an optional timestamp for the packet.
+// pcap or pcapng file. ts is an optional timestamp for the packet.
 func (e *Endpoint) DumpPacket(dir Direction, protocol tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer, ts *time.Time) {
 	if LogPackets.Load() == 1 {
 		LogPacket(e.logPrefix, dir, protocol, pkt)
 	}
-	if e.writer != nil {
-		packet := pcapPacket{
-			packet:        pkt,
-			maxCaptureLen: int(e.maxPCAPLen),
-		}
-		if ts == nil {
-			packet.timestamp = time.Now()
-		} else {
-			packet.timestamp = *ts
-		}
-		b, err := packet.MarshalBinary()
-		if err != nil {
-			panic(err)
+	if e.writer == nil && e.pcapng == nil {
+		return
+	}
+	if e.filter != nil {
+		clone := trimmedClone(pkt)
+		match := e.filter.Match(clone)
+		clone.DecRef()
+		if !match {
+			return
 		}
-		if _, err := e.writer.Write(b); err != nil {
+	}
+	timestamp := time.Now()
+	if ts != nil {
+		timestamp = *ts
+	}
+	if e.pcapng != nil {
+		clone := trimmedClone(pkt)
+		defer clone.DecRef()
+		if err := e.pcapng.WritePacket(e.pcapngID, dir, timestamp, clone, e.maxPCAPLen); err != nil {
 			panic(err)
 		}
+		return
+	}
+	packet := pcapPacket{
+		timestamp:     timestamp,
+		packet:        pkt,
+		maxCaptureLen: int(e.maxPCAPLen),
+	}
+	b, err := packet.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	if _, err := e.writer.Write(b); err != nil {
+		panic(err)
 	}
 }
 
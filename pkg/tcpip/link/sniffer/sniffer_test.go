@@ -0,0 +1,249 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sniffer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// udpFilter is `tcpdump -y RAW -ddd udp` for IPv4 packets.
+const udpFilter = "4,48 0 0 9,21 0 1 17,6 0 0 262144,6 0 0 0"
+
+func ipv4Packet(proto tcpip.TransportProtocolNumber, payloadSize int) *stack.PacketBuffer {
+	b := make([]byte, header.IPv4MinimumSize+payloadSize)
+	header.IPv4(b).Encode(&header.IPv4Fields{
+		TotalLength: uint16(len(b)),
+		TTL:         64,
+		Protocol:    uint8(proto),
+		SrcAddr:     tcpip.AddrFrom4([4]byte{10, 0, 0, 1}),
+		DstAddr:     tcpip.AddrFrom4([4]byte{10, 0, 0, 2}),
+	})
+	return stack.NewPacketBuffer(stack.PacketBufferOptions{
+		Payload: buffer.MakeWithData(b),
+	})
+}
+
+func TestFilter(t *testing.T) {
+	insns, err := ParseFilter(udpFilter)
+	if err != nil {
+		t.Fatalf("ParseFilter(%q): %v", udpFilter, err)
+	}
+	filter, err := NewFilter(insns)
+	if err != nil {
+		t.Fatalf("NewFilter(%v): %v", insns, err)
+	}
+
+	for _, test := range []struct {
+		proto tcpip.TransportProtocolNumber
+		want  bool
+	}{
+		{proto: header.UDPProtocolNumber, want: true},
+		{proto: header.TCPProtocolNumber, want: false},
+	} {
+		pkt := ipv4Packet(test.proto, 8)
+		if got := filter.Match(pkt); got != test.want {
+			t.Errorf("got filter.Match(<protocol %d>) = %t, want %t", test.proto, got, test.want)
+		}
+		if got := (*Filter)(nil).Match(pkt); !got {
+			t.Errorf("got nil filter.Match(<protocol %d>) = false, want true", test.proto)
+		}
+		pkt.DecRef()
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"x",
+		"2,6 0 0 0",
+		"1,6 0 0",
+		"1,6 0 256 0",
+	} {
+		if _, err := ParseFilter(s); err == nil {
+			t.Errorf("ParseFilter(%q) succeeded, want error", s)
+		}
+	}
+}
+
+type pcapngBlockInfo struct {
+	Type uint32
+	Body []byte
+}
+
+// parsePCAPNG splits b into blocks, checking their framing.
+func parsePCAPNG(t *testing.T, b []byte) []pcapngBlockInfo {
+	t.Helper()
+	var blocks []pcapngBlockInfo
+	for len(b) > 0 {
+		if len(b) < pcapngBlockOverhead {
+			t.Fatalf("truncated block: %v", b)
+		}
+		length := binary.LittleEndian.Uint32(b[4:8])
+		if length%4 != 0 || int(length) > len(b) {
+			t.Fatalf("invalid block length %d with %d bytes left", length, len(b))
+		}
+		if trailer := binary.LittleEndian.Uint32(b[length-4 : length]); trailer != length {
+			t.Fatalf("got trailing block length %d, want %d", trailer, length)
+		}
+		blocks = append(blocks, pcapngBlockInfo{
+			Type: binary.LittleEndian.Uint32(b[:4]),
+			Body: b[8 : length-4],
+		})
+		b = b[length:]
+	}
+	return blocks
+}
+
+func blockTypes(blocks []pcapngBlockInfo) []uint32 {
+	var types []uint32
+	for _, b := range blocks {
+		types = append(types, b.Type)
+	}
+	return types
+}
+
+func TestPCAPNGWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewPCAPNGWriter(&buf, "sandbox abc")
+	if err != nil {
+		t.Fatalf("NewPCAPNGWriter: %v", err)
+	}
+	for _, name := range []string{"lo", "eth0"} {
+		if _, err := w.AddInterface(name, "", 16); err != nil {
+			t.Fatalf("AddInterface(%q): %v", name, err)
+		}
+	}
+	pkt := ipv4Packet(header.UDPProtocolNumber, 10)
+	defer pkt.DecRef()
+	ts := time.Unix(1, 5)
+	if err := w.WritePacket(1, DirectionSend, ts, pkt, 16); err != nil {
+		t.Fatalf("WritePacket: %v", err)
+	}
+
+	blocks := parsePCAPNG(t, buf.Bytes())
+	want := []uint32{pcapngSectionHeaderBlock, pcapngInterfaceDescriptionBlock, pcapngInterfaceDescriptionBlock, pcapngEnhancedPacketBlock}
+	if diff := cmp.Diff(want, blockTypes(blocks)); diff != "" {
+		t.Fatalf("block types mismatch (-want +got):\n%s", diff)
+	}
+	if !bytes.Contains(blocks[0].Body, []byte("sandbox abc")) {
+		t.Errorf("section header %v doesn't contain the comment", blocks[0].Body)
+	}
+	if !bytes.Contains(blocks[2].Body, []byte("eth0")) {
+		t.Errorf("interface description %v doesn't contain the interface name", blocks[2].Body)
+	}
+
+	epb := blocks[3].Body
+	if got := binary.LittleEndian.Uint32(epb[0:4]); got != 1 {
+		t.Errorf("got interface ID %d, want 1", got)
+	}
+	nsec := uint64(binary.LittleEndian.Uint32(epb[4:8]))<<32 | uint64(binary.LittleEndian.Uint32(epb[8:12]))
+	if want := uint64(ts.UnixNano()); nsec != want {
+		t.Errorf("got timestamp %d, want %d", nsec, want)
+	}
+	if got := binary.LittleEndian.Uint32(epb[12:16]); got != 16 {
+		t.Errorf("got captured length %d, want 16", got)
+	}
+	if got, want := binary.LittleEndian.Uint32(epb[16:20]), uint32(pkt.Size()); got != want {
+		t.Errorf("got original length %d, want %d", got, want)
+	}
+	data := pkt.ToView()
+	defer data.Release()
+	if diff := cmp.Diff(data.AsSlice()[:16], epb[20:36]); diff != "" {
+		t.Errorf("packet data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+type closeBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closeBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestRotatingWriter(t *testing.T) {
+	var stream bytes.Buffer
+	w, err := NewPCAPNGWriter(&stream, "")
+	if err != nil {
+		t.Fatalf("NewPCAPNGWriter: %v", err)
+	}
+	if _, err := w.AddInterface("eth0", "", 64); err != nil {
+		t.Fatalf("AddInterface: %v", err)
+	}
+	pkt := ipv4Packet(header.UDPProtocolNumber, 10)
+	defer pkt.DecRef()
+	const numPackets = 5
+	for i := 0; i < numPackets; i++ {
+		if err := w.WritePacket(0, DirectionRecv, time.Now(), pkt, 64); err != nil {
+			t.Fatalf("WritePacket: %v", err)
+		}
+	}
+
+	var files []*closeBuffer
+	open := func(index int) (*closeBuffer, error) {
+		if index != len(files) {
+			t.Fatalf("got file index %d, want %d", index, len(files))
+		}
+		f := &closeBuffer{}
+		files = append(files, f)
+		return f, nil
+	}
+	// Each file holds two packets.
+	packetSize := len(marshalPCAPNGPacket(0, DirectionRecv, time.Now(), pkt, 64))
+	headersSize := stream.Len() - numPackets*packetSize
+	rw := NewRotatingWriter(func(index int) (io.WriteCloser, error) { return open(index) }, int64(headersSize+2*packetSize), 0)
+
+	// Write the stream in pieces that don't align with blocks.
+	b := stream.Bytes()
+	for len(b) > 0 {
+		n := min(7, len(b))
+		if _, err := rw.Write(b[:n]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		b = b[n:]
+	}
+	if err := rw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	wantPackets := []int{2, 2, 1}
+	if len(files) != len(wantPackets) {
+		t.Fatalf("got %d files, want %d", len(files), len(wantPackets))
+	}
+	for i, f := range files {
+		if !f.closed {
+			t.Errorf("file %d wasn't closed", i)
+		}
+		want := []uint32{pcapngSectionHeaderBlock, pcapngInterfaceDescriptionBlock}
+		for j := 0; j < wantPackets[i]; j++ {
+			want = append(want, pcapngEnhancedPacketBlock)
+		}
+		if diff := cmp.Diff(want, blockTypes(parsePCAPNG(t, f.Bytes()))); diff != "" {
+			t.Errorf("file %d block types mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
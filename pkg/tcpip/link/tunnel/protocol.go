@@ -0,0 +1,329 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/ports"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/raw"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	// GREProtocolNumber is the transport protocol number of GRE.
+	GREProtocolNumber = header.GREProtocolNumber
+
+	// IPIPProtocolNumber is the transport protocol number of IPv4
+	// encapsulation.
+	IPIPProtocolNumber tcpip.TransportProtocolNumber = 4
+
+	// IPv6EncapProtocolNumber is the transport protocol number of IPv6
+	// encapsulation.
+	IPv6EncapProtocolNumber tcpip.TransportProtocolNumber = 41
+)
+
+// protocol implements stack.TransportProtocol for one of the tunneling
+// protocols. It doesn't provide sockets; it only demultiplexes encapsulated
+// packets to tunnel link endpoints.
+//
+// +stateify savable
+type protocol struct {
+	stack  *stack.Stack
+	number tcpip.TransportProtocolNumber
+
+	mu protocolMutex `state:"nosave"`
+	// groups holds the tunnels attached to this protocol, keyed by their
+	// outer addresses.
+	//
+	// +checklocks:mu
+	groups map[groupKey]*group
+}
+
+// groupKey identifies the set of tunnels that share outer addresses.
+//
+// +stateify savable
+type groupKey struct {
+	netProto tcpip.NetworkProtocolNumber
+	local    tcpip.Address
+	remote   tcpip.Address
+}
+
+// Number implements stack.TransportProtocol.Number.
+func (p *protocol) Number() tcpip.TransportProtocolNumber {
+	return p.number
+}
+
+// NewEndpoint implements stack.TransportProtocol.NewEndpoint.
+func (*protocol) NewEndpoint(tcpip.NetworkProtocolNumber, *waiter.Queue) (tcpip.Endpoint, tcpip.Error) {
+	return nil, &tcpip.ErrNotSupported{}
+}
+
+// NewRawEndpoint implements stack.TransportProtocol.NewRawEndpoint.
+func (p *protocol) NewRawEndpoint(netProto tcpip.NetworkProtocolNumber, waiterQueue *waiter.Queue) (tcpip.Endpoint, tcpip.Error) {
+	return raw.NewEndpoint(p.stack, netProto, p.number, waiterQueue)
+}
+
+// MinimumPacketSize implements stack.TransportProtocol.MinimumPacketSize.
+func (p *protocol) MinimumPacketSize() int {
+	switch p.number {
+	case GREProtocolNumber:
+		return header.GREMinimumSize
+	case IPIPProtocolNumber:
+		return header.IPv4MinimumSize
+	case IPv6EncapProtocolNumber:
+		return header.IPv6MinimumSize
+	}
+	panic(fmt.Sprint("unknown protocol number: ", p.number))
+}
+
+// ParsePorts implements stack.TransportProtocol.ParsePorts. Tunneling protocols
+// don't have ports, so src and dst are always 0.
+func (p *protocol) ParsePorts(v []byte) (src, dst uint16, err tcpip.Error) {
+	if p.number == GREProtocolNumber && header.GRE(v).Version() != 0 {
+		return 0, 0, &tcpip.ErrUnknownProtocol{}
+	}
+	return 0, 0, nil
+}
+
+// HandleUnknownDestinationPacket implements
+// stack.TransportProtocol.HandleUnknownDestinationPacket.
+func (p *protocol) HandleUnknownDestinationPacket(_ stack.TransportEndpointID, pkt *stack.PacketBuffer) stack.UnknownDestinationPacketDisposition {
+	// Like Linux, drop packets with an invalid GRE header silently, and have
+	// the stack reply with a port unreachable error to other packets that
+	// don't match any tunnel.
+	if p.number == GREProtocolNumber && !header.GRE(pkt.TransportHeader().Slice()).IsValid() {
+		return stack.UnknownDestinationPacketMalformed
+	}
+	return stack.UnknownDestinationPacketUnhandled
+}
+
+// SetOption implements stack.TransportProtocol.SetOption.
+func (*protocol) SetOption(tcpip.SettableTransportProtocolOption) tcpip.Error {
+	return &tcpip.ErrUnknownProtocolOption{}
+}
+
+// Option implements stack.TransportProtocol.Option.
+func (*protocol) Option(tcpip.GettableTransportProtocolOption) tcpip.Error {
+	return &tcpip.ErrUnknownProtocolOption{}
+}
+
+// Close implements stack.TransportProtocol.Close.
+func (*protocol) Close() {}
+
+// Wait implements stack.TransportProtocol.Wait.
+func (*protocol) Wait() {}
+
+// Pause implements stack.TransportProtocol.Pause.
+func (*protocol) Pause() {}
+
+// Resume implements stack.TransportProtocol.Resume.
+func (*protocol) Resume() {}
+
+// Restore implements stack.TransportProtocol.Restore.
+func (*protocol) Restore() {}
+
+// Parse implements stack.TransportProtocol.Parse.
+//
+// For GRE, the transport header is the GRE header. For IP encapsulation, the
+// transport header is the fixed part of the inner IP header, which is put
+// back in front of the payload on decapsulation.
+func (p *protocol) Parse(pkt *stack.PacketBuffer) bool {
+	switch p.number {
+	case GREProtocolNumber:
+		hdr, ok := pkt.Data().PullUp(header.GREMinimumSize)
+		if !ok {
+			return false
+		}
+		_, ok = pkt.TransportHeader().Consume(header.GRE(hdr).HeaderLength())
+		return ok
+	default:
+		_, ok := pkt.TransportHeader().Consume(p.MinimumPacketSize())
+		return ok
+	}
+}
+
+// attach adds ep to the tunnels handled by p.
+func (p *protocol) attach(key groupKey, ep *Endpoint) tcpip.Error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	g, ok := p.groups[key]
+	if !ok {
+		g = &group{
+			protocol: p,
+			key:      key,
+			tunnels:  make(map[tunnelID]*Endpoint),
+		}
+		id := stack.TransportEndpointID{
+			LocalAddress:  key.local,
+			RemoteAddress: key.remote,
+		}
+		if err := p.stack.RegisterTransportEndpoint([]tcpip.NetworkProtocolNumber{key.netProto}, p.number, id, g, ports.Flags{}, 0 /* bindToDevice */); err != nil {
+			return err
+		}
+		if p.groups == nil {
+			p.groups = make(map[groupKey]*group)
+		}
+		p.groups[key] = g
+	}
+	return g.add(ep)
+}
+
+// detach removes ep from the tunnels handled by p.
+func (p *protocol) detach(key groupKey, ep *Endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	g, ok := p.groups[key]
+	if !ok {
+		return
+	}
+	if g.remove(ep) > 0 {
+		return
+	}
+	id := stack.TransportEndpointID{
+		LocalAddress:  key.local,
+		RemoteAddress: key.remote,
+	}
+	p.stack.UnregisterTransportEndpoint([]tcpip.NetworkProtocolNumber{key.netProto}, p.number, id, g, ports.Flags{}, 0 /* bindToDevice */)
+	delete(p.groups, key)
+}
+
+// tunnelID distinguishes tunnels that share outer addresses.
+//
+// +stateify savable
+type tunnelID struct {
+	// keyed is true if the tunnel requires GRE packets to carry key.
+	keyed bool
+	key   uint32
+
+	// tap is true for tunnels that carry ethernet frames.
+	tap bool
+}
+
+// group is the transport endpoint registered with the stack for all tunnels
+// sharing a pair of outer addresses. It implements stack.TransportEndpoint.
+//
+// +stateify savable
+type group struct {
+	protocol *protocol
+	key      groupKey
+
+	mu groupRWMutex `state:"nosave"`
+	// +checklocks:mu
+	tunnels map[tunnelID]*Endpoint
+}
+
+func (g *group) add(ep *Endpoint) tcpip.Error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id := ep.tunnelID()
+	if _, ok := g.tunnels[id]; ok {
+		return &tcpip.ErrDuplicateAddress{}
+	}
+	g.tunnels[id] = ep
+	return nil
+}
+
+// remove removes ep from g and returns the number of remaining tunnels.
+func (g *group) remove(ep *Endpoint) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.tunnels[ep.tunnelID()] == ep {
+		delete(g.tunnels, ep.tunnelID())
+	}
+	return len(g.tunnels)
+}
+
+func (g *group) find(id tunnelID) *Endpoint {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.tunnels[id]
+}
+
+// HandlePacket implements stack.TransportEndpoint.HandlePacket.
+func (g *group) HandlePacket(_ stack.TransportEndpointID, pkt *stack.PacketBuffer) {
+	var (
+		id       tunnelID
+		netProto tcpip.NetworkProtocolNumber
+		payload  buffer.Buffer
+	)
+	switch g.protocol.number {
+	case GREProtocolNumber:
+		hdr := header.GRE(pkt.TransportHeader().Slice())
+		if !hdr.IsValid() || !hdr.IsChecksumValid(pkt.Data().Checksum()) {
+			return
+		}
+		id.key, id.keyed = hdr.Key()
+		netProto = hdr.Protocol()
+		id.tap = netProto == header.GREProtocolTransparentEthernetBridging
+	case IPIPProtocolNumber:
+		netProto = header.IPv4ProtocolNumber
+		payload = buffer.MakeWithView(pkt.TransportHeader().View())
+	case IPv6EncapProtocolNumber:
+		netProto = header.IPv6ProtocolNumber
+		payload = buffer.MakeWithView(pkt.TransportHeader().View())
+	}
+
+	ep := g.find(id)
+	if ep == nil {
+		payload.Release()
+		return
+	}
+	data := pkt.Data().ToBuffer()
+	payload.Merge(&data)
+	ep.deliverInbound(netProto, pkt, payload)
+}
+
+// HandleError implements stack.TransportEndpoint.HandleError.
+func (g *group) HandleError(transErr stack.TransportError, pkt *stack.PacketBuffer) {
+	if transErr.Kind() != stack.PacketTooBigTransportError {
+		return
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for _, ep := range g.tunnels {
+		ep.updatePMTU(transErr.Info())
+	}
+}
+
+// Abort implements stack.TransportEndpoint.Abort.
+func (*group) Abort() {}
+
+// Wait implements stack.TransportEndpoint.Wait.
+func (*group) Wait() {}
+
+// NewGREProtocol returns a GRE transport protocol, which demultiplexes packets
+// to gre and gretap devices.
+func NewGREProtocol(s *stack.Stack) stack.TransportProtocol {
+	return &protocol{stack: s, number: GREProtocolNumber}
+}
+
+// NewIPIPProtocol returns an IPv4 encapsulation transport protocol, which
+// demultiplexes packets to ipip and ip6tnl devices.
+func NewIPIPProtocol(s *stack.Stack) stack.TransportProtocol {
+	return &protocol{stack: s, number: IPIPProtocolNumber}
+}
+
+// NewIPv6EncapProtocol returns an IPv6 encapsulation transport protocol, which
+// demultiplexes packets to sit and ip6tnl devices.
+func NewIPv6EncapProtocol(s *stack.Stack) stack.TransportProtocol {
+	return &protocol{stack: s, number: IPv6EncapProtocolNumber}
+}
@@ -0,0 +1,610 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tunnel provides link endpoints for IP tunnel devices: gre, gretap,
+// ipip, sit and ip6tnl.
+//
+// A tunnel endpoint encapsulates the packets written to it in an outer IP
+// packet which it sends through the stack it was created on. Encapsulated
+// packets are received by the GRE, IPIP and IPv6 encapsulation transport
+// protocols (see NewGREProtocol, NewIPIPProtocol and NewIPv6EncapProtocol),
+// which must be registered with the stack, and are demultiplexed to tunnel
+// endpoints by their outer addresses and GRE key.
+package tunnel
+
+import (
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// Kind is the type of a tunnel device.
+type Kind int
+
+const (
+	// KindGRE carries IP packets in GRE over IPv4.
+	KindGRE Kind = iota
+
+	// KindGRETap carries ethernet frames in GRE over IPv4.
+	KindGRETap
+
+	// KindIPIP carries IPv4 packets in IPv4.
+	KindIPIP
+
+	// KindSIT carries IPv6 packets in IPv4.
+	KindSIT
+
+	// KindIP6Tnl carries IPv4 or IPv6 packets in IPv6.
+	KindIP6Tnl
+)
+
+// String returns the name of the tunnel kind as used by IFLA_INFO_KIND.
+func (k Kind) String() string {
+	switch k {
+	case KindGRE:
+		return "gre"
+	case KindGRETap:
+		return "gretap"
+	case KindIPIP:
+		return "ipip"
+	case KindSIT:
+		return "sit"
+	case KindIP6Tnl:
+		return "ip6tnl"
+	default:
+		return "unknown"
+	}
+}
+
+// KindFromString returns the tunnel kind named s.
+func KindFromString(s string) (Kind, bool) {
+	for k := KindGRE; k <= KindIP6Tnl; k++ {
+		if k.String() == s {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// TOSInherit is the bit of Config.TOS that requests inheriting the TOS of
+// encapsulated packets, as in Linux.
+const TOSInherit = 1
+
+// defaultLinkMTU is the MTU assumed for the underlying link of tunnels that
+// aren't bound to a NIC.
+const defaultLinkMTU = 1500
+
+// Config describes a tunnel.
+//
+// +stateify savable
+type Config struct {
+	// Kind is the type of the tunnel.
+	Kind Kind
+
+	// Local is the source address of outer packets. It may be unspecified,
+	// in which case packets to any local address are received and the
+	// source address is picked by the stack.
+	Local tcpip.Address
+
+	// Remote is the destination address of outer packets. It may be
+	// unspecified, in which case packets from any remote address are
+	// received, but nothing can be sent.
+	Remote tcpip.Address
+
+	// Link is the NIC outer packets are sent through. Zero means any.
+	Link tcpip.NICID
+
+	// IFlags and OFlags are the GRE flags of received and sent packets.
+	// Only GREFlagKey, GREFlagChecksum and GREFlagSequence are supported.
+	IFlags header.GREFlags
+	OFlags header.GREFlags
+
+	// IKey and OKey are the GRE keys of received and sent packets. They are
+	// only used if GREFlagKey is set in IFlags and OFlags respectively.
+	IKey uint32
+	OKey uint32
+
+	// TTL is the TTL of outer packets. Zero means inherit the TTL of the
+	// encapsulated packet.
+	TTL uint8
+
+	// TOS is the TOS of outer packets. If the TOSInherit bit is set, the TOS
+	// of the encapsulated packet is used.
+	TOS uint8
+
+	// PMTUDisc enables path MTU discovery on the tunnel: outer packets are
+	// sent with DF set, and the MTU of the tunnel is lowered when ICMP
+	// fragmentation needed or packet too big errors are received.
+	PMTUDisc bool
+
+	// Proto restricts the encapsulated protocol of ip6tnl tunnels to
+	// IPIPProtocolNumber or IPv6EncapProtocolNumber. Zero means any.
+	Proto tcpip.TransportProtocolNumber
+}
+
+// outerNetProto returns the network protocol of outer packets.
+func (c *Config) outerNetProto() tcpip.NetworkProtocolNumber {
+	if c.Kind == KindIP6Tnl {
+		return header.IPv6ProtocolNumber
+	}
+	return header.IPv4ProtocolNumber
+}
+
+// transportProtocols returns the protocols that carry the tunnel's packets.
+func (c *Config) transportProtocols() []tcpip.TransportProtocolNumber {
+	switch c.Kind {
+	case KindGRE, KindGRETap:
+		return []tcpip.TransportProtocolNumber{GREProtocolNumber}
+	case KindIPIP:
+		return []tcpip.TransportProtocolNumber{IPIPProtocolNumber}
+	case KindSIT:
+		return []tcpip.TransportProtocolNumber{IPv6EncapProtocolNumber}
+	case KindIP6Tnl:
+		if c.Proto != 0 {
+			return []tcpip.TransportProtocolNumber{c.Proto}
+		}
+		return []tcpip.TransportProtocolNumber{IPIPProtocolNumber, IPv6EncapProtocolNumber}
+	}
+	return nil
+}
+
+// encapHeaderLength returns the length of the headers added between the outer
+// IP header and the encapsulated packet.
+func (c *Config) encapHeaderLength() int {
+	if c.Kind == KindGRE || c.Kind == KindGRETap {
+		return header.GREHeaderLength(c.OFlags)
+	}
+	return 0
+}
+
+// overhead returns the number of bytes added to an encapsulated network packet.
+func (c *Config) overhead() uint32 {
+	overhead := uint32(header.IPv4MinimumSize)
+	if c.outerNetProto() == header.IPv6ProtocolNumber {
+		overhead = header.IPv6MinimumSize
+	}
+	overhead += uint32(c.encapHeaderLength())
+	if c.Kind == KindGRETap {
+		overhead += header.EthernetMinimumSize
+	}
+	return overhead
+}
+
+// validate checks that c describes a supported tunnel.
+func (c *Config) validate() tcpip.Error {
+	addrLen := header.IPv4AddressSize
+	if c.outerNetProto() == header.IPv6ProtocolNumber {
+		addrLen = header.IPv6AddressSize
+	}
+	for _, addr := range []tcpip.Address{c.Local, c.Remote} {
+		if addr.Len() != 0 && addr.Len() != addrLen {
+			return &tcpip.ErrBadAddress{}
+		}
+	}
+	switch c.Kind {
+	case KindGRE, KindGRETap:
+		if (c.IFlags|c.OFlags)&^header.GREFlagsMask != 0 {
+			return &tcpip.ErrNotSupported{}
+		}
+	case KindIPIP, KindSIT:
+	case KindIP6Tnl:
+		if c.Proto != 0 && c.Proto != IPIPProtocolNumber && c.Proto != IPv6EncapProtocolNumber {
+			return &tcpip.ErrNotSupported{}
+		}
+	default:
+		return &tcpip.ErrNotSupported{}
+	}
+	return nil
+}
+
+var _ stack.LinkEndpoint = (*Endpoint)(nil)
+
+// Endpoint is a tunnel link endpoint.
+//
+// +stateify savable
+type Endpoint struct {
+	stack *stack.Stack
+
+	// cfg is immutable.
+	cfg Config
+
+	// seq is the sequence number of the next GRE packet.
+	seq atomicbitops.Uint32
+
+	mu endpointRWMutex `state:"nosave"`
+	// +checklocks:mu
+	dispatcher stack.NetworkDispatcher
+	// +checklocks:mu
+	linkAddr tcpip.LinkAddress
+	// +checklocks:mu
+	mtu uint32
+	// +checklocks:mu
+	closed bool
+	// +checklocks:mu
+	onCloseAction func() `state:"nosave"`
+}
+
+// New creates a tunnel endpoint on s. The transport protocols carrying the
+// tunnel's packets must be registered with s.
+//
+// The returned endpoint is usually wrapped by ethernet.New for KindGRETap
+// before being passed to s.CreateNIC.
+func New(s *stack.Stack, cfg Config) (*Endpoint, tcpip.Error) {
+	// Unspecified addresses are wildcards when demultiplexing packets.
+	if cfg.Local.Unspecified() {
+		cfg.Local = tcpip.Address{}
+	}
+	if cfg.Remote.Unspecified() {
+		cfg.Remote = tcpip.Address{}
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	linkMTU := uint32(defaultLinkMTU)
+	if cfg.Link != 0 {
+		info, ok := s.NICInfo()[cfg.Link]
+		if !ok {
+			return nil, &tcpip.ErrUnknownNICID{}
+		}
+		linkMTU = info.MTU
+	}
+	if linkMTU <= cfg.overhead() {
+		return nil, &tcpip.ErrInvalidEndpointState{}
+	}
+	var linkAddr tcpip.LinkAddress
+	if cfg.Kind == KindGRETap {
+		linkAddr = tcpip.GetRandMacAddr()
+	}
+	e := &Endpoint{
+		stack:    s,
+		cfg:      cfg,
+		linkAddr: linkAddr,
+		mtu:      linkMTU - cfg.overhead(),
+	}
+
+	var attached []*protocol
+	for _, number := range cfg.transportProtocols() {
+		p, ok := s.TransportProtocolInstance(number).(*protocol)
+		if !ok {
+			e.detach(attached)
+			return nil, &tcpip.ErrUnknownProtocol{}
+		}
+		if err := p.attach(e.groupKey(), e); err != nil {
+			e.detach(attached)
+			return nil, err
+		}
+		attached = append(attached, p)
+	}
+	return e, nil
+}
+
+func (e *Endpoint) detach(protocols []*protocol) {
+	for _, p := range protocols {
+		p.detach(e.groupKey(), e)
+	}
+}
+
+func (e *Endpoint) groupKey() groupKey {
+	return groupKey{
+		netProto: e.cfg.outerNetProto(),
+		local:    e.cfg.Local,
+		remote:   e.cfg.Remote,
+	}
+}
+
+func (e *Endpoint) tunnelID() tunnelID {
+	var id tunnelID
+	if e.cfg.Kind == KindGRE || e.cfg.Kind == KindGRETap {
+		id.keyed = e.cfg.IFlags&header.GREFlagKey != 0
+		if id.keyed {
+			id.key = e.cfg.IKey
+		}
+		id.tap = e.cfg.Kind == KindGRETap
+	}
+	return id
+}
+
+// Config returns the configuration of the tunnel.
+func (e *Endpoint) Config() Config {
+	return e.cfg
+}
+
+// encapProtocol returns the transport protocol used to carry a packet of the
+// given network protocol.
+func (e *Endpoint) encapProtocol(netProto tcpip.NetworkProtocolNumber) (tcpip.TransportProtocolNumber, bool) {
+	switch e.cfg.Kind {
+	case KindGRE, KindGRETap:
+		return GREProtocolNumber, true
+	case KindIPIP:
+		return IPIPProtocolNumber, netProto == header.IPv4ProtocolNumber
+	case KindSIT:
+		return IPv6EncapProtocolNumber, netProto == header.IPv6ProtocolNumber
+	case KindIP6Tnl:
+		var p tcpip.TransportProtocolNumber
+		switch netProto {
+		case header.IPv4ProtocolNumber:
+			p = IPIPProtocolNumber
+		case header.IPv6ProtocolNumber:
+			p = IPv6EncapProtocolNumber
+		default:
+			return 0, false
+		}
+		return p, e.cfg.Proto == 0 || e.cfg.Proto == p
+	}
+	return 0, false
+}
+
+// innerFields returns the TTL, TOS and DF bit of an encapsulated IP packet. ok
+// is false if pkt isn't an IP packet.
+func innerFields(pkt *stack.PacketBuffer) (ttl, tos uint8, df, ok bool) {
+	h := pkt.NetworkHeader().Slice()
+	switch pkt.NetworkProtocolNumber {
+	case header.IPv4ProtocolNumber:
+		if len(h) < header.IPv4MinimumSize {
+			return 0, 0, false, false
+		}
+		ip := header.IPv4(h)
+		tos, _ = ip.TOS()
+		return ip.TTL(), tos, ip.Flags()&header.IPv4FlagDontFragment != 0, true
+	case header.IPv6ProtocolNumber:
+		if len(h) < header.IPv6MinimumSize {
+			return 0, 0, false, false
+		}
+		ip := header.IPv6(h)
+		tos, _ = ip.TOS()
+		// IPv6 packets are never fragmented by routers.
+		return ip.HopLimit(), tos, true, true
+	}
+	return 0, 0, false, false
+}
+
+// writePacket encapsulates pkt and sends it to the remote end of the tunnel.
+func (e *Endpoint) writePacket(pkt *stack.PacketBuffer) tcpip.Error {
+	transProto, ok := e.encapProtocol(pkt.NetworkProtocolNumber)
+	if !ok {
+		return &tcpip.ErrNotSupported{}
+	}
+	if e.cfg.Remote.Unspecified() {
+		return &tcpip.ErrHostUnreachable{}
+	}
+	r, err := e.stack.FindRoute(e.cfg.Link, e.cfg.Local, e.cfg.Remote, e.cfg.outerNetProto(), false /* multicastLoop */)
+	if err != nil {
+		return err
+	}
+	defer r.Release()
+
+	params := stack.NetworkHeaderParams{
+		Protocol: transProto,
+		TTL:      e.cfg.TTL,
+		TOS:      e.cfg.TOS &^ TOSInherit,
+	}
+	ttl, tos, df, isIP := innerFields(pkt)
+	if params.TTL == 0 {
+		params.TTL = r.DefaultTTL()
+		if isIP {
+			params.TTL = ttl
+		}
+	}
+	if e.cfg.TOS&TOSInherit != 0 && isIP {
+		params.TOS = tos
+	}
+	// As in Linux, with path MTU discovery enabled the DF bit of IPv4
+	// packets is copied to the outer header, and other packets are never
+	// fragmented.
+	params.DF = e.cfg.PMTUDisc && (df || !isIP)
+
+	hdrLen := e.cfg.encapHeaderLength()
+	payload := pkt.ToBuffer()
+	if params.DF && int(payload.Size())+hdrLen > int(r.MTU()) {
+		payload.Release()
+		return &tcpip.ErrMessageTooLong{}
+	}
+	outer := stack.NewPacketBuffer(stack.PacketBufferOptions{
+		ReserveHeaderBytes: int(r.MaxHeaderLength()) + hdrLen,
+		Payload:            payload,
+	})
+	defer outer.DecRef()
+	if hdrLen != 0 {
+		fields := header.GREFields{
+			Flags:    e.cfg.OFlags,
+			Protocol: pkt.NetworkProtocolNumber,
+			Key:      e.cfg.OKey,
+		}
+		if e.cfg.Kind == KindGRETap {
+			fields.Protocol = header.GREProtocolTransparentEthernetBridging
+		}
+		if fields.Flags&header.GREFlagSequence != 0 {
+			fields.Sequence = e.seq.Add(1) - 1
+		}
+		gre := header.GRE(outer.TransportHeader().Push(hdrLen))
+		gre.Encode(&fields)
+		if fields.Flags&header.GREFlagChecksum != 0 {
+			gre.SetChecksum(^gre.CalculateChecksum(outer.Data().Checksum()))
+		}
+	}
+	outer.TransportProtocolNumber = transProto
+	return r.WritePacket(params, outer)
+}
+
+// deliverInbound delivers a decapsulated packet to the stack.
+func (e *Endpoint) deliverInbound(netProto tcpip.NetworkProtocolNumber, outer *stack.PacketBuffer, payload buffer.Buffer) {
+	e.mu.RLock()
+	d := e.dispatcher
+	e.mu.RUnlock()
+	if d == nil {
+		payload.Release()
+		return
+	}
+	if e.cfg.Kind == KindGRE || e.cfg.Kind == KindGRETap {
+		hdr := header.GRE(outer.TransportHeader().Slice())
+		if hdr.Flags()&header.GREFlagChecksum == 0 && e.cfg.IFlags&header.GREFlagChecksum != 0 {
+			// The tunnel requires checksums.
+			payload.Release()
+			return
+		}
+	}
+	pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{
+		Payload: payload,
+	})
+	defer pkt.DecRef()
+	d.DeliverNetworkPacket(netProto, pkt)
+}
+
+// updatePMTU lowers the MTU of the tunnel to fit in the path MTU reported by
+// an ICMP error.
+func (e *Endpoint) updatePMTU(pathMTU uint32) {
+	if !e.cfg.PMTUDisc || pathMTU <= e.cfg.overhead() {
+		return
+	}
+	mtu := pathMTU - e.cfg.overhead()
+	if mtu < header.IPv4MinimumMTU {
+		mtu = header.IPv4MinimumMTU
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// Routes read the MTU of the link on every write, so lowering the MTU
+	// of the tunnel is enough for local transports to pick up the new
+	// path MTU.
+	if mtu < e.mtu {
+		e.mtu = mtu
+	}
+}
+
+// WritePackets implements stack.LinkEndpoint.WritePackets.
+func (e *Endpoint) WritePackets(pkts stack.PacketBufferList) (int, tcpip.Error) {
+	e.mu.RLock()
+	closed := e.closed
+	e.mu.RUnlock()
+	if closed {
+		return 0, &tcpip.ErrClosedForSend{}
+	}
+
+	n := 0
+	for _, pkt := range pkts.AsSlice() {
+		if err := e.writePacket(pkt); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Attach implements stack.LinkEndpoint.Attach.
+func (e *Endpoint) Attach(dispatcher stack.NetworkDispatcher) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dispatcher = dispatcher
+}
+
+// IsAttached implements stack.LinkEndpoint.IsAttached.
+func (e *Endpoint) IsAttached() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.dispatcher != nil
+}
+
+// MTU implements stack.LinkEndpoint.MTU.
+func (e *Endpoint) MTU() uint32 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.mtu
+}
+
+// SetMTU implements stack.LinkEndpoint.SetMTU.
+func (e *Endpoint) SetMTU(mtu uint32) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.mtu = mtu
+}
+
+// Capabilities implements stack.LinkEndpoint.Capabilities.
+func (*Endpoint) Capabilities() stack.LinkEndpointCapabilities {
+	return stack.CapabilitySaveRestore
+}
+
+// MaxHeaderLength implements stack.LinkEndpoint.MaxHeaderLength. Outer headers
+// are added to a new packet, so no space is reserved for them.
+func (*Endpoint) MaxHeaderLength() uint16 {
+	return 0
+}
+
+// LinkAddress implements stack.LinkEndpoint.LinkAddress.
+func (e *Endpoint) LinkAddress() tcpip.LinkAddress {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.linkAddr
+}
+
+// SetLinkAddress implements stack.LinkEndpoint.SetLinkAddress.
+func (e *Endpoint) SetLinkAddress(addr tcpip.LinkAddress) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.linkAddr = addr
+}
+
+// Wait implements stack.LinkEndpoint.Wait.
+func (*Endpoint) Wait() {}
+
+// ARPHardwareType implements stack.LinkEndpoint.ARPHardwareType.
+func (e *Endpoint) ARPHardwareType() header.ARPHardwareType {
+	switch e.cfg.Kind {
+	case KindGRE:
+		return header.ARPHardwareIPGRE
+	case KindIPIP:
+		return header.ARPHardwareTunnel
+	case KindSIT:
+		return header.ARPHardwareSIT
+	case KindIP6Tnl:
+		return header.ARPHardwareTunnel6
+	}
+	// gretap devices are wrapped in an ethernet endpoint.
+	return header.ARPHardwareNone
+}
+
+// AddHeader implements stack.LinkEndpoint.AddHeader.
+func (*Endpoint) AddHeader(*stack.PacketBuffer) {}
+
+// ParseHeader implements stack.LinkEndpoint.ParseHeader.
+func (*Endpoint) ParseHeader(*stack.PacketBuffer) bool { return true }
+
+// Close implements stack.LinkEndpoint.Close.
+func (e *Endpoint) Close() {
+	e.mu.Lock()
+	closed := e.closed
+	e.closed = true
+	action := e.onCloseAction
+	e.onCloseAction = nil
+	e.mu.Unlock()
+	if closed {
+		return
+	}
+
+	for _, number := range e.cfg.transportProtocols() {
+		if p, ok := e.stack.TransportProtocolInstance(number).(*protocol); ok {
+			p.detach(e.groupKey(), e)
+		}
+	}
+	if action != nil {
+		action()
+	}
+}
+
+// SetOnCloseAction implements stack.LinkEndpoint.SetOnCloseAction.
+func (e *Endpoint) SetOnCloseAction(action func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onCloseAction = action
+}
@@ -0,0 +1,286 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/refs"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/adapters/gonet"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/ethernet"
+	"gvisor.dev/gvisor/pkg/tcpip/link/tunnel"
+	"gvisor.dev/gvisor/pkg/tcpip/link/veth"
+	"gvisor.dev/gvisor/pkg/tcpip/network/arp"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/testutil"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+)
+
+const (
+	underlayNICID = 1
+	tunnelNICID   = 2
+	udpPort       = 4242
+)
+
+var (
+	underlayAddrs = [2]tcpip.Address{testutil.MustParse4("10.0.0.1"), testutil.MustParse4("10.0.0.2")}
+	inner4Addrs   = [2]tcpip.Address{testutil.MustParse4("192.168.0.1"), testutil.MustParse4("192.168.0.2")}
+	inner6Addrs   = [2]tcpip.Address{testutil.MustParse6("fd00::1"), testutil.MustParse6("fd00::2")}
+)
+
+func newStack() *stack.Stack {
+	return stack.New(stack.Options{
+		NetworkProtocols: []stack.NetworkProtocolFactory{ipv4.NewProtocol, ipv6.NewProtocol, arp.NewProtocol},
+		TransportProtocols: []stack.TransportProtocolFactory{
+			udp.NewProtocol,
+			tunnel.NewGREProtocol,
+			tunnel.NewIPIPProtocol,
+			tunnel.NewIPv6EncapProtocol,
+		},
+	})
+}
+
+func addAddress(t *testing.T, s *stack.Stack, nicID tcpip.NICID, addr tcpip.Address, prefixLen int) {
+	t.Helper()
+	proto := ipv4.ProtocolNumber
+	if addr.Len() == header.IPv6AddressSize {
+		proto = ipv6.ProtocolNumber
+	}
+	addrWithPrefix := tcpip.AddressWithPrefix{Address: addr, PrefixLen: prefixLen}
+	protocolAddr := tcpip.ProtocolAddress{Protocol: proto, AddressWithPrefix: addrWithPrefix}
+	if err := s.AddProtocolAddress(nicID, protocolAddr, stack.AddressProperties{}); err != nil {
+		t.Fatalf("s.AddProtocolAddress(%d, %+v, {}): %s", nicID, protocolAddr, err)
+	}
+	s.AddRoute(tcpip.Route{Destination: addrWithPrefix.Subnet(), NIC: nicID})
+}
+
+// setup creates two stacks connected by a veth pair, with a tunnel of the
+// given kind between them. cfgs[i] is the tunnel configuration of stack i,
+// without addresses.
+func setup(t *testing.T, kind tunnel.Kind, cfgs [2]tunnel.Config) [2]*stack.Stack {
+	t.Helper()
+	ep1, ep2 := veth.NewPair(1500, veth.DefaultBacklogSize)
+	vethEPs := [2]stack.LinkEndpoint{ep1, ep2}
+	var stacks [2]*stack.Stack
+	for i := range stacks {
+		s := newStack()
+		t.Cleanup(func() {
+			s.Close()
+			s.Wait()
+		})
+		if err := s.CreateNIC(underlayNICID, ethernet.New(vethEPs[i])); err != nil {
+			t.Fatalf("s.CreateNIC(%d, _): %s", underlayNICID, err)
+		}
+		addAddress(t, s, underlayNICID, underlayAddrs[i], 24)
+
+		cfg := cfgs[i]
+		cfg.Kind = kind
+		cfg.Local = underlayAddrs[i]
+		cfg.Remote = underlayAddrs[1-i]
+		tun, err := tunnel.New(s, cfg)
+		if err != nil {
+			t.Fatalf("tunnel.New(_, %+v): %s", cfg, err)
+		}
+		var linkEP stack.LinkEndpoint = tun
+		if kind == tunnel.KindGRETap {
+			linkEP = ethernet.New(tun)
+		}
+		if err := s.CreateNIC(tunnelNICID, linkEP); err != nil {
+			t.Fatalf("s.CreateNIC(%d, _): %s", tunnelNICID, err)
+		}
+		addAddress(t, s, tunnelNICID, inner4Addrs[i], 24)
+		addAddress(t, s, tunnelNICID, inner6Addrs[i], 64)
+		stacks[i] = s
+	}
+	return stacks
+}
+
+// exchange sends a UDP datagram from stacks[0] to stacks[1] through the tunnel
+// and reports whether it was received.
+func exchange(t *testing.T, stacks [2]*stack.Stack, addrs [2]tcpip.Address) bool {
+	t.Helper()
+	netProto := ipv4.ProtocolNumber
+	if addrs[0].Len() == header.IPv6AddressSize {
+		netProto = ipv6.ProtocolNumber
+	}
+	server, err := gonet.DialUDP(stacks[1], &tcpip.FullAddress{Addr: addrs[1], Port: udpPort}, nil, netProto)
+	if err != nil {
+		t.Fatalf("gonet.DialUDP(_, %s:%d, nil, %d): %s", addrs[1], udpPort, netProto, err)
+	}
+	defer server.Close()
+	client, err := gonet.DialUDP(stacks[0], nil, &tcpip.FullAddress{Addr: addrs[1], Port: udpPort}, netProto)
+	if err != nil {
+		t.Fatalf("gonet.DialUDP(_, nil, %s:%d, %d): %s", addrs[1], udpPort, netProto, err)
+	}
+	defer client.Close()
+
+	want := []byte("hello through the tunnel")
+	if _, err := client.Write(want); err != nil {
+		t.Fatalf("client.Write(_): %s", err)
+	}
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 100)
+	n, _, err := server.ReadFrom(buf)
+	if err != nil {
+		return false
+	}
+	if got := buf[:n]; !bytes.Equal(got, want) {
+		t.Fatalf("got server.ReadFrom(_) = %q, want = %q", got, want)
+	}
+	return true
+}
+
+func TestTunnels(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  tunnel.Kind
+		cfgs  [2]tunnel.Config
+		addrs [2]tcpip.Address
+	}{
+		{
+			name:  "gre ipv4",
+			kind:  tunnel.KindGRE,
+			addrs: inner4Addrs,
+		},
+		{
+			name:  "gre ipv6",
+			kind:  tunnel.KindGRE,
+			addrs: inner6Addrs,
+		},
+		{
+			name: "gre key checksum sequence",
+			kind: tunnel.KindGRE,
+			cfgs: [2]tunnel.Config{
+				{
+					OFlags: header.GREFlagKey | header.GREFlagChecksum | header.GREFlagSequence,
+					OKey:   42,
+				},
+				{
+					IFlags: header.GREFlagKey | header.GREFlagChecksum,
+					IKey:   42,
+				},
+			},
+			addrs: inner4Addrs,
+		},
+		{
+			name:  "gretap",
+			kind:  tunnel.KindGRETap,
+			addrs: inner4Addrs,
+		},
+		{
+			name:  "ipip",
+			kind:  tunnel.KindIPIP,
+			addrs: inner4Addrs,
+		},
+		{
+			name:  "sit",
+			kind:  tunnel.KindSIT,
+			addrs: inner6Addrs,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stacks := setup(t, test.kind, test.cfgs)
+			if !exchange(t, stacks, test.addrs) {
+				t.Fatalf("datagram wasn't received through the tunnel")
+			}
+		})
+	}
+}
+
+func TestGREKeyMismatch(t *testing.T) {
+	stacks := setup(t, tunnel.KindGRE, [2]tunnel.Config{
+		{
+			OFlags: header.GREFlagKey,
+			OKey:   1,
+		},
+		{
+			IFlags: header.GREFlagKey,
+			IKey:   2,
+		},
+	})
+	if exchange(t, stacks, inner4Addrs) {
+		t.Fatalf("datagram was received through a tunnel with a different key")
+	}
+}
+
+func TestDuplicateTunnel(t *testing.T) {
+	s := newStack()
+	defer func() {
+		s.Close()
+		s.Wait()
+	}()
+	cfg := tunnel.Config{
+		Kind:   tunnel.KindGRE,
+		Local:  underlayAddrs[0],
+		Remote: underlayAddrs[1],
+	}
+	tun, err := tunnel.New(s, cfg)
+	if err != nil {
+		t.Fatalf("tunnel.New(_, %+v): %s", cfg, err)
+	}
+	if _, err := tunnel.New(s, cfg); err == nil {
+		t.Fatalf("tunnel.New(_, %+v) succeeded for a duplicate tunnel", cfg)
+	}
+	tun.Close()
+	tun, err = tunnel.New(s, cfg)
+	if err != nil {
+		t.Fatalf("tunnel.New(_, %+v) after closing the duplicate: %s", cfg, err)
+	}
+	tun.Close()
+}
+
+func TestMTU(t *testing.T) {
+	s := newStack()
+	defer func() {
+		s.Close()
+		s.Wait()
+	}()
+	tests := []struct {
+		cfg  tunnel.Config
+		want uint32
+	}{
+		{cfg: tunnel.Config{Kind: tunnel.KindGRE}, want: 1476},
+		{cfg: tunnel.Config{Kind: tunnel.KindGRE, OFlags: header.GREFlagKey}, want: 1472},
+		{cfg: tunnel.Config{Kind: tunnel.KindGRETap}, want: 1462},
+		{cfg: tunnel.Config{Kind: tunnel.KindIPIP}, want: 1480},
+		{cfg: tunnel.Config{Kind: tunnel.KindIP6Tnl}, want: 1460},
+	}
+	for _, test := range tests {
+		tun, err := tunnel.New(s, test.cfg)
+		if err != nil {
+			t.Fatalf("tunnel.New(_, %+v): %s", test.cfg, err)
+		}
+		if got := tun.MTU(); got != test.want {
+			t.Errorf("got tunnel.New(_, %+v).MTU() = %d, want = %d", test.cfg, got, test.want)
+		}
+		tun.Close()
+	}
+}
+
+func TestMain(m *testing.M) {
+	refs.SetLeakMode(refs.LeaksPanic)
+	code := m.Run()
+	refs.DoLeakCheck()
+	os.Exit(code)
+}
@@ -222,6 +222,10 @@ type SocketOptions struct {
 	// the incoming packet should be returned as an ancillary message.
 	receiveOriginalDstAddress atomicbitops.Uint32
 
+	// ipTransparentEnabled is used to specify if the socket may bind to
+	// non-local addresses and receive packets diverted by the TPROXY target.
+	ipTransparentEnabled atomicbitops.Uint32
+
 	// ipv4RecvErrEnabled determines whether extended reliable error message
 	// passing is enabled for IPv4.
 	ipv4RecvErrEnabled atomicbitops.Uint32
@@ -488,6 +492,16 @@ func (so *SocketOptions) SetReceiveOriginalDstAddress(v bool) {
 	storeAtomicBool(&so.receiveOriginalDstAddress, v)
 }
 
+// GetIPTransparent gets value for IP(V6)_TRANSPARENT option.
+func (so *SocketOptions) GetIPTransparent() bool {
+	return so.ipTransparentEnabled.Load() != 0
+}
+
+// SetIPTransparent sets value for IP(V6)_TRANSPARENT option.
+func (so *SocketOptions) SetIPTransparent(v bool) {
+	storeAtomicBool(&so.ipTransparentEnabled, v)
+}
+
 // GetIPv4RecvError gets value for IP_RECVERR option.
 func (so *SocketOptions) GetIPv4RecvError() bool {
 	return so.ipv4RecvErrEnabled.Load() != 0
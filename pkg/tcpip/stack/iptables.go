@@ -215,6 +215,22 @@ func EmptyNATTable() Table {
 	}
 }
 
+// EmptyMangleTable returns a Table with no rules and the mangle table chains
+// that aren't supported mapped to HookUnset.
+func EmptyMangleTable() Table {
+	return Table{
+		Rules: []Rule{},
+		BuiltinChains: [NumHooks]int{
+			Input:   HookUnset,
+			Forward: HookUnset,
+		},
+		Underflows: [NumHooks]int{
+			Input:   HookUnset,
+			Forward: HookUnset,
+		},
+	}
+}
+
 // GetTable returns a table with the given id and IP version. It panics when an
 // invalid id is provided.
 func (it *IPTables) GetTable(id TableID, ipv6 bool) Table {
@@ -275,6 +275,81 @@ func (rt *RedirectTarget) Action(pkt *PacketBuffer, hook Hook, r *Route, address
 	return dnatAction(pkt, hook, r, rt.Port, address, true /* changePort */, true /* changeAddress */)
 }
 
+// TProxyTarget diverts packets to a local socket without modifying them, as
+// done by transparent proxies. A packet is delivered to the socket that owns
+// its connection if there is one, or else to a listening socket bound to the
+// target's address and port; either socket must have IP_TRANSPARENT set.
+// Packets that match no such socket are dropped.
+//
+// Unlike Linux, no policy routing is needed to deliver diverted packets
+// locally, so the packet mark set by the target is not applied.
+//
+// +stateify savable
+type TProxyTarget struct {
+	// Addr is the address of the listening socket. If unspecified, the
+	// primary address of the incoming interface is used. It is immutable.
+	Addr tcpip.Address
+
+	// Port is the port of the listening socket. If zero, the packet's
+	// destination port is used. It is immutable.
+	Port uint16
+
+	// MarkMask and MarkValue describe the packet mark set by the target. They
+	// are only kept so the rule can be read back. They are immutable.
+	MarkMask  uint32
+	MarkValue uint32
+
+	// NetworkProtocol is the network protocol the target is used with. It
+	// is immutable.
+	NetworkProtocol tcpip.NetworkProtocolNumber
+}
+
+// Action implements Target.Action.
+func (tt *TProxyTarget) Action(pkt *PacketBuffer, hook Hook, _ *Route, addressEP AddressableEndpoint) (RuleVerdict, int) {
+	// Sanity check.
+	if tt.NetworkProtocol != pkt.NetworkProtocolNumber {
+		panic(fmt.Sprintf(
+			"TProxyTarget.Action with NetworkProtocol %d called on packet with NetworkProtocolNumber %d",
+			tt.NetworkProtocol, pkt.NetworkProtocolNumber))
+	}
+	if hook != Prerouting {
+		panic(fmt.Sprintf("%s not supported for TPROXY", hook))
+	}
+
+	var dstPort uint16
+	switch pkt.TransportProtocolNumber {
+	case header.TCPProtocolNumber:
+		tcpHeader := header.TCP(pkt.TransportHeader().Slice())
+		if len(tcpHeader) < header.TCPMinimumSize {
+			return RuleDrop, 0
+		}
+		dstPort = tcpHeader.DestinationPort()
+	case header.UDPProtocolNumber:
+		udpHeader := header.UDP(pkt.TransportHeader().Slice())
+		if len(udpHeader) < header.UDPMinimumSize {
+			return RuleDrop, 0
+		}
+		dstPort = udpHeader.DestinationPort()
+	default:
+		return RuleDrop, 0
+	}
+
+	address := tt.Addr
+	if address.BitLen() == 0 {
+		// addressEP is expected to be set for the prerouting hook.
+		address = addressEP.MainAddress().Address
+	}
+	port := tt.Port
+	if port == 0 {
+		port = dstPort
+	}
+
+	pkt.NetworkPacketInfo.TransparentProxy = true
+	pkt.NetworkPacketInfo.TransparentProxyAddr = address
+	pkt.NetworkPacketInfo.TransparentProxyPort = port
+	return RuleAccept, 0
+}
+
 // SNATTarget modifies the source port/IP in the outgoing packets.
 //
 // +stateify savable
@@ -64,6 +64,14 @@ type NetworkPacketInfo struct {
 
 	// IsForwardedPacket is true if the packet is being forwarded.
 	IsForwardedPacket bool
+
+	// TransparentProxy is true if the packet was diverted by the TPROXY
+	// target. Such packets are delivered locally regardless of their
+	// destination address, to a socket bound to TransparentProxyAddr and
+	// TransparentProxyPort unless they belong to an existing connection.
+	TransparentProxy     bool
+	TransparentProxyAddr tcpip.Address
+	TransparentProxyPort uint16
 }
 
 // TransportErrorKind enumerates error types that are handled by the transport
@@ -58,6 +58,21 @@ type RestoredEndpoint interface {
 	Restore(*Stack)
 }
 
+// RestoreResetEndpoint describes a connected endpoint that was reset while
+// the stack was being restored, because its connection couldn't be restored
+// on the stack's NICs, e.g. because its local address isn't assigned to any
+// NIC or there is no longer a route to its peer.
+type RestoreResetEndpoint struct {
+	// TransProto is the endpoint's transport protocol.
+	TransProto tcpip.TransportProtocolNumber
+
+	// ID identifies the endpoint's connection.
+	ID TransportEndpointID
+
+	// Err is the error that prevented the connection from being restored.
+	Err tcpip.Error
+}
+
 // ResumableEndpoint is an endpoint that needs to be resumed after save.
 type ResumableEndpoint interface {
 	// Resume resumes an endpoint.
@@ -122,6 +137,10 @@ type Stack struct {
 	// stack is being restored.
 	restoredEndpoints []RestoredEndpoint
 
+	// restoreResets are the endpoints that were reset by the last call to
+	// Restore.
+	restoreResets []RestoreResetEndpoint `state:"nosave"`
+
 	// resumableEndpoints is a list of endpoints that need to be resumed
 	// after save.
 	resumableEndpoints []ResumableEndpoint
@@ -1615,6 +1634,34 @@ func (s *Stack) FindRoute(id tcpip.NICID, localAddr, remoteAddr tcpip.Address, n
 	return nil, &tcpip.ErrNetworkUnreachable{}
 }
 
+// FindTransparentRoute is like FindRoute, but localAddr need not be assigned
+// to a NIC. It is used by sockets with IP_TRANSPARENT set, which may send
+// packets from foreign addresses.
+func (s *Stack) FindTransparentRoute(id tcpip.NICID, localAddr, remoteAddr tcpip.Address, netProto tcpip.NetworkProtocolNumber, multicastLoop bool) (*Route, tcpip.Error) {
+	if r, err := s.FindRoute(id, localAddr, remoteAddr, netProto, multicastLoop); err == nil || localAddr.BitLen() == 0 {
+		return r, err
+	}
+
+	// Find the route that would be used with an assigned address, and send
+	// from a temporary address endpoint on its outgoing NIC instead.
+	r, err := s.FindRoute(id, tcpip.Address{}, remoteAddr, netProto, multicastLoop)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Release()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	addressEndpoint := r.outgoingNIC.getAddressOrCreateTempInner(netProto, localAddr, true /* createTemp */, NeverPrimaryEndpoint)
+	if addressEndpoint == nil {
+		return nil, &tcpip.ErrBadLocalAddress{}
+	}
+	if tr := constructAndValidateRoute(netProto, addressEndpoint, r.outgoingNIC, r.outgoingNIC, r.NextHop(), localAddr, remoteAddr, s.handleLocal, multicastLoop, r.mtu); tr != nil {
+		return tr, nil
+	}
+	return nil, &tcpip.ErrBadLocalAddress{}
+}
+
 // CheckNetworkProtocol checks if a given network protocol is enabled in the
 // stack.
 func (s *Stack) CheckNetworkProtocol(protocol tcpip.NetworkProtocolNumber) bool {
@@ -1855,6 +1902,25 @@ func (s *Stack) RegisterRestoredEndpoint(e RestoredEndpoint) {
 	s.restoredEndpoints = append(s.restoredEndpoints, e)
 }
 
+// RecordRestoreReset records that a restored endpoint was reset because its
+// connection couldn't be restored. It must only be called by
+// RestoredEndpoint.Restore.
+func (s *Stack) RecordRestoreReset(r RestoreResetEndpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.restoreResets = append(s.restoreResets, r)
+}
+
+// RestoreResets returns the endpoints that were reset by the last call to
+// Restore, since their connections couldn't be restored.
+func (s *Stack) RestoreResets() []RestoreResetEndpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.restoreResets
+}
+
 // RegisterResumableEndpoint records e as an endpoint that has to be resumed.
 func (s *Stack) RegisterResumableEndpoint(e ResumableEndpoint) {
 	s.mu.Lock()
@@ -2004,6 +2070,7 @@ func (s *Stack) Restore() {
 	s.mu.Lock()
 	eps := s.restoredEndpoints
 	s.restoredEndpoints = nil
+	s.restoreResets = nil
 	saveRestoreEnabled := s.saveRestoreEnabled
 	s.mu.Unlock()
 	for _, e := range eps {
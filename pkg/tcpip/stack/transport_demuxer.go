@@ -179,6 +179,12 @@ func (epsByNIC *endpointsByNIC) handlePacket(id TransportEndpointID, pkt *Packet
 	}
 	// multiPortEndpoints are guaranteed to have at least one element.
 	transEP := mpep.selectEndpoint(id, epsByNIC.seed)
+	if pkt.NetworkPacketInfo.TransparentProxy && !isTransparent(transEP) {
+		// Packets diverted by the TPROXY target may only be delivered to
+		// sockets with IP_TRANSPARENT set; others are dropped.
+		epsByNIC.mu.RUnlock()
+		return true
+	}
 	if queuedProtocol, mustQueue := mpep.demux.queuedProtocols[protocolIDs{mpep.netProto, mpep.transProto}]; mustQueue {
 		queuedProtocol.QueuePacket(transEP, id, pkt)
 		epsByNIC.mu.RUnlock()
@@ -591,6 +597,10 @@ func (d *transportDemuxer) deliverPacket(protocol tcpip.TransportProtocolNumber,
 		return true
 	}
 
+	if pkt.NetworkPacketInfo.TransparentProxy {
+		return d.deliverTransparentPacket(eps, pkt, id)
+	}
+
 	eps.mu.RLock()
 	ep := eps.findEndpointLocked(id)
 	eps.mu.RUnlock()
@@ -603,6 +613,35 @@ func (d *transportDemuxer) deliverPacket(protocol tcpip.TransportProtocolNumber,
 	return ep.handlePacket(id, pkt)
 }
 
+// deliverTransparentPacket delivers a packet diverted by the TPROXY target to
+// the endpoint connected to its source and destination if there is one, or
+// else to the endpoint bound to the address and port chosen by the target.
+// The endpoint still sees the packet's original destination. As in Linux,
+// diverted packets that match no endpoint are dropped.
+func (d *transportDemuxer) deliverTransparentPacket(eps *transportEndpoints, pkt *PacketBuffer, id TransportEndpointID) bool {
+	eps.mu.RLock()
+	ep, ok := eps.endpoints[id]
+	if !ok {
+		ep = eps.findEndpointLocked(TransportEndpointID{
+			LocalPort:     pkt.NetworkPacketInfo.TransparentProxyPort,
+			LocalAddress:  pkt.NetworkPacketInfo.TransparentProxyAddr,
+			RemotePort:    id.RemotePort,
+			RemoteAddress: id.RemoteAddress,
+		})
+	}
+	eps.mu.RUnlock()
+	if ep != nil {
+		ep.handlePacket(id, pkt)
+	}
+	return true
+}
+
+// isTransparent returns true if ep has IP_TRANSPARENT set.
+func isTransparent(ep TransportEndpoint) bool {
+	sep, ok := ep.(interface{ SocketOptions() *tcpip.SocketOptions })
+	return ok && sep.SocketOptions().GetIPTransparent()
+}
+
 // deliverRawPacket attempts to deliver the given packet and returns whether it
 // was delivered successfully.
 func (d *transportDemuxer) deliverRawPacket(protocol tcpip.TransportProtocolNumber, pkt *PacketBuffer) bool {
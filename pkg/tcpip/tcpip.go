@@ -1394,6 +1394,106 @@ func (*TCPSynRetriesOption) isGettableTransportProtocolOption() {}
 
 func (*TCPSynRetriesOption) isSettableTransportProtocolOption() {}
 
+// SCTPRTOInfoOption is used by SetSockOpt/GetSockOpt to set/get the
+// retransmission timeout parameters of an SCTP association, or the defaults
+// for new associations if AssocID is zero. Zero durations are left unchanged
+// by SetSockOpt.
+type SCTPRTOInfoOption struct {
+	AssocID int32
+	Initial time.Duration
+	Max     time.Duration
+	Min     time.Duration
+}
+
+func (*SCTPRTOInfoOption) isGettableSocketOption() {}
+
+func (*SCTPRTOInfoOption) isSettableSocketOption() {}
+
+// SCTPInitMsgOption is used by SetSockOpt/GetSockOpt to set/get the
+// parameters used when initiating SCTP associations. Zero values are left
+// unchanged by SetSockOpt.
+type SCTPInitMsgOption struct {
+	// NumOStreams is the number of outbound streams requested.
+	NumOStreams uint16
+
+	// MaxInStreams is the maximum number of inbound streams accepted.
+	MaxInStreams uint16
+
+	// MaxAttempts is the maximum number of INIT retransmissions.
+	MaxAttempts uint16
+
+	// MaxInitTimeout is the maximum retransmission timeout of INIT chunks.
+	MaxInitTimeout time.Duration
+}
+
+func (*SCTPInitMsgOption) isGettableSocketOption() {}
+
+func (*SCTPInitMsgOption) isSettableSocketOption() {}
+
+// SCTPDefaultSendInfoOption is used by SetSockOpt/GetSockOpt to set/get the
+// parameters of messages sent on an SCTP association, or the defaults for
+// new associations if AssocID is zero.
+type SCTPDefaultSendInfoOption struct {
+	AssocID int32
+
+	// Stream is the outbound stream messages are sent on.
+	Stream uint16
+
+	// Unordered is true if messages are delivered unordered.
+	Unordered bool
+
+	// PPID is the payload protocol identifier of messages, in network byte
+	// order.
+	PPID uint32
+
+	// Context is an opaque value returned with send failures.
+	Context uint32
+}
+
+func (*SCTPDefaultSendInfoOption) isGettableSocketOption() {}
+
+func (*SCTPDefaultSendInfoOption) isSettableSocketOption() {}
+
+// SCTPPathInfo describes a transport address of an SCTP peer.
+type SCTPPathInfo struct {
+	Addr   FullAddress
+	Active bool
+	CWnd   uint32
+	SRTT   time.Duration
+	RTO    time.Duration
+	MTU    uint32
+}
+
+// SCTPStatusOption is used by GetSockOpt to expose the status of an SCTP
+// association. AssocID may be zero for one-to-one style sockets.
+type SCTPStatusOption struct {
+	AssocID int32
+
+	// State is the association state.
+	State EndpointState
+
+	// RWnd is the peer's current receive window.
+	RWnd uint32
+
+	// UnackedData is the number of DATA chunks awaiting acknowledgement.
+	UnackedData uint16
+
+	// PendingData is the number of DATA chunks waiting to be sent.
+	PendingData uint16
+
+	// InStreams and OutStreams are the negotiated stream counts.
+	InStreams  uint16
+	OutStreams uint16
+
+	// FragmentationPoint is the largest user data size of a DATA chunk.
+	FragmentationPoint uint32
+
+	// Primary describes the primary path.
+	Primary SCTPPathInfo
+}
+
+func (*SCTPStatusOption) isGettableSocketOption() {}
+
 // MulticastInterfaceOption is used by SetSockOpt/GetSockOpt to specify a
 // default interface for multicast.
 type MulticastInterfaceOption struct {
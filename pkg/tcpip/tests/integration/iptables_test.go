@@ -16,9 +16,11 @@ package iptables_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"gvisor.dev/gvisor/pkg/buffer"
@@ -3728,3 +3730,228 @@ func genUDP6(offset int8) *stack.PacketBuffer {
 	buf := buffer.MakeWithData(append([]byte{}, hdr.View()...))
 	return stack.NewPacketBuffer(stack.PacketBufferOptions{Payload: buf})
 }
+
+func setupTProxy(t *testing.T, s *stack.Stack, netProto tcpip.NetworkProtocolNumber, transProto tcpip.TransportProtocolNumber, target stack.Target) {
+	t.Helper()
+
+	ipv6 := netProto == ipv6.ProtocolNumber
+	filter := stack.EmptyFilter4()
+	if ipv6 {
+		filter = stack.EmptyFilter6()
+	}
+	tproxyFilter := filter
+	tproxyFilter.Protocol = transProto
+	tproxyFilter.CheckProtocol = true
+	table := stack.Table{
+		Rules: []stack.Rule{
+			{Filter: tproxyFilter, Target: target},
+			{Filter: filter, Target: &stack.AcceptTarget{NetworkProtocol: netProto}},
+			{Filter: filter, Target: &stack.AcceptTarget{NetworkProtocol: netProto}},
+			{Filter: filter, Target: &stack.AcceptTarget{NetworkProtocol: netProto}},
+			{Filter: filter, Target: &stack.ErrorTarget{NetworkProtocol: netProto}},
+		},
+		BuiltinChains: [stack.NumHooks]int{
+			stack.Prerouting:  0,
+			stack.Input:       stack.HookUnset,
+			stack.Forward:     stack.HookUnset,
+			stack.Output:      2,
+			stack.Postrouting: 3,
+		},
+		Underflows: [stack.NumHooks]int{
+			stack.Prerouting:  1,
+			stack.Input:       stack.HookUnset,
+			stack.Forward:     stack.HookUnset,
+			stack.Output:      2,
+			stack.Postrouting: 3,
+		},
+	}
+	s.IPTables().ForceReplaceTable(stack.MangleID, table, ipv6)
+}
+
+func TestTProxyUDP(t *testing.T) {
+	const (
+		srcPort      = 1000
+		origDstPort  = 53
+		listenPort   = 8080
+		nonLocalAddr = "\xc0\x00\x02\x01"
+	)
+	nonLocalAddrV4 := tcpip.AddrFromSlice([]byte(nonLocalAddr))
+	nonLocalAddrV6 := tcpip.AddrFromSlice([]byte("\x20\x01\x0d\xb8\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01"))
+
+	tests := []struct {
+		name        string
+		netProto    tcpip.NetworkProtocolNumber
+		genStack    func(*testing.T) (*stack.Stack, *channel.Endpoint)
+		genPacket   func(srcAddr, dstAddr tcpip.Address, srcPort, dstPort uint16, dataSize int) []byte
+		srcAddr     tcpip.Address
+		listenAddr  tcpip.Address
+		origDstAddr tcpip.Address
+		transparent bool
+		wantRecv    bool
+	}{
+		{
+			name:        "IPv4 transparent",
+			netProto:    ipv4.ProtocolNumber,
+			genStack:    genStackV4,
+			genPacket:   udpv4Packet,
+			srcAddr:     srcAddrV4,
+			listenAddr:  dstAddrV4,
+			origDstAddr: nonLocalAddrV4,
+			transparent: true,
+			wantRecv:    true,
+		},
+		{
+			name:        "IPv4 not transparent",
+			netProto:    ipv4.ProtocolNumber,
+			genStack:    genStackV4,
+			genPacket:   udpv4Packet,
+			srcAddr:     srcAddrV4,
+			listenAddr:  dstAddrV4,
+			origDstAddr: nonLocalAddrV4,
+			transparent: false,
+			wantRecv:    false,
+		},
+		{
+			name:        "IPv6 transparent",
+			netProto:    ipv6.ProtocolNumber,
+			genStack:    genStackV6,
+			genPacket:   udpv6Packet,
+			srcAddr:     srcAddrV6,
+			listenAddr:  dstAddrV6,
+			origDstAddr: nonLocalAddrV6,
+			transparent: true,
+			wantRecv:    true,
+		},
+		{
+			name:        "IPv6 not transparent",
+			netProto:    ipv6.ProtocolNumber,
+			genStack:    genStackV6,
+			genPacket:   udpv6Packet,
+			srcAddr:     srcAddrV6,
+			listenAddr:  dstAddrV6,
+			origDstAddr: nonLocalAddrV6,
+			transparent: false,
+			wantRecv:    false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, e := test.genStack(t)
+			defer s.Destroy()
+
+			setupTProxy(t, s, test.netProto, udp.ProtocolNumber, &stack.TProxyTarget{
+				Addr:            test.listenAddr,
+				Port:            listenPort,
+				NetworkProtocol: test.netProto,
+			})
+
+			var wq waiter.Queue
+			ep, err := s.NewEndpoint(udp.ProtocolNumber, test.netProto, &wq)
+			if err != nil {
+				t.Fatalf("s.NewEndpoint(%d, %d, _): %s", udp.ProtocolNumber, test.netProto, err)
+			}
+			defer ep.Close()
+			ep.SocketOptions().SetIPTransparent(test.transparent)
+			bindAddr := tcpip.FullAddress{Addr: test.listenAddr, Port: listenPort}
+			if err := ep.Bind(bindAddr); err != nil {
+				t.Fatalf("ep.Bind(%#v): %s", bindAddr, err)
+			}
+
+			e.InjectInbound(test.netProto, stack.NewPacketBuffer(stack.PacketBufferOptions{
+				Payload: buffer.MakeWithData(test.genPacket(test.srcAddr, test.origDstAddr, srcPort, origDstPort, payloadSize)),
+			}))
+
+			var buf bytes.Buffer
+			res, err := ep.Read(&buf, tcpip.ReadOptions{NeedRemoteAddr: true})
+			if !test.wantRecv {
+				if _, ok := err.(*tcpip.ErrWouldBlock); !ok {
+					t.Fatalf("got ep.Read(_, _) = (%#v, %s), want = (_, %s)", res, err, &tcpip.ErrWouldBlock{})
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ep.Read(_, _): %s", err)
+			}
+			if res.Count != payloadSize {
+				t.Errorf("got res.Count = %d, want = %d", res.Count, payloadSize)
+			}
+			want := tcpip.FullAddress{Addr: test.srcAddr, Port: srcPort, NIC: nicID}
+			if diff := cmp.Diff(want, res.RemoteAddr); diff != "" {
+				t.Errorf("remote address mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTProxyTCP(t *testing.T) {
+	const (
+		srcPort     = 1000
+		origDstPort = 80
+		listenPort  = 8080
+	)
+	nonLocalAddr := tcpip.AddrFromSlice([]byte("\xc0\x00\x02\x01"))
+
+	s, e := genStackV4(t)
+	defer s.Destroy()
+	s.SetRouteTable([]tcpip.Route{{Destination: header.IPv4EmptySubnet, NIC: nicID}})
+
+	// Packets to any port are diverted to the listener.
+	setupTProxy(t, s, ipv4.ProtocolNumber, tcp.ProtocolNumber, &stack.TProxyTarget{
+		Port:            listenPort,
+		NetworkProtocol: ipv4.ProtocolNumber,
+	})
+
+	var wq waiter.Queue
+	ep, err := s.NewEndpoint(tcp.ProtocolNumber, ipv4.ProtocolNumber, &wq)
+	if err != nil {
+		t.Fatalf("s.NewEndpoint(%d, %d, _): %s", tcp.ProtocolNumber, ipv4.ProtocolNumber, err)
+	}
+	defer ep.Close()
+	ep.SocketOptions().SetIPTransparent(true)
+	bindAddr := tcpip.FullAddress{Port: listenPort}
+	if err := ep.Bind(bindAddr); err != nil {
+		t.Fatalf("ep.Bind(%#v): %s", bindAddr, err)
+	}
+	if err := ep.Listen(1); err != nil {
+		t.Fatalf("ep.Listen(1): %s", err)
+	}
+
+	tcpSize := header.TCPMinimumSize
+	hdr := prependable.New(header.IPv4MinimumSize + tcpSize)
+	syn := header.TCP(hdr.Prepend(tcpSize))
+	syn.Encode(&header.TCPFields{
+		SrcPort:    srcPort,
+		DstPort:    origDstPort,
+		SeqNum:     100,
+		DataOffset: header.TCPMinimumSize,
+		Flags:      header.TCPFlagSyn,
+		WindowSize: 30000,
+	})
+	syn.SetChecksum(^syn.CalculateChecksum(header.PseudoHeaderChecksum(header.TCPProtocolNumber, srcAddrV4, nonLocalAddr, uint16(tcpSize))))
+	encodeIPv4Header(hdr.Prepend(header.IPv4MinimumSize), hdr.UsedLength(), header.TCPProtocolNumber, srcAddrV4, nonLocalAddr)
+	e.InjectInbound(ipv4.ProtocolNumber, stack.NewPacketBuffer(stack.PacketBufferOptions{
+		Payload: buffer.MakeWithData(hdr.View()),
+	}))
+
+	// The SYN-ACK must come from the original destination.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	p := e.ReadContext(ctx)
+	if p == nil {
+		t.Fatalf("got e.ReadContext(_) = nil, want SYN-ACK")
+	}
+	payload := stack.PayloadSince(p.NetworkHeader())
+	defer payload.Release()
+	p.DecRef()
+	checker.IPv4(t, payload,
+		checker.SrcAddr(nonLocalAddr),
+		checker.DstAddr(srcAddrV4),
+		checker.TCP(
+			checker.SrcPort(origDstPort),
+			checker.DstPort(srcPort),
+			checker.TCPFlags(header.TCPFlagSyn|header.TCPFlagAck),
+			checker.TCPAckNum(101),
+		),
+	)
+}
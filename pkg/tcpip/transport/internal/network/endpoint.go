@@ -624,7 +624,11 @@ func (e *Endpoint) connectRouteRLocked(nicID tcpip.NICID, localAddr tcpip.Addres
 	}
 
 	// Find a route to the desired destination.
-	r, err := e.stack.FindRoute(nicID, localAddr, addr.Addr, netProto, e.ops.GetMulticastLoop())
+	findRoute := e.stack.FindRoute
+	if e.ops.GetIPTransparent() {
+		findRoute = e.stack.FindTransparentRoute
+	}
+	r, err := findRoute(nicID, localAddr, addr.Addr, netProto, e.ops.GetMulticastLoop())
 	if err != nil {
 		return nil, 0, err
 	}
@@ -767,8 +771,10 @@ func (e *Endpoint) BindAndThen(addr tcpip.FullAddress, f func(tcpip.NetworkProto
 
 	nicID := addr.NIC
 	if addr.Addr.BitLen() != 0 && !e.isBroadcastOrMulticast(addr.NIC, netProto, addr.Addr) {
-		nicID = e.stack.CheckLocalAddress(nicID, netProto, addr.Addr)
-		if nicID == 0 {
+		if nic := e.stack.CheckLocalAddress(nicID, netProto, addr.Addr); nic != 0 {
+			nicID = nic
+		} else if !e.ops.GetIPTransparent() {
+			// Only transparent sockets may bind to foreign addresses.
 			return &tcpip.ErrBadLocalAddress{}
 		}
 	}
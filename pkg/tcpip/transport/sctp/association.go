@@ -0,0 +1,747 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sctp
+
+import (
+	"encoding/binary"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// inPacket is a parsed incoming SCTP packet.
+type inPacket struct {
+	netProto tcpip.NetworkProtocolNumber
+
+	// id holds the packet's addresses and ports, from the receiver's point
+	// of view.
+	id stack.TransportEndpointID
+
+	nicID  tcpip.NICID
+	vtag   uint32
+	chunks []header.SCTPChunk
+}
+
+// outPacket is a fully formed outgoing SCTP packet.
+type outPacket struct {
+	nicID    tcpip.NICID
+	netProto tcpip.NetworkProtocolNumber
+	local    tcpip.Address
+	remote   tcpip.Address
+	owner    tcpip.PacketOwner
+	b        []byte
+}
+
+// association is an SCTP association. All of its fields are protected by the
+// mutex of the owning endpoint, which never changes.
+//
+// +stateify savable
+type association struct {
+	ep *Endpoint
+
+	// id is the association identifier exposed to applications.
+	id int32
+
+	state EndpointState
+
+	// localTag and peerTag are the verification tags expected in incoming
+	// and sent in outgoing packets, respectively.
+	localTag uint32
+	peerTag  uint32
+
+	netProto  tcpip.NetworkProtocolNumber
+	localPort uint16
+	peerPort  uint16
+
+	// localAddr is the source address of outgoing packets. It is empty if
+	// the route decides.
+	localAddr tcpip.Address
+
+	// paths are the transport addresses of the peer. primary is the path
+	// new data is sent to.
+	paths   []*path
+	primary *path
+
+	// rtoInitial, rtoMin and rtoMax bound the retransmission timeout of
+	// all paths.
+	rtoInitial time.Duration
+	rtoMin     time.Duration
+	rtoMax     time.Duration
+
+	// errorCount counts consecutive retransmission timeouts and unanswered
+	// heartbeats across all paths.
+	errorCount int
+
+	// handshake is the INIT or COOKIE ECHO chunk retransmitted by t1
+	// during association setup.
+	handshake      []byte
+	initRetries    int
+	maxInitRetries int
+	maxInitTimeout time.Duration
+	t1             timer
+
+	// shutdownRetries counts retransmissions of SHUTDOWN or SHUTDOWN ACK
+	// chunks by t2.
+	shutdownRetries int
+	t2              timer
+
+	// numOutStreams and numInStreams are the negotiated stream counts.
+	numOutStreams uint16
+	numInStreams  uint16
+
+	// Send side state.
+	snd sender
+
+	// Receive side state.
+	rcv receiver
+
+	// err is the error that terminated the association, if any.
+	err tcpip.Error
+}
+
+// sendInfo holds the parameters of outgoing messages.
+//
+// +stateify savable
+type sendInfo struct {
+	stream    uint16
+	unordered bool
+	ppid      uint32
+	context   uint32
+}
+
+// tsnLT returns true if TSN a precedes b, using serial number arithmetic.
+func tsnLT(a, b uint32) bool {
+	return int32(a-b) < 0
+}
+
+// tsnLTE returns true if TSN a precedes or equals b.
+func tsnLTE(a, b uint32) bool {
+	return int32(a-b) <= 0
+}
+
+// newAssociation creates an association owned by e. The caller must add it
+// to the protocol with addAssociation.
+//
+// +checklocks:e.mu
+func (e *Endpoint) newAssociation(netProto tcpip.NetworkProtocolNumber, localAddr tcpip.Address, peer tcpip.FullAddress) *association {
+	a := &association{
+		ep:             e,
+		state:          StateClosed,
+		netProto:       netProto,
+		localPort:      e.ID.LocalPort,
+		peerPort:       peer.Port,
+		localAddr:      localAddr,
+		rtoInitial:     e.rtoInitial,
+		rtoMin:         e.rtoMin,
+		rtoMax:         e.rtoMax,
+		maxInitRetries: int(e.initMsg.MaxAttempts),
+		maxInitTimeout: e.initMsg.MaxInitTimeout,
+	}
+	clock := e.stack.Clock()
+	a.t1.init(clock, a.onT1)
+	a.t2.init(clock, a.onT2)
+	a.snd.t3.init(clock, a.onT3)
+	a.rcv.sackTimer.init(clock, a.onSackTimer)
+	a.primary = a.addPath(peer.Addr)
+	return a
+}
+
+// resumeTimers re-arms the association's timers after restore.
+func (a *association) resumeTimers() {
+	clock := a.ep.stack.Clock()
+	a.t1.resume(clock, a.onT1)
+	a.t2.resume(clock, a.onT2)
+	a.snd.t3.resume(clock, a.onT3)
+	a.rcv.sackTimer.resume(clock, a.onSackTimer)
+	for _, p := range a.paths {
+		p := p
+		p.hbTimer.resume(clock, func() { a.onHeartbeatTimer(p) })
+	}
+}
+
+// connect starts the four-way handshake by sending an INIT chunk.
+//
+// +checklocks:a.ep.mu
+func (a *association) connect() {
+	e := a.ep
+	a.snd.init(a, e.stack.InsecureRNG().Uint32())
+	a.numOutStreams = e.initMsg.NumOStreams
+	a.numInStreams = e.initMsg.MaxInStreams
+
+	params := a.localAddressParams()
+	c := make([]byte, header.SCTPInitSize, header.SCTPInitSize+len(params))
+	header.SCTPInit(c).Encode(header.SCTPChunkInit, &header.SCTPInitFields{
+		InitiateTag:              a.localTag,
+		AdvertisedReceiverWindow: e.receiveWindow(a),
+		OutboundStreams:          a.numOutStreams,
+		InboundStreams:           a.numInStreams,
+		InitialTSN:               a.snd.nextTSN,
+	}, len(params))
+	c = append(c, params...)
+
+	a.state = StateCookieWait
+	a.handshake = c
+	a.initRetries = 0
+	a.sendHandshake()
+}
+
+// sendHandshake (re)transmits the pending INIT or COOKIE ECHO chunk. INIT
+// chunks are sent with a zero verification tag.
+//
+// +checklocks:a.ep.mu
+func (a *association) sendHandshake() {
+	vtag := a.peerTag
+	if a.state == StateCookieWait {
+		vtag = 0
+	}
+	pb := a.newPacketBuilder(a.primary, vtag)
+	pb.add(a.handshake)
+	pb.flush()
+	a.t1.enable(min(a.primary.rto, a.maxInitTimeout))
+}
+
+// onT1 handles expiration of the INIT or COOKIE ECHO retransmission timer.
+func (a *association) onT1() {
+	e := a.ep
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+	if !a.t1.checkExpiration() || !a.state.handshaking() {
+		return
+	}
+	a.initRetries++
+	if a.initRetries > a.maxInitRetries {
+		a.terminate(&tcpip.ErrTimeout{})
+		return
+	}
+	a.primary.rto = min(2*a.primary.rto, a.rtoMax)
+	a.sendHandshake()
+}
+
+// onT2 handles expiration of the SHUTDOWN or SHUTDOWN ACK retransmission
+// timer.
+func (a *association) onT2() {
+	e := a.ep
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+	if !a.t2.checkExpiration() {
+		return
+	}
+	a.shutdownRetries++
+	a.errorCount++
+	if a.errorCount > associationMaxRetrans {
+		a.terminate(&tcpip.ErrTimeout{})
+		return
+	}
+	a.primary.rto = min(2*a.primary.rto, a.rtoMax)
+	switch a.state {
+	case StateShutdownSent:
+		a.sendShutdown()
+	case StateShutdownAckSent:
+		a.sendShutdownAck()
+	}
+}
+
+// handleInitAck processes an INIT ACK received in the COOKIE-WAIT state.
+//
+// +checklocks:a.ep.mu
+func (a *association) handleInitAck(in *inPacket, c header.SCTPInit) {
+	if a.state != StateCookieWait || len(c) < header.SCTPInitSize {
+		return
+	}
+	params, ok := c.Parameters()
+	if !ok || c.InitiateTag() == 0 || c.OutboundStreams() == 0 || c.InboundStreams() == 0 {
+		a.sendAbort(header.SCTPCauseInvalidMandatoryParameter)
+		a.terminate(&tcpip.ErrConnectionRefused{})
+		return
+	}
+	var cookie []byte
+	for _, p := range params {
+		switch p.Type() {
+		case header.SCTPParamStateCookie:
+			cookie = p.Value()
+		case header.SCTPParamIPv4Address, header.SCTPParamIPv6Address:
+			if addr, ok := parseAddressParam(p); ok && addr.BitLen() == a.primary.addr.BitLen() {
+				a.addPath(addr)
+			}
+		}
+	}
+	if cookie == nil {
+		a.sendAbort(header.SCTPCauseMissingMandatoryParameter)
+		a.terminate(&tcpip.ErrConnectionRefused{})
+		return
+	}
+
+	a.t1.disable()
+	a.peerTag = c.InitiateTag()
+	a.numOutStreams = min(a.numOutStreams, c.InboundStreams())
+	a.numInStreams = min(a.numInStreams, c.OutboundStreams())
+	a.snd.peerRwnd = c.AdvertisedReceiverWindow()
+	a.rcv.init(c.InitialTSN(), a.numInStreams)
+	a.snd.initStreams(a.numOutStreams)
+
+	ce := make([]byte, header.SCTPChunkHeaderSize+len(cookie))
+	header.EncodeSCTPChunkHeader(ce, header.SCTPChunkCookieEcho, 0, len(cookie))
+	copy(ce[header.SCTPChunkHeaderSize:], cookie)
+	a.state = StateCookieEchoed
+	a.handshake = ce
+	a.initRetries = 0
+	a.sendHandshake()
+}
+
+// handleCookieAck processes a COOKIE ACK received in the COOKIE-ECHOED
+// state.
+//
+// +checklocks:a.ep.mu
+func (a *association) handleCookieAck() {
+	if a.state != StateCookieEchoed {
+		return
+	}
+	a.t1.disable()
+	a.handshake = nil
+	a.established()
+}
+
+// established moves the association to the ESTABLISHED state.
+//
+// +checklocks:a.ep.mu
+func (a *association) established() {
+	a.state = StateEstablished
+	a.errorCount = 0
+	for _, p := range a.paths {
+		p.startHeartbeats(a)
+	}
+	a.ep.associationEstablished(a)
+}
+
+// handleCookieEchoExisting processes a COOKIE ECHO received for an existing
+// association, which happens after an INIT collision or if a COOKIE ACK was
+// lost (RFC 9260 section 5.2.4).
+//
+// +checklocks:a.ep.mu
+func (a *association) handleCookieEchoExisting(in *inPacket, c header.SCTPChunk) {
+	ck, ok := a.ep.protocol.verifyCookie(c.Value(), in.id, in.vtag)
+	if !ok || ck.localTag != a.localTag {
+		return
+	}
+	switch a.state {
+	case StateCookieWait, StateCookieEchoed:
+		// Both sides initiated at once, and the peer echoed the cookie
+		// of our INIT ACK, which carries our existing tags.
+		a.t1.disable()
+		a.handshake = nil
+		a.peerTag = ck.peerTag
+		a.numOutStreams = ck.outStreams
+		a.numInStreams = ck.inStreams
+		a.snd.peerRwnd = ck.peerRwnd
+		a.rcv.init(ck.peerTSN, a.numInStreams)
+		a.snd.initStreams(a.numOutStreams)
+		for _, addr := range ck.peerAddrs {
+			a.addPath(addr)
+		}
+		a.established()
+		a.sendCookieAck()
+	default:
+		if ck.peerTag == a.peerTag {
+			// Our COOKIE ACK got lost.
+			a.sendCookieAck()
+		}
+	}
+}
+
+// sendCookieAck sends a COOKIE ACK, bundled with any pending data.
+//
+// +checklocks:a.ep.mu
+func (a *association) sendCookieAck() {
+	c := make([]byte, header.SCTPChunkHeaderSize)
+	header.EncodeSCTPChunkHeader(c, header.SCTPChunkCookieAck, 0, 0)
+	pb := a.newPacketBuilder(a.primary, a.peerTag)
+	pb.add(c)
+	pb.flush()
+}
+
+// handlePacket processes the chunks of an incoming packet.
+//
+// +checklocks:a.ep.mu
+func (a *association) handlePacket(in *inPacket) {
+	from := a.pathFor(in.id.RemoteAddress)
+	hadData := false
+	for _, c := range in.chunks {
+		if a.state == StateClosed {
+			return
+		}
+		switch c.Type() {
+		case header.SCTPChunkData:
+			if len(c) < header.SCTPDataHeaderSize {
+				return
+			}
+			hadData = true
+			a.handleData(header.SCTPData(c))
+		case header.SCTPChunkSack:
+			if len(c) < header.SCTPSackSize {
+				return
+			}
+			a.handleSack(header.SCTPSack(c))
+		case header.SCTPChunkInitAck:
+			a.handleInitAck(in, header.SCTPInit(c))
+		case header.SCTPChunkCookieEcho:
+			a.handleCookieEchoExisting(in, c)
+		case header.SCTPChunkCookieAck:
+			a.handleCookieAck()
+		case header.SCTPChunkHeartbeat:
+			a.handleHeartbeat(in, c)
+		case header.SCTPChunkHeartbeatAck:
+			a.handleHeartbeatAck(c)
+		case header.SCTPChunkAbort:
+			a.handleAbort()
+			return
+		case header.SCTPChunkShutdown:
+			if len(c) < header.SCTPShutdownSize {
+				return
+			}
+			a.handleShutdown(binary.BigEndian.Uint32(c.Value()))
+		case header.SCTPChunkShutdownAck:
+			a.handleShutdownAck()
+		case header.SCTPChunkShutdownComplete:
+			if a.state == StateShutdownAckSent {
+				a.terminate(nil)
+			}
+			return
+		case header.SCTPChunkError:
+			a.handleError(c)
+		case header.SCTPChunkInit:
+			// INIT chunks are never bundled, and are handled by the
+			// endpoint.
+			return
+		default:
+			if c.Type().ReportUnrecognized() {
+				a.sendError(header.SCTPCauseUnrecognizedChunkType, c)
+			}
+			if !c.Type().SkipUnrecognized() {
+				return
+			}
+		}
+	}
+	if from != nil {
+		// Any packet from a peer address confirms that it's reachable.
+		from.confirmed = true
+	}
+	if hadData {
+		a.rcv.packetsSinceSack++
+		if a.rcv.sackNow || a.rcv.packetsSinceSack >= 2 || a.state != StateEstablished {
+			a.rcv.sackNeeded = true
+		} else if !a.rcv.sackTimer.enabled {
+			a.rcv.sackTimer.enable(sackDelay)
+		}
+	}
+	a.sendData()
+}
+
+// handleAbort processes an ABORT chunk.
+//
+// +checklocks:a.ep.mu
+func (a *association) handleAbort() {
+	if a.state.handshaking() {
+		a.terminate(&tcpip.ErrConnectionRefused{})
+		return
+	}
+	a.terminate(&tcpip.ErrConnectionReset{})
+}
+
+// handleError processes an ERROR chunk.
+//
+// +checklocks:a.ep.mu
+func (a *association) handleError(c header.SCTPChunk) {
+	causes, ok := header.SCTPParameters(c.Value())
+	if !ok {
+		return
+	}
+	for _, cause := range causes {
+		if header.SCTPErrorCause(cause.Type()) == header.SCTPCauseStaleCookie && a.state == StateCookieEchoed {
+			// Our cookie expired before it reached the peer; start over.
+			a.state = StateCookieWait
+			a.connectRetry()
+			return
+		}
+	}
+}
+
+// connectRetry restarts the handshake after a stale cookie.
+//
+// +checklocks:a.ep.mu
+func (a *association) connectRetry() {
+	a.initRetries++
+	if a.initRetries > a.maxInitRetries {
+		a.terminate(&tcpip.ErrTimeout{})
+		return
+	}
+	retries := a.initRetries
+	queued := a.snd.queue
+	a.connect()
+	a.initRetries = retries
+	a.snd.queue = queued
+}
+
+// handleShutdown processes a SHUTDOWN chunk.
+//
+// +checklocks:a.ep.mu
+func (a *association) handleShutdown(cumTSN uint32) {
+	switch a.state {
+	case StateEstablished, StateShutdownPending:
+		a.state = StateShutdownReceived
+		a.ep.peerShutdown(a)
+		a.snd.handleCumAck(a, cumTSN)
+		a.maybeShutdown()
+	case StateShutdownReceived:
+		a.snd.handleCumAck(a, cumTSN)
+		a.maybeShutdown()
+	case StateShutdownSent:
+		// Both sides are shutting down.
+		a.ep.peerShutdown(a)
+		a.t2.disable()
+		a.sendShutdownAck()
+	}
+}
+
+// handleShutdownAck processes a SHUTDOWN ACK chunk.
+//
+// +checklocks:a.ep.mu
+func (a *association) handleShutdownAck() {
+	switch a.state {
+	case StateShutdownSent, StateShutdownAckSent:
+		c := make([]byte, header.SCTPChunkHeaderSize)
+		header.EncodeSCTPChunkHeader(c, header.SCTPChunkShutdownComplete, 0, 0)
+		pb := a.newPacketBuilder(a.primary, a.peerTag)
+		pb.add(c)
+		pb.flush()
+		a.terminate(nil)
+	}
+}
+
+// shutdown starts a graceful shutdown of the association.
+//
+// +checklocks:a.ep.mu
+func (a *association) shutdown() {
+	switch a.state {
+	case StateCookieWait, StateCookieEchoed:
+		a.abort()
+	case StateEstablished:
+		a.state = StateShutdownPending
+		a.maybeShutdown()
+	}
+}
+
+// maybeShutdown sends a SHUTDOWN or SHUTDOWN ACK once all outstanding data
+// has been acknowledged.
+//
+// +checklocks:a.ep.mu
+func (a *association) maybeShutdown() {
+	if !a.snd.idle() {
+		return
+	}
+	switch a.state {
+	case StateShutdownPending:
+		a.state = StateShutdownSent
+		a.shutdownRetries = 0
+		a.sendShutdown()
+	case StateShutdownReceived:
+		a.state = StateShutdownAckSent
+		a.shutdownRetries = 0
+		a.sendShutdownAck()
+	}
+}
+
+// sendShutdown sends a SHUTDOWN chunk and arms t2.
+//
+// +checklocks:a.ep.mu
+func (a *association) sendShutdown() {
+	c := make([]byte, header.SCTPShutdownSize)
+	header.EncodeSCTPChunkHeader(c, header.SCTPChunkShutdown, 0, 4)
+	binary.BigEndian.PutUint32(c[header.SCTPChunkHeaderSize:], a.rcv.cumTSN)
+	pb := a.newPacketBuilder(a.primary, a.peerTag)
+	pb.add(c)
+	pb.flush()
+	a.rcv.sackSent()
+	a.t2.enable(a.primary.rto)
+}
+
+// sendShutdownAck sends a SHUTDOWN ACK chunk and arms t2.
+//
+// +checklocks:a.ep.mu
+func (a *association) sendShutdownAck() {
+	a.state = StateShutdownAckSent
+	c := make([]byte, header.SCTPChunkHeaderSize)
+	header.EncodeSCTPChunkHeader(c, header.SCTPChunkShutdownAck, 0, 0)
+	pb := a.newPacketBuilder(a.primary, a.peerTag)
+	pb.add(c)
+	pb.flush()
+	a.t2.enable(a.primary.rto)
+}
+
+// abort sends an ABORT chunk to the peer and terminates the association.
+//
+// +checklocks:a.ep.mu
+func (a *association) abort() {
+	a.sendAbort(header.SCTPCauseUserInitiatedAbort)
+	a.terminate(&tcpip.ErrConnectionAborted{})
+}
+
+// sendAbort sends an ABORT chunk with the given cause.
+//
+// +checklocks:a.ep.mu
+func (a *association) sendAbort(cause header.SCTPErrorCause) {
+	if a.state == StateClosed {
+		return
+	}
+	vtag, flags := a.peerTag, uint8(0)
+	if a.state == StateCookieWait {
+		// The peer hasn't told us its tag yet.
+		vtag, flags = a.localTag, header.SCTPFlagTagReflected
+	}
+	c := make([]byte, header.SCTPChunkHeaderSize+header.SCTPParameterHeaderSize)
+	header.EncodeSCTPChunkHeader(c, header.SCTPChunkAbort, flags, header.SCTPParameterHeaderSize)
+	binary.BigEndian.PutUint16(c[header.SCTPChunkHeaderSize:], uint16(cause))
+	binary.BigEndian.PutUint16(c[header.SCTPChunkHeaderSize+2:], header.SCTPParameterHeaderSize)
+	pb := a.newPacketBuilder(a.primary, vtag)
+	pb.add(c)
+	pb.flush()
+}
+
+// sendError sends an ERROR chunk with a single cause carrying info.
+//
+// +checklocks:a.ep.mu
+func (a *association) sendError(cause header.SCTPErrorCause, info []byte) {
+	causeLen := header.SCTPParameterHeaderSize + len(info)
+	c := make([]byte, header.SCTPChunkHeaderSize+header.SCTPPadded(causeLen))
+	header.EncodeSCTPChunkHeader(c, header.SCTPChunkError, 0, causeLen)
+	binary.BigEndian.PutUint16(c[header.SCTPChunkHeaderSize:], uint16(cause))
+	binary.BigEndian.PutUint16(c[header.SCTPChunkHeaderSize+2:], uint16(causeLen))
+	copy(c[header.SCTPChunkHeaderSize+header.SCTPParameterHeaderSize:], info)
+	pb := a.newPacketBuilder(a.primary, a.peerTag)
+	if !pb.add(c) {
+		return
+	}
+	pb.flush()
+}
+
+// terminate closes the association. err is nil after a graceful shutdown.
+//
+// +checklocks:a.ep.mu
+func (a *association) terminate(err tcpip.Error) {
+	if a.state == StateClosed {
+		return
+	}
+	a.state = StateClosed
+	a.err = err
+	a.t1.disable()
+	a.t2.disable()
+	a.snd.t3.disable()
+	a.rcv.sackTimer.disable()
+	for _, p := range a.paths {
+		p.hbTimer.disable()
+	}
+	a.ep.protocol.removeAssociation(a)
+	a.ep.associationClosed(a)
+}
+
+// localAddressParams returns the address parameters listing the local
+// addresses of the association, for INIT chunks. The source address of the
+// packet is implicitly included, so only endpoints bound to the wildcard
+// address that aren't talking over loopback list their other addresses.
+//
+// +checklocks:a.ep.mu
+func (a *association) localAddressParams() []byte {
+	if a.ep.BindAddr != (tcpip.Address{}) || isLoopback(a.primary.addr) {
+		return nil
+	}
+	return a.ep.localAddressParams(a.netProto)
+}
+
+// isLoopback returns true if addr is a loopback address.
+func isLoopback(addr tcpip.Address) bool {
+	switch addr.BitLen() {
+	case header.IPv4AddressSizeBits:
+		return header.IPv4LoopbackSubnet.Contains(addr)
+	case header.IPv6AddressSizeBits:
+		return addr == header.IPv6Loopback
+	}
+	return false
+}
+
+// isUnicast returns true if addr can be the address of an SCTP endpoint:
+// it isn't unspecified, broadcast or multicast.
+func isUnicast(addr tcpip.Address) bool {
+	switch addr.BitLen() {
+	case header.IPv4AddressSizeBits:
+		return !addr.Unspecified() && addr != header.IPv4Broadcast && !header.IsV4MulticastAddress(addr)
+	case header.IPv6AddressSizeBits:
+		return !addr.Unspecified() && !header.IsV6MulticastAddress(addr)
+	}
+	return false
+}
+
+// appendAddressParam appends an IPv4 or IPv6 address parameter to b.
+func appendAddressParam(b []byte, addr tcpip.Address) []byte {
+	typ := header.SCTPParamIPv4Address
+	if addr.BitLen() == header.IPv6AddressSizeBits {
+		typ = header.SCTPParamIPv6Address
+	}
+	return header.AppendSCTPParameter(b, typ, addr.AsSlice())
+}
+
+// parseAddressParam parses an IPv4 or IPv6 address parameter. Addresses
+// that can't be those of the peer are rejected.
+func parseAddressParam(p header.SCTPParameter) (tcpip.Address, bool) {
+	v := p.Value()
+	var addr tcpip.Address
+	switch {
+	case p.Type() == header.SCTPParamIPv4Address && len(v) == header.IPv4AddressSize:
+		addr = tcpip.AddrFrom4Slice(v)
+	case p.Type() == header.SCTPParamIPv6Address && len(v) == header.IPv6AddressSize:
+		addr = tcpip.AddrFrom16Slice(v)
+		if header.IsV4MappedAddress(addr) {
+			return tcpip.Address{}, false
+		}
+	default:
+		return tcpip.Address{}, false
+	}
+	return addr, isUnicast(addr)
+}
+
+// status returns the association's status for SCTP_STATUS.
+//
+// +checklocks:a.ep.mu
+func (a *association) status() tcpip.SCTPStatusOption {
+	return tcpip.SCTPStatusOption{
+		AssocID:            a.id,
+		State:              tcpip.EndpointState(a.state),
+		RWnd:               a.snd.peerRwnd,
+		UnackedData:        uint16(min(len(a.snd.inflight), 0xffff)),
+		PendingData:        uint16(min(len(a.snd.queue), 0xffff)),
+		InStreams:          a.numInStreams,
+		OutStreams:         a.numOutStreams,
+		FragmentationPoint: uint32(a.maxDataPayload()),
+		Primary:            a.primary.info(a),
+	}
+}
+
+// notifyWritable wakes up writers once send buffer space is freed.
+//
+// +checklocks:a.ep.mu
+func (a *association) notifyWritable() {
+	a.ep.notify(waiter.WritableEvents)
+}
@@ -0,0 +1,199 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sctp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+const (
+	// cookieFixedSize is the size of the fixed part of an encoded cookie.
+	cookieFixedSize = 8 + 4*5 + 2*4 + 1
+
+	// cookieMACSize is the size of the cookie signature.
+	cookieMACSize = sha256.Size
+
+	// maxCookieAddrs bounds the number of peer addresses kept in a cookie.
+	maxCookieAddrs = 16
+)
+
+// cookie holds the state of an association that is being set up by a
+// listening endpoint. It is sent to the peer in the INIT ACK and echoed back
+// in the COOKIE ECHO, so that no state is kept until the peer proves that it
+// can receive packets at its address (RFC 9260 section 5.1.3).
+type cookie struct {
+	// created is the time the cookie was made, in nanoseconds of the stack's
+	// monotonic clock.
+	created int64
+
+	localTag   uint32
+	peerTag    uint32
+	localTSN   uint32
+	peerTSN    uint32
+	peerRwnd   uint32
+	outStreams uint16
+	inStreams  uint16
+	localPort  uint16
+	peerPort   uint16
+
+	// localAddr and peerAddr are the addresses of the INIT the cookie
+	// answers. Along with the ports and localTag, they bind the cookie to
+	// the packet that may echo it.
+	localAddr tcpip.Address
+	peerAddr  tcpip.Address
+
+	// peerAddrs holds the addresses listed by the peer in its INIT, of the
+	// same family as its source address.
+	peerAddrs []tcpip.Address
+}
+
+// makeCookie returns the signed encoding of ck.
+func (p *protocol) makeCookie(ck *cookie) []byte {
+	addrLen := ck.peerAddr.Len()
+	b := make([]byte, cookieFixedSize, cookieFixedSize+(2+len(ck.peerAddrs))*addrLen+cookieMACSize)
+	binary.BigEndian.PutUint64(b[0:], uint64(ck.created))
+	binary.BigEndian.PutUint32(b[8:], ck.localTag)
+	binary.BigEndian.PutUint32(b[12:], ck.peerTag)
+	binary.BigEndian.PutUint32(b[16:], ck.localTSN)
+	binary.BigEndian.PutUint32(b[20:], ck.peerTSN)
+	binary.BigEndian.PutUint32(b[24:], ck.peerRwnd)
+	binary.BigEndian.PutUint16(b[28:], ck.outStreams)
+	binary.BigEndian.PutUint16(b[30:], ck.inStreams)
+	binary.BigEndian.PutUint16(b[32:], ck.localPort)
+	binary.BigEndian.PutUint16(b[34:], ck.peerPort)
+	b[36] = uint8(addrLen)
+	b = append(b, ck.localAddr.AsSlice()...)
+	b = append(b, ck.peerAddr.AsSlice()...)
+	for _, addr := range ck.peerAddrs {
+		b = append(b, addr.AsSlice()...)
+	}
+	return p.sign(b)
+}
+
+// sign appends the signature of b to b.
+func (p *protocol) sign(b []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret[:])
+	mac.Write(b)
+	return mac.Sum(b)
+}
+
+// openCookie checks the signature of an echoed cookie and decodes it.
+func (p *protocol) openCookie(b []byte) (*cookie, bool) {
+	if len(b) < cookieFixedSize+cookieMACSize {
+		return nil, false
+	}
+	data := b[:len(b)-cookieMACSize]
+	if !hmac.Equal(p.sign(append([]byte(nil), data...))[len(data):], b[len(data):]) {
+		return nil, false
+	}
+	ck := &cookie{
+		created:    int64(binary.BigEndian.Uint64(data[0:])),
+		localTag:   binary.BigEndian.Uint32(data[8:]),
+		peerTag:    binary.BigEndian.Uint32(data[12:]),
+		localTSN:   binary.BigEndian.Uint32(data[16:]),
+		peerTSN:    binary.BigEndian.Uint32(data[20:]),
+		peerRwnd:   binary.BigEndian.Uint32(data[24:]),
+		outStreams: binary.BigEndian.Uint16(data[28:]),
+		inStreams:  binary.BigEndian.Uint16(data[30:]),
+		localPort:  binary.BigEndian.Uint16(data[32:]),
+		peerPort:   binary.BigEndian.Uint16(data[34:]),
+	}
+	addrLen := int(data[36])
+	addrs := data[cookieFixedSize:]
+	if addrLen == 0 || len(addrs) < 2*addrLen || len(addrs)%addrLen != 0 {
+		return nil, false
+	}
+	ck.localAddr = tcpip.AddrFromSlice(addrs[:addrLen])
+	ck.peerAddr = tcpip.AddrFromSlice(addrs[addrLen : 2*addrLen])
+	for addrs = addrs[2*addrLen:]; len(addrs) > 0; addrs = addrs[addrLen:] {
+		ck.peerAddrs = append(ck.peerAddrs, tcpip.AddrFromSlice(addrs[:addrLen]))
+	}
+	return ck, true
+}
+
+// matches returns whether ck was issued in answer to an INIT of the
+// association that a packet with id and verification tag vtag belongs to. A
+// cookie copied from another association or echoed from another address
+// doesn't match.
+func (ck *cookie) matches(id stack.TransportEndpointID, vtag uint32) bool {
+	return ck.localAddr == id.LocalAddress && ck.peerAddr == id.RemoteAddress &&
+		ck.localPort == id.LocalPort && ck.peerPort == id.RemotePort &&
+		ck.localTag == vtag
+}
+
+// cookieAge returns how long ago ck was made.
+func (p *protocol) cookieAge(ck *cookie) time.Duration {
+	return time.Duration(p.now() - ck.created)
+}
+
+// verifyCookie checks the signature and lifetime of a cookie echoed by a
+// packet with id and verification tag vtag, and that it was issued for that
+// packet's association.
+func (p *protocol) verifyCookie(b []byte, id stack.TransportEndpointID, vtag uint32) (*cookie, bool) {
+	ck, ok := p.openCookie(b)
+	if !ok || !ck.matches(id, vtag) {
+		return nil, false
+	}
+	if age := p.cookieAge(ck); age < 0 || age > validCookieLife {
+		return nil, false
+	}
+	return ck, true
+}
+
+// staleCookie checks whether b is a correctly signed cookie, echoed by a
+// packet with id and verification tag vtag that it was issued for, whose
+// lifetime expired. It returns the peer's tag and by how long the lifetime
+// was exceeded.
+func (p *protocol) staleCookie(b []byte, id stack.TransportEndpointID, vtag uint32) (uint32, time.Duration, bool) {
+	ck, ok := p.openCookie(b)
+	if !ok || !ck.matches(id, vtag) {
+		return 0, 0, false
+	}
+	staleness := p.cookieAge(ck) - validCookieLife
+	if staleness <= 0 {
+		return 0, 0, false
+	}
+	return ck.peerTag, staleness, true
+}
+
+// now returns the current time in nanoseconds of the stack's monotonic
+// clock.
+func (p *protocol) now() int64 {
+	return int64(p.stack.Clock().NowMonotonic().Sub(tcpip.MonotonicTime{}))
+}
+
+// peerAddresses returns the addresses listed in the parameters of an INIT
+// chunk that have the same family as src, excluding src itself.
+func peerAddresses(params []header.SCTPParameter, src tcpip.Address) []tcpip.Address {
+	var addrs []tcpip.Address
+	for _, prm := range params {
+		addr, ok := parseAddressParam(prm)
+		if !ok || addr.BitLen() != src.BitLen() || addr == src {
+			continue
+		}
+		if len(addrs) == maxCookieAddrs {
+			break
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
@@ -0,0 +1,857 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sctp
+
+import (
+	"sort"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+)
+
+const (
+	// maxGapAckBlocks and maxDupTSNs bound the size of SACK chunks.
+	maxGapAckBlocks = 64
+	maxDupTSNs      = 16
+
+	// fastRetransmitThreshold is the number of miss indications that
+	// trigger a fast retransmission (RFC 9260 section 7.2.4).
+	fastRetransmitThreshold = 3
+)
+
+// dataChunk is an outgoing DATA chunk.
+//
+// +stateify savable
+type dataChunk struct {
+	// tsn is assigned when the chunk is first transmitted.
+	tsn     uint32
+	stream  uint16
+	ssn     uint16
+	ppid    uint32
+	flags   uint8
+	payload []byte
+
+	// sentAt is the time of the last transmission, and path the path it
+	// was sent on.
+	sentAt tcpip.MonotonicTime
+	path   *path
+
+	transmissions   int
+	gapAcked        bool
+	missIndications int
+	needRetransmit  bool
+}
+
+// sender holds the send side state of an association.
+//
+// +stateify savable
+type sender struct {
+	// nextTSN is the TSN of the next new DATA chunk.
+	nextTSN uint32
+
+	// cumAck is the peer's cumulative TSN ack point.
+	cumAck uint32
+
+	// queue holds chunks that were never sent, and inflight the chunks
+	// sent but not yet cumulatively acknowledged, in TSN order.
+	queue    []*dataChunk
+	inflight []*dataChunk
+
+	// ssn holds the next stream sequence number of each outbound stream.
+	ssn []uint16
+
+	// peerRwnd is the peer's receive window, minus the data in flight.
+	peerRwnd uint32
+
+	// Congestion control state, from RFC 9260 section 7.2. A single
+	// congestion window is shared by all paths, since data is only sent to
+	// the primary path and retransmissions are rare.
+	cwnd              uint32
+	ssthresh          uint32
+	partialBytesAcked uint32
+
+	// inFastRecovery is true until the TSN recoveryPoint is acknowledged.
+	inFastRecovery bool
+	recoveryPoint  uint32
+
+	// t3 is the retransmission timer.
+	t3 timer
+}
+
+// init initializes the sender with initial TSN tsn.
+//
+// +checklocks:a.ep.mu
+func (s *sender) init(a *association, tsn uint32) {
+	s.nextTSN = tsn
+	s.cumAck = tsn - 1
+	s.inflight = nil
+	mtu := uint32(a.primary.mtu)
+	s.cwnd = min(4*mtu, max(2*mtu, 4380))
+	s.ssthresh = ^uint32(0)
+}
+
+// initStreams sets the number of outbound streams.
+func (s *sender) initStreams(n uint16) {
+	s.ssn = make([]uint16, n)
+}
+
+// idle returns true if all data has been sent and acknowledged.
+func (s *sender) idle() bool {
+	return len(s.queue) == 0 && len(s.inflight) == 0
+}
+
+// flightSize returns the amount of outstanding data.
+func (s *sender) flightSize() uint32 {
+	var n uint32
+	for _, c := range s.inflight {
+		if !c.gapAcked && !c.needRetransmit {
+			n += uint32(len(c.payload))
+		}
+	}
+	return n
+}
+
+// queuedBytes returns the amount of data that was never sent.
+func (s *sender) queuedBytes() int {
+	n := 0
+	for _, c := range s.queue {
+		n += len(c.payload)
+	}
+	return n
+}
+
+// recentlySent returns true if data was sent on p within its RTO.
+//
+// +checklocks:a.ep.mu
+func (s *sender) recentlySent(p *path) bool {
+	for _, c := range s.inflight {
+		if c.path == p {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue splits msg into DATA chunks and queues them for transmission.
+// Stream sequence numbers are assigned at first transmission, since the
+// number of outbound streams isn't known before the association is
+// established.
+//
+// +checklocks:a.ep.mu
+func (a *association) enqueue(msg []byte, info sendInfo) {
+	flags := uint8(0)
+	if info.unordered {
+		flags |= header.SCTPDataFlagUnordered
+	}
+	maxPayload := a.maxDataPayload()
+	for off := 0; off < len(msg); off += maxPayload {
+		end := min(off+maxPayload, len(msg))
+		f := flags
+		if off == 0 {
+			f |= header.SCTPDataFlagBeginning
+		}
+		if end == len(msg) {
+			f |= header.SCTPDataFlagEnd
+		}
+		a.snd.queue = append(a.snd.queue, &dataChunk{
+			stream:  info.stream,
+			ppid:    info.ppid,
+			flags:   f,
+			payload: msg[off:end],
+		})
+	}
+}
+
+// ordered returns true if c is part of an ordered message.
+func (c *dataChunk) ordered() bool {
+	return c.flags&header.SCTPDataFlagUnordered == 0
+}
+
+// encode returns the wire encoding of c.
+func (c *dataChunk) encode() []byte {
+	b := make([]byte, header.SCTPDataHeaderSize+len(c.payload))
+	header.SCTPData(b).Encode(&header.SCTPDataFields{
+		Flags:                     c.flags,
+		TSN:                       c.tsn,
+		StreamIdentifier:          c.stream,
+		StreamSequenceNumber:      c.ssn,
+		PayloadProtocolIdentifier: c.ppid,
+	}, len(c.payload))
+	copy(b[header.SCTPDataHeaderSize:], c.payload)
+	return b
+}
+
+// sendData transmits retransmissions, new data and pending SACKs, within the
+// limits of the congestion and receive windows.
+//
+// +checklocks:a.ep.mu
+func (a *association) sendData() {
+	if !a.state.canSend() {
+		if a.rcv.sackNeeded {
+			a.sendSack()
+		}
+		return
+	}
+	burst := 0
+
+	// Retransmissions go first, preferably to an alternate path.
+	for burst < maxBurst {
+		var pb *packetBuilder
+		for _, c := range a.snd.inflight {
+			if !c.needRetransmit {
+				continue
+			}
+			if pb == nil {
+				pb = a.newPacketBuilder(a.alternatePath(c.path), a.peerTag)
+				a.addSack(pb)
+			}
+			if !pb.add(c.encode()) {
+				break
+			}
+			a.transmitted(c, pb.path)
+		}
+		if pb == nil {
+			break
+		}
+		pb.flush()
+		burst++
+	}
+
+	// Then new data.
+	for burst < maxBurst && len(a.snd.queue) > 0 {
+		if !a.canSendNew(a.snd.queue[0]) {
+			break
+		}
+		if a.ep.ops.GetDelayOption() && len(a.snd.inflight) > 0 && a.snd.queuedBytes() < a.maxDataPayload() {
+			// Nagle's algorithm: wait for outstanding data to be
+			// acknowledged before sending a small packet.
+			break
+		}
+		pb := a.newPacketBuilder(a.primary, a.peerTag)
+		a.addSack(pb)
+		a.addNewData(pb)
+		pb.flush()
+		burst++
+	}
+
+	if a.rcv.sackNeeded {
+		a.sendSack()
+	}
+	if len(a.snd.inflight) > 0 && !a.snd.t3.enabled {
+		a.snd.t3.enable(a.primary.rto)
+	}
+}
+
+// canSendNew returns true if the congestion and receive windows allow
+// sending c.
+//
+// +checklocks:a.ep.mu
+func (a *association) canSendNew(c *dataChunk) bool {
+	flight := a.snd.flightSize()
+	if flight == 0 {
+		// A single packet may always be sent, which also probes a
+		// closed receive window (RFC 9260 section 6.1).
+		return true
+	}
+	l := uint32(len(c.payload))
+	return flight+l <= a.snd.cwnd && l <= a.snd.peerRwnd
+}
+
+// addNewData bundles as many queued chunks into pb as fit and the windows
+// allow.
+//
+// +checklocks:a.ep.mu
+func (a *association) addNewData(pb *packetBuilder) {
+	for len(a.snd.queue) > 0 {
+		c := a.snd.queue[0]
+		if int(c.stream) >= len(a.snd.ssn) {
+			// The stream was negotiated away during setup.
+			a.snd.queue = a.snd.queue[1:]
+			a.ep.sndBufUsed -= len(c.payload)
+			continue
+		}
+		if !pb.empty() && !a.canSendNew(c) {
+			return
+		}
+		c.tsn = a.snd.nextTSN
+		if c.ordered() {
+			c.ssn = a.snd.ssn[c.stream]
+		}
+		if !pb.add(c.encode()) {
+			return
+		}
+		if c.ordered() && c.flags&header.SCTPDataFlagEnd != 0 {
+			// The fragments of a message are queued consecutively, so
+			// the last one completes the message's sequence number.
+			a.snd.ssn[c.stream]++
+		}
+		a.snd.nextTSN++
+		a.snd.queue = a.snd.queue[1:]
+		a.snd.inflight = append(a.snd.inflight, c)
+		a.transmitted(c, pb.path)
+	}
+}
+
+// transmitted records the (re)transmission of c on p.
+//
+// +checklocks:a.ep.mu
+func (a *association) transmitted(c *dataChunk, p *path) {
+	c.transmissions++
+	c.needRetransmit = false
+	c.missIndications = 0
+	c.path = p
+	c.sentAt = a.ep.stack.Clock().NowMonotonic()
+	l := uint32(len(c.payload))
+	if a.snd.peerRwnd > l {
+		a.snd.peerRwnd -= l
+	} else {
+		a.snd.peerRwnd = 0
+	}
+}
+
+// handleSack processes a SACK chunk (RFC 9260 section 6.2.1).
+//
+// +checklocks:a.ep.mu
+func (a *association) handleSack(c header.SCTPSack) {
+	if !a.state.canSend() && a.state != StateShutdownSent && a.state != StateShutdownAckSent {
+		return
+	}
+	cum := c.CumulativeTSNAck()
+	if tsnLT(cum, a.snd.cumAck) || !tsnLT(cum, a.snd.nextTSN) {
+		// Stale or bogus.
+		return
+	}
+	gaps, ok := c.GapAckBlocks()
+	if !ok {
+		return
+	}
+
+	acked, advanced := a.snd.handleCumAck(a, cum)
+
+	// Process gap acks and count miss indications for the chunks below
+	// the highest TSN newly reported as received.
+	var highest uint32
+	haveHighest := false
+	for _, d := range a.snd.inflight {
+		off := d.tsn - cum
+		inGap := false
+		for _, g := range gaps {
+			if off >= uint32(g.Start) && off <= uint32(g.End) {
+				inGap = true
+				break
+			}
+		}
+		if inGap {
+			if !d.gapAcked {
+				d.gapAcked = true
+				d.needRetransmit = false
+				acked += uint32(len(d.payload))
+				d.path.onSuccess(a)
+			}
+			highest, haveHighest = d.tsn, true
+		} else {
+			// The peer may renege on previously gap acked chunks.
+			d.gapAcked = false
+		}
+	}
+	fastRetransmit := false
+	if haveHighest {
+		for _, d := range a.snd.inflight {
+			if !tsnLT(d.tsn, highest) {
+				break
+			}
+			if d.gapAcked || d.needRetransmit {
+				continue
+			}
+			d.missIndications++
+			if d.missIndications == fastRetransmitThreshold {
+				d.needRetransmit = true
+				fastRetransmit = true
+			}
+		}
+	}
+
+	// Update the congestion window (RFC 9260 section 7.2).
+	s := &a.snd
+	mtu := a.primary.mtu
+	if fastRetransmit && !s.inFastRecovery {
+		s.ssthresh = max(s.cwnd/2, 4*mtu)
+		s.cwnd = s.ssthresh
+		s.partialBytesAcked = 0
+		s.inFastRecovery = true
+		s.recoveryPoint = s.nextTSN - 1
+	} else if advanced && !s.inFastRecovery {
+		if s.cwnd <= s.ssthresh {
+			s.cwnd += min(acked, mtu)
+		} else {
+			s.partialBytesAcked += acked
+			if s.partialBytesAcked >= s.cwnd {
+				s.partialBytesAcked -= s.cwnd
+				s.cwnd += mtu
+			}
+		}
+	}
+
+	flight := s.flightSize()
+	if rwnd := c.AdvertisedReceiverWindow(); rwnd > flight {
+		s.peerRwnd = rwnd - flight
+	} else {
+		s.peerRwnd = 0
+	}
+
+	if len(s.inflight) == 0 {
+		s.t3.disable()
+	} else if advanced {
+		s.t3.enable(a.primary.rto)
+	}
+	if acked > 0 {
+		a.notifyWritable()
+	}
+	a.maybeShutdown()
+}
+
+// handleCumAck removes the chunks up to and including cum from the
+// retransmission queue. It returns the number of newly acknowledged bytes,
+// and whether the cumulative ack point advanced.
+//
+// +checklocks:a.ep.mu
+func (s *sender) handleCumAck(a *association, cum uint32) (uint32, bool) {
+	if !tsnLT(s.cumAck, cum) {
+		return 0, false
+	}
+	var acked uint32
+	rttSampled := false
+	now := a.ep.stack.Clock().NowMonotonic()
+	n := 0
+	for _, c := range s.inflight {
+		if !tsnLTE(c.tsn, cum) {
+			break
+		}
+		if !c.gapAcked {
+			acked += uint32(len(c.payload))
+		}
+		if !rttSampled && c.transmissions == 1 {
+			// Karn's algorithm: only sample chunks that were sent once.
+			c.path.updateRTT(a, now.Sub(c.sentAt))
+			rttSampled = true
+		}
+		c.path.onSuccess(a)
+		a.ep.sndBufUsed -= len(c.payload)
+		n++
+	}
+	s.inflight = s.inflight[n:]
+	s.cumAck = cum
+	if s.inFastRecovery && !tsnLT(cum, s.recoveryPoint) {
+		s.inFastRecovery = false
+	}
+	return acked, true
+}
+
+// onT3 handles expiration of the retransmission timer (RFC 9260 section
+// 6.3.3).
+func (a *association) onT3() {
+	e := a.ep
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+	if !a.snd.t3.checkExpiration() || len(a.snd.inflight) == 0 || a.state == StateClosed {
+		return
+	}
+	p := a.snd.inflight[0].path
+	p.onError(a)
+	if a.state == StateClosed {
+		return
+	}
+	p.rto = min(2*p.rto, a.rtoMax)
+
+	s := &a.snd
+	mtu := a.primary.mtu
+	s.ssthresh = max(s.cwnd/2, 4*mtu)
+	s.cwnd = mtu
+	s.partialBytesAcked = 0
+	s.inFastRecovery = false
+	for _, c := range s.inflight {
+		if !c.gapAcked {
+			c.needRetransmit = true
+		}
+	}
+	a.sendData()
+	if len(s.inflight) > 0 {
+		s.t3.enable(a.alternatePath(p).rto)
+	}
+}
+
+// inChunk is a received DATA chunk awaiting reassembly.
+//
+// +stateify savable
+type inChunk struct {
+	stream  uint16
+	ssn     uint16
+	ppid    uint32
+	flags   uint8
+	payload []byte
+}
+
+// inStream holds the ordered delivery state of an inbound stream.
+//
+// +stateify savable
+type inStream struct {
+	// nextSSN is the stream sequence number of the next message to
+	// deliver.
+	nextSSN uint16
+
+	// pending holds complete messages received out of order.
+	pending map[uint16]*message
+}
+
+// receiver holds the receive side state of an association.
+//
+// +stateify savable
+type receiver struct {
+	// cumTSN is the highest TSN up to which all DATA was received.
+	cumTSN uint32
+
+	// received holds the TSNs above cumTSN that were received.
+	received map[uint32]struct{}
+
+	// reasm holds received chunks that don't form a complete message yet.
+	reasm map[uint32]*inChunk
+
+	streams []inStream
+
+	// held is the amount of data in reasm and in the pending messages of
+	// streams, which counts against the receive window.
+	held int
+
+	// dups holds duplicate TSNs to report in the next SACK.
+	dups []uint32
+
+	// packetsSinceSack counts packets with DATA received since the last
+	// SACK. sackNeeded is true if a SACK must be sent right away, and
+	// sackNow if it must not be delayed.
+	packetsSinceSack int
+	sackNeeded       bool
+	sackNow          bool
+
+	// lastRwnd is the receive window advertised in the last SACK.
+	lastRwnd uint32
+
+	sackTimer timer
+}
+
+// init initializes the receiver for a peer with initial TSN tsn and n inbound
+// streams.
+func (r *receiver) init(tsn uint32, n uint16) {
+	r.cumTSN = tsn - 1
+	r.received = make(map[uint32]struct{})
+	r.reasm = make(map[uint32]*inChunk)
+	r.streams = make([]inStream, n)
+}
+
+// sackSent resets the delayed acknowledgement state.
+func (r *receiver) sackSent() {
+	r.packetsSinceSack = 0
+	r.sackNeeded = false
+	r.sackNow = false
+	r.dups = nil
+	r.sackTimer.disable()
+}
+
+// handleData processes a DATA chunk (RFC 9260 section 6.2).
+//
+// +checklocks:a.ep.mu
+func (a *association) handleData(c header.SCTPData) {
+	r := &a.rcv
+	if !a.state.canReceive() || r.received == nil {
+		return
+	}
+	if header.SCTPChunk(c).Flags()&header.SCTPDataFlagImmediate != 0 {
+		r.sackNow = true
+	}
+	tsn := c.TSN()
+	if _, ok := r.received[tsn]; ok || tsnLTE(tsn, r.cumTSN) {
+		if len(r.dups) < maxDupTSNs {
+			r.dups = append(r.dups, tsn)
+		}
+		r.sackNow = true
+		return
+	}
+	payload := c.Payload()
+	if len(payload) == 0 {
+		a.sendAbort(header.SCTPCauseNoUserData)
+		a.terminate(&tcpip.ErrConnectionReset{})
+		return
+	}
+	if int(a.ep.receiveWindow(a)) < len(payload) && tsn != r.cumTSN+1 {
+		// No room; the peer will retransmit.
+		r.sackNow = true
+		return
+	}
+	if c.StreamIdentifier() >= uint16(len(r.streams)) {
+		var info [4]byte
+		info[0], info[1] = byte(c.StreamIdentifier()>>8), byte(c.StreamIdentifier())
+		a.sendError(header.SCTPCauseInvalidStreamIdentifier, info[:])
+		r.markReceived(tsn)
+		return
+	}
+
+	r.markReceived(tsn)
+	if tsn != r.cumTSN {
+		// There is a gap, let the peer know right away.
+		r.sackNow = true
+	}
+	data := make([]byte, len(payload))
+	copy(data, payload)
+	r.reasm[tsn] = &inChunk{
+		stream:  c.StreamIdentifier(),
+		ssn:     c.StreamSequenceNumber(),
+		ppid:    c.PayloadProtocolIdentifier(),
+		flags:   header.SCTPChunk(c).Flags(),
+		payload: data,
+	}
+	r.held += len(data)
+	a.reassemble(tsn)
+}
+
+// markReceived records the reception of tsn.
+func (r *receiver) markReceived(tsn uint32) {
+	r.received[tsn] = struct{}{}
+	for {
+		if _, ok := r.received[r.cumTSN+1]; !ok {
+			return
+		}
+		r.cumTSN++
+		delete(r.received, r.cumTSN)
+	}
+}
+
+// reassemble delivers the message containing the chunk with TSN tsn, if all
+// of its fragments were received.
+//
+// +checklocks:a.ep.mu
+func (a *association) reassemble(tsn uint32) {
+	r := &a.rcv
+	c := r.reasm[tsn]
+	const kind = header.SCTPDataFlagUnordered
+	sameMessage := func(o *inChunk) bool {
+		if o == nil || o.stream != c.stream || o.flags&kind != c.flags&kind {
+			return false
+		}
+		return o.flags&kind != 0 || o.ssn == c.ssn
+	}
+
+	first := tsn
+	for r.reasm[first].flags&header.SCTPDataFlagBeginning == 0 {
+		if !sameMessage(r.reasm[first-1]) {
+			return
+		}
+		first--
+	}
+	last := tsn
+	for r.reasm[last].flags&header.SCTPDataFlagEnd == 0 {
+		if !sameMessage(r.reasm[last+1]) {
+			return
+		}
+		last++
+	}
+
+	var data []byte
+	if first == last {
+		data = r.reasm[first].payload
+	} else {
+		for t := first; ; t++ {
+			data = append(data, r.reasm[t].payload...)
+			if t == last {
+				break
+			}
+		}
+	}
+	for t := first; ; t++ {
+		delete(r.reasm, t)
+		if t == last {
+			break
+		}
+	}
+
+	m := &message{
+		assoc:     a,
+		from:      tcpip.FullAddress{Addr: a.primary.addr, Port: a.peerPort},
+		stream:    c.stream,
+		ssn:       c.ssn,
+		ppid:      c.ppid,
+		unordered: c.flags&kind != 0,
+		data:      data,
+	}
+	if m.unordered {
+		r.held -= len(data)
+		a.ep.deliver(m)
+		return
+	}
+	s := &r.streams[c.stream]
+	if m.ssn != s.nextSSN {
+		if s.pending == nil {
+			s.pending = make(map[uint16]*message)
+		}
+		s.pending[m.ssn] = m
+		return
+	}
+	for m != nil {
+		r.held -= len(m.data)
+		a.ep.deliver(m)
+		s.nextSSN++
+		m = s.pending[s.nextSSN]
+		delete(s.pending, s.nextSSN)
+	}
+}
+
+// addSack bundles a SACK into pb if one is pending.
+//
+// +checklocks:a.ep.mu
+func (a *association) addSack(pb *packetBuilder) {
+	if !a.rcv.sackNeeded && a.rcv.packetsSinceSack == 0 {
+		return
+	}
+	if pb.add(a.makeSack()) {
+		a.rcv.sackSent()
+	}
+}
+
+// sendSack sends a SACK on its own.
+//
+// +checklocks:a.ep.mu
+func (a *association) sendSack() {
+	if a.rcv.received == nil {
+		return
+	}
+	pb := a.newPacketBuilder(a.primary, a.peerTag)
+	a.addSack(pb)
+	pb.flush()
+}
+
+// makeSack returns a SACK chunk describing the received data.
+//
+// +checklocks:a.ep.mu
+func (a *association) makeSack() []byte {
+	r := &a.rcv
+	tsns := make([]uint32, 0, len(r.received))
+	for t := range r.received {
+		tsns = append(tsns, t-r.cumTSN)
+	}
+	sort.Slice(tsns, func(i, j int) bool { return tsns[i] < tsns[j] })
+	var gaps []header.SCTPGapAckBlock
+	for _, off := range tsns {
+		if off > 0xffff {
+			break
+		}
+		if n := len(gaps); n > 0 && uint32(gaps[n-1].End)+1 == off {
+			gaps[n-1].End = uint16(off)
+			continue
+		}
+		if len(gaps) == maxGapAckBlocks {
+			break
+		}
+		gaps = append(gaps, header.SCTPGapAckBlock{Start: uint16(off), End: uint16(off)})
+	}
+	rwnd := a.ep.receiveWindow(a)
+	r.lastRwnd = rwnd
+	b := make([]byte, header.SCTPSackSizeWith(len(gaps), len(r.dups)))
+	header.SCTPSack(b).Encode(r.cumTSN, rwnd, gaps, r.dups)
+	return b
+}
+
+// onSackTimer sends a delayed SACK.
+func (a *association) onSackTimer() {
+	e := a.ep
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+	if !a.rcv.sackTimer.checkExpiration() || a.state == StateClosed {
+		return
+	}
+	a.rcv.sackNeeded = true
+	a.sendSack()
+}
+
+// windowOpened sends a window update once the application has consumed
+// enough data to reopen a window that was advertised as small (RFC 9260
+// section 6.2).
+//
+// +checklocks:a.ep.mu
+func (a *association) windowOpened() {
+	if a.state == StateClosed || a.rcv.received == nil {
+		return
+	}
+	rwnd := a.ep.receiveWindow(a)
+	if rwnd >= a.rcv.lastRwnd+a.primary.mtu && a.rcv.lastRwnd < uint32(a.ep.ops.GetReceiveBufferSize()/2) {
+		a.rcv.sackNeeded = true
+		a.sendSack()
+	}
+}
+
+// packetBuilder bundles chunks into a packet for a path.
+type packetBuilder struct {
+	a    *association
+	path *path
+	vtag uint32
+	b    []byte
+}
+
+// newPacketBuilder returns a packetBuilder for packets sent on p with
+// verification tag vtag.
+//
+// +checklocks:a.ep.mu
+func (a *association) newPacketBuilder(p *path, vtag uint32) *packetBuilder {
+	b := make([]byte, header.SCTPMinimumSize, p.mtu)
+	header.SCTP(b).EncodeCommonHeader(a.localPort, a.peerPort, vtag)
+	return &packetBuilder{a: a, path: p, vtag: vtag, b: b}
+}
+
+// empty returns true if no chunk was added to the packet.
+func (pb *packetBuilder) empty() bool {
+	return len(pb.b) == header.SCTPMinimumSize
+}
+
+// add adds chunk c to the packet, padded to a multiple of 4 bytes. It returns
+// false if c doesn't fit, unless the packet is empty, since chunks that
+// don't fit in an empty packet would never be sent otherwise.
+func (pb *packetBuilder) add(c []byte) bool {
+	padded := header.SCTPPadded(len(c))
+	if !pb.empty() && len(pb.b)+padded > int(pb.path.mtu) {
+		return false
+	}
+	pb.b = append(pb.b, c...)
+	for len(pb.b)%4 != 0 {
+		pb.b = append(pb.b, 0)
+	}
+	return true
+}
+
+// flush queues the packet for transmission, if it isn't empty.
+//
+// +checklocks:pb.a.ep.mu
+func (pb *packetBuilder) flush() {
+	if pb.empty() {
+		return
+	}
+	header.SCTP(pb.b).SetChecksum()
+	a := pb.a
+	a.ep.outbox = append(a.ep.outbox, outPacket{
+		nicID:    a.ep.BindNICID,
+		netProto: a.netProto,
+		local:    a.localAddr,
+		remote:   pb.path.addr,
+		owner:    a.ep.owner,
+		b:        pb.b,
+	})
+	pb.b = pb.b[:header.SCTPMinimumSize]
+}
@@ -0,0 +1,1304 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sctp
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/ports"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// message is a complete user message received on an association.
+//
+// +stateify savable
+type message struct {
+	assoc     *association
+	from      tcpip.FullAddress
+	stream    uint16
+	ssn       uint16
+	ppid      uint32
+	unordered bool
+	data      []byte
+
+	// off is the amount of data already read.
+	off int
+}
+
+// Endpoint represents an SCTP endpoint. This struct serves as the interface
+// between users of the endpoint and the protocol implementation; it is legal
+// to have concurrent goroutines make calls into the endpoint, they are
+// properly synchronized.
+//
+// It implements tcpip.Endpoint.
+//
+// +stateify savable
+type Endpoint struct {
+	tcpip.DefaultSocketOptionsHandler
+
+	// The following fields are initialized at creation time and do not
+	// change throughout the lifetime of the endpoint.
+	stack       *stack.Stack
+	protocol    *protocol
+	waiterQueue *waiter.Queue
+	ops         tcpip.SocketOptions
+	stats       tcpip.TransportEndpointStats
+
+	// oneToMany is true for one-to-many style (SOCK_SEQPACKET) endpoints.
+	// It may only be changed before the endpoint is bound.
+	oneToMany bool
+
+	// mu protects all the fields below, as well as the state of the
+	// endpoint's associations.
+	mu sync.Mutex `state:"nosave"`
+
+	// TransportEndpointInfo holds the endpoint's addresses. ID.RemotePort
+	// and ID.RemoteAddress are only set for one-to-one endpoints.
+	stack.TransportEndpointInfo
+
+	state endpointState
+
+	// Values used to reserve a port and register with the stack.
+	portFlags          ports.Flags
+	boundBindToDevice  tcpip.NICID
+	boundPortFlags     ports.Flags
+	boundNICID         tcpip.NICID
+	effectiveNetProtos []tcpip.NetworkProtocolNumber
+
+	// isPortReserved is true if the endpoint owns its port, which accepted
+	// endpoints don't.
+	isPortReserved bool
+
+	// assocs holds the endpoint's associations. One-to-one endpoints have
+	// at most one.
+	assocs []*association
+
+	// acceptQueue holds the established endpoints of a listening
+	// one-to-one endpoint, and backlog bounds its length.
+	acceptQueue []*Endpoint
+	backlog     int
+
+	// rcvQueue holds received messages, and rcvBufUsed the amount of
+	// unread data in it.
+	rcvQueue   []*message
+	rcvBufUsed int
+
+	// rcvClosed and sndClosed are set after the endpoint or its peer shut
+	// down the respective direction.
+	rcvClosed bool
+	sndClosed bool
+
+	// sndBufUsed is the amount of data queued or in flight on all
+	// associations.
+	sndBufUsed int
+
+	// hardError is the error that terminated the association of a
+	// one-to-one endpoint, reported once by Read, Write or Connect.
+	hardError tcpip.Error
+
+	// connectErr is the error that made the last association setup of a
+	// one-to-many endpoint fail, reported once by Connect.
+	connectErr tcpip.Error
+
+	// isConnectNotified is true once Connect reported success.
+	isConnectNotified bool
+
+	// outbox holds packets to write once mu is released, and events the
+	// waiter queue notifications to send.
+	outbox []outPacket      `state:"nosave"`
+	events waiter.EventMask `state:"nosave"`
+
+	lastError tcpip.Error
+
+	// Parameters of new associations.
+	rtoInitial time.Duration
+	rtoMin     time.Duration
+	rtoMax     time.Duration
+	initMsg    tcpip.SCTPInitMsgOption
+	sendInfo   sendInfo
+
+	// maxSeg is the maximum size of user data in a DATA chunk, or zero if
+	// it is only limited by the path MTU.
+	maxSeg int
+
+	owner tcpip.PacketOwner
+}
+
+func newEndpoint(p *protocol, netProto tcpip.NetworkProtocolNumber, waiterQueue *waiter.Queue) *Endpoint {
+	e := &Endpoint{
+		stack:       p.stack,
+		protocol:    p,
+		waiterQueue: waiterQueue,
+		TransportEndpointInfo: stack.TransportEndpointInfo{
+			NetProto:   netProto,
+			TransProto: ProtocolNumber,
+		},
+		rtoInitial: DefaultRTOInitial,
+		rtoMin:     DefaultRTOMin,
+		rtoMax:     DefaultRTOMax,
+		initMsg: tcpip.SCTPInitMsgOption{
+			NumOStreams:    DefaultOutboundStreams,
+			MaxInStreams:   MaxStreams,
+			MaxAttempts:    DefaultMaxInitRetransmits,
+			MaxInitTimeout: DefaultMaxInitTimeout,
+		},
+	}
+	e.ops.InitHandler(e, e.stack, tcpip.GetStackSendBufferLimits, tcpip.GetStackReceiveBufferLimits)
+	e.ops.SetSendBufferSize(DefaultSendBufferSize, false /* notify */)
+	e.ops.SetReceiveBufferSize(DefaultReceiveBufferSize, false /* notify */)
+	return e
+}
+
+// SetOneToMany makes e a one-to-many style endpoint, as created by
+// socket(2) with SOCK_SEQPACKET.
+func (e *Endpoint) SetOneToMany() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.oneToMany = true
+}
+
+// notify queues waiter notifications for mask, which are sent once e.mu is
+// released.
+//
+// +checklocks:e.mu
+func (e *Endpoint) notify(mask waiter.EventMask) {
+	e.events |= mask
+}
+
+// unlockAndFlush releases e.mu, then writes the packets and sends the
+// notifications queued while it was held.
+//
+// +checklocksrelease:e.mu
+func (e *Endpoint) unlockAndFlush() {
+	out := e.outbox
+	e.outbox = nil
+	events := e.events
+	e.events = 0
+	e.mu.Unlock()
+	if len(out) > 0 {
+		e.stats.PacketsSent.IncrementBy(uint64(len(out)))
+		e.protocol.send(out)
+	}
+	if events != 0 {
+		e.waiterQueue.Notify(events)
+	}
+}
+
+// queueReply queues a packet carrying chunks to the source of in.
+//
+// +checklocks:e.mu
+func (e *Endpoint) queueReply(in *inPacket, vtag uint32, chunks []byte) {
+	b := make([]byte, header.SCTPMinimumSize+len(chunks))
+	h := header.SCTP(b)
+	h.EncodeCommonHeader(in.id.LocalPort, in.id.RemotePort, vtag)
+	copy(b[header.SCTPMinimumSize:], chunks)
+	h.SetChecksum()
+	e.outbox = append(e.outbox, outPacket{
+		nicID:    in.nicID,
+		netProto: in.netProto,
+		local:    in.id.LocalAddress,
+		remote:   in.id.RemoteAddress,
+		owner:    e.owner,
+		b:        b,
+	})
+}
+
+// HandlePacket implements stack.TransportEndpoint.HandlePacket.
+func (e *Endpoint) HandlePacket(id stack.TransportEndpointID, pkt *stack.PacketBuffer) {
+	if !e.protocol.deliver(e, id, pkt) {
+		e.stats.ReceiveErrors.MalformedPacketsReceived.Increment()
+	}
+}
+
+// HandleError implements stack.TransportEndpoint.HandleError.
+func (e *Endpoint) HandleError(transErr stack.TransportError, pkt *stack.PacketBuffer) {
+	if transErr.Kind() != stack.PacketTooBigTransportError {
+		return
+	}
+	// The packet that didn't fit carries the association's tags.
+	h := header.SCTP(pkt.Data().AsRange().ToSlice())
+	if len(h) < header.SCTPMinimumSize {
+		return
+	}
+	a := e.protocol.lookupReflected(h.SourcePort(), h.DestinationPort(), h.VerificationTag())
+	if a == nil {
+		return
+	}
+	ae := a.ep
+	ae.mu.Lock()
+	defer ae.unlockAndFlush()
+	mtu := uint32(transErr.Info())
+	for _, p := range a.paths {
+		if mtu >= header.IPv6MinimumMTU && mtu < p.mtu {
+			p.mtu = mtu
+		}
+	}
+}
+
+// handlePacket delivers an incoming packet to association a of e.
+func (e *Endpoint) handlePacket(a *association, in *inPacket) {
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+	e.stats.PacketsReceived.Increment()
+	a.handlePacket(in)
+}
+
+// handleInit responds to an INIT chunk with an INIT ACK carrying a state
+// cookie. No state is kept until the cookie is echoed. It returns false if
+// the INIT must be answered with an ABORT.
+func (e *Endpoint) handleInit(in *inPacket, c header.SCTPInit) bool {
+	if len(c) < header.SCTPInitSize {
+		return false
+	}
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+	params, ok := c.Parameters()
+	if !ok || c.InitiateTag() == 0 || c.OutboundStreams() == 0 || c.InboundStreams() == 0 {
+		return false
+	}
+
+	// An INIT from a peer we are setting up an association with means that
+	// both sides initiated at once. The INIT ACK then carries our existing
+	// tag and TSN, so that both handshakes complete the same association
+	// (RFC 9260 section 5.2.1).
+	var existing *association
+	for _, a := range e.assocs {
+		if a.peerPort == in.id.RemotePort && a.state.handshaking() && a.pathFor(in.id.RemoteAddress) != nil {
+			existing = a
+			break
+		}
+	}
+	if existing == nil {
+		if e.state != endpointListening {
+			return false
+		}
+		if !e.oneToMany && len(e.acceptQueue) >= e.backlog {
+			// Let the peer retransmit once there's room.
+			return true
+		}
+	}
+
+	ck := &cookie{
+		created:    e.protocol.now(),
+		peerTag:    c.InitiateTag(),
+		peerTSN:    c.InitialTSN(),
+		peerRwnd:   c.AdvertisedReceiverWindow(),
+		outStreams: min(e.initMsg.NumOStreams, c.InboundStreams()),
+		inStreams:  min(e.initMsg.MaxInStreams, c.OutboundStreams()),
+		localPort:  in.id.LocalPort,
+		peerPort:   in.id.RemotePort,
+		localAddr:  in.id.LocalAddress,
+		peerAddr:   in.id.RemoteAddress,
+		peerAddrs:  peerAddresses(params, in.id.RemoteAddress),
+	}
+	if existing != nil {
+		ck.localTag = existing.localTag
+		ck.localTSN = existing.snd.nextTSN
+		ck.outStreams = min(existing.numOutStreams, c.InboundStreams())
+		ck.inStreams = min(existing.numInStreams, c.OutboundStreams())
+	} else {
+		ck.localTag = e.protocol.newTag()
+		ck.localTSN = e.stack.InsecureRNG().Uint32()
+	}
+
+	var addrParams []byte
+	if e.BindAddr == (tcpip.Address{}) && !isLoopback(in.id.RemoteAddress) {
+		addrParams = e.localAddressParams(in.netProto)
+	}
+	cookieParam := header.AppendSCTPParameter(nil, header.SCTPParamStateCookie, e.protocol.makeCookie(ck))
+	paramsLen := len(cookieParam) + len(addrParams)
+	ia := make([]byte, header.SCTPInitSize, header.SCTPInitSize+paramsLen)
+	header.SCTPInit(ia).Encode(header.SCTPChunkInitAck, &header.SCTPInitFields{
+		InitiateTag:              ck.localTag,
+		AdvertisedReceiverWindow: e.receiveWindow(nil),
+		OutboundStreams:          ck.outStreams,
+		InboundStreams:           ck.inStreams,
+		InitialTSN:               ck.localTSN,
+	}, paramsLen)
+	ia = append(ia, cookieParam...)
+	ia = append(ia, addrParams...)
+	e.queueReply(in, c.InitiateTag(), ia)
+	return true
+}
+
+// handleCookieEcho creates an association from a COOKIE ECHO that doesn't
+// match an existing one. It returns false if the chunk must be treated as
+// out of the blue.
+func (e *Endpoint) handleCookieEcho(in *inPacket) bool {
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+	if e.state != endpointListening {
+		return false
+	}
+	value := in.chunks[0].Value()
+	ck, ok := e.protocol.verifyCookie(value, in.id, in.vtag)
+	if !ok {
+		if peerTag, staleness, ok := e.protocol.staleCookie(value, in.id, in.vtag); ok {
+			var info [4]byte
+			binary.BigEndian.PutUint32(info[:], uint32(min(staleness.Microseconds(), 1<<32-1)))
+			e.queueError(in, peerTag, header.SCTPCauseStaleCookie, info[:])
+		}
+		// Invalid cookies are silently discarded.
+		return true
+	}
+
+	target := e
+	if !e.oneToMany {
+		if len(e.acceptQueue) >= e.backlog {
+			return true
+		}
+		target = e.newAcceptedEndpoint(in)
+		target.mu.Lock()
+	}
+	a := target.newAssociation(in.netProto, in.id.LocalAddress, tcpip.FullAddress{Addr: in.id.RemoteAddress, Port: in.id.RemotePort})
+	a.localTag = ck.localTag
+	if !e.protocol.addAssociation(a) {
+		// A duplicate cookie raced with the first one.
+		if target != e {
+			target.mu.Unlock()
+		}
+		return true
+	}
+	a.snd.init(a, ck.localTSN)
+	a.peerTag = ck.peerTag
+	a.numOutStreams = ck.outStreams
+	a.numInStreams = ck.inStreams
+	a.snd.peerRwnd = ck.peerRwnd
+	a.snd.initStreams(a.numOutStreams)
+	a.rcv.init(ck.peerTSN, a.numInStreams)
+	for _, addr := range ck.peerAddrs {
+		a.addPath(addr)
+	}
+	a.primary.confirmed = true
+	target.assocs = append(target.assocs, a)
+	a.established()
+	a.sendCookieAck()
+	if len(in.chunks) > 1 {
+		// Process the DATA bundled with the COOKIE ECHO.
+		rest := *in
+		rest.chunks = in.chunks[1:]
+		a.handlePacket(&rest)
+	}
+
+	if target != e {
+		// The accepted endpoint's packets are sent along with ours; nobody
+		// waits on it yet.
+		e.outbox = append(e.outbox, target.outbox...)
+		target.outbox = nil
+		target.events = 0
+		target.mu.Unlock()
+		e.acceptQueue = append(e.acceptQueue, target)
+		e.notify(waiter.ReadableEvents)
+	}
+	return true
+}
+
+// queueError queues an ERROR chunk with a single cause to the source of in.
+//
+// +checklocks:e.mu
+func (e *Endpoint) queueError(in *inPacket, vtag uint32, cause header.SCTPErrorCause, info []byte) {
+	causeLen := header.SCTPParameterHeaderSize + len(info)
+	c := make([]byte, header.SCTPChunkHeaderSize+header.SCTPPadded(causeLen))
+	header.EncodeSCTPChunkHeader(c, header.SCTPChunkError, 0, causeLen)
+	binary.BigEndian.PutUint16(c[header.SCTPChunkHeaderSize:], uint16(cause))
+	binary.BigEndian.PutUint16(c[header.SCTPChunkHeaderSize+2:], uint16(causeLen))
+	copy(c[header.SCTPChunkHeaderSize+header.SCTPParameterHeaderSize:], info)
+	e.queueReply(in, vtag, c)
+}
+
+// newAcceptedEndpoint returns the endpoint for a new association of a
+// listening one-to-one endpoint. It shares the listener's port without
+// reserving it.
+//
+// +checklocks:e.mu
+func (e *Endpoint) newAcceptedEndpoint(in *inPacket) *Endpoint {
+	n := newEndpoint(e.protocol, e.NetProto, &waiter.Queue{})
+	n.ops.SetSendBufferSize(e.ops.GetSendBufferSize(), false /* notify */)
+	n.ops.SetReceiveBufferSize(e.ops.GetReceiveBufferSize(), false /* notify */)
+	n.ops.SetDelayOption(e.ops.GetDelayOption())
+	n.ops.SetV6Only(e.ops.GetV6Only())
+	n.ops.SetLinger(e.ops.GetLinger())
+	n.ID = stack.TransportEndpointID{
+		LocalPort:     e.ID.LocalPort,
+		LocalAddress:  in.id.LocalAddress,
+		RemotePort:    in.id.RemotePort,
+		RemoteAddress: in.id.RemoteAddress,
+	}
+	n.BindNICID = e.BindNICID
+	n.BindAddr = e.BindAddr
+	n.boundNICID = e.boundNICID
+	n.effectiveNetProtos = []tcpip.NetworkProtocolNumber{in.netProto}
+	n.state = endpointConnecting
+	n.isConnectNotified = true
+	n.rtoInitial = e.rtoInitial
+	n.rtoMin = e.rtoMin
+	n.rtoMax = e.rtoMax
+	n.initMsg = e.initMsg
+	n.sendInfo = e.sendInfo
+	n.maxSeg = e.maxSeg
+	n.owner = e.owner
+	return n
+}
+
+// localAddressParams returns the address parameters listing the local
+// unicast addresses of protocol netProto.
+//
+// +checklocks:e.mu
+func (e *Endpoint) localAddressParams(netProto tcpip.NetworkProtocolNumber) []byte {
+	var params []byte
+	for _, addrs := range e.stack.AllAddresses() {
+		for _, pa := range addrs {
+			addr := pa.AddressWithPrefix.Address
+			if pa.Protocol != netProto || isLoopback(addr) || !isUnicast(addr) {
+				continue
+			}
+			params = appendAddressParam(params, addr)
+		}
+	}
+	return params
+}
+
+// receiveWindow returns the receive window to advertise to the peer of a,
+// or for a new association if a is nil.
+//
+// +checklocks:e.mu
+func (e *Endpoint) receiveWindow(a *association) uint32 {
+	used := e.rcvBufUsed
+	if a != nil {
+		used += a.rcv.held
+	}
+	n := int(e.ops.GetReceiveBufferSize()) - used
+	if n < 0 {
+		return 0
+	}
+	return uint32(n)
+}
+
+// deliver queues a received message for reading.
+//
+// +checklocks:e.mu
+func (e *Endpoint) deliver(m *message) {
+	if e.state == endpointClosed {
+		return
+	}
+	e.rcvQueue = append(e.rcvQueue, m)
+	e.rcvBufUsed += len(m.data)
+	e.notify(waiter.ReadableEvents)
+}
+
+// associationEstablished is called when a completes its handshake.
+//
+// +checklocks:e.mu
+func (e *Endpoint) associationEstablished(a *association) {
+	if !e.oneToMany && e.state == endpointConnecting {
+		e.state = endpointConnected
+	}
+	e.notify(waiter.WritableEvents)
+}
+
+// associationClosed is called when a terminates.
+//
+// +checklocks:e.mu
+func (e *Endpoint) associationClosed(a *association) {
+	for i, b := range e.assocs {
+		if b == a {
+			e.assocs = append(e.assocs[:i], e.assocs[i+1:]...)
+			break
+		}
+	}
+	for _, c := range a.snd.queue {
+		e.sndBufUsed -= len(c.payload)
+	}
+	for _, c := range a.snd.inflight {
+		e.sndBufUsed -= len(c.payload)
+	}
+	a.snd.queue = nil
+	a.snd.inflight = nil
+
+	if e.oneToMany {
+		if a.err != nil && a.handshake != nil {
+			e.connectErr = a.err
+		}
+		e.notify(waiter.WritableEvents)
+		return
+	}
+	if e.state == endpointConnecting || e.state == endpointConnected {
+		e.state = endpointDisconnected
+		e.hardError = a.err
+		e.rcvClosed = true
+		e.sndClosed = true
+	}
+	e.notify(waiter.EventHUp | waiter.EventErr | waiter.ReadableEvents | waiter.WritableEvents)
+}
+
+// peerShutdown is called when the peer of a shuts down its sending side.
+//
+// +checklocks:e.mu
+func (e *Endpoint) peerShutdown(a *association) {
+	if !e.oneToMany {
+		e.rcvClosed = true
+		e.notify(waiter.ReadableEvents | waiter.EventRdHUp)
+	}
+}
+
+// Abort implements stack.TransportEndpoint.Abort.
+func (e *Endpoint) Abort() {
+	e.ops.SetLinger(tcpip.LingerOption{Enabled: true})
+	e.Close()
+}
+
+// Close puts the endpoint in a closed state and frees all resources
+// associated with it. Associations are shut down gracefully, unless unread
+// data is discarded or SO_LINGER is set with a zero timeout, in which case
+// they are aborted.
+func (e *Endpoint) Close() {
+	e.mu.Lock()
+	if e.state == endpointClosed {
+		e.mu.Unlock()
+		return
+	}
+	linger := e.ops.GetLinger()
+	abortive := (linger.Enabled && linger.Timeout == 0) || e.rcvBufUsed > 0
+	for _, a := range append([]*association(nil), e.assocs...) {
+		if abortive {
+			a.abort()
+		} else {
+			a.shutdown()
+		}
+	}
+	pending := e.acceptQueue
+	e.acceptQueue = nil
+	if e.isPortReserved {
+		e.stack.UnregisterTransportEndpoint(e.effectiveNetProtos, ProtocolNumber, e.ID, e, e.boundPortFlags, e.boundBindToDevice)
+		e.stack.ReleasePort(ports.Reservation{
+			Networks:     e.effectiveNetProtos,
+			Transport:    ProtocolNumber,
+			Addr:         e.ID.LocalAddress,
+			Port:         e.ID.LocalPort,
+			Flags:        e.boundPortFlags,
+			BindToDevice: e.boundBindToDevice,
+		})
+		e.isPortReserved = false
+	}
+	e.state = endpointClosed
+	e.rcvQueue = nil
+	e.rcvBufUsed = 0
+	e.rcvClosed = true
+	e.sndClosed = true
+	e.notify(waiter.EventHUp | waiter.EventErr | waiter.ReadableEvents | waiter.WritableEvents)
+	e.unlockAndFlush()
+
+	for _, n := range pending {
+		n.Abort()
+	}
+}
+
+// Wait implements stack.TransportEndpoint.Wait.
+func (*Endpoint) Wait() {}
+
+// ModerateRecvBuf implements tcpip.Endpoint.ModerateRecvBuf.
+func (*Endpoint) ModerateRecvBuf(int) {}
+
+// SetOwner implements tcpip.Endpoint.SetOwner.
+func (e *Endpoint) SetOwner(owner tcpip.PacketOwner) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.owner = owner
+}
+
+// LastError implements tcpip.Endpoint.LastError.
+func (e *Endpoint) LastError() tcpip.Error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.lastError
+	e.lastError = nil
+	return err
+}
+
+// UpdateLastError implements tcpip.SocketOptionsHandler.UpdateLastError.
+func (e *Endpoint) UpdateLastError(err tcpip.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastError = err
+}
+
+// hardErrorLocked returns the error that terminated the association of a
+// one-to-one endpoint, and clears it.
+//
+// +checklocks:e.mu
+func (e *Endpoint) hardErrorLocked() tcpip.Error {
+	err := e.hardError
+	e.hardError = nil
+	return err
+}
+
+// Read implements tcpip.Endpoint.Read. Each call reads from at most one
+// message; the rest of a partially read message is returned by the next
+// call.
+func (e *Endpoint) Read(dst io.Writer, opts tcpip.ReadOptions) (tcpip.ReadResult, tcpip.Error) {
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+
+	if len(e.rcvQueue) == 0 {
+		switch {
+		case e.state == endpointDisconnected:
+			if err := e.hardErrorLocked(); err != nil {
+				return tcpip.ReadResult{}, err
+			}
+			return tcpip.ReadResult{}, &tcpip.ErrClosedForReceive{}
+		case e.rcvClosed:
+			e.stats.ReadErrors.ReadClosed.Increment()
+			return tcpip.ReadResult{}, &tcpip.ErrClosedForReceive{}
+		case !e.oneToMany && e.state != endpointConnected && e.state != endpointConnecting:
+			e.stats.ReadErrors.NotConnected.Increment()
+			return tcpip.ReadResult{}, &tcpip.ErrNotConnected{}
+		}
+		return tcpip.ReadResult{}, &tcpip.ErrWouldBlock{}
+	}
+
+	m := e.rcvQueue[0]
+	n, err := dst.Write(m.data[m.off:])
+	if n == 0 && err != nil {
+		return tcpip.ReadResult{}, &tcpip.ErrBadBuffer{}
+	}
+	res := tcpip.ReadResult{
+		Count: n,
+		Total: n,
+	}
+	if opts.NeedRemoteAddr {
+		res.RemoteAddr = m.from
+	}
+	if opts.Peek {
+		return res, nil
+	}
+	m.off += n
+	e.rcvBufUsed -= n
+	if m.off == len(m.data) {
+		e.rcvQueue[0] = nil
+		e.rcvQueue = e.rcvQueue[1:]
+	}
+	if a := m.assoc; a.state != StateClosed {
+		a.windowOpened()
+	}
+	return res, nil
+}
+
+// Write implements tcpip.Endpoint.Write. Each call sends a single message.
+// One-to-many endpoints set up an association implicitly when writing to a
+// new peer.
+func (e *Endpoint) Write(p tcpip.Payloader, opts tcpip.WriteOptions) (int64, tcpip.Error) {
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+
+	var a *association
+	if e.oneToMany {
+		if e.state == endpointClosed {
+			return 0, &tcpip.ErrClosedForSend{}
+		}
+		if opts.To == nil {
+			return 0, &tcpip.ErrDestinationRequired{}
+		}
+		var err tcpip.Error
+		if a, err = e.associationFor(*opts.To); err != nil {
+			return 0, err
+		}
+	} else {
+		switch e.state {
+		case endpointConnecting, endpointConnected:
+			if e.sndClosed {
+				e.stats.WriteErrors.WriteClosed.Increment()
+				return 0, &tcpip.ErrClosedForSend{}
+			}
+			a = e.assocs[0]
+		case endpointDisconnected:
+			if err := e.hardErrorLocked(); err != nil {
+				return 0, err
+			}
+			return 0, &tcpip.ErrClosedForSend{}
+		case endpointClosed:
+			return 0, &tcpip.ErrClosedForSend{}
+		default:
+			e.stats.WriteErrors.InvalidEndpointState.Increment()
+			return 0, &tcpip.ErrNotConnected{}
+		}
+	}
+
+	l := p.Len()
+	sndBuf := int(e.ops.GetSendBufferSize())
+	switch {
+	case l == 0:
+		e.stats.WriteErrors.InvalidArgs.Increment()
+		return 0, &tcpip.ErrInvalidOptionValue{}
+	case l > sndBuf:
+		return 0, &tcpip.ErrMessageTooLong{}
+	case e.sndBufUsed+l > sndBuf:
+		return 0, &tcpip.ErrWouldBlock{}
+	}
+	info := e.sendInfo
+	if a.snd.ssn != nil && int(info.stream) >= len(a.snd.ssn) {
+		e.stats.WriteErrors.InvalidArgs.Increment()
+		return 0, &tcpip.ErrInvalidOptionValue{}
+	}
+	msg := make([]byte, l)
+	if _, err := io.ReadFull(p, msg); err != nil {
+		return 0, &tcpip.ErrBadBuffer{}
+	}
+	a.enqueue(msg, info)
+	e.sndBufUsed += l
+	a.sendData()
+	return int64(l), nil
+}
+
+// associationFor returns the association of a one-to-many endpoint with the
+// peer at addr, setting it up if it doesn't exist.
+//
+// +checklocks:e.mu
+func (e *Endpoint) associationFor(addr tcpip.FullAddress) (*association, tcpip.Error) {
+	addr, netProto, err := e.TransportEndpointInfo.AddrNetProtoLocked(addr, e.ops.GetV6Only(), false /* bind */)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range e.assocs {
+		if a.peerPort == addr.Port && a.pathFor(addr.Addr) != nil {
+			if !a.state.canSend() && !a.state.handshaking() {
+				return nil, &tcpip.ErrClosedForSend{}
+			}
+			return a, nil
+		}
+	}
+	return e.startAssociation(addr, netProto)
+}
+
+// startAssociation sends an INIT to addr, binding the endpoint first if
+// needed.
+//
+// +checklocks:e.mu
+func (e *Endpoint) startAssociation(addr tcpip.FullAddress, netProto tcpip.NetworkProtocolNumber) (*association, tcpip.Error) {
+	if addr.Port == 0 || addr.Addr.Len() == 0 {
+		return nil, &tcpip.ErrInvalidEndpointState{}
+	}
+	if e.state == endpointInitial {
+		if err := e.bindLocked(tcpip.FullAddress{}); err != nil {
+			return nil, err
+		}
+	}
+
+	localAddr := e.ID.LocalAddress
+	r, err := e.stack.FindRoute(e.BindNICID, localAddr, addr.Addr, netProto, false /* multicastLoop */)
+	if err != nil {
+		return nil, err
+	}
+	if localAddr.Len() == 0 {
+		localAddr = r.LocalAddress()
+	}
+	r.Release()
+
+	a := e.newAssociation(netProto, localAddr, addr)
+	e.protocol.addAssociation(a)
+	e.assocs = append(e.assocs, a)
+	a.connect()
+	return a, nil
+}
+
+// Connect implements tcpip.Endpoint.Connect.
+func (e *Endpoint) Connect(addr tcpip.FullAddress) tcpip.Error {
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+
+	if e.oneToMany {
+		if err := e.connectErr; err != nil {
+			e.connectErr = nil
+			return err
+		}
+		a, err := e.associationFor(addr)
+		if err != nil {
+			return err
+		}
+		if a.state.handshaking() {
+			return &tcpip.ErrConnectStarted{}
+		}
+		return nil
+	}
+
+	switch e.state {
+	case endpointConnecting:
+		return &tcpip.ErrAlreadyConnecting{}
+	case endpointConnected:
+		if !e.isConnectNotified {
+			e.isConnectNotified = true
+			return nil
+		}
+		return &tcpip.ErrAlreadyConnected{}
+	case endpointDisconnected:
+		if err := e.hardErrorLocked(); err != nil {
+			return err
+		}
+		return &tcpip.ErrConnectionAborted{}
+	case endpointListening, endpointClosed:
+		return &tcpip.ErrInvalidEndpointState{}
+	}
+
+	addr, netProto, err := e.TransportEndpointInfo.AddrNetProtoLocked(addr, e.ops.GetV6Only(), false /* bind */)
+	if err != nil {
+		return err
+	}
+	a, err := e.startAssociation(addr, netProto)
+	if err != nil {
+		return err
+	}
+	e.ID.RemoteAddress = addr.Addr
+	e.ID.RemotePort = addr.Port
+	if e.ID.LocalAddress.Len() == 0 {
+		e.ID.LocalAddress = a.localAddr
+	}
+	e.state = endpointConnecting
+	return &tcpip.ErrConnectStarted{}
+}
+
+// Disconnect implements tcpip.Endpoint.Disconnect.
+func (*Endpoint) Disconnect() tcpip.Error {
+	return &tcpip.ErrNotSupported{}
+}
+
+// Shutdown implements tcpip.Endpoint.Shutdown. Shutting down the sending
+// side starts a graceful shutdown of the endpoint's associations.
+func (e *Endpoint) Shutdown(flags tcpip.ShutdownFlags) tcpip.Error {
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+
+	if !e.oneToMany && e.state != endpointConnected && e.state != endpointConnecting {
+		return &tcpip.ErrNotConnected{}
+	}
+	if flags&tcpip.ShutdownRead != 0 {
+		e.rcvClosed = true
+		e.notify(waiter.ReadableEvents)
+	}
+	if flags&tcpip.ShutdownWrite != 0 {
+		if !e.oneToMany {
+			e.sndClosed = true
+		}
+		for _, a := range append([]*association(nil), e.assocs...) {
+			a.shutdown()
+		}
+		e.notify(waiter.WritableEvents)
+	}
+	return nil
+}
+
+// Listen implements tcpip.Endpoint.Listen.
+func (e *Endpoint) Listen(backlog int) tcpip.Error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch e.state {
+	case endpointInitial:
+		if err := e.bindLocked(tcpip.FullAddress{}); err != nil {
+			return err
+		}
+	case endpointBound:
+	case endpointListening:
+		e.backlog = backlog
+		return nil
+	default:
+		return &tcpip.ErrInvalidEndpointState{}
+	}
+	if !e.oneToMany && len(e.assocs) != 0 {
+		return &tcpip.ErrInvalidEndpointState{}
+	}
+	e.backlog = backlog
+	e.state = endpointListening
+	return nil
+}
+
+// Accept implements tcpip.Endpoint.Accept.
+func (e *Endpoint) Accept(peerAddr *tcpip.FullAddress) (tcpip.Endpoint, *waiter.Queue, tcpip.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.oneToMany {
+		return nil, nil, &tcpip.ErrNotSupported{}
+	}
+	if e.state != endpointListening {
+		return nil, nil, &tcpip.ErrInvalidEndpointState{}
+	}
+	if len(e.acceptQueue) == 0 {
+		return nil, nil, &tcpip.ErrWouldBlock{}
+	}
+	n := e.acceptQueue[0]
+	e.acceptQueue[0] = nil
+	e.acceptQueue = e.acceptQueue[1:]
+	if peerAddr != nil {
+		*peerAddr = tcpip.FullAddress{
+			Addr: n.ID.RemoteAddress,
+			Port: n.ID.RemotePort,
+		}
+	}
+	return n, n.waiterQueue, nil
+}
+
+// Bind implements tcpip.Endpoint.Bind.
+func (e *Endpoint) Bind(addr tcpip.FullAddress) tcpip.Error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.bindLocked(addr)
+}
+
+// +checklocks:e.mu
+func (e *Endpoint) bindLocked(addr tcpip.FullAddress) tcpip.Error {
+	if e.state != endpointInitial {
+		return &tcpip.ErrAlreadyBound{}
+	}
+
+	e.BindAddr = addr.Addr
+	addr, netProto, err := e.TransportEndpointInfo.AddrNetProtoLocked(addr, e.ops.GetV6Only(), true /* bind */)
+	if err != nil {
+		return err
+	}
+	netProtos := []tcpip.NetworkProtocolNumber{netProto}
+	if netProto == header.IPv6ProtocolNumber && !e.ops.GetV6Only() && addr.Addr == (tcpip.Address{}) && e.stack.CheckNetworkProtocol(header.IPv4ProtocolNumber) {
+		netProtos = append(netProtos, header.IPv4ProtocolNumber)
+	}
+
+	var nic tcpip.NICID
+	if addr.Addr.Len() != 0 {
+		nic = e.stack.CheckLocalAddress(addr.NIC, netProto, addr.Addr)
+		if nic == 0 {
+			return &tcpip.ErrBadLocalAddress{}
+		}
+	}
+
+	bindToDevice := tcpip.NICID(e.ops.GetBindToDevice())
+	id := stack.TransportEndpointID{LocalAddress: addr.Addr}
+	port, err := e.stack.ReservePort(e.stack.SecureRNG(), ports.Reservation{
+		Networks:     netProtos,
+		Transport:    ProtocolNumber,
+		Addr:         addr.Addr,
+		Port:         addr.Port,
+		Flags:        e.portFlags,
+		BindToDevice: bindToDevice,
+	}, nil /* testPort */)
+	if err != nil {
+		return err
+	}
+	id.LocalPort = port
+	if err := e.stack.RegisterTransportEndpoint(netProtos, ProtocolNumber, id, e, e.portFlags, bindToDevice); err != nil {
+		e.stack.ReleasePort(ports.Reservation{
+			Networks:     netProtos,
+			Transport:    ProtocolNumber,
+			Addr:         addr.Addr,
+			Port:         port,
+			Flags:        e.portFlags,
+			BindToDevice: bindToDevice,
+		})
+		return err
+	}
+
+	e.ID = id
+	e.BindNICID = addr.NIC
+	e.boundNICID = nic
+	e.boundBindToDevice = bindToDevice
+	e.boundPortFlags = e.portFlags
+	e.effectiveNetProtos = netProtos
+	e.isPortReserved = true
+	e.state = endpointBound
+	return nil
+}
+
+// GetLocalAddress implements tcpip.Endpoint.GetLocalAddress.
+func (e *Endpoint) GetLocalAddress() (tcpip.FullAddress, tcpip.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return tcpip.FullAddress{
+		Addr: e.ID.LocalAddress,
+		Port: e.ID.LocalPort,
+		NIC:  e.boundNICID,
+	}, nil
+}
+
+// GetRemoteAddress implements tcpip.Endpoint.GetRemoteAddress.
+func (e *Endpoint) GetRemoteAddress() (tcpip.FullAddress, tcpip.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.oneToMany || (e.state != endpointConnected && e.state != endpointConnecting) {
+		return tcpip.FullAddress{}, &tcpip.ErrNotConnected{}
+	}
+	return tcpip.FullAddress{
+		Addr: e.ID.RemoteAddress,
+		Port: e.ID.RemotePort,
+		NIC:  e.boundNICID,
+	}, nil
+}
+
+// Readiness implements tcpip.Endpoint.Readiness.
+func (e *Endpoint) Readiness(mask waiter.EventMask) waiter.EventMask {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var result waiter.EventMask
+	writable := e.sndClosed || e.sndBufUsed < int(e.ops.GetSendBufferSize())
+	readable := len(e.rcvQueue) != 0 || e.rcvClosed
+	switch {
+	case e.state == endpointClosed || e.state == endpointDisconnected:
+		result = mask
+	case e.oneToMany:
+		if writable {
+			result |= waiter.WritableEvents
+		}
+		if readable {
+			result |= waiter.ReadableEvents
+		}
+	case e.state == endpointInitial || e.state == endpointBound:
+		result |= waiter.EventHUp
+	case e.state == endpointListening:
+		if len(e.acceptQueue) != 0 {
+			result |= waiter.ReadableEvents
+		}
+	case e.state == endpointConnected:
+		if writable {
+			result |= waiter.WritableEvents
+		}
+		if readable {
+			result |= waiter.ReadableEvents
+		}
+	}
+	return result & mask
+}
+
+// SetSockOpt implements tcpip.Endpoint.SetSockOpt.
+func (e *Endpoint) SetSockOpt(opt tcpip.SettableSocketOption) tcpip.Error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch v := opt.(type) {
+	case *tcpip.SCTPRTOInfoOption:
+		initial, lo, hi := e.rtoInitial, e.rtoMin, e.rtoMax
+		if a := e.associationByID(v.AssocID); a != nil {
+			initial, lo, hi = a.rtoInitial, a.rtoMin, a.rtoMax
+		} else if v.AssocID != 0 {
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		if v.Initial != 0 {
+			initial = v.Initial
+		}
+		if v.Min != 0 {
+			lo = v.Min
+		}
+		if v.Max != 0 {
+			hi = v.Max
+		}
+		if lo > hi || initial < lo || initial > hi {
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		if a := e.associationByID(v.AssocID); a != nil {
+			a.rtoInitial, a.rtoMin, a.rtoMax = initial, lo, hi
+		} else {
+			e.rtoInitial, e.rtoMin, e.rtoMax = initial, lo, hi
+		}
+	case *tcpip.SCTPInitMsgOption:
+		if v.NumOStreams != 0 {
+			e.initMsg.NumOStreams = v.NumOStreams
+		}
+		if v.MaxInStreams != 0 {
+			e.initMsg.MaxInStreams = v.MaxInStreams
+		}
+		if v.MaxAttempts != 0 {
+			e.initMsg.MaxAttempts = v.MaxAttempts
+		}
+		if v.MaxInitTimeout != 0 {
+			e.initMsg.MaxInitTimeout = v.MaxInitTimeout
+		}
+	case *tcpip.SCTPDefaultSendInfoOption:
+		if v.AssocID != 0 && e.associationByID(v.AssocID) == nil {
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		e.sendInfo = sendInfo{
+			stream:    v.Stream,
+			unordered: v.Unordered,
+			ppid:      v.PPID,
+			context:   v.Context,
+		}
+	default:
+		return &tcpip.ErrUnknownProtocolOption{}
+	}
+	return nil
+}
+
+// SetSockOptInt implements tcpip.Endpoint.SetSockOptInt.
+func (e *Endpoint) SetSockOptInt(opt tcpip.SockOptInt, v int) tcpip.Error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch opt {
+	case tcpip.MaxSegOption:
+		if v < 0 || v > 1<<16-1 {
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		e.maxSeg = v
+	default:
+		return &tcpip.ErrUnknownProtocolOption{}
+	}
+	return nil
+}
+
+// GetSockOpt implements tcpip.Endpoint.GetSockOpt.
+func (e *Endpoint) GetSockOpt(opt tcpip.GettableSocketOption) tcpip.Error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch v := opt.(type) {
+	case *tcpip.SCTPRTOInfoOption:
+		if a := e.associationByID(v.AssocID); a != nil {
+			v.Initial, v.Min, v.Max = a.rtoInitial, a.rtoMin, a.rtoMax
+		} else if v.AssocID == 0 {
+			v.Initial, v.Min, v.Max = e.rtoInitial, e.rtoMin, e.rtoMax
+		} else {
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+	case *tcpip.SCTPInitMsgOption:
+		*v = e.initMsg
+	case *tcpip.SCTPDefaultSendInfoOption:
+		v.Stream = e.sendInfo.stream
+		v.Unordered = e.sendInfo.unordered
+		v.PPID = e.sendInfo.ppid
+		v.Context = e.sendInfo.context
+	case *tcpip.SCTPStatusOption:
+		a := e.associationByID(v.AssocID)
+		if a == nil && v.AssocID == 0 && len(e.assocs) != 0 {
+			a = e.assocs[0]
+		}
+		if a == nil {
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		*v = a.status()
+	default:
+		return &tcpip.ErrUnknownProtocolOption{}
+	}
+	return nil
+}
+
+// GetSockOptInt implements tcpip.Endpoint.GetSockOptInt.
+func (e *Endpoint) GetSockOptInt(opt tcpip.SockOptInt) (int, tcpip.Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch opt {
+	case tcpip.MaxSegOption:
+		if e.maxSeg == 0 && !e.oneToMany && len(e.assocs) != 0 {
+			return e.assocs[0].maxDataPayload(), nil
+		}
+		return e.maxSeg, nil
+	case tcpip.ReceiveQueueSizeOption:
+		return e.rcvBufUsed, nil
+	case tcpip.SendQueueSizeOption:
+		return e.sndBufUsed, nil
+	default:
+		return -1, &tcpip.ErrUnknownProtocolOption{}
+	}
+}
+
+// associationByID returns the association with identifier id, or nil.
+//
+// +checklocks:e.mu
+func (e *Endpoint) associationByID(id int32) *association {
+	for _, a := range e.assocs {
+		if a.id == id {
+			return a
+		}
+	}
+	return nil
+}
+
+// State implements tcpip.Endpoint.State. It returns the state of the
+// endpoint's first association, which is the only one of one-to-one
+// endpoints.
+func (e *Endpoint) State() uint32 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.assocs) == 0 {
+		return uint32(StateClosed)
+	}
+	return uint32(e.assocs[0].state)
+}
+
+// Info returns a copy of the endpoint info.
+func (e *Endpoint) Info() tcpip.EndpointInfo {
+	e.mu.Lock()
+	// Make a copy of the endpoint info.
+	ret := e.TransportEndpointInfo
+	e.mu.Unlock()
+	return &ret
+}
+
+// Stats returns a pointer to the endpoint stats.
+func (e *Endpoint) Stats() tcpip.EndpointStats {
+	return &e.stats
+}
+
+// SocketOptions implements tcpip.Endpoint.SocketOptions.
+func (e *Endpoint) SocketOptions() *tcpip.SocketOptions {
+	return &e.ops
+}
+
+// OnReuseAddressSet implements tcpip.SocketOptionsHandler.OnReuseAddressSet.
+func (e *Endpoint) OnReuseAddressSet(v bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.portFlags.TupleOnly = v
+}
+
+// OnReusePortSet implements tcpip.SocketOptionsHandler.OnReusePortSet.
+func (e *Endpoint) OnReusePortSet(v bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.portFlags.LoadBalanced = v
+}
+
+// OnDelayOptionSet implements tcpip.SocketOptionsHandler.OnDelayOptionSet.
+func (e *Endpoint) OnDelayOptionSet(v bool) {
+	if v {
+		return
+	}
+	// Send the data held back by Nagle's algorithm.
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+	for _, a := range e.assocs {
+		a.sendData()
+	}
+}
+
+// HasNIC implements tcpip.SocketOptionsHandler.HasNIC.
+func (e *Endpoint) HasNIC(id int32) bool {
+	return id == 0 || e.stack.HasNIC(tcpip.NICID(id))
+}
+
+// GetAcceptConn implements tcpip.SocketOptionsHandler.GetAcceptConn.
+func (e *Endpoint) GetAcceptConn() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state == endpointListening
+}
+
+// WakeupWriters implements tcpip.SocketOptionsHandler.WakeupWriters.
+func (e *Endpoint) WakeupWriters() {
+	e.waiterQueue.Notify(waiter.WritableEvents)
+}
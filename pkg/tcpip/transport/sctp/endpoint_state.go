@@ -0,0 +1,69 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sctp
+
+import (
+	"context"
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/ports"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// afterLoad is invoked by stateify.
+func (e *Endpoint) afterLoad(ctx context.Context) {
+	if e.stack.IsSaveRestoreEnabled() {
+		e.stack.RegisterRestoredEndpoint(e)
+	} else {
+		stack.RestoreStackFromContext(ctx).RegisterRestoredEndpoint(e)
+	}
+}
+
+// Restore implements tcpip.RestoredEndpoint.Restore.
+func (e *Endpoint) Restore(s *stack.Stack) {
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+
+	if !e.stack.IsSaveRestoreEnabled() {
+		// The stack was recreated, so the port reservation and the
+		// associations' tags need to be registered again.
+		e.stack = s
+		e.protocol = s.TransportProtocolInstance(ProtocolNumber).(*protocol)
+		if e.isPortReserved {
+			if _, err := e.stack.ReservePort(e.stack.SecureRNG(), ports.Reservation{
+				Networks:     e.effectiveNetProtos,
+				Transport:    ProtocolNumber,
+				Addr:         e.ID.LocalAddress,
+				Port:         e.ID.LocalPort,
+				Flags:        e.boundPortFlags,
+				BindToDevice: e.boundBindToDevice,
+			}, nil /* testPort */); err != nil {
+				panic(fmt.Sprintf("reserving sctp port %d failed during restore: %v", e.ID.LocalPort, err))
+			}
+			id := stack.TransportEndpointID{LocalAddress: e.ID.LocalAddress, LocalPort: e.ID.LocalPort}
+			if err := e.stack.RegisterTransportEndpoint(e.effectiveNetProtos, ProtocolNumber, id, e, e.boundPortFlags, e.boundBindToDevice); err != nil {
+				panic(fmt.Sprintf("registering sctp endpoint with the stack failed during restore: %v", err))
+			}
+		}
+		for _, a := range e.assocs {
+			e.protocol.restoreAssociation(a)
+		}
+	}
+	e.ops.InitHandler(e, e.stack, tcpip.GetStackSendBufferLimits, tcpip.GetStackReceiveBufferLimits)
+	for _, a := range e.assocs {
+		a.resumeTimers()
+	}
+}
@@ -0,0 +1,567 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sctp_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/faketime"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/channel"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/sctp"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+var (
+	clientAddr  = tcpip.AddrFrom4([4]byte{10, 0, 0, 1})
+	serverAddr  = tcpip.AddrFrom4([4]byte{10, 0, 0, 2})
+	serverAddr2 = tcpip.AddrFrom4([4]byte{10, 0, 1, 2})
+	spoofedAddr = tcpip.AddrFrom4([4]byte{10, 0, 0, 3})
+)
+
+// packet is an SCTP packet carried between the stacks of a network.
+type packet struct {
+	toServer bool
+	src, dst tcpip.Address
+	sctp     header.SCTP
+}
+
+// chunks returns the chunks of p of type typ.
+func (p *packet) chunks(typ header.SCTPChunkType) []header.SCTPChunk {
+	chunks, _ := p.sctp.Chunks()
+	var matching []header.SCTPChunk
+	for _, c := range chunks {
+		if c.Type() == typ {
+			matching = append(matching, c)
+		}
+	}
+	return matching
+}
+
+// network connects a client stack to a multihomed server stack, with
+// addresses serverAddr and serverAddr2, over links that lose the packets
+// selected by drop. Both stacks share a manual clock.
+type network struct {
+	t      *testing.T
+	clock  *faketime.ManualClock
+	client *stack.Stack
+	server *stack.Stack
+
+	clientLink *channel.Endpoint
+	serverLink *channel.Endpoint
+
+	// drop returns true if p must be lost.
+	drop func(p *packet) bool
+
+	// sent holds the packets that crossed the links, including lost ones.
+	sent []*packet
+}
+
+func newNetwork(t *testing.T) *network {
+	t.Helper()
+	n := &network{
+		t:          t,
+		clock:      faketime.NewManualClock(),
+		clientLink: channel.New(256, 1500, ""),
+		serverLink: channel.New(256, 1500, ""),
+		drop:       func(*packet) bool { return false },
+	}
+	n.client = n.newStack(n.clientLink, clientAddr)
+	n.server = n.newStack(n.serverLink, serverAddr, serverAddr2)
+	return n
+}
+
+func (n *network) newStack(link *channel.Endpoint, addrs ...tcpip.Address) *stack.Stack {
+	n.t.Helper()
+	s := stack.New(stack.Options{
+		NetworkProtocols:   []stack.NetworkProtocolFactory{ipv4.NewProtocol},
+		TransportProtocols: []stack.TransportProtocolFactory{sctp.NewProtocol},
+		Clock:              n.clock,
+	})
+	if err := s.CreateNIC(nicID, link); err != nil {
+		n.t.Fatalf("CreateNIC(%d, _): %s", nicID, err)
+	}
+	for _, addr := range addrs {
+		protocolAddr := tcpip.ProtocolAddress{
+			Protocol:          ipv4.ProtocolNumber,
+			AddressWithPrefix: tcpip.AddressWithPrefix{Address: addr, PrefixLen: 24},
+		}
+		if err := s.AddProtocolAddress(nicID, protocolAddr, stack.AddressProperties{}); err != nil {
+			n.t.Fatalf("AddProtocolAddress(%d, %+v, {}): %s", nicID, protocolAddr, err)
+		}
+	}
+	s.SetRouteTable([]tcpip.Route{{Destination: header.IPv4EmptySubnet, NIC: nicID}})
+	n.t.Cleanup(func() {
+		s.Close()
+		s.Wait()
+	})
+	return s
+}
+
+// exchange carries the packets queued on the links to the other stack, until
+// none are left.
+func (n *network) exchange() {
+	for {
+		moved := false
+		for _, dir := range []struct {
+			from, to *channel.Endpoint
+			toServer bool
+		}{
+			{from: n.clientLink, to: n.serverLink, toServer: true},
+			{from: n.serverLink, to: n.clientLink, toServer: false},
+		} {
+			for {
+				pkt := dir.from.Read()
+				if pkt == nil {
+					break
+				}
+				moved = true
+				v := pkt.ToView()
+				b := append([]byte(nil), v.AsSlice()...)
+				v.Release()
+				pkt.DecRef()
+				ip := header.IPv4(b)
+				p := &packet{
+					toServer: dir.toServer,
+					src:      ip.SourceAddress(),
+					dst:      ip.DestinationAddress(),
+					sctp:     header.SCTP(ip.Payload()),
+				}
+				n.sent = append(n.sent, p)
+				if !n.drop(p) {
+					n.inject(dir.to, b)
+				}
+			}
+		}
+		if !moved {
+			return
+		}
+	}
+}
+
+// inject delivers the IPv4 packet b to the stack of link.
+func (n *network) inject(link *channel.Endpoint, b []byte) {
+	pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{
+		Payload: buffer.MakeWithData(b),
+	})
+	link.InjectInbound(ipv4.ProtocolNumber, pkt)
+	pkt.DecRef()
+}
+
+// injectToServer delivers an SCTP packet from src to the server.
+func (n *network) injectToServer(src tcpip.Address, sctpPkt header.SCTP) {
+	b := make([]byte, header.IPv4MinimumSize+len(sctpPkt))
+	ip := header.IPv4(b)
+	ip.Encode(&header.IPv4Fields{
+		TotalLength: uint16(len(b)),
+		TTL:         64,
+		Protocol:    uint8(sctp.ProtocolNumber),
+		SrcAddr:     src,
+		DstAddr:     serverAddr,
+	})
+	ip.SetChecksum(^ip.CalculateChecksum())
+	copy(b[header.IPv4MinimumSize:], sctpPkt)
+	n.inject(n.serverLink, b)
+	n.exchange()
+}
+
+// advance moves the clock forward by d in small steps, exchanging packets
+// after each.
+func (n *network) advance(d time.Duration) {
+	const step = 100 * time.Millisecond
+	for ; d > 0; d -= step {
+		n.clock.Advance(min(d, step))
+		n.exchange()
+	}
+}
+
+// advanceUntil advances the clock until cond returns true, failing the test
+// if it doesn't within limit.
+func (n *network) advanceUntil(limit time.Duration, cond func() bool) {
+	n.t.Helper()
+	const step = 100 * time.Millisecond
+	for elapsed := time.Duration(0); !cond(); elapsed += step {
+		if elapsed >= limit {
+			n.t.Fatalf("condition not met after %s", limit)
+		}
+		n.advance(step)
+	}
+}
+
+// listen returns a listening one-to-one endpoint on the server.
+func (n *network) listen() *sctp.Endpoint {
+	n.t.Helper()
+	ep := n.newEndpoint(n.server)
+	if err := ep.Bind(tcpip.FullAddress{Port: serverPort}); err != nil {
+		n.t.Fatalf("Bind: %s", err)
+	}
+	if err := ep.Listen(10); err != nil {
+		n.t.Fatalf("Listen: %s", err)
+	}
+	return ep
+}
+
+func (n *network) newEndpoint(s *stack.Stack) *sctp.Endpoint {
+	n.t.Helper()
+	var wq waiter.Queue
+	ep, err := s.NewEndpoint(sctp.ProtocolNumber, ipv4.ProtocolNumber, &wq)
+	if err != nil {
+		n.t.Fatalf("NewEndpoint: %s", err)
+	}
+	e := ep.(*sctp.Endpoint)
+	n.t.Cleanup(e.Close)
+	return e
+}
+
+// connect returns a client endpoint connected to serverAddr, and its
+// accepted peer.
+func (n *network) connect() (client, server tcpip.Endpoint) {
+	n.t.Helper()
+	listener := n.listen()
+	client = n.newEndpoint(n.client)
+	addr := tcpip.FullAddress{Addr: serverAddr, Port: serverPort}
+	if err := client.Connect(addr); !errIs[*tcpip.ErrConnectStarted](err) {
+		n.t.Fatalf("Connect: got %v, want %s", err, &tcpip.ErrConnectStarted{})
+	}
+	n.exchange()
+	if err := client.Connect(addr); err != nil {
+		n.t.Fatalf("second Connect: %s", err)
+	}
+	server, _, err := listener.Accept(nil)
+	if err != nil {
+		n.t.Fatalf("Accept: %s", err)
+	}
+	n.t.Cleanup(server.Close)
+	return client, server
+}
+
+// status returns the SCTP_STATUS of ep.
+func status(t *testing.T, ep tcpip.Endpoint) tcpip.SCTPStatusOption {
+	t.Helper()
+	var s tcpip.SCTPStatusOption
+	if err := ep.GetSockOpt(&s); err != nil {
+		t.Fatalf("GetSockOpt(SCTPStatusOption): %s", err)
+	}
+	return s
+}
+
+// dataPayload returns the payload of the first DATA chunk of p, or nil.
+func dataPayload(p *packet) []byte {
+	if chunks := p.chunks(header.SCTPChunkData); len(chunks) > 0 {
+		return header.SCTPData(chunks[0]).Payload()
+	}
+	return nil
+}
+
+// expectMessages reads msgs from ep, in order.
+func expectMessages(t *testing.T, ep tcpip.Endpoint, msgs ...string) {
+	t.Helper()
+	for _, want := range msgs {
+		var buf bytes.Buffer
+		if _, err := ep.Read(&buf, tcpip.ReadOptions{}); err != nil {
+			t.Fatalf("Read: got %s, want message %q", err, want)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("got message %q, want %q", got, want)
+		}
+	}
+}
+
+func TestRetransmission(t *testing.T) {
+	n := newNetwork(t)
+	client, server := n.connect()
+
+	lost := 0
+	n.drop = func(p *packet) bool {
+		if p.toServer && string(dataPayload(p)) == "lost" && lost == 0 {
+			lost++
+			return true
+		}
+		return false
+	}
+	write(t, client, []byte("lost"), nil)
+	n.exchange()
+	if _, err := server.Read(&bytes.Buffer{}, tcpip.ReadOptions{}); !errIs[*tcpip.ErrWouldBlock](err) {
+		t.Fatalf("Read before retransmission: got %v, want %s", err, &tcpip.ErrWouldBlock{})
+	}
+	// The chunk is retransmitted once the RTO expires.
+	n.advance(sctp.DefaultRTOInitial + time.Second)
+	expectMessages(t, server, "lost")
+	if lost != 1 {
+		t.Errorf("lost %d packets, want 1", lost)
+	}
+	if s := status(t, client); s.UnackedData != 0 || s.PendingData != 0 {
+		t.Errorf("got %d unacked and %d pending chunks, want none", s.UnackedData, s.PendingData)
+	}
+}
+
+func TestSACKGapAndFastRetransmit(t *testing.T) {
+	n := newNetwork(t)
+	client, server := n.connect()
+
+	dropped := false
+	n.drop = func(p *packet) bool {
+		if p.toServer && string(dataPayload(p)) == "2" && !dropped {
+			dropped = true
+			return true
+		}
+		return false
+	}
+	msgs := []string{"1", "2", "3", "4", "5"}
+	for _, m := range msgs {
+		write(t, client, []byte(m), nil)
+	}
+	// Without advancing the clock, the three SACKs reporting the gap trigger
+	// a fast retransmission of the lost chunk.
+	n.exchange()
+	expectMessages(t, server, msgs...)
+
+	gaps := 0
+	for _, p := range n.sent {
+		for _, c := range p.chunks(header.SCTPChunkSack) {
+			blocks, ok := header.SCTPSack(c).GapAckBlocks()
+			if !ok {
+				t.Fatalf("malformed SACK chunk %x", []byte(c))
+			}
+			if len(blocks) == 0 {
+				continue
+			}
+			gaps++
+			// The chunks after the lost one are reported as received.
+			if want := (header.SCTPGapAckBlock{Start: 2, End: uint16(gaps) + 1}); blocks[0] != want {
+				t.Errorf("got gap ack block %+v, want %+v", blocks[0], want)
+			}
+		}
+	}
+	if gaps != 3 {
+		t.Errorf("got %d SACKs reporting a gap, want 3", gaps)
+	}
+}
+
+func TestHeartbeatPathFailure(t *testing.T) {
+	n := newNetwork(t)
+	client, server := n.connect()
+
+	// The server's second address is confirmed by a heartbeat.
+	n.advance(2 * sctp.DefaultRTOInitial)
+	if s := status(t, client); s.Primary.Addr.Addr != serverAddr || !s.Primary.Active {
+		t.Fatalf("got primary path %+v, want active path to %s", s.Primary, serverAddr)
+	}
+
+	// The primary address becomes unreachable while the association is
+	// idle. Unanswered heartbeats mark it inactive, and the association
+	// moves to the other address.
+	n.drop = func(p *packet) bool {
+		return p.toServer && p.dst == serverAddr
+	}
+	n.advanceUntil(15*time.Minute, func() bool {
+		return status(t, client).Primary.Addr.Addr == serverAddr2
+	})
+	heartbeats := 0
+	for _, p := range n.sent {
+		if p.toServer && p.dst == serverAddr && len(p.chunks(header.SCTPChunkHeartbeat)) > 0 {
+			heartbeats++
+		}
+		if p.toServer && len(p.chunks(header.SCTPChunkData)) > 0 {
+			t.Errorf("got DATA chunk on idle association")
+		}
+	}
+	if heartbeats <= 5 {
+		t.Errorf("path failed after %d heartbeats, want more than 5", heartbeats)
+	}
+	if got, want := sctp.EndpointState(client.State()), sctp.StateEstablished; got != want {
+		t.Errorf("got client state %s, want %s", got, want)
+	}
+
+	write(t, client, []byte("after failure"), nil)
+	n.exchange()
+	expectMessages(t, server, "after failure")
+}
+
+func TestMultihomingFailover(t *testing.T) {
+	n := newNetwork(t)
+	client, server := n.connect()
+	n.advance(2 * sctp.DefaultRTOInitial)
+
+	n.drop = func(p *packet) bool {
+		return p.toServer && p.dst == serverAddr
+	}
+	// Retransmissions go to the other address right away.
+	write(t, client, []byte("0"), nil)
+	n.exchange()
+	n.advance(sctp.DefaultRTOInitial + time.Second)
+	expectMessages(t, server, "0")
+	var retransmitted *packet
+	for _, p := range n.sent {
+		if string(dataPayload(p)) == "0" && p.dst == serverAddr2 {
+			retransmitted = p
+		}
+	}
+	if retransmitted == nil {
+		t.Errorf("DATA chunk wasn't retransmitted to %s", serverAddr2)
+	}
+
+	// Data keeps flowing while new chunks time out on the primary address,
+	// until it's given up on.
+	for i := 1; status(t, client).Primary.Addr.Addr == serverAddr; i++ {
+		if i > 20 {
+			t.Fatalf("primary address %s still in use after %d messages", serverAddr, i)
+		}
+		m := string(rune('0' + i))
+		write(t, client, []byte(m), nil)
+		n.exchange()
+		n.advanceUntil(2*sctp.DefaultRTOMax, func() bool {
+			return status(t, client).UnackedData == 0
+		})
+		expectMessages(t, server, m)
+	}
+	if s := status(t, client); s.Primary.Addr.Addr != serverAddr2 || !s.Primary.Active {
+		t.Errorf("got primary path %+v, want active path to %s", s.Primary, serverAddr2)
+	}
+
+	// Once failed over, data is delivered without retransmission.
+	write(t, client, []byte("direct"), nil)
+	n.exchange()
+	expectMessages(t, server, "direct")
+}
+
+// cookieEcho returns a packet echoing cookie with verification tag vtag.
+func cookieEcho(srcPort uint16, vtag uint32, cookie []byte) header.SCTP {
+	b := make([]byte, header.SCTPMinimumSize+header.SCTPChunkHeaderSize+header.SCTPPadded(len(cookie)))
+	h := header.SCTP(b)
+	h.EncodeCommonHeader(srcPort, serverPort, vtag)
+	header.EncodeSCTPChunkHeader(b[header.SCTPMinimumSize:], header.SCTPChunkCookieEcho, 0, len(cookie))
+	copy(b[header.SCTPMinimumSize+header.SCTPChunkHeaderSize:], cookie)
+	h.SetChecksum()
+	return h
+}
+
+// issueCookie starts a handshake from the client, keeping the server from
+// receiving the client's COOKIE ECHO. It returns the client's port, and the
+// server's tag and cookie from the INIT ACK.
+func issueCookie(t *testing.T, n *network) (port uint16, vtag uint32, cookie []byte) {
+	t.Helper()
+	n.drop = func(p *packet) bool {
+		return p.toServer && len(p.chunks(header.SCTPChunkCookieEcho)) > 0
+	}
+	client := n.newEndpoint(n.client)
+	if err := client.Connect(tcpip.FullAddress{Addr: serverAddr, Port: serverPort}); !errIs[*tcpip.ErrConnectStarted](err) {
+		t.Fatalf("Connect: got %v, want %s", err, &tcpip.ErrConnectStarted{})
+	}
+	n.exchange()
+	for _, p := range n.sent {
+		chunks := p.chunks(header.SCTPChunkInitAck)
+		if len(chunks) == 0 {
+			continue
+		}
+		initAck := header.SCTPInit(chunks[0])
+		params, ok := initAck.Parameters()
+		if !ok {
+			t.Fatalf("malformed INIT ACK chunk %x", []byte(initAck))
+		}
+		for _, prm := range params {
+			if prm.Type() == header.SCTPParamStateCookie {
+				return p.sctp.DestinationPort(), initAck.InitiateTag(), append([]byte(nil), prm.Value()...)
+			}
+		}
+	}
+	t.Fatalf("no INIT ACK with a state cookie sent")
+	return 0, 0, nil
+}
+
+func TestCookieValidation(t *testing.T) {
+	n := newNetwork(t)
+	listener := n.listen()
+	port, vtag, cookie := issueCookie(t, n)
+
+	forged := append([]byte(nil), cookie...)
+	forged[4] ^= 1
+	for _, test := range []struct {
+		name string
+		src  tcpip.Address
+		pkt  header.SCTP
+	}{
+		{name: "forged", src: clientAddr, pkt: cookieEcho(port, vtag, forged)},
+		{name: "truncated", src: clientAddr, pkt: cookieEcho(port, vtag, cookie[:len(cookie)-4])},
+		{name: "wrong address", src: spoofedAddr, pkt: cookieEcho(port, vtag, cookie)},
+		{name: "wrong port", src: clientAddr, pkt: cookieEcho(port+1, vtag, cookie)},
+		{name: "wrong tag", src: clientAddr, pkt: cookieEcho(port, vtag+1, cookie)},
+	} {
+		n.sent = nil
+		n.injectToServer(test.src, test.pkt)
+		if _, _, err := listener.Accept(nil); !errIs[*tcpip.ErrWouldBlock](err) {
+			t.Errorf("%s cookie: Accept got %v, want %s", test.name, err, &tcpip.ErrWouldBlock{})
+		}
+		// Invalid cookies are silently discarded.
+		for _, p := range n.sent {
+			if !p.toServer {
+				t.Errorf("%s cookie: got reply %x, want none", test.name, []byte(p.sctp))
+			}
+		}
+	}
+
+	n.injectToServer(clientAddr, cookieEcho(port, vtag, cookie))
+	if _, _, err := listener.Accept(nil); err != nil {
+		t.Errorf("valid cookie: Accept: %s", err)
+	}
+}
+
+func TestStaleCookie(t *testing.T) {
+	n := newNetwork(t)
+	listener := n.listen()
+	port, vtag, cookie := issueCookie(t, n)
+
+	// Keep the client from answering the ERROR chunk.
+	n.drop = func(p *packet) bool { return true }
+	n.advance(61 * time.Second)
+	// A stale cookie echoed from another address is discarded without
+	// reply.
+	n.sent = nil
+	n.injectToServer(spoofedAddr, cookieEcho(port, vtag, cookie))
+	if len(n.sent) != 0 {
+		t.Errorf("stale cookie from wrong address: got reply %x, want none", []byte(n.sent[0].sctp))
+	}
+
+	n.injectToServer(clientAddr, cookieEcho(port, vtag, cookie))
+	if _, _, err := listener.Accept(nil); !errIs[*tcpip.ErrWouldBlock](err) {
+		t.Errorf("Accept got %v, want %s", err, &tcpip.ErrWouldBlock{})
+	}
+	var staleness []byte
+	for _, p := range n.sent {
+		for _, c := range p.chunks(header.SCTPChunkError) {
+			if p.toServer || p.dst != clientAddr || p.sctp.VerificationTag() == 0 {
+				t.Errorf("got ERROR chunk in packet %x, want one to %s with the client's tag", []byte(p.sctp), clientAddr)
+			}
+			causes, ok := header.SCTPParameters(c.Value())
+			if !ok || len(causes) != 1 || header.SCTPErrorCause(causes[0].Type()) != header.SCTPCauseStaleCookie {
+				t.Fatalf("got ERROR chunk %x, want stale cookie error", []byte(c))
+			}
+			staleness = causes[0].Value()
+		}
+	}
+	if len(staleness) != 4 {
+		t.Fatalf("got staleness %x, want 4 bytes", staleness)
+	}
+	if got := time.Duration(binary.BigEndian.Uint32(staleness)) * time.Microsecond; got <= 0 || got > 2*time.Second {
+		t.Errorf("got staleness %s, want (0, 2s]", got)
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sctp
+
+import (
+	"encoding/binary"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+)
+
+// heartbeatInfoSize is the size of the heartbeat information we send: the
+// peer address (padded to an IPv6 address), its length, and the send time.
+const heartbeatInfoSize = header.IPv6AddressSize + 4 + 8
+
+// path is a transport address of the peer of an association. Each path has
+// its own round trip time estimate and is probed with heartbeats while
+// idle, so that the association can fail over to another address when the
+// primary becomes unreachable (RFC 9260 section 8.2).
+//
+// +stateify savable
+type path struct {
+	addr tcpip.Address
+
+	// active is false once the path exceeded pathMaxRetrans consecutive
+	// errors. It is set again by a successful heartbeat.
+	active bool
+
+	// confirmed is true once a packet or heartbeat acknowledgement was
+	// received from the address.
+	confirmed bool
+
+	errorCount int
+
+	// srtt and rttvar are the smoothed round trip time and its variation.
+	// measured is false until the first sample.
+	srtt     time.Duration
+	rttvar   time.Duration
+	measured bool
+	rto      time.Duration
+
+	// mtu is the maximum size of SCTP packets on the path.
+	mtu uint32
+
+	// hbTimer fires when the next heartbeat is due. hbOutstanding is true
+	// while a heartbeat awaits its acknowledgement.
+	hbTimer       timer
+	hbOutstanding bool
+}
+
+// addPath adds addr as a peer address, unless it is known already, and
+// returns its path.
+//
+// +checklocks:a.ep.mu
+func (a *association) addPath(addr tcpip.Address) *path {
+	if p := a.pathFor(addr); p != nil {
+		return p
+	}
+	p := &path{
+		addr:   addr,
+		active: true,
+		rto:    a.rtoInitial,
+		mtu:    a.routeMTU(addr),
+	}
+	a.paths = append(a.paths, p)
+	p.initTimer(a)
+	if a.state == StateEstablished {
+		p.startHeartbeats(a)
+	}
+	return p
+}
+
+// pathFor returns the path for addr, or nil.
+//
+// +checklocks:a.ep.mu
+func (a *association) pathFor(addr tcpip.Address) *path {
+	for _, p := range a.paths {
+		if p.addr == addr {
+			return p
+		}
+	}
+	return nil
+}
+
+// routeMTU returns the maximum size of SCTP packets sent to addr.
+//
+// +checklocks:a.ep.mu
+func (a *association) routeMTU(addr tcpip.Address) uint32 {
+	const defaultMTU = 1280
+	r, err := a.ep.stack.FindRoute(a.ep.BindNICID, a.localAddr, addr, a.netProto, false /* multicastLoop */)
+	if err != nil {
+		return defaultMTU
+	}
+	defer r.Release()
+	return r.MTU()
+}
+
+// maxDataPayload returns the largest amount of user data that fits in a
+// single DATA chunk on the primary path.
+//
+// +checklocks:a.ep.mu
+func (a *association) maxDataPayload() int {
+	mtu := a.primary.mtu
+	for _, p := range a.paths {
+		mtu = min(mtu, p.mtu)
+	}
+	n := (int(mtu) - header.SCTPMinimumSize - header.SCTPDataHeaderSize) &^ 3
+	if s := a.ep.maxSeg; s > 0 && s < n {
+		n = s
+	}
+	return n
+}
+
+// initTimer initializes the heartbeat timer of p.
+func (p *path) initTimer(a *association) {
+	p.hbTimer.init(a.ep.stack.Clock(), func() { a.onHeartbeatTimer(p) })
+}
+
+// startHeartbeats schedules the first heartbeat on p.
+//
+// +checklocks:a.ep.mu
+func (p *path) startHeartbeats(a *association) {
+	p.hbTimer.enable(p.heartbeatDelay(a))
+}
+
+// heartbeatDelay returns the time until the next heartbeat: the heartbeat
+// interval plus the path's RTO, jittered by +/- 50% of the RTO.
+//
+// +checklocks:a.ep.mu
+func (p *path) heartbeatDelay(a *association) time.Duration {
+	jitter := time.Duration(a.ep.stack.InsecureRNG().Int63n(int64(p.rto)+1)) - p.rto/2
+	if !p.confirmed {
+		// Unconfirmed addresses are probed right away (RFC 9260
+		// section 5.4).
+		return p.rto + jitter
+	}
+	return heartbeatInterval + p.rto + jitter
+}
+
+// updateRTT updates the RTO of p with a round trip time sample, as
+// described in RFC 9260 section 6.3.1.
+//
+// +checklocks:a.ep.mu
+func (p *path) updateRTT(a *association, r time.Duration) {
+	if !p.measured {
+		p.srtt = r
+		p.rttvar = r / 2
+		p.measured = true
+	} else {
+		// RTO.Alpha is 1/8 and RTO.Beta is 1/4.
+		diff := p.srtt - r
+		if diff < 0 {
+			diff = -diff
+		}
+		p.rttvar = (3*p.rttvar + diff) / 4
+		p.srtt = (7*p.srtt + r) / 8
+	}
+	p.rto = min(max(p.srtt+4*p.rttvar, a.rtoMin), a.rtoMax)
+}
+
+// onError records a retransmission timeout or unanswered heartbeat on p.
+//
+// +checklocks:a.ep.mu
+func (p *path) onError(a *association) {
+	p.errorCount++
+	a.errorCount++
+	if p.errorCount > pathMaxRetrans && p.active {
+		p.active = false
+		if p == a.primary {
+			a.failover()
+		}
+	}
+	if a.errorCount > associationMaxRetrans {
+		a.sendAbort(header.SCTPCauseProtocolViolation)
+		a.terminate(&tcpip.ErrTimeout{})
+	}
+}
+
+// onSuccess records an acknowledgement received for data or a heartbeat sent
+// on p.
+//
+// +checklocks:a.ep.mu
+func (p *path) onSuccess(a *association) {
+	p.errorCount = 0
+	p.confirmed = true
+	p.active = true
+	a.errorCount = 0
+}
+
+// failover picks a new primary path after the current one became inactive.
+//
+// +checklocks:a.ep.mu
+func (a *association) failover() {
+	for _, p := range a.paths {
+		if p.active {
+			a.primary = p
+			return
+		}
+	}
+}
+
+// alternatePath returns an active path other than p to retransmit on, or p
+// if there is none (RFC 9260 section 6.4).
+//
+// +checklocks:a.ep.mu
+func (a *association) alternatePath(p *path) *path {
+	for _, q := range a.paths {
+		if q != p && q.active && q.confirmed {
+			return q
+		}
+	}
+	return p
+}
+
+// onHeartbeatTimer sends a heartbeat on p.
+func (a *association) onHeartbeatTimer(p *path) {
+	e := a.ep
+	e.mu.Lock()
+	defer e.unlockAndFlush()
+	if !p.hbTimer.checkExpiration() || a.state == StateClosed {
+		return
+	}
+	if p.hbOutstanding {
+		// The previous heartbeat went unanswered.
+		p.onError(a)
+		if a.state == StateClosed {
+			return
+		}
+		p.rto = min(2*p.rto, a.rtoMax)
+	}
+	if a.snd.recentlySent(p) {
+		// Data is flowing on the path, so it doesn't need probing.
+		p.hbOutstanding = false
+	} else {
+		a.sendHeartbeat(p)
+		p.hbOutstanding = true
+	}
+	p.hbTimer.enable(p.heartbeatDelay(a))
+}
+
+// sendHeartbeat sends a HEARTBEAT chunk on p.
+//
+// +checklocks:a.ep.mu
+func (a *association) sendHeartbeat(p *path) {
+	var info [heartbeatInfoSize]byte
+	copy(info[:], p.addr.AsSlice())
+	binary.BigEndian.PutUint32(info[header.IPv6AddressSize:], uint32(p.addr.Len()))
+	binary.BigEndian.PutUint64(info[header.IPv6AddressSize+4:], uint64(a.ep.stack.Clock().NowMonotonic().Sub(tcpip.MonotonicTime{})))
+	param := header.AppendSCTPParameter(nil, header.SCTPParamHeartbeatInfo, info[:])
+
+	c := make([]byte, header.SCTPChunkHeaderSize+len(param))
+	header.EncodeSCTPChunkHeader(c, header.SCTPChunkHeartbeat, 0, len(param))
+	copy(c[header.SCTPChunkHeaderSize:], param)
+	pb := a.newPacketBuilder(p, a.peerTag)
+	pb.add(c)
+	pb.flush()
+}
+
+// handleHeartbeat answers a HEARTBEAT chunk with a HEARTBEAT ACK echoing
+// its information, sent to the address the heartbeat came from.
+//
+// +checklocks:a.ep.mu
+func (a *association) handleHeartbeat(in *inPacket, c []byte) {
+	ack := make([]byte, len(c))
+	copy(ack, c)
+	ack[0] = uint8(header.SCTPChunkHeartbeatAck)
+	ack[1] = 0
+	p := a.pathFor(in.id.RemoteAddress)
+	if p == nil {
+		p = a.primary
+	}
+	pb := a.newPacketBuilder(p, a.peerTag)
+	pb.add(ack)
+	pb.flush()
+}
+
+// handleHeartbeatAck processes a HEARTBEAT ACK chunk.
+//
+// +checklocks:a.ep.mu
+func (a *association) handleHeartbeatAck(c []byte) {
+	params, ok := header.SCTPParameters(c[header.SCTPChunkHeaderSize:])
+	if !ok || len(params) == 0 || params[0].Type() != header.SCTPParamHeartbeatInfo {
+		return
+	}
+	info := params[0].Value()
+	if len(info) != heartbeatInfoSize {
+		return
+	}
+	l := int(binary.BigEndian.Uint32(info[header.IPv6AddressSize:]))
+	if l != header.IPv4AddressSize && l != header.IPv6AddressSize {
+		return
+	}
+	p := a.pathFor(tcpip.AddrFromSlice(info[:l]))
+	if p == nil {
+		return
+	}
+	sent := tcpip.MonotonicTime{}.Add(time.Duration(binary.BigEndian.Uint64(info[header.IPv6AddressSize+4:])))
+	if rtt := a.ep.stack.Clock().NowMonotonic().Sub(sent); rtt >= 0 {
+		p.updateRTT(a, rtt)
+	}
+	p.hbOutstanding = false
+	p.onSuccess(a)
+}
+
+// info returns the path information exposed by SCTP_STATUS.
+//
+// +checklocks:a.ep.mu
+func (p *path) info(a *association) tcpip.SCTPPathInfo {
+	return tcpip.SCTPPathInfo{
+		Addr:   tcpip.FullAddress{Addr: p.addr, Port: a.peerPort},
+		Active: p.active,
+		CWnd:   a.snd.cwnd,
+		SRTT:   p.srtt,
+		RTO:    p.rto,
+		MTU:    p.mtu,
+	}
+}
@@ -0,0 +1,435 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sctp contains the implementation of the SCTP transport protocol
+// (RFC 9260).
+//
+// An Endpoint owns a local port and zero or more associations. One-to-one
+// style endpoints (SOCK_STREAM) have at most one association, and listening
+// one-to-one endpoints peel every new association off into a new endpoint
+// that is returned by Accept. One-to-many style endpoints (SOCK_SEQPACKET)
+// may hold any number of associations at once.
+//
+// Incoming packets are demultiplexed to associations by verification tag
+// rather than by address, since a multihomed peer may send from any of its
+// addresses.
+package sctp
+
+import (
+	"time"
+
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/header/parse"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/raw"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	// ProtocolNumber is the sctp protocol number.
+	ProtocolNumber = header.SCTPProtocolNumber
+
+	// DefaultSendBufferSize is the default size of the send buffer for an
+	// endpoint.
+	DefaultSendBufferSize = 208 << 10 // 208KiB
+
+	// DefaultReceiveBufferSize is the default size of the receive buffer for
+	// an endpoint.
+	DefaultReceiveBufferSize = 208 << 10 // 208KiB
+
+	// DefaultOutboundStreams is the default number of outbound streams
+	// requested when initiating an association.
+	DefaultOutboundStreams = 10
+
+	// MaxStreams is the maximum number of streams in either direction.
+	MaxStreams = 65535
+
+	// DefaultRTOInitial, DefaultRTOMin and DefaultRTOMax are the default
+	// retransmission timeout parameters, matching Linux.
+	DefaultRTOInitial = 3 * time.Second
+	DefaultRTOMin     = 1 * time.Second
+	DefaultRTOMax     = 60 * time.Second
+
+	// DefaultMaxInitRetransmits is the default number of INIT and COOKIE
+	// ECHO retransmissions before an association setup is aborted.
+	DefaultMaxInitRetransmits = 8
+
+	// DefaultMaxInitTimeout is the default maximum retransmission timeout
+	// of INIT chunks.
+	DefaultMaxInitTimeout = 60 * time.Second
+
+	// pathMaxRetrans is the number of consecutive retransmission timeouts
+	// or unanswered heartbeats after which a peer address is considered
+	// inactive.
+	pathMaxRetrans = 5
+
+	// associationMaxRetrans is the number of consecutive retransmission
+	// timeouts or unanswered heartbeats, across all paths, after which an
+	// association is aborted.
+	associationMaxRetrans = 10
+
+	// heartbeatInterval is the interval between heartbeats on idle paths,
+	// not counting the path's RTO.
+	heartbeatInterval = 30 * time.Second
+
+	// sackDelay is the maximum delay before acknowledging received DATA.
+	sackDelay = 200 * time.Millisecond
+
+	// validCookieLife is the lifetime of state cookies.
+	validCookieLife = 60 * time.Second
+
+	// maxBurst limits the number of packets sent at once in response to a
+	// single event.
+	maxBurst = 4
+)
+
+// protocol implements stack.TransportProtocol.
+//
+// +stateify savable
+type protocol struct {
+	stack *stack.Stack
+
+	// secret is the key used to sign state cookies.
+	secret [32]byte
+
+	mu sync.Mutex `state:"nosave"`
+
+	// assocs maps local verification tags to associations. It is protected
+	// by mu.
+	assocs map[uint32]*association
+
+	// nextAssocID is the identifier of the next association. It is
+	// protected by mu.
+	nextAssocID int32
+
+	sendMu sync.Mutex `state:"nosave"`
+
+	// sendQueue holds packets waiting to be written by the goroutine that
+	// is sending, if sending is true. They are protected by sendMu.
+	sendQueue []outPacket `state:"nosave"`
+	sending   bool        `state:"nosave"`
+}
+
+// Number returns the sctp protocol number.
+func (*protocol) Number() tcpip.TransportProtocolNumber {
+	return ProtocolNumber
+}
+
+// NewEndpoint creates a new sctp endpoint.
+func (p *protocol) NewEndpoint(netProto tcpip.NetworkProtocolNumber, waiterQueue *waiter.Queue) (tcpip.Endpoint, tcpip.Error) {
+	return newEndpoint(p, netProto, waiterQueue), nil
+}
+
+// NewRawEndpoint creates a new raw SCTP endpoint. It implements
+// stack.TransportProtocol.NewRawEndpoint.
+func (p *protocol) NewRawEndpoint(netProto tcpip.NetworkProtocolNumber, waiterQueue *waiter.Queue) (tcpip.Endpoint, tcpip.Error) {
+	return raw.NewEndpoint(p.stack, netProto, header.SCTPProtocolNumber, waiterQueue)
+}
+
+// MinimumPacketSize returns the minimum valid sctp packet size.
+func (*protocol) MinimumPacketSize() int {
+	return header.SCTPMinimumSize
+}
+
+// ParsePorts returns the source and destination ports stored in the given sctp
+// packet.
+func (*protocol) ParsePorts(v []byte) (src, dst uint16, err tcpip.Error) {
+	h := header.SCTP(v)
+	return h.SourcePort(), h.DestinationPort(), nil
+}
+
+// HandleUnknownDestinationPacket handles packets that are targeted at this
+// protocol but don't match any endpoint bound to their destination port.
+// These may still belong to an association of an accepted endpoint, whose
+// listener has since been closed.
+func (p *protocol) HandleUnknownDestinationPacket(id stack.TransportEndpointID, pkt *stack.PacketBuffer) stack.UnknownDestinationPacketDisposition {
+	if !p.deliver(nil /* ep */, id, pkt) {
+		return stack.UnknownDestinationPacketMalformed
+	}
+	return stack.UnknownDestinationPacketHandled
+}
+
+// SetOption implements stack.TransportProtocol.SetOption.
+func (*protocol) SetOption(tcpip.SettableTransportProtocolOption) tcpip.Error {
+	return &tcpip.ErrUnknownProtocolOption{}
+}
+
+// Option implements stack.TransportProtocol.Option.
+func (*protocol) Option(tcpip.GettableTransportProtocolOption) tcpip.Error {
+	return &tcpip.ErrUnknownProtocolOption{}
+}
+
+// Close implements stack.TransportProtocol.Close.
+func (*protocol) Close() {}
+
+// Wait implements stack.TransportProtocol.Wait.
+func (*protocol) Wait() {}
+
+// Pause implements stack.TransportProtocol.Pause.
+func (*protocol) Pause() {}
+
+// Resume implements stack.TransportProtocol.Resume.
+func (*protocol) Resume() {}
+
+// Restore implements stack.TransportProtocol.Restore.
+func (*protocol) Restore() {}
+
+// Parse implements stack.TransportProtocol.Parse.
+func (*protocol) Parse(pkt *stack.PacketBuffer) bool {
+	return parse.SCTP(pkt)
+}
+
+// addAssociation assigns a verification tag, unless a already has one, and
+// an association identifier to a, and makes it reachable by incoming
+// packets. It returns false if the tag of a is in use.
+func (p *protocol) addAssociation(a *association) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if a.localTag == 0 {
+		a.localTag = p.newTagLocked()
+	} else if _, ok := p.assocs[a.localTag]; ok {
+		return false
+	}
+	p.assocs[a.localTag] = a
+	p.nextAssocID++
+	if p.nextAssocID <= 0 {
+		p.nextAssocID = 1
+	}
+	a.id = p.nextAssocID
+	return true
+}
+
+// restoreAssociation makes a reachable by incoming packets after restore,
+// keeping its tag and identifier.
+func (p *protocol) restoreAssociation(a *association) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.assocs[a.localTag] = a
+	p.nextAssocID = max(p.nextAssocID, a.id)
+}
+
+// removeAssociation makes a unreachable by incoming packets.
+func (p *protocol) removeAssociation(a *association) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.assocs[a.localTag] == a {
+		delete(p.assocs, a.localTag)
+	}
+}
+
+// lookupAssociation returns the association with local verification tag
+// vtag, or nil.
+func (p *protocol) lookupAssociation(vtag uint32) *association {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.assocs[vtag]
+}
+
+// lookupReflected returns the association on the given ports whose peer
+// verification tag is vtag, or nil. It is used for chunks sent with the T
+// bit set.
+func (p *protocol) lookupReflected(localPort, peerPort uint16, vtag uint32) *association {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, a := range p.assocs {
+		if a.peerTag == vtag && a.localPort == localPort && a.peerPort == peerPort {
+			return a
+		}
+	}
+	return nil
+}
+
+// newTag returns a new, unused, nonzero verification tag.
+func (p *protocol) newTag() uint32 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.newTagLocked()
+}
+
+// +checklocks:p.mu
+func (p *protocol) newTagLocked() uint32 {
+	for {
+		tag := p.stack.InsecureRNG().Uint32()
+		if _, ok := p.assocs[tag]; tag != 0 && !ok {
+			return tag
+		}
+	}
+}
+
+// deliver processes an incoming packet. ep is the endpoint bound to the
+// destination port, or nil. It returns false if the packet is malformed.
+func (p *protocol) deliver(ep *Endpoint, id stack.TransportEndpointID, pkt *stack.PacketBuffer) bool {
+	b := make([]byte, 0, len(pkt.TransportHeader().Slice())+pkt.Data().Size())
+	b = append(b, pkt.TransportHeader().Slice()...)
+	b = append(b, pkt.Data().AsRange().ToSlice()...)
+	h := header.SCTP(b)
+	if len(h) < header.SCTPMinimumSize || !h.IsChecksumValid() {
+		return false
+	}
+	chunks, ok := h.Chunks()
+	if !ok {
+		return false
+	}
+	in := inPacket{
+		netProto: pkt.NetworkProtocolNumber,
+		id:       id,
+		nicID:    pkt.NICID,
+		vtag:     h.VerificationTag(),
+		chunks:   chunks,
+	}
+
+	// RFC 9260 section 6.10: INIT, INIT ACK and SHUTDOWN COMPLETE chunks
+	// must not be bundled with other chunks.
+	first := chunks[0]
+	switch first.Type() {
+	case header.SCTPChunkInit:
+		if len(chunks) != 1 || in.vtag != 0 {
+			return false
+		}
+		if ep != nil && ep.handleInit(&in, header.SCTPInit(first)) {
+			return true
+		}
+		p.sendOOTBAbort(&in, header.SCTPInit(first))
+		return true
+	case header.SCTPChunkAbort, header.SCTPChunkShutdownComplete:
+		if first.Flags()&header.SCTPFlagTagReflected != 0 {
+			if a := p.lookupReflected(id.LocalPort, id.RemotePort, in.vtag); a != nil {
+				a.ep.handlePacket(a, &in)
+			}
+			return true
+		}
+	}
+
+	if a := p.lookupAssociation(in.vtag); a != nil && a.localPort == id.LocalPort && a.peerPort == id.RemotePort {
+		a.ep.handlePacket(a, &in)
+		return true
+	}
+
+	if first.Type() == header.SCTPChunkCookieEcho && ep != nil && ep.handleCookieEcho(&in) {
+		return true
+	}
+
+	// This is an "out of the blue" packet (RFC 9260 section 8.4).
+	for _, c := range chunks {
+		switch c.Type() {
+		case header.SCTPChunkAbort, header.SCTPChunkShutdownComplete, header.SCTPChunkCookieAck:
+			return true
+		case header.SCTPChunkShutdownAck:
+			p.sendReflected(&in, header.SCTPChunkShutdownComplete)
+			return true
+		}
+	}
+	p.sendReflected(&in, header.SCTPChunkAbort)
+	return true
+}
+
+// sendOOTBAbort responds to an INIT that no endpoint accepts with an ABORT.
+func (p *protocol) sendOOTBAbort(in *inPacket, init header.SCTPInit) {
+	if len(init) < header.SCTPInitSize {
+		return
+	}
+	c := make([]byte, header.SCTPChunkHeaderSize)
+	header.EncodeSCTPChunkHeader(c, header.SCTPChunkAbort, 0, 0)
+	p.sendTo(in, init.InitiateTag(), c)
+}
+
+// sendReflected responds to an out of the blue packet with a chunk of type
+// typ carrying the packet's own verification tag.
+func (p *protocol) sendReflected(in *inPacket, typ header.SCTPChunkType) {
+	c := make([]byte, header.SCTPChunkHeaderSize)
+	header.EncodeSCTPChunkHeader(c, typ, header.SCTPFlagTagReflected, 0)
+	p.sendTo(in, in.vtag, c)
+}
+
+// sendTo sends chunks to the source of an incoming packet.
+func (p *protocol) sendTo(in *inPacket, vtag uint32, chunks []byte) {
+	b := make([]byte, header.SCTPMinimumSize+len(chunks))
+	h := header.SCTP(b)
+	h.EncodeCommonHeader(in.id.LocalPort, in.id.RemotePort, vtag)
+	copy(b[header.SCTPMinimumSize:], chunks)
+	h.SetChecksum()
+	p.send([]outPacket{{
+		nicID:    in.nicID,
+		netProto: in.netProto,
+		local:    in.id.LocalAddress,
+		remote:   in.id.RemoteAddress,
+		b:        b,
+	}})
+}
+
+// send writes packets. Packets sent in response to packets delivered
+// synchronously by writePacket, as happens over loopback, are queued and
+// written by the outermost call, so that the recursion depth stays bounded.
+// No endpoint lock may be held, since delivery may take any of them.
+func (p *protocol) send(out []outPacket) {
+	if len(out) == 0 {
+		return
+	}
+	p.sendMu.Lock()
+	p.sendQueue = append(p.sendQueue, out...)
+	if p.sending {
+		p.sendMu.Unlock()
+		return
+	}
+	p.sending = true
+	for len(p.sendQueue) > 0 {
+		o := p.sendQueue[0]
+		p.sendQueue[0] = outPacket{}
+		p.sendQueue = p.sendQueue[1:]
+		p.sendMu.Unlock()
+		_ = p.writePacket(o)
+		p.sendMu.Lock()
+	}
+	p.sendQueue = nil
+	p.sending = false
+	p.sendMu.Unlock()
+}
+
+// writePacket sends a fully formed SCTP packet.
+func (p *protocol) writePacket(o outPacket) tcpip.Error {
+	r, err := p.stack.FindRoute(o.nicID, o.local, o.remote, o.netProto, false /* multicastLoop */)
+	if err != nil {
+		return err
+	}
+	defer r.Release()
+
+	pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{
+		ReserveHeaderBytes: header.SCTPMinimumSize + int(r.MaxHeaderLength()),
+		Payload:            buffer.MakeWithData(o.b[header.SCTPMinimumSize:]),
+	})
+	defer pkt.DecRef()
+	copy(pkt.TransportHeader().Push(header.SCTPMinimumSize), o.b[:header.SCTPMinimumSize])
+	pkt.TransportProtocolNumber = ProtocolNumber
+	pkt.Owner = o.owner
+	return r.WritePacket(stack.NetworkHeaderParams{
+		Protocol: ProtocolNumber,
+		TTL:      r.DefaultTTL(),
+		TOS:      stack.DefaultTOS,
+	}, pkt)
+}
+
+// NewProtocol returns an SCTP transport protocol.
+func NewProtocol(s *stack.Stack) stack.TransportProtocol {
+	p := &protocol{
+		stack:  s,
+		assocs: make(map[uint32]*association),
+	}
+	if _, err := s.SecureRNG().Reader.Read(p.secret[:]); err != nil {
+		panic("failed to generate SCTP cookie secret: " + err.Error())
+	}
+	return p
+}
@@ -0,0 +1,356 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sctp_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/faketime"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/loopback"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/sctp"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	nicID      = 1
+	serverPort = 3868
+)
+
+var localAddr = tcpip.AddrFrom4([4]byte{127, 0, 0, 1})
+
+type testContext struct {
+	t     *testing.T
+	s     *stack.Stack
+	clock *faketime.ManualClock
+}
+
+func newTestContext(t *testing.T) *testContext {
+	t.Helper()
+	clock := faketime.NewManualClock()
+	s := stack.New(stack.Options{
+		NetworkProtocols:   []stack.NetworkProtocolFactory{ipv4.NewProtocol},
+		TransportProtocols: []stack.TransportProtocolFactory{sctp.NewProtocol},
+		Clock:              clock,
+	})
+	if err := s.CreateNIC(nicID, loopback.New()); err != nil {
+		t.Fatalf("CreateNIC(%d, _): %s", nicID, err)
+	}
+	protocolAddr := tcpip.ProtocolAddress{
+		Protocol:          ipv4.ProtocolNumber,
+		AddressWithPrefix: localAddr.WithPrefix(),
+	}
+	if err := s.AddProtocolAddress(nicID, protocolAddr, stack.AddressProperties{}); err != nil {
+		t.Fatalf("AddProtocolAddress(%d, %+v, {}): %s", nicID, protocolAddr, err)
+	}
+	s.SetRouteTable([]tcpip.Route{{Destination: header.IPv4EmptySubnet, NIC: nicID}})
+	t.Cleanup(func() {
+		s.Close()
+		s.Wait()
+	})
+	return &testContext{t: t, s: s, clock: clock}
+}
+
+func (c *testContext) newEndpoint(oneToMany bool) (*sctp.Endpoint, *waiter.Queue) {
+	c.t.Helper()
+	var wq waiter.Queue
+	ep, err := c.s.NewEndpoint(sctp.ProtocolNumber, ipv4.ProtocolNumber, &wq)
+	if err != nil {
+		c.t.Fatalf("NewEndpoint: %s", err)
+	}
+	e := ep.(*sctp.Endpoint)
+	if oneToMany {
+		e.SetOneToMany()
+	}
+	c.t.Cleanup(e.Close)
+	return e, &wq
+}
+
+func (c *testContext) listen(oneToMany bool) *sctp.Endpoint {
+	c.t.Helper()
+	ep, _ := c.newEndpoint(oneToMany)
+	if err := ep.Bind(tcpip.FullAddress{Port: serverPort}); err != nil {
+		c.t.Fatalf("Bind: %s", err)
+	}
+	if err := ep.Listen(10); err != nil {
+		c.t.Fatalf("Listen: %s", err)
+	}
+	return ep
+}
+
+// connect returns a connected one-to-one endpoint and its accepted peer.
+func (c *testContext) connect() (client, server tcpip.Endpoint) {
+	c.t.Helper()
+	listener := c.listen(false)
+	client, _ = c.newEndpoint(false)
+	addr := tcpip.FullAddress{Addr: localAddr, Port: serverPort}
+	if err := client.Connect(addr); !errIs[*tcpip.ErrConnectStarted](err) {
+		c.t.Fatalf("Connect: got %v, want %s", err, &tcpip.ErrConnectStarted{})
+	}
+	// Packets are delivered synchronously over loopback, so the handshake
+	// already completed.
+	if err := client.Connect(addr); err != nil {
+		c.t.Fatalf("second Connect: %s", err)
+	}
+	var peer tcpip.FullAddress
+	server, _, err := listener.Accept(&peer)
+	if err != nil {
+		c.t.Fatalf("Accept: %s", err)
+	}
+	c.t.Cleanup(server.Close)
+	local, _ := client.GetLocalAddress()
+	if peer.Port != local.Port {
+		c.t.Errorf("got accepted peer port %d, want %d", peer.Port, local.Port)
+	}
+	return client, server
+}
+
+// errIs returns true if err has type T.
+func errIs[T tcpip.Error](err tcpip.Error) bool {
+	_, ok := err.(T)
+	return ok
+}
+
+func write(t *testing.T, ep tcpip.Endpoint, b []byte, to *tcpip.FullAddress) {
+	t.Helper()
+	var r bytes.Reader
+	r.Reset(b)
+	n, err := ep.Write(&r, tcpip.WriteOptions{To: to})
+	if err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	if n != int64(len(b)) {
+		t.Fatalf("Write: got %d bytes, want %d", n, len(b))
+	}
+}
+
+func read(t *testing.T, ep tcpip.Endpoint) ([]byte, tcpip.FullAddress) {
+	t.Helper()
+	var buf bytes.Buffer
+	res, err := ep.Read(&buf, tcpip.ReadOptions{NeedRemoteAddr: true})
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	return buf.Bytes(), res.RemoteAddr
+}
+
+func TestHandshakeAndDataExchange(t *testing.T) {
+	c := newTestContext(t)
+	client, server := c.connect()
+
+	if got, want := sctp.EndpointState(client.State()), sctp.StateEstablished; got != want {
+		t.Errorf("got client state %s, want %s", got, want)
+	}
+	if got := client.Readiness(waiter.WritableEvents); got != waiter.WritableEvents {
+		t.Errorf("got client readiness %v, want %v", got, waiter.WritableEvents)
+	}
+
+	msgs := [][]byte{[]byte("CER"), []byte("CEA"), bytes.Repeat([]byte{'x'}, 1000)}
+	for _, m := range msgs {
+		write(t, client, m, nil)
+	}
+	// Messages keep their boundaries.
+	for _, want := range msgs {
+		if got, _ := read(t, server); !bytes.Equal(got, want) {
+			t.Errorf("got message %q, want %q", got, want)
+		}
+	}
+	if _, err := server.Read(&bytes.Buffer{}, tcpip.ReadOptions{}); !errIs[*tcpip.ErrWouldBlock](err) {
+		t.Errorf("Read on empty queue: got %v, want %s", err, &tcpip.ErrWouldBlock{})
+	}
+
+	write(t, server, []byte("reply"), nil)
+	if got, _ := read(t, client); string(got) != "reply" {
+		t.Errorf("got reply %q, want %q", got, "reply")
+	}
+
+	// Delayed SACKs acknowledge everything once the timer fires.
+	c.clock.Advance(time.Second)
+	for _, ep := range []tcpip.Endpoint{client, server} {
+		var status tcpip.SCTPStatusOption
+		if err := ep.GetSockOpt(&status); err != nil {
+			t.Fatalf("GetSockOpt(SCTPStatusOption): %s", err)
+		}
+		if status.UnackedData != 0 || status.PendingData != 0 {
+			t.Errorf("got %d unacked and %d pending chunks, want none", status.UnackedData, status.PendingData)
+		}
+		if status.OutStreams != sctp.DefaultOutboundStreams || status.InStreams != sctp.DefaultOutboundStreams {
+			t.Errorf("got %d/%d streams, want %d", status.OutStreams, status.InStreams, sctp.DefaultOutboundStreams)
+		}
+	}
+}
+
+func TestFragmentation(t *testing.T) {
+	c := newTestContext(t)
+	client, server := c.connect()
+	if err := client.SetSockOptInt(tcpip.MaxSegOption, 512); err != nil {
+		t.Fatalf("SetSockOptInt(MaxSegOption, 512): %s", err)
+	}
+
+	msg := make([]byte, 20000)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	write(t, client, msg, nil)
+	for i := 0; i < 10 && server.Readiness(waiter.ReadableEvents) == 0; i++ {
+		c.clock.Advance(time.Second)
+	}
+	if got, _ := read(t, server); !bytes.Equal(got, msg) {
+		t.Errorf("got reassembled message of %d bytes, want %d bytes", len(got), len(msg))
+	}
+}
+
+func TestStreamsAndUnordered(t *testing.T) {
+	c := newTestContext(t)
+	client, server := c.connect()
+
+	opt := tcpip.SCTPDefaultSendInfoOption{Stream: 3, Unordered: true, PPID: 46}
+	if err := client.SetSockOpt(&opt); err != nil {
+		t.Fatalf("SetSockOpt(%+v): %s", opt, err)
+	}
+	write(t, client, []byte("unordered"), nil)
+	if got, _ := read(t, server); string(got) != "unordered" {
+		t.Errorf("got %q, want %q", got, "unordered")
+	}
+
+	opt = tcpip.SCTPDefaultSendInfoOption{Stream: sctp.DefaultOutboundStreams}
+	if err := client.SetSockOpt(&opt); err != nil {
+		t.Fatalf("SetSockOpt(%+v): %s", opt, err)
+	}
+	var r bytes.Reader
+	r.Reset([]byte("x"))
+	if _, err := client.Write(&r, tcpip.WriteOptions{}); !errIs[*tcpip.ErrInvalidOptionValue](err) {
+		t.Errorf("Write on invalid stream: got %v, want %s", err, &tcpip.ErrInvalidOptionValue{})
+	}
+}
+
+func TestOneToMany(t *testing.T) {
+	c := newTestContext(t)
+	server := c.listen(true)
+	to := tcpip.FullAddress{Addr: localAddr, Port: serverPort}
+
+	var clients []*sctp.Endpoint
+	for i := 0; i < 3; i++ {
+		ep, _ := c.newEndpoint(true)
+		write(t, ep, []byte{byte(i)}, &to)
+		clients = append(clients, ep)
+	}
+
+	for i := range clients {
+		got, from := read(t, server)
+		if len(got) != 1 || got[0] != byte(i) {
+			t.Fatalf("got message %v, want [%d]", got, i)
+		}
+		local, _ := clients[i].GetLocalAddress()
+		if from.Port != local.Port {
+			t.Errorf("got message from port %d, want %d", from.Port, local.Port)
+		}
+		write(t, server, []byte("ack"), &from)
+	}
+	for _, ep := range clients {
+		if got, _ := read(t, ep); string(got) != "ack" {
+			t.Errorf("got reply %q, want %q", got, "ack")
+		}
+	}
+	if _, _, err := server.Accept(nil); !errIs[*tcpip.ErrNotSupported](err) {
+		t.Errorf("Accept on one-to-many endpoint: got %v, want %s", err, &tcpip.ErrNotSupported{})
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	c := newTestContext(t)
+	client, server := c.connect()
+
+	write(t, client, []byte("last"), nil)
+	client.Close()
+	c.clock.Advance(time.Second)
+
+	if got, _ := read(t, server); string(got) != "last" {
+		t.Errorf("got %q, want %q", got, "last")
+	}
+	if _, err := server.Read(&bytes.Buffer{}, tcpip.ReadOptions{}); !errIs[*tcpip.ErrClosedForReceive](err) {
+		t.Errorf("Read after shutdown: got %v, want %s", err, &tcpip.ErrClosedForReceive{})
+	}
+	if got, want := sctp.EndpointState(server.State()), sctp.StateClosed; got != want {
+		t.Errorf("got server state %s, want %s", got, want)
+	}
+}
+
+func TestAbortOnCloseWithUnreadData(t *testing.T) {
+	c := newTestContext(t)
+	client, server := c.connect()
+
+	write(t, client, []byte("unread"), nil)
+	server.Close()
+
+	if _, err := client.Read(&bytes.Buffer{}, tcpip.ReadOptions{}); !errIs[*tcpip.ErrConnectionReset](err) {
+		t.Errorf("Read after peer abort: got %v, want %s", err, &tcpip.ErrConnectionReset{})
+	}
+}
+
+func TestConnectRefused(t *testing.T) {
+	c := newTestContext(t)
+	client, _ := c.newEndpoint(false)
+	addr := tcpip.FullAddress{Addr: localAddr, Port: serverPort}
+	if err := client.Connect(addr); !errIs[*tcpip.ErrConnectStarted](err) {
+		t.Fatalf("Connect: got %v, want %s", err, &tcpip.ErrConnectStarted{})
+	}
+	if err := client.Connect(addr); !errIs[*tcpip.ErrConnectionRefused](err) {
+		t.Errorf("second Connect: got %v, want %s", err, &tcpip.ErrConnectionRefused{})
+	}
+}
+
+func TestSockOpts(t *testing.T) {
+	c := newTestContext(t)
+	ep, _ := c.newEndpoint(false)
+
+	rto := tcpip.SCTPRTOInfoOption{Initial: 2 * time.Second, Min: 500 * time.Millisecond}
+	if err := ep.SetSockOpt(&rto); err != nil {
+		t.Fatalf("SetSockOpt(%+v): %s", rto, err)
+	}
+	var gotRTO tcpip.SCTPRTOInfoOption
+	if err := ep.GetSockOpt(&gotRTO); err != nil {
+		t.Fatalf("GetSockOpt(SCTPRTOInfoOption): %s", err)
+	}
+	if want := (tcpip.SCTPRTOInfoOption{Initial: 2 * time.Second, Min: 500 * time.Millisecond, Max: sctp.DefaultRTOMax}); gotRTO != want {
+		t.Errorf("got %+v, want %+v", gotRTO, want)
+	}
+	bad := tcpip.SCTPRTOInfoOption{Min: time.Hour}
+	if err := ep.SetSockOpt(&bad); !errIs[*tcpip.ErrInvalidOptionValue](err) {
+		t.Errorf("SetSockOpt(%+v): got %v, want %s", bad, err, &tcpip.ErrInvalidOptionValue{})
+	}
+
+	initMsg := tcpip.SCTPInitMsgOption{NumOStreams: 32}
+	if err := ep.SetSockOpt(&initMsg); err != nil {
+		t.Fatalf("SetSockOpt(%+v): %s", initMsg, err)
+	}
+	var gotInit tcpip.SCTPInitMsgOption
+	if err := ep.GetSockOpt(&gotInit); err != nil {
+		t.Fatalf("GetSockOpt(SCTPInitMsgOption): %s", err)
+	}
+	if gotInit.NumOStreams != 32 || gotInit.MaxInStreams != sctp.MaxStreams {
+		t.Errorf("got %+v, want 32 outbound and %d inbound streams", gotInit, sctp.MaxStreams)
+	}
+
+	var status tcpip.SCTPStatusOption
+	if err := ep.GetSockOpt(&status); !errIs[*tcpip.ErrInvalidOptionValue](err) {
+		t.Errorf("GetSockOpt(SCTPStatusOption) without association: got %v, want %s", err, &tcpip.ErrInvalidOptionValue{})
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sctp
+
+import (
+	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+// EndpointState represents the state of an SCTP association. The values match
+// Linux's enum sctp_sstat_state.
+type EndpointState tcpip.EndpointState
+
+// Association states, from RFC 9260 section 4.
+const (
+	StateEmpty EndpointState = iota
+	StateClosed
+	StateCookieWait
+	StateCookieEchoed
+	StateEstablished
+	StateShutdownPending
+	StateShutdownSent
+	StateShutdownReceived
+	StateShutdownAckSent
+)
+
+// String implements fmt.Stringer.
+func (s EndpointState) String() string {
+	switch s {
+	case StateEmpty:
+		return "EMPTY"
+	case StateClosed:
+		return "CLOSED"
+	case StateCookieWait:
+		return "COOKIE-WAIT"
+	case StateCookieEchoed:
+		return "COOKIE-ECHOED"
+	case StateEstablished:
+		return "ESTABLISHED"
+	case StateShutdownPending:
+		return "SHUTDOWN-PENDING"
+	case StateShutdownSent:
+		return "SHUTDOWN-SENT"
+	case StateShutdownReceived:
+		return "SHUTDOWN-RECEIVED"
+	case StateShutdownAckSent:
+		return "SHUTDOWN-ACK-SENT"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// handshaking returns true if the association is being set up.
+func (s EndpointState) handshaking() bool {
+	return s == StateCookieWait || s == StateCookieEchoed
+}
+
+// canSend returns true if new DATA may be transmitted in this state.
+func (s EndpointState) canSend() bool {
+	return s == StateEstablished || s == StateShutdownPending || s == StateShutdownReceived
+}
+
+// canReceive returns true if DATA may be accepted in this state.
+func (s EndpointState) canReceive() bool {
+	return s == StateEstablished || s == StateShutdownPending || s == StateShutdownSent
+}
+
+// endpointState is the socket-level state of an endpoint.
+type endpointState uint8
+
+const (
+	endpointInitial endpointState = iota
+	endpointBound
+	endpointListening
+	endpointConnecting
+	endpointConnected
+
+	// endpointDisconnected is the state of one-to-one endpoints whose
+	// association terminated.
+	endpointDisconnected
+	endpointClosed
+)
+
+// timer is a one-shot timer whose callback runs with the owning endpoint's
+// mutex held. Stale expirations, i.e. those of a clock timer that fired after
+// the timer was disabled or re-enabled, are filtered out by checkExpiration.
+//
+// +stateify savable
+type timer struct {
+	// enabled is true if the timer is armed.
+	enabled bool
+
+	// target is the expiration time. It is only meaningful if enabled is
+	// true.
+	target tcpip.MonotonicTime
+
+	clock    tcpip.Clock `state:"nosave"`
+	t        tcpip.Timer `state:"nosave"`
+	callback func()      `state:"nosave"`
+}
+
+// init initializes the timer. f is called when the clock timer fires, and
+// must call checkExpiration.
+func (t *timer) init(clock tcpip.Clock, f func()) {
+	t.clock = clock
+	t.callback = f
+}
+
+// enable arms the timer to fire after d.
+func (t *timer) enable(d time.Duration) {
+	t.enabled = true
+	t.target = t.clock.NowMonotonic().Add(d)
+	if t.t == nil {
+		t.t = t.clock.AfterFunc(d, t.callback)
+		return
+	}
+	t.t.Stop()
+	t.t.Reset(d)
+}
+
+// disable disarms the timer.
+func (t *timer) disable() {
+	t.enabled = false
+	if t.t != nil {
+		t.t.Stop()
+	}
+}
+
+// checkExpiration returns true if the timer has expired, and disarms it.
+func (t *timer) checkExpiration() bool {
+	if !t.enabled {
+		return false
+	}
+	if now := t.clock.NowMonotonic(); now.Before(t.target) {
+		t.t.Reset(t.target.Sub(now))
+		return false
+	}
+	t.enabled = false
+	return true
+}
+
+// resume re-arms the clock timer of an enabled timer after restore. The
+// remaining time is capped, since monotonic clocks are not comparable across
+// hosts.
+func (t *timer) resume(clock tcpip.Clock, f func()) {
+	t.init(clock, f)
+	if !t.enabled {
+		return
+	}
+	d := t.target.Sub(clock.NowMonotonic())
+	if d < 0 || d > DefaultRTOMax {
+		d = 0
+	}
+	t.enable(d)
+}
@@ -190,7 +190,7 @@ func (l *listenContext) createConnectingEndpoint(s *segment, rcvdSynOpts header.
 		netProto = s.pkt.NetworkProtocolNumber
 	}
 
-	route, err := l.stack.FindRoute(s.pkt.NICID, s.pkt.Network().DestinationAddress(), s.pkt.Network().SourceAddress(), s.pkt.NetworkProtocolNumber, false /* multicastLoop */)
+	route, err := findReplyRoute(l.stack, s)
 	if err != nil {
 		return nil, err // +checklocksignore
 	}
@@ -352,6 +352,17 @@ func (l *listenContext) performHandshake(s *segment, opts header.TCPSynOptions,
 	return ep, nil
 }
 
+// findReplyRoute returns a route for replying to the sender of s. Packets
+// diverted by the TPROXY target may be destined to a foreign address, which
+// is then used as the source of replies.
+func findReplyRoute(st *stack.Stack, s *segment) (*stack.Route, tcpip.Error) {
+	net := s.pkt.Network()
+	if s.pkt.NetworkPacketInfo.TransparentProxy {
+		return st.FindTransparentRoute(s.pkt.NICID, net.DestinationAddress(), net.SourceAddress(), s.pkt.NetworkProtocolNumber, false /* multicastLoop */)
+	}
+	return st.FindRoute(s.pkt.NICID, net.DestinationAddress(), net.SourceAddress(), s.pkt.NetworkProtocolNumber, false /* multicastLoop */)
+}
+
 // propagateInheritableOptionsLocked propagates any options set on the listening
 // endpoint to the newly created endpoint.
 //
@@ -363,6 +374,7 @@ func (e *Endpoint) propagateInheritableOptionsLocked(n *Endpoint) {
 	n.boundBindToDevice = e.boundBindToDevice
 	n.boundPortFlags = e.boundPortFlags
 	n.userMSS = e.userMSS
+	n.ops.SetIPTransparent(e.ops.GetIPTransparent())
 }
 
 // reserveTupleLocked reserves an accepted endpoint's tuple.
@@ -499,7 +511,7 @@ func (e *Endpoint) handleListenSegment(ctx *listenContext, s *segment) tcpip.Err
 		}
 
 		net := s.pkt.Network()
-		route, err := e.stack.FindRoute(s.pkt.NICID, net.DestinationAddress(), net.SourceAddress(), s.pkt.NetworkProtocolNumber, false /* multicastLoop */)
+		route, err := findReplyRoute(e.stack, s)
 		if err != nil {
 			return err
 		}
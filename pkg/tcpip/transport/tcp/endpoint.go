@@ -2439,7 +2439,11 @@ func (e *Endpoint) connect(addr tcpip.FullAddress, handshake bool) tcpip.Error {
 	}
 
 	// Find a route to the desired destination.
-	r, err := e.stack.FindRoute(nicID, e.TransportEndpointInfo.ID.LocalAddress, addr.Addr, netProto, false /* multicastLoop */)
+	findRoute := e.stack.FindRoute
+	if e.ops.GetIPTransparent() {
+		findRoute = e.stack.FindTransparentRoute
+	}
+	r, err := findRoute(nicID, e.TransportEndpointInfo.ID.LocalAddress, addr.Addr, netProto, false /* multicastLoop */)
 	if err != nil {
 		return err
 	}
@@ -2777,7 +2781,11 @@ func (e *Endpoint) bindLocked(addr tcpip.FullAddress) (err tcpip.Error) {
 	if addr.Addr.Len() != 0 {
 		nic = e.stack.CheckLocalAddress(addr.NIC, netProto, addr.Addr)
 		if nic == 0 {
-			return &tcpip.ErrBadLocalAddress{}
+			// Transparent sockets may bind to foreign addresses.
+			if !e.ops.GetIPTransparent() {
+				return &tcpip.ErrBadLocalAddress{}
+			}
+			nic = addr.NIC
 		}
 		e.TransportEndpointInfo.ID.LocalAddress = addr.Addr
 	}
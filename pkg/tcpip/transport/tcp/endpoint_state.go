@@ -26,6 +26,7 @@ import (
 	"gvisor.dev/gvisor/pkg/tcpip/ports"
 	"gvisor.dev/gvisor/pkg/tcpip/seqnum"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/waiter"
 )
 
 // logDisconnectOnce ensures we don't spam logs when many connections are terminated.
@@ -211,7 +212,10 @@ func (e *Endpoint) Restore(s *stack.Stack) {
 		e.mu.Lock()
 		err := e.connect(tcpip.FullAddress{NIC: e.boundNICID, Addr: e.connectingAddress, Port: e.TransportEndpointInfo.ID.RemotePort}, false /* handshake */)
 		if _, ok := err.(*tcpip.ErrConnectStarted); !ok {
-			panic("endpoint connecting failed: " + err.String())
+			e.resetRestoredLocked(err)
+			e.mu.Unlock()
+			connectedLoading.Done()
+			break
 		}
 		e.state.Store(e.origEndpointState)
 		// For FIN-WAIT-2 and TIME-WAIT we need to start the appropriate timers so
@@ -274,7 +278,9 @@ func (e *Endpoint) Restore(s *stack.Stack) {
 			bind()
 			err := e.Connect(tcpip.FullAddress{NIC: e.boundNICID, Addr: e.connectingAddress, Port: e.TransportEndpointInfo.ID.RemotePort})
 			if _, ok := err.(*tcpip.ErrConnectStarted); !ok {
-				panic("endpoint connecting failed: " + err.String())
+				e.mu.Lock()
+				e.resetRestoredLocked(err)
+				e.mu.Unlock()
 			}
 			connectingLoading.Done()
 			tcpip.AsyncLoading.Done()
@@ -293,7 +299,10 @@ func (e *Endpoint) Restore(s *stack.Stack) {
 			e.setEndpointState(epState)
 			r, err := e.stack.FindRoute(e.boundNICID, e.TransportEndpointInfo.ID.LocalAddress, e.TransportEndpointInfo.ID.RemoteAddress, e.effectiveNetProtos[0], false /* multicastLoop */)
 			if err != nil {
-				panic(fmt.Sprintf("FindRoute failed when restoring endpoint w/ ID: %+v", e.ID))
+				e.resetRestoredLocked(err)
+				connectingLoading.Done()
+				tcpip.AsyncLoading.Done()
+				return
 			}
 			e.route = r
 			timer, err := newBackoffTimer(e.stack.Clock(), InitialRTO, MaxRTO, timerHandler(e, e.h.retransmitHandlerLocked))
@@ -325,6 +334,24 @@ func (e *Endpoint) Restore(s *stack.Stack) {
 	}
 }
 
+// resetRestoredLocked resets the endpoint during restore, because its
+// connection couldn't be restored on the stack's NICs due to err, e.g. since
+// the sandbox was restored with a different network configuration. No RST is
+// sent, since the peer is unreachable from the endpoint's address.
+//
+// +checklocks:e.mu
+func (e *Endpoint) resetRestoredLocked(err tcpip.Error) {
+	id := e.TransportEndpointInfo.ID
+	log.Infof("tcp: resetting restored endpoint %+v: %v", id, err)
+	e.resetConnectionLocked(&tcpip.ErrConnectionReset{})
+	e.waiterQueue.Notify(waiter.EventHUp | waiter.EventErr | waiter.ReadableEvents | waiter.WritableEvents)
+	e.stack.RecordRestoreReset(stack.RestoreResetEndpoint{
+		TransProto: ProtocolNumber,
+		ID:         id,
+		Err:        err,
+	})
+}
+
 // Resume implements tcpip.ResumableEndpoint.Resume.
 func (e *Endpoint) Resume() {
 	e.segmentQueue.thaw()
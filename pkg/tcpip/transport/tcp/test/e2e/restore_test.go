@@ -0,0 +1,218 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gvisor.dev/gvisor/pkg/refs"
+	"gvisor.dev/gvisor/pkg/state"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/loopback"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	nicID      = 1
+	listenPort = 8080
+)
+
+var (
+	stackAddr   = tcpip.AddrFrom4([4]byte{10, 0, 0, 1})
+	changedAddr = tcpip.AddrFrom4([4]byte{10, 0, 0, 2})
+)
+
+// newStack returns a stack with a loopback NIC that has addr assigned. If
+// route is true, the NIC is the default route.
+func newStack(t *testing.T, addr tcpip.Address, route bool) *stack.Stack {
+	t.Helper()
+	s := stack.New(stack.Options{
+		NetworkProtocols:   []stack.NetworkProtocolFactory{ipv4.NewProtocol},
+		TransportProtocols: []stack.TransportProtocolFactory{tcp.NewProtocol},
+	})
+	t.Cleanup(func() {
+		s.Close()
+		s.Wait()
+	})
+	if err := s.CreateNIC(nicID, loopback.New()); err != nil {
+		t.Fatalf("CreateNIC(%d, _): %s", nicID, err)
+	}
+	protocolAddr := tcpip.ProtocolAddress{
+		Protocol:          ipv4.ProtocolNumber,
+		AddressWithPrefix: addr.WithPrefix(),
+	}
+	if err := s.AddProtocolAddress(nicID, protocolAddr, stack.AddressProperties{}); err != nil {
+		t.Fatalf("AddProtocolAddress(%d, %+v, {}): %s", nicID, protocolAddr, err)
+	}
+	if route {
+		s.SetRouteTable([]tcpip.Route{{Destination: header.IPv4EmptySubnet, NIC: nicID}})
+	}
+	return s
+}
+
+// connectedEndpoint returns an endpoint in s that is connected to a listener
+// in s.
+func connectedEndpoint(t *testing.T, s *stack.Stack) *tcp.Endpoint {
+	t.Helper()
+	var lwq waiter.Queue
+	lep, err := s.NewEndpoint(tcp.ProtocolNumber, ipv4.ProtocolNumber, &lwq)
+	if err != nil {
+		t.Fatalf("NewEndpoint(): %s", err)
+	}
+	t.Cleanup(lep.Close)
+	if err := lep.Bind(tcpip.FullAddress{Addr: stackAddr, Port: listenPort}); err != nil {
+		t.Fatalf("Bind(): %s", err)
+	}
+	if err := lep.Listen(1); err != nil {
+		t.Fatalf("Listen(): %s", err)
+	}
+
+	var wq waiter.Queue
+	ep, err := s.NewEndpoint(tcp.ProtocolNumber, ipv4.ProtocolNumber, &wq)
+	if err != nil {
+		t.Fatalf("NewEndpoint(): %s", err)
+	}
+	we, ch := waiter.NewChannelEntry(waiter.WritableEvents)
+	wq.EventRegister(&we)
+	defer wq.EventUnregister(&we)
+	switch err := ep.Connect(tcpip.FullAddress{Addr: stackAddr, Port: listenPort}); err.(type) {
+	case *tcpip.ErrConnectStarted:
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for connection")
+		}
+	case nil:
+	default:
+		t.Fatalf("Connect(): %s", err)
+	}
+	if got := tcp.EndpointState(ep.State()); got != tcp.StateEstablished {
+		t.Fatalf("got endpoint state %s, want %s", got, tcp.StateEstablished)
+	}
+	return ep.(*tcp.Endpoint)
+}
+
+// saveAndRestore saves ep and restores it in s, returning the restored
+// endpoint.
+func saveAndRestore(t *testing.T, ep *tcp.Endpoint, s *stack.Stack) *tcp.Endpoint {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := state.Save(context.Background(), &buf, &ep); err != nil {
+		t.Fatalf("state.Save(): %v", err)
+	}
+	ep.Close()
+
+	var restored *tcp.Endpoint
+	ctx := context.WithValue(context.Background(), stack.CtxRestoreStack, s)
+	if _, err := state.Load(ctx, &buf, &restored); err != nil {
+		t.Fatalf("state.Load(): %v", err)
+	}
+	s.Restore()
+	tcpip.AsyncLoading.Wait()
+	t.Cleanup(restored.Close)
+	return restored
+}
+
+func TestRestoreConnectedEndpointResets(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		// addr is the address of the stack that the endpoint is restored
+		// in.
+		addr tcpip.Address
+		// route is whether that stack has a route to the endpoint's peer.
+		route   bool
+		wantErr tcpip.Error
+	}{
+		{
+			name:    "changed local address",
+			addr:    changedAddr,
+			route:   true,
+			wantErr: &tcpip.ErrHostUnreachable{},
+		},
+		{
+			name:    "missing route",
+			addr:    stackAddr,
+			route:   false,
+			wantErr: &tcpip.ErrHostUnreachable{},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ep := connectedEndpoint(t, newStack(t, stackAddr, true /* route */))
+			id := ep.Info().(*stack.TransportEndpointInfo).ID
+
+			s := newStack(t, test.addr, test.route)
+			restored := saveAndRestore(t, ep, s)
+
+			if got := tcp.EndpointState(restored.State()); got != tcp.StateError {
+				t.Errorf("got restored endpoint state %s, want %s", got, tcp.StateError)
+			}
+			if d := cmp.Diff(&tcpip.ErrConnectionReset{}, restored.LastError()); d != "" {
+				t.Errorf("restored endpoint LastError() mismatch (-want +got):\n%s", d)
+			}
+
+			// The listener's end of the connection is restored and reset
+			// along with the endpoint, since they share the stack.
+			var found bool
+			for _, r := range s.RestoreResets() {
+				if r.ID != id {
+					continue
+				}
+				found = true
+				if r.TransProto != tcp.ProtocolNumber {
+					t.Errorf("got reset TransProto = %d, want %d", r.TransProto, tcp.ProtocolNumber)
+				}
+				if d := cmp.Diff(test.wantErr, r.Err); d != "" {
+					t.Errorf("reset Err mismatch (-want +got):\n%s", d)
+				}
+			}
+			if !found {
+				t.Errorf("got RestoreResets() = %+v, want reset of %+v", s.RestoreResets(), id)
+			}
+		})
+	}
+}
+
+func TestRestoreConnectedEndpoint(t *testing.T) {
+	ep := connectedEndpoint(t, newStack(t, stackAddr, true /* route */))
+
+	s := newStack(t, stackAddr, true /* route */)
+	restored := saveAndRestore(t, ep, s)
+
+	if got := tcp.EndpointState(restored.State()); got != tcp.StateEstablished {
+		t.Errorf("got restored endpoint state %s, want %s", got, tcp.StateEstablished)
+	}
+	if resets := s.RestoreResets(); len(resets) != 0 {
+		t.Errorf("got RestoreResets() = %+v, want none", resets)
+	}
+}
+
+func TestMain(m *testing.M) {
+	refs.SetLeakMode(refs.LeaksPanic)
+	code := m.Run()
+	// Allow TCP async work to complete to avoid false reports of leaks.
+	// TODO(gvisor.dev/issue/5940): Use fake clock in tests.
+	time.Sleep(1 * time.Second)
+	refs.DoLeakCheck()
+	os.Exit(code)
+}
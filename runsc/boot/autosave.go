@@ -26,7 +26,6 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/strace"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/runsc/specutils"
-	"gvisor.dev/gvisor/runsc/version"
 )
 
 func getTargetForSaveResume(l *Loader) func(k *kernel.Kernel) {
@@ -39,8 +38,9 @@ func getTargetForSaveResume(l *Loader) func(k *kernel.Kernel) {
 			Autosave:    true,
 			Resume:      true,
 			Destination: &buf,
-			Metadata:    map[string]string{VersionKey: version.Version()},
+			Metadata:    make(map[string]string),
 		}
+		addVersionMetadata(saveOpts.Metadata)
 		specsStr, err := specutils.ConvertSpecsToString(l.GetContainerSpecs())
 		if err != nil {
 			panic(fmt.Sprintf("error to get container specs from metadata, %v", err))
@@ -62,8 +62,9 @@ func getTargetForSaveRestore(l *Loader, files []*fd.FD) func(k *kernel.Kernel) {
 				Autosave:    true,
 				Resume:      false,
 				Destination: files[0],
-				Metadata:    map[string]string{VersionKey: version.Version()},
+				Metadata:    make(map[string]string),
 			}
+			addVersionMetadata(saveOpts.Metadata)
 			if len(files) == 3 {
 				saveOpts.PagesMetadata = files[1]
 				saveOpts.PagesFile = files[2]
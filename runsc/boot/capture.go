@@ -0,0 +1,200 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/bpf"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/sniffer"
+	"gvisor.dev/gvisor/pkg/urpc"
+)
+
+// StartCaptureArgs are arguments to StartCapture.
+type StartCaptureArgs struct {
+	// FilePayload contains the destination of the pcapng stream.
+	urpc.FilePayload
+
+	// NICs are the names of the NICs to capture. If empty, all NICs are
+	// captured.
+	NICs []string
+
+	// Filter selects the packets to capture. If empty, all packets are
+	// captured.
+	Filter []bpf.Instruction
+
+	// SnapLen is the maximum number of bytes captured from each packet.
+	SnapLen uint32
+
+	// Duration is the maximum duration of the capture. If zero, the capture
+	// runs until it's stopped.
+	Duration time.Duration
+}
+
+// captureState tracks the running packet capture. Only one capture may run
+// at a time.
+type captureState struct {
+	mu sync.Mutex
+
+	// stop is closed to stop the running capture. It's nil if no capture is
+	// running or the running capture was already stopped.
+	//
+	// +checklocks:mu
+	stop chan struct{}
+
+	// running is true while a capture is running.
+	//
+	// +checklocks:mu
+	running bool
+}
+
+func (s *captureState) start() (<-chan struct{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return nil, fmt.Errorf("a packet capture is already running")
+	}
+	s.running = true
+	s.stop = make(chan struct{})
+	return s.stop, nil
+}
+
+func (s *captureState) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	s.stop = nil
+}
+
+// StartCapture captures packets of the sandbox's NICs to a pcapng stream
+// written to the payload file. It returns once the capture is over: when
+// args.Duration elapses, StopCapture is called, or the stream's reader goes
+// away.
+func (n *Network) StartCapture(args *StartCaptureArgs, _ *struct{}) error {
+	if len(args.FilePayload.Files) != 1 {
+		return fmt.Errorf("packet capture requires exactly one file, got %d", len(args.FilePayload.Files))
+	}
+	output := args.FilePayload.Files[0]
+	defer output.Close()
+
+	if n.Stack == nil || n.capture == nil {
+		return fmt.Errorf("packet capture is only supported with netstack")
+	}
+	var filter *sniffer.Filter
+	if len(args.Filter) > 0 {
+		var err error
+		if filter, err = sniffer.NewFilter(args.Filter); err != nil {
+			return err
+		}
+	}
+
+	// Select the NICs to capture, in ID order so that interfaces appear in
+	// the capture as they do in the sandbox.
+	nics := n.Stack.NICInfo()
+	wanted := make(map[string]bool)
+	for _, name := range args.NICs {
+		wanted[name] = true
+	}
+	var ids []tcpip.NICID
+	for id, info := range nics {
+		if len(args.NICs) == 0 || wanted[info.Name] {
+			ids = append(ids, id)
+			delete(wanted, info.Name)
+		}
+	}
+	for _, name := range args.NICs {
+		if wanted[name] {
+			return fmt.Errorf("unknown NIC %q", name)
+		}
+	}
+	slices.Sort(ids)
+
+	stop, err := n.capture.start()
+	if err != nil {
+		return err
+	}
+	defer n.capture.finish()
+
+	c, err := sniffer.NewCapture(output, sniffer.CaptureOptions{
+		Comment: "sandbox " + n.SandboxID,
+		SnapLen: args.SnapLen,
+		Filter:  filter,
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := c.AddNIC(id, nics[id].Name); err != nil {
+			c.Close()
+			return err
+		}
+	}
+	var registered []tcpip.NICID
+	for _, id := range ids {
+		if err := n.Stack.RegisterPacketEndpoint(id, header.EthernetProtocolAll, c); err != nil {
+			// The NIC was removed since NICInfo was called.
+			log.Warningf("Not capturing packets of NIC %q: %v", nics[id].Name, err)
+			continue
+		}
+		registered = append(registered, id)
+	}
+	log.Infof("Packet capture started on %d NICs", len(registered))
+
+	var timeout <-chan time.Time
+	if args.Duration > 0 {
+		timer := time.NewTimer(args.Duration)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-timeout:
+	case <-stop:
+	case <-c.Failed():
+	}
+
+	for _, id := range registered {
+		n.Stack.UnregisterPacketEndpoint(id, header.EthernetProtocolAll, c)
+	}
+	err = c.Close()
+	log.Infof("Packet capture stopped, %d packets dropped", c.Dropped())
+	if errors.Is(err, unix.EPIPE) {
+		// The reader stopped the capture by going away.
+		return nil
+	}
+	return err
+}
+
+// StopCapture stops the running packet capture.
+func (n *Network) StopCapture(_, _ *struct{}) error {
+	if n.capture == nil {
+		return fmt.Errorf("packet capture is only supported with netstack")
+	}
+	n.capture.mu.Lock()
+	defer n.capture.mu.Unlock()
+	if n.capture.stop == nil {
+		return fmt.Errorf("no packet capture is running")
+	}
+	close(n.capture.stop)
+	n.capture.stop = nil
+	return nil
+}
@@ -15,6 +15,7 @@
 package boot
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"path"
@@ -38,13 +39,13 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/socket/plugin"
 	"gvisor.dev/gvisor/pkg/sentry/state"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/state/statefile"
 	"gvisor.dev/gvisor/pkg/timing"
 	"gvisor.dev/gvisor/pkg/urpc"
 	"gvisor.dev/gvisor/runsc/boot/procfs"
 	"gvisor.dev/gvisor/runsc/config"
 	"gvisor.dev/gvisor/runsc/specutils"
 	"gvisor.dev/gvisor/runsc/starttime"
-	"gvisor.dev/gvisor/runsc/version"
 )
 
 const (
@@ -67,6 +68,9 @@ const (
 	// ContMgrPortForward starts port forwarding with the sandbox.
 	ContMgrPortForward = "containerManager.PortForward"
 
+	// ContMgrPreCopy copies memory for live migration while the sandbox runs.
+	ContMgrPreCopy = "containerManager.PreCopy"
+
 	// ContMgrProcesses lists processes running in a container.
 	ContMgrProcesses = "containerManager.Processes"
 
@@ -131,6 +135,12 @@ const (
 	// NetworkInitPluginStack initializes third-party network stack.
 	NetworkInitPluginStack = "Network.InitPluginStack"
 
+	// NetworkStartCapture captures packets to a pcapng stream.
+	NetworkStartCapture = "Network.StartCapture"
+
+	// NetworkStopCapture stops a capture started with NetworkStartCapture.
+	NetworkStopCapture = "Network.StopCapture"
+
 	// DebugStacks collects sandbox stacks for debugging.
 	DebugStacks = "debug.Stacks"
 )
@@ -142,6 +152,7 @@ const (
 	ProfileBlock = "Profile.Block"
 	ProfileMutex = "Profile.Mutex"
 	ProfileTrace = "Profile.Trace"
+	ProfileGuest = "Profile.Guest"
 )
 
 // Logging related commands (see logging.go for more details).
@@ -155,6 +166,11 @@ const (
 	UsageUsageFD = "Usage.UsageFD"
 )
 
+// Syscall stats related commands (see syscall_stats.go for more details).
+const (
+	SyscallStatsCollect = "SyscallStats.Collect"
+)
+
 // Metrics related commands (see metrics.go).
 const (
 	MetricsGetRegistered = "Metrics.GetRegisteredMetrics"
@@ -175,6 +191,10 @@ type controller struct {
 
 	// manager holds the containerManager methods.
 	manager *containerManager
+
+	// capture holds the state of runtime packet captures, which outlives
+	// handler refreshes.
+	capture captureState
 }
 
 // newController creates a new controller. The caller must call
@@ -205,14 +225,17 @@ func (c *controller) registerHandlers() {
 	c.srv.Register(&control.Logging{})
 	c.srv.Register(&control.Proc{Kernel: l.k})
 	c.srv.Register(&control.State{Kernel: l.k})
+	c.srv.Register(&control.SyscallStats{Kernel: l.k})
 	c.srv.Register(&control.Usage{Kernel: l.k})
 	c.srv.Register(&control.Metrics{})
 	c.srv.Register(&debug{})
 
 	if eps, ok := l.k.RootNetworkNamespace().Stack().(*netstack.Stack); ok {
 		c.srv.Register(&Network{
-			Stack:  eps.Stack,
-			Kernel: l.k,
+			Stack:     eps.Stack,
+			Kernel:    l.k,
+			SandboxID: l.sandboxID,
+			capture:   &c.capture,
 		})
 	}
 
@@ -457,6 +480,41 @@ func (cm *containerManager) Checkpoint(o *control.SaveOpts, _ *struct{}) error {
 	return cm.l.save(o)
 }
 
+// PreCopyOpts contains options for pre-copying memory.
+type PreCopyOpts struct {
+	// FilePayload contains the file that memory is written to.
+	urpc.FilePayload
+
+	// SaveID identifies the migration that memory is pre-copied for. Each call
+	// with the same SaveID only copies memory changed since the previous one,
+	// and a checkpoint with a matching pgalloc.SaveOpts.ParentSaveID only saves
+	// memory changed since the last call.
+	SaveID string
+}
+
+// PreCopy writes the sandbox's memory to the payload file without stopping
+// the sandbox, in the format written by pgalloc.MemoryFile.PreCopyTo. The
+// number of bytes of memory written is returned in sent.
+func (cm *containerManager) PreCopy(o *PreCopyOpts, sent *uint64) error {
+	log.Debugf("containerManager.PreCopy, save ID: %q", o.SaveID)
+	if len(o.Files) != 1 {
+		return fmt.Errorf("pre-copy requires exactly one file, got %d", len(o.Files))
+	}
+	f := o.Files[0]
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	n, err := cm.l.k.PreCopyMemory(cm.l.k.SupervisorContext(), w, o.SaveID)
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	*sent = n
+	return nil
+}
+
 // PortForwardOpts contains options for port forwarding to a port in a
 // container.
 type PortForwardOpts struct {
@@ -491,6 +549,10 @@ type RestoreOpts struct {
 	HavePagesFile  bool
 	HaveDeviceFile bool
 	Background     bool
+
+	// EncryptionKey is the key the checkpoint image was encrypted with, or nil
+	// if it isn't encrypted.
+	EncryptionKey []byte
 }
 
 // Restore loads a container from a statefile.
@@ -527,7 +589,7 @@ func (cm *containerManager) Restore(o *RestoreOpts, _ *struct{}) error {
 		return fmt.Errorf("statefile cannot be empty")
 	}
 
-	reader, metadata, err := state.NewStatefileReader(stateFile, nil)
+	reader, metadata, enc, err := state.NewEncryptedStatefileReader(stateFile, nil, o.EncryptionKey)
 	if err != nil {
 		return fmt.Errorf("creating statefile reader: %w", err)
 	}
@@ -566,8 +628,19 @@ func (cm *containerManager) Restore(o *RestoreOpts, _ *struct{}) error {
 		}
 		fileIdx++
 
+		pagesCodec, err := statefile.PagesCodec(metadata)
+		if err != nil {
+			return err
+		}
+
+		// Pages of checkpoints saved to a chunk store are read from its pack
+		// file, which the pages file links to.
+		_, pagesDeduplicated := metadata[CheckpointChunkStoreKey]
+		_, pagesSparse := metadata[CheckpointSparsePagesKey]
+
 		// This immediately starts loading the main MemoryFile asynchronously.
-		cm.restorer.asyncMFLoader = kernel.NewAsyncMFLoader(pagesMetadata, pagesFile, cm.restorer.mainMF, timer.Fork("PagesFileLoader"))
+		// Encrypted pages are keyed by the save ID of the checkpoint.
+		cm.restorer.asyncMFLoader = kernel.NewAsyncMFLoader(pagesMetadata, pagesFile, enc, metadata[CheckpointSaveIDKey], pagesCodec, pagesDeduplicated, pagesSparse, cm.restorer.mainMF, timer.Fork("PagesFileLoader"))
 	}
 
 	if o.HaveDeviceFile {
@@ -618,10 +691,11 @@ func (cm *containerManager) Restore(o *RestoreOpts, _ *struct{}) error {
 	}
 	cm.restorer.checkpointedSpecs = specs
 
-	checkpointVersion := metadata[VersionKey]
-	currentVersion := version.Version()
-	if checkpointVersion != currentVersion {
-		return fmt.Errorf("runsc version does not match across checkpoint restore, checkpoint: %v current: %v", checkpointVersion, currentVersion)
+	if err := checkVersionMetadata(metadata, cm.l.root.conf.RestoreAcrossVersions); err != nil {
+		return err
+	}
+	if _, ok := metadata[CheckpointParentSaveIDKey]; ok {
+		return fmt.Errorf("incremental checkpoints must be merged with their parents before restore")
 	}
 	return cm.restorer.restoreContainerInfo(cm.l, &cm.l.root, timer.Fork("cont:root"))
 }
@@ -837,15 +911,19 @@ type CreateTraceSessionArgs struct {
 // CreateTraceSession creates a new trace session.
 func (cm *containerManager) CreateTraceSession(args *CreateTraceSessionArgs, _ *struct{}) error {
 	log.Debugf("containerManager.CreateTraceSession: config: %+v", args.Config)
+	fds := make([]*fd.FD, len(args.Files))
 	for i, sinkFile := range args.Files {
 		if sinkFile != nil {
 			fd, err := fd.NewFromFile(sinkFile)
 			if err != nil {
 				return err
 			}
-			args.Config.Sinks[i].FD = fd
+			fds[i] = fd
 		}
 	}
+	if err := seccheck.SetSinkFDs(args.Config.Sinks, fds); err != nil {
+		return err
+	}
 	return seccheck.Create(&args.Config, args.Force)
 }
 
@@ -30,6 +30,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/devices/tpuproxy"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
 	"gvisor.dev/gvisor/pkg/sentry/socket/plugin"
+	"gvisor.dev/gvisor/pkg/sentry/socket/vsock"
 )
 
 // Options are seccomp filter related options.
@@ -37,6 +38,7 @@ type Options struct {
 	Platform              platform.SeccompInfo
 	HostNetwork           bool
 	HostNetworkRawSockets bool
+	HostNetworkTCPRepair  bool
 	HostFilesystem        bool
 	ProfileEnable         bool
 	NVProxy               bool
@@ -45,6 +47,8 @@ type Options struct {
 	ControllerFD          uint32
 	CgoEnabled            bool
 	PluginNetwork         bool
+	Vsock                 bool
+	VsockBrokerFD         int32
 }
 
 // isInstrumentationEnabled returns whether there are any
@@ -65,6 +69,7 @@ func (opt Options) ConfigKey() string {
 	sb.WriteString(fmt.Sprintf("Platform=%q ", opt.Platform.ConfigKey()))
 	sb.WriteString(fmt.Sprintf("HostNetwork=%t ", opt.HostNetwork))
 	sb.WriteString(fmt.Sprintf("HostNetworkRawSockets=%t ", opt.HostNetworkRawSockets))
+	sb.WriteString(fmt.Sprintf("HostNetworkTCPRepair=%t ", opt.HostNetworkTCPRepair))
 	sb.WriteString(fmt.Sprintf("HostFilesystem=%t ", opt.HostFilesystem))
 	sb.WriteString(fmt.Sprintf("ProfileEnable=%t ", opt.ProfileEnable))
 	sb.WriteString(fmt.Sprintf("Instrumentation=%t ", isInstrumentationEnabled()))
@@ -73,6 +78,7 @@ func (opt Options) ConfigKey() string {
 	sb.WriteString(fmt.Sprintf("TPUProxy=%t ", opt.TPUProxy))
 	sb.WriteString(fmt.Sprintf("CgoEnabled=%t ", opt.CgoEnabled))
 	sb.WriteString(fmt.Sprintf("PluginNetwork=%t ", opt.PluginNetwork))
+	sb.WriteString(fmt.Sprintf("Vsock=%t ", opt.Vsock))
 	return strings.TrimSpace(sb.String())
 }
 
@@ -108,6 +114,9 @@ func Warnings(opt Options) []string {
 	if opt.PluginNetwork {
 		warnings = append(warnings, "plugin network stack enabled: syscall filters less restrictive!")
 	}
+	if opt.Vsock {
+		warnings = append(warnings, "vsock enabled: syscall filters less restrictive!")
+	}
 	return warnings
 }
 
@@ -141,7 +150,7 @@ func rules(opt Options, vars precompiledseccomp.Values) (seccomp.SyscallRules, s
 	s.Merge(instrumentationFilters())
 
 	if opt.HostNetwork {
-		s.Merge(hostInetFilters(opt.HostNetworkRawSockets))
+		s.Merge(hostInetFilters(opt.HostNetworkRawSockets, opt.HostNetworkTCPRepair))
 	}
 	if opt.ProfileEnable {
 		s.Merge(profileFilters())
@@ -161,6 +170,9 @@ func rules(opt Options, vars precompiledseccomp.Values) (seccomp.SyscallRules, s
 	if opt.PluginNetwork {
 		s.Merge(plugin.SeccompFilters())
 	}
+	if opt.Vsock {
+		s.Merge(vsock.Filters(int(opt.VsockBrokerFD)))
+	}
 
 	s.Merge(opt.Platform.SyscallFilters(vars))
 	return s, seccomp.DenyNewExecMappings
@@ -113,6 +113,12 @@ func optionsToPrecompile() ([]Options, error) {
 			tpuProxyNo.TPUProxy = false
 			return []Options{tpuProxyYes, tpuProxyNo}, nil
 		},
+
+		// Only precompile options with vsock disabled.
+		func(opt Options) ([]Options, error) {
+			opt.Vsock = false
+			return []Options{opt}, nil
+		},
 	} {
 		var newOpts []Options
 		for _, opt := range opts {
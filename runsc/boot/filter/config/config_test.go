@@ -57,6 +57,11 @@ func TestIoctlFirstArgumentIsNonNegativeFD(t *testing.T) {
 			HostNetwork:           true,
 			HostNetworkRawSockets: true,
 		},
+		"host network with TCP repair": {
+			Platform:             (&systrap.Systrap{}).SeccompInfo(),
+			HostNetwork:          true,
+			HostNetworkTCPRepair: true,
+		},
 		"profiling": {
 			Platform:      (&systrap.Systrap{}).SeccompInfo(),
 			ProfileEnable: true,
@@ -65,6 +70,11 @@ func TestIoctlFirstArgumentIsNonNegativeFD(t *testing.T) {
 			Platform:       (&systrap.Systrap{}).SeccompInfo(),
 			HostFilesystem: true,
 		},
+		"vsock": {
+			Platform:      (&systrap.Systrap{}).SeccompInfo(),
+			Vsock:         true,
+			VsockBrokerFD: 10,
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			rules, _ := Rules(options)
@@ -106,6 +116,7 @@ func TestOptionsConfigKey(t *testing.T) {
 		},
 		"HostNetwork":           func(opt *Options) { opt.HostNetwork = !opt.HostNetwork },
 		"HostNetworkRawSockets": func(opt *Options) { opt.HostNetworkRawSockets = !opt.HostNetworkRawSockets },
+		"HostNetworkTCPRepair":  func(opt *Options) { opt.HostNetworkTCPRepair = !opt.HostNetworkTCPRepair },
 		"HostFilesystem":        func(opt *Options) { opt.HostFilesystem = !opt.HostFilesystem },
 		"ProfileEnable":         func(opt *Options) { opt.ProfileEnable = !opt.ProfileEnable },
 		"NVProxy":               func(opt *Options) { opt.NVProxy = !opt.NVProxy },
@@ -113,6 +124,7 @@ func TestOptionsConfigKey(t *testing.T) {
 		"TPUProxy":              func(opt *Options) { opt.TPUProxy = !opt.TPUProxy },
 		"CgoEnabled":            func(opt *Options) { opt.CgoEnabled = !opt.CgoEnabled },
 		"PluginNetwork":         func(opt *Options) { opt.PluginNetwork = !opt.PluginNetwork },
+		"Vsock":                 func(opt *Options) { opt.Vsock = !opt.Vsock },
 	}
 
 	// Map of `Options` struct field names mapped to a function to mutate them.
@@ -120,7 +132,8 @@ func TestOptionsConfigKey(t *testing.T) {
 	// filter generation; calling the mutation function of these should *not*
 	// change the value of `Options.Key`.
 	var varsFields = map[string]mutateFn{
-		"ControllerFD": func(opt *Options) { opt.ControllerFD++ },
+		"ControllerFD":  func(opt *Options) { opt.ControllerFD++ },
+		"VsockBrokerFD": func(opt *Options) { opt.VsockBrokerFD++ },
 	}
 
 	t.Run("fields are exhaustive", func(t *testing.T) {
@@ -169,3 +182,18 @@ func TestOptionsConfigKey(t *testing.T) {
 		}
 	})
 }
+
+// TestVsockCannotConnect verifies that enabling vsock does not allow the
+// sandbox to connect to host sockets by path.
+func TestVsockCannotConnect(t *testing.T) {
+	rules, _ := Rules(Options{
+		Platform:      (&systrap.Systrap{}).SeccompInfo(),
+		Vsock:         true,
+		VsockBrokerFD: 10,
+	})
+	for _, sysno := range []uintptr{unix.SYS_CONNECT, unix.SYS_SOCKET} {
+		if rules.Has(sysno) {
+			t.Errorf("vsock filters allow syscall %d: %v", sysno, rules.Get(sysno))
+		}
+	}
+}
@@ -15,6 +15,8 @@
 package config
 
 import (
+	"slices"
+
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/seccomp"
@@ -22,7 +24,7 @@ import (
 )
 
 // hostInetFilters contains syscalls that are needed by sentry/socket/hostinet.
-func hostInetFilters(allowRawSockets bool) seccomp.SyscallRules {
+func hostInetFilters(allowRawSockets, allowTCPRepair bool) seccomp.SyscallRules {
 	rules := seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
 		unix.SYS_ACCEPT4: seccomp.PerArg{
 			seccomp.AnyValue{},
@@ -143,7 +145,11 @@ func hostInetFilters(allowRawSockets bool) seccomp.SyscallRules {
 
 	// Generate rules for socket options based on hostinet's supported
 	// socket options.
-	for _, opt := range hostinet.SockOpts {
+	sockOpts := hostinet.SockOpts
+	if allowTCPRepair {
+		sockOpts = slices.Concat(sockOpts, hostinet.TCPRepairSockOpts)
+	}
+	for _, opt := range sockOpts {
 		if opt.AllowGet {
 			rules.Add(unix.SYS_GETSOCKOPT, seccomp.PerArg{
 				seccomp.AnyValue{},
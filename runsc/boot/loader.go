@@ -21,6 +21,7 @@ import (
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	gtime "time"
 
 	"github.com/moby/sys/capability"
@@ -68,12 +69,14 @@ import (
 	"gvisor.dev/gvisor/pkg/tcpip/link/ethernet"
 	"gvisor.dev/gvisor/pkg/tcpip/link/loopback"
 	"gvisor.dev/gvisor/pkg/tcpip/link/sniffer"
+	"gvisor.dev/gvisor/pkg/tcpip/link/tunnel"
 	"gvisor.dev/gvisor/pkg/tcpip/network/arp"
 	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
 	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 	"gvisor.dev/gvisor/pkg/tcpip/transport/icmp"
 	"gvisor.dev/gvisor/pkg/tcpip/transport/raw"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/sctp"
 	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
 	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
 	"gvisor.dev/gvisor/runsc/boot/filter"
@@ -93,6 +96,7 @@ import (
 	_ "gvisor.dev/gvisor/pkg/sentry/socket/netlink/route"
 	_ "gvisor.dev/gvisor/pkg/sentry/socket/netlink/uevent"
 	_ "gvisor.dev/gvisor/pkg/sentry/socket/unix"
+	"gvisor.dev/gvisor/pkg/sentry/socket/vsock"
 )
 
 // ContainerRuntimeState is the runtime state of a container.
@@ -275,6 +279,16 @@ type Loader struct {
 	// saveRestoreNet indicates if the saved network stack should be used
 	// during restore.
 	saveRestoreNet bool
+
+	// saveMu serializes checkpoints.
+	saveMu sync.Mutex
+
+	// autosave checkpoints the sandbox periodically. It is nil if periodic
+	// autosave is disabled.
+	autosave *periodicAutosave
+
+	// vsockBrokerFD is the control socket of the vsock broker, or -1.
+	vsockBrokerFD int
 }
 
 // execID uniquely identifies a sentry process that is executed in a container.
@@ -368,9 +382,21 @@ type Args struct {
 	// SinkFDs is an ordered array of file descriptors to be used by seccheck
 	// sinks configured from the --pod-init-config file.
 	SinkFDs []int
+	// VsockFD is the listening host socket through which host processes
+	// connect to AF_VSOCK listeners in the sandbox, or -1.
+	VsockFD int
+	// VsockBrokerFD is the control socket of the host process that makes
+	// AF_VSOCK connections to the host on behalf of the sandbox, or -1.
+	VsockBrokerFD int
+	// AutosaveFDs are the files that periodic autosave saves images to, as
+	// returned by CreateAutosaveFiles.
+	AutosaveFDs []int
 	// ProfileOpts contains the set of profiles to enable and the
 	// corresponding FDs where profile data will be written.
 	ProfileOpts profile.Opts
+	// StraceJSONFD is the file descriptor to write strace in JSON format to,
+	// or -1. The Loader takes ownership of this FD.
+	StraceJSONFD int
 	// NvidiaDriverVersion is the NVIDIA driver ABI version to use for
 	// communicating with NVIDIA devices on the host.
 	NvidiaDriverVersion nvconf.DriverVersion
@@ -554,9 +580,12 @@ func New(args Args) (*Loader, error) {
 	params := kernel.NewVDSOParamPage(l.k.MemoryFile(), vdso.ParamPage.FileRange())
 	tk.SetClocks(time.NewCalibratedClocks(), params)
 
-	if err := enableStrace(args.Conf); err != nil {
+	if err := enableStrace(args.Conf, args.StraceJSONFD); err != nil {
 		return nil, fmt.Errorf("enabling strace: %w", err)
 	}
+	if args.Conf.SyscallMetrics {
+		kernel.EnableSyscallMetrics()
+	}
 
 	creds := getRootCredentials(args.Spec, args.Conf, nil /* UserNamespace */)
 	if creds == nil {
@@ -678,6 +707,18 @@ func New(args Args) (*Loader, error) {
 		}
 	}
 
+	l.vsockBrokerFD = -1
+	if args.Conf.VsockUDS != "" {
+		if err := vsock.Init(vsock.Config{
+			CID:      uint32(args.Conf.VsockCID),
+			BrokerFD: args.VsockBrokerFD,
+			ListenFD: args.VsockFD,
+		}); err != nil {
+			return nil, fmt.Errorf("initializing vsock: %w", err)
+		}
+		l.vsockBrokerFD = args.VsockBrokerFD
+	}
+
 	l.k.RegisterContainerName(args.ID, l.root.containerName)
 
 	// We don't care about child signals; some platforms can generate a
@@ -689,6 +730,12 @@ func New(args Args) (*Loader, error) {
 	if len(args.Conf.TestOnlyAutosaveImagePath) != 0 {
 		enableAutosave(l, args.Conf.TestOnlyAutosaveResume, l.saveFDs)
 	}
+	if args.Conf.AutosaveInterval != 0 {
+		l.autosave, err = newPeriodicAutosave(l, args.AutosaveFDs)
+		if err != nil {
+			return nil, fmt.Errorf("enabling periodic autosave: %w", err)
+		}
+	}
 
 	l.kernelInitExtra()
 
@@ -760,6 +807,9 @@ func (l *Loader) Destroy() {
 	if l.stopSignalForwarding != nil {
 		l.stopSignalForwarding()
 	}
+	if l.autosave != nil {
+		l.autosave.Stop()
+	}
 	l.watchdog.Stop()
 
 	ctx := l.k.SupervisorContext()
@@ -908,6 +958,7 @@ func (l *Loader) installSeccompFilters() error {
 			Platform:              l.k.Platform.SeccompInfo(),
 			HostNetwork:           hostnet,
 			HostNetworkRawSockets: hostnet && l.root.conf.EnableRaw,
+			HostNetworkTCPRepair:  hostnet && l.root.conf.NetTCPRepair,
 			HostFilesystem:        l.root.conf.DirectFS,
 			ProfileEnable:         l.root.conf.ProfileEnable,
 			NVProxy:               nvproxyEnabled,
@@ -916,6 +967,8 @@ func (l *Loader) installSeccompFilters() error {
 			ControllerFD:          uint32(l.ctrl.srv.FD()),
 			CgoEnabled:            config.CgoEnabled,
 			PluginNetwork:         l.root.conf.Network == config.NetworkPlugin,
+			Vsock:                 l.root.conf.VsockUDS != "",
+			VsockBrokerFD:         int32(l.vsockBrokerFD),
 		}
 		if err := filter.Install(opts); err != nil {
 			return fmt.Errorf("installing seccomp filters: %w", err)
@@ -945,7 +998,7 @@ func (l *Loader) run() error {
 		// is configured after the loader is created and before Run() is called.
 		log.Debugf("Configuring host network")
 		s := l.k.RootNetworkNamespace().Stack().(*hostinet.Stack)
-		if err := s.Configure(l.root.conf.EnableRaw); err != nil {
+		if err := s.Configure(l.root.conf.EnableRaw, l.root.conf.NetTCPRepair); err != nil {
 			return err
 		}
 	}
@@ -997,9 +1050,11 @@ func (l *Loader) run() error {
 				evt.ContextData = &pb.ContextData{}
 				kernel.LoadSeccheckData(tg.Leader(), fields.Context, evt.ContextData)
 			}
-			_ = seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
-				return c.ContainerStart(context.Background(), fields, &evt)
-			})
+			if fields.Filter.Match(&evt) {
+				_ = seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
+					return c.ContainerStart(context.Background(), fields, &evt)
+				})
+			}
 		}
 	}
 
@@ -1035,6 +1090,9 @@ func (l *Loader) run() error {
 	if err := l.k.Start(); err != nil {
 		return err
 	}
+	if l.autosave != nil {
+		l.autosave.Start()
+	}
 	if l.state == restoringUnstarted {
 		l.state = restoringStarted
 	} else {
@@ -1161,9 +1219,11 @@ func (l *Loader) startSubcontainer(spec *specs.Spec, conf *config.Config, cid st
 			evt.ContextData = &pb.ContextData{}
 			kernel.LoadSeccheckData(ep.tg.Leader(), fields.Context, evt.ContextData)
 		}
-		_ = seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
-			return c.ContainerStart(context.Background(), fields, &evt)
-		})
+		if fields.Filter.Match(&evt) {
+			_ = seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
+				return c.ContainerStart(context.Background(), fields, &evt)
+			})
+		}
 	}
 
 	l.k.StartProcess(ep.tg)
@@ -1459,6 +1519,14 @@ func (l *Loader) waitContainer(cid string, waitStatus *uint32) error {
 	ws := l.wait(tg)
 	*waitStatus = ws
 
+	if l.k.SyscallStatsEnabled() {
+		if stats := l.k.TakeSyscallStats(cid); len(stats) > 0 {
+			var b strings.Builder
+			_ = control.WriteSyscallStatsTable(&b, stats)
+			log.Infof("Syscall stats for container %q:\n%s", cid, b.String())
+		}
+	}
+
 	// Check for leaks and write coverage report after the root container has
 	// exited. This guarantees that the report is written in cases where the
 	// sandbox is killed by a signal after the ContMgrWait request is completed.
@@ -1557,13 +1625,15 @@ func newRootNetworkNamespace(conf *config.Config, clock tcpip.Clock, userns *aut
 		return inet.NewRootNamespace(hostinet.NewStack(), nil, userns), nil
 
 	case config.NetworkNone, config.NetworkSandbox:
-		s, err := newEmptySandboxNetworkStack(clock, conf.AllowPacketEndpointWrite)
+		s, err := newEmptySandboxNetworkStack(clock, conf.AllowPacketEndpointWrite, conf.NetTunnels, conf.NetSCTP)
 		if err != nil {
 			return nil, err
 		}
 		creator := &sandboxNetstackCreator{
 			clock:                    clock,
 			allowPacketEndpointWrite: conf.AllowPacketEndpointWrite,
+			tunnels:                  conf.NetTunnels,
+			sctp:                     conf.NetSCTP,
 		}
 		return inet.NewRootNamespace(s, creator, userns), nil
 	case config.NetworkPlugin:
@@ -1575,7 +1645,7 @@ func newRootNetworkNamespace(conf *config.Config, clock tcpip.Clock, userns *aut
 
 }
 
-func newEmptySandboxNetworkStack(clock tcpip.Clock, allowPacketEndpointWrite bool) (*netstack.Stack, error) {
+func newEmptySandboxNetworkStack(clock tcpip.Clock, allowPacketEndpointWrite, tunnels, enableSCTP bool) (*netstack.Stack, error) {
 	netProtos := []stack.NetworkProtocolFactory{ipv4.NewProtocol, ipv6.NewProtocol, arp.NewProtocol}
 	transProtos := []stack.TransportProtocolFactory{
 		tcp.NewProtocol,
@@ -1583,6 +1653,12 @@ func newEmptySandboxNetworkStack(clock tcpip.Clock, allowPacketEndpointWrite boo
 		icmp.NewProtocol4,
 		icmp.NewProtocol6,
 	}
+	if tunnels {
+		transProtos = append(transProtos, tunnel.NewGREProtocol, tunnel.NewIPIPProtocol, tunnel.NewIPv6EncapProtocol)
+	}
+	if enableSCTP {
+		transProtos = append(transProtos, sctp.NewProtocol)
+	}
 	s := netstack.Stack{Stack: stack.New(stack.Options{
 		NetworkProtocols:   netProtos,
 		TransportProtocols: transProtos,
@@ -1632,11 +1708,13 @@ func newEmptySandboxNetworkStack(clock tcpip.Clock, allowPacketEndpointWrite boo
 type sandboxNetstackCreator struct {
 	clock                    tcpip.Clock
 	allowPacketEndpointWrite bool
+	tunnels                  bool
+	sctp                     bool
 }
 
 // CreateStack implements kernel.NetworkStackCreator.CreateStack.
 func (f *sandboxNetstackCreator) CreateStack() (inet.Stack, error) {
-	s, err := newEmptySandboxNetworkStack(f.clock, f.allowPacketEndpointWrite)
+	s, err := newEmptySandboxNetworkStack(f.clock, f.allowPacketEndpointWrite, f.tunnels, f.sctp)
 	if err != nil {
 		return nil, err
 	}
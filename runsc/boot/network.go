@@ -24,6 +24,7 @@ import (
 	"syscall"
 
 	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/bpf"
 	"gvisor.dev/gvisor/pkg/hostos"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
@@ -77,6 +78,12 @@ type Network struct {
 	// PluginStack is a third-party network stack to use in place of
 	// netstack when non-nil.
 	PluginStack plugin.PluginStack
+
+	// SandboxID identifies the sandbox in packet captures.
+	SandboxID string
+
+	// capture holds the state of runtime packet captures.
+	capture *captureState
 }
 
 // Route represents a route in the network stack.
@@ -178,6 +185,14 @@ type CreateLinksAndRoutesArgs struct {
 	// PCAP indicates that FilePayload also contains a PCAP log file.
 	PCAP bool
 
+	// PCAPNG indicates that the PCAP log file is written in the pcapng
+	// format, with all links sharing the file.
+	PCAPNG bool
+
+	// PCAPFilter selects the packets written to the PCAP log file. If empty,
+	// all packets are written.
+	PCAPFilter []bpf.Instruction
+
 	// LogPackets indicates that packets should be logged.
 	LogPackets bool
 
@@ -285,6 +300,18 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 		return fmt.Errorf("args.FilePayload.Files has %d FDs but we need %d entries based on FDBasedLinks, XDPLinks, and PCAP", got, wantFDs)
 	}
 
+	pcap := pcapLogger{
+		pcapng:  args.PCAPNG,
+		comment: "sandbox " + n.SandboxID,
+	}
+	if len(args.PCAPFilter) > 0 {
+		filter, err := sniffer.NewFilter(args.PCAPFilter)
+		if err != nil {
+			return err
+		}
+		pcap.filter = filter
+	}
+
 	nicids := make(map[string]tcpip.NICID)
 
 	// Collect routes from all links.
@@ -377,14 +404,9 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 
 			// Setup packet logging if requested.
 			if args.PCAP {
-				newFD, err := unix.Dup(int(args.FilePayload.Files[fdOffset].Fd()))
-				if err != nil {
-					return fmt.Errorf("failed to dup pcap FD: %v", err)
-				}
-				const packetTruncateSize = 4096
-				linkEP, err = sniffer.NewWithWriter(linkEP, os.NewFile(uintptr(newFD), "pcap-file"), packetTruncateSize)
+				linkEP, err = pcap.wrap(linkEP, args.FilePayload.Files[fdOffset], link.Name)
 				if err != nil {
-					return fmt.Errorf("failed to create PCAP logger: %v", err)
+					return err
 				}
 				fdOffset++
 			} else if args.LogPackets {
@@ -470,14 +492,9 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 		}
 
 		if args.PCAP {
-			newFD, err := unix.Dup(int(args.FilePayload.Files[fdOffset].Fd()))
-			if err != nil {
-				return fmt.Errorf("failed to dup pcap FD: %v", err)
-			}
-			const packetTruncateSize = 4096
-			linkEP, err = sniffer.NewWithWriter(linkEP, os.NewFile(uintptr(newFD), "pcap-file"), packetTruncateSize)
+			linkEP, err = pcap.wrap(linkEP, args.FilePayload.Files[fdOffset], link.Name)
 			if err != nil {
-				return fmt.Errorf("failed to create PCAP logger: %v", err)
+				return err
 			}
 			fdOffset++
 		} else if args.LogPackets {
@@ -583,6 +600,49 @@ func (n *Network) createNICWithAddrs(id tcpip.NICID, ep stack.LinkEndpoint, opts
 	return nil
 }
 
+// pcapLogger saves the packets of links to the PCAP log file.
+type pcapLogger struct {
+	pcapng  bool
+	comment string
+	filter  *sniffer.Filter
+
+	// writer is shared by all links when the log file is in the pcapng
+	// format. It's created with the first link.
+	writer *sniffer.PCAPNGWriter
+}
+
+// wrap returns a sniffer for linkEP, named name, writing to f.
+func (p *pcapLogger) wrap(linkEP stack.LinkEndpoint, f *os.File, name string) (stack.LinkEndpoint, error) {
+	const packetTruncateSize = 4096
+	opts := sniffer.Options{
+		Name:    name,
+		SnapLen: packetTruncateSize,
+		Filter:  p.filter,
+	}
+	if !p.pcapng || p.writer == nil {
+		newFD, err := unix.Dup(int(f.Fd()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to dup pcap FD: %v", err)
+		}
+		opts.Writer = os.NewFile(uintptr(newFD), "pcap-file")
+	}
+	if p.pcapng {
+		if p.writer == nil {
+			w, err := sniffer.NewPCAPNGWriter(opts.Writer, p.comment)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create PCAP logger: %v", err)
+			}
+			p.writer = w
+		}
+		opts.PCAPNG = p.writer
+	}
+	ep, err := sniffer.NewWithOptions(linkEP, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PCAP logger: %v", err)
+	}
+	return ep, nil
+}
+
 // ipToAddressAndProto converts IP to tcpip.Address and a protocol number.
 //
 // Note: don't use 'len(ip)' to determine IP version because length is always 16.
@@ -0,0 +1,389 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/eventchannel"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/control"
+	pb "gvisor.dev/gvisor/pkg/sentry/control/control_go_proto"
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/pkg/urpc"
+)
+
+const (
+	// autosaveImagePrefix is the name prefix of the directories holding
+	// autosave images. Images are saved in turn to directories
+	// autosaveImagePrefix followed by 0 to --autosave-keep.
+	autosaveImagePrefix = "image-"
+
+	// AutosaveMarkerFileName is the file within an autosave image directory
+	// that records that the image is complete. It's empty while the image is
+	// being written or after the image was pruned.
+	AutosaveMarkerFileName = "autosave.json"
+)
+
+// autosaveFileNames are the files in each autosave image directory, in the
+// order they are donated to the sandbox. The image files come first, in the
+// order expected by control.SaveOpts, followed by AutosaveMarkerFileName.
+var autosaveFileNames = []string{CheckpointStateFileName, CheckpointPagesMetadataFileName, CheckpointPagesFileName, AutosaveMarkerFileName}
+
+// autosaveMarker is the content of AutosaveMarkerFileName in complete images.
+type autosaveMarker struct {
+	// Sequence is the sequence number of the image. Later images have larger
+	// sequence numbers.
+	Sequence uint64 `json:"sequence"`
+}
+
+// AutosaveImageName returns the name of the i-th autosave image directory.
+func AutosaveImageName(i int) string {
+	return autosaveImagePrefix + strconv.Itoa(i)
+}
+
+// CreateAutosaveFiles creates the image directories that periodic autosave
+// saves to in dir, and returns their files in the order expected by the
+// sandbox. keep+1 directories are created, so that the keep most recent images
+// are preserved while a new image is written. Existing images are preserved.
+//
+// The sandbox only writes to the returned files, and doesn't need access to
+// dir.
+func CreateAutosaveFiles(dir string, keep int) ([]*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating autosave directory %q: %w", dir, err)
+	}
+	var files []*os.File
+	for i := 0; i <= keep; i++ {
+		imagePath := filepath.Join(dir, AutosaveImageName(i))
+		if err := os.Mkdir(imagePath, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+			closeFiles(files)
+			return nil, fmt.Errorf("creating autosave image directory %q: %w", imagePath, err)
+		}
+		for _, name := range autosaveFileNames {
+			f, err := os.OpenFile(filepath.Join(imagePath, name), os.O_RDWR|os.O_CREATE|unix.O_NOFOLLOW, 0644)
+			if err != nil {
+				closeFiles(files)
+				return nil, fmt.Errorf("opening autosave file: %w", err)
+			}
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// CheckAutosaveImage returns an error if imagePath is an autosave image that
+// is incomplete. Other images are not checked.
+func CheckAutosaveImage(imagePath string) error {
+	data, err := os.ReadFile(filepath.Join(imagePath, AutosaveMarkerFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, ok := parseAutosaveMarker(data); !ok {
+		return fmt.Errorf("autosave image %q is incomplete", imagePath)
+	}
+	return nil
+}
+
+func parseAutosaveMarker(data []byte) (uint64, bool) {
+	var m autosaveMarker
+	if err := json.Unmarshal(data, &m); err != nil || m.Sequence == 0 {
+		return 0, false
+	}
+	return m.Sequence, true
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// autosaveImage is one of the image directories that periodic autosave saves
+// to.
+type autosaveImage struct {
+	name string
+
+	// files are the state, pages metadata and pages files of the image.
+	files []*os.File
+
+	// marker is the image's AutosaveMarkerFileName.
+	marker *os.File
+
+	// seq is the sequence number of the image, or 0 if the directory doesn't
+	// hold a complete image.
+	seq uint64
+}
+
+// periodicAutosave checkpoints the sandbox in the background at a fixed
+// interval, leaving the workload running. Images are saved in turn to a fixed
+// set of directories created by runsc, whose files are donated to the sandbox.
+// An image is marked complete only once all its files are synced, so readers
+// never use partially written images. Only the most recent images are kept.
+type periodicAutosave struct {
+	l *Loader
+
+	// dirPath is the host directory containing the images, used only for
+	// reporting.
+	dirPath string
+
+	interval time.Duration
+	keep     int
+
+	// images are the image directories, keep+1 of them. They are protected
+	// by l.saveMu.
+	images []autosaveImage
+
+	// seq is the sequence number of the last saved image. It is protected by
+	// l.saveMu.
+	seq uint64
+
+	// stopped is set once the autosaver must not save any more images.
+	stopped atomicbitops.Bool
+
+	stop chan struct{}
+}
+
+// newPeriodicAutosave creates a periodic autosaver for l that saves images to
+// the files returned by CreateAutosaveFiles. It takes ownership of fds.
+func newPeriodicAutosave(l *Loader, fds []int) (*periodicAutosave, error) {
+	a := &periodicAutosave{
+		l:        l,
+		dirPath:  l.root.conf.AutosaveDir,
+		interval: l.root.conf.AutosaveInterval,
+		keep:     l.root.conf.AutosaveKeep,
+		stop:     make(chan struct{}),
+	}
+	if err := a.init(fds); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// init sets up the images from fds and continues numbering images after the
+// existing ones. It takes ownership of fds.
+func (a *periodicAutosave) init(fds []int) error {
+	var files []*os.File
+	for _, fd := range fds {
+		files = append(files, os.NewFile(uintptr(fd), "autosave"))
+	}
+	if want := (a.keep + 1) * len(autosaveFileNames); len(files) != want {
+		closeFiles(files)
+		return fmt.Errorf("got %d autosave files, want %d", len(files), want)
+	}
+	for i := 0; i <= a.keep; i++ {
+		imageFiles := files[i*len(autosaveFileNames) : (i+1)*len(autosaveFileNames)]
+		a.images = append(a.images, autosaveImage{
+			name:   AutosaveImageName(i),
+			files:  imageFiles[:len(imageFiles)-1],
+			marker: imageFiles[len(imageFiles)-1],
+		})
+	}
+	for i := range a.images {
+		img := &a.images[i]
+		data, err := io.ReadAll(io.NewSectionReader(img.marker, 0, 4096))
+		if err != nil {
+			closeFiles(files)
+			return fmt.Errorf("reading %q: %w", img.name, err)
+		}
+		if seq, ok := parseAutosaveMarker(data); ok {
+			img.seq = seq
+			a.seq = max(a.seq, seq)
+		}
+	}
+	return nil
+}
+
+// Start starts saving images in the background.
+func (a *periodicAutosave) Start() {
+	log.Infof("Autosave: saving to %q every %v, keeping %d images", a.dirPath, a.interval, a.keep)
+	go a.run() // S/R-SAFE: not saved.
+}
+
+// Stop stops saving images. It does not wait for an in-progress save to
+// complete, and may be called with l.saveMu held.
+func (a *periodicAutosave) Stop() {
+	if a.stopped.Swap(true) {
+		return
+	}
+	close(a.stop)
+}
+
+func (a *periodicAutosave) run() {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+			if err := a.saveOnce(); err != nil {
+				log.Warningf("Autosave: %v", err)
+			}
+		}
+	}
+}
+
+// saveOnce saves a single image, if the sandbox is running.
+func (a *periodicAutosave) saveOnce() error {
+	a.l.mu.Lock()
+	state := a.l.state
+	a.l.mu.Unlock()
+	if state != started && state != restored {
+		log.Debugf("Autosave: skipping save in loader state %v", state)
+		return nil
+	}
+
+	a.l.saveMu.Lock()
+	defer a.l.saveMu.Unlock()
+	if a.stopped.Load() {
+		return nil
+	}
+
+	seq := a.seq + 1
+	img := a.next()
+	if err := a.write(img, seq); err != nil {
+		return fmt.Errorf("saving image %d: %w", seq, err)
+	}
+	a.seq = seq
+	saved := time.Now()
+	log.Infof("Autosave: saved image %d to %q", seq, img.name)
+
+	if err := a.prune(); err != nil {
+		log.Warningf("Autosave: pruning old images: %v", err)
+	}
+
+	eventchannel.LogEmit(&pb.AutosaveEvent{
+		ImagePath: filepath.Join(a.dirPath, img.name),
+		Sequence:  seq,
+		Saved:     timestamppb.New(saved),
+	})
+	return nil
+}
+
+// next returns the image to save to next: an image directory that doesn't
+// hold a complete image, or the oldest image.
+//
+// Preconditions: a.l.saveMu is locked.
+func (a *periodicAutosave) next() *autosaveImage {
+	next := &a.images[0]
+	for i := range a.images {
+		if a.images[i].seq < next.seq {
+			next = &a.images[i]
+		}
+	}
+	return next
+}
+
+// write saves an image with sequence number seq to img, replacing its
+// contents.
+//
+// Preconditions: a.l.saveMu is locked.
+func (a *periodicAutosave) write(img *autosaveImage, seq uint64) error {
+	if err := a.clear(img); err != nil {
+		return err
+	}
+	// control.State.Save duplicates the files, so ours remain open for
+	// syncing below.
+	opts := &control.SaveOpts{
+		Metadata:      statefile.Options{Compression: statefile.CompressionLevelNone}.WriteToMetadata(map[string]string{}),
+		HavePagesFile: true,
+		FilePayload:   urpc.FilePayload{Files: img.files},
+		Resume:        true,
+	}
+	if err := a.l.saveLocked(opts); err != nil {
+		return err
+	}
+	for _, f := range img.files {
+		if err := f.Sync(); err != nil {
+			return fmt.Errorf("syncing %q: %w", img.name, err)
+		}
+	}
+
+	// Mark the image complete.
+	data, err := json.Marshal(autosaveMarker{Sequence: seq})
+	if err != nil {
+		return err
+	}
+	if _, err := img.marker.WriteAt(data, 0); err != nil {
+		return fmt.Errorf("marking %q complete: %w", img.name, err)
+	}
+	if err := img.marker.Sync(); err != nil {
+		return fmt.Errorf("marking %q complete: %w", img.name, err)
+	}
+	img.seq = seq
+	return nil
+}
+
+// clear marks img incomplete and truncates its files.
+//
+// Preconditions: a.l.saveMu is locked.
+func (a *periodicAutosave) clear(img *autosaveImage) error {
+	// The marker is cleared and synced first, so that the image is never
+	// considered complete while its files are changed.
+	img.seq = 0
+	if err := img.marker.Truncate(0); err != nil {
+		return fmt.Errorf("clearing %q: %w", img.name, err)
+	}
+	if err := img.marker.Sync(); err != nil {
+		return fmt.Errorf("clearing %q: %w", img.name, err)
+	}
+	for _, f := range img.files {
+		if err := f.Truncate(0); err != nil {
+			return fmt.Errorf("clearing %q: %w", img.name, err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("clearing %q: %w", img.name, err)
+		}
+	}
+	return nil
+}
+
+// prune clears complete images beyond the a.keep most recent ones.
+//
+// Preconditions: a.l.saveMu is locked.
+func (a *periodicAutosave) prune() error {
+	var complete []*autosaveImage
+	for i := range a.images {
+		if a.images[i].seq != 0 {
+			complete = append(complete, &a.images[i])
+		}
+	}
+	if len(complete) <= a.keep {
+		return nil
+	}
+	sort.Slice(complete, func(i, j int) bool { return complete[i].seq < complete[j].seq })
+	for _, img := range complete[:len(complete)-a.keep] {
+		if err := a.clear(img); err != nil {
+			return err
+		}
+		log.Infof("Autosave: removed image %q", img.name)
+	}
+	return nil
+}
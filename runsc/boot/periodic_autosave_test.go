@@ -0,0 +1,200 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// newTestAutosave returns a periodicAutosave for a new directory set up by
+// CreateAutosaveFiles, and the directory's path. setup is called before the
+// autosaver reads the directory.
+func newTestAutosave(t *testing.T, keep int, setup func(dir string)) (*periodicAutosave, string) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "autosave")
+	files, err := CreateAutosaveFiles(dir, keep)
+	if err != nil {
+		t.Fatalf("CreateAutosaveFiles: %v", err)
+	}
+	var fds []int
+	for _, f := range files {
+		fd, err := unix.Dup(int(f.Fd()))
+		if err != nil {
+			t.Fatalf("dup: %v", err)
+		}
+		f.Close()
+		fds = append(fds, fd)
+	}
+	if setup != nil {
+		setup(dir)
+	}
+	a := &periodicAutosave{dirPath: dir, keep: keep}
+	if err := a.init(fds); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, img := range a.images {
+			closeFiles(img.files)
+			img.marker.Close()
+		}
+	})
+	return a, dir
+}
+
+// writeImageFile writes data to the file called name in the i-th image.
+func writeImageFile(t *testing.T, dir string, i int, name, data string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, AutosaveImageName(i), name), []byte(data), 0644); err != nil {
+		t.Fatalf("writing %q: %v", name, err)
+	}
+}
+
+func TestCreateAutosaveFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "autosave")
+	files, err := CreateAutosaveFiles(dir, 2)
+	if err != nil {
+		t.Fatalf("CreateAutosaveFiles: %v", err)
+	}
+	closeFiles(files)
+	if got, want := len(files), 3*len(autosaveFileNames); got != want {
+		t.Errorf("CreateAutosaveFiles returned %d files, want %d", got, want)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("reading directory: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	if want := []string{"image-0", "image-1", "image-2"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("autosave directory has %v, want %v", names, want)
+	}
+
+	// Images from a previous sandbox are preserved.
+	writeImageFile(t, dir, 1, CheckpointStateFileName, "state")
+	files, err = CreateAutosaveFiles(dir, 2)
+	if err != nil {
+		t.Fatalf("CreateAutosaveFiles: %v", err)
+	}
+	closeFiles(files)
+	if data, err := os.ReadFile(filepath.Join(dir, "image-1", CheckpointStateFileName)); err != nil || string(data) != "state" {
+		t.Errorf("state file after second CreateAutosaveFiles = %q, %v, want %q", data, err, "state")
+	}
+}
+
+func TestAutosaveInit(t *testing.T) {
+	a, _ := newTestAutosave(t, 3, func(dir string) {
+		writeImageFile(t, dir, 0, AutosaveMarkerFileName, `{"sequence":7}`)
+		writeImageFile(t, dir, 1, AutosaveMarkerFileName, `{"sequence":5}`)
+		// Image 2 is incomplete and image 3 is corrupted.
+		writeImageFile(t, dir, 3, AutosaveMarkerFileName, `{"seq`)
+	})
+	var seqs []uint64
+	for _, img := range a.images {
+		seqs = append(seqs, img.seq)
+	}
+	if want := []uint64{7, 5, 0, 0}; !reflect.DeepEqual(seqs, want) {
+		t.Errorf("image sequence numbers = %v, want %v", seqs, want)
+	}
+	// Saving continues after the last complete image, to an image that
+	// isn't complete.
+	if a.seq != 7 {
+		t.Errorf("seq = %d, want 7", a.seq)
+	}
+	if next := a.next(); next.seq != 0 {
+		t.Errorf("next image %q has sequence %d, want an incomplete image", next.name, next.seq)
+	}
+}
+
+func TestAutosaveInitWrongFiles(t *testing.T) {
+	a := &periodicAutosave{keep: 1}
+	if err := a.init(nil); err == nil || !strings.Contains(err.Error(), "autosave files") {
+		t.Errorf("init without files = %v, want error", err)
+	}
+}
+
+func TestAutosavePrune(t *testing.T) {
+	a, dir := newTestAutosave(t, 2, func(dir string) {
+		for i, seq := range []string{"3", "1", "2"} {
+			writeImageFile(t, dir, i, AutosaveMarkerFileName, `{"sequence":`+seq+`}`)
+			writeImageFile(t, dir, i, CheckpointStateFileName, "state")
+		}
+	})
+	if err := a.prune(); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	// Only the 2 most recent complete images are kept, in sequence rather
+	// than directory order. The oldest image is cleared, and is saved to
+	// next.
+	for i, want := range []string{"state", "", "state"} {
+		if data, err := os.ReadFile(filepath.Join(dir, AutosaveImageName(i), CheckpointStateFileName)); err != nil || string(data) != want {
+			t.Errorf("image %d state file = %q, %v, want %q", i, data, err, want)
+		}
+	}
+	if err := CheckAutosaveImage(filepath.Join(dir, AutosaveImageName(1))); err == nil {
+		t.Errorf("CheckAutosaveImage of pruned image succeeded")
+	}
+	if next := a.next(); next.name != AutosaveImageName(1) {
+		t.Errorf("next image = %q, want %q", next.name, AutosaveImageName(1))
+	}
+
+	// Pruning again is a no-op.
+	if err := a.prune(); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	for _, i := range []int{0, 2} {
+		if err := CheckAutosaveImage(filepath.Join(dir, AutosaveImageName(i))); err != nil {
+			t.Errorf("CheckAutosaveImage of kept image %d: %v", i, err)
+		}
+	}
+}
+
+func TestCheckAutosaveImage(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		marker  *string
+		wantErr bool
+	}{
+		{name: "not autosave"},
+		{name: "complete", marker: ptr(`{"sequence":1}`)},
+		{name: "incomplete", marker: ptr(""), wantErr: true},
+		{name: "zero", marker: ptr(`{"sequence":0}`), wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tc.marker != nil {
+				if err := os.WriteFile(filepath.Join(dir, AutosaveMarkerFileName), []byte(*tc.marker), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := CheckAutosaveImage(dir); (err != nil) != tc.wantErr {
+				t.Errorf("CheckAutosaveImage = %v, want error %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
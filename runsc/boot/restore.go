@@ -38,6 +38,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/time"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sentry/watchdog"
+	"gvisor.dev/gvisor/pkg/state"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 	"gvisor.dev/gvisor/pkg/timing"
@@ -60,11 +61,35 @@ const (
 	// VersionKey is the key used to save runsc version in the save metadata and compare
 	// it across checkpoint restore.
 	VersionKey = "runsc_version"
+	// StateFormatVersionKey is the key used to save state.FormatVersion in
+	// the save metadata. Checkpoints taken by a different runsc version can be
+	// restored if their state format version matches.
+	StateFormatVersionKey = "state_format_version"
 	// ContainerCountKey is the key used to save number of containers in the save metadata.
 	ContainerCountKey = "container_count"
 	// ContainerSpecsKey is the key used to add and pop the container specs to the
 	// metadata during save/restore.
 	ContainerSpecsKey = "container_specs"
+	// CheckpointSaveIDKey is the key used to save pgalloc.SaveOpts.SaveID in
+	// the save metadata of checkpoints that later checkpoints can be
+	// incremental to.
+	CheckpointSaveIDKey = "mf_save_id"
+	// CheckpointParentSaveIDKey is the key used to save
+	// pgalloc.SaveOpts.ParentSaveID in the save metadata of incremental
+	// checkpoints.
+	CheckpointParentSaveIDKey = "mf_parent_save_id"
+	// CheckpointParentLinkName is the symbolic link within an incremental
+	// checkpoint's image-path directory that points to its parent's
+	// image-path.
+	CheckpointParentLinkName = "parent"
+	// CheckpointChunkStoreKey is the key used to save the directory of the
+	// chunk store holding the pages of checkpoints saved to one. The pages
+	// file of such checkpoints is the chunk store's pack file.
+	CheckpointChunkStoreKey = "mf_chunk_store"
+	// CheckpointSparsePagesKey is set in the save metadata of checkpoints
+	// whose pages file holds each page at its MemoryFile offset, as written
+	// for migrated sandboxes by kernel.WritePreCopiedMemoryFiles.
+	CheckpointSparsePagesKey = "mf_sparse_pages"
 )
 
 // restorer manages a restore session for a sandbox. It stores information about
@@ -183,6 +208,10 @@ func createNetworkStackForRestore(l *Loader) (*stack.Stack, inet.Stack) {
 	if eps, ok := curNetwork.(*netstack.Stack); ok {
 		return eps.Stack, curNetwork
 	}
+	if hs, ok := curNetwork.(*hostinet.Stack); ok {
+		// Host sockets are recreated in the configured host network stack.
+		return nil, hs
+	}
 	return nil, hostinet.NewStack()
 }
 
@@ -233,6 +262,9 @@ func (r *restorer) restore(l *Loader) error {
 	if oldStack != nil {
 		ctx = context.WithValue(ctx, stack.CtxRestoreStack, oldStack)
 	}
+	if hs, ok := oldInetStack.(*hostinet.Stack); ok {
+		ctx = context.WithValue(ctx, hostinet.CtxRestoreStack, hs)
+	}
 
 	l.mu.Lock()
 	cu := cleanup.Make(func() {
@@ -402,15 +434,64 @@ func (r *restorer) restore(l *Loader) error {
 	return nil
 }
 
-func (l *Loader) save(o *control.SaveOpts) (err error) {
+func (l *Loader) save(o *control.SaveOpts) error {
+	l.saveMu.Lock()
+	defer l.saveMu.Unlock()
+	if err := l.saveLocked(o); err != nil {
+		return err
+	}
+	if !o.Resume && l.autosave != nil {
+		// The sandbox is no longer running.
+		l.autosave.Stop()
+	}
+	return nil
+}
+
+// addVersionMetadata adds the runsc and state format versions to the save
+// metadata.
+func addVersionMetadata(metadata map[string]string) {
+	metadata[VersionKey] = version.Version()
+	metadata[StateFormatVersionKey] = strconv.Itoa(state.FormatVersion)
+}
+
+// checkVersionMetadata returns an error if a checkpoint with the given save
+// metadata can't be restored by this runsc version. If acrossVersions is
+// true, checkpoints taken by a different runsc version can be restored if they
+// have the same state format version. Types are then reconciled when the
+// state is loaded, which fails if unversioned types have changed, and migrates
+// versioned types.
+func checkVersionMetadata(metadata map[string]string, acrossVersions bool) error {
+	checkpointVersion := metadata[VersionKey]
+	currentVersion := version.Version()
+	if checkpointVersion == currentVersion {
+		return nil
+	}
+	if !acrossVersions {
+		return fmt.Errorf("runsc version does not match across checkpoint restore, checkpoint: %v current: %v (see --restore-across-versions)", checkpointVersion, currentVersion)
+	}
+	checkpointFormat, ok := metadata[StateFormatVersionKey]
+	if !ok {
+		return fmt.Errorf("runsc version does not match across checkpoint restore and checkpoint has no state format version, checkpoint: %v current: %v", checkpointVersion, currentVersion)
+	}
+	if currentFormat := strconv.Itoa(state.FormatVersion); checkpointFormat != currentFormat {
+		return fmt.Errorf("state format version does not match across checkpoint restore, checkpoint: %v (runsc %v) current: %v (runsc %v)", checkpointFormat, checkpointVersion, currentFormat, currentVersion)
+	}
+	log.Infof("Restoring checkpoint taken by runsc version %v with runsc version %v", checkpointVersion, currentVersion)
+	return nil
+}
+
+// saveLocked implements save.
+//
+// Preconditions: l.saveMu is locked.
+func (l *Loader) saveLocked(o *control.SaveOpts) (err error) {
 	defer func() {
 		// This closure is required to capture the final value of err.
 		l.k.OnCheckpointAttempt(err)
 	}()
 
-	// TODO(gvisor.dev/issues/6243): save/restore not supported w/ hostinet
-	if l.root.conf.Network == config.NetworkHost {
-		return errors.New("checkpoint not supported when using hostinet")
+	// Without TCP_REPAIR, established host TCP connections can't be saved.
+	if l.root.conf.Network == config.NetworkHost && !l.root.conf.NetTCPRepair {
+		return errors.New("checkpoint with --network=host requires --net-tcp-repair")
 	}
 
 	if o.Metadata == nil {
@@ -418,8 +499,8 @@ func (l *Loader) save(o *control.SaveOpts) (err error) {
 	}
 	o.Metadata[ContainerCountKey] = strconv.Itoa(l.containerCount())
 
-	// Save runsc version.
-	o.Metadata[VersionKey] = version.Version()
+	// Save runsc and state format versions.
+	addVersionMetadata(o.Metadata)
 
 	// Save container specs.
 	specsStr, err := specutils.ConvertSpecsToString(l.GetContainerSpecs())
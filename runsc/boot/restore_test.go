@@ -0,0 +1,73 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"strconv"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/state"
+	"gvisor.dev/gvisor/runsc/version"
+)
+
+func TestCheckVersionMetadata(t *testing.T) {
+	current := make(map[string]string)
+	addVersionMetadata(current)
+	format := strconv.Itoa(state.FormatVersion)
+	for _, tc := range []struct {
+		name           string
+		metadata       map[string]string
+		acrossVersions bool
+		wantErr        bool
+	}{
+		{
+			name:     "current",
+			metadata: current,
+		},
+		{
+			name:     "same runsc version without state format",
+			metadata: map[string]string{VersionKey: version.Version()},
+		},
+		{
+			name:     "different runsc version",
+			metadata: map[string]string{VersionKey: "release-00000000.0", StateFormatVersionKey: format},
+			wantErr:  true,
+		},
+		{
+			name:           "different runsc version across versions",
+			metadata:       map[string]string{VersionKey: "release-00000000.0", StateFormatVersionKey: format},
+			acrossVersions: true,
+		},
+		{
+			name:           "different runsc version without state format",
+			metadata:       map[string]string{VersionKey: "release-00000000.0"},
+			acrossVersions: true,
+			wantErr:        true,
+		},
+		{
+			name:           "different state format",
+			metadata:       map[string]string{VersionKey: "release-00000000.0", StateFormatVersionKey: strconv.Itoa(state.FormatVersion + 1)},
+			acrossVersions: true,
+			wantErr:        true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkVersionMetadata(tc.metadata, tc.acrossVersions)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("checkVersionMetadata(%v, %t) = %v, want error: %t", tc.metadata, tc.acrossVersions, err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -23,7 +23,9 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 
 	// Register supported of sinks.
+	_ "gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/file"
 	_ "gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/null"
+	_ "gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/otlp"
 	_ "gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/remote"
 )
 
@@ -71,10 +73,14 @@ func (c *InitConfig) Setup() ([]*os.File, error) {
 }
 
 func (c *InitConfig) create(sinkFDs []int) error {
+	fds := make([]*fd.FD, len(sinkFDs))
 	for i, sinkFD := range sinkFDs {
 		if sinkFD >= 0 {
-			c.TraceSession.Sinks[i].FD = fd.New(sinkFD)
+			fds[i] = fd.New(sinkFD)
 		}
 	}
+	if err := seccheck.SetSinkFDs(c.TraceSession.Sinks, fds); err != nil {
+		return err
+	}
 	return seccheck.Create(&c.TraceSession, false)
 }
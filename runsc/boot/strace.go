@@ -15,13 +15,14 @@
 package boot
 
 import (
+	"os"
 	"strings"
 
 	"gvisor.dev/gvisor/pkg/sentry/strace"
 	"gvisor.dev/gvisor/runsc/config"
 )
 
-func enableStrace(conf *config.Config) error {
+func enableStrace(conf *config.Config, jsonFD int) error {
 	// We must initialize even if strace is not enabled.
 	strace.Initialize()
 
@@ -42,6 +43,10 @@ func enableStrace(conf *config.Config) error {
 	if conf.StraceEvent {
 		sink = strace.SinkTypeEvent
 	}
+	if jsonFD >= 0 {
+		sink = strace.SinkTypeJSON
+		strace.SetJSONOutput(os.NewFile(uintptr(jsonFD), "strace json file"), strace.JSONOptions{MaxDataSize: max})
+	}
 
 	if len(conf.StraceSyscalls) == 0 {
 		strace.EnableAll(sink)
@@ -256,6 +256,7 @@ func forEachCmd(cb func(cmd subcommands.Command, group string)) {
 	cb(new(cmd.Exec), "")
 	cb(new(cmd.Kill), "")
 	cb(new(cmd.List), "")
+	cb(new(cmd.Migrate), "")
 	cb(new(cmd.PS), "")
 	cb(new(cmd.Pause), "")
 	cb(new(cmd.PortForward), "")
@@ -293,6 +294,7 @@ func forEachCmd(cb func(cmd subcommands.Command, group string)) {
 	cb(new(cmd.Boot), internalGroup)
 	cb(new(cmd.Gofer), internalGroup)
 	cb(new(cmd.Umount), internalGroup)
+	cb(new(cmd.VsockBroker), internalGroup)
 }
 
 func newEmitter(format string, logFile io.Writer) log.Emitter {
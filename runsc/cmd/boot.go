@@ -149,6 +149,17 @@ type Boot struct {
 
 	sinkFDs intFlags
 
+	// vsockFD is the listening host socket through which host processes
+	// connect to AF_VSOCK listeners in the sandbox.
+	vsockFD int
+
+	// vsockBrokerFD is the control socket of the host process that makes
+	// AF_VSOCK connections to the host on behalf of the sandbox.
+	vsockBrokerFD int
+
+	// autosaveFDs are the files that periodic autosave saves images to.
+	autosaveFDs intFlags
+
 	saveFDs intFlags
 
 	// attached is set to true to kill the sandbox process when the parent process
@@ -176,6 +187,9 @@ type Boot struct {
 	// If so, the format used to write to it will contain a checksum.
 	profilingMetricsLossy bool
 
+	// straceJSONFD is a file descriptor to write strace in JSON format to.
+	straceJSONFD int
+
 	// procMountSyncFD is a file descriptor that has to be closed when the
 	// procfs mount isn't needed anymore.
 	procMountSyncFD int
@@ -236,6 +250,9 @@ func (b *Boot) SetFlags(f *flag.FlagSet) {
 	f.IntVar(&b.mountsFD, "mounts-fd", -1, "mountsFD is an optional file descriptor to read list of mounts after they have been resolved (direct paths, no symlinks).")
 	f.IntVar(&b.podInitConfigFD, "pod-init-config-fd", -1, "file descriptor to the pod init configuration file.")
 	f.Var(&b.sinkFDs, "sink-fds", "ordered list of file descriptors to be used by the sinks defined in --pod-init-config.")
+	f.IntVar(&b.vsockFD, "vsock-fd", -1, "listening host socket through which host processes connect to AF_VSOCK listeners.")
+	f.IntVar(&b.vsockBrokerFD, "vsock-broker-fd", -1, "control socket of the host process that makes AF_VSOCK connections to the host on behalf of the sandbox.")
+	f.Var(&b.autosaveFDs, "autosave-fds", "ordered list of file descriptors that periodic autosave saves images to.")
 	f.Var(&b.saveFDs, "save-fds", "ordered list of file descriptors to be used save checkpoints. Order: kernel state, page metadata, page file")
 
 	// Profiling flags.
@@ -243,6 +260,7 @@ func (b *Boot) SetFlags(f *flag.FlagSet) {
 	f.IntVar(&b.finalMetricsFD, "final-metrics-log-fd", -1, "file descriptor to write metrics to upon sandbox termination.")
 	f.IntVar(&b.profilingMetricsFD, "profiling-metrics-fd", -1, "file descriptor to write sentry profiling metrics.")
 	f.BoolVar(&b.profilingMetricsLossy, "profiling-metrics-fd-lossy", false, "if true, treat the sentry profiling metrics FD as lossy and write a checksum to it.")
+	f.IntVar(&b.straceJSONFD, "strace-json-fd", -1, "file descriptor to write strace in JSON format to.")
 }
 
 // Execute implements subcommands.Command.Execute.  It starts a sandbox in a
@@ -523,7 +541,11 @@ func (b *Boot) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomma
 		ProductName:         b.productName,
 		PodInitConfigFD:     b.podInitConfigFD,
 		SinkFDs:             b.sinkFDs.GetArray(),
+		VsockFD:             b.vsockFD,
+		VsockBrokerFD:       b.vsockBrokerFD,
+		AutosaveFDs:         b.autosaveFDs.GetArray(),
 		ProfileOpts:         b.profileFDs.ToOpts(),
+		StraceJSONFD:        b.straceJSONFD,
 		NvidiaDriverVersion: nvidiaDriverVersion,
 		HostTHP:             b.hostTHP,
 		SaveFDs:             b.saveFDs.GetFDs(),
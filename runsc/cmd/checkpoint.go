@@ -16,16 +16,22 @@ package cmd
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/google/subcommands"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/runsc/boot"
 	"gvisor.dev/gvisor/runsc/cmd/util"
 	"gvisor.dev/gvisor/runsc/config"
 	"gvisor.dev/gvisor/runsc/container"
 	"gvisor.dev/gvisor/runsc/flag"
+	"gvisor.dev/gvisor/runsc/sandbox"
 )
 
 // Checkpoint implements subcommands.Command for the "checkpoint" command.
@@ -35,12 +41,29 @@ type Checkpoint struct {
 	compression               CheckpointCompression
 	excludeCommittedZeroPages bool
 
+	// incremental indicates that the checkpoint may be used as the parent of
+	// incremental checkpoints.
+	incremental bool
+
+	// parentImagePath is the path to the checkpoint that the checkpoint is
+	// incremental to. If set, only memory changed since the parent
+	// checkpoint is saved.
+	parentImagePath string
+
 	// direct indicates whether O_DIRECT should be used for writing the
 	// checkpoint pages file. It bypasses the kernel page cache. It is beneficial
 	// if the checkpoint files are not expected to be read again on this host.
 	// For example, if the checkpoint files will be stored on a network block
 	// device, which will be detached after the checkpoint is done.
 	direct bool
+
+	// encryptionKeyFile is the path to the file holding the key to encrypt the
+	// checkpoint with.
+	encryptionKeyFile string
+
+	// chunkStore is the path to the directory of a chunk store that pages
+	// are saved to, deduplicated with pages saved to it by other checkpoints.
+	chunkStore string
 }
 
 // Name implements subcommands.Command.Name.
@@ -62,9 +85,13 @@ func (*Checkpoint) Usage() string {
 func (c *Checkpoint) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&c.imagePath, "image-path", "", "directory path to saved container image")
 	f.BoolVar(&c.leaveRunning, "leave-running", false, "restart the container after checkpointing")
-	f.Var(newCheckpointCompressionValue(statefile.CompressionLevelDefault, &c.compression), "compression", "compress checkpoint image on disk. Values: none|flate-best-speed.")
+	f.Var(newCheckpointCompressionValue(statefile.CompressionLevelDefault, &c.compression), "compression", "compress checkpoint image on disk. Values: none|flate-best-speed|zstd|lz4. zstd and lz4 are faster than flate and also compress the pages file used by restore --background.")
 	f.BoolVar(&c.excludeCommittedZeroPages, "exclude-committed-zero-pages", false, "exclude committed zero-filled pages from checkpoint")
 	f.BoolVar(&c.direct, "direct", false, "use O_DIRECT for writing checkpoint pages file")
+	f.BoolVar(&c.incremental, "incremental", false, "allow later checkpoints to be incremental to this one. Requires --compression=none, zstd or lz4.")
+	f.StringVar(&c.parentImagePath, "parent-image-path", "", "only save memory changed since the checkpoint at this path, which must have been taken with --incremental from the same sandbox. Implies --incremental.")
+	f.StringVar(&c.encryptionKeyFile, "encryption-key-file", "", "encrypt the checkpoint image with the key in this file, either 32 raw bytes or 64 hex digits; use /dev/fd/N to pass it as a file descriptor.")
+	f.StringVar(&c.chunkStore, "chunk-store", "", "save memory pages to the content-addressed chunk store in this directory, storing each distinct page once across all checkpoints saved to it. Restoring the checkpoint requires the chunk store, which only grows and must be kept until all checkpoints saved to it are deleted. Checkpoints saved to a chunk store can read each other's pages, so only share it between checkpoints of mutually trusted containers. Requires --compression=none, zstd or lz4; can't be used with --incremental or --encryption-key-file.")
 
 	// Unimplemented flags necessary for compatibility with docker.
 	var wp string
@@ -97,22 +124,83 @@ func (c *Checkpoint) Execute(_ context.Context, f *flag.FlagSet, args ...any) su
 	sOpts := statefile.Options{
 		Compression: c.compression.Level(),
 	}
+	if sOpts.EncryptionKey, err = readEncryptionKey(c.encryptionKeyFile); err != nil {
+		util.Fatalf("reading encryption key: %v", err)
+	}
 	mfOpts := pgalloc.SaveOpts{
 		ExcludeCommittedZeroPages: c.excludeCommittedZeroPages,
 	}
+	if c.incremental || c.parentImagePath != "" {
+		var id [16]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			util.Fatalf("generating checkpoint ID: %v", err)
+		}
+		mfOpts.SaveID = hex.EncodeToString(id[:])
+	}
+	// A parent link left by an earlier checkpoint to the same path would make
+	// restore treat this checkpoint as incremental.
+	link := filepath.Join(c.imagePath, boot.CheckpointParentLinkName)
+	if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+		util.Fatalf("removing stale parent link: %v", err)
+	}
+	var parent string
+	if c.parentImagePath != "" {
+		if parent, err = filepath.Abs(c.parentImagePath); err != nil {
+			util.Fatalf("resolving parent image path: %v", err)
+		}
+		if mfOpts.ParentSaveID, err = sandbox.CheckpointSaveID(parent); err != nil {
+			util.Fatalf("reading parent checkpoint: %v", err)
+		}
+	}
 
 	if c.leaveRunning {
 		// Do not destroy the sandbox after saving.
 		sOpts.Resume = true
 	}
 
+	if c.chunkStore != "" {
+		// The checkpoint's pages file links to the chunk store.
+		if sOpts.ChunkStore, err = filepath.Abs(c.chunkStore); err != nil {
+			util.Fatalf("resolving chunk store path: %v", err)
+		}
+	}
+
 	if err := cont.Checkpoint(c.imagePath, c.direct, sOpts, mfOpts); err != nil {
 		util.Fatalf("checkpoint failed: %v", err)
 	}
 
+	// Only link the parent once the checkpoint is complete, so that a failed
+	// checkpoint doesn't leave a partial incremental image behind.
+	if parent != "" {
+		if err := os.Symlink(parent, link); err != nil {
+			util.Fatalf("linking parent checkpoint: %v", err)
+		}
+	}
+
 	return subcommands.ExitSuccess
 }
 
+// readEncryptionKey returns the checkpoint encryption key in the file at path,
+// or nil if path is empty. The file holds either the raw key or its hex
+// encoding.
+func readEncryptionKey(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == statefile.EncryptionKeySize {
+		return b, nil
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(key) != statefile.EncryptionKeySize {
+		return nil, fmt.Errorf("key must be %d bytes, or %d hex digits", statefile.EncryptionKeySize, 2*statefile.EncryptionKeySize)
+	}
+	return key, nil
+}
+
 // CheckpointCompression represents checkpoint image writer behavior. The
 // default behavior is to compress because the default behavior used to be to
 // always compress.
@@ -16,8 +16,11 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -27,6 +30,9 @@ import (
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/control"
+	"gvisor.dev/gvisor/pkg/tcpip/link/sniffer"
+	"gvisor.dev/gvisor/pkg/urpc"
+	"gvisor.dev/gvisor/runsc/boot"
 	"gvisor.dev/gvisor/runsc/cmd/util"
 	"gvisor.dev/gvisor/runsc/config"
 	"gvisor.dev/gvisor/runsc/container"
@@ -44,12 +50,24 @@ type Debug struct {
 	profileMutex string
 	trace        string
 	strace       string
+	straceJSON   string
+	stracePIDs   string
+	straceCIDs   string
 	logLevel     string
 	logPackets   string
 	delay        time.Duration
 	duration     time.Duration
 	ps           bool
+	syscallStats string
 	mount        string
+
+	capture               string
+	captureFilter         string
+	captureNICs           string
+	captureSnapLen        uint
+	captureRotateSize     int64
+	captureRotateInterval time.Duration
+	captureStop           bool
 }
 
 // Name implements subcommands.Command.
@@ -76,14 +94,25 @@ func (d *Debug) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&d.profileHeap, "profile-heap", "", "writes heap profile to the given file.")
 	f.StringVar(&d.profileMutex, "profile-mutex", "", "writes mutex profile to the given file.")
 	f.DurationVar(&d.delay, "delay", time.Hour, "amount of time to delay for collecting heap and goroutine profiles.")
-	f.DurationVar(&d.duration, "duration", time.Hour, "amount of time to wait for CPU and trace profiles.")
+	f.DurationVar(&d.duration, "duration", time.Hour, "amount of time to wait for CPU and trace profiles, and packet captures.")
 	f.StringVar(&d.trace, "trace", "", "writes an execution trace to the given file.")
 	f.IntVar(&d.signal, "signal", -1, "sends signal to the sandbox")
-	f.StringVar(&d.strace, "strace", "", `A comma separated list of syscalls to trace. "all" enables all traces, "off" disables all.`)
+	f.StringVar(&d.strace, "strace", "", `A comma separated list of syscalls or syscall classes (e.g. %file, %net) to trace. "all" enables all traces, "off" disables all.`)
+	f.StringVar(&d.straceJSON, "strace-json", "", "writes strace output as JSON lines to the given file instead of the log. Syscalls are selected with -strace.")
+	f.StringVar(&d.stracePIDs, "strace-pids", "", "comma separated list of process IDs to trace with -strace-json. Empty means all processes.")
+	f.StringVar(&d.straceCIDs, "strace-containers", "", "comma separated list of container IDs to trace with -strace-json. Empty means all containers.")
 	f.StringVar(&d.logLevel, "log-level", "", "The log level to set: warning (0), info (1), or debug (2).")
 	f.StringVar(&d.logPackets, "log-packets", "", "A boolean value to enable or disable packet logging: true or false.")
 	f.BoolVar(&d.ps, "ps", false, "lists processes")
+	f.StringVar(&d.syscallStats, "syscall-stats", "", `counts calls, errors and time spent per syscall in the container, like "strace -c": "on" starts counting, discarding previous counts, "print" prints the counts, and "off" prints the counts and stops counting. While counting is on, the counts are also written to the sandbox log when the container exits.`)
 	f.StringVar(&d.mount, "mount", "", "Mount a filesystem (-mount fstype:source:destination).")
+	f.StringVar(&d.capture, "capture", "", "captures network packets to the given file in the pcapng format. Stop the capture with -capture-stop, or by interrupting runsc debug.")
+	f.StringVar(&d.captureFilter, "capture-filter", "", "classic BPF program selecting the packets to capture, as printed by `tcpdump -y RAW -ddd <expression>`. Newlines may be replaced by commas.")
+	f.StringVar(&d.captureNICs, "capture-nics", "", "comma separated list of NICs to capture. Empty means all NICs.")
+	f.UintVar(&d.captureSnapLen, "capture-snaplen", 262144, "maximum number of bytes captured from each packet.")
+	f.Int64Var(&d.captureRotateSize, "capture-rotate-size", 0, "starts a new capture file once the current one reaches the given size in bytes. Files after the first are named by inserting their index before the extension, e.g. capture.1.pcapng.")
+	f.DurationVar(&d.captureRotateInterval, "capture-rotate-interval", 0, "starts a new capture file once the current one is older than the given duration.")
+	f.BoolVar(&d.captureStop, "capture-stop", false, "stops a running packet capture.")
 }
 
 // Execute implements subcommands.Command.Execute.
@@ -167,19 +196,54 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		case "off":
 			util.Infof("Disabling strace")
 			args.SetStrace = true
+			args.SetJSONStrace = true
 
 		case "all":
+			if len(d.straceJSON) != 0 {
+				util.Infof("Enabling all JSON straces to %q", d.straceJSON)
+				args.SetJSONStrace = true
+				args.EnableJSONStrace = true
+				break
+			}
 			util.Infof("Enabling all straces")
 			args.SetStrace = true
 			args.EnableStrace = true
 
 		default:
+			if len(d.straceJSON) != 0 {
+				util.Infof("Enabling JSON strace to %q for syscalls: %s", d.straceJSON, d.strace)
+				args.SetJSONStrace = true
+				args.EnableJSONStrace = true
+				args.StraceJSONAllowlist = strings.Split(d.strace, ",")
+				break
+			}
 			util.Infof("Enabling strace for syscalls: %s", d.strace)
 			args.SetStrace = true
 			args.EnableStrace = true
 			args.StraceAllowlist = strings.Split(d.strace, ",")
 		}
 
+		if args.EnableJSONStrace {
+			if len(d.stracePIDs) != 0 {
+				for _, p := range strings.Split(d.stracePIDs, ",") {
+					pid, err := strconv.ParseInt(p, 10, 32)
+					if err != nil {
+						return util.Errorf("invalid PID %q in -strace-pids: %v", p, err)
+					}
+					args.StraceJSONPIDs = append(args.StraceJSONPIDs, int32(pid))
+				}
+			}
+			if len(d.straceCIDs) != 0 {
+				args.StraceJSONContainerIDs = strings.Split(d.straceCIDs, ",")
+			}
+			f, err := os.OpenFile(d.straceJSON, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return util.Errorf("error opening strace JSON file %q: %v", d.straceJSON, err)
+			}
+			defer f.Close()
+			args.FilePayload = urpc.FilePayload{Files: []*os.File{f}}
+		}
+
 		if len(d.logLevel) != 0 {
 			args.SetLevel = true
 			switch strings.ToLower(d.logLevel) {
@@ -214,6 +278,37 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		}
 		util.Infof("Logging options changed")
 	}
+	if len(d.syscallStats) != 0 {
+		args := control.SyscallStatsArgs{ContainerID: c.ID}
+		switch strings.ToLower(d.syscallStats) {
+		case "on":
+			util.Infof("Enabling syscall stats")
+			args.SetEnabled = true
+			args.Enabled = true
+		case "off":
+			util.Infof("Disabling syscall stats")
+			args.SetEnabled = true
+		case "print":
+		default:
+			return util.Errorf("invalid value for syscall-stats %q, must be on, off or print", d.syscallStats)
+		}
+		res, err := c.Sandbox.SyscallStats(args)
+		if err != nil {
+			return util.Errorf("%s", err.Error())
+		}
+		if args.SetEnabled && args.Enabled {
+			util.Infof("Syscall stats enabled")
+		} else {
+			var b strings.Builder
+			if err := control.WriteSyscallStatsTable(&b, res.Stats); err != nil {
+				return util.Errorf("formatting syscall stats: %v", err)
+			}
+			util.Infof("     *** Syscall stats ***\n%s", b.String())
+			if !res.Enabled && !args.SetEnabled {
+				util.Infof("Syscall stats are disabled, enable them with -syscall-stats=on")
+			}
+		}
+	}
 	if d.ps {
 		util.Infof("Retrieving process list")
 		pList, err := c.Processes()
@@ -239,6 +334,13 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		}
 	}
 
+	if d.captureStop {
+		util.Infof("Stopping packet capture")
+		if err := c.Sandbox.StopCapture(); err != nil {
+			return util.Errorf("stopping packet capture: %v", err)
+		}
+	}
+
 	// Open profiling files.
 	var (
 		blockFile *os.File
@@ -286,15 +388,39 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		}
 		traceFile = f
 	}
+	var (
+		captureArgs   *boot.StartCaptureArgs
+		captureWriter *sniffer.RotatingWriter
+	)
+	if d.capture != "" {
+		captureArgs = &boot.StartCaptureArgs{
+			SnapLen:  uint32(d.captureSnapLen),
+			Duration: d.duration,
+		}
+		if d.captureNICs != "" {
+			captureArgs.NICs = strings.Split(d.captureNICs, ",")
+		}
+		if d.captureFilter != "" {
+			filter, err := sniffer.ParseFilter(d.captureFilter)
+			if err != nil {
+				return util.Errorf("invalid capture filter: %v", err)
+			}
+			captureArgs.Filter = filter
+		}
+		captureWriter = sniffer.NewRotatingWriter(func(index int) (io.WriteCloser, error) {
+			return os.OpenFile(captureFileName(d.capture, index), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		}, d.captureRotateSize, d.captureRotateInterval)
+	}
 
 	// Collect profiles.
 	var (
-		wg       sync.WaitGroup
-		blockErr error
-		cpuErr   error
-		heapErr  error
-		mutexErr error
-		traceErr error
+		wg         sync.WaitGroup
+		blockErr   error
+		cpuErr     error
+		heapErr    error
+		mutexErr   error
+		traceErr   error
+		captureErr error
 	)
 	if blockFile != nil {
 		wg.Add(1)
@@ -332,6 +458,18 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		}()
 	}
 
+	if captureWriter != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			util.Infof("Capturing packets to %q", d.capture)
+			captureErr = c.Sandbox.Capture(captureWriter, captureArgs)
+			if err := captureWriter.Close(); captureErr == nil {
+				captureErr = err
+			}
+		}()
+	}
+
 	// Before sleeping, allow us to catch signals and try to exit
 	// gracefully before just exiting. If we can't wait for wg, then
 	// we will not be able to read the errors below safely.
@@ -347,6 +485,11 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		break // Safe to proceed.
 	case <-signals:
 		util.Infof("caught signal, waiting at most one more second.")
+		if captureWriter != nil {
+			if err := c.Sandbox.StopCapture(); err != nil {
+				util.Infof("error stopping packet capture: %v", err)
+			}
+		}
 		select {
 		case <-signals:
 			util.Infof("caught second signal, exiting immediately.")
@@ -387,9 +530,23 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		os.Remove(traceFile.Name())
 	}
 
+	if captureErr != nil {
+		errorCount++
+		util.Infof("error collecting packet capture: %v", captureErr)
+	}
+
 	if errorCount > 0 {
 		return subcommands.ExitFailure
 	}
 
 	return subcommands.ExitSuccess
 }
+
+// captureFileName returns the name of the index-th packet capture file.
+func captureFileName(name string, index int) string {
+	if index == 0 {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(name, ext), index, ext)
+}
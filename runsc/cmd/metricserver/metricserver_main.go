@@ -44,12 +44,24 @@ func (c *cmd) Execute(ctx context.Context, f *flag.FlagSet, args ...any) subcomm
 		flag.CommandLine.Usage()
 		return subcommands.ExitUsageError
 	}
+	otlpHeaders, err := metricserver.ParseOTLPHeaders(c.Cmd.OTLPHeaders)
+	if err != nil {
+		util.Fatalf("invalid --otlp-headers: %v", err)
+	}
 	server := metricserver.Server{
 		Config:                 conf,
 		PIDFile:                c.Cmd.PIDFile,
 		ExporterPrefix:         c.Cmd.ExporterPrefix,
 		ExposeProfileEndpoints: c.Cmd.ExposeProfileEndpoints,
 		AllowUnknownRoot:       c.Cmd.AllowUnknownRoot,
+		OTLPEndpoint:           c.Cmd.OTLPEndpoint,
+		OTLPProtocol:           c.Cmd.OTLPProtocol,
+		OTLPHeaders:            otlpHeaders,
+		OTLPInterval:           c.Cmd.OTLPInterval,
+		OTLPTLS:                c.Cmd.OTLPTLS,
+		OTLPTLSCAFile:          c.Cmd.OTLPTLSCAFile,
+		SandboxProfileInterval: c.Cmd.SandboxProfileInterval,
+		SandboxProfileDuration: c.Cmd.SandboxProfileDuration,
 	}
 	if err := server.Run(ctx); err != nil {
 		return util.Errorf("%v", err)
@@ -16,6 +16,8 @@
 package metricservercmd
 
 import (
+	"time"
+
 	"gvisor.dev/gvisor/runsc/flag"
 )
 
@@ -25,6 +27,14 @@ type Cmd struct {
 	PIDFile                string
 	ExposeProfileEndpoints bool
 	AllowUnknownRoot       bool
+	OTLPEndpoint           string
+	OTLPProtocol           string
+	OTLPHeaders            string
+	OTLPInterval           time.Duration
+	OTLPTLS                bool
+	OTLPTLSCAFile          string
+	SandboxProfileInterval time.Duration
+	SandboxProfileDuration time.Duration
 }
 
 // Name implements subcommands.Command.Name.
@@ -48,4 +58,12 @@ func (c *Cmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&c.PIDFile, "pid-file", "", "If set, write the metric server's own PID to this file after binding to the --metric-server address. The parent directory of this file must already exist.")
 	f.BoolVar(&c.ExposeProfileEndpoints, "allow-profiling", false, "If true, expose /runsc-metrics/profile-cpu and /runsc-metrics/profile-heap to get profiling data about the metric server")
 	f.BoolVar(&c.AllowUnknownRoot, "allow-unknown-root", false, "if set, the metric server will keep running regardless of the existence of --root or the metric server's ability to access it.")
+	f.StringVar(&c.OTLPEndpoint, "otlp-endpoint", "", "If set, periodically push metrics to the OpenTelemetry collector at this address (host:port or unix:<path>), in addition to serving them over HTTP.")
+	f.StringVar(&c.OTLPProtocol, "otlp-protocol", "grpc", "Protocol used to push metrics to --otlp-endpoint: grpc or http.")
+	f.StringVar(&c.OTLPHeaders, "otlp-headers", "", "Comma-separated list of key=value headers added to requests sent to --otlp-endpoint.")
+	f.DurationVar(&c.OTLPInterval, "otlp-interval", 60*time.Second, "Interval at which metrics are pushed to --otlp-endpoint.")
+	f.BoolVar(&c.OTLPTLS, "otlp-tls", false, "If true, connect to --otlp-endpoint over TLS.")
+	f.StringVar(&c.OTLPTLSCAFile, "otlp-tls-ca", "", "PEM file holding the CA certificates used to verify --otlp-endpoint with --otlp-tls. Defaults to the host's CA certificates.")
+	f.DurationVar(&c.SandboxProfileInterval, "sandbox-profile-interval", 0, "If set, collect sentry CPU, heap and mutex profiles and guest CPU profiles from each sandbox at this interval, and serve the latest ones in pprof format on /runsc-metrics/sandbox-profile/<type>?sandbox=<id>. Sandboxes must run with --profile.")
+	f.DurationVar(&c.SandboxProfileDuration, "sandbox-profile-duration", 10*time.Second, "Duration of the CPU, mutex and guest profiles collected with --sandbox-profile-interval.")
 }
@@ -0,0 +1,141 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"net"
+	"os"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/container"
+	"gvisor.dev/gvisor/runsc/flag"
+	"gvisor.dev/gvisor/runsc/sandbox"
+)
+
+// Migrate implements subcommands.Command for the "migrate" command.
+type Migrate struct {
+	// keyFile is the path to the key that authenticates and encrypts the
+	// migration stream, which both sides must share.
+	keyFile string
+
+	// Sending side.
+	rounds        int
+	stopCopyBytes uint64
+
+	// Receiving side.
+	receive   bool
+	imagePath string
+}
+
+// Name implements subcommands.Command.Name.
+func (*Migrate) Name() string {
+	return "migrate"
+}
+
+// Synopsis implements subcommands.Command.Synopsis.
+func (*Migrate) Synopsis() string {
+	return "live migrate a container to another sandbox (experimental)"
+}
+
+// Usage implements subcommands.Command.Usage.
+func (*Migrate) Usage() string {
+	return `migrate --key-file=<path> [flags] <container id> <socket path> - copy the
+    container's memory to the receiver listening on socket path while the
+    container runs, then stop the container and send the memory changed since.
+migrate --receive --key-file=<path> --image-path=<path> <socket path> - receive
+    a container on socket path and save it to image path, to be restored with
+    "runsc restore --background".
+
+Both sides must use the same key, which authenticates and encrypts the
+migration stream.
+`
+}
+
+// SetFlags implements subcommands.Command.SetFlags.
+func (m *Migrate) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&m.keyFile, "key-file", "", "path to a file holding the migration key, as 32 raw bytes or 64 hex digits (required)")
+	f.IntVar(&m.rounds, "rounds", 5, "maximum number of rounds copying memory while the container runs")
+	f.Uint64Var(&m.stopCopyBytes, "stop-copy-bytes", 64<<20, "stop the container once a round copies fewer bytes of memory than this")
+	f.BoolVar(&m.receive, "receive", false, "receive a container instead of sending one")
+	f.StringVar(&m.imagePath, "image-path", "", "directory path to save the received container image to")
+}
+
+// Execute implements subcommands.Command.Execute.
+func (m *Migrate) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if m.keyFile == "" {
+		util.Fatalf("key-file flag must be provided")
+	}
+	key, err := readEncryptionKey(m.keyFile)
+	if err != nil {
+		util.Fatalf("reading migration key: %v", err)
+	}
+	if m.receive {
+		if f.NArg() != 1 {
+			f.Usage()
+			return subcommands.ExitUsageError
+		}
+		return m.executeReceive(f.Arg(0), key)
+	}
+	if f.NArg() != 2 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	id := f.Arg(0)
+	conf := args[0].(*config.Config)
+
+	cont, err := container.Load(conf.RootDir, container.FullID{ContainerID: id}, container.LoadOpts{})
+	if err != nil {
+		util.Fatalf("loading container: %v", err)
+	}
+	conn, err := net.Dial("unix", f.Arg(1))
+	if err != nil {
+		util.Fatalf("connecting to receiver: %v", err)
+	}
+	defer conn.Close()
+	opts := container.MigrateOpts{
+		Rounds:        m.rounds,
+		StopCopyBytes: m.stopCopyBytes,
+	}
+	if err := cont.Migrate(conn, key, opts); err != nil {
+		util.Fatalf("migrating container: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
+
+func (m *Migrate) executeReceive(socketPath string, key []byte) subcommands.ExitStatus {
+	if m.imagePath == "" {
+		util.Fatalf("image-path flag must be provided")
+	}
+	if err := os.MkdirAll(m.imagePath, 0755); err != nil {
+		util.Fatalf("making directories at path provided: %v", err)
+	}
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		util.Fatalf("listening on %q: %v", socketPath, err)
+	}
+	conn, err := l.Accept()
+	l.Close()
+	if err != nil {
+		util.Fatalf("accepting connection: %v", err)
+	}
+	defer conn.Close()
+	if err := sandbox.ReceiveMigration(conn, key, m.imagePath); err != nil {
+		util.Fatalf("receiving container: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
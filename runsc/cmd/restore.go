@@ -19,9 +19,11 @@ import (
 	"os"
 
 	"github.com/google/subcommands"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/cleanup"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/state/statefile"
 	"gvisor.dev/gvisor/runsc/cmd/util"
 	"gvisor.dev/gvisor/runsc/config"
 	"gvisor.dev/gvisor/runsc/container"
@@ -49,10 +51,21 @@ type Restore struct {
 
 	// If background is true, the container image may continue to be read after
 	// the restore command exits. For large images, this significantly shortens
-	// the amount of time taken by the restore command. The checkpoint must be
-	// uncompressed for background to work; if the checkpoint is compressed,
+	// the amount of time taken by the restore command. The checkpoint must have
+	// a pages file, i.e. be uncompressed or compressed with zstd or lz4, for
+	// background to work; if the checkpoint is compressed with flate,
 	// background has no effect.
 	background bool
+
+	// encryptionKeyFile is the path to the file holding the key the
+	// checkpoint was encrypted with.
+	encryptionKeyFile string
+
+	// networkNamespace is the path to the network namespace the restored
+	// sandbox joins, instead of the one in the spec. With --network=sandbox,
+	// netstack NICs, addresses and routes are reconfigured from it, and
+	// connections that can't be restored on it are reset.
+	networkNamespace string
 }
 
 // Name implements subcommands.Command.Name.
@@ -76,7 +89,9 @@ func (r *Restore) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&r.imagePath, "image-path", "", "directory path to saved container image")
 	f.BoolVar(&r.detach, "detach", false, "detach from the container's process")
 	f.BoolVar(&r.direct, "direct", false, "use O_DIRECT for reading checkpoint pages file")
-	f.BoolVar(&r.background, "background", false, "allow image loading to continue after restore exits (requires uncompressed checkpoint)")
+	f.BoolVar(&r.background, "background", false, "allow image loading to continue after restore exits (requires a checkpoint taken with --compression=none, zstd or lz4)")
+	f.StringVar(&r.encryptionKeyFile, "encryption-key-file", "", "file holding the key the checkpoint image was encrypted with; use /dev/fd/N to pass it as a file descriptor")
+	f.StringVar(&r.networkNamespace, "network-namespace", "", "path to the network namespace to restore the sandbox into, instead of the one in the spec")
 
 	// Unimplemented flags necessary for compatibility with docker.
 
@@ -109,6 +124,13 @@ func (r *Restore) Execute(_ context.Context, f *flag.FlagSet, args ...any) subco
 	if r.imagePath == "" {
 		return util.Errorf("image-path flag must be provided")
 	}
+	var (
+		sfOpts statefile.Options
+		err    error
+	)
+	if sfOpts.EncryptionKey, err = readEncryptionKey(r.encryptionKeyFile); err != nil {
+		return util.Errorf("reading encryption key: %v", err)
+	}
 
 	var cu cleanup.Cleanup
 	defer cu.Clean()
@@ -137,6 +159,9 @@ func (r *Restore) Execute(_ context.Context, f *flag.FlagSet, args ...any) subco
 		if runArgs.Spec, err = specutils.ReadSpec(bundleDir, conf); err != nil {
 			return util.Errorf("reading spec: %v", err)
 		}
+		if r.networkNamespace != "" {
+			setNetworkNamespace(runArgs.Spec, r.networkNamespace)
+		}
 		specutils.LogSpecDebug(runArgs.Spec, conf.OCISeccomp)
 
 		if c, err = container.New(conf, runArgs); err != nil {
@@ -149,11 +174,14 @@ func (r *Restore) Execute(_ context.Context, f *flag.FlagSet, args ...any) subco
 			c.Destroy()
 		})
 	} else {
+		if r.networkNamespace != "" {
+			return util.Errorf("network-namespace flag can't be used to restore existing container %q", id)
+		}
 		runArgs.Spec = c.Spec
 	}
 
 	log.Debugf("Restore: %v", r.imagePath)
-	err = c.Restore(conf, r.imagePath, r.direct, r.background)
+	err = c.RestoreWithOptions(conf, r.imagePath, r.direct, r.background, sfOpts)
 	if err != nil {
 		return util.Errorf("starting container: %v", err)
 	}
@@ -178,3 +206,18 @@ func (r *Restore) Execute(_ context.Context, f *flag.FlagSet, args ...any) subco
 
 	return subcommands.ExitSuccess
 }
+
+// setNetworkNamespace makes the sandbox created from spec join the network
+// namespace at path.
+func setNetworkNamespace(spec *specs.Spec, path string) {
+	ns := specs.LinuxNamespace{Type: specs.NetworkNamespace, Path: path}
+	if spec.Linux != nil {
+		for i := range spec.Linux.Namespaces {
+			if spec.Linux.Namespaces[i].Type == specs.NetworkNamespace {
+				spec.Linux.Namespaces[i] = ns
+				return
+			}
+		}
+	}
+	addNamespace(spec, ns)
+}
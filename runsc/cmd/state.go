@@ -25,10 +25,19 @@ import (
 	"gvisor.dev/gvisor/runsc/config"
 	"gvisor.dev/gvisor/runsc/container"
 	"gvisor.dev/gvisor/runsc/flag"
+	"gvisor.dev/gvisor/runsc/sandbox"
 )
 
 // State implements subcommands.Command for the "state" command.
-type State struct{}
+type State struct {
+	// merge is the path to an incremental checkpoint to merge with its
+	// parents, instead of getting the state of a container.
+	merge string
+
+	// encryptionKeyFile is the path to the file holding the key that the
+	// checkpoints to merge were encrypted with.
+	encryptionKeyFile string
+}
 
 // Name implements subcommands.Command.Name.
 func (*State) Name() string {
@@ -37,23 +46,33 @@ func (*State) Name() string {
 
 // Synopsis implements subcommands.Command.Synopsis.
 func (*State) Synopsis() string {
-	return "get the state of a container"
+	return "get the state of a container, or merge incremental checkpoints"
 }
 
 // Usage implements subcommands.Command.Usage.
 func (*State) Usage() string {
-	return "state [flags] <container id> - get the state of a container\n"
+	return `state [flags] <container id> - get the state of a container
+state -merge=<image path> [-encryption-key-file=<path>] <output path> - merge
+    the incremental checkpoint at <image path> with its parents into a full
+    checkpoint at <output path>
+`
 }
 
 // SetFlags implements subcommands.Command.SetFlags.
-func (*State) SetFlags(*flag.FlagSet) {}
+func (s *State) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&s.merge, "merge", "", "path to an incremental checkpoint image to merge with its parents into a full checkpoint image at the output path. The input images are not modified.")
+	f.StringVar(&s.encryptionKeyFile, "encryption-key-file", "", "with -merge, file holding the key the checkpoint images were encrypted with, which also encrypts the merged image; use /dev/fd/N to pass it as a file descriptor")
+}
 
 // Execute implements subcommands.Command.Execute.
-func (*State) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+func (s *State) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
 	if f.NArg() != 1 {
 		f.Usage()
 		return subcommands.ExitUsageError
 	}
+	if s.merge != "" {
+		return mergeCheckpoint(s.merge, f.Arg(0), s.encryptionKeyFile)
+	}
 
 	id := f.Arg(0)
 	conf := args[0].(*config.Config)
@@ -74,3 +93,19 @@ func (*State) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomman
 	}
 	return subcommands.ExitSuccess
 }
+
+// mergeCheckpoint writes the checkpoint at imagePath, merged with its parents,
+// as a full checkpoint to outPath.
+func mergeCheckpoint(imagePath, outPath, encryptionKeyFile string) subcommands.ExitStatus {
+	encryptionKey, err := readEncryptionKey(encryptionKeyFile)
+	if err != nil {
+		util.Fatalf("reading encryption key: %v", err)
+	}
+	if err := os.MkdirAll(outPath, 0755); err != nil {
+		util.Fatalf("making directories at output path: %v", err)
+	}
+	if err := sandbox.MergeCheckpoint(imagePath, outPath, encryptionKey); err != nil {
+		util.Fatalf("merging checkpoint: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
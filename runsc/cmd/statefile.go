@@ -15,24 +15,33 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/google/subcommands"
 	"gvisor.dev/gvisor/pkg/state/pretty"
 	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/runsc/boot"
 	"gvisor.dev/gvisor/runsc/cmd/util"
 	"gvisor.dev/gvisor/runsc/flag"
 )
 
 // Statefile implements subcommands.Command for the "statefile" command.
 type Statefile struct {
-	list   bool
-	get    string
-	key    string
-	output string
-	html   bool
+	list              bool
+	get               string
+	summary           bool
+	pages             bool
+	diff              string
+	key               string
+	encryptionKeyFile string
+	output            string
+	html              bool
 }
 
 // Name implements subcommands.Command.
@@ -47,14 +56,22 @@ func (*Statefile) Synopsis() string {
 
 // Usage implements subcommands.Command.
 func (*Statefile) Usage() string {
-	return "statefile [flags] <statefile>\n"
+	return `statefile [flags] <statefile>
+
+The -summary, -pages and -diff flags also read the pages metadata file next to
+<statefile>, if any, so that they cover the whole checkpoint image.
+`
 }
 
 // SetFlags implements subcommands.Command.
 func (s *Statefile) SetFlags(f *flag.FlagSet) {
 	f.BoolVar(&s.list, "list", false, "lists the metadata in the statefile.")
 	f.StringVar(&s.get, "get", "", "extracts the given metadata key.")
+	f.BoolVar(&s.summary, "summary", false, "summarizes the objects in the statefile by type.")
+	f.BoolVar(&s.pages, "pages", false, "breaks down the saved pages by mapping: anonymous memory per process, shared memory, tmpfs, etc.")
+	f.StringVar(&s.diff, "diff", "", "compares the statefile's objects with those of the given statefile.")
 	f.StringVar(&s.key, "key", "", "the integrity key for the file.")
+	f.StringVar(&s.encryptionKeyFile, "encryption-key-file", "", "file holding the key the checkpoint image was encrypted with.")
 	f.StringVar(&s.output, "output", "", "target to write the result.")
 	f.BoolVar(&s.html, "html", false, "outputs in HTML format.")
 }
@@ -62,8 +79,25 @@ func (s *Statefile) SetFlags(f *flag.FlagSet) {
 // Execute implements subcommands.Command.Execute.
 func (s *Statefile) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
 	// Check arguments.
-	if s.list && s.get != "" {
-		util.Fatalf("error: can't specify -list and -get simultaneously.")
+	modes := 0
+	for _, set := range []bool{s.list, s.get != "", s.summary, s.pages, s.diff != ""} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		util.Fatalf("error: can't specify more than one of -list, -get, -summary, -pages and -diff.")
+	}
+	if s.html && (s.summary || s.pages || s.diff != "") {
+		util.Fatalf("error: -html can't be used with -summary, -pages or -diff.")
+	}
+	var key []byte
+	if s.key != "" {
+		key = []byte(s.key)
+	}
+	encryptionKey, err := readEncryptionKey(s.encryptionKeyFile)
+	if err != nil {
+		util.Fatalf("reading encryption key: %v", err)
 	}
 
 	// Setup output.
@@ -86,6 +120,36 @@ func (s *Statefile) Execute(_ context.Context, f *flag.FlagSet, args ...any) sub
 		f.Usage()
 		return subcommands.ExitUsageError
 	}
+
+	// Inspect the whole image?
+	if s.summary || s.pages || s.diff != "" {
+		r, err := openImageState(f.Arg(0), key, encryptionKey)
+		if err != nil {
+			util.Fatalf("error opening image: %v", err)
+		}
+		defer r.Close()
+		switch {
+		case s.summary:
+			err = pretty.PrintSummary(output, r)
+		case s.pages:
+			var graphs []*pretty.Graph
+			if graphs, err = pretty.ReadGraphs(r); err == nil {
+				err = printPagesBreakdown(output, graphs)
+			}
+		default:
+			var other io.ReadCloser
+			if other, err = openImageState(s.diff, key, encryptionKey); err != nil {
+				util.Fatalf("error opening image: %v", err)
+			}
+			defer other.Close()
+			err = pretty.PrintDiff(output, r, other)
+		}
+		if err != nil {
+			util.Fatalf("error inspecting state: %v", err)
+		}
+		return subcommands.ExitSuccess
+	}
+
 	input, err := os.Open(f.Arg(0))
 	if err != nil {
 		util.Fatalf("error opening input: %v\n", err)
@@ -98,11 +162,7 @@ func (s *Statefile) Execute(_ context.Context, f *flag.FlagSet, args ...any) sub
 
 	// Dump the full file?
 	if !s.list && s.get == "" {
-		var key []byte
-		if s.key != "" {
-			key = []byte(s.key)
-		}
-		rc, _, err := statefile.NewReader(input, key)
+		rc, _, _, err := statefile.NewEncryptedReader(input, key, encryptionKey)
 		if err != nil {
 			util.Fatalf("error parsing statefile: %v", err)
 		}
@@ -149,3 +209,57 @@ func (s *Statefile) Execute(_ context.Context, f *flag.FlagSet, args ...any) sub
 	}
 	return subcommands.ExitSuccess
 }
+
+// imageState is the state of a checkpoint image: the state file's state
+// stream followed by the pages metadata file's, if any.
+type imageState struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close implements io.Closer.Close.
+func (s *imageState) Close() error {
+	var errs []error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		errs = append(errs, s.closers[i].Close())
+	}
+	return errors.Join(errs...)
+}
+
+// openImageState returns the state of the checkpoint image whose state file
+// is at path.
+func openImageState(path string, key, encryptionKey []byte) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	rc, _, enc, err := statefile.NewEncryptedReader(f, key, encryptionKey)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("parsing statefile: %w", err)
+	}
+	s := &imageState{
+		Reader:  bufio.NewReader(rc),
+		closers: []io.Closer{rc},
+	}
+	pmf, err := os.Open(filepath.Join(filepath.Dir(path), boot.CheckpointPagesMetadataFileName))
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.closers = append(s.closers, pmf)
+	var pagesMetadata io.Reader = pmf
+	if enc != nil {
+		pmr, err := enc.NewStreamReader(pmf, statefile.EncryptionPurposePagesMetadata)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.closers = append(s.closers, pmr)
+		pagesMetadata = pmr
+	}
+	s.Reader = io.MultiReader(s.Reader, bufio.NewReader(pagesMetadata))
+	return s, nil
+}
@@ -0,0 +1,290 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/state/pretty"
+	"gvisor.dev/gvisor/pkg/state/wire"
+)
+
+// Names of the types inspected to break down saved pages. These are read
+// from the image without decoding it, so they must be kept in sync with the
+// corresponding types' fields.
+const (
+	memAcctSetType    = "pkg/sentry/pgalloc.memAcctSet"
+	privateMFMetaType = "pkg/sentry/kernel.privateMemoryFileMetadata"
+	memoryManagerType = "pkg/sentry/mm.MemoryManager"
+	taskType          = "pkg/sentry/kernel.Task"
+	shmType           = "pkg/sentry/kernel/shm.Shm"
+)
+
+// ownedRange is a range of offsets into the main MemoryFile that is
+// attributed to a single owner.
+type ownedRange struct {
+	start, end uint64
+	owner      string
+}
+
+// memoryFilePages is the breakdown of the pages saved for one MemoryFile.
+type memoryFilePages struct {
+	name string
+
+	// committed maps categories to the number of committed bytes in them.
+	committed map[string]uint64
+
+	// saved is the number of bytes of page data saved.
+	saved uint64
+}
+
+// wireUint returns the value of an integer field, which may be encoded as a
+// zero value.
+func wireUint(obj wire.Object) uint64 {
+	switch x := obj.(type) {
+	case wire.Uint:
+		return uint64(x)
+	case wire.Int:
+		return uint64(x)
+	default:
+		return 0
+	}
+}
+
+// wireBool returns the value of a boolean field.
+func wireBool(obj wire.Object) bool {
+	b, ok := obj.(wire.Bool)
+	return ok && bool(b)
+}
+
+// wireString returns the value of a string field.
+func wireString(obj wire.Object) string {
+	if s, ok := obj.(*wire.String); ok {
+		return string(*s)
+	}
+	return ""
+}
+
+// wireStruct returns obj, or the object it refers to, as a struct.
+func wireStruct(g *pretty.Graph, obj wire.Object) *wire.Struct {
+	if ref, ok := obj.(*wire.Ref); ok {
+		obj = g.Deref(ref)
+	}
+	s, _ := obj.(*wire.Struct)
+	return s
+}
+
+// flatSegments returns the FlatSegments saved for a segment.Set.
+func flatSegments(g *pretty.Graph, set wire.Object) []*wire.Struct {
+	s := wireStruct(g, set)
+	if s == nil {
+		return nil
+	}
+	root, ok := g.Field(s, "root").(*wire.Slice)
+	if !ok {
+		return nil
+	}
+	var segs []*wire.Struct
+	for _, elem := range g.Elements(root) {
+		if seg, ok := elem.(*wire.Struct); ok {
+			segs = append(segs, seg)
+		}
+	}
+	return segs
+}
+
+// ownedRanges returns the ranges of the main MemoryFile used by shared memory
+// segments and by private mappings of each process, sorted by offset and
+// trimmed to be non-overlapping.
+func ownedRanges(graphs []*pretty.Graph) []ownedRange {
+	var owned []ownedRange
+	for _, g := range graphs {
+		mmNames := make(map[uint64]string)
+		for _, obj := range g.Objects {
+			s, ok := obj.(*wire.Struct)
+			if !ok || g.TypeName(s) != taskType {
+				continue
+			}
+			image := wireStruct(g, g.Field(s, "image"))
+			if image == nil {
+				continue
+			}
+			if ref, ok := g.Field(image, "MemoryManager").(*wire.Ref); ok && ref.Root != 0 {
+				mmNames[uint64(ref.Root)] = wireString(g.Field(image, "Name"))
+			}
+		}
+		for id, obj := range g.Objects {
+			s, ok := obj.(*wire.Struct)
+			if !ok {
+				continue
+			}
+			switch g.TypeName(s) {
+			case shmType:
+				if fr := wireStruct(g, g.Field(s, "fr")); fr != nil {
+					owned = append(owned, ownedRange{
+						start: wireUint(g.Field(fr, "Start")),
+						end:   wireUint(g.Field(fr, "End")),
+						owner: "shm",
+					})
+				}
+			case memoryManagerType:
+				owner := fmt.Sprintf("process %q (mm %d)", mmNames[id], id)
+				for _, seg := range flatSegments(g, g.Field(s, "pmas")) {
+					pma := wireStruct(g, g.Field(seg, "Value"))
+					if pma == nil || !wireBool(g.Field(pma, "private")) {
+						continue
+					}
+					off := wireUint(g.Field(pma, "off"))
+					owned = append(owned, ownedRange{
+						start: off,
+						end:   off + wireUint(g.Field(seg, "End")) - wireUint(g.Field(seg, "Start")),
+						owner: owner,
+					})
+				}
+			}
+		}
+	}
+
+	// Pages shared by multiple processes, e.g. after fork, are attributed
+	// to only one of them.
+	sort.Slice(owned, func(i, j int) bool {
+		if owned[i].start != owned[j].start {
+			return owned[i].start < owned[j].start
+		}
+		return owned[i].owner < owned[j].owner
+	})
+	var (
+		trimmed []ownedRange
+		covered uint64
+	)
+	for _, r := range owned {
+		r.start = max(r.start, covered)
+		if r.start >= r.end {
+			continue
+		}
+		trimmed = append(trimmed, r)
+		covered = r.end
+	}
+	return trimmed
+}
+
+// pagesBreakdown returns the breakdown of the pages saved for each
+// MemoryFile in graphs, main MemoryFile first.
+func pagesBreakdown(graphs []*pretty.Graph) []*memoryFilePages {
+	var owners []string
+	for _, g := range graphs {
+		if s := wireStruct(g, g.Root()); s != nil && g.TypeName(s) == privateMFMetaType {
+			if ownersSlice, ok := g.Field(s, "owners").(*wire.Slice); ok {
+				for _, owner := range g.Elements(ownersSlice) {
+					owners = append(owners, wireString(owner))
+				}
+			}
+		}
+	}
+	owned := ownedRanges(graphs)
+
+	var mfs []*memoryFilePages
+	for _, g := range graphs {
+		s := wireStruct(g, g.Root())
+		if s == nil || g.TypeName(s) != memAcctSetType {
+			if len(mfs) != 0 {
+				// Page data follows the MemoryFile's metadata.
+				mfs[len(mfs)-1].saved += g.DataBytes()
+			}
+			continue
+		}
+		mf := &memoryFilePages{
+			name:      "main",
+			committed: make(map[string]uint64),
+			saved:     g.DataBytes(),
+		}
+		if i := len(mfs); i != 0 {
+			mf.name = fmt.Sprintf("private #%d", i)
+			if i <= len(owners) {
+				mf.name = fmt.Sprintf("private %q", owners[i-1])
+			}
+		}
+		for _, seg := range flatSegments(g, s) {
+			info := wireStruct(g, g.Field(seg, "Value"))
+			if info == nil || !wireBool(g.Field(info, "knownCommitted")) {
+				continue
+			}
+			start, end := wireUint(g.Field(seg, "Start")), wireUint(g.Field(seg, "End"))
+			kind := usage.MemoryKind(wireUint(g.Field(info, "kind")))
+			if kind != usage.Anonymous || len(mfs) != 0 {
+				mf.committed[kind.String()] += end - start
+				continue
+			}
+			// Attribute anonymous memory in the main MemoryFile to
+			// its owners.
+			unowned := end - start
+			i := sort.Search(len(owned), func(i int) bool { return owned[i].end > start })
+			for ; i < len(owned) && owned[i].start < end; i++ {
+				n := min(end, owned[i].end) - max(start, owned[i].start)
+				mf.committed[owned[i].owner] += n
+				unowned -= n
+			}
+			mf.committed[kind.String()] += unowned
+		}
+		mfs = append(mfs, mf)
+	}
+	return mfs
+}
+
+// printPagesBreakdown prints the breakdown of the pages saved in the image
+// made of graphs to w.
+func printPagesBreakdown(w io.Writer, graphs []*pretty.Graph) error {
+	mfs := pagesBreakdown(graphs)
+	if len(mfs) == 0 {
+		return fmt.Errorf("no MemoryFile found in image")
+	}
+	for _, mf := range mfs {
+		type category struct {
+			name  string
+			bytes uint64
+		}
+		var (
+			categories []category
+			total      uint64
+		)
+		for name, bytes := range mf.committed {
+			if bytes != 0 {
+				categories = append(categories, category{name, bytes})
+				total += bytes
+			}
+		}
+		sort.Slice(categories, func(i, j int) bool {
+			if categories[i].bytes != categories[j].bytes {
+				return categories[i].bytes > categories[j].bytes
+			}
+			return categories[i].name < categories[j].name
+		})
+		fmt.Fprintf(w, "MemoryFile %s: %d bytes committed, %d bytes saved\n", mf.name, total, mf.saved)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "BYTES\tCATEGORY\n")
+		for _, c := range categories {
+			fmt.Fprintf(tw, "%d\t%s\n", c.bytes, c.name)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/google/subcommands"
+	"google.golang.org/protobuf/encoding/protojson"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/file"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/remote/wire"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+const (
+	readFormatJSON   = "json"
+	readFormatReplay = "replay"
+)
+
+// read implements subcommands.Command for the "read" command.
+type read struct {
+	format string
+	out    string
+}
+
+// Name implements subcommands.Command.
+func (*read) Name() string {
+	return "read"
+}
+
+// Synopsis implements subcommands.Command.
+func (*read) Synopsis() string {
+	return "read points from files written by the file sink"
+}
+
+// Usage implements subcommands.Command.
+func (*read) Usage() string {
+	return `read [flags] <file>... - read points from files written by the file sink
+
+Files written by a sink that rotates files are read in the order they were
+written, so all of them can be passed at once, e.g. "trace.*". Empty files are
+skipped. Compressed files are detected automatically.
+
+With --format=json, one JSON object is printed per point. With
+--format=replay, points from all files are combined into a single uncompressed
+file that can be replayed with tools/tracereplay.
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (r *read) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&r.format, "format", readFormatJSON, "output format: json or replay")
+	f.StringVar(&r.out, "out", "", "file to write output to, defaults to stdout")
+}
+
+// Execute implements subcommands.Command.
+func (r *read) Execute(_ context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
+	if f.NArg() == 0 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	if r.format != readFormatJSON && r.format != readFormatReplay {
+		util.Fatalf("invalid --format %q, must be %q or %q", r.format, readFormatJSON, readFormatReplay)
+	}
+
+	out := os.Stdout
+	if len(r.out) > 0 {
+		var err error
+		out, err = os.Create(r.out)
+		if err != nil {
+			util.Fatalf("creating output file: %v", err)
+		}
+		defer out.Close()
+	}
+	w := bufio.NewWriter(out)
+
+	if r.format == readFormatReplay {
+		if err := writeReplayHeader(w); err != nil {
+			util.Fatalf("writing output: %v", err)
+		}
+	}
+	var inputs []input
+	defer func() {
+		for _, in := range inputs {
+			_ = in.f.Close()
+		}
+	}()
+	for _, name := range f.Args() {
+		in, err := os.Open(name)
+		if err != nil {
+			util.Fatalf("opening %q: %v", name, err)
+		}
+		rd, err := file.NewReader(in)
+		if err != nil {
+			_ = in.Close()
+			if errors.Is(err, io.EOF) {
+				// Files used for rotation are empty until the sink gets to
+				// them.
+				continue
+			}
+			util.Fatalf("reading %q: %v", name, err)
+		}
+		inputs = append(inputs, input{name: name, f: in, rd: rd})
+	}
+	sort.SliceStable(inputs, func(i, j int) bool {
+		return inputs[i].rd.Config().Sequence < inputs[j].rd.Config().Sequence
+	})
+	for _, in := range inputs {
+		if err := r.readFile(w, in.rd); err != nil {
+			util.Fatalf("reading %q: %v", in.name, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		util.Fatalf("writing output: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
+
+// input is a trace file being read.
+type input struct {
+	name string
+	f    *os.File
+	rd   *file.Reader
+}
+
+func (r *read) readFile(w io.Writer, rd *file.Reader) error {
+	for {
+		rec, err := rd.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if r.format == readFormatReplay {
+			err = writeWithSize(w, rec)
+		} else {
+			err = writeJSON(w, rec)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// jsonPoint is the JSON representation of a point.
+type jsonPoint struct {
+	Type         string          `json:"type"`
+	DroppedCount uint32          `json:"dropped_count,omitempty"`
+	Point        json.RawMessage `json:"point"`
+}
+
+func writeJSON(w io.Writer, rec []byte) error {
+	hdr, msg, err := file.Decode(rec)
+	if err != nil {
+		return err
+	}
+	point, err := protojson.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	out, err := json.Marshal(jsonPoint{
+		Type:         pb.MessageType(hdr.MessageType).String(),
+		DroppedCount: hdr.DroppedCount,
+		Point:        point,
+	})
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+	_, err = w.Write(out)
+	return err
+}
+
+// writeReplayHeader writes the beginning of a tools/tracereplay file.
+func writeReplayHeader(w io.Writer) error {
+	if _, err := io.WriteString(w, file.Signature); err != nil {
+		return err
+	}
+	cfg, err := json.Marshal(file.Config{Version: wire.CurrentVersion})
+	if err != nil {
+		return err
+	}
+	return writeWithSize(w, cfg)
+}
+
+func writeWithSize(w io.Writer, buf []byte) error {
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(buf)))
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(buf)
+	return err
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/socket/vsock"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// VsockBroker implements subcommands.Command for the "vsock-broker" command.
+type VsockBroker struct {
+	controlFD int
+}
+
+// Name implements subcommands.Command.Name.
+func (*VsockBroker) Name() string {
+	return "vsock-broker"
+}
+
+// Synopsis implements subcommands.Command.Synopsis.
+func (*VsockBroker) Synopsis() string {
+	return "connect to host Unix sockets on behalf of AF_VSOCK sockets in the sandbox"
+}
+
+// Usage implements subcommands.Command.Usage.
+func (*VsockBroker) Usage() string {
+	return "vsock-broker --control-fd=FD\n"
+}
+
+// SetFlags implements subcommands.Command.SetFlags.
+func (b *VsockBroker) SetFlags(f *flag.FlagSet) {
+	f.IntVar(&b.controlFD, "control-fd", -1, "control socket shared with the sandbox.")
+}
+
+// Execute implements subcommands.Command.Execute.
+func (b *VsockBroker) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if f.NArg() != 0 || b.controlFD < 0 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	conf := args[0].(*config.Config)
+	if conf.VsockUDS == "" {
+		util.Fatalf("--vsock-uds is not set")
+	}
+
+	if err := vsock.ServeBroker(b.controlFD, conf.VsockUDS); err != nil {
+		util.Fatalf("serving vsock broker: %v", err)
+	}
+	log.Debugf("vsock broker exiting, sandbox closed the control socket")
+	return subcommands.ExitSuccess
+}
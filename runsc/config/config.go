@@ -24,6 +24,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/refs"
@@ -119,6 +120,20 @@ type Config struct {
 	// capabilities.
 	EnableRaw bool `flag:"net-raw"`
 
+	// NetTCPRepair indicates whether established TCP connections of host
+	// network sockets are saved and restored using TCP_REPAIR. Sandboxes
+	// using host networking can only be checkpointed if it is set.
+	NetTCPRepair bool `flag:"net-tcp-repair"`
+
+	// NetTunnels indicates whether the GRE and IP-in-IP protocols are
+	// enabled in the sandbox network stack, which allows creating gre,
+	// gretap, ipip, sit and ip6tnl devices.
+	NetTunnels bool `flag:"net-tunnels"`
+
+	// NetSCTP indicates whether the SCTP protocol is enabled in the sandbox
+	// network stack.
+	NetSCTP bool `flag:"net-sctp"`
+
 	// AllowPacketEndpointWrite enables write operations on packet endpoints.
 	AllowPacketEndpointWrite bool `flag:"TESTONLY-allow-packet-endpoint-write"`
 
@@ -148,6 +163,13 @@ type Config struct {
 	// PCAP is a file to which network packets should be logged in PCAP format.
 	PCAP string `flag:"pcap-log"`
 
+	// PCAPFormat is the format of the PCAP log file: "pcap" or "pcapng".
+	PCAPFormat string `flag:"pcap-format"`
+
+	// PCAPFilter is a classic BPF program, in `tcpdump -ddd` format, that
+	// selects the packets logged to the PCAP log file.
+	PCAPFilter string `flag:"pcap-filter"`
+
 	// Platform is the platform to run on.
 	Platform string `flag:"platform"`
 
@@ -186,6 +208,10 @@ type Config struct {
 	// profiling metrics will be snapshotted.
 	ProfilingMetricsRate int `flag:"profiling-metrics-rate-us"`
 
+	// SyscallMetrics enables per-syscall latency and error metrics. They add a
+	// small overhead to every syscall.
+	SyscallMetrics bool `flag:"syscall-metrics"`
+
 	// Strace indicates that strace should be enabled.
 	Strace bool `flag:"strace"`
 
@@ -197,6 +223,10 @@ type Config struct {
 	// StraceLogSize is the max size of data blobs to display.
 	StraceLogSize uint `flag:"strace-log-size"`
 
+	// StraceJSON is the file to which strace is written in JSON format, one
+	// record per line. The same patterns as DebugLog are supported.
+	StraceJSON string `flag:"strace-json"`
+
 	// StraceEvent indicates sending strace to events if true. Strace is
 	// sent to log if false.
 	StraceEvent bool `flag:"strace-event"`
@@ -375,6 +405,29 @@ type Config struct {
 	// sockets should be disconnected upon save."
 	NetDisconnectOk bool `flag:"net-disconnect-ok"`
 
+	// VsockUDS enables AF_VSOCK sockets, forwarded to host Unix domain
+	// sockets at this path. Host processes connect to sandbox listeners
+	// through the socket at VsockUDS, and sandbox connections to the host
+	// on port P are forwarded to the socket at VsockUDS_P by the
+	// "vsock-broker" helper process, which runs outside the sandbox.
+	VsockUDS string `flag:"vsock-uds"`
+
+	// VsockCID is the AF_VSOCK context ID of the sandbox.
+	VsockCID uint `flag:"vsock-cid"`
+
+	// AutosaveInterval, if non-zero, is the interval at which the sandbox
+	// checkpoints itself to AutosaveDir while continuing to run.
+	AutosaveInterval time.Duration `flag:"autosave-interval"`
+
+	// AutosaveDir is the directory holding the images saved by periodic
+	// autosave. Images are saved in turn to AutosaveKeep+1 subdirectories,
+	// which are created by runsc before the sandbox starts.
+	AutosaveDir string `flag:"autosave-dir"`
+
+	// AutosaveKeep is the number of most recent images kept in AutosaveDir
+	// by periodic autosave.
+	AutosaveKeep int `flag:"autosave-keep"`
+
 	// TestOnlyAutosaveImagePath if not empty enables auto save for syscall tests
 	// and stores the directory path to the saved state file.
 	TestOnlyAutosaveImagePath string `flag:"TESTONLY-autosave-image-path"`
@@ -389,6 +442,11 @@ type Config struct {
 	// performed during restore.
 	RestoreSpecValidation RestoreSpecValidationPolicy `flag:"restore-spec-validation"`
 
+	// RestoreAcrossVersions allows restoring checkpoints taken by a
+	// different runsc version, as long as they have the same state format
+	// version. By default, the runsc versions must match exactly.
+	RestoreAcrossVersions bool `flag:"restore-across-versions"`
+
 	// GVisorMarkerFile enables the /proc/gvisor/kernel_is_gvisor marker file.
 	GVisorMarkerFile bool `flag:"gvisor-marker-file"`
 
@@ -422,6 +480,9 @@ func (c *Config) validate() error {
 	if c.ProfileMutex != "" && !c.ProfileEnable {
 		return fmt.Errorf("profile-mutex flag requires enabling profiling with profile flag")
 	}
+	if c.PCAPFormat != "pcap" && c.PCAPFormat != "pcapng" {
+		return fmt.Errorf("pcap-format must be pcap or pcapng, got: %q", c.PCAPFormat)
+	}
 	if c.FSGoferHostUDS && c.HostUDS != HostUDSNone {
 		// Deprecated flag was used together with flag that replaced it.
 		return fmt.Errorf("fsgofer-host-uds has been replaced with host-uds flag")
@@ -429,6 +490,9 @@ func (c *Config) validate() error {
 	if len(c.ProfilingMetrics) > 0 && len(c.ProfilingMetricsLog) == 0 {
 		return fmt.Errorf("profiling-metrics flag requires defining a profiling-metrics-log for output")
 	}
+	if len(c.StraceJSON) > 0 && c.StraceEvent {
+		return fmt.Errorf("strace-json and strace-event flags are mutually exclusive")
+	}
 	allowedCaps, _, err := nvconf.DriverCapsFromString(c.NVProxyAllowedDriverCapabilities)
 	if err != nil {
 		return fmt.Errorf("--nvproxy-allowed-driver-capabilities=%q: %w", c.NVProxyAllowedDriverCapabilities, err)
@@ -436,6 +500,30 @@ func (c *Config) validate() error {
 	if unsupported := allowedCaps & ^nvconf.SupportedDriverCaps; unsupported != 0 {
 		return fmt.Errorf("--nvproxy-allowed-driver-capabilities=%q: unsupported capabilities: %v", c.NVProxyAllowedDriverCapabilities, unsupported)
 	}
+	if c.VsockUDS != "" {
+		if !filepath.IsAbs(c.VsockUDS) {
+			return fmt.Errorf("--vsock-uds=%q must be an absolute path", c.VsockUDS)
+		}
+		// CIDs 0-2 are reserved for the hypervisor, loopback and host, and
+		// 0xFFFFFFFF is VMADDR_CID_ANY.
+		if c.VsockCID <= 2 || c.VsockCID >= 0xFFFFFFFF {
+			return fmt.Errorf("--vsock-cid=%d must be between 3 and 4294967294", c.VsockCID)
+		}
+	}
+	if c.NetTCPRepair && c.Network != NetworkHost {
+		return fmt.Errorf("--net-tcp-repair requires --network=host")
+	}
+	if c.AutosaveInterval < 0 {
+		return fmt.Errorf("--autosave-interval=%v must not be negative", c.AutosaveInterval)
+	}
+	if c.AutosaveInterval > 0 {
+		if !filepath.IsAbs(c.AutosaveDir) {
+			return fmt.Errorf("--autosave-dir=%q must be an absolute path when --autosave-interval is set", c.AutosaveDir)
+		}
+		if c.AutosaveKeep < 1 {
+			return fmt.Errorf("--autosave-keep=%d must be at least 1", c.AutosaveKeep)
+		}
+	}
 	return nil
 }
 
@@ -66,6 +66,8 @@ func RegisterFlags(flagSet *flag.FlagSet) {
 	flagSet.String("coverage-report", "", "file path where Go coverage reports are written. Reports will only be generated if runsc is built with --collect_code_coverage and --instrumentation_filter Bazel flags.")
 	flagSet.Bool("log-packets", false, "enable network packet logging.")
 	flagSet.String("pcap-log", "", "location of PCAP log file.")
+	flagSet.String("pcap-format", "pcap", "format of the PCAP log file: pcap or pcapng. pcapng records the name of each interface.")
+	flagSet.String("pcap-filter", "", "classic BPF program selecting the packets written to the PCAP log file, as printed by `tcpdump -y RAW -ddd <expression>`. Newlines may be replaced by commas.")
 	flagSet.String("debug-log-format", "text", "log format: text (default), json, or json-k8s.")
 	flagSet.Bool(flagDebugToUserLog, false, "also emit Sentry logs to user-visible logs")
 	// Only register -alsologtostderr flag if it is not already defined on this flagSet.
@@ -81,12 +83,14 @@ func RegisterFlags(flagSet *flag.FlagSet) {
 	flagSet.String("profiling-metrics", "", "comma separated list of metric names which are going to be written to the profiling-metrics-log file from within the sentry in CSV format. profiling-metrics will be snapshotted at a rate specified by profiling-metrics-rate-us. Requires profiling-metrics-log to be set. (DO NOT USE IN PRODUCTION).")
 	flagSet.String("profiling-metrics-log", "", "file name to use for profiling-metrics output; use the special value '-' to write to the user-visible logs. (DO NOT USE IN PRODUCTION)")
 	flagSet.Int("profiling-metrics-rate-us", 1000, "the target rate (in microseconds) at which profiling metrics will be snapshotted.")
+	flagSet.Bool("syscall-metrics", false, "export per-syscall latency distributions and error counters. Adds a small overhead to every syscall.")
 
 	// Debugging flags: strace related
 	flagSet.Bool(flagStrace, false, "enable strace.")
-	flagSet.String(flagStraceSyscalls, "", "comma-separated list of syscalls to trace. If --strace is true and this list is empty, then all syscalls will be traced.")
+	flagSet.String(flagStraceSyscalls, "", "comma-separated list of syscalls or syscall classes (e.g. %file, %net, %process) to trace. If --strace is true and this list is empty, then all syscalls will be traced.")
 	flagSet.Uint(flagStraceLogSize, 1024, "default size (in bytes) to log data argument blobs.")
 	flagSet.Bool("strace-event", false, "send strace to event.")
+	flagSet.String("strace-json", "", "if set, write strace to this file in JSON format, one syscall per line, instead of the log. The same patterns as --debug-log are supported.")
 
 	// Flags that control sandbox runtime behavior.
 	flagSet.String("platform", "systrap", "specifies which platform to use: systrap (default), ptrace, kvm.")
@@ -107,6 +111,10 @@ func RegisterFlags(flagSet *flag.FlagSet) {
 	flagSet.String("pod-init-config", "", "path to configuration file with additional steps to take during pod creation.")
 	flagSet.Var(HostSettingsCheck.Ptr(), "host-settings", "how to handle non-optimal host kernel settings: check (default, advisory-only), ignore (do not check), adjust (best-effort auto-adjustment), or enforce (auto-adjustment must succeed).")
 	flagSet.Var(RestoreSpecValidationEnforce.Ptr(), "restore-spec-validation", "how to handle spec validation during restore.")
+	flagSet.Bool("restore-across-versions", false, "allow restoring checkpoints taken by a different runsc version with the same state format version (experimental). By default, the runsc versions must match.")
+	flagSet.Duration("autosave-interval", 0, "if non-zero, checkpoint the sandbox at this interval without stopping it, saving images to --autosave-dir.")
+	flagSet.String("autosave-dir", "", "absolute path of the directory holding the images saved by --autosave-interval.")
+	flagSet.Int("autosave-keep", 3, "number of most recent images kept in --autosave-dir by --autosave-interval.")
 	flagSet.Bool("systrap-disable-syscall-patching", false, "disables syscall patching when using the Systrap platform. May be necessary to use in case the workload uses the GS register, or uses ptrace within gVisor. Has significant performance implications and is only recommended when the sandbox is known to run otherwise-incompatible workloads. Only relevant for x86.")
 
 	// Flags that control sandbox runtime behavior: MM related.
@@ -135,6 +143,9 @@ func RegisterFlags(flagSet *flag.FlagSet) {
 	// Flags that control sandbox runtime behavior: network related.
 	flagSet.Var(networkTypePtr(NetworkSandbox), "network", "specifies which network to use: sandbox (default), host, none. Using network inside the sandbox is more secure because it's isolated from the host network.")
 	flagSet.Bool("net-raw", false, "enable raw sockets. When false, raw sockets are disabled by removing CAP_NET_RAW from containers (`runsc exec` will still be able to utilize raw sockets). Raw sockets allow malicious containers to craft packets and potentially attack the network.")
+	flagSet.Bool("net-tcp-repair", false, "with --network=host, save and restore established TCP connections using TCP_REPAIR, which checkpointing the sandbox requires. Requires CAP_NET_ADMIN in the host network namespace.")
+	flagSet.Bool("net-tunnels", false, "with --network=sandbox, enable the GRE and IP-in-IP protocols, which gre, gretap, ipip, sit and ip6tnl devices require.")
+	flagSet.Bool("net-sctp", false, "with --network=sandbox, enable SCTP sockets. When false, creating them fails with EPROTONOSUPPORT, as on Linux without the sctp module.")
 	flagSet.Bool("gso", true, "enable host segmentation offload if it is supported by a network device.")
 	flagSet.Bool("software-gso", true, "enable gVisor segmentation offload when host offload can't be enabled.")
 	flagSet.Bool("gvisor-gro", false, "enable gVisor generic receive offload")
@@ -149,6 +160,8 @@ func RegisterFlags(flagSet *flag.FlagSet) {
 	flagSet.Bool("reproduce-nat", false, "Scrape the host netns NAT table and reproduce it in the sandbox.")
 	flagSet.Bool(flagReproduceNFTables, false, "Attempt to scrape and reproduce nftable rules inside the sandbox. Overrides reproduce-nat when true.")
 	flagSet.Bool(flagNetDisconnectOK, true, "Indicates whether open network connections and open unix domain sockets should be disconnected upon save.")
+	flagSet.String("vsock-uds", "", "EXPERIMENTAL: enable AF_VSOCK sockets, forwarded to host Unix domain sockets using the Firecracker vsock-over-UDS convention. Host processes connect to sandbox listeners through the socket at this path, and sandbox connections to the host (CID 2) on port P are forwarded to the socket at <path>_P by a helper process running outside the sandbox.")
+	flagSet.Uint("vsock-cid", 3, "AF_VSOCK context ID of the sandbox. Only used with --vsock-uds.")
 
 	// Flags that control sandbox runtime behavior: accelerator related.
 	flagSet.Bool("nvproxy", false, "EXPERIMENTAL: enable support for Nvidia GPUs")
@@ -18,8 +18,11 @@ package container
 import (
 	"bufio"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
@@ -420,10 +423,17 @@ func (c *Container) Start(conf *config.Config) error {
 // Restore takes a container and replaces its kernel and file system
 // to restore a container from its state file.
 func (c *Container) Restore(conf *config.Config, imagePath string, direct, background bool) error {
+	return c.RestoreWithOptions(conf, imagePath, direct, background, statefile.Options{})
+}
+
+// RestoreWithOptions is equivalent to Restore, but also takes the options
+// that the checkpoint was taken with that are needed to read it, i.e.
+// sfOpts.EncryptionKey.
+func (c *Container) RestoreWithOptions(conf *config.Config, imagePath string, direct, background bool, sfOpts statefile.Options) error {
 	log.Debugf("Restore container, cid: %s", c.ID)
 
 	restore := func(conf *config.Config, spec *specs.Spec) error {
-		return c.Sandbox.Restore(conf, spec, c.ID, imagePath, direct, background)
+		return c.Sandbox.Restore(conf, spec, c.ID, imagePath, direct, background, sfOpts)
 	}
 	return c.startImpl(conf, "restore", restore, c.Sandbox.RestoreSubcontainer)
 }
@@ -710,6 +720,82 @@ func (c *Container) Checkpoint(imagePath string, direct bool, sfOpts statefile.O
 	return c.Sandbox.Checkpoint(c.ID, imagePath, direct, sfOpts, mfOpts)
 }
 
+// PreCopy writes the container's memory that changed since the last call
+// with the same saveID to w, for live migration. Unlike Checkpoint, the
+// container keeps running.
+func (c *Container) PreCopy(w io.Writer, saveID string) (uint64, error) {
+	log.Debugf("Pre-copy container, cid: %s", c.ID)
+	if err := c.requireStatus("pre-copy", Created, Running, Paused); err != nil {
+		return 0, err
+	}
+	return c.Sandbox.PreCopy(w, saveID)
+}
+
+// MigrateOpts contains options for Container.Migrate.
+type MigrateOpts struct {
+	// Rounds is the maximum number of rounds copying memory while the
+	// container runs.
+	Rounds int
+
+	// StopCopyBytes stops copying memory while the container runs once a
+	// round copies fewer bytes than this.
+	StopCopyBytes uint64
+}
+
+// Migrate live migrates the container to the receiver connected to conn, which
+// must call sandbox.ReceiveMigration with the same key. Memory is copied while
+// the container runs, before the container is checkpointed with the memory
+// changed since.
+func (c *Container) Migrate(conn io.ReadWriter, key []byte, opts MigrateOpts) error {
+	log.Debugf("Migrate container, cid: %s", c.ID)
+	var idBytes [16]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return fmt.Errorf("generating migration ID: %w", err)
+	}
+	saveID := hex.EncodeToString(idBytes[:])
+
+	sender, err := sandbox.NewMigrationSender(conn, key)
+	if err != nil {
+		return err
+	}
+	// Copy memory while the container runs until little enough changes
+	// between rounds.
+	for round := 0; round < opts.Rounds; round++ {
+		start := time.Now()
+		sent, err := sender.PreCopy(c, saveID)
+		if err != nil {
+			return fmt.Errorf("pre-copy round %d: %w", round, err)
+		}
+		log.Infof("Pre-copy round %d sent %d bytes in %s", round, sent, time.Since(start))
+		if sent < opts.StopCopyBytes {
+			break
+		}
+	}
+
+	// Stop the container and send the memory changed since the last round,
+	// along with the rest of its state.
+	imagePath, err := os.MkdirTemp("", "runsc-migrate-")
+	if err != nil {
+		return fmt.Errorf("creating checkpoint directory: %w", err)
+	}
+	defer os.RemoveAll(imagePath)
+	start := time.Now()
+	sfOpts := statefile.Options{
+		Compression: statefile.CompressionLevelNone,
+	}
+	mfOpts := pgalloc.SaveOpts{
+		ParentSaveID: saveID,
+	}
+	if err := c.Checkpoint(imagePath, false /* direct */, sfOpts, mfOpts); err != nil {
+		return fmt.Errorf("checkpoint failed: %w", err)
+	}
+	if err := sender.Finish(imagePath); err != nil {
+		return fmt.Errorf("sending checkpoint: %w", err)
+	}
+	log.Infof("Final checkpoint taken and sent in %s", time.Since(start))
+	return nil
+}
+
 // Pause suspends the container and its kernel.
 // The call only succeeds if the container's status is created or running.
 func (c *Container) Pause() error {
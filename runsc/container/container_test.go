@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"math"
 	"math/rand"
+	"net"
 	"os"
 	"os/exec"
 	"path"
@@ -53,6 +54,7 @@ import (
 	"gvisor.dev/gvisor/runsc/cgroup"
 	"gvisor.dev/gvisor/runsc/config"
 	"gvisor.dev/gvisor/runsc/flag"
+	"gvisor.dev/gvisor/runsc/sandbox"
 	"gvisor.dev/gvisor/runsc/specutils"
 )
 
@@ -1190,6 +1192,118 @@ func TestCheckpointRestore(t *testing.T) {
 	}
 }
 
+// TestMigrate checks that a container live migrated to another sandbox picks
+// up from where it left off.
+func TestMigrate(t *testing.T) {
+	// Skip overlay because test requires writing to host file.
+	for name, conf := range configs(t, true /* noOverlay */) {
+		t.Run(name, func(t *testing.T) {
+			dir, err := os.MkdirTemp(testutil.TmpDir(), "migrate-test")
+			if err != nil {
+				t.Fatalf("os.MkdirTemp failed: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			if err := os.Chmod(dir, 0777); err != nil {
+				t.Fatalf("error chmoding file: %q, %v", dir, err)
+			}
+			imagePath := filepath.Join(dir, "image")
+			if err := os.Mkdir(imagePath, 0755); err != nil {
+				t.Fatalf("os.Mkdir failed: %v", err)
+			}
+
+			outputPath := filepath.Join(dir, "output")
+			outputFile, err := createWriteableOutputFile(outputPath)
+			if err != nil {
+				t.Fatalf("error creating output file: %v", err)
+			}
+			defer outputFile.Close()
+
+			// The container dirties memory between pre-copy rounds, so that
+			// the final checkpoint isn't empty.
+			script := fmt.Sprintf("i=0; while true; do echo $i >> %q; x=$(head -c 1048576 /dev/zero | tr '\\0' a); sleep 1; i=$((i+1)); done", outputPath)
+			spec := testutil.NewSpecWithArgs("bash", "-c", script)
+			_, bundleDir, cleanup, err := testutil.SetupContainer(spec, conf)
+			if err != nil {
+				t.Fatalf("error setting up container: %v", err)
+			}
+			defer cleanup()
+
+			args := Args{
+				ID:        testutil.RandomContainerID(),
+				Spec:      spec,
+				BundleDir: bundleDir,
+			}
+			cont, err := New(conf, args)
+			if err != nil {
+				t.Fatalf("error creating container: %v", err)
+			}
+			defer cont.Destroy()
+			if err := cont.Start(conf); err != nil {
+				t.Fatalf("error starting container: %v", err)
+			}
+			if err := waitForFileNotEmpty(outputFile); err != nil {
+				t.Fatalf("Failed to wait for output file: %v", err)
+			}
+
+			key := bytes.Repeat([]byte{'k'}, statefile.EncryptionKeySize)
+			sendConn, recvConn := net.Pipe()
+			recvErr := make(chan error, 1)
+			go func() {
+				defer recvConn.Close()
+				recvErr <- sandbox.ReceiveMigration(recvConn, key, imagePath)
+			}()
+			// Run every pre-copy round, since StopCopyBytes is 0.
+			opts := MigrateOpts{
+				Rounds: 3,
+			}
+			if err := cont.Migrate(sendConn, key, opts); err != nil {
+				t.Fatalf("error migrating container: %v", err)
+			}
+			sendConn.Close()
+			if err := <-recvErr; err != nil {
+				t.Fatalf("error receiving container: %v", err)
+			}
+
+			lastNum, err := readOutputNum(outputPath, -1)
+			if err != nil {
+				t.Fatalf("error with outputFile: %v", err)
+			}
+			// The migrated container replaces the original one.
+			cont.Destroy()
+			cont = nil
+
+			// Delete and recreate file before restoring.
+			if err := os.Remove(outputPath); err != nil {
+				t.Fatalf("error removing file")
+			}
+			outputFile2, err := createWriteableOutputFile(outputPath)
+			if err != nil {
+				t.Fatalf("error creating output file: %v", err)
+			}
+			defer outputFile2.Close()
+
+			cont2, err := New(conf, args)
+			if err != nil {
+				t.Fatalf("error creating container: %v", err)
+			}
+			defer cont2.Destroy()
+			if err := cont2.Restore(conf, imagePath, false /* direct */, true /* background */); err != nil {
+				t.Fatalf("error restoring container: %v", err)
+			}
+			if err := waitForFileNotEmpty(outputFile2); err != nil {
+				t.Fatalf("Failed to wait for output file: %v", err)
+			}
+			firstNum, err := readOutputNum(outputPath, 0)
+			if err != nil {
+				t.Fatalf("error with outputFile: %v", err)
+			}
+			if lastNum+1 != firstNum {
+				t.Errorf("error numbers not in order, previous: %d, next: %d", lastNum, firstNum)
+			}
+		})
+	}
+}
+
 // TestCheckpointRestoreExecKilled checks that exec'd processes are killed
 // after the container is restored.
 func TestCheckpointRestoreExecKilled(t *testing.T) {
@@ -35,6 +35,7 @@ import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/otlp"
 	"gvisor.dev/gvisor/pkg/prometheus"
 	"gvisor.dev/gvisor/pkg/sentry/control"
 	"gvisor.dev/gvisor/pkg/state"
@@ -100,6 +101,13 @@ type servedSandbox struct {
 	// cleanupVerifier holds a reference to the cleanup function of the verifier.
 	cleanupVerifier func()
 
+	// profiling is true while profiles are being collected from the sandbox.
+	profiling bool
+
+	// profiles maps profile types to the last profile of that type collected
+	// from the sandbox.
+	profiles map[string]*sandboxProfile
+
 	// extra contains additional per-sandbox data.
 	extra sandboxData
 }
@@ -256,6 +264,14 @@ type metricServer struct {
 	startTime              time.Time
 	srv                    http.Server
 
+	// otlpClient, if set, is used to periodically push metrics to an
+	// OpenTelemetry collector.
+	otlpClient otlp.Client
+
+	// sandboxProfileDuration is the duration of the profiles periodically
+	// collected from sandboxes. It is zero if sandbox profiling is disabled.
+	sandboxProfileDuration time.Duration
+
 	// Size of the map of written metrics during the last /metrics export. Initially zero.
 	// Used to efficiently reallocate a map of the right size during the next export.
 	lastMetricsWrittenSize atomicbitops.Uint32
@@ -838,6 +854,38 @@ type Server struct {
 	// AllowUnknownRoot causes the metric server to keep running regardless of the existence of the
 	// Config's root directory or the metric server's ability to access it.
 	AllowUnknownRoot bool
+
+	// OTLPEndpoint, if set, causes the metric server to periodically push metrics to an
+	// OpenTelemetry collector at this address, in addition to serving them over HTTP.
+	// It is either "host:port" or "unix:<path>".
+	OTLPEndpoint string
+
+	// OTLPProtocol is the protocol used to push metrics, either "grpc" or "http".
+	OTLPProtocol string
+
+	// OTLPHeaders are added to every request sent to the OpenTelemetry collector.
+	OTLPHeaders map[string]string
+
+	// OTLPInterval is the interval at which metrics are pushed. Defaults to 60s.
+	OTLPInterval time.Duration
+
+	// OTLPTLS, if true, causes connections to the OpenTelemetry collector to use TLS.
+	OTLPTLS bool
+
+	// OTLPTLSCAFile, if set, is a PEM file holding the CA certificates used to
+	// verify the OpenTelemetry collector, instead of the host's.
+	OTLPTLSCAFile string
+
+	// SandboxProfileInterval, if set, causes the metric server to collect
+	// sentry and guest profiles from each sandbox at this interval, and to
+	// serve the latest ones on /runsc-metrics/sandbox-profile/. Sandboxes
+	// must run with profiling enabled (--profile).
+	SandboxProfileInterval time.Duration
+
+	// SandboxProfileDuration is the duration of the CPU, mutex and guest
+	// profiles collected from sandboxes. Defaults to 10s, and is capped at
+	// SandboxProfileInterval.
+	SandboxProfileDuration time.Duration
 }
 
 // Run runs the metric server.
@@ -944,6 +992,14 @@ func (s *Server) Run(ctx context.Context) error {
 		// Disable memory profiling, since we don't expose it.
 		runtime.MemProfileRate = 0
 	}
+	if s.SandboxProfileInterval > 0 {
+		m.sandboxProfileDuration = s.SandboxProfileDuration
+		if m.sandboxProfileDuration <= 0 {
+			m.sandboxProfileDuration = defaultSandboxProfileDuration
+		}
+		m.sandboxProfileDuration = min(m.sandboxProfileDuration, s.SandboxProfileInterval)
+		mux.HandleFunc(sandboxProfilePath, logRequest(m.serveSandboxProfile))
+	}
 	mux.HandleFunc("/metrics", logRequest(m.serveMetrics))
 	mux.HandleFunc("/", logRequest(m.serveIndex))
 	m.srv.Handler = mux
@@ -952,6 +1008,39 @@ func (s *Server) Run(ctx context.Context) error {
 	if err := m.startVerifyLoop(ctx); err != nil {
 		return fmt.Errorf("cannot start background loop: %w", err)
 	}
+	if s.OTLPEndpoint != "" {
+		protocol := s.OTLPProtocol
+		if protocol == "" {
+			protocol = otlp.ProtocolGRPC
+		}
+		cfg := otlp.Config{
+			Endpoint: s.OTLPEndpoint,
+			Protocol: protocol,
+			Headers:  s.OTLPHeaders,
+		}
+		if s.OTLPTLS {
+			tlsConfig, err := otlpTLSConfig(s.OTLPTLSCAFile)
+			if err != nil {
+				return fmt.Errorf("cannot configure OTLP TLS: %w", err)
+			}
+			cfg.TLS = tlsConfig
+		}
+		client, err := otlp.NewClient(cfg)
+		if err != nil {
+			return fmt.Errorf("cannot create OTLP client: %w", err)
+		}
+		interval := s.OTLPInterval
+		if interval <= 0 {
+			interval = defaultOTLPInterval
+		}
+		m.otlpClient = client
+		m.startOTLPExportLoop(ctx, interval)
+		log.Infof("Pushing metrics to OTLP collector at %s over %s (TLS: %t) every %v.", s.OTLPEndpoint, protocol, s.OTLPTLS, interval)
+	}
+	if s.SandboxProfileInterval > 0 {
+		m.startSandboxProfileLoop(ctx, s.SandboxProfileInterval)
+		log.Infof("Collecting profiles from sandboxes every %v.", s.SandboxProfileInterval)
+	}
 	if m.pidFile != "" {
 		if err := os.WriteFile(m.pidFile, []byte(fmt.Sprintf("%d", m.pid)), 0644); err != nil {
 			return fmt.Errorf("cannot write PID to file %q: %w", m.pidFile, err)
@@ -0,0 +1,285 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/log"
+	mpb "gvisor.dev/gvisor/pkg/metric/metric_go_proto"
+	"gvisor.dev/gvisor/pkg/otlp"
+	otlppb "gvisor.dev/gvisor/pkg/otlp/otlp_go_proto"
+	"gvisor.dev/gvisor/pkg/prometheus"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// otlpScopeName is the instrumentation scope of metrics pushed over OTLP.
+const otlpScopeName = "gvisor.dev/gvisor/runsc/metricserver"
+
+// defaultOTLPInterval is the default interval at which metrics are pushed.
+const defaultOTLPInterval = 60 * time.Second
+
+// otlpResourceAttributes maps labels added to all metrics of a sandbox to the
+// attributes of the OTLP resource that represents the sandbox.
+var otlpResourceAttributes = map[string]string{
+	prometheus.SandboxIDLabel:   "gvisor.sandbox.id",
+	prometheus.IterationIDLabel: "gvisor.sandbox.iteration",
+	prometheus.PodNameLabel:     "k8s.pod.name",
+	prometheus.NamespaceLabel:   "k8s.namespace.name",
+}
+
+// ParseOTLPHeaders parses headers in the "key1=value1,key2=value2" format.
+func ParseOTLPHeaders(headers string) (map[string]string, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	rv := make(map[string]string)
+	for _, header := range strings.Split(headers, ",") {
+		k, v, ok := strings.Cut(header, "=")
+		if !ok || len(strings.TrimSpace(k)) == 0 {
+			return nil, fmt.Errorf("invalid header %q, must be in the key=value format", header)
+		}
+		rv[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return rv, nil
+}
+
+// otlpTLSConfig returns the TLS configuration used to connect to the
+// collector. If caFile is empty, the collector is verified with the host's CA
+// certificates.
+func otlpTLSConfig(caFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(caFile) == 0 {
+		return cfg, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg.RootCAs = x509.NewCertPool()
+	if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %q", caFile)
+	}
+	return cfg, nil
+}
+
+// otlpUnit returns the OTLP unit of a metric, in UCUM format.
+func otlpUnit(units mpb.MetricMetadata_Units) string {
+	switch units {
+	case mpb.MetricMetadata_UNITS_NANOSECONDS:
+		return "ns"
+	default:
+		return "1"
+	}
+}
+
+// otlpAttributes converts labels to OTLP attributes, sorted by key.
+func otlpAttributes(labels map[string]string) []*otlppb.KeyValue {
+	if len(labels) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]*otlppb.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, otlp.StringAttr(k, labels[k]))
+	}
+	return attrs
+}
+
+// otlpNumberDataPoint converts a number to an OTLP data point.
+func otlpNumberDataPoint(n *prometheus.Number, labels map[string]string, start, when time.Time) *otlppb.NumberDataPoint {
+	point := &otlppb.NumberDataPoint{
+		Attributes:        otlpAttributes(labels),
+		StartTimeUnixNano: uint64(start.UnixNano()),
+		TimeUnixNano:      uint64(when.UnixNano()),
+	}
+	if n.IsInteger() {
+		point.Value = &otlppb.NumberDataPoint_AsInt{AsInt: n.Int}
+	} else {
+		point.Value = &otlppb.NumberDataPoint_AsDouble{AsDouble: n.Float}
+	}
+	return point
+}
+
+// otlpHistogramDataPoint converts a histogram to an OTLP data point. Both
+// representations have explicit buckets, with the first bucket going from -Inf
+// to the first bound and the last one going from the last bound to +Inf.
+func otlpHistogramDataPoint(h *prometheus.Histogram, labels map[string]string, start, when time.Time) *otlppb.HistogramDataPoint {
+	point := &otlppb.HistogramDataPoint{
+		Attributes:        otlpAttributes(labels),
+		StartTimeUnixNano: uint64(start.UnixNano()),
+		TimeUnixNano:      uint64(when.UnixNano()),
+	}
+	for i, bucket := range h.Buckets {
+		point.Count += bucket.Samples
+		point.BucketCounts = append(point.BucketCounts, bucket.Samples)
+		if i < len(h.Buckets)-1 {
+			point.ExplicitBounds = append(point.ExplicitBounds, bucket.UpperBound.ToFloat())
+		}
+	}
+	sum := h.Total.ToFloat()
+	point.Sum = &sum
+	if point.Count > 0 {
+		min, max := h.Min.ToFloat(), h.Max.ToFloat()
+		point.Min = &min
+		point.Max = &max
+	}
+	return point
+}
+
+// snapshotToOTLP converts a sandbox snapshot to OTLP metrics. Data points of
+// the same metric are grouped together. metadata is used to determine the
+// units of each metric, by Prometheus name. Cumulative values are reported
+// since start.
+func snapshotToOTLP(snapshot *prometheus.Snapshot, metadata map[string]*mpb.MetricMetadata, prefix string, start time.Time) []*otlppb.Metric {
+	var metrics []*otlppb.Metric
+	byName := make(map[string]*otlppb.Metric)
+	for _, d := range snapshot.Data {
+		name := d.Metric.Name
+		m, ok := byName[name]
+		if !ok {
+			m = &otlppb.Metric{
+				Name:        prefix + name,
+				Description: d.Metric.Help,
+				Unit:        otlpUnit(metadata[name].GetUnits()),
+			}
+			switch d.Metric.Type {
+			case prometheus.TypeCounter:
+				m.Data = &otlppb.Metric_Sum{Sum: &otlppb.Sum{
+					AggregationTemporality: otlppb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+					IsMonotonic:            true,
+				}}
+			case prometheus.TypeHistogram:
+				m.Data = &otlppb.Metric_Histogram{Histogram: &otlppb.Histogram{
+					AggregationTemporality: otlppb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				}}
+			default:
+				m.Data = &otlppb.Metric_Gauge{Gauge: &otlppb.Gauge{}}
+			}
+			byName[name] = m
+			metrics = append(metrics, m)
+		}
+		switch data := m.Data.(type) {
+		case *otlppb.Metric_Sum:
+			if d.Number != nil {
+				data.Sum.DataPoints = append(data.Sum.DataPoints, otlpNumberDataPoint(d.Number, d.Labels, start, snapshot.When))
+			}
+		case *otlppb.Metric_Gauge:
+			if d.Number != nil {
+				data.Gauge.DataPoints = append(data.Gauge.DataPoints, otlpNumberDataPoint(d.Number, d.Labels, time.Time{}, snapshot.When))
+			}
+		case *otlppb.Metric_Histogram:
+			if d.HistogramValue != nil {
+				data.Histogram.DataPoints = append(data.Histogram.DataPoints, otlpHistogramDataPoint(d.HistogramValue, d.Labels, start, snapshot.When))
+			}
+		}
+	}
+	return metrics
+}
+
+// otlpResource returns the resource that represents a sandbox, based on the
+// labels added to all its metrics.
+func otlpResource(extraLabels map[string]string) *otlppb.Resource {
+	resource := &otlppb.Resource{
+		Attributes: []*otlppb.KeyValue{otlp.StringAttr("service.name", "gvisor")},
+	}
+	labels := make(map[string]string, len(extraLabels))
+	for label, val := range extraLabels {
+		if attr, ok := otlpResourceAttributes[label]; ok {
+			labels[attr] = val
+		} else {
+			labels[label] = val
+		}
+	}
+	resource.Attributes = append(resource.Attributes, otlpAttributes(labels)...)
+	return resource
+}
+
+// exportOTLP pushes metrics from all sandboxes to the OTLP collector.
+func (m *metricServer) exportOTLP(ctx context.Context) error {
+	ctx, ctxCancel := context.WithTimeout(ctx, metricsExportTimeout)
+	defer ctxCancel()
+
+	m.mu.Lock()
+	if m.shuttingDown {
+		m.mu.Unlock()
+		return nil
+	}
+	loadedSandboxes := m.loadSandboxesLocked(ctx)
+	m.mu.Unlock()
+
+	var mu sync.Mutex
+	var resourceMetrics []*otlppb.ResourceMetrics // Protected by mu.
+	queryMultiSandboxMetrics(ctx, loadedSandboxes, "", func(r sandboxMetricsResult) {
+		if r.err != nil {
+			if r.isRunning {
+				log.Warningf("Could not export metrics from sandbox %s over OTLP: %v", r.served.rootContainerID.SandboxID, r.err)
+			}
+			return
+		}
+		metadata := make(map[string]*mpb.MetricMetadata)
+		for _, md := range r.verifier.AllMetrics() {
+			metadata[md.GetPrometheusName()] = md
+		}
+		metrics := snapshotToOTLP(r.snapshot, metadata, m.exporterPrefix, r.served.createdAt)
+		rm := &otlppb.ResourceMetrics{
+			Resource: otlpResource(r.served.extraLabels),
+			ScopeMetrics: []*otlppb.ScopeMetrics{
+				{
+					Scope:   &otlppb.InstrumentationScope{Name: otlpScopeName},
+					Metrics: metrics,
+				},
+			},
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		resourceMetrics = append(resourceMetrics, rm)
+	})
+	if len(resourceMetrics) == 0 {
+		return nil
+	}
+	return m.otlpClient.ExportMetrics(ctx, &otlppb.ExportMetricsServiceRequest{ResourceMetrics: resourceMetrics})
+}
+
+// startOTLPExportLoop periodically pushes metrics to the OTLP collector in the
+// background, until ctx is canceled.
+func (m *metricServer) startOTLPExportLoop(ctx context.Context, interval time.Duration) {
+	go func() {
+		defer m.otlpClient.Close()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.exportOTLP(ctx); err != nil {
+					log.Warningf("Failed to export metrics over OTLP: %v", err)
+				}
+			}
+		}
+	}()
+}
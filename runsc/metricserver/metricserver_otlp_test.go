@@ -0,0 +1,164 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricserver
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	mpb "gvisor.dev/gvisor/pkg/metric/metric_go_proto"
+	otlppb "gvisor.dev/gvisor/pkg/otlp/otlp_go_proto"
+	"gvisor.dev/gvisor/pkg/prometheus"
+)
+
+// TestSnapshotToOTLP tests the conversion of snapshots to OTLP metrics.
+func TestSnapshotToOTLP(t *testing.T) {
+	start := time.Unix(100, 0)
+	when := time.Unix(200, 0)
+	counter := &prometheus.Metric{Name: "syscalls", Type: prometheus.TypeCounter, Help: "Number of syscalls."}
+	gauge := &prometheus.Metric{Name: "memory", Type: prometheus.TypeGauge}
+	histogram := &prometheus.Metric{Name: "latency", Type: prometheus.TypeHistogram}
+	snapshot := &prometheus.Snapshot{
+		When: when,
+		Data: []*prometheus.Data{
+			prometheus.LabeledIntData(counter, map[string]string{"syscall": "read"}, 10),
+			prometheus.LabeledIntData(counter, map[string]string{"syscall": "write"}, 20),
+			prometheus.NewFloatData(gauge, 1.5),
+			{
+				Metric: histogram,
+				HistogramValue: &prometheus.Histogram{
+					Total: *prometheus.NewInt(35),
+					Min:   *prometheus.NewInt(5),
+					Max:   *prometheus.NewInt(30),
+					Buckets: []prometheus.Bucket{
+						{UpperBound: *prometheus.NewInt(0), Samples: 0},
+						{UpperBound: *prometheus.NewInt(10), Samples: 1},
+						{UpperBound: *prometheus.NewFloat(math.Inf(1)), Samples: 1},
+					},
+				},
+			},
+		},
+	}
+	metadata := map[string]*mpb.MetricMetadata{
+		"latency": {PrometheusName: "latency", Units: mpb.MetricMetadata_UNITS_NANOSECONDS},
+	}
+	metrics := snapshotToOTLP(snapshot, metadata, "runsc_", start)
+	if len(metrics) != 3 {
+		t.Fatalf("wrong number of metrics, got: %d, want: 3", len(metrics))
+	}
+
+	syscalls := metrics[0]
+	if syscalls.GetName() != "runsc_syscalls" || syscalls.GetUnit() != "1" || syscalls.GetDescription() != counter.Help {
+		t.Errorf("wrong counter metadata: %v", syscalls)
+	}
+	if !syscalls.GetSum().GetIsMonotonic() || syscalls.GetSum().GetAggregationTemporality() != otlppb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		t.Errorf("counter is not a monotonic cumulative sum: %v", syscalls)
+	}
+	var values []int64
+	for _, point := range syscalls.GetSum().GetDataPoints() {
+		values = append(values, point.GetAsInt())
+		if point.GetStartTimeUnixNano() != uint64(start.UnixNano()) || point.GetTimeUnixNano() != uint64(when.UnixNano()) {
+			t.Errorf("wrong timestamps: %v", point)
+		}
+		if attrs := point.GetAttributes(); len(attrs) != 1 || attrs[0].GetKey() != "syscall" {
+			t.Errorf("wrong attributes: %v", attrs)
+		}
+	}
+	if diff := cmp.Diff([]int64{10, 20}, values); diff != "" {
+		t.Errorf("wrong counter values (-want +got):\n%s", diff)
+	}
+
+	memory := metrics[1]
+	if got := memory.GetGauge().GetDataPoints(); len(got) != 1 || got[0].GetAsDouble() != 1.5 {
+		t.Errorf("wrong gauge: %v", memory)
+	}
+
+	latency := metrics[2]
+	if latency.GetUnit() != "ns" {
+		t.Errorf("wrong histogram unit, got: %q, want: %q", latency.GetUnit(), "ns")
+	}
+	points := latency.GetHistogram().GetDataPoints()
+	if len(points) != 1 {
+		t.Fatalf("wrong number of histogram data points: %v", latency)
+	}
+	point := points[0]
+	if point.GetCount() != 2 || point.GetSum() != 35 || point.GetMin() != 5 || point.GetMax() != 30 {
+		t.Errorf("wrong histogram statistics: %v", point)
+	}
+	if diff := cmp.Diff([]float64{0, 10}, point.GetExplicitBounds()); diff != "" {
+		t.Errorf("wrong histogram bounds (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]uint64{0, 1, 1}, point.GetBucketCounts()); diff != "" {
+		t.Errorf("wrong histogram bucket counts (-want +got):\n%s", diff)
+	}
+}
+
+// TestOTLPResource tests that sandbox labels become resource attributes.
+func TestOTLPResource(t *testing.T) {
+	resource := otlpResource(map[string]string{
+		prometheus.SandboxIDLabel: "sandbox",
+		prometheus.PodNameLabel:   "pod",
+	})
+	got := make(map[string]string)
+	for _, attr := range resource.GetAttributes() {
+		got[attr.GetKey()] = attr.GetValue().GetStringValue()
+	}
+	want := map[string]string{
+		"service.name":      "gvisor",
+		"gvisor.sandbox.id": "sandbox",
+		"k8s.pod.name":      "pod",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wrong resource attributes (-want +got):\n%s", diff)
+	}
+}
+
+// TestParseOTLPHeaders tests ParseOTLPHeaders.
+func TestParseOTLPHeaders(t *testing.T) {
+	got, err := ParseOTLPHeaders("authorization=Bearer token, x-tenant = a=b")
+	if err != nil {
+		t.Fatalf("ParseOTLPHeaders(): %v", err)
+	}
+	want := map[string]string{"authorization": "Bearer token", "x-tenant": "a=b"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wrong headers (-want +got):\n%s", diff)
+	}
+	if _, err := ParseOTLPHeaders("authorization"); err == nil {
+		t.Errorf("ParseOTLPHeaders() should fail without value")
+	}
+}
+
+func TestOTLPTLSConfig(t *testing.T) {
+	cfg, err := otlpTLSConfig("")
+	if err != nil {
+		t.Fatalf("otlpTLSConfig(): %v", err)
+	}
+	if cfg.RootCAs != nil {
+		t.Errorf("otlpTLSConfig() without CA file should use the host's CA certificates")
+	}
+	noCerts := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(noCerts, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("WriteFile(): %v", err)
+	}
+	for _, caFile := range []string{noCerts, filepath.Join(t.TempDir(), "missing.pem")} {
+		if _, err := otlpTLSConfig(caFile); err == nil {
+			t.Errorf("otlpTLSConfig(%q) should fail", caFile)
+		}
+	}
+}
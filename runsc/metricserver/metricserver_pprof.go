@@ -0,0 +1,332 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"gvisor.dev/gvisor/pkg/sentry/control"
+)
+
+// Field numbers of the messages of the pprof format, see
+// https://github.com/google/pprof/blob/main/proto/profile.proto.
+const (
+	pprofProfileSampleType    = 1
+	pprofProfileSample        = 2
+	pprofProfileMapping       = 3
+	pprofProfileLocation      = 4
+	pprofProfileStringTable   = 6
+	pprofProfileTimeNanos     = 9
+	pprofProfileDurationNanos = 10
+	pprofProfilePeriodType    = 11
+	pprofProfilePeriod        = 12
+
+	pprofValueTypeType = 1
+	pprofValueTypeUnit = 2
+
+	pprofSampleLocationID = 1
+	pprofSampleValue      = 2
+	pprofSampleLabel      = 3
+
+	pprofLabelKey = 1
+	pprofLabelStr = 2
+	pprofLabelNum = 3
+
+	pprofMappingID          = 1
+	pprofMappingMemoryStart = 2
+	pprofMappingMemoryLimit = 3
+	pprofMappingFileOffset  = 4
+	pprofMappingFilename    = 5
+
+	pprofLocationID        = 1
+	pprofLocationMappingID = 2
+	pprofLocationAddress   = 3
+)
+
+// gzipMagic is the header of gzip-compressed data.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// gzipBytes compresses b with gzip.
+func gzipBytes(b []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(b) // Writes to bytes.Buffer don't fail.
+	zw.Close()
+	return buf.Bytes()
+}
+
+// pprofBuilder builds a profile in the pprof format.
+type pprofBuilder struct {
+	// buf contains the encoded fields of the profile, except for the string
+	// table.
+	buf []byte
+
+	// stringsBase is the number of strings that precede strings in the
+	// string table.
+	stringsBase int64
+
+	// strings are the strings added to the string table.
+	strings []string
+
+	// stringIndex maps strings to their index in the string table.
+	stringIndex map[string]int64
+}
+
+// newPprofBuilder returns a pprofBuilder whose string table follows
+// stringsBase existing strings.
+func newPprofBuilder(stringsBase int64) *pprofBuilder {
+	b := &pprofBuilder{
+		stringsBase: stringsBase,
+		stringIndex: make(map[string]int64),
+	}
+	if stringsBase == 0 {
+		// The first string of the string table must be empty.
+		b.str("")
+	}
+	return b
+}
+
+// str returns the index of s in the string table, adding it if needed.
+func (b *pprofBuilder) str(s string) int64 {
+	if i, ok := b.stringIndex[s]; ok {
+		return i
+	}
+	i := b.stringsBase + int64(len(b.strings))
+	b.strings = append(b.strings, s)
+	b.stringIndex[s] = i
+	return i
+}
+
+// uint64Field appends a varint field to msg.
+func uint64Field(msg []byte, num protowire.Number, v uint64) []byte {
+	msg = protowire.AppendTag(msg, num, protowire.VarintType)
+	return protowire.AppendVarint(msg, v)
+}
+
+// messageField appends an embedded message or packed repeated field to msg.
+func messageField(msg []byte, num protowire.Number, v []byte) []byte {
+	msg = protowire.AppendTag(msg, num, protowire.BytesType)
+	return protowire.AppendBytes(msg, v)
+}
+
+// valueType appends a ValueType message to the profile.
+func (b *pprofBuilder) valueType(num protowire.Number, typ, unit string) {
+	var msg []byte
+	msg = uint64Field(msg, pprofValueTypeType, uint64(b.str(typ)))
+	msg = uint64Field(msg, pprofValueTypeUnit, uint64(b.str(unit)))
+	b.buf = messageField(b.buf, num, msg)
+}
+
+// pprofLabel is a label of a sample. Its value is str if not empty, and num
+// otherwise.
+type pprofLabel struct {
+	key string
+	str string
+	num int64
+}
+
+// labelMessage returns an encoded Label message.
+func (b *pprofBuilder) labelMessage(l pprofLabel) []byte {
+	var msg []byte
+	msg = uint64Field(msg, pprofLabelKey, uint64(b.str(l.key)))
+	if l.str != "" {
+		msg = uint64Field(msg, pprofLabelStr, uint64(b.str(l.str)))
+	} else {
+		msg = uint64Field(msg, pprofLabelNum, uint64(l.num))
+	}
+	return msg
+}
+
+// sample appends a Sample message to the profile.
+func (b *pprofBuilder) sample(locationIDs []uint64, values []int64, labels []pprofLabel) {
+	var ids, vals, msg []byte
+	for _, id := range locationIDs {
+		ids = protowire.AppendVarint(ids, id)
+	}
+	for _, v := range values {
+		vals = protowire.AppendVarint(vals, uint64(v))
+	}
+	msg = messageField(msg, pprofSampleLocationID, ids)
+	msg = messageField(msg, pprofSampleValue, vals)
+	for _, l := range labels {
+		msg = messageField(msg, pprofSampleLabel, b.labelMessage(l))
+	}
+	b.buf = messageField(b.buf, pprofProfileSample, msg)
+}
+
+// mapping appends a Mapping message with the given ID to the profile.
+func (b *pprofBuilder) mapping(id uint64, m control.GuestMapping) {
+	var msg []byte
+	msg = uint64Field(msg, pprofMappingID, id)
+	msg = uint64Field(msg, pprofMappingMemoryStart, m.Start)
+	msg = uint64Field(msg, pprofMappingMemoryLimit, m.End)
+	msg = uint64Field(msg, pprofMappingFileOffset, m.Offset)
+	msg = uint64Field(msg, pprofMappingFilename, uint64(b.str(m.Path)))
+	b.buf = messageField(b.buf, pprofProfileMapping, msg)
+}
+
+// location appends a Location message with the given ID to the profile. A
+// mappingID of zero means that the address isn't in a known mapping.
+func (b *pprofBuilder) location(id, mappingID, addr uint64) {
+	var msg []byte
+	msg = uint64Field(msg, pprofLocationID, id)
+	if mappingID != 0 {
+		msg = uint64Field(msg, pprofLocationMappingID, mappingID)
+	}
+	msg = uint64Field(msg, pprofLocationAddress, addr)
+	b.buf = messageField(b.buf, pprofProfileLocation, msg)
+}
+
+// bytes returns the gzip-compressed profile.
+func (b *pprofBuilder) bytes() []byte {
+	msg := b.buf
+	for _, s := range b.strings {
+		msg = protowire.AppendTag(msg, pprofProfileStringTable, protowire.BytesType)
+		msg = protowire.AppendString(msg, s)
+	}
+	return gzipBytes(msg)
+}
+
+// guestProfileToPprof converts a guest profile to the pprof format. Samples
+// refer to unsymbolized locations in the mappings of their process, which
+// pprof symbolizes given the process's binaries, and have labels identifying
+// their process. Sample values are the number of samples and the CPU time
+// they account for.
+func guestProfileToPprof(p *control.GuestProfile) []byte {
+	b := newPprofBuilder(0)
+	b.valueType(pprofProfileSampleType, "samples", "count")
+	b.valueType(pprofProfileSampleType, "cpu", "nanoseconds")
+	b.buf = uint64Field(b.buf, pprofProfileTimeNanos, uint64(p.Start.UnixNano()))
+	b.buf = uint64Field(b.buf, pprofProfileDurationNanos, uint64(p.Duration.Nanoseconds()))
+	b.valueType(pprofProfilePeriodType, "cpu", "nanoseconds")
+	b.buf = uint64Field(b.buf, pprofProfilePeriod, uint64(p.Period.Nanoseconds()))
+
+	// Processes have distinct address spaces, so their mappings and
+	// locations are distinct even if they share addresses.
+	var numMappings uint64
+	mappingIDs := make([][]uint64, len(p.Processes))
+	for i, proc := range p.Processes {
+		for _, m := range proc.Mappings {
+			numMappings++
+			b.mapping(numMappings, m)
+			mappingIDs[i] = append(mappingIDs[i], numMappings)
+		}
+	}
+	type location struct {
+		proc int
+		addr uint64
+	}
+	locationIDs := make(map[location]uint64)
+
+	for _, s := range p.Samples {
+		if s.Process < 0 || s.Process >= len(p.Processes) {
+			continue
+		}
+		proc := &p.Processes[s.Process]
+		ids := make([]uint64, 0, len(s.Stack))
+		for _, addr := range s.Stack {
+			loc := location{s.Process, addr}
+			id, ok := locationIDs[loc]
+			if !ok {
+				id = uint64(len(locationIDs) + 1)
+				locationIDs[loc] = id
+				var mappingID uint64
+				for i, m := range proc.Mappings {
+					if m.Start <= addr && addr < m.End {
+						mappingID = mappingIDs[s.Process][i]
+						break
+					}
+				}
+				b.location(id, mappingID, addr)
+			}
+			ids = append(ids, id)
+		}
+		labels := []pprofLabel{{key: "pid", num: int64(proc.PID)}}
+		for _, l := range []pprofLabel{
+			{key: "container", str: proc.ContainerID},
+			{key: "comm", str: proc.Comm},
+			{key: "exe", str: proc.Exe},
+		} {
+			if l.str != "" {
+				labels = append(labels, l)
+			}
+		}
+		b.sample(ids, []int64{int64(s.Count), int64(s.Count) * p.Period.Nanoseconds()}, labels)
+	}
+	return b.bytes()
+}
+
+// addPprofLabels adds the given string labels to all samples of a profile in
+// the pprof format, which may be gzip-compressed. Labels with empty values are
+// skipped. It returns the gzip-compressed profile.
+func addPprofLabels(profile []byte, labels map[string]string) ([]byte, error) {
+	if bytes.HasPrefix(profile, gzipMagic) {
+		zr, err := gzip.NewReader(bytes.NewReader(profile))
+		if err != nil {
+			return nil, err
+		}
+		if profile, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("cannot decompress profile: %w", err)
+		}
+	}
+
+	// Count the strings of the string table, so that strings added at the end
+	// of the profile get the following indices.
+	numStrings := 0
+	for b := profile; len(b) > 0; {
+		num, typ, n := protowire.ConsumeField(b)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid profile: %w", protowire.ParseError(n))
+		}
+		if num == pprofProfileStringTable && typ == protowire.BytesType {
+			numStrings++
+		}
+		b = b[n:]
+	}
+
+	b := newPprofBuilder(int64(numStrings))
+	keys := make([]string, 0, len(labels))
+	for k, v := range labels {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var labelFields []byte
+	for _, k := range keys {
+		labelFields = messageField(labelFields, pprofSampleLabel, b.labelMessage(pprofLabel{key: k, str: labels[k]}))
+	}
+
+	for len(profile) > 0 {
+		num, typ, n := protowire.ConsumeField(profile)
+		field := profile[:n]
+		profile = profile[n:]
+		if num != pprofProfileSample || typ != protowire.BytesType {
+			b.buf = append(b.buf, field...)
+			continue
+		}
+		_, _, tagLen := protowire.ConsumeTag(field)
+		sample, _ := protowire.ConsumeBytes(field[tagLen:])
+		msg := make([]byte, 0, len(sample)+len(labelFields))
+		msg = append(append(msg, sample...), labelFields...)
+		b.buf = messageField(b.buf, pprofProfileSample, msg)
+	}
+	return b.bytes(), nil
+}
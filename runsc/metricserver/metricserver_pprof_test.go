@@ -0,0 +1,298 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/encoding/protowire"
+	"gvisor.dev/gvisor/pkg/sentry/control"
+)
+
+// testSample is a decoded pprof sample.
+type testSample struct {
+	// Frames are the locations of the sample, formatted as the filename of
+	// their mapping and their address.
+	Frames    []string
+	Values    []int64
+	Labels    map[string]string
+	NumLabels map[string]int64
+}
+
+// forEachField calls f for each field of msg.
+func forEachField(t *testing.T, msg []byte, f func(num protowire.Number, v uint64, b []byte)) {
+	t.Helper()
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			t.Fatalf("invalid tag: %v", protowire.ParseError(n))
+		}
+		msg = msg[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(msg)
+			if n < 0 {
+				t.Fatalf("invalid varint: %v", protowire.ParseError(n))
+			}
+			f(num, v, nil)
+			msg = msg[n:]
+		case protowire.BytesType:
+			b, n := protowire.ConsumeBytes(msg)
+			if n < 0 {
+				t.Fatalf("invalid bytes: %v", protowire.ParseError(n))
+			}
+			f(num, 0, b)
+			msg = msg[n:]
+		default:
+			t.Fatalf("unexpected wire type %v", typ)
+		}
+	}
+}
+
+// packedVarints decodes a packed repeated varint field.
+func packedVarints(t *testing.T, b []byte) []uint64 {
+	t.Helper()
+	var vs []uint64
+	for len(b) > 0 {
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			t.Fatalf("invalid varint: %v", protowire.ParseError(n))
+		}
+		vs = append(vs, v)
+		b = b[n:]
+	}
+	return vs
+}
+
+// decodeSamples decodes the samples of a gzip-compressed pprof profile.
+func decodeSamples(t *testing.T, profile []byte) []testSample {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(profile))
+	if err != nil {
+		t.Fatalf("gzip.NewReader failed: %v", err)
+	}
+	msg, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("cannot decompress profile: %v", err)
+	}
+
+	var strs []string
+	var samples [][]byte
+	mappings := make(map[uint64]uint64)     // Mapping ID to filename.
+	locations := make(map[uint64][2]uint64) // Location ID to mapping ID and address.
+	forEachField(t, msg, func(num protowire.Number, _ uint64, b []byte) {
+		switch num {
+		case pprofProfileStringTable:
+			strs = append(strs, string(b))
+		case pprofProfileSample:
+			samples = append(samples, b)
+		case pprofProfileMapping:
+			var id, filename uint64
+			forEachField(t, b, func(num protowire.Number, v uint64, _ []byte) {
+				switch num {
+				case pprofMappingID:
+					id = v
+				case pprofMappingFilename:
+					filename = v
+				}
+			})
+			mappings[id] = filename
+		case pprofProfileLocation:
+			var id, mapping, addr uint64
+			forEachField(t, b, func(num protowire.Number, v uint64, _ []byte) {
+				switch num {
+				case pprofLocationID:
+					id = v
+				case pprofLocationMappingID:
+					mapping = v
+				case pprofLocationAddress:
+					addr = v
+				}
+			})
+			locations[id] = [2]uint64{mapping, addr}
+		}
+	})
+	if len(strs) == 0 || strs[0] != "" {
+		t.Fatalf("string table %q doesn't start with an empty string", strs)
+	}
+	str := func(i uint64) string {
+		if i >= uint64(len(strs)) {
+			t.Fatalf("string index %d out of range", i)
+		}
+		return strs[i]
+	}
+
+	var got []testSample
+	for _, b := range samples {
+		s := testSample{Labels: make(map[string]string), NumLabels: make(map[string]int64)}
+		forEachField(t, b, func(num protowire.Number, _ uint64, b []byte) {
+			switch num {
+			case pprofSampleLocationID:
+				for _, id := range packedVarints(t, b) {
+					loc, ok := locations[id]
+					if !ok {
+						t.Fatalf("sample refers to unknown location %d", id)
+					}
+					filename := "?"
+					if loc[0] != 0 {
+						filename = str(mappings[loc[0]])
+					}
+					s.Frames = append(s.Frames, fmt.Sprintf("%s@%#x", filename, loc[1]))
+				}
+			case pprofSampleValue:
+				for _, v := range packedVarints(t, b) {
+					s.Values = append(s.Values, int64(v))
+				}
+			case pprofSampleLabel:
+				var key, val, numVal uint64
+				forEachField(t, b, func(num protowire.Number, v uint64, _ []byte) {
+					switch num {
+					case pprofLabelKey:
+						key = v
+					case pprofLabelStr:
+						val = v
+					case pprofLabelNum:
+						numVal = v
+					}
+				})
+				if val != 0 {
+					s.Labels[str(key)] = str(val)
+				} else {
+					s.NumLabels[str(key)] = int64(numVal)
+				}
+			}
+		})
+		got = append(got, s)
+	}
+	return got
+}
+
+func TestGuestProfileToPprof(t *testing.T) {
+	python := []control.GuestMapping{
+		{Start: 0x400000, End: 0x600000, Path: "/usr/bin/python3.11"},
+		{Start: 0x7f0000000000, End: 0x7f0000100000, Offset: 0x1000, Path: "/lib/libc.so.6"},
+	}
+	profile := &control.GuestProfile{
+		Start:    time.Unix(1000, 0),
+		Duration: time.Second,
+		Period:   10 * time.Millisecond,
+		Processes: []control.GuestProcess{
+			{ContainerID: "c1", PID: 1, Comm: "python3", Exe: "/usr/bin/python3.11", Mappings: python},
+			{ContainerID: "c2", PID: 7, Comm: "worker", Exe: "/usr/bin/python3.11", Mappings: python},
+			{ContainerID: "c1", PID: 3, Comm: "kworker"},
+		},
+		Samples: []control.GuestSample{
+			{Process: 0, Stack: []uint64{0x7f0000000010, 0x400100}, Count: 50},
+			{Process: 0, Stack: []uint64{0x400200, 0x400100}, Count: 3},
+			{Process: 1, Stack: []uint64{0x400200}, Count: 2},
+			{Process: 2, Stack: []uint64{0x1000}, Count: 1},
+		},
+	}
+	got := decodeSamples(t, guestProfileToPprof(profile))
+	want := []testSample{
+		{
+			Frames:    []string{"/lib/libc.so.6@0x7f0000000010", "/usr/bin/python3.11@0x400100"},
+			Values:    []int64{50, 500e6},
+			Labels:    map[string]string{"container": "c1", "comm": "python3", "exe": "/usr/bin/python3.11"},
+			NumLabels: map[string]int64{"pid": 1},
+		},
+		{
+			Frames:    []string{"/usr/bin/python3.11@0x400200", "/usr/bin/python3.11@0x400100"},
+			Values:    []int64{3, 30e6},
+			Labels:    map[string]string{"container": "c1", "comm": "python3", "exe": "/usr/bin/python3.11"},
+			NumLabels: map[string]int64{"pid": 1},
+		},
+		{
+			Frames:    []string{"/usr/bin/python3.11@0x400200"},
+			Values:    []int64{2, 20e6},
+			Labels:    map[string]string{"container": "c2", "comm": "worker", "exe": "/usr/bin/python3.11"},
+			NumLabels: map[string]int64{"pid": 7},
+		},
+		{
+			Frames:    []string{"?@0x1000"},
+			Values:    []int64{1, 10e6},
+			Labels:    map[string]string{"container": "c1", "comm": "kworker"},
+			NumLabels: map[string]int64{"pid": 3},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("guestProfileToPprof returned unexpected samples (-want +got):\n%s", diff)
+	}
+}
+
+func TestAddPprofLabels(t *testing.T) {
+	profile := &control.GuestProfile{
+		Period: 10 * time.Millisecond,
+		Processes: []control.GuestProcess{
+			{ContainerID: "c1", PID: 1, Comm: "sh"},
+			{ContainerID: "c1", PID: 2, Comm: "sleep"},
+		},
+		Samples: []control.GuestSample{
+			{Process: 0, Stack: []uint64{0x1000}, Count: 2},
+			{Process: 1, Stack: []uint64{0x2000}, Count: 1},
+		},
+	}
+	labels := map[string]string{
+		"sandbox":  "sandbox1",
+		"platform": "systrap",
+		"pod_name": "",
+		// Reuses a string already in the profile.
+		"namespace_name": "sh",
+	}
+	for _, compressed := range []bool{true, false} {
+		data := guestProfileToPprof(profile)
+		if !compressed {
+			zr, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("gzip.NewReader failed: %v", err)
+			}
+			if data, err = io.ReadAll(zr); err != nil {
+				t.Fatalf("cannot decompress profile: %v", err)
+			}
+		}
+		labeled, err := addPprofLabels(data, labels)
+		if err != nil {
+			t.Fatalf("addPprofLabels failed: %v", err)
+		}
+		got := decodeSamples(t, labeled)
+		want := []testSample{
+			{
+				Frames:    []string{"?@0x1000"},
+				Values:    []int64{2, 20e6},
+				Labels:    map[string]string{"container": "c1", "comm": "sh", "sandbox": "sandbox1", "platform": "systrap", "namespace_name": "sh"},
+				NumLabels: map[string]int64{"pid": 1},
+			},
+			{
+				Frames:    []string{"?@0x2000"},
+				Values:    []int64{1, 10e6},
+				Labels:    map[string]string{"container": "c1", "comm": "sleep", "sandbox": "sandbox1", "platform": "systrap", "namespace_name": "sh"},
+				NumLabels: map[string]int64{"pid": 2},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("addPprofLabels(compressed=%t) returned unexpected samples (-want +got):\n%s", compressed, diff)
+		}
+	}
+
+	if _, err := addPprofLabels([]byte{0xff}, labels); err == nil {
+		t.Errorf("addPprofLabels with invalid profile succeeded")
+	}
+}
@@ -15,16 +15,59 @@
 package metricserver
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
 	"runtime"
 	"runtime/pprof"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/runsc/sandbox"
 )
 
+const (
+	// sandboxProfilePath is the path under which sandbox profiles are served.
+	sandboxProfilePath = "/runsc-metrics/sandbox-profile/"
+
+	// defaultSandboxProfileDuration is the default duration of profiles
+	// collected from sandboxes.
+	defaultSandboxProfileDuration = 10 * time.Second
+)
+
+// Types of profiles collected from sandboxes. All but guest profiles are
+// profiles of the sentry.
+const (
+	sandboxProfileCPU   = "cpu"
+	sandboxProfileHeap  = "heap"
+	sandboxProfileMutex = "mutex"
+	sandboxProfileGuest = "guest"
+)
+
+// sandboxProfileTypes lists the types of profiles collected from sandboxes.
+var sandboxProfileTypes = []string{sandboxProfileCPU, sandboxProfileHeap, sandboxProfileMutex, sandboxProfileGuest}
+
+// sandboxProfile is the result of the last attempt to collect a profile of a
+// given type from a sandbox.
+type sandboxProfile struct {
+	// data is the last profile successfully collected, in the
+	// gzip-compressed pprof format.
+	data []byte
+
+	// collectedAt is the time at which data was collected.
+	collectedAt time.Time
+
+	// err is the error of the last attempt, if it failed.
+	err error
+}
+
 // profileCPU returns a CPU profile over HTTP.
 func (m *metricServer) profileCPU(w *httpResponseWriter, req *http.Request) httpResult {
 	// Time to finish up profiling and flush out the results to the client.
@@ -81,3 +124,232 @@ func (m *metricServer) profileHeap(w *httpResponseWriter, req *http.Request) htt
 	}
 	return httpOK
 }
+
+// readSandboxProfile calls collect with the write end of a pipe, and returns
+// the data written to the pipe.
+func readSandboxProfile(collect func(f *os.File) error) ([]byte, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating pipe: %w", err)
+	}
+	defer r.Close()
+	type readResult struct {
+		data []byte
+		err  error
+	}
+	readCh := make(chan readResult, 1)
+	go func() {
+		data, err := io.ReadAll(r)
+		readCh <- readResult{data, err}
+	}()
+	// The sandbox closes its copy of the pipe once the profile is written.
+	err = collect(w)
+	w.Close()
+	res := <-readCh
+	if err != nil {
+		return nil, err
+	}
+	return res.data, res.err
+}
+
+// collectProfile collects a profile of the given type from sand.
+func collectProfile(sand *sandbox.Sandbox, typ string, duration time.Duration) ([]byte, error) {
+	switch typ {
+	case sandboxProfileCPU:
+		return readSandboxProfile(func(f *os.File) error { return sand.CPUProfile(f, duration) })
+	case sandboxProfileHeap:
+		return readSandboxProfile(func(f *os.File) error { return sand.HeapProfile(f, 0) })
+	case sandboxProfileMutex:
+		return readSandboxProfile(func(f *os.File) error { return sand.MutexProfile(f, duration) })
+	case sandboxProfileGuest:
+		profile, err := sand.GuestProfile(duration, 0)
+		if err != nil {
+			return nil, err
+		}
+		return guestProfileToPprof(profile), nil
+	default:
+		return nil, fmt.Errorf("unknown profile type %q", typ)
+	}
+}
+
+// collectProfiles collects all types of profiles from the sandbox and stores
+// them. Profiles that take time to collect are collected concurrently, and
+// the heap profile is collected once they are done.
+func (s *servedSandbox) collectProfiles(sand *sandbox.Sandbox, duration time.Duration) {
+	s.mu.Lock()
+	labels := make(map[string]string, len(s.extraLabels)+1)
+	for k, v := range s.extraLabels {
+		labels[k] = v
+	}
+	s.mu.Unlock()
+	labels["platform"] = sand.MetricMetadata["platform"]
+
+	collect := func(typ string) {
+		data, err := collectProfile(sand, typ, duration)
+		if err == nil {
+			data, err = addPprofLabels(data, labels)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.profiles == nil {
+			s.profiles = make(map[string]*sandboxProfile, len(sandboxProfileTypes))
+		}
+		profile, ok := s.profiles[typ]
+		if !ok {
+			profile = &sandboxProfile{}
+			s.profiles[typ] = profile
+		}
+		if err != nil {
+			// Only log errors once, since they are likely to persist, e.g.
+			// if the sandbox doesn't have profiling enabled.
+			if profile.err == nil || profile.err.Error() != err.Error() {
+				log.Warningf("Cannot collect %s profile from sandbox %s: %v", typ, s.rootContainerID.SandboxID, err)
+			}
+			profile.err = err
+			return
+		}
+		profile.data = data
+		profile.collectedAt = time.Now()
+		profile.err = nil
+	}
+	var wg sync.WaitGroup
+	for _, typ := range sandboxProfileTypes {
+		if typ == sandboxProfileHeap {
+			continue
+		}
+		wg.Add(1)
+		go func(typ string) {
+			defer wg.Done()
+			collect(typ)
+		}(typ)
+	}
+	wg.Wait()
+	collect(sandboxProfileHeap)
+}
+
+// collectSandboxProfiles starts collecting profiles from all sandboxes that
+// are not already being profiled. It doesn't wait for collection to finish, so
+// that sandboxes which take long to respond don't hold up the others.
+func (m *metricServer) collectSandboxProfiles(ctx context.Context) {
+	m.mu.Lock()
+	if m.shuttingDown {
+		m.mu.Unlock()
+		return
+	}
+	loadedSandboxes := m.loadSandboxesLocked(ctx)
+	m.mu.Unlock()
+
+	for _, l := range loadedSandboxes {
+		if l.err != nil || !l.sandbox.IsRunning() {
+			continue
+		}
+		s := l.served
+		s.mu.Lock()
+		profiling := s.profiling
+		s.profiling = true
+		s.mu.Unlock()
+		if profiling {
+			continue
+		}
+		go func(sand *sandbox.Sandbox) {
+			s.collectProfiles(sand, m.sandboxProfileDuration)
+			s.mu.Lock()
+			s.profiling = false
+			s.mu.Unlock()
+		}(l.sandbox)
+	}
+}
+
+// startSandboxProfileLoop periodically collects profiles from sandboxes in the
+// background, until ctx is canceled.
+func (m *metricServer) startSandboxProfileLoop(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.collectSandboxProfiles(ctx)
+			}
+		}
+	}()
+}
+
+// serveSandboxProfile serves the last profile collected from a sandbox, in
+// the pprof format. The profile type is the last path component, and the
+// sandbox is given by the "sandbox" query parameter, which may be omitted if
+// there is only one sandbox. If the profile type is omitted, the profiles
+// available for all sandboxes are listed.
+func (m *metricServer) serveSandboxProfile(w *httpResponseWriter, req *http.Request) httpResult {
+	typ := strings.TrimPrefix(req.URL.Path, sandboxProfilePath)
+	sandboxID := req.URL.Query().Get("sandbox")
+
+	m.mu.Lock()
+	if m.shuttingDown {
+		m.mu.Unlock()
+		return httpResult{http.StatusServiceUnavailable, errors.New("server is shutting down already")}
+	}
+	var served []*servedSandbox
+	for id, s := range m.sandboxes {
+		if sandboxID == "" || id.SandboxID == sandboxID {
+			served = append(served, s)
+		}
+	}
+	m.mu.Unlock()
+	sort.Slice(served, func(i, j int) bool {
+		return served[i].rootContainerID.SandboxID < served[j].rootContainerID.SandboxID
+	})
+
+	if typ == "" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, s := range served {
+			s.mu.Lock()
+			for _, typ := range sandboxProfileTypes {
+				profile, ok := s.profiles[typ]
+				switch {
+				case !ok:
+					fmt.Fprintf(w, "%s %s pending\n", s.rootContainerID.SandboxID, typ)
+				case profile.err != nil:
+					fmt.Fprintf(w, "%s %s error: %v\n", s.rootContainerID.SandboxID, typ, profile.err)
+				default:
+					fmt.Fprintf(w, "%s %s %s\n", s.rootContainerID.SandboxID, typ, profile.collectedAt.UTC().Format(time.RFC3339))
+				}
+			}
+			s.mu.Unlock()
+		}
+		return httpOK
+	}
+
+	switch len(served) {
+	case 0:
+		return httpResult{http.StatusNotFound, fmt.Errorf("sandbox %q not found", sandboxID)}
+	case 1:
+	default:
+		return httpResult{http.StatusBadRequest, errors.New("multiple sandboxes are served, the \"sandbox\" query parameter must be set")}
+	}
+	s := served[0]
+	s.mu.Lock()
+	profile, ok := s.profiles[typ]
+	var data []byte
+	var collectedAt time.Time
+	if ok {
+		data, collectedAt = profile.data, profile.collectedAt
+	}
+	s.mu.Unlock()
+	if data == nil {
+		for _, t := range sandboxProfileTypes {
+			if t == typ {
+				return httpResult{http.StatusNotFound, fmt.Errorf("no %s profile collected from sandbox %q yet", typ, s.rootContainerID.SandboxID)}
+			}
+		}
+		return httpResult{http.StatusNotFound, fmt.Errorf("unknown profile type %q, must be one of %v", typ, sandboxProfileTypes)}
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.pb.gz", s.rootContainerID.SandboxID, typ)))
+	w.Header().Set("Last-Modified", collectedAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+	return httpOK
+}
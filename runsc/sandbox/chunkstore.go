@@ -0,0 +1,149 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/chunkstore"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/runsc/boot"
+)
+
+const (
+	// chunkStorePackFileName is the name of the file holding the pages of a
+	// chunk store, which is the pages file of all checkpoints saved to it.
+	chunkStorePackFileName = "pages.pack"
+
+	// chunkStoreIndexFileName is the name of the file mapping page hashes to
+	// locations in the pack file. Checkpoints lock it while saving to the
+	// chunk store.
+	chunkStoreIndexFileName = "index"
+)
+
+// chunkStoreFileNames returns the names of the pack and index files holding
+// pages compressed at the given compression level in a chunk store. Pages
+// compressed differently are kept in separate files, since each page is only
+// stored once.
+func chunkStoreFileNames(compression statefile.CompressionLevel) (pack, index string) {
+	if compression == statefile.CompressionLevelNone {
+		return chunkStorePackFileName, chunkStoreIndexFileName
+	}
+	return fmt.Sprintf("pages.%s.pack", compression), fmt.Sprintf("%s.%s", chunkStoreIndexFileName, compression)
+}
+
+// chunkStoreServer serves a chunk store to a sandbox being checkpointed.
+type chunkStoreServer struct {
+	store *chunkstore.Store
+	pack  *os.File
+	index *os.File
+
+	// conn is runsc's end of the connection to the sandbox.
+	conn *os.File
+}
+
+// serve serves the chunk store until the sandbox closes its end of the
+// connection.
+func (c *chunkStoreServer) serve() error {
+	return c.store.Serve(c.conn)
+}
+
+// close closes c's files, releasing the lock on the chunk store.
+func (c *chunkStoreServer) close() {
+	_ = c.conn.Close()
+	_ = c.pack.Close()
+	_ = c.index.Close()
+}
+
+// createChunkStoreSaveFiles is equivalent to createSaveFiles, but for a
+// checkpoint whose pages are saved to the chunk store in directory
+// chunkStore, compressing them at the given level. The checkpoint's pages file
+// is a symbolic link to the chunk store's pack file. Instead of the pages
+// file, the sandbox is given a connection to the returned chunkStoreServer,
+// which holds the lock on the chunk store until it is closed.
+//
+// The chunk store is opened by runsc rather than the sandbox, which can thus
+// only add pages to it, and not modify pages saved by other checkpoints.
+func createChunkStoreSaveFiles(path, chunkStore string, compression statefile.CompressionLevel) ([]*os.File, *chunkStoreServer, error) {
+	if err := os.MkdirAll(chunkStore, 0755); err != nil {
+		return nil, nil, fmt.Errorf("creating chunk store directory %q: %w", chunkStore, err)
+	}
+	var (
+		files   []*os.File
+		server  *chunkStoreServer
+		success bool
+	)
+	defer func() {
+		if success {
+			return
+		}
+		for _, f := range files {
+			_ = f.Close()
+		}
+		if server != nil {
+			server.close()
+		}
+	}()
+
+	stateFilePath := filepath.Join(path, boot.CheckpointStateFileName)
+	f, err := os.OpenFile(stateFilePath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating checkpoint state file %q: %w", stateFilePath, err)
+	}
+	files = append(files, f)
+
+	pagesMetadataFilePath := filepath.Join(path, boot.CheckpointPagesMetadataFileName)
+	f, err = os.OpenFile(pagesMetadataFilePath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating checkpoint pages metadata file %q: %w", pagesMetadataFilePath, err)
+	}
+	files = append(files, f)
+
+	codec, err := compression.Codec()
+	if err != nil {
+		return nil, nil, err
+	}
+	packFileName, indexFileName := chunkStoreFileNames(compression)
+	packFilePath := filepath.Join(chunkStore, packFileName)
+	log.Infof("Locking chunk store %q", chunkStore)
+	pack, index, err := chunkstore.OpenFiles(packFilePath, filepath.Join(chunkStore, indexFileName))
+	if err != nil {
+		return nil, nil, err
+	}
+	server = &chunkStoreServer{
+		pack:  pack,
+		index: index,
+	}
+	if server.store, err = chunkstore.New(pack, index, codec); err != nil {
+		return nil, nil, err
+	}
+	pagesFilePath := filepath.Join(path, boot.CheckpointPagesFileName)
+	if err := os.Symlink(packFilePath, pagesFilePath); err != nil {
+		return nil, nil, fmt.Errorf("linking checkpoint pages file %q to chunk store: %w", pagesFilePath, err)
+	}
+
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating chunk store connection: %w", err)
+	}
+	server.conn = os.NewFile(uintptr(fds[0]), "chunk store connection")
+	files = append(files, os.NewFile(uintptr(fds[1]), "chunk store connection"))
+	success = true
+	return files, server, nil
+}
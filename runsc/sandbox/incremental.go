@@ -0,0 +1,265 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sandbox
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gvisor.dev/gvisor/pkg/cleanup"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/runsc/boot"
+)
+
+// checkpointMetadata returns the metadata of the checkpoint at imagePath.
+func checkpointMetadata(imagePath string) (map[string]string, error) {
+	f, err := os.Open(filepath.Join(imagePath, boot.CheckpointStateFileName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return statefile.MetadataUnsafe(f)
+}
+
+// CheckpointSaveID returns the ID that incremental checkpoints use to refer
+// to the checkpoint at imagePath as their parent.
+func CheckpointSaveID(imagePath string) (string, error) {
+	metadata, err := checkpointMetadata(imagePath)
+	if err != nil {
+		return "", err
+	}
+	id, ok := metadata[boot.CheckpointSaveIDKey]
+	if !ok {
+		return "", fmt.Errorf("checkpoint %q wasn't taken with --incremental", imagePath)
+	}
+	return id, nil
+}
+
+// IsIncrementalCheckpoint returns true if the checkpoint at imagePath is
+// incremental, i.e. it must be merged with its parents to be restored.
+func IsIncrementalCheckpoint(imagePath string) (bool, error) {
+	if _, err := os.Lstat(filepath.Join(imagePath, boot.CheckpointParentLinkName)); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// checkpointChain returns the image paths of the incremental checkpoint at
+// imagePath and its ancestors, ending with a full checkpoint.
+func checkpointChain(imagePath string) ([]string, error) {
+	chain := []string{imagePath}
+	for {
+		cur := chain[len(chain)-1]
+		incremental, err := IsIncrementalCheckpoint(cur)
+		if err != nil {
+			return nil, err
+		}
+		if !incremental {
+			return chain, nil
+		}
+		metadata, err := checkpointMetadata(cur)
+		if err != nil {
+			return nil, err
+		}
+		parentID, ok := metadata[boot.CheckpointParentSaveIDKey]
+		if !ok {
+			return nil, fmt.Errorf("checkpoint %q has a parent but no parent save ID", cur)
+		}
+		parent, err := filepath.EvalSymlinks(filepath.Join(cur, boot.CheckpointParentLinkName))
+		if err != nil {
+			return nil, fmt.Errorf("resolving parent of checkpoint %q: %w", cur, err)
+		}
+		id, err := CheckpointSaveID(parent)
+		if err != nil {
+			return nil, err
+		}
+		if id != parentID {
+			return nil, fmt.Errorf("checkpoint %q isn't the parent of checkpoint %q: got save ID %q, want %q", parent, cur, id, parentID)
+		}
+		// Save IDs are unique, so a cycle can't pass the check above.
+		chain = append(chain, parent)
+	}
+}
+
+// MergeCheckpoint writes the incremental checkpoint at imagePath, merged with
+// its ancestors, to outPath as a full checkpoint. outPath must not contain a
+// checkpoint already. If the checkpoints are encrypted, encryptionKey must be
+// their key, which also encrypts the merged checkpoint.
+func MergeCheckpoint(imagePath, outPath string, encryptionKey []byte) error {
+	chain, err := checkpointChain(imagePath)
+	if err != nil {
+		return err
+	}
+	if len(chain) == 1 {
+		return fmt.Errorf("checkpoint %q isn't incremental", imagePath)
+	}
+	log.Infof("Merging checkpoints %v into %q", chain, outPath)
+
+	enc, err := mergeStateFile(imagePath, outPath, encryptionKey, nil /* setMetadata */)
+	if err != nil {
+		return err
+	}
+
+	var images []kernel.MergeImage
+	for _, path := range chain {
+		image, closeImage, err := openMergeImage(path, encryptionKey)
+		if err != nil {
+			return err
+		}
+		defer closeImage()
+		images = append(images, image)
+	}
+	// The merged pages are compressed like those of the newest checkpoint,
+	// whose state file is the merged checkpoint's.
+	opts := pgalloc.MergeOpts{
+		PagesCodec: images[0].PagesCodec,
+	}
+	if enc != nil {
+		// The merged checkpoint has no save ID.
+		if opts.PagesAEAD, err = enc.PagesAEAD(""); err != nil {
+			return err
+		}
+	}
+
+	outMetadata, err := os.OpenFile(filepath.Join(outPath, boot.CheckpointPagesMetadataFileName), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer outMetadata.Close()
+	outPages, err := os.OpenFile(filepath.Join(outPath, boot.CheckpointPagesFileName), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer outPages.Close()
+
+	var metadataOut io.WriteCloser = outMetadata
+	if enc != nil {
+		if metadataOut, err = enc.NewStreamWriter(outMetadata, statefile.EncryptionPurposePagesMetadata); err != nil {
+			return err
+		}
+	}
+	mw := bufio.NewWriter(metadataOut)
+	pw := bufio.NewWriter(outPages)
+	if err := kernel.MergeMemoryFiles(context.Background(), images, mw, pw, opts); err != nil {
+		return err
+	}
+	if err := mw.Flush(); err != nil {
+		return err
+	}
+	if err := pw.Flush(); err != nil {
+		return err
+	}
+	if err := metadataOut.Close(); err != nil {
+		return err
+	}
+	return outPages.Close()
+}
+
+// openMergeImage opens the pages files of the checkpoint at imagePath for
+// kernel.MergeMemoryFiles(). The returned function closes them.
+func openMergeImage(imagePath string, encryptionKey []byte) (kernel.MergeImage, func(), error) {
+	var image kernel.MergeImage
+	in, err := os.Open(filepath.Join(imagePath, boot.CheckpointStateFileName))
+	if err != nil {
+		return image, nil, err
+	}
+	r, metadata, enc, err := statefile.NewEncryptedReader(in, nil, encryptionKey)
+	if err != nil {
+		in.Close()
+		return image, nil, fmt.Errorf("reading state file of checkpoint %q: %w", imagePath, err)
+	}
+	r.Close()
+	if image.PagesCodec, err = statefile.PagesCodec(metadata); err != nil {
+		return image, nil, err
+	}
+
+	mf, err := os.Open(filepath.Join(imagePath, boot.CheckpointPagesMetadataFileName))
+	if err != nil {
+		return image, nil, err
+	}
+	cu := cleanup.Make(func() { mf.Close() })
+	defer cu.Clean()
+	var metadataIn io.Reader = mf
+	if enc != nil {
+		if metadataIn, err = enc.NewStreamReader(mf, statefile.EncryptionPurposePagesMetadata); err != nil {
+			return image, nil, err
+		}
+		// Pages are keyed by the save ID of the checkpoint.
+		if image.PagesAEAD, err = enc.PagesAEAD(metadata[boot.CheckpointSaveIDKey]); err != nil {
+			return image, nil, err
+		}
+	}
+	image.PagesMetadata = bufio.NewReader(metadataIn)
+	pf, err := os.Open(filepath.Join(imagePath, boot.CheckpointPagesFileName))
+	if err != nil {
+		return image, nil, err
+	}
+	cu.Add(func() { pf.Close() })
+	image.PagesFile = pf
+	return image, cu.Release(), nil
+}
+
+// mergeStateFile copies the state file of the checkpoint at imagePath to
+// outPath, without the metadata that makes it incremental, and with the
+// metadata in setMetadata. It returns the Encryption of the copy, which is nil
+// if encryptionKey is nil.
+func mergeStateFile(imagePath, outPath string, encryptionKey []byte, setMetadata map[string]string) (*statefile.Encryption, error) {
+	in, err := os.Open(filepath.Join(imagePath, boot.CheckpointStateFileName))
+	if err != nil {
+		return nil, err
+	}
+	r, metadata, _, err := statefile.NewEncryptedReader(in, nil, encryptionKey)
+	if err != nil {
+		in.Close()
+		return nil, fmt.Errorf("reading state file: %w", err)
+	}
+	defer r.Close()
+	delete(metadata, boot.CheckpointParentSaveIDKey)
+	// The merged checkpoint isn't the parent of any incremental checkpoint.
+	delete(metadata, boot.CheckpointSaveIDKey)
+	// The copy is encrypted with a new salt.
+	delete(metadata, statefile.EncryptionKey)
+	delete(metadata, statefile.EncryptionSaltKey)
+	for k, v := range setMetadata {
+		metadata[k] = v
+	}
+
+	out, err := os.OpenFile(filepath.Join(outPath, boot.CheckpointStateFileName), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	w, enc, err := statefile.NewEncryptedWriter(out, nil, encryptionKey, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("writing state file: %w", err)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return nil, fmt.Errorf("copying state file: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return enc, out.Close()
+}
@@ -0,0 +1,418 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sandbox
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/pkg/urpc"
+	"gvisor.dev/gvisor/runsc/boot"
+)
+
+// A migration stream starts with a handshake: the receiver sends
+// migrationChallengeSize random bytes, and the sender replies with as many
+// random bytes of its own. Both are the salt of a statefile.Encryption derived
+// from the migration key, which encrypts and authenticates the rest of the
+// stream, so that a stream can't be forged, altered, truncated or replayed
+// without the key. The encrypted stream is a sequence of messages, each
+// starting with one of the following tags:
+//
+//   - migratePreCopy is followed by a pre-copy round written by
+//     pgalloc.MemoryFile.PreCopyTo.
+//
+//   - migrateFile is followed by a file of the final checkpoint: the length of
+//     the file name as a uint16, the file name, the length of the file as a
+//     uint64, and the file's contents. Integers are little-endian.
+//
+//   - migrateDone ends the stream, and must be followed by the end of the
+//     encrypted stream.
+const (
+	migratePreCopy byte = 'P'
+	migrateFile    byte = 'F'
+	migrateDone    byte = 'D'
+)
+
+const (
+	// migrationChallengeSize is the size of the random values exchanged at
+	// the start of a migration stream.
+	migrationChallengeSize = 32
+
+	// migrationStreamPurpose is the statefile.Encryption purpose of
+	// migration streams.
+	migrationStreamPurpose = "migration"
+)
+
+// preCopyFileName is the name of the file holding pre-copied memory while a
+// migration is received.
+const preCopyFileName = "precopy.img"
+
+// PreCopy writes the sandbox's memory that changed since the last call with
+// the same saveID to w, without stopping the sandbox. It returns the number of
+// bytes of memory written.
+func (s *Sandbox) PreCopy(w io.Writer, saveID string) (uint64, error) {
+	log.Debugf("Pre-copy sandbox %q, save ID %q", s.ID, saveID)
+	r, pw, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("creating pipe: %w", err)
+	}
+	defer r.Close()
+	opts := boot.PreCopyOpts{
+		FilePayload: urpc.FilePayload{Files: []*os.File{pw}},
+		SaveID:      saveID,
+	}
+
+	var sent uint64
+	callErr := make(chan error, 1)
+	go func() {
+		callErr <- s.call(boot.ContMgrPreCopy, &opts, &sent)
+		// The sandbox closes its copy of the pipe when it's done.
+		pw.Close()
+	}()
+	_, copyErr := io.Copy(w, r)
+	// Closing the pipe fails the pre-copy if copying failed.
+	r.Close()
+	if err := <-callErr; err != nil {
+		return 0, fmt.Errorf("pre-copying sandbox %q: %w", s.ID, err)
+	}
+	if copyErr != nil {
+		return 0, copyErr
+	}
+	return sent, nil
+}
+
+// MigrationSender writes a migration stream.
+type MigrationSender struct {
+	ew io.WriteCloser
+	w  *bufio.Writer
+}
+
+// NewMigrationSender returns a MigrationSender writing to conn, which must be
+// connected to ReceiveMigration called with the same key.
+func NewMigrationSender(conn io.ReadWriter, key []byte) (*MigrationSender, error) {
+	salt := make([]byte, 2*migrationChallengeSize)
+	if _, err := io.ReadFull(conn, salt[:migrationChallengeSize]); err != nil {
+		return nil, fmt.Errorf("reading migration challenge: %w", err)
+	}
+	// The sender's half of the salt ensures that the stream is never
+	// encrypted with a salt that was used before, even if the receiver
+	// repeats its challenge.
+	if _, err := rand.Read(salt[migrationChallengeSize:]); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(salt[migrationChallengeSize:]); err != nil {
+		return nil, fmt.Errorf("writing migration nonce: %w", err)
+	}
+	enc, err := statefile.NewStreamEncryption(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	// Hide conn's Close method, which would be called by ew.Close.
+	ew, err := enc.NewStreamWriter(struct{ io.Writer }{conn}, migrationStreamPurpose)
+	if err != nil {
+		return nil, err
+	}
+	return &MigrationSender{ew: ew, w: bufio.NewWriter(ew)}, nil
+}
+
+// PreCopier is implemented by Sandbox and container.Container.
+type PreCopier interface {
+	PreCopy(w io.Writer, saveID string) (uint64, error)
+}
+
+// PreCopy sends a pre-copy round of the memory of pc. See Sandbox.PreCopy.
+func (m *MigrationSender) PreCopy(pc PreCopier, saveID string) (uint64, error) {
+	if err := m.w.WriteByte(migratePreCopy); err != nil {
+		return 0, err
+	}
+	sent, err := pc.PreCopy(m.w, saveID)
+	if err != nil {
+		return 0, err
+	}
+	return sent, m.w.Flush()
+}
+
+// Finish sends the checkpoint at imagePath, which must have been taken
+// without compression or encryption, with a pgalloc.SaveOpts.ParentSaveID
+// equal to the save ID of previous pre-copy rounds, and ends the stream.
+func (m *MigrationSender) Finish(imagePath string) error {
+	entries, err := os.ReadDir(imagePath)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if err := m.sendFile(imagePath, e.Name()); err != nil {
+			return fmt.Errorf("sending checkpoint file %q: %w", e.Name(), err)
+		}
+	}
+	if err := m.w.WriteByte(migrateDone); err != nil {
+		return err
+	}
+	if err := m.w.Flush(); err != nil {
+		return err
+	}
+	return m.ew.Close()
+}
+
+func (m *MigrationSender) sendFile(dir, name string) error {
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := m.w.WriteByte(migrateFile); err != nil {
+		return err
+	}
+	hdr := binary.LittleEndian.AppendUint16(nil, uint16(len(name)))
+	hdr = append(hdr, name...)
+	hdr = binary.LittleEndian.AppendUint64(hdr, uint64(stat.Size()))
+	if _, err := m.w.Write(hdr); err != nil {
+		return err
+	}
+	n, err := io.Copy(m.w, f)
+	if err != nil {
+		return err
+	}
+	if n != stat.Size() {
+		return fmt.Errorf("file changed size while sending: got %d bytes, want %d", n, stat.Size())
+	}
+	return nil
+}
+
+// ReceiveMigration reads a migration stream from conn, which must be
+// connected to a MigrationSender created with the same key, and writes the
+// migrated sandbox to imagePath as a full checkpoint, which can be restored
+// by Sandbox.Restore. Pre-copied pages are written to the checkpoint's pages
+// file as they're received, so that restoring the checkpoint doesn't need to
+// copy them again, and can start while they're loaded in the background.
+func ReceiveMigration(conn io.ReadWriter, key []byte, imagePath string) error {
+	salt := make([]byte, 2*migrationChallengeSize)
+	if _, err := rand.Read(salt[:migrationChallengeSize]); err != nil {
+		return err
+	}
+	if _, err := conn.Write(salt[:migrationChallengeSize]); err != nil {
+		return fmt.Errorf("writing migration challenge: %w", err)
+	}
+	if _, err := io.ReadFull(conn, salt[migrationChallengeSize:]); err != nil {
+		return fmt.Errorf("reading migration nonce: %w", err)
+	}
+	enc, err := statefile.NewStreamEncryption(key, salt)
+	if err != nil {
+		return err
+	}
+	er, err := enc.NewStreamReader(struct{ io.Reader }{conn}, migrationStreamPurpose)
+	if err != nil {
+		return err
+	}
+
+	staging, err := os.MkdirTemp(imagePath, "migrate-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	preCopied, err := os.Create(filepath.Join(staging, preCopyFileName))
+	if err != nil {
+		return err
+	}
+	defer preCopied.Close()
+
+	br := bufio.NewReader(er)
+	var (
+		preCopiedFRs []memmap.FileRange
+		rounds       int
+	)
+	for {
+		tag, err := br.ReadByte()
+		if err != nil {
+			return fmt.Errorf("reading migration stream: %w", err)
+		}
+		switch tag {
+		case migratePreCopy:
+			frs, err := pgalloc.ReadPreCopy(context.Background(), br, preCopied)
+			if err != nil {
+				return fmt.Errorf("reading pre-copy round %d: %w", rounds, err)
+			}
+			preCopiedFRs = append(preCopiedFRs, frs...)
+			rounds++
+		case migrateFile:
+			if err := receiveFile(br, staging); err != nil {
+				return err
+			}
+		case migrateDone:
+			// Only the end of the encrypted stream shows that the stream
+			// wasn't truncated by an attacker.
+			if _, err := br.ReadByte(); err != io.EOF {
+				if err == nil {
+					err = errors.New("data after the end of the stream")
+				}
+				return fmt.Errorf("reading migration stream: %w", err)
+			}
+			log.Infof("Received %d pre-copy rounds, writing checkpoint to %q", rounds, imagePath)
+			return writeMigratedImage(staging, preCopied, preCopiedFRs, imagePath)
+		default:
+			return fmt.Errorf("invalid migration stream message %q", tag)
+		}
+	}
+}
+
+func receiveFile(r io.Reader, dir string) error {
+	var nameLen uint16
+	if err := binary.Read(r, binary.LittleEndian, &nameLen); err != nil {
+		return err
+	}
+	name := make([]byte, nameLen)
+	if _, err := io.ReadFull(r, name); err != nil {
+		return err
+	}
+	if !filepath.IsLocal(string(name)) || filepath.Base(string(name)) != string(name) || string(name) == preCopyFileName {
+		return fmt.Errorf("invalid checkpoint file name %q", name)
+	}
+	var size uint64
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, string(name)), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.CopyN(f, r, int64(size)); err != nil {
+		return fmt.Errorf("receiving checkpoint file %q: %w", name, err)
+	}
+	return f.Close()
+}
+
+// checkMigratedStateFile returns an error if the state file of the checkpoint
+// in dir can't be written by writeMigratedImage.
+func checkMigratedStateFile(dir string) error {
+	f, err := os.Open(filepath.Join(dir, boot.CheckpointStateFileName))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	metadata, err := statefile.MetadataUnsafe(f)
+	if err != nil {
+		return fmt.Errorf("reading state file: %w", err)
+	}
+	if _, ok := metadata[boot.CheckpointParentSaveIDKey]; !ok {
+		return errors.New("migrated checkpoint isn't incremental")
+	}
+	if _, ok := metadata[statefile.EncryptionKey]; ok {
+		return errors.New("migrated checkpoint is encrypted")
+	}
+	if _, ok := metadata[boot.CheckpointChunkStoreKey]; ok {
+		return errors.New("migrated checkpoint was saved to a chunk store")
+	}
+	codec, err := statefile.PagesCodec(metadata)
+	if err != nil {
+		return err
+	}
+	if codec != nil {
+		return errors.New("migrated checkpoint has compressed pages")
+	}
+	return nil
+}
+
+// writeMigratedImage writes the checkpoint received in staging to imagePath,
+// using preCopied, which holds pre-copied pages, as its pages file.
+func writeMigratedImage(staging string, preCopied *os.File, preCopiedFRs []memmap.FileRange, imagePath string) error {
+	if err := checkMigratedStateFile(staging); err != nil {
+		return err
+	}
+	// The checkpoint is only moved to imagePath once it's complete.
+	out, err := os.MkdirTemp(staging, "image-")
+	if err != nil {
+		return err
+	}
+	if _, err := mergeStateFile(staging, out, nil /* encryptionKey */, map[string]string{boot.CheckpointSparsePagesKey: "true"}); err != nil {
+		return err
+	}
+	metadata, err := os.Open(filepath.Join(staging, boot.CheckpointPagesMetadataFileName))
+	if err != nil {
+		return err
+	}
+	defer metadata.Close()
+	pages, err := os.Open(filepath.Join(staging, boot.CheckpointPagesFileName))
+	if err != nil {
+		return err
+	}
+	defer pages.Close()
+
+	outMetadata, err := os.OpenFile(filepath.Join(out, boot.CheckpointPagesMetadataFileName), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer outMetadata.Close()
+	mw := bufio.NewWriter(outMetadata)
+	if err := kernel.WritePreCopiedMemoryFiles(context.Background(), bufio.NewReader(metadata), pages, preCopiedFRs, mw, preCopied); err != nil {
+		return err
+	}
+	if err := mw.Flush(); err != nil {
+		return err
+	}
+	if err := outMetadata.Close(); err != nil {
+		return err
+	}
+	if err := preCopied.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(preCopied.Name(), filepath.Join(out, boot.CheckpointPagesFileName)); err != nil {
+		return err
+	}
+
+	// Move any other checkpoint files as is.
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		switch e.Name() {
+		case filepath.Base(out), boot.CheckpointStateFileName, boot.CheckpointPagesMetadataFileName, boot.CheckpointPagesFileName:
+			continue
+		}
+		if err := os.Rename(filepath.Join(staging, e.Name()), filepath.Join(out, e.Name())); err != nil {
+			return err
+		}
+	}
+	entries, err = os.ReadDir(out)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.Rename(filepath.Join(out, e.Name()), filepath.Join(imagePath, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sandbox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/runsc/boot"
+)
+
+func migrationKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, statefile.EncryptionKeySize)
+}
+
+// writeStateFile writes an unencrypted state file with the given metadata to
+// dir.
+func writeStateFile(t *testing.T, dir string, metadata map[string]string) {
+	t.Helper()
+	f, err := os.Create(filepath.Join(dir, boot.CheckpointStateFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := statefile.NewWriter(f, nil, metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("state")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReceiveMigrationMalformed(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// key is the key of the sender; the receiver uses migrationKey(1).
+		key []byte
+		// send writes to the stream of m, but doesn't end it.
+		send func(t *testing.T, m *MigrationSender)
+		// finish is true if the encrypted stream is ended after send.
+		finish bool
+		// want is an error that the receiver's error must match, or the
+		// empty string if it must wrap wantErr.
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "wrong key",
+			key:     migrationKey(2),
+			send:    func(t *testing.T, m *MigrationSender) { m.w.WriteByte(migrateDone) },
+			finish:  true,
+			wantErr: statefile.ErrDecryption,
+		},
+		{
+			name:    "truncated",
+			key:     migrationKey(1),
+			send:    func(t *testing.T, m *MigrationSender) { m.w.WriteByte(migrateDone) },
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:   "invalid message",
+			key:    migrationKey(1),
+			send:   func(t *testing.T, m *MigrationSender) { m.w.WriteByte('X') },
+			finish: true,
+			want:   "invalid migration stream message",
+		},
+		{
+			name: "data after done",
+			key:  migrationKey(1),
+			send: func(t *testing.T, m *MigrationSender) {
+				m.w.WriteByte(migrateDone)
+				m.w.WriteByte(migratePreCopy)
+			},
+			finish: true,
+			want:   "data after the end of the stream",
+		},
+		{
+			name: "invalid file name",
+			key:  migrationKey(1),
+			send: func(t *testing.T, m *MigrationSender) {
+				name := "../" + boot.CheckpointStateFileName
+				m.w.WriteByte(migrateFile)
+				binary.Write(m.w, binary.LittleEndian, uint16(len(name)))
+				m.w.WriteString(name)
+				binary.Write(m.w, binary.LittleEndian, uint64(0))
+				m.w.WriteByte(migrateDone)
+			},
+			finish: true,
+			want:   "invalid checkpoint file name",
+		},
+		{
+			name: "short file",
+			key:  migrationKey(1),
+			send: func(t *testing.T, m *MigrationSender) {
+				name := boot.CheckpointStateFileName
+				m.w.WriteByte(migrateFile)
+				binary.Write(m.w, binary.LittleEndian, uint16(len(name)))
+				m.w.WriteString(name)
+				binary.Write(m.w, binary.LittleEndian, uint64(100))
+				m.w.WriteString("state")
+			},
+			finish:  true,
+			wantErr: io.EOF,
+		},
+		{
+			name: "not incremental",
+			key:  migrationKey(1),
+			send: func(t *testing.T, m *MigrationSender) {
+				dir := t.TempDir()
+				writeStateFile(t, dir, map[string]string{})
+				if err := m.sendFile(dir, boot.CheckpointStateFileName); err != nil {
+					t.Errorf("sendFile: %v", err)
+				}
+				m.w.WriteByte(migrateDone)
+			},
+			finish: true,
+			want:   "migrated checkpoint isn't incremental",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sendConn, recvConn := net.Pipe()
+			go func() {
+				defer sendConn.Close()
+				m, err := NewMigrationSender(sendConn, tc.key)
+				if err != nil {
+					return
+				}
+				tc.send(t, m)
+				// Writes fail once the receiver gives up.
+				if err := m.w.Flush(); err != nil {
+					return
+				}
+				if tc.finish {
+					m.ew.Close()
+				}
+			}()
+
+			imagePath := t.TempDir()
+			err := ReceiveMigration(recvConn, migrationKey(1), imagePath)
+			recvConn.Close()
+			switch {
+			case err == nil:
+				t.Fatalf("ReceiveMigration succeeded, want error")
+			case tc.wantErr != nil && !errors.Is(err, tc.wantErr):
+				t.Errorf("ReceiveMigration got error %v, want %v", err, tc.wantErr)
+			case tc.want != "" && !strings.Contains(err.Error(), tc.want):
+				t.Errorf("ReceiveMigration got error %v, want %q", err, tc.want)
+			}
+			// Nothing may be left behind, in particular no image that
+			// could be restored.
+			entries, err := os.ReadDir(imagePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("image path holds %v after failed migration, want nothing", entries)
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/socket/plugin"
 	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/sniffer"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 	"gvisor.dev/gvisor/pkg/urpc"
 	"gvisor.dev/gvisor/runsc/boot"
@@ -583,6 +584,14 @@ func pcapAndNAT(args *boot.CreateLinksAndRoutesArgs, conf *config.Config) error
 			return fmt.Errorf("failed to open PCAP file %s: %v", conf.PCAP, err)
 		}
 		args.FilePayload.Files = append(args.FilePayload.Files, pcap)
+		args.PCAPNG = conf.PCAPFormat == "pcapng"
+		if conf.PCAPFilter != "" {
+			filter, err := sniffer.ParseFilter(conf.PCAPFilter)
+			if err != nil {
+				return fmt.Errorf("invalid pcap-filter: %w", err)
+			}
+			args.PCAPFilter = filter
+		}
 	}
 
 	// Pass the host's NAT table if requested.
@@ -103,6 +103,70 @@ func createControlSocket(rootDir, id string) (string, int, error) {
 	return "", -1, fmt.Errorf("unable to find location to write socket file")
 }
 
+// vsockBacklog is the listen(2) backlog of the host socket through which
+// host processes connect to AF_VSOCK listeners in the sandbox.
+const vsockBacklog = 128
+
+// createVsockFiles creates the listening host socket at path through which
+// host processes connect to AF_VSOCK listeners in the sandbox, and starts the
+// broker that connects to the host sockets that sandbox connections to the
+// host are forwarded to. It returns the listening socket and the control
+// socket of the broker.
+func createVsockFiles(conf *config.Config) (*os.File, *os.File, error) {
+	path := conf.VsockUDS
+	// Replace a stale socket left behind by a previous sandbox, e.g. the one
+	// a checkpoint being restored was taken from.
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, nil, fmt.Errorf("removing stale vsock socket %q: %w", path, err)
+		}
+	}
+	sockFD, err := server.CreateSocket(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating vsock socket %q: %w", path, err)
+	}
+	listenFile := os.NewFile(uintptr(sockFD), "vsock_socket")
+	if err := unix.Listen(sockFD, vsockBacklog); err != nil {
+		_ = listenFile.Close()
+		return nil, nil, fmt.Errorf("listening on vsock socket %q: %w", path, err)
+	}
+	brokerFile, err := startVsockBroker(conf)
+	if err != nil {
+		_ = listenFile.Close()
+		return nil, nil, err
+	}
+	return listenFile, brokerFile, nil
+}
+
+// startVsockBroker starts the process that connects to host Unix sockets on
+// behalf of the sandbox, and returns the sandbox end of its control socket.
+// The broker exits once the sandbox closes the control socket.
+func startVsockBroker(conf *config.Config) (*os.File, error) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("creating vsock broker socket: %w", err)
+	}
+	sandboxEnd := os.NewFile(uintptr(fds[0]), "vsock_broker_sandbox")
+	brokerEnd := os.NewFile(uintptr(fds[1]), "vsock_broker")
+	defer brokerEnd.Close()
+
+	cmd := exec.Command(specutils.ExePath, conf.ToFlags()...)
+	cmd.Args[0] = "runsc-vsock-broker"
+	cmd.Args = append(cmd.Args, "vsock-broker", "--control-fd=3")
+	cmd.ExtraFiles = []*os.File{brokerEnd}
+	cmd.SysProcAttr = &unix.SysProcAttr{
+		// Detach from this session, like the sandbox process.
+		Setsid: true,
+	}
+	if err := cmd.Start(); err != nil {
+		_ = sandboxEnd.Close()
+		return nil, fmt.Errorf("starting vsock broker: %w", err)
+	}
+	log.Infof("vsock broker started, PID: %d", cmd.Process.Pid)
+	go func() { _ = cmd.Wait() }()
+	return sandboxEnd, nil
+}
+
 // pid is an atomic type that implements JSON marshal/unmarshal interfaces.
 type pid struct {
 	val atomicbitops.Int64
@@ -492,13 +556,36 @@ func (s *Sandbox) StartSubcontainer(spec *specs.Spec, conf *config.Config, cid s
 }
 
 // Restore sends the restore call for a container in the sandbox.
-func (s *Sandbox) Restore(conf *config.Config, spec *specs.Spec, cid string, imagePath string, direct, background bool) error {
+func (s *Sandbox) Restore(conf *config.Config, spec *specs.Spec, cid string, imagePath string, direct, background bool, sfOpts statefile.Options) error {
 	if err := hostsettings.Handle(conf); err != nil {
 		return fmt.Errorf("host settings: %w (use --host-settings=ignore to bypass)", err)
 	}
 
 	log.Debugf("Restore sandbox %q from path %q", s.ID, imagePath)
 
+	if err := boot.CheckAutosaveImage(imagePath); err != nil {
+		return err
+	}
+	incremental, err := IsIncrementalCheckpoint(imagePath)
+	if err != nil {
+		return fmt.Errorf("checking for incremental checkpoint: %w", err)
+	}
+	if incremental {
+		// The sentry only restores full checkpoints, so merge the chain of
+		// checkpoints into one first. The merged image goes outside of the
+		// image path, which may be read-only, and is removed once its files
+		// have been handed to the sandbox.
+		merged, err := os.MkdirTemp("", "runsc-merged-checkpoint-")
+		if err != nil {
+			return fmt.Errorf("creating merged checkpoint directory: %w", err)
+		}
+		defer os.RemoveAll(merged)
+		if err := MergeCheckpoint(imagePath, merged, sfOpts.EncryptionKey); err != nil {
+			return fmt.Errorf("merging incremental checkpoint %q: %w", imagePath, err)
+		}
+		imagePath = merged
+	}
+
 	stateFileName := path.Join(imagePath, boot.CheckpointStateFileName)
 	sf, err := os.Open(stateFileName)
 	if err != nil {
@@ -510,15 +597,31 @@ func (s *Sandbox) Restore(conf *config.Config, spec *specs.Spec, cid string, ima
 		FilePayload: urpc.FilePayload{
 			Files: []*os.File{sf},
 		},
-		Background: background,
+		Background:    background,
+		EncryptionKey: sfOpts.EncryptionKey,
 	}
 
 	// If the pages file exists, we must pass it in.
 	pagesFileName := path.Join(imagePath, boot.CheckpointPagesFileName)
 	pagesReadFlags := os.O_RDONLY
 	if direct {
-		// The contents are page-aligned, so it can be opened with O_DIRECT.
-		pagesReadFlags |= syscall.O_DIRECT
+		metadata, err := checkpointMetadata(imagePath)
+		if err != nil {
+			return fmt.Errorf("reading checkpoint metadata: %w", err)
+		}
+		if codec, err := statefile.PagesCodec(metadata); err != nil {
+			return err
+		} else if codec != nil {
+			// Compressed pages aren't page-aligned.
+			log.Warningf("Ignoring --direct for checkpoint %q with compressed pages", imagePath)
+		} else if _, ok := metadata[boot.CheckpointChunkStoreKey]; ok {
+			// Pages in a chunk store are read one at a time, to verify
+			// their hashes, into buffers that aren't page-aligned.
+			log.Warningf("Ignoring --direct for checkpoint %q saved to a chunk store", imagePath)
+		} else {
+			// The contents are page-aligned, so it can be opened with O_DIRECT.
+			pagesReadFlags |= syscall.O_DIRECT
+		}
 	}
 	if pf, err := os.OpenFile(pagesFileName, pagesReadFlags, 0); err == nil {
 		defer pf.Close()
@@ -535,6 +638,11 @@ func (s *Sandbox) Restore(conf *config.Config, spec *specs.Spec, cid string, ima
 
 	} else if !os.IsNotExist(err) {
 		return fmt.Errorf("opening restore pages file %q failed: %v", pagesFileName, err)
+	} else if metadata, err := checkpointMetadata(imagePath); err != nil {
+		return fmt.Errorf("reading checkpoint metadata: %w", err)
+	} else if chunkStore, ok := metadata[boot.CheckpointChunkStoreKey]; ok {
+		// The pages file is a dangling link to the chunk store's pack file.
+		return fmt.Errorf("checkpoint %q requires chunk store %q, which is missing", imagePath, chunkStore)
 	} else {
 		log.Infof("Using single checkpoint file for sandbox %q", s.ID)
 	}
@@ -952,6 +1060,23 @@ func (s *Sandbox) createSandboxProcess(conf *config.Config, args *Args, startSyn
 	}
 	donations.DonateAndClose("sink-fds", args.SinkFiles...)
 
+	if conf.VsockUDS != "" {
+		listenFile, brokerFile, err := createVsockFiles(conf)
+		if err != nil {
+			return err
+		}
+		donations.DonateAndClose("vsock-fd", listenFile)
+		donations.DonateAndClose("vsock-broker-fd", brokerFile)
+	}
+
+	if conf.AutosaveInterval != 0 {
+		files, err := boot.CreateAutosaveFiles(conf.AutosaveDir, conf.AutosaveKeep)
+		if err != nil {
+			return err
+		}
+		donations.DonateAndClose("autosave-fds", files...)
+	}
+
 	if len(conf.TestOnlyAutosaveImagePath) != 0 {
 		files, err := createSaveFiles(conf.TestOnlyAutosaveImagePath, false, statefile.CompressionLevelFlateBestSpeed)
 		if err != nil {
@@ -1194,6 +1319,11 @@ func (s *Sandbox) createSandboxProcess(conf *config.Config, args *Args, startSyn
 		}
 		cmd.Args = append(cmd.Args, "--profiling-metrics-fd-lossy=false")
 	}
+	if conf.Strace {
+		if err := donations.DonateDebugLogFile("strace-json-fd", conf.StraceJSON, "strace", test, s.StartTime); err != nil {
+			return err
+		}
+	}
 
 	totalSysMem, err := totalSystemMemory()
 	if err != nil {
@@ -1449,11 +1579,46 @@ func (s *Sandbox) SignalProcess(cid string, pid int32, sig unix.Signal, fgProces
 }
 
 // Checkpoint sends the checkpoint call for a container in the sandbox.
-// The statefile will be written to f.
+// The statefile will be written to f. If sfOpts.ChunkStore is not empty, pages
+// are saved to the chunk store in that directory instead.
 func (s *Sandbox) Checkpoint(cid string, imagePath string, direct bool, sfOpts statefile.Options, mfOpts pgalloc.SaveOpts) error {
 	log.Debugf("Checkpoint sandbox %q, statefile options %+v, MemoryFile options %+v", s.ID, sfOpts, mfOpts)
+	if (mfOpts.SaveID != "" || mfOpts.ParentSaveID != "") && sfOpts.Compression != statefile.CompressionLevelNone && !sfOpts.Compression.CompressesPages() {
+		// Merging incremental checkpoints requires a separate pages file.
+		return fmt.Errorf("incremental checkpoints require --compression=none, zstd or lz4")
+	}
+	if direct && sfOpts.Compression.CompressesPages() {
+		// Compressed pages aren't page-aligned.
+		return fmt.Errorf("writing compressed pages with O_DIRECT is not supported, use --compression=none")
+	}
+
+	chunkStore := sfOpts.ChunkStore
+	if chunkStore != "" {
+		if sfOpts.Compression != statefile.CompressionLevelNone && !sfOpts.Compression.CompressesPages() {
+			// Pages must be saved to the pack file.
+			return fmt.Errorf("checkpoints saved to a chunk store require --compression=none, zstd or lz4")
+		}
+		if sfOpts.EncryptionKey != nil || mfOpts.SaveID != "" || mfOpts.ParentSaveID != "" {
+			// Pages in a chunk store are addressed by their contents, and
+			// are shared by all checkpoints saved to it.
+			return fmt.Errorf("checkpoints saved to a chunk store can't be encrypted or incremental")
+		}
+		if direct {
+			// Pages are written to the chunk store by runsc.
+			log.Warningf("Ignoring --direct for checkpoint saved to chunk store %q", chunkStore)
+		}
+	}
 
-	files, err := createSaveFiles(imagePath, direct, sfOpts.Compression)
+	var (
+		files  []*os.File
+		server *chunkStoreServer
+		err    error
+	)
+	if chunkStore != "" {
+		files, server, err = createChunkStoreSaveFiles(imagePath, chunkStore, sfOpts.Compression)
+	} else {
+		files, err = createSaveFiles(imagePath, direct, sfOpts.Compression)
+	}
 	if err != nil {
 		return err
 	}
@@ -1463,18 +1628,51 @@ func (s *Sandbox) Checkpoint(cid string, imagePath string, direct bool, sfOpts s
 		}
 	}()
 
+	metadata := sfOpts.WriteToMetadata(map[string]string{})
+	if mfOpts.SaveID != "" {
+		metadata[boot.CheckpointSaveIDKey] = mfOpts.SaveID
+	}
+	if mfOpts.ParentSaveID != "" {
+		metadata[boot.CheckpointParentSaveIDKey] = mfOpts.ParentSaveID
+	}
+	if chunkStore != "" {
+		metadata[boot.CheckpointChunkStoreKey] = chunkStore
+	}
+
 	opt := control.SaveOpts{
-		Metadata:           sfOpts.WriteToMetadata(map[string]string{}),
+		EncryptionKey:      sfOpts.EncryptionKey,
+		Metadata:           metadata,
 		MemoryFileSaveOpts: mfOpts,
 		FilePayload: urpc.FilePayload{
 			Files: files,
 		},
-		HavePagesFile: len(files) > 1,
-		Resume:        sfOpts.Resume,
+		HavePagesFile:  len(files) > 1,
+		HaveChunkStore: chunkStore != "",
+		Resume:         sfOpts.Resume,
 	}
 
-	if err := s.call(boot.ContMgrCheckpoint, &opt, nil); err != nil {
-		return fmt.Errorf("checkpointing container %q: %w", cid, err)
+	if server == nil {
+		if err := s.call(boot.ContMgrCheckpoint, &opt, nil); err != nil {
+			return fmt.Errorf("checkpointing container %q: %w", cid, err)
+		}
+		s.Checkpointed = true
+		return nil
+	}
+
+	defer server.close()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.serve()
+	}()
+	callErr := s.call(boot.ContMgrCheckpoint, &opt, nil)
+	// Close runsc's copy of the sandbox's end of the chunk store connection,
+	// so that serving ends once the sandbox closes its copy.
+	_ = files[2].Close()
+	if err := <-serveErr; err != nil {
+		return fmt.Errorf("serving chunk store %q: %w", chunkStore, err)
+	}
+	if callErr != nil {
+		return fmt.Errorf("checkpointing container %q: %w", cid, callErr)
 	}
 	s.Checkpointed = true
 	return nil
@@ -1495,8 +1693,9 @@ func createSaveFiles(path string, direct bool, compression statefile.Compression
 
 	// When there is no compression, MemoryFile contents are page-aligned.
 	// It is beneficial to store them separately so certain optimizations can be
-	// applied during restore. See Restore().
-	if compression == statefile.CompressionLevelNone {
+	// applied during restore. See Restore(). Compression levels that compress
+	// pages in independent frames also allow restoring in the background.
+	if compression == statefile.CompressionLevelNone || compression.CompressesPages() {
 		pagesMetadataFilePath := filepath.Join(path, boot.CheckpointPagesMetadataFileName)
 		f, err = os.OpenFile(pagesMetadataFilePath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
 		if err != nil {
@@ -1662,6 +1861,54 @@ func (s *Sandbox) Trace(f *os.File, duration time.Duration) error {
 	return s.call(boot.ProfileTrace, &opts, nil)
 }
 
+// GuestProfile collects a CPU profile of the user stacks of the processes in
+// the sandbox.
+func (s *Sandbox) GuestProfile(duration, period time.Duration) (*control.GuestProfile, error) {
+	log.Debugf("Guest profile %q", s.ID)
+	opts := control.GuestProfileOpts{
+		Duration: duration,
+		Period:   period,
+	}
+	var profile control.GuestProfile
+	if err := s.call(boot.ProfileGuest, &opts, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Capture captures packets of the sandbox's NICs in the pcapng format and
+// copies them to w. It returns when the capture is over, see
+// boot.Network.StartCapture.
+func (s *Sandbox) Capture(w io.Writer, args *boot.StartCaptureArgs) error {
+	log.Debugf("Capture %q", s.ID)
+	r, pw, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("creating pipe: %w", err)
+	}
+	defer r.Close()
+	args.FilePayload = urpc.FilePayload{Files: []*os.File{pw}}
+
+	callErr := make(chan error, 1)
+	go func() {
+		callErr <- s.call(boot.NetworkStartCapture, args, nil)
+		// The sandbox closes its copy of the pipe when the capture is over.
+		pw.Close()
+	}()
+	_, copyErr := io.Copy(w, r)
+	// Closing the pipe stops the capture if copying failed.
+	r.Close()
+	if err := <-callErr; err != nil {
+		return err
+	}
+	return copyErr
+}
+
+// StopCapture stops a capture started with Capture.
+func (s *Sandbox) StopCapture() error {
+	log.Debugf("Stop capture %q", s.ID)
+	return s.call(boot.NetworkStopCapture, nil, nil)
+}
+
 // ChangeLogging changes logging options.
 func (s *Sandbox) ChangeLogging(args control.LoggingArgs) error {
 	log.Debugf("Change logging start %q", s.ID)
@@ -1671,6 +1918,17 @@ func (s *Sandbox) ChangeLogging(args control.LoggingArgs) error {
 	return nil
 }
 
+// SyscallStats returns the syscall stats collected for the given container,
+// and optionally enables or disables syscall stats collection.
+func (s *Sandbox) SyscallStats(args control.SyscallStatsArgs) (*control.SyscallStatsResult, error) {
+	log.Debugf("Syscall stats %q", s.ID)
+	var res control.SyscallStatsResult
+	if err := s.call(boot.SyscallStatsCollect, &args, &res); err != nil {
+		return nil, fmt.Errorf("collecting syscall stats for sandbox %q: %w", s.ID, err)
+	}
+	return &res, nil
+}
+
 // DestroyContainer destroys the given container. If it is the root container,
 // then the entire sandbox is destroyed.
 func (s *Sandbox) DestroyContainer(cid string) error {
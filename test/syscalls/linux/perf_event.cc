@@ -0,0 +1,216 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <errno.h>
+#include <linux/perf_event.h>
+#include <sys/ioctl.h>
+#include <sys/mman.h>
+#include <sys/syscall.h>
+#include <unistd.h>
+
+#include <atomic>
+#include <cstdint>
+#include <cstring>
+
+#include "gtest/gtest.h"
+#include "absl/time/clock.h"
+#include "absl/time/time.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/memory_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+PosixErrorOr<FileDescriptor> PerfEventOpen(perf_event_attr* attr, pid_t pid,
+                                           int cpu, int group_fd,
+                                           unsigned long flags) {
+  int fd = syscall(SYS_perf_event_open, attr, pid, cpu, group_fd, flags);
+  MaybeSave();
+  if (fd < 0) {
+    return PosixError(errno, "perf_event_open");
+  }
+  return FileDescriptor(fd);
+}
+
+// Skips the test if perf events are not available, e.g. because they are
+// blocked by the container runtime when running natively.
+#define SKIP_IF_NO_PERF_EVENTS()                                             \
+  do {                                                                       \
+    perf_event_attr probe = {};                                              \
+    probe.size = sizeof(probe);                                              \
+    probe.type = PERF_TYPE_SOFTWARE;                                         \
+    probe.config = PERF_COUNT_SW_DUMMY;                                      \
+    probe.exclude_kernel = 1;                                                \
+    auto probe_fd = PerfEventOpen(&probe, 0, -1, -1, PERF_FLAG_FD_CLOEXEC);  \
+    SKIP_IF(!probe_fd.ok() && !IsRunningOnGvisor());                         \
+  } while (0)
+
+// BurnCPU spins for at least the given duration of wall time.
+void BurnCPU(absl::Duration d) {
+  std::atomic<uint64_t> n = 0;
+  const absl::Time deadline = absl::Now() + d;
+  while (absl::Now() < deadline) {
+    for (int i = 0; i < 100000; i++) {
+      n.fetch_add(1, std::memory_order_relaxed);
+    }
+  }
+}
+
+perf_event_attr TaskClockAttr() {
+  perf_event_attr attr = {};
+  attr.size = sizeof(attr);
+  attr.type = PERF_TYPE_SOFTWARE;
+  attr.config = PERF_COUNT_SW_TASK_CLOCK;
+  attr.exclude_kernel = 1;
+  return attr;
+}
+
+TEST(PerfEventTest, CountTaskClock) {
+  SKIP_IF_NO_PERF_EVENTS();
+
+  perf_event_attr attr = TaskClockAttr();
+  attr.disabled = 1;
+  attr.read_format =
+      PERF_FORMAT_TOTAL_TIME_ENABLED | PERF_FORMAT_TOTAL_TIME_RUNNING;
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(
+      PerfEventOpen(&attr, 0, -1, -1, PERF_FLAG_FD_CLOEXEC));
+
+  uint64_t values[3];
+  ASSERT_THAT(read(fd.get(), values, sizeof(values)),
+              SyscallSucceedsWithValue(sizeof(values)));
+  EXPECT_EQ(values[0], 0);
+
+  ASSERT_THAT(ioctl(fd.get(), PERF_EVENT_IOC_ENABLE, 0), SyscallSucceeds());
+  BurnCPU(absl::Milliseconds(200));
+  ASSERT_THAT(ioctl(fd.get(), PERF_EVENT_IOC_DISABLE, 0), SyscallSucceeds());
+
+  ASSERT_THAT(read(fd.get(), values, sizeof(values)),
+              SyscallSucceedsWithValue(sizeof(values)));
+  EXPECT_GT(values[0], 0);
+  EXPECT_GT(values[1], 0);
+  EXPECT_EQ(values[1], values[2]);
+
+  // The buffer must hold all values.
+  EXPECT_THAT(read(fd.get(), values, sizeof(uint64_t)),
+              SyscallFailsWithErrno(ENOSPC));
+}
+
+TEST(PerfEventTest, SampleCPUClock) {
+  SKIP_IF_NO_PERF_EVENTS();
+
+  perf_event_attr attr = TaskClockAttr();
+  attr.config = PERF_COUNT_SW_CPU_CLOCK;
+  attr.freq = 1;
+  attr.sample_freq = 100;
+  attr.sample_type = PERF_SAMPLE_IP | PERF_SAMPLE_TID | PERF_SAMPLE_CALLCHAIN;
+  attr.wakeup_events = 1;
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(
+      PerfEventOpen(&attr, 0, -1, -1, PERF_FLAG_FD_CLOEXEC));
+
+  const size_t page_size = getpagesize();
+  const size_t len = (1 + 8) * page_size;
+  Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      Mmap(nullptr, len, PROT_READ | PROT_WRITE, MAP_SHARED, fd.get(), 0));
+  auto* page = static_cast<perf_event_mmap_page*>(m.ptr());
+  const char* data = static_cast<char*>(m.ptr()) + page->data_offset;
+
+  BurnCPU(absl::Milliseconds(500));
+
+  uint64_t head = __atomic_load_n(&page->data_head, __ATOMIC_ACQUIRE);
+  ASSERT_GT(head, 0);
+  ASSERT_LE(head, page->data_size);
+
+  int samples = 0;
+  for (uint64_t off = 0; off < head;) {
+    perf_event_header hdr;
+    memcpy(&hdr, data + off, sizeof(hdr));
+    ASSERT_GE(hdr.size, sizeof(hdr));
+    if (hdr.type == PERF_RECORD_SAMPLE) {
+      struct {
+        uint64_t ip;
+        uint32_t pid, tid;
+        uint64_t nr;
+        uint64_t ips[2];
+      } sample;
+      ASSERT_GE(hdr.size, sizeof(hdr) + sizeof(sample));
+      memcpy(&sample, data + off + sizeof(hdr), sizeof(sample));
+      EXPECT_EQ(sample.pid, getpid());
+      EXPECT_EQ(sample.tid, syscall(SYS_gettid));
+      ASSERT_GE(sample.nr, 2);
+      EXPECT_EQ(sample.ips[0], PERF_CONTEXT_USER);
+      EXPECT_EQ(sample.ips[1], sample.ip);
+      samples++;
+    }
+    off += hdr.size;
+  }
+  EXPECT_GT(samples, 0);
+  __atomic_store_n(&page->data_tail, head, __ATOMIC_RELEASE);
+
+  uint64_t id;
+  ASSERT_THAT(ioctl(fd.get(), PERF_EVENT_IOC_ID, &id), SyscallSucceeds());
+}
+
+TEST(PerfEventTest, InvalidMmapSize) {
+  SKIP_IF_NO_PERF_EVENTS();
+
+  perf_event_attr attr = TaskClockAttr();
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(
+      PerfEventOpen(&attr, 0, -1, -1, PERF_FLAG_FD_CLOEXEC));
+
+  // The data area must be a power of two pages.
+  const size_t len = (1 + 3) * getpagesize();
+  EXPECT_THAT(
+      Mmap(nullptr, len, PROT_READ | PROT_WRITE, MAP_SHARED, fd.get(), 0),
+      PosixErrorIs(EINVAL, ::testing::_));
+}
+
+TEST(PerfEventTest, AttrTooBig) {
+  SKIP_IF_NO_PERF_EVENTS();
+
+  struct {
+    perf_event_attr attr;
+    char extra[8];
+  } big = {};
+  big.attr = TaskClockAttr();
+  big.attr.size = sizeof(big);
+  big.extra[0] = 1;
+  EXPECT_THAT(syscall(SYS_perf_event_open, &big, 0, -1, -1, 0),
+              SyscallFailsWithErrno(E2BIG));
+  EXPECT_LT(big.attr.size, sizeof(big));
+}
+
+TEST(PerfEventTest, UnsupportedEvents) {
+  SKIP_IF(!IsRunningOnGvisor());
+
+  perf_event_attr attr = {};
+  attr.size = sizeof(attr);
+  attr.type = PERF_TYPE_HARDWARE;
+  attr.config = PERF_COUNT_HW_CPU_CYCLES;
+  EXPECT_THAT(syscall(SYS_perf_event_open, &attr, 0, -1, -1, 0),
+              SyscallFailsWithErrno(ENOENT));
+
+  // System-wide events.
+  attr = TaskClockAttr();
+  EXPECT_THAT(syscall(SYS_perf_event_open, &attr, -1, 0, -1, 0),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -133,6 +133,21 @@ func matchPoints(t *testing.T, msgs []test.Message) map[pb.MessageType]*checkers
 		pb.MessageType_MESSAGE_SYSCALL_INOTIFY_ADD_WATCH: {checker: checkSyscallInotifyInitAddWatch},
 		pb.MessageType_MESSAGE_SYSCALL_INOTIFY_RM_WATCH:  {checker: checkSyscallInotifyInitRmWatch},
 		pb.MessageType_MESSAGE_SYSCALL_CLONE:             {checker: checkSyscallClone},
+		pb.MessageType_MESSAGE_SYSCALL_UNLINK:            {checker: checkSyscallUnlink},
+		pb.MessageType_MESSAGE_SYSCALL_RENAME:            {checker: checkSyscallRename},
+		pb.MessageType_MESSAGE_SYSCALL_CHMOD:             {checker: checkSyscallChmod},
+		pb.MessageType_MESSAGE_SYSCALL_CHOWN:             {checker: checkSyscallChown},
+		pb.MessageType_MESSAGE_SYSCALL_LINK:              {checker: checkSyscallLink},
+		pb.MessageType_MESSAGE_SYSCALL_SYMLINK:           {checker: checkSyscallSymlink},
+		pb.MessageType_MESSAGE_SYSCALL_MMAP:              {checker: checkSyscallMmap},
+		pb.MessageType_MESSAGE_SYSCALL_MPROTECT:          {checker: checkSyscallMprotect},
+		pb.MessageType_MESSAGE_SYSCALL_MOUNT:             {checker: checkSyscallMount},
+		pb.MessageType_MESSAGE_SYSCALL_UMOUNT:            {checker: checkSyscallUmount},
+		pb.MessageType_MESSAGE_SYSCALL_PTRACE:            {checker: checkSyscallPtrace},
+		pb.MessageType_MESSAGE_SYSCALL_SETSOCKOPT:        {checker: checkSyscallSetsockopt},
+		pb.MessageType_MESSAGE_SYSCALL_INIT_MODULE:       {checker: checkSyscallInitModule},
+		pb.MessageType_MESSAGE_SYSCALL_DELETE_MODULE:     {checker: checkSyscallDeleteModule},
+		pb.MessageType_MESSAGE_SYSCALL_CAPSET:            {checker: checkSyscallCapset},
 	}
 	return matchers
 }
@@ -878,3 +893,269 @@ func checkSyscallInotifyInitRmWatch(msg test.Message) error {
 	}
 	return nil
 }
+
+func checkSyscallUnlink(msg test.Message) error {
+	p := pb.Unlink{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if want := "trace_test.abc"; !strings.Contains(p.Pathname, want) {
+		return fmt.Errorf("wrong pathname, want: %q, got: %q", want, p.Pathname)
+	}
+	if !strings.HasPrefix(p.ResolvedPath, "/") || !strings.HasSuffix(p.ResolvedPath, p.Pathname) {
+		return fmt.Errorf("invalid resolved path: %q", p.ResolvedPath)
+	}
+	return nil
+}
+
+func checkSyscallRename(msg test.Message) error {
+	p := pb.Rename{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if want := "trace_test.abc"; !strings.Contains(p.OldPathname, want) {
+		return fmt.Errorf("wrong old pathname, want: %q, got: %q", want, p.OldPathname)
+	}
+	if want := "trace_test.abc.new"; !strings.Contains(p.NewPathname, want) {
+		return fmt.Errorf("wrong new pathname, want: %q, got: %q", want, p.NewPathname)
+	}
+	if !strings.HasSuffix(p.OldResolvedPath, p.OldPathname) || !strings.HasSuffix(p.NewResolvedPath, p.NewPathname) {
+		return fmt.Errorf("invalid resolved paths: %q, %q", p.OldResolvedPath, p.NewResolvedPath)
+	}
+	return nil
+}
+
+func checkSyscallChmod(msg test.Message) error {
+	p := pb.Chmod{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if p.Pathname == "" {
+		if p.Fd < 0 {
+			return fmt.Errorf("invalid fd: %d", p.Fd)
+		}
+		if want := "trace_test.abc"; !strings.Contains(p.FdPath, want) {
+			return fmt.Errorf("wrong fd path, want: %q, got: %q", want, p.FdPath)
+		}
+	} else if want := "trace_test.abc"; !strings.Contains(p.Pathname, want) {
+		return fmt.Errorf("wrong pathname, want: %q, got: %q", want, p.Pathname)
+	}
+	if p.Mode != 0755 && p.Mode != 0700 {
+		return fmt.Errorf("invalid mode: %#o", p.Mode)
+	}
+	if p.Exit == nil && !p.HasOldMode {
+		return fmt.Errorf("old mode missing")
+	}
+	return nil
+}
+
+func checkSyscallChown(msg test.Message) error {
+	p := pb.Chown{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if p.Pathname == "" {
+		if p.Fd < 0 {
+			return fmt.Errorf("invalid fd: %d", p.Fd)
+		}
+		if want := "trace_test.abc"; !strings.Contains(p.FdPath, want) {
+			return fmt.Errorf("wrong fd path, want: %q, got: %q", want, p.FdPath)
+		}
+	} else if want := "trace_test.abc"; !strings.Contains(p.Pathname, want) {
+		return fmt.Errorf("wrong pathname, want: %q, got: %q", want, p.Pathname)
+	}
+	if p.Exit == nil && !p.HasOldOwner {
+		return fmt.Errorf("old owner missing")
+	}
+	return nil
+}
+
+func checkSyscallLink(msg test.Message) error {
+	p := pb.Link{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if want := "trace_test.abc"; !strings.Contains(p.OldPathname, want) {
+		return fmt.Errorf("wrong old pathname, want: %q, got: %q", want, p.OldPathname)
+	}
+	if want := "trace_test.abc.link"; !strings.Contains(p.NewPathname, want) {
+		return fmt.Errorf("wrong new pathname, want: %q, got: %q", want, p.NewPathname)
+	}
+	return nil
+}
+
+func checkSyscallSymlink(msg test.Message) error {
+	p := pb.Symlink{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if want := "trace_test.abc"; p.Target != want {
+		return fmt.Errorf("wrong target, want: %q, got: %q", want, p.Target)
+	}
+	if want := "trace_test.abc.symlink"; !strings.Contains(p.NewPathname, want) {
+		return fmt.Errorf("wrong new pathname, want: %q, got: %q", want, p.NewPathname)
+	}
+	if !strings.HasSuffix(p.NewResolvedPath, p.NewPathname) {
+		return fmt.Errorf("invalid resolved path: %q", p.NewResolvedPath)
+	}
+	return nil
+}
+
+func checkSyscallMmap(msg test.Message) error {
+	p := pb.Mmap{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if p.Length == 0 {
+		return fmt.Errorf("invalid length: %d", p.Length)
+	}
+	return nil
+}
+
+func checkSyscallMprotect(msg test.Message) error {
+	p := pb.Mprotect{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if p.Length == 0 {
+		return fmt.Errorf("invalid length: %d", p.Length)
+	}
+	return nil
+}
+
+func checkSyscallMount(msg test.Message) error {
+	p := pb.Mount{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if want := "trace_test.abc"; !strings.Contains(p.Target, want) {
+		return fmt.Errorf("wrong target, want: %q, got: %q", want, p.Target)
+	}
+	if want := "tmpfs"; p.Fstype != want {
+		return fmt.Errorf("wrong fstype, want: %q, got: %q", want, p.Fstype)
+	}
+	return nil
+}
+
+func checkSyscallUmount(msg test.Message) error {
+	p := pb.Umount{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if want := "trace_test.abc"; !strings.Contains(p.Target, want) {
+		return fmt.Errorf("wrong target, want: %q, got: %q", want, p.Target)
+	}
+	return nil
+}
+
+func checkSyscallPtrace(msg test.Message) error {
+	p := pb.Ptrace{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if p.Request != unix.PTRACE_PEEKUSR {
+		return fmt.Errorf("invalid request, want: PTRACE_PEEKUSER, got: %d", p.Request)
+	}
+	if p.Pid != -1 {
+		return fmt.Errorf("invalid pid, want: -1, got: %d", p.Pid)
+	}
+	return nil
+}
+
+func checkSyscallSetsockopt(msg test.Message) error {
+	p := pb.Setsockopt{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if p.Fd < 0 {
+		return fmt.Errorf("invalid fd: %d", p.Fd)
+	}
+	if p.Level != unix.SOL_SOCKET || p.Optname != unix.SO_PASSCRED {
+		return fmt.Errorf("invalid option, want: SOL_SOCKET/SO_PASSCRED, got: %d/%d", p.Level, p.Optname)
+	}
+	if p.Optlen != 4 || len(p.Optval) != 4 {
+		return fmt.Errorf("invalid optval, optlen: %d, optval: %v", p.Optlen, p.Optval)
+	}
+	return nil
+}
+
+func checkSyscallInitModule(msg test.Message) error {
+	p := pb.InitModule{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if p.Exit != nil && p.Exit.Result == 0 {
+		return fmt.Errorf("module loading should fail")
+	}
+	return nil
+}
+
+func checkSyscallDeleteModule(msg test.Message) error {
+	p := pb.DeleteModule{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if want := "trace_test"; p.Name != want {
+		return fmt.Errorf("wrong name, want: %q, got: %q", want, p.Name)
+	}
+	return nil
+}
+
+func checkSyscallCapset(msg test.Message) error {
+	p := pb.Capset{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if p.Version != unix.LINUX_CAPABILITY_VERSION_3 {
+		return fmt.Errorf("invalid version: %#x", p.Version)
+	}
+	if p.Effective&^p.Permitted != 0 {
+		return fmt.Errorf("effective capabilities %#x not in permitted %#x", p.Effective, p.Permitted)
+	}
+	return nil
+}
@@ -15,20 +15,25 @@
 #include <bits/types/struct_itimerspec.h>
 #include <err.h>
 #include <fcntl.h>
+#include <linux/capability.h>
 #include <sched.h>
 #include <stdlib.h>
 #include <sys/eventfd.h>
 #include <sys/inotify.h>
 #include <sys/mman.h>
+#include <sys/mount.h>
+#include <sys/ptrace.h>
 #include <sys/resource.h>
 #include <sys/signalfd.h>
 #include <sys/socket.h>
 #include <sys/stat.h>
+#include <sys/syscall.h>
 #include <sys/timerfd.h>
 #include <sys/types.h>
 #include <sys/un.h>
 #include <unistd.h>
 
+#include <cerrno>
 #include <csignal>
 #include <cstdio>
 #include <iostream>
@@ -662,6 +667,156 @@ void runInotifyRmWatch() {
   rmdir(pathname);
 }
 
+
+void runUnlink() {
+  const auto pathname = "trace_test.abc";
+  int fd = open(pathname, O_CREAT | O_WRONLY, 0644);
+  if (fd < 0) {
+    err(1, "open");
+  }
+  close(fd);
+  if (unlink(pathname)) {
+    err(1, "unlink");
+  }
+}
+
+void runRename() {
+  const auto oldpath = "trace_test.abc";
+  const auto newpath = "trace_test.abc.new";
+  int fd = open(oldpath, O_CREAT | O_WRONLY, 0644);
+  if (fd < 0) {
+    err(1, "open");
+  }
+  close(fd);
+  if (rename(oldpath, newpath)) {
+    err(1, "rename");
+  }
+  unlink(newpath);
+}
+
+void runChmod() {
+  const auto pathname = "trace_test.abc";
+  int fd = open(pathname, O_CREAT | O_WRONLY, 0644);
+  if (fd < 0) {
+    err(1, "open");
+  }
+  auto cleanup = absl::MakeCleanup([fd, pathname] {
+    close(fd);
+    unlink(pathname);
+  });
+  if (chmod(pathname, 0755)) {
+    err(1, "chmod");
+  }
+  if (fchmod(fd, 0700)) {
+    err(1, "fchmod");
+  }
+}
+
+void runChown() {
+  const auto pathname = "trace_test.abc";
+  int fd = open(pathname, O_CREAT | O_WRONLY, 0644);
+  if (fd < 0) {
+    err(1, "open");
+  }
+  auto cleanup = absl::MakeCleanup([fd, pathname] {
+    close(fd);
+    unlink(pathname);
+  });
+  if (chown(pathname, getuid(), getgid())) {
+    err(1, "chown");
+  }
+  if (fchown(fd, getuid(), getgid())) {
+    err(1, "fchown");
+  }
+}
+
+void runLink() {
+  const auto oldpath = "trace_test.abc";
+  const auto newpath = "trace_test.abc.link";
+  int fd = open(oldpath, O_CREAT | O_WRONLY, 0644);
+  if (fd < 0) {
+    err(1, "open");
+  }
+  close(fd);
+  if (link(oldpath, newpath)) {
+    err(1, "link");
+  }
+  unlink(newpath);
+  unlink(oldpath);
+}
+
+void runSymlink() {
+  const auto target = "trace_test.abc";
+  const auto linkpath = "trace_test.abc.symlink";
+  if (symlink(target, linkpath)) {
+    err(1, "symlink");
+  }
+  unlink(linkpath);
+}
+
+void runMmap() {
+  void* addr = mmap(nullptr, kPageSize, PROT_READ | PROT_WRITE,
+                    MAP_PRIVATE | MAP_ANONYMOUS, -1, 0);
+  if (addr == MAP_FAILED) {
+    err(1, "mmap");
+  }
+  auto cleanup = absl::MakeCleanup([addr] { munmap(addr, kPageSize); });
+  if (mprotect(addr, kPageSize, PROT_READ | PROT_EXEC)) {
+    err(1, "mprotect");
+  }
+}
+
+void runMount() {
+  const auto pathname = "trace_test.abc";
+  static constexpr mode_t kDefaultDirMode = 0755;
+  if (mkdir(pathname, kDefaultDirMode)) {
+    err(1, "mkdir");
+  }
+  // The workload may not be allowed to mount, but the points must still be
+  // generated.
+  if (mount("none", pathname, "tmpfs", 0, nullptr) == 0) {
+    umount2(pathname, 0);
+  } else {
+    umount2(pathname, MNT_DETACH);
+  }
+  rmdir(pathname);
+}
+
+void runPtrace() {
+  // Expected to fail, the point is generated regardless.
+  ptrace(PTRACE_PEEKUSER, -1, nullptr, nullptr);
+}
+
+void runSetsockopt() {
+  int fd = socket(AF_UNIX, SOCK_STREAM, 0);
+  if (fd < 0) {
+    err(1, "socket");
+  }
+  auto fd_closer = absl::MakeCleanup([fd] { close(fd); });
+  int val = 1;
+  if (setsockopt(fd, SOL_SOCKET, SO_PASSCRED, &val, sizeof(val))) {
+    err(1, "setsockopt");
+  }
+}
+
+void runModule() {
+  // Module operations are not supported, the points are generated regardless.
+  syscall(SYS_init_module, nullptr, 0, "");
+  syscall(SYS_finit_module, -1, "", 0);
+  syscall(SYS_delete_module, "trace_test", 0);
+}
+
+void runCapset() {
+  struct __user_cap_header_struct hdr = {_LINUX_CAPABILITY_VERSION_3, 0};
+  struct __user_cap_data_struct data[_LINUX_CAPABILITY_U32S_3] = {};
+  if (syscall(SYS_capget, &hdr, data)) {
+    err(1, "capget");
+  }
+  if (syscall(SYS_capset, &hdr, data)) {
+    err(1, "capset");
+  }
+}
+
 }  // namespace testing
 }  // namespace gvisor
 
@@ -697,6 +852,18 @@ int main(int argc, char** argv) {
   ::gvisor::testing::runInotifyInit1();
   ::gvisor::testing::runInotifyAddWatch();
   ::gvisor::testing::runInotifyRmWatch();
+  ::gvisor::testing::runUnlink();
+  ::gvisor::testing::runRename();
+  ::gvisor::testing::runChmod();
+  ::gvisor::testing::runChown();
+  ::gvisor::testing::runLink();
+  ::gvisor::testing::runSymlink();
+  ::gvisor::testing::runMmap();
+  ::gvisor::testing::runMount();
+  ::gvisor::testing::runPtrace();
+  ::gvisor::testing::runSetsockopt();
+  ::gvisor::testing::runModule();
+  ::gvisor::testing::runCapset();
 // signalfd(2), fork(2), and vfork(2) system calls are not supported in arm
 // architecture.
 #ifdef __x86_64__
@@ -25,6 +25,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -318,7 +319,8 @@ func main() {
 			// If the type also has a "// +stateify identtype"
 			// comment, the functions are instead generated to refer to
 			// the type that this newly-defined type is identical to, rather
-			// than about the newly-defined type itself.
+			// than about the newly-defined type itself. If a struct type has a
+			// "// +stateify version N" comment, its encoding is versioned.
 			if d.Doc == nil {
 				continue
 			}
@@ -326,6 +328,7 @@ func main() {
 				generateTypeInfo    = false
 				generateSaverLoader = false
 				isIdentType         = false
+				version             = uint64(0)
 			)
 			for _, l := range d.Doc.List {
 				if l.Text == "// +stateify savable" {
@@ -338,6 +341,13 @@ func main() {
 				if l.Text == "// +stateify identtype" {
 					isIdentType = true
 				}
+				if v, ok := strings.CutPrefix(l.Text, "// +stateify version "); ok {
+					version, err = strconv.ParseUint(v, 10, 32)
+					if err != nil || version == 0 {
+						fmt.Fprintf(os.Stderr, "Invalid `+stateify version` %q; must be a positive integer.", v)
+						os.Exit(1)
+					}
+				}
 			}
 			if !generateTypeInfo && !generateSaverLoader {
 				continue
@@ -399,6 +409,13 @@ func main() {
 					fmt.Fprintf(outputFile, "	return \"%s.%s\"\n", *fullPkg, ts.Name.Name)
 					fmt.Fprintf(outputFile, "}\n\n")
 
+					// Generate the version method.
+					if version != 0 {
+						fmt.Fprintf(outputFile, "func (%s *%s) StateTypeVersion() uint32 {\n", recv, ts.Name.Name)
+						fmt.Fprintf(outputFile, "	return %d\n", version)
+						fmt.Fprintf(outputFile, "}\n\n")
+					}
+
 					// Generate the fields method.
 					fmt.Fprintf(outputFile, "func (%s *%s) StateFields() []string {\n", recv, ts.Name.Name)
 					fmt.Fprintf(outputFile, "	return []string{\n")
@@ -447,6 +464,14 @@ func main() {
 						fmt.Fprintf(outputFile, "func (%s *%s) afterLoad(context.Context) {}\n\n", recv, ts.Name.Name)
 					}
 
+					// Call migrate if a definition was found. It may convert fields
+					// saved by other versions of the type, and is called after all
+					// fields are loaded, but before afterLoad.
+					_, hasMigrate := simpleMethods[method{
+						typeName:   ts.Name.Name,
+						methodName: "migrate",
+					}]
+
 					// Generate the load method.
 					//
 					// N.B. See the comment above for the save method.
@@ -455,6 +480,9 @@ func main() {
 						fmt.Fprintf(outputFile, "func (%s *%s) StateLoad(ctx context.Context, stateSourceObject %sSource) {\n", recv, ts.Name.Name, statePrefix)
 						scanFields(x, scanFunctions{normal: emitLoad, wait: emitLoadWait})
 						scanFields(x, scanFunctions{value: emitLoadValue})
+						if hasMigrate {
+							fmt.Fprintf(outputFile, "	%s.migrate(ctx, stateSourceObject)\n", recv)
+						}
 						if hasAfterLoad {
 							// The call to afterLoad is made conditionally, because when
 							// AfterLoad is called, the object encodes a dependency on